@@ -34,8 +34,21 @@ var (
 		CuponNotFound                 Error
 		ReportNoDataFound             Error // <--- NUEVO ERROR AGREGADO
 		OrdenNotFound                 Error
+		PagoNotFound                  Error
+		TicketNotFound                Error
 		EventoOrganizadorNotDataFound Error
+		TransferenciaNotFound         Error
+		PublicacionReventaNotFound    Error
+		NotificacionFallidaNotFound   Error
+		EventoDominioFallidoNotFound  Error
+		NotificacionNotFound          Error
+		ReembolsoFallidoNotFound      Error
+		RecintoNotFound               Error
 	}{
+		RecintoNotFound: Error{
+			Code:    "VENUE_ERROR_001",
+			Message: "Venue not found",
+		},
 		CommunityNotFound: Error{
 			Code:    "COMMUNITY_ERROR_001",
 			Message: "Community not found",
@@ -105,10 +118,42 @@ var (
 			Code:    "ORDEN_ERROR_001",
 			Message: "Orden de compra no encontrada",
 		},
+		PagoNotFound: Error{
+			Code:    "PAGO_ERROR_001",
+			Message: "Pago no encontrado para la orden",
+		},
+		TicketNotFound: Error{
+			Code:    "CHECKIN_ERROR_002",
+			Message: "Ticket not found for this QR code",
+		},
+		TransferenciaNotFound: Error{
+			Code:    "TRANSFER_ERROR_001",
+			Message: "Ticket transfer not found",
+		},
+		PublicacionReventaNotFound: Error{
+			Code:    "RESALE_ERROR_001",
+			Message: "Resale listing not found",
+		},
 		EventoOrganizadorNotDataFound: Error{
 			Code:    "EVENTO_ORGANIZADOR_ERROR_002",
 			Message: "El organizador no tiene eventos que mostrar",
 		},
+		NotificacionFallidaNotFound: Error{
+			Code:    "NOTIFICATION_ERROR_001",
+			Message: "Dead-lettered notification not found",
+		},
+		EventoDominioFallidoNotFound: Error{
+			Code:    "OUTBOX_ERROR_001",
+			Message: "Dead-lettered outbox event not found",
+		},
+		NotificacionNotFound: Error{
+			Code:    "NOTIFICATION_ERROR_002",
+			Message: "Notification not found",
+		},
+		ReembolsoFallidoNotFound: Error{
+			Code:    "REFUND_ERROR_001",
+			Message: "Failed refund not found",
+		},
 	}
 
 	// For 422 Unprocessable Entity errors
@@ -131,7 +176,102 @@ var (
 		InvalidDateFormat            Error
 		EmailAlreadyRegistered       Error
 		InvalidEventoId              Error
+		TotalMismatch                Error
+		TarifaNotAvailable           Error
+		IdempotencyKeyReused         Error
+		InvalidTicketQR              Error
+		TicketWrongEvent             Error
+		TicketWrongDate              Error
+		InvalidScanTime              Error
+		InvalidTransferRecipient     Error
+		InvalidResalePrice           Error
+		InvalidResalePolicy          Error
+		CannotBuyOwnResale           Error
+		InvalidNotificationPref      Error
+		InvalidReschedule            Error
+		InvalidPagination            Error
+		InvalidPaginationCursor      Error
+		InvalidSearchQuery           Error
+		InvalidSearchFilter          Error
+		InvalidVenue                 Error
+		InvalidGeoQuery              Error
 	}{
+		InvalidVenue: Error{
+			Code:    "VENUE_ERROR_002",
+			Message: "A venue needs a name, an address, its district, province and department, a 6-digit ubigeo, valid coordinates and a non-negative capacity",
+		},
+		InvalidGeoQuery: Error{
+			Code:    "VENUE_ERROR_003",
+			Message: "lat and lng must be sent together as valid coordinates, with a radius of up to 500 km",
+		},
+		InvalidSearchQuery: Error{
+			Code:    "SEARCH_ERROR_001",
+			Message: "Search text is required and must be at most 200 characters",
+		},
+		InvalidSearchFilter: Error{
+			Code:    "SEARCH_ERROR_002",
+			Message: "Unknown date or price range for the search facets",
+		},
+		InvalidPagination: Error{
+			Code:    "PAGINATION_ERROR_001",
+			Message: "limite must be between 1 and 100 and orden one of fecha, popularidad, precio or relevancia",
+		},
+		InvalidPaginationCursor: Error{
+			Code:    "PAGINATION_ERROR_002",
+			Message: "Cursor is malformed or was issued for a different sort order",
+		},
+		InvalidReschedule: Error{
+			Code:    "RESCHEDULE_ERROR_007",
+			Message: "New start must be in the future and the answer deadline between now and the new start",
+		},
+		TotalMismatch: Error{
+			Code:    "ORDEN_ERROR_004",
+			Message: "Order total does not match the computed price",
+		},
+		TarifaNotAvailable: Error{
+			Code:    "ORDEN_ERROR_005",
+			Message: "Tarifa is not on sale for this event",
+		},
+		IdempotencyKeyReused: Error{
+			Code:    "IDEMPOTENCY_ERROR_001",
+			Message: "Idempotency-Key was already used with a different request",
+		},
+		InvalidTicketQR: Error{
+			Code:    "CHECKIN_ERROR_001",
+			Message: "QR code is not a valid ticket",
+		},
+		TicketWrongEvent: Error{
+			Code:    "CHECKIN_ERROR_006",
+			Message: "Ticket belongs to a different event",
+		},
+		TicketWrongDate: Error{
+			Code:    "CHECKIN_ERROR_007",
+			Message: "Ticket is for a different event date",
+		},
+		InvalidScanTime: Error{
+			Code:    "CHECKIN_ERROR_009",
+			Message: "Scan time is missing or in the future",
+		},
+		InvalidTransferRecipient: Error{
+			Code:    "TRANSFER_ERROR_002",
+			Message: "Recipient email is invalid or belongs to the current holder",
+		},
+		InvalidResalePrice: Error{
+			Code:    "RESALE_ERROR_002",
+			Message: "Resale price must be positive and within the event's price cap",
+		},
+		InvalidResalePolicy: Error{
+			Code:    "RESALE_ERROR_003",
+			Message: "Price cap must be positive and royalty between 0 and 100 percent (fee included)",
+		},
+		CannotBuyOwnResale: Error{
+			Code:    "RESALE_ERROR_007",
+			Message: "You cannot buy your own resale listing",
+		},
+		InvalidNotificationPref: Error{
+			Code:    "NOTIFICATION_ERROR_003",
+			Message: "Unknown notification type or channel, or the channel cannot be disabled for that type",
+		},
 		InvalidRequestBody: Error{
 			Code:    "REQUEST_ERROR_001",
 			Message: "Invalid body request",
@@ -257,7 +397,21 @@ var (
 		UsuarioCuponNotUpdate         Error
 		InvalidBodyFormat             Error
 		OrdenNotCreated               Error
+		InvalidVerificationCode       Error
+		ExpiredVerificationCode       Error
+		PaymentNotCaptured            Error
+		PaymentMethodNotSupported     Error
+		PaymentAmountMismatch         Error
+		InvalidIdempotencyKey         Error
 	}{
+		InvalidVerificationCode: Error{
+			Code:    "USER_ERROR_008",
+			Message: "Invalid verification code",
+		},
+		ExpiredVerificationCode: Error{
+			Code:    "USER_ERROR_009",
+			Message: "Verification code has expired",
+		},
 		InvalidUpdatedByValue: Error{
 			Code:    "REQUEST_ERROR_002",
 			Message: "Invalid updated by value error",
@@ -418,15 +572,32 @@ var (
 			Code:    "ORDEN_NOT_CREATED_ERROR_007",
 			Message: "Orden not created",
 		},
+		PaymentNotCaptured: Error{
+			Code:    "PAGO_ERROR_002",
+			Message: "Payment was not captured",
+		},
+		PaymentMethodNotSupported: Error{
+			Code:    "PAGO_ERROR_003",
+			Message: "Payment method not supported",
+		},
+		PaymentAmountMismatch: Error{
+			Code:    "PAGO_ERROR_004",
+			Message: "Captured amount does not match the order total",
+		},
+		InvalidIdempotencyKey: Error{
+			Code:    "IDEMPOTENCY_ERROR_002",
+			Message: "Idempotency-Key must be between 1 and 255 characters",
+		},
 	}
 
 	// For 401 Unauthorized errors
 	AuthenticationError = struct {
-		UnauthorizedUser    Error
-		InvalidRefreshToken Error
-		InvalidAccessToken  Error
-		InvalidCredentials  Error
-		ExpiredToken        Error
+		UnauthorizedUser        Error
+		InvalidRefreshToken     Error
+		InvalidAccessToken      Error
+		InvalidCredentials      Error
+		ExpiredToken            Error
+		InvalidWebhookSignature Error
 	}{
 		UnauthorizedUser: Error{
 			Code:    "AUTHENTICATION_ERROR_001",
@@ -448,6 +619,55 @@ var (
 			Code:    "AUTHENTICATION_ERROR_005",
 			Message: "Token has expired",
 		},
+		InvalidWebhookSignature: Error{
+			Code:    "PAGO_ERROR_005",
+			Message: "Invalid webhook signature",
+		},
+	}
+
+	// For 403 Forbidden errors
+	ForbiddenError = struct {
+		InsufficientPermissions Error
+		NotTicketHolder         Error
+		TransferNotForUser      Error
+		NotRescheduleHolder     Error
+		AccountNotVerified      Error
+	}{
+		NotRescheduleHolder: Error{
+			Code:    "RESCHEDULE_ERROR_006",
+			Message: "Only the current ticket holder can answer the reschedule",
+		},
+		InsufficientPermissions: Error{
+			Code:    "FORBIDDEN_ERROR_001",
+			Message: "You do not have permission to access this resource",
+		},
+		NotTicketHolder: Error{
+			Code:    "TRANSFER_ERROR_003",
+			Message: "Only the current ticket holder can transfer or resell it",
+		},
+		TransferNotForUser: Error{
+			Code:    "TRANSFER_ERROR_004",
+			Message: "This transfer was offered to a different email",
+		},
+		AccountNotVerified: Error{
+			Code:    "TRANSFER_ERROR_009",
+			Message: "Verify your account before accepting a ticket transfer",
+		},
+	}
+
+	// For 429 Too Many Requests errors
+	TooManyRequestsError = struct {
+		VerificationCodeResendLimit  Error
+		VerificationAttemptsExceeded Error
+	}{
+		VerificationCodeResendLimit: Error{
+			Code:    "RATE_LIMIT_ERROR_001",
+			Message: "Too many verification codes requested, try again later",
+		},
+		VerificationAttemptsExceeded: Error{
+			Code:    "RATE_LIMIT_ERROR_002",
+			Message: "Too many failed attempts, request a new verification code",
+		},
 	}
 
 	// For 409 Conflict errors
@@ -456,7 +676,142 @@ var (
 		UserAlreadyExists        Error
 		CuponAlreadyExists       Error
 		InteraccionAlreadyExists Error
+		InsufficientStock        Error
+		TicketsAlreadyIssued     Error
+		OrderNotPayable          Error
+		IdempotencyKeyInUse      Error
+		TicketAlreadyUsed        Error
+		TicketCancelled          Error
+		TicketNotSold            Error
+		DoorsClosed              Error
+		TransferAlreadyPending   Error
+		TransferNotPending       Error
+		TransferExpired          Error
+		TicketNotTransferable    Error
+		ResaleNotEnabled         Error
+		TicketAlreadyListed      Error
+		ResaleNotAvailable       Error
+		TicketNotDownloadable    Error
+		WalletPassesDisabled     Error
+		OrderNotRefundable       Error
+		TicketNotRefundable      Error
+		CancellationClosed       Error
+		EventNotOnSale           Error
+		InvalidEventTransition   Error
+		DateHasSoldTickets       Error
+		DateNotReschedulable     Error
+		RescheduleDateTaken      Error
+		RescheduleNotPending     Error
+		RescheduleExpired        Error
 	}{
+		DateHasSoldTickets: Error{
+			Code:    "RESCHEDULE_ERROR_001",
+			Message: "Event date has sold tickets: reschedule it so holders can accept or get a refund",
+		},
+		DateNotReschedulable: Error{
+			Code:    "RESCHEDULE_ERROR_002",
+			Message: "Only active dates of published or postponed events without used tickets can be rescheduled",
+		},
+		RescheduleDateTaken: Error{
+			Code:    "RESCHEDULE_ERROR_003",
+			Message: "Event already has a date at that day and time",
+		},
+		RescheduleNotPending: Error{
+			Code:    "RESCHEDULE_ERROR_004",
+			Message: "Ticket has no pending reschedule to answer",
+		},
+		RescheduleExpired: Error{
+			Code:    "RESCHEDULE_ERROR_005",
+			Message: "The deadline to answer the reschedule has passed",
+		},
+		EventNotOnSale: Error{
+			Code:    "EVENTO_ERROR_005",
+			Message: "Event is not published or was cancelled",
+		},
+		InvalidEventTransition: Error{
+			Code:    "EVENTO_ERROR_006",
+			Message: "Event cannot move to the requested state",
+		},
+		OrderNotRefundable: Error{
+			Code:    "REFUND_ERROR_002",
+			Message: "Order is not confirmed or its payment was already refunded",
+		},
+		TicketNotRefundable: Error{
+			Code:    "REFUND_ERROR_003",
+			Message: "Only sold tickets of the order that never went through resale can be refunded",
+		},
+		CancellationClosed: Error{
+			Code:    "REFUND_ERROR_004",
+			Message: "Tickets can no longer be cancelled: their event date has started or is about to start",
+		},
+		InsufficientStock: Error{
+			Code:    "ORDEN_ERROR_002",
+			Message: "Not enough tickets available in the selected sector",
+		},
+		TicketsAlreadyIssued: Error{
+			Code:    "ORDEN_ERROR_003",
+			Message: "Tickets were already issued for this order",
+		},
+		OrderNotPayable: Error{
+			Code:    "ORDEN_ERROR_006",
+			Message: "Order is no longer pending payment",
+		},
+		IdempotencyKeyInUse: Error{
+			Code:    "IDEMPOTENCY_ERROR_003",
+			Message: "A request with this Idempotency-Key is still being processed",
+		},
+		TicketAlreadyUsed: Error{
+			Code:    "CHECKIN_ERROR_003",
+			Message: "Ticket was already used",
+		},
+		TicketCancelled: Error{
+			Code:    "CHECKIN_ERROR_004",
+			Message: "Ticket was cancelled",
+		},
+		TicketNotSold: Error{
+			Code:    "CHECKIN_ERROR_005",
+			Message: "Ticket is not valid for entry",
+		},
+		DoorsClosed: Error{
+			Code:    "CHECKIN_ERROR_008",
+			Message: "Doors are not open for this event date",
+		},
+		TransferAlreadyPending: Error{
+			Code:    "TRANSFER_ERROR_005",
+			Message: "Ticket already has a pending transfer",
+		},
+		TransferNotPending: Error{
+			Code:    "TRANSFER_ERROR_006",
+			Message: "Transfer was already accepted, cancelled or expired",
+		},
+		TransferExpired: Error{
+			Code:    "TRANSFER_ERROR_007",
+			Message: "Transfer offer has expired",
+		},
+		ResaleNotEnabled: Error{
+			Code:    "RESALE_ERROR_004",
+			Message: "The organizer has not enabled resale for this event",
+		},
+		TicketAlreadyListed: Error{
+			Code:    "RESALE_ERROR_005",
+			Message: "Ticket is already listed for resale",
+		},
+		ResaleNotAvailable: Error{
+			Code:    "RESALE_ERROR_006",
+			Message: "Resale listing is no longer available",
+		},
+		TicketNotTransferable: Error{
+			Code:    "TRANSFER_ERROR_008",
+			Message: "Only sold, unused tickets without a pending transfer or resale can change hands",
+		},
+		TicketNotDownloadable: Error{
+			Code:    "PASS_ERROR_001",
+			Message: "Only sold tickets can be downloaded",
+		},
+		WalletPassesDisabled: Error{
+			Code:    "PASS_ERROR_002",
+			Message: "Wallet passes are not available",
+		},
 		UserAlreadyExists: Error{
 			Code:    "USER_ERROR_006",
 			Message: "User already exists with this email",
@@ -520,9 +875,18 @@ func HandleError(err Error, c echo.Context) error {
 	case isInErrorGroup(err, BadRequestError):
 		statusCode = http.StatusBadRequest
 
+	case isInErrorGroup(err, AuthenticationError):
+		statusCode = http.StatusUnauthorized
+
+	case isInErrorGroup(err, ForbiddenError):
+		statusCode = http.StatusForbidden
+
 	case isInErrorGroup(err, ConflictError):
 		statusCode = http.StatusConflict
 
+	case isInErrorGroup(err, TooManyRequestsError):
+		statusCode = http.StatusTooManyRequests
+
 	case isInErrorGroup(err, InternalServerError):
 		statusCode = http.StatusInternalServerError
 
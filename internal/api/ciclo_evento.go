@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/schemas"
+	"github.com/labstack/echo/v4"
+)
+
+// POST /api/eventos/{id}/estado
+
+// @Summary      Cambiar el estado de un evento
+// @Description  Mueve el evento por su ciclo de vida: BORRADOR → PUBLICADO | CANCELADO; PUBLICADO → POSTERGADO | FINALIZADO | CANCELADO; POSTERGADO → PUBLICADO | CANCELADO. Al cancelar se cortan las ventas, se cancelan las órdenes pendientes de pago y las reventas publicadas, se reembolsa lo vendido (con el cargo por servicio) y se avisa a los asistentes. Solo el organizador del evento o un administrador.
+// @Tags         Evento
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID del evento"
+// @Param        request body schemas.CambiarEstadoEventoRequest true "Nuevo estado"
+// @Success      200 {object} schemas.EstadoEventoResponse "OK"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/eventos/{id}/estado [post]
+func (a *Api) CambiarEstadoEvento(c echo.Context) error {
+	eventoID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+	var req schemas.CambiarEstadoEventoRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Evento.CambiarEstadoEvento(eventoID, req, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// PUT /api/eventos/{id}/publicacion
+
+// @Summary      Programar la publicación de un evento
+// @Description  Fija la fecha y hora (RFC3339, futura) en que un evento en BORRADOR se publica solo. Con publicarEn null se quita la programación. Solo el organizador del evento o un administrador.
+// @Tags         Evento
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID del evento"
+// @Param        request body schemas.ProgramarPublicacionRequest true "Fecha de publicación"
+// @Success      200 {object} schemas.EstadoEventoResponse "OK"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/eventos/{id}/publicacion [put]
+func (a *Api) ProgramarPublicacionEvento(c echo.Context) error {
+	eventoID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+	var req schemas.ProgramarPublicacionRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Evento.ProgramarPublicacion(eventoID, req, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
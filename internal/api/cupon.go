@@ -19,6 +19,7 @@ import (
 // @Param               request body schemas.CuponResquest true "Create Cupon Request"
 // @Success 			201 {object} schemas.CuponResponse "Created"
 // @Failure 			400 {object} errors.Error "Bad Request"
+// @Failure 			403 {object} errors.Error "Forbidden"
 // @Failure 			404 {object} errors.Error "Not Found"
 // @Failure 			422 {object} errors.Error "Unprocessable Entity"
 // @Failure 			500 {object} errors.Error "Internal Server Error"
@@ -30,6 +31,9 @@ func (a *Api) CreateCupon(c echo.Context) error {
 	if err != nil || usuarioCreacionId <= 0 {
 		return errors.HandleError(errors.BadRequestError.InvalidUpdatedByValue, c)
 	}
+	if !esElMismoOAdmin(c, usuarioCreacionId) {
+		return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+	}
 
 	var request schemas.CuponResquest
 	result := c.Bind(&request)
@@ -38,7 +42,7 @@ func (a *Api) CreateCupon(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	response, newErr := a.BllController.Cupon.CreateCupon(request, usuarioCreacionId)
+	response, newErr := a.BllController.Cupon.CreateCupon(request, usuarioDesdeContexto(c))
 
 	if newErr != nil {
 		return errors.HandleError(*newErr, c)
@@ -56,6 +60,7 @@ func (a *Api) CreateCupon(c echo.Context) error {
 // @Param               request body schemas.CuponResquest true "Update Cupon Request"
 // @Success 			200 {object} schemas.CuponResponse "Updated"
 // @Failure 			400 {object} errors.Error "Bad Request"
+// @Failure 			403 {object} errors.Error "Forbidden"
 // @Failure 			404 {object} errors.Error "Not Found"
 // @Failure 			422 {object} errors.Error "Unprocessable Entity"
 // @Failure 			500 {object} errors.Error "Internal Server Error"
@@ -67,13 +72,16 @@ func (a *Api) UpdateCupon(c echo.Context) error {
 	if err != nil || usuarioModId <= 0 {
 		return errors.HandleError(errors.BadRequestError.InvalidUpdatedByValue, c)
 	}
+	if !esElMismoOAdmin(c, usuarioModId) {
+		return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+	}
 
 	var request schemas.CuponResquest
 	if err := c.Bind(&request); err != nil {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	response, newErr := a.BllController.Cupon.UpdateCupon(request, usuarioModId)
+	response, newErr := a.BllController.Cupon.UpdateCupon(request, usuarioDesdeContexto(c))
 	if newErr != nil {
 		return errors.HandleError(*newErr, c)
 	}
@@ -89,6 +97,7 @@ func (a *Api) UpdateCupon(c echo.Context) error {
 // @Param               organizadorId path int true "ID del organizador"
 // @Success 			200 {object} schemas.CuponesOrganizator "OK"
 // @Failure 			400 {object} errors.Error "Bad Request"
+// @Failure 			403 {object} errors.Error "Forbidden"
 // @Failure 			404 {object} errors.Error "Not Found"
 // @Failure 			500 {object} errors.Error "Internal Server Error"
 // @Router 				/cupon/organizador/{organizadorId} [get]
@@ -99,6 +108,9 @@ func (a *Api) FetchCuponPorOrganizador(c echo.Context) error {
 	if err != nil || organizadorId <= 0 {
 		return errors.HandleError(errors.BadRequestError.InvalidUpdatedByValue, c)
 	}
+	if !esElMismoOAdmin(c, organizadorId) {
+		return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+	}
 
 	response, newErr := a.BllController.Cupon.FetchCuponPorOrganizador(organizadorId)
 	if newErr != nil {
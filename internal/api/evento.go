@@ -41,10 +41,13 @@ func (a *Api) GetEvento(c echo.Context) error {
 }
 
 // @Summary 			Fetch Eventos.
-// @Description 		Fetches all available events.
+// @Description 		Fetches a page of the available events, ordered by date by default, without sectors or tarifas.
 // @Tags 				Evento
 // @Accept 				json
 // @Produce 			json
+// @Param               cursor      query  string  false  "siguiente_cursor de la página anterior"
+// @Param               limite      query  int     false  "Eventos por página (1-100, 20 por defecto)"
+// @Param               orden       query  string  false  "fecha | popularidad | precio | relevancia"
 // @Success 			200 {object} schemas.EventosPaginados "OK"
 // @Failure 			400 {object} errors.Error "Bad Request"
 // @Failure 			404 {object} errors.Error "Not Found"
@@ -52,7 +55,12 @@ func (a *Api) GetEvento(c echo.Context) error {
 // @Failure 			500 {object} errors.Error "Internal Server Error"
 // @Router 				/evento/ [get]
 func (a *Api) FetchEventos(c echo.Context) error {
-	response, err := a.BllController.Evento.FetchEventos()
+	pagina, perr := paginaEventosDesdeQuery(c)
+	if perr != nil {
+		return errors.HandleError(*perr, c)
+	}
+
+	response, err := a.BllController.Evento.FetchEventos(pagina)
 	if err != nil {
 		return errors.HandleError(*err, c)
 	}
@@ -61,11 +69,14 @@ func (a *Api) FetchEventos(c echo.Context) error {
 }
 
 // @Summary      Feed de eventos del usuario
-// @Description  Obtiene eventos activos para el feed, excluyendo los que ya tienen interacción del usuario
+// @Description  Obtiene una página de eventos activos para el feed, excluyendo los que ya tienen interacción del usuario. Por defecto se ordena por relevancia.
 // @Tags         Evento
 // @Accept       json
 // @Produce      json
 // @Param        usuarioId query int64 false "ID del usuario (opcional)"
+// @Param        cursor    query string false "siguiente_cursor de la página anterior"
+// @Param        limite    query int    false "Eventos por página (1-100, 20 por defecto)"
+// @Param        orden     query string false "fecha | popularidad | precio | relevancia"
 // @Success      200 {object} schemas.EventosPaginados "OK"
 // @Failure      400 {object} errors.Error "Bad Request"
 // @Failure      422 {object} errors.Error "Unprocessable Entity"
@@ -87,8 +98,13 @@ func (a *Api) FetchEventosFeed(c echo.Context) error {
 		usuarioId = &uid
 	}
 
+	pagina, perr := paginaEventosDesdeQuery(c)
+	if perr != nil {
+		return errors.HandleError(*perr, c)
+	}
+
 	// 3. Llamar a la lógica de negocio (tu función real)
-	resp, newErr := a.BllController.Evento.FetchEventosFeed(usuarioId)
+	resp, newErr := a.BllController.Evento.FetchEventosFeed(usuarioId, pagina)
 	if newErr != nil {
 		// 4. Si la capa BLL devuelve error → responderlo
 		return errors.HandleError(*newErr, c)
@@ -114,8 +130,13 @@ func (a *Api) FetchEventosConInteraccionesFeed(c echo.Context) error {
 		usuarioId = &uid
 	}
 
+	pagina, perr := paginaEventosDesdeQuery(c)
+	if perr != nil {
+		return errors.HandleError(*perr, c)
+	}
+
 	// 3. Llamar a la lógica de negocio (tu función real)
-	resp, newErr := a.BllController.Evento.FetchEventosConInteraccionesFeed(usuarioId)
+	resp, newErr := a.BllController.Evento.FetchEventosConInteraccionesFeed(usuarioId, pagina)
 	if newErr != nil {
 		// 4. Si la capa BLL devuelve error → responderlo
 		return errors.HandleError(*newErr, c)
@@ -126,7 +147,7 @@ func (a *Api) FetchEventosConInteraccionesFeed(c echo.Context) error {
 }
 
 // @Summary      Fetch Eventos filtrados.
-// @Description  Obtiene la lista de eventos disponibles aplicando filtros opcionales.
+// @Description  Obtiene una página de los eventos disponibles aplicando filtros opcionales, sin sectores ni tarifas. Por defecto se ordena por fecha. Con lat y lng solo quedan los eventos cuyo recinto está dentro del radio, cada uno con su distancia, y se puede ordenar por distancia.
 // @Tags         Evento
 // @Accept       json
 // @Produce      json
@@ -134,11 +155,18 @@ func (a *Api) FetchEventosConInteraccionesFeed(c echo.Context) error {
 // @Param        organizadorId query   int     false  "ID de organizador"
 // @Param        titulo        query   string  false  "Título del evento (coincidencia parcial)"
 // @Param        descripcion   query   string  false  "Descripción (coincidencia parcial)"
-// @Param        lugar         query   string  false  "Lugar del evento (coincidencia parcial)"
+// @Param        lugar         query   string  false  "Lugar del evento, nombre o distrito del recinto (coincidencia parcial)"
+// @Param        recintoId     query   int     false  "ID del recinto"
+// @Param        lat           query   number  false  "Latitud del punto de búsqueda (junto con lng)"
+// @Param        lng           query   number  false  "Longitud del punto de búsqueda (junto con lat)"
+// @Param        radioKm       query   number  false  "Radio en km alrededor del punto (hasta 500, 10 por defecto)"
 // @Param        fecha         query   string  false  "Fecha del evento (YYYY-MM-DD)"
 // @Param        horaInicio    query   string  false  "Hora de inicio (HH:MM)"
 // @Param        estado        query   string  false  "Estado del evento (BORRADOR|PUBLICADO|CANCELADO)"
 // @Param        soloFuturos   query   bool    false  "Si es true, solo eventos con fecha desde hoy"
+// @Param        cursor        query   string  false  "siguiente_cursor de la página anterior"
+// @Param        limite        query   int     false  "Eventos por página (1-100, 20 por defecto)"
+// @Param        orden         query   string  false  "fecha | popularidad | precio | relevancia | distancia (con lat y lng)"
 // @Success      200  {object}  schemas.EventosPaginados  "OK"
 // @Failure      400  {object}  errors.Error              "Bad Request"
 // @Failure      404  {object}  errors.Error              "Not Found"
@@ -181,6 +209,20 @@ func (a *Api) FetchEventosWithFilters(c echo.Context) error {
 		lugar = &l
 	}
 
+	var recintoID *int64
+	if recStr := c.QueryParam("recintoId"); recStr != "" {
+		parsed, err := strconv.ParseInt(recStr, 10, 64)
+		if err != nil {
+			return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+		}
+		recintoID = &parsed
+	}
+
+	cercania, cerr := cercaniaDesdeQuery(c)
+	if cerr != nil {
+		return errors.HandleError(*cerr, c)
+	}
+
 	var fecha *time.Time
 	if fStr := c.QueryParam("fecha"); fStr != "" {
 		parsed, err := time.Parse("2006-01-02", fStr)
@@ -223,16 +265,24 @@ func (a *Api) FetchEventosWithFilters(c echo.Context) error {
 		}
 	}
 
+	pagina, perr := paginaEventosDesdeQuery(c)
+	if perr != nil {
+		return errors.HandleError(*perr, c)
+	}
+
 	response, err := a.BllController.Evento.FetchEventosWithFilters(
 		categoriaID,
 		organizadorID,
 		titulo,
 		descripcion,
 		lugar,
+		recintoID,
+		cercania,
 		fecha,
 		horaInicio,
 		estado,
 		soloFuturos,
+		pagina,
 	)
 	if err != nil {
 		return errors.HandleError(*err, c)
@@ -241,6 +291,65 @@ func (a *Api) FetchEventosWithFilters(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+// GET /evento/buscar
+
+// @Summary      Buscar eventos
+// @Description  Busca en el título, el lugar y la descripción de los eventos a la venta, en español y sin importar tildes, tolerando errores de tipeo en el título. Devuelve los resultados por relevancia con el título y un fragmento resaltados (HTML escapado, coincidencias entre <mark>) y las facetas por categoría, fecha y precio; cada faceta cuenta con los demás filtros aplicados.
+// @Tags         Evento
+// @Produce      json
+// @Param        q             query   string  true   "Texto a buscar (hasta 200 caracteres)"
+// @Param        categoriaId   query   int     false  "ID de categoría"
+// @Param        fecha         query   string  false  "HOY | PROXIMOS_7_DIAS | PROXIMOS_30_DIAS | MAS_ADELANTE"
+// @Param        precio        query   string  false  "HASTA_50 | DE_50_A_100 | DE_100_A_200 | MAS_DE_200"
+// @Param        cursor        query   string  false  "siguiente_cursor de la página anterior"
+// @Param        limite        query   int     false  "Eventos por página (1-100, 20 por defecto)"
+// @Param        orden         query   string  false  "relevancia | fecha | popularidad | precio"
+// @Success      200  {object}  schemas.BusquedaEventosResponse  "OK"
+// @Failure      422  {object}  errors.Error                     "Unprocessable Entity"
+// @Failure      500  {object}  errors.Error                     "Internal Server Error"
+// @Router       /evento/buscar [get]
+func (a *Api) BuscarEventos(c echo.Context) error {
+	req := schemas.BusquedaEventosRequest{
+		Consulta: c.QueryParam("q"),
+		Fecha:    c.QueryParam("fecha"),
+		Precio:   c.QueryParam("precio"),
+	}
+	if catStr := c.QueryParam("categoriaId"); catStr != "" {
+		parsed, err := strconv.ParseInt(catStr, 10, 64)
+		if err != nil {
+			return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+		}
+		req.CategoriaID = &parsed
+	}
+	pagina, perr := paginaEventosDesdeQuery(c)
+	if perr != nil {
+		return errors.HandleError(*perr, c)
+	}
+	req.Pagina = pagina
+
+	response, err := a.BllController.Evento.BuscarEventos(req)
+	if err != nil {
+		return errors.HandleError(*err, c)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// paginaEventosDesdeQuery lee ?cursor=, ?limite= y ?orden= de los listados de eventos.
+func paginaEventosDesdeQuery(c echo.Context) (schemas.PaginaEventosRequest, *errors.Error) {
+	pagina := schemas.PaginaEventosRequest{
+		Cursor: c.QueryParam("cursor"),
+		Orden:  c.QueryParam("orden"),
+	}
+	if lStr := c.QueryParam("limite"); lStr != "" {
+		limite, err := strconv.Atoi(lStr)
+		if err != nil {
+			return pagina, &errors.UnprocessableEntityError.InvalidParsingInteger
+		}
+		pagina.Limite = limite
+	}
+	return pagina, nil
+}
+
 // @Summary 			Create Evento.
 // @Description 		Create a new event with all related entities.
 // @Tags 				Evento
@@ -249,21 +358,14 @@ func (a *Api) FetchEventosWithFilters(c echo.Context) error {
 // @Param               request body schemas.EventoRequest true "Create Evento Request"
 // @Success 			201 {object} schemas.EventoResponse "Created"
 // @Failure 			400 {object} errors.Error "Bad Request"
+// @Failure 			401 {object} errors.Error "Unauthorized"
+// @Failure 			403 {object} errors.Error "Forbidden"
 // @Failure 			404 {object} errors.Error "Not Found"
 // @Failure 			422 {object} errors.Error "Unprocessable Entity"
 // @Failure 			500 {object} errors.Error "Internal Server Error"
 // @Router 				/evento/ [post]
 func (a *Api) CreateEvento(c echo.Context) error {
-	// TODO: Add access token validation (from here we will get the `usuarioCreacion` param)
-	usuarioCreacion := int64(1) // Hardcoded for now///////////////////////
-
-	// hashToken := c.Request().Header.Get("Authorization")
-	// token, err := a.BllController.Token.ValidateToken(hashToken)
-	// if err != nil {
-	// 	return errors.HandleError(*err, c)
-	// }
-
-	// usuarioCreacion = token.UsuarioID
+	usuarioCreacion := usuarioDesdeContexto(c).ID
 
 	var request schemas.EventoRequest
 	if err := c.Bind(&request); err != nil {
@@ -292,18 +394,21 @@ func (a *Api) CreateEvento(c echo.Context) error {
 // @Param               fechaHasta   query string  false "Fecha hasta (YYYY-MM-DD)"
 // @Success             200 {array}  schemas.EventoReporte "OK"
 // @Failure             400 {object} errors.Error "Bad Request"
+// @Failure             403 {object} errors.Error "Forbidden"
 // @Failure             404 {object} errors.Error "Not Found"
 // @Failure             422 {object} errors.Error "Unprocessable Entity"
 // @Failure             500 {object} errors.Error "Internal Server Error"
 // @Router              /evento/reporte [get]
 func (a *Api) GetReporteEvento(c echo.Context) error {
-	// TODO: Validar access token
-
 	usuarioIDStr := c.Param("organizadorId")
 	usuarioID, parseErr := strconv.ParseInt(usuarioIDStr, 10, 64)
 	if parseErr != nil {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
 	}
+	// Cada organizador ve solo sus reportes; un administrador, los de cualquiera
+	if !esElMismoOAdmin(c, usuarioID) {
+		return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+	}
 
 	// --- Obtener query params ---
 	var (
@@ -364,6 +469,7 @@ func (a *Api) GetReporteEvento(c echo.Context) error {
 // @Param               fechaHasta    query string false "Fecha hasta (YYYY-MM-DD)"
 // @Success             200 {array}  schemas.EventoOrganizadorReporte "OK"
 // @Failure             400 {object} errors.Error "Bad Request"
+// @Failure             403 {object} errors.Error "Forbidden"
 // @Failure             404 {object} errors.Error "Not Found"
 // @Failure             422 {object} errors.Error "Unprocessable Entity"
 // @Failure             500 {object} errors.Error "Internal Server Error"
@@ -374,6 +480,9 @@ func (a *Api) GetReporteEventosOrganizador(c echo.Context) error { /////////////
 	if parseErr != nil || organizadorID <= 0 {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
 	}
+	if !esElMismoOAdmin(c, organizadorID) {
+		return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+	}
 
 	var fechaDesde *time.Time
 	if q := c.QueryParam("fechaDesde"); q != "" {
@@ -429,7 +538,7 @@ func (a *Api) EditarEventoFull(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	resp, errBll := a.BllController.Evento.EditarEventoFull(id, req)
+	resp, errBll := a.BllController.Evento.EditarEventoFull(id, req, usuarioDesdeContexto(c))
 	if errBll != nil {
 		return errors.HandleError(*errBll, c)
 	}
@@ -451,16 +560,13 @@ func (a *Api) EditarEvento(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	// 3) Forzar que el ID venga del path (por seguridad)
+	// 3) Forzar que el ID venga del path y el usuario de la sesión (por seguridad)
 	req.IdEvento = id
-
-	// (Opcional) si luego lees el usuario del token:
-	// if userID, ok := c.Get("userID").(int64); ok {
-	//     req.UsuarioModificacion = &userID
-	// }
+	usuario := usuarioDesdeContexto(c)
+	req.UsuarioModificacion = usuario.ID
 
 	// 4) Llamar al BO / controller
-	resp, errBll := a.BllController.Evento.EditarEvento(&req)
+	resp, errBll := a.BllController.Evento.EditarEvento(&req, usuario)
 	if errBll != nil {
 		return errors.HandleError(*errBll, c)
 	}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	contextKeyUsuario   = "usuario"
+	contextKeyAuthError = "auth_error"
+
+	headerIdempotencyKey     = "Idempotency-Key"
+	headerIdempotentReplayed = "Idempotent-Replayed"
+	largoMaximoClave         = 255
+	tamanoMaximoCuerpo       = 1 << 20 // 1MB
+)
+
+// Authenticate resuelve el bearer token del header Authorization al usuario dueño del token
+// y lo deja en el contexto. Si no hay token, o el token no es válido, el usuario queda como
+// model.AnonymousUser; las rutas protegidas deciden luego si eso es un 401.
+func (a *Api) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Add(echo.HeaderVary, echo.HeaderAuthorization)
+
+		plaintext := extraerBearerToken(c)
+		if plaintext == "" {
+			c.Set(contextKeyUsuario, model.AnonymousUser)
+			return next(c)
+		}
+
+		token, err := a.BllController.Token.ValidateToken(model.ScopeAuthentication, plaintext)
+		if err != nil {
+			if err.Code == errors.InternalServerError.Default.Code {
+				return errors.HandleError(*err, c)
+			}
+			c.Set(contextKeyUsuario, model.AnonymousUser)
+			c.Set(contextKeyAuthError, err)
+			return next(c)
+		}
+
+		usuario, err := a.BllController.Usuario.GetUsuarioAutenticado(token.UsuarioID)
+		if err != nil {
+			if err.Code == errors.InternalServerError.Default.Code {
+				return errors.HandleError(*err, c)
+			}
+			c.Set(contextKeyUsuario, model.AnonymousUser)
+			c.Set(contextKeyAuthError, err)
+			return next(c)
+		}
+
+		c.Set(contextKeyUsuario, usuario)
+		return next(c)
+	}
+}
+
+// RequireAuthenticated rechaza con 401 las peticiones hechas sin un token válido.
+func (a *Api) RequireAuthenticated(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if usuarioDesdeContexto(c).IsAnonymous() {
+			if authErr, ok := c.Get(contextKeyAuthError).(*errors.Error); ok && authErr != nil {
+				return errors.HandleError(*authErr, c)
+			}
+			return errors.HandleError(errors.AuthenticationError.UnauthorizedUser, c)
+		}
+		return next(c)
+	}
+}
+
+// RequireRoles exige un usuario autenticado con al menos uno de los roles indicados (403 si no los tiene).
+func (a *Api) RequireRoles(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return a.RequireAuthenticated(func(c echo.Context) error {
+			if !usuarioDesdeContexto(c).TieneAlgunRol(roles...) {
+				return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+			}
+			return next(c)
+		})
+	}
+}
+
+// esElMismoOAdmin indica si el usuario de la sesión es usuarioID o un administrador; lo usan
+// las rutas que reciben el id de un usuario u organizador en la URL.
+func esElMismoOAdmin(c echo.Context, usuarioID int64) bool {
+	sesion := usuarioDesdeContexto(c)
+	return sesion.ID == usuarioID || sesion.TieneAlgunRol(model.RolAdministrador)
+}
+
+// Idempotente hace seguros los reintentos de las rutas que crean o cobran algo. Si la petición
+// trae el header Idempotency-Key, la primera respuesta se guarda por (usuario, clave) y los
+// reintentos con la misma petición la reciben otra vez sin volver a ejecutar el handler.
+// Las respuestas 5xx no se guardan para que el cliente pueda reintentar. Debe ir después de
+// RequireAuthenticated; sin el header la ruta se comporta como siempre.
+func (a *Api) Idempotente(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		clave := strings.TrimSpace(c.Request().Header.Get(headerIdempotencyKey))
+		if clave == "" {
+			return next(c)
+		}
+		if len(clave) > largoMaximoClave {
+			return errors.HandleError(errors.BadRequestError.InvalidIdempotencyKey, c)
+		}
+
+		cuerpo, err := io.ReadAll(io.LimitReader(c.Request().Body, tamanoMaximoCuerpo+1))
+		if err != nil || len(cuerpo) > tamanoMaximoCuerpo {
+			return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+		}
+		c.Request().Body = io.NopCloser(bytes.NewReader(cuerpo))
+
+		usuario := usuarioDesdeContexto(c)
+		ruta := c.Request().Method + " " + c.Request().URL.Path
+		hash := sha256.Sum256(append([]byte(ruta+"\n"), cuerpo...))
+
+		registro, creada, errBll := a.BllController.Idempotencia.Reservar(usuario.ID, clave, ruta, hash[:])
+		if errBll != nil {
+			return errors.HandleError(*errBll, c)
+		}
+		if !creada {
+			c.Response().Header().Set(headerIdempotentReplayed, "true")
+			return c.Blob(*registro.CodigoRespuesta, registro.TipoContenido, registro.CuerpoRespuesta)
+		}
+
+		captura := &respuestaCapturada{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = captura
+
+		// Si el handler entra en pánico la reserva se libera y el pánico sigue su curso
+		defer func() {
+			if r := recover(); r != nil {
+				a.BllController.Idempotencia.Liberar(usuario.ID, clave)
+				panic(r)
+			}
+		}()
+
+		if err := next(c); err != nil || !c.Response().Committed || c.Response().Status >= http.StatusInternalServerError {
+			a.BllController.Idempotencia.Liberar(usuario.ID, clave)
+			return err
+		}
+
+		if errBll := a.BllController.Idempotencia.Completar(
+			usuario.ID,
+			clave,
+			c.Response().Status,
+			c.Response().Header().Get(echo.HeaderContentType),
+			captura.cuerpo.Bytes(),
+		); errBll != nil {
+			// La respuesta ya salió; la clave queda en curso hasta que venza su bloqueo
+			a.Logger.Errorf("Idempotente: no se guardó la respuesta de la clave %q del usuario %d: %s", clave, usuario.ID, errBll.Message)
+		}
+		return nil
+	}
+}
+
+// respuestaCapturada copia lo que el handler escribe para guardarlo junto a la clave de idempotencia.
+type respuestaCapturada struct {
+	http.ResponseWriter
+	cuerpo bytes.Buffer
+}
+
+func (w *respuestaCapturada) Write(b []byte) (int, error) {
+	w.cuerpo.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// usuarioDesdeContexto devuelve el usuario resuelto por Authenticate (model.AnonymousUser si no hay sesión).
+func usuarioDesdeContexto(c echo.Context) *model.Usuario {
+	usuario, ok := c.Get(contextKeyUsuario).(*model.Usuario)
+	if !ok || usuario == nil {
+		return model.AnonymousUser
+	}
+	return usuario
+}
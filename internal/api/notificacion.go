@@ -0,0 +1,304 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerLastEventID = "Last-Event-ID"
+	// intervaloStreamNotificaciones es cada cuánto el stream revisa la base y manda un latido.
+	intervaloStreamNotificaciones = 25 * time.Second
+)
+
+// GET /api/admin/notificaciones/fallidas
+
+// @Summary      Listar notificaciones en dead letter
+// @Description  Notificaciones que agotaron sus reintentos (NO_ENVIADO) y eventos de dominio que no se pudieron convertir en notificaciones (FALLIDO), con el último error de cada uno.
+// @Tags         Notificacion
+// @Produce      json
+// @Success      200 {object} schemas.NotificacionesFallidasResponse "Dead letters"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/admin/notificaciones/fallidas [get]
+func (a *Api) ListarNotificacionesFallidas(c echo.Context) error {
+	resp, ferr := a.BllController.Notificacion.ListarFallidas()
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /api/admin/notificaciones/{id}/reintentar
+
+// @Summary      Reintentar una notificación en dead letter
+// @Description  Devuelve la notificación NO_ENVIADO a la cola con los intentos en cero.
+// @Tags         Notificacion
+// @Param        id path int true "ID de la notificación"
+// @Success      202 "Notificación encolada"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/admin/notificaciones/{id}/reintentar [post]
+func (a *Api) ReintentarNotificacion(c echo.Context) error {
+	id, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	if ferr := a.BllController.Notificacion.ReintentarNotificacion(id); ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// POST /api/admin/outbox/{id}/reintentar
+
+// @Summary      Reintentar un evento de dominio fallido
+// @Description  Devuelve el evento FALLIDO al outbox para que el despachador vuelva a generar sus notificaciones.
+// @Tags         Notificacion
+// @Param        id path int true "ID del evento de dominio"
+// @Success      202 "Evento encolado"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/admin/outbox/{id}/reintentar [post]
+func (a *Api) ReintentarEventoDominio(c echo.Context) error {
+	id, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	if ferr := a.BllController.Notificacion.ReintentarEventoDominio(id); ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// GET /member/notificaciones
+
+// @Summary      Bandeja de notificaciones
+// @Description  Notificaciones in-app del usuario, de la más reciente a la más antigua. Para la página siguiente se envía como cursor el siguienteCursor de la respuesta anterior; es null en la última página.
+// @Tags         Notificacion
+// @Produce      json
+// @Param        cursor   query int  false "siguienteCursor de la página anterior"
+// @Param        limite   query int  false "Tamaño de página (por defecto 20, máximo 100)"
+// @Param        noLeidas query bool false "Solo las no leídas"
+// @Success      200 {object} schemas.BandejaNotificacionesResponse "OK"
+// @Failure      401 {object} errors.Error "Unauthorized"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/notificaciones [get]
+func (a *Api) ListarBandejaNotificaciones(c echo.Context) error {
+	var cursor int64
+	if v := c.QueryParam("cursor"); v != "" {
+		parsed, parseErr := strconv.ParseInt(v, 10, 64)
+		if parseErr != nil {
+			return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+		}
+		cursor = parsed
+	}
+	var limite int
+	if v := c.QueryParam("limite"); v != "" {
+		parsed, parseErr := strconv.Atoi(v)
+		if parseErr != nil {
+			return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+		}
+		limite = parsed
+	}
+	soloNoLeidas, _ := strconv.ParseBool(c.QueryParam("noLeidas"))
+
+	resp, ferr := a.BllController.Notificacion.ListarBandeja(usuarioDesdeContexto(c), cursor, soloNoLeidas, limite)
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET /member/notificaciones/no-leidas
+
+// @Summary      Contar notificaciones no leídas
+// @Tags         Notificacion
+// @Produce      json
+// @Success      200 {object} schemas.NotificacionesNoLeidasResponse "OK"
+// @Failure      401 {object} errors.Error "Unauthorized"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/notificaciones/no-leidas [get]
+func (a *Api) ContarNotificacionesNoLeidas(c echo.Context) error {
+	resp, ferr := a.BllController.Notificacion.ContarNoLeidas(usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /member/notificaciones/{id}/leer
+
+// @Summary      Marcar una notificación como leída
+// @Tags         Notificacion
+// @Param        id path int true "ID de la notificación"
+// @Success      204 "Notificación leída"
+// @Failure      401 {object} errors.Error "Unauthorized"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/notificaciones/{id}/leer [post]
+func (a *Api) MarcarNotificacionLeida(c echo.Context) error {
+	id, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	if ferr := a.BllController.Notificacion.MarcarLeida(id, usuarioDesdeContexto(c)); ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// POST /member/notificaciones/leer-todas
+
+// @Summary      Marcar toda la bandeja como leída
+// @Tags         Notificacion
+// @Produce      json
+// @Success      200 {object} schemas.NotificacionesMarcadasResponse "Cantidad de notificaciones marcadas"
+// @Failure      401 {object} errors.Error "Unauthorized"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/notificaciones/leer-todas [post]
+func (a *Api) MarcarTodasNotificacionesLeidas(c echo.Context) error {
+	resp, ferr := a.BllController.Notificacion.MarcarTodasLeidas(usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET /member/notificaciones/preferencias
+
+// @Summary      Preferencias de notificación
+// @Description  Para cada tipo de notificación y canal (EMAIL, IN_APP), si el usuario lo recibe. Las combinaciones obligatorias no se pueden deshabilitar.
+// @Tags         Notificacion
+// @Produce      json
+// @Success      200 {object} schemas.PreferenciasNotificacionResponse "OK"
+// @Failure      401 {object} errors.Error "Unauthorized"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/notificaciones/preferencias [get]
+func (a *Api) GetPreferenciasNotificacion(c echo.Context) error {
+	resp, ferr := a.BllController.Notificacion.ListarPreferencias(usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// PUT /member/notificaciones/preferencias
+
+// @Summary      Cambiar preferencias de notificación
+// @Description  Habilita o deshabilita las combinaciones de tipo y canal enviadas; las demás no cambian.
+// @Tags         Notificacion
+// @Accept       json
+// @Produce      json
+// @Param        request body schemas.PreferenciasNotificacionRequest true "Preferencias a cambiar"
+// @Success      200 {object} schemas.PreferenciasNotificacionResponse "Preferencias resultantes"
+// @Failure      401 {object} errors.Error "Unauthorized"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/notificaciones/preferencias [put]
+func (a *Api) GuardarPreferenciasNotificacion(c echo.Context) error {
+	var req schemas.PreferenciasNotificacionRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Notificacion.GuardarPreferencias(usuarioDesdeContexto(c), req)
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET /member/notificaciones/stream
+
+// @Summary      Stream de notificaciones (SSE)
+// @Description  Server-Sent Events con las notificaciones in-app nuevas del usuario: un evento "notificacion" por cada una, con su id como id del evento y la notificación en JSON como data. Al reconectar con el header Last-Event-ID se reenvían las posteriores a ese id. Cada 25 segundos se envía un comentario de latido.
+// @Tags         Notificacion
+// @Produce      text/event-stream
+// @Param        Last-Event-ID header int false "Último id recibido"
+// @Success      200 {object} schemas.NotificacionBandeja "Stream de eventos"
+// @Failure      401 {object} errors.Error "Unauthorized"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/notificaciones/stream [get]
+func (a *Api) StreamNotificaciones(c echo.Context) error {
+	var ultimoID *int64
+	if v := c.Request().Header.Get(headerLastEventID); v != "" {
+		parsed, parseErr := strconv.ParseInt(v, 10, 64)
+		if parseErr != nil {
+			return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+		}
+		ultimoID = &parsed
+	}
+
+	res := c.Response()
+	// Los headers se escriben recién con el primer envío, para poder responder un error antes
+	abrir := func() {
+		if res.Committed {
+			return
+		}
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set(echo.HeaderCacheControl, "no-cache")
+		res.Header().Set(echo.HeaderConnection, "keep-alive")
+		res.Header().Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+	}
+	enviar := func(n schemas.NotificacionBandeja) error {
+		abrir()
+		datos, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(res, "id: %d\nevent: notificacion\ndata: %s\n\n", n.IdNotificacion, datos); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	}
+	latido := func() error {
+		abrir()
+		if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	}
+
+	ferr := a.BllController.Notificacion.TransmitirBandeja(
+		c.Request().Context(),
+		usuarioDesdeContexto(c),
+		ultimoID,
+		intervaloStreamNotificaciones,
+		enviar,
+		latido,
+	)
+	if ferr != nil && !res.Committed {
+		return errors.HandleError(*ferr, c)
+	}
+	return nil
+}
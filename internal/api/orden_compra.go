@@ -1,6 +1,7 @@
 package api
 
 import (
+	"io"
 	"net/http"
 	"strconv"
 
@@ -14,11 +15,12 @@ import (
 // -----------------------------------------------------------------------------
 
 // @Summary      Crear sesión de compra temporal
-// @Description  Crea una orden en estado TEMPORAL con expiración (hold).
+// @Description  Crea una orden en estado TEMPORAL con expiración (hold). El precio se calcula en el backend; si "total" no coincide se responde 422.
 // @Tags         Orden
 // @Accept       json
 // @Produce      json
 // @Param        request body schemas.CrearOrdenTemporalRequest true "Datos de la reserva"
+// @Param        Idempotency-Key header string false "Clave para reintentar sin duplicar la operación"
 // @Success      201 {object} schemas.CrearOrdenTemporalResponse "Created"
 // @Failure      400 {object} errors.Error "Bad Request"
 // @Failure      409 {object} errors.Error "Conflict"
@@ -31,9 +33,8 @@ func (a *Api) CrearSesionOrdenTemporal(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	// En producción, podrías sobreescribir req.IdUsuario con el user del token:
-	// userID := c.Get("userID").(int64)
-	// req.IdUsuario = userID
+	// La orden (y el cupón, si viene) siempre se asocia al usuario del token
+	req.IdUsuario = usuarioDesdeContexto(c).ID
 
 	resp, errBll := a.BllController.Orden.CrearSesionOrdenTemporal(req)
 	if errBll != nil {
@@ -53,6 +54,7 @@ func (a *Api) CrearSesionOrdenTemporal(c echo.Context) error {
 // @Produce      json
 // @Param        orderId path int true "ID de la orden"
 // @Success      200 {object} schemas.ObtenerHoldResponse "OK"
+// @Failure      403 {object} errors.Error "Forbidden"
 // @Failure      404 {object} errors.Error "Not Found"
 // @Failure      410 {object} errors.Error "Gone"
 // @Failure      422 {object} errors.Error "Unprocessable Entity"
@@ -65,7 +67,7 @@ func (a *Api) ObtenerEstadoHold(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
 	}
 
-	resp, errBll := a.BllController.Orden.ObtenerEstadoHold(orderID)
+	resp, errBll := a.BllController.Orden.ObtenerEstadoHold(orderID, usuarioDesdeContexto(c).ID)
 	if errBll != nil {
 		return errors.HandleError(*errBll, c)
 	}
@@ -77,12 +79,13 @@ func (a *Api) ObtenerEstadoHold(c echo.Context) error {
 // -----------------------------------------------------------------------------
 
 // @Summary      Confirmar orden de compra
-// @Description  Verifica el pago y actualiza la orden a estado CONFIRMADA.
+// @Description  Captura el pago en la pasarela y, si se completa, actualiza la orden a estado CONFIRMADA.
 // @Tags         Orden
 // @Accept       json
 // @Produce      json
 // @Param        orderId path int true "ID de la orden"
 // @Param        request body schemas.ConfirmarOrdenRequest true "Datos de pago"
+// @Param        Idempotency-Key header string false "Clave para reintentar sin duplicar la operación"
 // @Success      200 {object} schemas.ConfirmarOrdenResponse "OK"
 // @Failure      400 {object} errors.Error "Bad Request"
 // @Failure      402 {object} errors.Error "Payment Required"
@@ -103,9 +106,74 @@ func (a *Api) ConfirmarOrden(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	resp, errBll := a.BllController.Orden.ConfirmarOrden(orderID, req)
+	resp, errBll := a.BllController.Orden.ConfirmarOrden(orderID, usuarioDesdeContexto(c).ID, req)
 	if errBll != nil {
 		return errors.HandleError(*errBll, c)
 	}
 	return c.JSON(http.StatusOK, resp)
 }
+
+// -----------------------------------------------------------------------------
+// POST /api/orders/{orderId}/pago
+// -----------------------------------------------------------------------------
+
+// @Summary      Crear intento de pago
+// @Description  Abre el cobro de una orden TEMPORAL en la pasarela del método elegido (Tarjeta o Yape).
+// @Tags         Orden
+// @Accept       json
+// @Produce      json
+// @Param        orderId path int true "ID de la orden"
+// @Param        request body schemas.CrearIntentoPagoRequest true "Método de pago"
+// @Param        Idempotency-Key header string false "Clave para reintentar sin duplicar la operación"
+// @Success      201 {object} schemas.CrearIntentoPagoResponse "Created"
+// @Failure      400 {object} errors.Error "Bad Request"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/orders/{orderId}/pago [post]
+func (a *Api) CrearIntentoPago(c echo.Context) error {
+	orderID, parseErr := strconv.ParseInt(c.Param("orderId"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	var req schemas.CrearIntentoPagoRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, errBll := a.BllController.Orden.CrearIntentoPago(orderID, usuarioDesdeContexto(c).ID, req)
+	if errBll != nil {
+		return errors.HandleError(*errBll, c)
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// -----------------------------------------------------------------------------
+// POST /pagos/webhook/{metodo}
+// -----------------------------------------------------------------------------
+
+// @Summary      Webhook de la pasarela de pagos
+// @Description  Recibe notificaciones firmadas de la pasarela; confirma la orden cuando el pago queda capturado.
+// @Tags         Orden
+// @Accept       json
+// @Produce      json
+// @Param        metodo path string true "Método de pago (Tarjeta | Yape)"
+// @Success      200 {object} map[string]bool "OK"
+// @Failure      401 {object} errors.Error "Invalid signature"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /pagos/webhook/{metodo} [post]
+func (a *Api) WebhookPago(c echo.Context) error {
+	// La firma se calcula sobre el cuerpo crudo, así que no se usa Bind
+	payload, err := io.ReadAll(io.LimitReader(c.Request().Body, 1<<20))
+	if err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	if errBll := a.BllController.Orden.ProcesarWebhookPago(c.Param("metodo"), payload, c.Request().Header); errBll != nil {
+		return errors.HandleError(*errBll, c)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"recibido": true})
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	tipoPDF      = "application/pdf"
+	tipoPkpass   = "application/vnd.apple.pkpass"
+	tipoPkpasses = "application/vnd.apple.pkpasses"
+)
+
+// descargar responde con un archivo adjunto.
+func descargar(c echo.Context, tipo string, nombre string, datos []byte) error {
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", nombre))
+	return c.Blob(http.StatusOK, tipo, datos)
+}
+
+// GET /member/tickets/{id}/pdf
+
+// @Summary      Descargar un ticket en PDF
+// @Description  PDF imprimible con el evento, lugar, fecha y hora, sector, perfil y el QR del ticket. Solo para el titular de un ticket VENDIDO.
+// @Tags         Ticket
+// @Produce      application/pdf
+// @Param        id path int true "ID del ticket"
+// @Success      200 {file} file "PDF del ticket"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/tickets/{id}/pdf [get]
+func (a *Api) DescargarTicketPDF(c echo.Context) error {
+	ticketID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	pdf, ferr := a.BllController.Ticket.PDFTicket(ticketID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return descargar(c, tipoPDF, fmt.Sprintf("ticket-%d.pdf", ticketID), pdf)
+}
+
+// GET /member/tickets/{id}/pkpass
+
+// @Summary      Descargar el pase de billetera de un ticket
+// @Description  Pase .pkpass firmado con el certificado de la plataforma para agregar el ticket a la billetera del teléfono.
+// @Tags         Ticket
+// @Produce      application/vnd.apple.pkpass
+// @Param        id path int true "ID del ticket"
+// @Success      200 {file} file "Pase del ticket"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/tickets/{id}/pkpass [get]
+func (a *Api) DescargarTicketPkpass(c echo.Context) error {
+	ticketID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	pkpass, ferr := a.BllController.Ticket.PkpassTicket(ticketID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return descargar(c, tipoPkpass, fmt.Sprintf("ticket-%d.pkpass", ticketID), pkpass)
+}
+
+// GET /orden_de_compra/{orderId}/tickets/pdf
+
+// @Summary      Descargar los tickets de una orden en PDF
+// @Description  Un PDF con una página por ticket de la orden que el usuario conserva (no incluye los transferidos o revendidos).
+// @Tags         Ticket
+// @Produce      application/pdf
+// @Param        orderId path int true "ID de la orden"
+// @Success      200 {file} file "PDF de los tickets"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /orden_de_compra/{orderId}/tickets/pdf [get]
+func (a *Api) DescargarOrdenPDF(c echo.Context) error {
+	orderID, parseErr := strconv.ParseInt(c.Param("orderId"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	pdf, ferr := a.BllController.Ticket.PDFOrden(orderID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return descargar(c, tipoPDF, fmt.Sprintf("orden-%d.pdf", orderID), pdf)
+}
+
+// GET /orden_de_compra/{orderId}/tickets/pkpasses
+
+// @Summary      Descargar los pases de billetera de una orden
+// @Description  Paquete .pkpasses con un pase por ticket de la orden que el usuario conserva.
+// @Tags         Ticket
+// @Produce      application/vnd.apple.pkpasses
+// @Param        orderId path int true "ID de la orden"
+// @Success      200 {file} file "Pases de los tickets"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /orden_de_compra/{orderId}/tickets/pkpasses [get]
+func (a *Api) DescargarOrdenPkpasses(c echo.Context) error {
+	orderID, parseErr := strconv.ParseInt(c.Param("orderId"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	paquete, ferr := a.BllController.Ticket.PkpassesOrden(orderID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return descargar(c, tipoPkpasses, fmt.Sprintf("orden-%d.pkpasses", orderID), paquete)
+}
@@ -38,7 +38,7 @@ func (a *Api) CrearPerfilPersona(c echo.Context) error {
 	}
 	req.EventoID = eventoID
 
-	usuarioCreacion := int64(1) // luego lo cambias por el ID del token
+	usuarioCreacion := usuarioDesdeContexto(c).ID
 
 	resp, e := a.BllController.PerfilPersona.CrearPerfilPersona(req, usuarioCreacion)
 	if e != nil {
@@ -60,7 +60,7 @@ func (a *Api) ActualizarPerfilPersona(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	usuarioModificacion := int64(1)
+	usuarioModificacion := usuarioDesdeContexto(c).ID
 
 	resp, e := a.BllController.PerfilPersona.ActualizarPerfilPersona(perfilID, req, usuarioModificacion)
 	if e != nil {
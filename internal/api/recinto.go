@@ -0,0 +1,147 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/schemas"
+	"github.com/labstack/echo/v4"
+)
+
+// cercaniaDesdeQuery lee lat, lng y radioKm; la validación de rangos la hace el adapter.
+func cercaniaDesdeQuery(c echo.Context) (schemas.CercaniaRequest, *errors.Error) {
+	var req schemas.CercaniaRequest
+	var ok bool
+	if req.Latitud, ok = numeroDesdeQuery(c, "lat"); !ok {
+		return req, &errors.UnprocessableEntityError.InvalidGeoQuery
+	}
+	if req.Longitud, ok = numeroDesdeQuery(c, "lng"); !ok {
+		return req, &errors.UnprocessableEntityError.InvalidGeoQuery
+	}
+	if req.RadioKm, ok = numeroDesdeQuery(c, "radioKm"); !ok {
+		return req, &errors.UnprocessableEntityError.InvalidGeoQuery
+	}
+	return req, nil
+}
+
+// numeroDesdeQuery devuelve nil si el parámetro no vino y false si no es un número.
+func numeroDesdeQuery(c echo.Context, param string) (*float64, bool) {
+	texto := c.QueryParam(param)
+	if texto == "" {
+		return nil, true
+	}
+	valor, err := strconv.ParseFloat(texto, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &valor, true
+}
+
+// GET /recintos
+
+// @Summary      Listar recintos
+// @Description  Recintos activos, por nombre. Se filtran por ubigeo (2 dígitos: departamento, 4: provincia, 6: distrito) y, con lat y lng, por cercanía, del más cercano al más lejano y con su distancia.
+// @Tags         Recinto
+// @Produce      json
+// @Param        ubigeo   query   string  false  "Prefijo de ubigeo INEI (2, 4 o 6 dígitos)"
+// @Param        lat      query   number  false  "Latitud del punto de búsqueda (junto con lng)"
+// @Param        lng      query   number  false  "Longitud del punto de búsqueda (junto con lat)"
+// @Param        radioKm  query   number  false  "Radio en km alrededor del punto (hasta 500, 10 por defecto)"
+// @Success      200  {array}   schemas.RecintoResponse  "OK"
+// @Failure      422  {object}  errors.Error             "Unprocessable Entity"
+// @Failure      500  {object}  errors.Error             "Internal Server Error"
+// @Router       /recintos [get]
+func (a *Api) ListarRecintos(c echo.Context) error {
+	cercania, cerr := cercaniaDesdeQuery(c)
+	if cerr != nil {
+		return errors.HandleError(*cerr, c)
+	}
+
+	resp, ferr := a.BllController.Recinto.ListarRecintos(c.QueryParam("ubigeo"), cercania)
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET /recintos/{id}
+
+// @Summary      Obtener un recinto
+// @Tags         Recinto
+// @Produce      json
+// @Param        id path int true "ID del recinto"
+// @Success      200  {object}  schemas.RecintoResponse  "OK"
+// @Failure      404  {object}  errors.Error             "Not Found"
+// @Failure      422  {object}  errors.Error             "Unprocessable Entity"
+// @Router       /recintos/{id} [get]
+func (a *Api) GetRecinto(c echo.Context) error {
+	id, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Recinto.ObtenerRecinto(id)
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /api/recintos
+
+// @Summary      Crear un recinto
+// @Description  Registra un local con su dirección, ubigeo y coordenadas para asignarlo a eventos (idRecinto al crear o editar el evento).
+// @Tags         Recinto
+// @Accept       json
+// @Produce      json
+// @Param        request body schemas.RecintoRequest true "Recinto"
+// @Success      201  {object}  schemas.RecintoResponse  "Created"
+// @Failure      422  {object}  errors.Error             "Unprocessable Entity"
+// @Failure      500  {object}  errors.Error             "Internal Server Error"
+// @Router       /api/recintos [post]
+func (a *Api) CrearRecinto(c echo.Context) error {
+	var req schemas.RecintoRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Recinto.CrearRecinto(req, usuarioDesdeContexto(c), time.Now())
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// PUT /api/recintos/{id}
+
+// @Summary      Editar un recinto
+// @Description  Reemplaza los datos del recinto. Solo quien lo creó o un administrador.
+// @Tags         Recinto
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID del recinto"
+// @Param        request body schemas.RecintoRequest true "Recinto"
+// @Success      200  {object}  schemas.RecintoResponse  "OK"
+// @Failure      403  {object}  errors.Error             "Forbidden"
+// @Failure      404  {object}  errors.Error             "Not Found"
+// @Failure      422  {object}  errors.Error             "Unprocessable Entity"
+// @Failure      500  {object}  errors.Error             "Internal Server Error"
+// @Router       /api/recintos/{id} [put]
+func (a *Api) ActualizarRecinto(c echo.Context) error {
+	id, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+	var req schemas.RecintoRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Recinto.ActualizarRecinto(id, req, usuarioDesdeContexto(c), time.Now())
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"github.com/labstack/echo/v4"
+)
+
+// GET /orden_de_compra/{orderId}/reembolsos
+
+// @Summary      Listar reembolsos de una orden
+// @Description  Reembolsos de la orden, del más reciente al más antiguo, con sus tickets y la nota de crédito emitida. Los ve el comprador y los administradores.
+// @Tags         Orden
+// @Produce      json
+// @Param        orderId path int true "ID de la orden"
+// @Success      200 {object} schemas.ReembolsosResponse "OK"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /orden_de_compra/{orderId}/reembolsos [get]
+func (a *Api) ListarReembolsosDeOrden(c echo.Context) error {
+	orderID, parseErr := strconv.ParseInt(c.Param("orderId"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Orden.ListarReembolsosDeOrden(orderID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /api/admin/ordenes/{orderId}/reembolsos
+
+// @Summary      Reembolsar tickets de una orden
+// @Description  Cancela y reembolsa los tickets indicados de una orden CONFIRMADA (o todos los reembolsables si no se indica ninguno). Se devuelve lo pagado por cada ticket con el descuento del cupón ya aplicado; la parte del fee de servicio solo si reembolsarFee es true. Si la pasarela rechaza la devolución el reembolso queda FALLIDO.
+// @Tags         Orden
+// @Accept       json
+// @Produce      json
+// @Param        orderId path int true "ID de la orden"
+// @Param        request body schemas.ReembolsarOrdenRequest true "Tickets a reembolsar"
+// @Param        Idempotency-Key header string false "Clave para reintentar sin duplicar la operación"
+// @Success      201 {object} schemas.Reembolso "Created"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/admin/ordenes/{orderId}/reembolsos [post]
+func (a *Api) ReembolsarOrden(c echo.Context) error {
+	orderID, parseErr := strconv.ParseInt(c.Param("orderId"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	var req schemas.ReembolsarOrdenRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Orden.ReembolsarOrden(orderID, req, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// GET /api/admin/reembolsos/fallidos
+
+// @Summary      Listar reembolsos fallidos
+// @Description  Reembolsos que la pasarela rechazó, con el último error. Sus tickets ya están cancelados y el dinero se le debe al comprador.
+// @Tags         Orden
+// @Produce      json
+// @Success      200 {object} schemas.ReembolsosResponse "OK"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/admin/reembolsos/fallidos [get]
+func (a *Api) ListarReembolsosFallidos(c echo.Context) error {
+	resp, ferr := a.BllController.Orden.ListarReembolsosFallidos()
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /api/admin/reembolsos/{id}/reintentar
+
+// @Summary      Reintentar un reembolso fallido
+// @Description  Vuelve a pedir a la pasarela la devolución de un reembolso FALLIDO y devuelve cómo quedó.
+// @Tags         Orden
+// @Produce      json
+// @Param        id path int true "ID del reembolso"
+// @Success      200 {object} schemas.Reembolso "OK"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/admin/reembolsos/{id}/reintentar [post]
+func (a *Api) ReintentarReembolso(c echo.Context) error {
+	id, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Orden.ReintentarReembolso(id)
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
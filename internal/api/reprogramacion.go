@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"github.com/labstack/echo/v4"
+)
+
+// POST /api/eventos/{id}/fechas/{idFechaEvento}/reprogramacion
+
+// @Summary      Reprogramar una fecha del evento
+// @Description  Mueve una fecha con entradas vendidas a otro día y hora. La fecha original queda inactiva, se cancelan sus órdenes pendientes de pago y reventas publicadas, los tickets vendidos pasan a la nueva fecha con un QR nuevo y sus titulares reciben un correo con el plazo (fechaLimite, antes del nuevo inicio) para pedir el reembolso. Quien no responde se queda con la nueva fecha. Solo el organizador del evento o un administrador.
+// @Tags         Evento
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID del evento"
+// @Param        idFechaEvento path int true "ID de la fecha del evento"
+// @Param        request body schemas.ReprogramarFechaRequest true "Nueva fecha y plazo de respuesta"
+// @Success      201 {object} schemas.ReprogramacionResponse "Created"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/eventos/{id}/fechas/{idFechaEvento}/reprogramacion [post]
+func (a *Api) ReprogramarFecha(c echo.Context) error {
+	eventoID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+	eventoFechaID, parseErr := strconv.ParseInt(c.Param("idFechaEvento"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+	var req schemas.ReprogramarFechaRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Evento.ReprogramarFecha(eventoID, eventoFechaID, req, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// POST /api/tickets/{ticketId}/reprogramacion
+
+// @Summary      Responder a la reprogramación de un ticket
+// @Description  El titular de un ticket cuya fecha se reprogramó acepta la nueva fecha (ACEPTAR) o pide el reembolso (REEMBOLSAR) antes del plazo. El reembolso cancela el ticket y devuelve lo pagado con el fee de servicio incluido; en un ticket de reventa se devuelve al último comprador.
+// @Tags         Ticket
+// @Accept       json
+// @Produce      json
+// @Param        ticketId path int true "ID del ticket"
+// @Param        request body schemas.ResponderReprogramacionRequest true "Respuesta del titular"
+// @Param        Idempotency-Key header string false "Clave para reintentar sin duplicar la operación"
+// @Success      200 {object} schemas.RespuestaReprogramacionResponse "OK"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/tickets/{ticketId}/reprogramacion [post]
+func (a *Api) ResponderReprogramacion(c echo.Context) error {
+	ticketID, parseErr := strconv.ParseInt(c.Param("ticketId"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+	var req schemas.ResponderReprogramacionRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Orden.ResponderReprogramacion(ticketID, req, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/schemas"
+	"github.com/labstack/echo/v4"
+)
+
+// PUT /api/eventos/{id}/reventa/politica
+
+// @Summary      Configurar la reventa de un evento
+// @Description  El organizador habilita la reventa, fija el precio máximo (en % del precio original de la tarifa) y su regalía por venta.
+// @Tags         Reventa
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID del evento"
+// @Param        request body schemas.PoliticaReventaRequest true "Política de reventa"
+// @Success      200 {object} schemas.PoliticaReventaResponse "Política guardada"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/eventos/{id}/reventa/politica [put]
+func (a *Api) GuardarPoliticaReventa(c echo.Context) error {
+	eventoID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+	var req schemas.PoliticaReventaRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Orden.GuardarPoliticaReventa(eventoID, req, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET /api/eventos/{id}/reventa/politica
+
+// @Summary      Política de reventa de un evento
+// @Description  Si el organizador no la configuró, la reventa figura deshabilitada.
+// @Tags         Reventa
+// @Produce      json
+// @Param        id path int true "ID del evento"
+// @Success      200 {object} schemas.PoliticaReventaResponse "OK"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/eventos/{id}/reventa/politica [get]
+func (a *Api) GetPoliticaReventa(c echo.Context) error {
+	eventoID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Orden.ObtenerPoliticaReventa(eventoID)
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET /api/eventos/{id}/reventa/resumen
+
+// @Summary      Resumen de reventas de un evento
+// @Description  Publicaciones activas, reventas concretadas y su reparto entre plataforma, organizador y vendedores.
+// @Tags         Reventa
+// @Produce      json
+// @Param        id path int true "ID del evento"
+// @Success      200 {object} schemas.ResumenReventaResponse "OK"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/eventos/{id}/reventa/resumen [get]
+func (a *Api) GetResumenReventa(c echo.Context) error {
+	eventoID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Orden.ResumenReventa(eventoID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /member/tickets/{id}/reventa
+
+// @Summary      Publicar un ticket en reventa
+// @Description  El titular pone a la venta un ticket VENDIDO. El precio no puede superar el tope que fijó el organizador.
+// @Tags         Reventa
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID del ticket"
+// @Param        request body schemas.PublicarReventaRequest true "Precio de reventa"
+// @Success      201 {object} schemas.PublicacionReventa "Publicación creada"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/tickets/{id}/reventa [post]
+func (a *Api) PublicarReventa(c echo.Context) error {
+	ticketID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+	var req schemas.PublicarReventaRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Orden.PublicarReventa(ticketID, req, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// GET /member/reventa
+
+// @Summary      Mis publicaciones de reventa
+// @Description  Publicaciones del usuario con el reparto de cada venta
+// @Tags         Reventa
+// @Produce      json
+// @Success      200 {object} schemas.PublicacionesReventaResponse "OK"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/reventa [get]
+func (a *Api) ListarMisReventas(c echo.Context) error {
+	resp, ferr := a.BllController.Orden.ListarMisReventas(usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET /evento/{eventoId}/reventa
+
+// @Summary      Reventas disponibles de un evento
+// @Tags         Reventa
+// @Produce      json
+// @Param        eventoId path int true "ID del evento"
+// @Success      200 {object} schemas.PublicacionesReventaResponse "OK"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /evento/{eventoId}/reventa [get]
+func (a *Api) ListarReventasDeEvento(c echo.Context) error {
+	eventoID, parseErr := strconv.ParseInt(c.Param("eventoId"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Orden.ListarReventasDeEvento(eventoID)
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /reventa/{id}/cancelar
+
+// @Summary      Retirar una publicación de reventa
+// @Description  El vendedor retira su publicación mientras ningún comprador la tenga reservada.
+// @Tags         Reventa
+// @Produce      json
+// @Param        id path int true "ID de la publicación"
+// @Success      200 {object} schemas.PublicacionReventa "Publicación cancelada"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /reventa/{id}/cancelar [post]
+func (a *Api) CancelarReventa(c echo.Context) error {
+	publicacionID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Orden.CancelarReventa(publicacionID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /reventa/{id}/hold
+
+// @Summary      Reservar un ticket en reventa
+// @Description  Crea una orden TEMPORAL por el precio de reventa que se paga y confirma como cualquier otra. Al confirmarse, el ticket pasa al comprador con un QR nuevo.
+// @Tags         Reventa
+// @Produce      json
+// @Param        id path int true "ID de la publicación"
+// @Param        Idempotency-Key header string false "Clave para reintentar sin duplicar la operación"
+// @Success      201 {object} schemas.CrearOrdenTemporalResponse "Hold creado"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /reventa/{id}/hold [post]
+func (a *Api) ReservarReventa(c echo.Context) error {
+	publicacionID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Orden.ReservarReventa(publicacionID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
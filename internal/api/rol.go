@@ -40,6 +40,9 @@ func (a *Api) GetRolPorUsuario(c echo.Context) error {
 	if parseErr != nil {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
 	}
+	if !esElMismoOAdmin(c, usuarioId) {
+		return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+	}
 
 	response, err := a.BllController.Rol.GetRolPorUsuario(usuarioId)
 	if err != nil {
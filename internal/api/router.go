@@ -1,11 +1,15 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	config "github.com/Nexivent/nexivent-backend/internal/config"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
@@ -45,12 +49,13 @@ func (a *Api) RegisterRoutes(configEnv *config.ConfigEnv) {
 	corsConfig := middleware.CORSConfig{
 		AllowOrigins:     allowOrigins,
 		AllowCredentials: true,
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "X-Requested-With", "X-CSRF-Token"},
+		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "X-Requested-With", "X-CSRF-Token", headerIdempotencyKey, headerLastEventID},
 		AllowMethods:     []string{echo.GET, echo.POST, echo.PUT, echo.DELETE, echo.OPTIONS},
 		ExposeHeaders: []string{
 			"Content-Length",
 			"Content-Type",
 			"Authorization",
+			headerIdempotentReplayed,
 		},
 		MaxAge: 86400,
 	}
@@ -61,6 +66,9 @@ func (a *Api) RegisterRoutes(configEnv *config.ConfigEnv) {
 		a.Echo.GET("/swagger/*", echoSwagger.EchoWrapHandler(echoSwagger.InstanceName("server")))
 	}
 
+	// Resuelve el usuario del bearer token (o AnonymousUser) para todas las rutas
+	a.Echo.Use(a.Authenticate)
+
 	// ===== PUBLIC ENDPOINTS =====
 	healthCheck := a.Echo.Group("/health-check")
 	healthCheck.GET("/", a.HealthCheck)
@@ -77,93 +85,178 @@ func (a *Api) RegisterRoutes(configEnv *config.ConfigEnv) {
 	a.Echo.POST("/login", a.AuthenticateUsuario)
 	a.Echo.POST("/loginorg", a.AuthenticateOrganizador)
 	a.Echo.POST("/logout", a.Logout)
+	a.Echo.POST("/password/forgot", a.ForgotPassword)
+	a.Echo.POST("/password/reset", a.ResetPassword)
 
-	a.Echo.GET("/usuario/:id", a.GetUsuario)
-	a.Echo.PATCH("/usuario/:id", a.DesactivarUsuario)
-	a.Echo.PATCH("/usuario/:id/password", a.ActualizarContrasenha)
-
-	// Eventos endpoints
+	// Eventos (catálogo público)
 	a.Echo.GET("/evento/", a.FetchEventos)
 	a.Echo.GET("/evento/:eventoId/", a.GetEvento)
-	a.Echo.POST("/evento/", a.CreateEvento) //falta usuario creacion
-	a.Echo.PUT("/api/eventos/:id/full", a.EditarEventoFull)
 	a.Echo.GET("/evento/filter", a.FetchEventosWithFilters)
-	a.Echo.GET("/evento/reporte/:organizadorId", a.GetReporteEvento)
-	a.Echo.GET("/organizador/:organizadorId/eventos/reporte", a.GetReporteEventosOrganizador)
-	a.Echo.GET("/api/events/:id/summary", a.GetEventoSummary)
-	a.Echo.GET("/eventos/:eventoId/asistentes", a.GetAsistentesPorEvento)
+	a.Echo.GET("/evento/buscar", a.BuscarEventos)
 	a.Echo.GET("/feed/eventos", a.FetchEventosFeed)
 	a.Echo.GET("/feed/eventos/con-interacciones", a.FetchEventosConInteraccionesFeed)
-	// Interacción Usuario ↔ Evento
-	a.Echo.POST("/evento/interaccion", a.PostInteraccionUsuarioEvento)
-	a.Echo.PUT("/evento/interaccion", a.PutInteraccionUsuarioEvento)
-
 	a.Echo.GET("/categorias/", a.FetchCategorias)
-	a.Echo.POST("/categoria/", a.CreateCategoria)
 	a.Echo.GET("/categoria/:categoriaId/", a.GetCategoria)
-	a.Echo.PUT("/api/eventos/:id", a.EditarEvento)
-	// 2. Reporte Administrativo Global (Dashboard BI)
-	a.Echo.POST("/api/admin/reports", a.GetAdminReports)
-	a.Echo.GET("/api/admin/transactions/:eventoId", a.GetAdminTransactionsByEvento)
+	a.Echo.GET("/evento/:eventoId/perfiles", a.ListarPerfilesPorEvento)
+	a.Echo.GET("/evento/:eventoId/sectores", a.ListarSectoresPorEvento)
+	a.Echo.GET("/evento/:eventoId/tipos-ticket", a.ListarTiposTicketPorEvento)
+	a.Echo.GET("/evento/:eventoId/reventa", a.ListarReventasDeEvento)
+	a.Echo.GET("/recintos", a.ListarRecintos)
+	a.Echo.GET("/recintos/:id", a.GetRecinto)
+	a.Echo.GET("/roles/", a.FetchRoles)
+	a.Echo.GET("/rol/:nombre/name", a.GetRolPorNombre)
+
+	// Claves públicas para validar los QR de tickets sin conexión
+	a.Echo.GET("/tickets/qr/claves", a.GetClavesQR)
+
+	// Webhooks de la pasarela de pagos (autenticados por firma, no por token)
+	a.Echo.POST("/pagos/webhook/:metodo", a.WebhookPago)
+
+	// ===== AUTHENTICATED ENDPOINTS (cualquier rol) =====
+	autenticado := a.Echo.Group("", a.RequireAuthenticated)
+
+	autenticado.POST("/logout/all", a.LogoutAll)
+
+	autenticado.GET("/usuario/:id", a.GetUsuario)
+	autenticado.PATCH("/usuario/:id", a.DesactivarUsuario)
+	autenticado.PATCH("/usuario/:id/password", a.ActualizarContrasenha)
+	autenticado.GET("/rol/:usuarioId/user", a.GetRolPorUsuario)
+
+	// Interacción Usuario ↔ Evento
+	autenticado.POST("/evento/interaccion", a.PostInteraccionUsuarioEvento)
+	autenticado.PUT("/evento/interaccion", a.PutInteraccionUsuarioEvento)
+
+	//Cupon (uso en compra)
+	autenticado.GET("/cupon/validar", a.ValidateCupon)
+	autenticado.POST("/cupon/usuario", a.CreateUsuarioCuponForOrdenCompra)
+
+	//Orden de compra (los POST aceptan Idempotency-Key para reintentos seguros)
+	autenticado.POST("/orden_de_compra/hold", a.CrearSesionOrdenTemporal, a.Idempotente)
+	autenticado.GET("/orden_de_compra/:orderId/hold", a.ObtenerEstadoHold)
+	autenticado.POST("/orden_de_compra/:orderId/pago", a.CrearIntentoPago, a.Idempotente)
+	autenticado.POST("/orden_de_compra/:orderId/confirm", a.ConfirmarOrden, a.Idempotente)
+	autenticado.GET("/orden_de_compra/:orderId/tickets/pdf", a.DescargarOrdenPDF)
+	autenticado.GET("/orden_de_compra/:orderId/tickets/pkpasses", a.DescargarOrdenPkpasses)
+	autenticado.GET("/orden_de_compra/:orderId/reembolsos", a.ListarReembolsosDeOrden)
+
+	// Tickets
+	autenticado.POST("/api/tickets/issue", a.EmitirTickets, a.Idempotente)
+	autenticado.POST("/api/tickets/cancel", a.CancelarTickets, a.Idempotente)
+	autenticado.POST("/api/tickets/:ticketId/reprogramacion", a.ResponderReprogramacion, a.Idempotente)
+	autenticado.GET("/member/tickets/:id", a.GetTicketsByUser)
+	autenticado.GET("/member/tickets/:id/pdf", a.DescargarTicketPDF)
+	autenticado.GET("/member/tickets/:id/pkpass", a.DescargarTicketPkpass)
+
+	// Transferencias de tickets entre usuarios
+	autenticado.POST("/member/tickets/:id/transferencias", a.OfrecerTransferencia)
+	autenticado.GET("/member/tickets/:id/historial", a.GetHistorialTitulares)
+	autenticado.GET("/member/transferencias", a.ListarTransferencias)
+	autenticado.POST("/member/transferencias/:id/aceptar", a.AceptarTransferencia)
+	autenticado.POST("/member/transferencias/:id/cancelar", a.CancelarTransferencia)
+
+	// Reventa
+	autenticado.POST("/member/tickets/:id/reventa", a.PublicarReventa)
+	autenticado.GET("/member/reventa", a.ListarMisReventas)
+	autenticado.POST("/reventa/:id/cancelar", a.CancelarReventa)
+	autenticado.POST("/reventa/:id/hold", a.ReservarReventa, a.Idempotente)
+
+	// Bandeja de notificaciones in-app
+	autenticado.GET("/member/notificaciones", a.ListarBandejaNotificaciones)
+	autenticado.GET("/member/notificaciones/no-leidas", a.ContarNotificacionesNoLeidas)
+	autenticado.GET("/member/notificaciones/stream", a.StreamNotificaciones)
+	autenticado.POST("/member/notificaciones/leer-todas", a.MarcarTodasNotificacionesLeidas)
+	autenticado.POST("/member/notificaciones/:id/leer", a.MarcarNotificacionLeida)
+	autenticado.GET("/member/notificaciones/preferencias", a.GetPreferenciasNotificacion)
+	autenticado.PUT("/member/notificaciones/preferencias", a.GuardarPreferenciasNotificacion)
+
+	// ===== ORGANIZADOR / ADMINISTRADOR =====
+	organizador := a.Echo.Group("", a.RequireRoles(model.RolOrganizador, model.RolAdministrador))
+
+	// Eventos
+	organizador.POST("/evento/", a.CreateEvento)
+	organizador.PUT("/api/eventos/:id/full", a.EditarEventoFull)
+	organizador.PUT("/api/eventos/:id", a.EditarEvento)
+	organizador.POST("/api/eventos/:id/estado", a.CambiarEstadoEvento)
+	organizador.PUT("/api/eventos/:id/publicacion", a.ProgramarPublicacionEvento)
+	organizador.POST("/api/eventos/:id/fechas/:idFechaEvento/reprogramacion", a.ReprogramarFecha)
+	organizador.GET("/evento/reporte/:organizadorId", a.GetReporteEvento)
+	organizador.GET("/organizador/:organizadorId/eventos/reporte", a.GetReporteEventosOrganizador)
+	organizador.GET("/api/events/:id/summary", a.GetEventoSummary)
+	organizador.GET("/eventos/:eventoId/asistentes", a.GetAsistentesPorEvento)
+	organizador.PUT("/api/eventos/:id/reventa/politica", a.GuardarPoliticaReventa)
+	organizador.GET("/api/eventos/:id/reventa/politica", a.GetPoliticaReventa)
+	organizador.GET("/api/eventos/:id/reventa/resumen", a.GetResumenReventa)
+
+	// Recintos
+	organizador.POST("/api/recintos", a.CrearRecinto)
+	organizador.PUT("/api/recintos/:id", a.ActualizarRecinto)
+
+	// Check-in en puerta
+	organizador.POST("/api/tickets/checkin", a.RegistrarIngreso)
+	organizador.GET("/api/tickets/checkin/manifiesto/:idFechaEvento", a.GetManifiestoIngreso)
+	organizador.POST("/api/tickets/checkin/sync", a.SincronizarIngresos)
+
 	// Media uploads
-	a.Echo.POST("/media/upload-url", a.GenerateUploadURL)
-	//Cupones
-	//Cupon
-	a.Echo.POST("/cupon/:usuarioCreacion", a.CreateCupon)
-	a.Echo.PUT("/cupon/:usuarioModificacion", a.UpdateCupon)
-	a.Echo.GET("/cupon/organizador/:organizadorId", a.FetchCuponPorOrganizador)
-	a.Echo.GET("/cupon/validar", a.ValidateCupon)
-	a.Echo.POST("/cupon/usuario", a.CreateUsuarioCuponForOrdenCompra)
+	organizador.POST("/media/upload-url", a.GenerateUploadURL)
 
-	//Orden de compra
-	a.Echo.POST("/orden_de_compra/hold", a.CrearSesionOrdenTemporal)
-	a.Echo.GET("/orden_de_compra/:orderId/hold", a.ObtenerEstadoHold)
-	a.Echo.POST("/orden_de_compra/:orderId/confirm", a.ConfirmarOrden)
+	//Cupon
+	organizador.POST("/cupon/:usuarioCreacion", a.CreateCupon)
+	organizador.PUT("/cupon/:usuarioModificacion", a.UpdateCupon)
+	organizador.GET("/cupon/organizador/:organizadorId", a.FetchCuponPorOrganizador)
 
 	// Perfiles de persona
-	a.Echo.GET("/evento/:eventoId/perfiles", a.ListarPerfilesPorEvento)
-	a.Echo.POST("/evento/:eventoId/perfiles", a.CrearPerfilPersona)
-	a.Echo.PUT("/perfiles/:perfilId", a.ActualizarPerfilPersona)
+	organizador.POST("/evento/:eventoId/perfiles", a.CrearPerfilPersona)
+	organizador.PUT("/perfiles/:perfilId", a.ActualizarPerfilPersona)
 
 	// Sectores
-	a.Echo.GET("/evento/:eventoId/sectores", a.ListarSectoresPorEvento)
-	a.Echo.POST("/evento/:eventoId/sectores", a.CrearSector)
-	a.Echo.PUT("/sectores/:sectorId", a.ActualizarSector)
+	organizador.POST("/evento/:eventoId/sectores", a.CrearSector)
+	organizador.PUT("/sectores/:sectorId", a.ActualizarSector)
 
 	// Tipos de ticket
-	a.Echo.GET("/evento/:eventoId/tipos-ticket", a.ListarTiposTicketPorEvento)
-	a.Echo.POST("/evento/:eventoId/tipos-ticket", a.CrearTipoTicket)
-	a.Echo.PUT("/tipos-ticket/:tipoTicketId", a.ActualizarTipoTicket)
+	organizador.POST("/evento/:eventoId/tipos-ticket", a.CrearTipoTicket)
+	organizador.PUT("/tipos-ticket/:tipoTicketId", a.ActualizarTipoTicket)
 
 	// Tarifas
-	a.Echo.POST("/tarifas", a.CrearTarifa)
-	a.Echo.PUT("/tarifas/:tarifaId", a.ActualizarTarifa)
+	organizador.POST("/tarifas", a.CrearTarifa)
+	organizador.PUT("/tarifas/:tarifaId", a.ActualizarTarifa)
 
-	// Tickets
-	a.Echo.POST("/api/tickets/issue", a.EmitirTickets)
-	a.Echo.POST("/api/tickets/cancel", a.CancelarTickets)
-	a.Echo.GET("/member/tickets/:id", a.GetTicketsByUser)
+	// ===== ADMINISTRADOR =====
+	admin := a.Echo.Group("", a.RequireRoles(model.RolAdministrador))
+
+	admin.POST("/categoria/", a.CreateCategoria)
+
+	// 2. Reporte Administrativo Global (Dashboard BI)
+	admin.POST("/api/admin/reports", a.GetAdminReports)
+	admin.GET("/api/admin/transactions/:eventoId", a.GetAdminTransactionsByEvento)
 
 	//Roles
-	a.Echo.GET("/rol/:nombre/name", a.GetRolPorNombre)
-	a.Echo.GET("/rol/:usuarioId/user", a.GetRolPorUsuario)
-	a.Echo.PUT("/rol/:userId/update/:rolId", a.UpdateRol)
-	a.Echo.GET("/roles/", a.FetchRoles)
+	admin.PUT("/rol/:userId/update/:rolId", a.UpdateRol)
 
 	//roles_usuario
-	a.Echo.GET("/api/users/:id/roles", a.ListarRolesDeUsuario)
-	a.Echo.POST("/api/roles/assign", a.CreateRolUser)
-	a.Echo.DELETE("/api/roles/revoke", a.DeleteRolUser)
-	a.Echo.GET("/api/users", a.ListarUsuariosPorRol)
+	admin.GET("/api/users/:id/roles", a.ListarRolesDeUsuario)
+	admin.POST("/api/roles/assign", a.CreateRolUser)
+	admin.DELETE("/api/roles/revoke", a.DeleteRolUser)
+	admin.GET("/api/users", a.ListarUsuariosPorRol)
 
 	// Gestión de estado de usuarios
-	a.Echo.POST("/api/users/:id/status", a.CambiarEstadoUsuario)
-	a.Echo.POST("/api/users/:id/activate", a.ActivarUsuario)
-	a.Echo.POST("/api/users/:id/deactivate", a.DesactivarUsuario)
+	admin.POST("/api/users/:id/status", a.CambiarEstadoUsuario)
+	admin.POST("/api/users/:id/activate", a.ActivarUsuario)
+	admin.POST("/api/users/:id/deactivate", a.DesactivarUsuario)
+
+	// Notificaciones en dead letter
+	admin.GET("/api/admin/notificaciones/fallidas", a.ListarNotificacionesFallidas)
+	admin.POST("/api/admin/notificaciones/:id/reintentar", a.ReintentarNotificacion)
+	admin.POST("/api/admin/outbox/:id/reintentar", a.ReintentarEventoDominio)
+
+	// Reembolsos
+	admin.POST("/api/admin/ordenes/:orderId/reembolsos", a.ReembolsarOrden, a.Idempotente)
+	admin.GET("/api/admin/reembolsos/fallidos", a.ListarReembolsosFallidos)
+	admin.POST("/api/admin/reembolsos/:id/reintentar", a.ReintentarReembolso)
 
 }
 
-func (a *Api) RunApi(configEnv *config.ConfigEnv) {
+// RunApi levanta el servidor HTTP y lo apaga ordenadamente cuando se cancela ctx.
+func (a *Api) RunApi(ctx context.Context, configEnv *config.ConfigEnv) {
 	a.RegisterRoutes(configEnv)
 
 	// Start the server
@@ -172,5 +265,23 @@ func (a *Api) RunApi(configEnv *config.ConfigEnv) {
 		port = "8080"
 	}
 	a.Logger.Infoln(fmt.Sprintf("Nexivent server running on port %s", port))
-	a.Logger.Fatal(a.Echo.Start(fmt.Sprintf(":%s", port)))
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- a.Echo.Start(fmt.Sprintf(":%s", port))
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil && err != http.ErrServerClosed {
+			a.Logger.Fatal(err)
+		}
+	case <-ctx.Done():
+		a.Logger.Infoln("Shutting down Nexivent server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := a.Echo.Shutdown(shutdownCtx); err != nil {
+			a.Logger.Errorf("Error shutting down server: %v", err)
+		}
+	}
 }
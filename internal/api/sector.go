@@ -38,7 +38,7 @@ func (a *Api) CrearSector(c echo.Context) error {
 	}
 	req.EventoID = eventoID
 
-	usuarioCreacion := int64(1)
+	usuarioCreacion := usuarioDesdeContexto(c).ID
 
 	resp, e := a.BllController.Sector.CrearSector(req, usuarioCreacion)
 	if e != nil {
@@ -60,7 +60,7 @@ func (a *Api) ActualizarSector(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	usuarioModificacion := int64(1)
+	usuarioModificacion := usuarioDesdeContexto(c).ID
 
 	resp, e := a.BllController.Sector.ActualizarSector(sectorID, req, usuarioModificacion)
 	if e != nil {
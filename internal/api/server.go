@@ -1,6 +1,13 @@
 package api
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
 	"github.com/Nexivent/nexivent-backend/internal/application/controller"
 	config "github.com/Nexivent/nexivent-backend/internal/config"
 	"github.com/Nexivent/nexivent-backend/logging"
@@ -40,6 +47,50 @@ func NewApi(
 // @description Nexivent Event Management API
 // @BasePath /
 func RunService(configEnv *config.ConfigEnv, logger logging.Logger) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	api, _ := NewApi(logger, configEnv)
-	api.RunApi(configEnv)
+
+	// Workers en segundo plano: se detienen cuando se cancela ctx
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		api.BllController.Orden.IniciarLiberadorDeHolds(
+			ctx,
+			time.Duration(configEnv.HoldReaperInterval)*time.Second,
+			configEnv.HoldReaperBatchSize,
+		)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		api.BllController.Notificacion.IniciarEnvioNotificaciones(
+			ctx,
+			time.Duration(configEnv.OutboxInterval)*time.Second,
+			configEnv.OutboxBatchSize,
+			configEnv.OutboxWorkers,
+		)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		api.BllController.Evento.IniciarCicloDeVidaEventos(
+			ctx,
+			time.Duration(configEnv.EventLifecycleInterval)*time.Second,
+			configEnv.EventLifecycleBatchSize,
+		)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		api.BllController.Idempotencia.IniciarLimpieza(ctx, time.Hour)
+	}()
+
+	api.RunApi(ctx, configEnv)
+
+	stop()
+	workers.Wait()
+	logger.Infoln("Nexivent server stopped")
 }
@@ -16,7 +16,7 @@ func (a *Api) CrearTarifa(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	usuarioCreacion := int64(1)
+	usuarioCreacion := usuarioDesdeContexto(c).ID
 
 	resp, e := a.BllController.Tarifa.CrearTarifa(req, usuarioCreacion)
 	if e != nil {
@@ -38,7 +38,7 @@ func (a *Api) ActualizarTarifa(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	usuarioModificacion := int64(1)
+	usuarioModificacion := usuarioDesdeContexto(c).ID
 
 	resp, e := a.BllController.Tarifa.ActualizarTarifa(id, req, usuarioModificacion)
 	if e != nil {
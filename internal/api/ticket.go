@@ -11,13 +11,15 @@ import (
 // POST /api/tickets/issue
 
 // @Summary      Emitir tickets para una orden confirmada
-// @Description  Genera tickets individuales con código QR único
+// @Description  Devuelve los tickets de la orden (se emiten al confirmarse el pago) y los genera si una orden confirmada antes aún no los tiene; cada código QR es un token firmado con Ed25519
 // @Tags         Ticket
 // @Accept       json
 // @Produce      json
 // @Param        request body schemas.EmitirTicketsRequest true "Datos para emitir tickets"
+// @Param        Idempotency-Key header string false "Clave para reintentar sin duplicar la operación"
 // @Success      201 {object} schemas.EmitirTicketsResponse "Tickets generados"
 // @Failure      404 {object} map[string]string "Orden no encontrada"
+// @Failure      409 {object} errors.Error "El evento de la orden está cancelado"
 // @Failure      422 {object} errors.Error "Datos inválidos"
 // @Failure      500 {object} errors.Error "Error interno"
 // @Router       /api/tickets/issue [post]
@@ -27,6 +29,9 @@ func (a *Api) EmitirTickets(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
+	// Solo el dueño de la orden emite sus tickets: el usuario sale del token, no del body
+	req.UserID = usuarioDesdeContexto(c).ID
+
 	resp, ferr := a.BllController.Ticket.EmitirTicketsConInfo(req)
 	if ferr != nil {
 		if *ferr == errors.ObjectNotFoundError.EventoNotFound {
@@ -40,16 +45,114 @@ func (a *Api) EmitirTickets(c echo.Context) error {
 	return c.JSON(http.StatusCreated, resp)
 }
 
+// POST /api/tickets/checkin
+
+// @Summary      Registrar ingreso de un ticket (check-in en puerta)
+// @Description  Valida el QR escaneado y pasa el ticket de VENDIDO a USADO. Si se rechaza, el código de error indica el motivo (ya usado, cancelado, evento o fecha equivocados, puertas cerradas).
+// @Tags         Ticket
+// @Accept       json
+// @Produce      json
+// @Param        request body schemas.CheckInRequest true "QR escaneado y puerta"
+// @Success      200 {object} schemas.CheckInResponse "Ticket admitido"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/tickets/checkin [post]
+func (a *Api) RegistrarIngreso(c echo.Context) error {
+	var req schemas.CheckInRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Ticket.RegistrarIngreso(req, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET /api/tickets/checkin/manifiesto/{idFechaEvento}
+
+// @Summary      Manifiesto offline para escáneres
+// @Description  Hash SHA-256 y estado de cada ticket emitido para una fecha del evento, la ventana de ingreso y las claves para validar los QR sin conexión.
+// @Tags         Ticket
+// @Produce      json
+// @Param        idFechaEvento path int true "ID de la fecha del evento"
+// @Success      200 {object} schemas.ManifiestoIngresoResponse "OK"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/tickets/checkin/manifiesto/{idFechaEvento} [get]
+func (a *Api) GetManifiestoIngreso(c echo.Context) error {
+	eventoFechaID, parseErr := strconv.ParseInt(c.Param("idFechaEvento"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Ticket.ManifiestoIngreso(eventoFechaID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /api/tickets/checkin/sync
+
+// @Summary      Sincronizar escaneos offline
+// @Description  Concilia un lote de escaneos hechos sin conexión (con la hora del dispositivo). Si un ticket se escaneó en varios dispositivos gana el escaneo más temprano. Devuelve un veredicto por escaneo.
+// @Tags         Ticket
+// @Accept       json
+// @Produce      json
+// @Param        request body schemas.SincronizarIngresosRequest true "Lote de escaneos"
+// @Success      200 {object} schemas.SincronizarIngresosResponse "Veredictos"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /api/tickets/checkin/sync [post]
+func (a *Api) SincronizarIngresos(c echo.Context) error {
+	var req schemas.SincronizarIngresosRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Ticket.SincronizarIngresos(req, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET /tickets/qr/claves
+
+// @Summary      Claves públicas de los QR
+// @Description  Claves Ed25519 (por kid) con las que el escáner de puerta valida los QR sin conexión
+// @Tags         Ticket
+// @Produce      json
+// @Success      200 {object} schemas.ClavesQRResponse "OK"
+// @Router       /tickets/qr/claves [get]
+func (a *Api) GetClavesQR(c echo.Context) error {
+	return c.JSON(http.StatusOK, a.BllController.Ticket.ClavesQR())
+}
+
 // POST /api/tickets/cancel
 
 // @Summary      Cancelar uno o varios tickets.
-// @Description  Cancela tickets (no USADOS ni ya CANCELADOS), actualiza stock y devuelve resumen.
+// @Description  Cancela y reembolsa tickets VENDIDOS que el usuario compró y de los que sigue siendo titular (un administrador puede cancelar cualquiera). Salvo para un administrador, la cancelación se cierra al inicio de la fecha del ticket o TICKET_CANCEL_CUTOFF_MINUTES antes. Se genera un reembolso por orden con lo pagado por cada ticket, sin la parte del fee de servicio; los tickets que no se pueden cancelar se devuelven en noCancelables.
 // @Tags         Ticket
 // @Accept       json
 // @Produce      json
 // @Param        request body schemas.TicketCancelRequest true "Cancelar Tickets Request"
+// @Param        Idempotency-Key header string false "Clave para reintentar sin duplicar la operación"
 // @Success      200 {object} schemas.TicketCancelResponse "OK"
 // @Failure      404 {object} map[string]map[string]string "Error al cancelar los tickets"
+// @Failure      409 {object} errors.Error "La fecha de los tickets ya no admite cancelaciones"
 // @Failure      422 {object} errors.Error "Unprocessable Entity"
 // @Failure      500 {object} errors.Error "Internal Server Error"
 // @Router       /api/tickets/cancel [post]
@@ -59,7 +162,7 @@ func (a *Api) CancelarTickets(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	resp, ferr := a.BllController.Ticket.CancelarTickets(req)
+	resp, ferr := a.BllController.Orden.CancelarTickets(req, usuarioDesdeContexto(c))
 	if ferr != nil {
 		if *ferr == errors.ObjectNotFoundError.EventoNotFound {
 			return c.JSON(http.StatusNotFound, map[string]map[string]string{
@@ -81,6 +184,9 @@ func (a *Api) GetTicketsByUser(c echo.Context) error {
 	if parseErr != nil {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
 	}
+	if !esElMismoOAdmin(c, idUser) {
+		return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+	}
 
 	response, err := a.BllController.Ticket.ObtenerTicketsPorUsuario(idUser)
 	if err != nil {
@@ -88,4 +194,139 @@ func (a *Api) GetTicketsByUser(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+// POST /member/tickets/{id}/transferencias
+
+// @Summary      Ofrecer un ticket a otra persona
+// @Description  El titular ofrece su ticket al correo indicado. La oferta vence a los 7 días y solo puede haber una pendiente por ticket.
+// @Tags         Ticket
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID del ticket"
+// @Param        request body schemas.OfrecerTransferenciaRequest true "Correo del destinatario"
+// @Success      201 {object} schemas.TransferenciaTicket "Oferta creada"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/tickets/{id}/transferencias [post]
+func (a *Api) OfrecerTransferencia(c echo.Context) error {
+	ticketID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+	var req schemas.OfrecerTransferenciaRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	resp, ferr := a.BllController.Ticket.OfrecerTransferencia(ticketID, req, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// GET /member/transferencias
+
+// @Summary      Listar transferencias del usuario
+// @Description  Ofertas de transferencia enviadas por el usuario y recibidas en su correo
+// @Tags         Ticket
+// @Produce      json
+// @Success      200 {object} schemas.TransferenciasResponse "OK"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/transferencias [get]
+func (a *Api) ListarTransferencias(c echo.Context) error {
+	resp, ferr := a.BllController.Ticket.ListarTransferencias(usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /member/transferencias/{id}/aceptar
+
+// @Summary      Aceptar una transferencia
+// @Description  El destinatario pasa a ser el titular del ticket. Se emite un QR nuevo y el anterior deja de ser válido.
+// @Tags         Ticket
+// @Produce      json
+// @Param        id path int true "ID de la transferencia"
+// @Success      200 {object} schemas.TransferenciaTicket "Transferencia aceptada"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/transferencias/{id}/aceptar [post]
+func (a *Api) AceptarTransferencia(c echo.Context) error {
+	transferenciaID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Ticket.AceptarTransferencia(transferenciaID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// POST /member/transferencias/{id}/cancelar
+
+// @Summary      Cancelar o rechazar una transferencia
+// @Description  Quien ofreció el ticket la retira, o el destinatario la rechaza. Solo aplica a ofertas pendientes.
+// @Tags         Ticket
+// @Produce      json
+// @Param        id path int true "ID de la transferencia"
+// @Success      200 {object} schemas.TransferenciaTicket "Transferencia cancelada"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      409 {object} errors.Error "Conflict"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/transferencias/{id}/cancelar [post]
+func (a *Api) CancelarTransferencia(c echo.Context) error {
+	transferenciaID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Ticket.CancelarTransferencia(transferenciaID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GET /member/tickets/{id}/historial
+
+// @Summary      Historial de titulares de un ticket
+// @Description  Todos los titulares que tuvo el ticket, desde la emisión hasta el vigente
+// @Tags         Ticket
+// @Produce      json
+// @Param        id path int true "ID del ticket"
+// @Success      200 {object} schemas.HistorialTitularesResponse "OK"
+// @Failure      403 {object} errors.Error "Forbidden"
+// @Failure      404 {object} errors.Error "Not Found"
+// @Failure      422 {object} errors.Error "Unprocessable Entity"
+// @Failure      500 {object} errors.Error "Internal Server Error"
+// @Router       /member/tickets/{id}/historial [get]
+func (a *Api) GetHistorialTitulares(c echo.Context) error {
+	ticketID, parseErr := strconv.ParseInt(c.Param("id"), 10, 64)
+	if parseErr != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidParsingInteger, c)
+	}
+
+	resp, ferr := a.BllController.Ticket.HistorialTitulares(ticketID, usuarioDesdeContexto(c))
+	if ferr != nil {
+		return errors.HandleError(*ferr, c)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
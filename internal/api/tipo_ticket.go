@@ -38,7 +38,7 @@ func (a *Api) CrearTipoTicket(c echo.Context) error {
 	}
 	req.EventoID = eventoID
 
-	usuarioCreacion := int64(1)
+	usuarioCreacion := usuarioDesdeContexto(c).ID
 
 	resp, e := a.BllController.TipoTicket.CrearTipoTicket(req, usuarioCreacion)
 	if e != nil {
@@ -60,7 +60,7 @@ func (a *Api) ActualizarTipoTicket(c echo.Context) error {
 		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
 	}
 
-	usuarioModificacion := int64(1)
+	usuarioModificacion := usuarioDesdeContexto(c).ID
 
 	resp, e := a.BllController.TipoTicket.ActualizarTipoTicket(id, req, usuarioModificacion)
 	if e != nil {
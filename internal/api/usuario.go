@@ -4,12 +4,12 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Nexivent/nexivent-backend/errors"
 	"github.com/Nexivent/nexivent-backend/internal/application/controller"
 	"github.com/Nexivent/nexivent-backend/internal/dao/model"
-	"github.com/Nexivent/nexivent-backend/internal/dao/repository"
 	"github.com/Nexivent/nexivent-backend/internal/schemas"
 	"github.com/labstack/echo/v4"
 )
@@ -43,30 +43,10 @@ func (a *Api) RegisterUsuario(c echo.Context) error {
 			return errors.HandleError(*err, c)
 		}
 
-		// Generar nuevo código
-		codigo, codeErr := repository.GenerarCodigoVerificacion()
-		if codeErr != nil {
-			a.Logger.Errorf("Error al generar código: %v", codeErr)
-			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"error":   "CODE_GENERATION_ERROR",
-				"message": "Error al generar código de verificación",
-			})
-		}
-
-		// Actualizar código en BD
-		expira := time.Now().Add(15 * time.Minute)
-		updateErr := a.BllController.Usuario.DB.Usuario.ActualizarCodigoVerificacion(usuario.ID, codigo, expira)
-		if updateErr != nil {
-			a.Logger.Errorf("Error al actualizar código: %v", updateErr)
-			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"error":   "CODE_UPDATE_ERROR",
-				"message": "Error al actualizar código de verificación",
-			})
+		if sendErr := a.BllController.Usuario.EnviarCodigoVerificacion(usuario); sendErr != nil {
+			return errors.HandleError(*sendErr, c)
 		}
 
-		a.Logger.Infof("Código reenviado para usuario %d: %s", usuario.ID, codigo)
-
-		// Retornar nuevo código
 		return c.JSON(http.StatusOK, map[string]interface{}{
 			"message": "Código reenviado exitosamente",
 			"usuario": map[string]interface{}{
@@ -74,7 +54,6 @@ func (a *Api) RegisterUsuario(c echo.Context) error {
 				"nombre": usuario.Nombre,
 				"correo": usuario.Correo,
 			},
-			"codigo_verificacion": codigo,
 		})
 	}
 
@@ -114,29 +93,13 @@ func (a *Api) RegisterUsuario(c echo.Context) error {
 		return errors.HandleError(*newErr, c)
 	}
 
-	// Generar código de verificación
-	codigo, codeErr := repository.GenerarCodigoVerificacion()
-	if codeErr != nil {
-		a.Logger.Errorf("Error al generar código: %v", codeErr)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"error":   "CODE_GENERATION_ERROR",
-			"message": "Error al generar código de verificación",
-		})
+	// El código se envía por correo desde el backend, nunca se devuelve en la respuesta
+	if sendErr := a.BllController.Usuario.EnviarCodigoVerificacion(&usuarioRegistrado); sendErr != nil {
+		return errors.HandleError(*sendErr, c)
 	}
 
-	expira := time.Now().Add(15 * time.Minute)
-	updateErr := a.BllController.Usuario.DB.Usuario.ActualizarCodigoVerificacion(usuarioRegistrado.ID, codigo, expira)
-	if updateErr != nil {
-		a.Logger.Errorf("Error al guardar código: %v", updateErr)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"error":   "CODE_SAVE_ERROR",
-			"message": "Error al guardar código de verificación",
-		})
-	}
-
-	a.Logger.Infof("Usuario registrado: %d - Código generado: %s", usuarioRegistrado.ID, codigo)
+	a.Logger.Infof("Usuario registrado: %d - Código de verificación enviado", usuarioRegistrado.ID)
 
-	// Retornar código al frontend para que lo envíe por email
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"message": "Usuario registrado exitosamente",
 		"usuario": map[string]interface{}{
@@ -146,7 +109,6 @@ func (a *Api) RegisterUsuario(c echo.Context) error {
 			"tipo_documento": usuarioRegistrado.TipoDocumento,
 			"num_documento":  usuarioRegistrado.NumDocumento,
 		},
-		"codigo_verificacion":   codigo,
 		"requiere_verificacion": true,
 	})
 }
@@ -156,6 +118,9 @@ func (a *Api) GetUsuario(c echo.Context) error {
 	if err != nil {
 		return errors.HandleError(errors.BadRequestError.InvalidIDParam, c)
 	}
+	if !esElMismoOAdmin(c, int64(id)) {
+		return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+	}
 
 	usuario, newErr := a.BllController.Usuario.GetUsuario(int64(id))
 	if newErr != nil {
@@ -246,7 +211,7 @@ func (a *Api) AuthenticateUsuario(c echo.Context) error {
 	}
 
 	// Generar token
-	token, err := a.BllController.Token.CreateToken(usuario.ID, 24*time.Hour, "authentication")
+	token, err := a.BllController.Token.CreateToken(usuario.ID, 24*time.Hour, model.ScopeAuthentication)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error":   "TOKEN_GENERATION_ERROR",
@@ -258,7 +223,7 @@ func (a *Api) AuthenticateUsuario(c echo.Context) error {
 		"message": "Autenticación exitosa",
 		"token": map[string]interface{}{
 			"token":  token.Plaintext,
-			"expiry": time.Now().Add(24 * time.Hour).Unix(),
+			"expiry": token.Expiry.Unix(),
 		},
 		"usuario": map[string]interface{}{
 			"id":             usuario.ID,
@@ -330,7 +295,7 @@ func (a *Api) AuthenticateOrganizador(c echo.Context) error {
 	}
 
 	// Generar token
-	token, err := a.BllController.Token.CreateToken(usuario.ID, 24*time.Hour, "authentication")
+	token, err := a.BllController.Token.CreateToken(usuario.ID, 24*time.Hour, model.ScopeAuthentication)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error":   "TOKEN_GENERATION_ERROR",
@@ -420,7 +385,7 @@ func (a *Api) GoogleAuth(c echo.Context) error {
 	}
 
 	if err == nil && usuarioExistente != nil {
-		token, tokenErr := a.BllController.Token.CreateToken(usuarioExistente.ID, 24*time.Hour, "authentication")
+		token, tokenErr := a.BllController.Token.CreateToken(usuarioExistente.ID, 24*time.Hour, model.ScopeAuthentication)
 		if tokenErr != nil {
 			a.Logger.Errorf("Error al generar token: %v", tokenErr)
 			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
@@ -464,7 +429,7 @@ func (a *Api) GoogleAuth(c echo.Context) error {
 		return errors.HandleError(*newErr, c)
 	}
 
-	token, tokenErr := a.BllController.Token.CreateToken(usuarioRegistrado.ID, 24*time.Hour, "authentication")
+	token, tokenErr := a.BllController.Token.CreateToken(usuarioRegistrado.ID, 24*time.Hour, model.ScopeAuthentication)
 	if tokenErr != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error":   "TOKEN_GENERATION_ERROR",
@@ -493,7 +458,8 @@ func (a *Api) GoogleAuth(c echo.Context) error {
 
 func (a *Api) VerifyEmail(c echo.Context) error {
 	var input struct {
-		UsuarioID int64 `json:"usuario_id"`
+		UsuarioID int64  `json:"usuario_id"`
+		Codigo    string `json:"codigo"`
 	}
 
 	if err := c.Bind(&input); err != nil {
@@ -510,42 +476,17 @@ func (a *Api) VerifyEmail(c echo.Context) error {
 		})
 	}
 
-	usuarioPrev, usuarioErr := a.BllController.Usuario.GetUsuario(input.UsuarioID)
-	if usuarioErr != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"error":   "USER_NOT_FOUND",
-			"message": "Usuario no encontrado",
-		})
-	}
-	var estadoCuenta int
-	if usuarioPrev.TipoDocumento == "RUC_PERSONA" || usuarioPrev.TipoDocumento == "RUC_EMPRESA" {
-		estadoCuenta = 0
-	} else {
-		estadoCuenta = 1
-	}
-	// Marcar como verificado
-	err := a.BllController.Usuario.DB.Usuario.PostgresqlDB.Model(&model.Usuario{}).
-		Where("usuario_id = ?", input.UsuarioID).
-		Updates(map[string]interface{}{
-			"estado_de_cuenta":        estadoCuenta,
-			"codigo_verificacion":     nil,
-			"fecha_expiracion_codigo": nil,
-		}).Error
-
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"error":   "VERIFICATION_ERROR",
-			"message": "Error al verificar el usuario",
+	input.Codigo = strings.TrimSpace(input.Codigo)
+	if input.Codigo == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":   "INVALID_CODE",
+			"message": "El código de verificación es obligatorio",
 		})
 	}
 
-	// Obtener usuario actualizado
-	usuario, usuarioErr := a.BllController.Usuario.GetUsuario(input.UsuarioID)
-	if usuarioErr != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"error":   "USER_NOT_FOUND",
-			"message": "Usuario no encontrado",
-		})
+	usuario, verifyErr := a.BllController.Usuario.VerificarCorreo(input.UsuarioID, input.Codigo)
+	if verifyErr != nil {
+		return errors.HandleError(*verifyErr, c)
 	}
 
 	if usuario == nil {
@@ -576,9 +517,13 @@ func (a *Api) VerifyEmail(c echo.Context) error {
 		telefono = *usuario.Telefono
 	}
 
-	// Generar token simple (sin dependencias)
-	tokenString := fmt.Sprintf("nexivent_auth_%d_%d", input.UsuarioID, time.Now().Unix())
-	tokenExpiry := time.Now().Add(24 * time.Hour)
+	token, tokenErr := a.BllController.Token.CreateToken(usuario.ID, 24*time.Hour, model.ScopeAuthentication)
+	if tokenErr != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error":   "TOKEN_GENERATION_ERROR",
+			"message": "Error al generar token de autenticación",
+		})
+	}
 
 	// Preparar respuesta con validaciones
 	usuarioResponse := map[string]interface{}{
@@ -603,35 +548,108 @@ func (a *Api) VerifyEmail(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message": "Email verificado exitosamente",
 		"token": map[string]interface{}{
-			"token":  tokenString,
-			"expiry": tokenExpiry.Unix(),
+			"token":  token.Plaintext,
+			"expiry": token.Expiry.Unix(),
 		},
 		"usuario": usuarioResponse,
 	})
 }
 
+// extraerBearerToken devuelve el token del header Authorization ("Bearer <token>") o "" si no viene.
+func extraerBearerToken(c echo.Context) string {
+	authHeader := c.Request().Header.Get(echo.HeaderAuthorization)
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		return strings.TrimSpace(authHeader[7:])
+	}
+	return ""
+}
+
+// Logout revoca el token con el que se hizo la petición (solo este dispositivo)
+// POST /logout
 func (a *Api) Logout(c echo.Context) error {
-	// Obtener el token del header Authorization
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
+	token := extraerBearerToken(c)
+	if token == "" {
 		return c.JSON(http.StatusOK, map[string]interface{}{
 			"message": "Sesión cerrada exitosamente",
 		})
 	}
 
-	// Extraer el token (formato: "Bearer <token>")
-	var token string
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		token = authHeader[7:]
+	if err := a.BllController.Token.RevokeToken(model.ScopeAuthentication, token); err != nil {
+		return errors.HandleError(*err, c)
 	}
 
-	a.Logger.Infof("Usuario cerró sesión con token: %s", token)
-
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message": "Sesión cerrada exitosamente",
 	})
 }
 
+// LogoutAll revoca todos los tokens de autenticación del usuario (todos los dispositivos)
+// POST /logout/all
+func (a *Api) LogoutAll(c echo.Context) error {
+	usuario := usuarioDesdeContexto(c)
+
+	if err := a.BllController.Token.DeleteTokensForUser(model.ScopeAuthentication, usuario.ID); err != nil {
+		return errors.HandleError(*err, c)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Sesión cerrada en todos los dispositivos",
+	})
+}
+
+// ForgotPassword envía un link de recuperación de contraseña al correo indicado.
+// Siempre responde 202 para no revelar si el correo está registrado.
+// POST /password/forgot
+func (a *Api) ForgotPassword(c echo.Context) error {
+	var input struct {
+		Correo string `json:"correo"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	input.Correo = strings.TrimSpace(input.Correo)
+	if input.Correo == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "El correo es obligatorio",
+		})
+	}
+
+	if err := a.BllController.Usuario.SolicitarRecuperacionContrasenha(input.Correo); err != nil {
+		return errors.HandleError(*err, c)
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"message": "Si el correo está registrado, recibirás un enlace para restablecer tu contraseña",
+	})
+}
+
+// ResetPassword cambia la contraseña usando el token recibido por correo.
+// POST /password/reset
+func (a *Api) ResetPassword(c echo.Context) error {
+	var input struct {
+		Token            string `json:"token"`
+		NuevaContrasenha string `json:"nuevaContrasenha"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return errors.HandleError(errors.UnprocessableEntityError.InvalidRequestBody, c)
+	}
+
+	if input.Token == "" || input.NuevaContrasenha == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "El token y la nueva contraseña son obligatorios",
+		})
+	}
+
+	if err := a.BllController.Usuario.RestablecerContrasenha(input.Token, input.NuevaContrasenha); err != nil {
+		return errors.HandleError(*err, c)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Contraseña restablecida correctamente",
+	})
+}
+
 // ===================================================
 // GESTIÓN DE ESTADO DE USUARIOS
 // ===================================================
@@ -648,8 +666,7 @@ func (a *Api) ActivarUsuario(c echo.Context) error {
 		})
 	}
 
-	// TODO: Obtener del contexto/sesión
-	updatedBy := int64(1)
+	updatedBy := usuarioDesdeContexto(c).ID
 
 	apiErr := a.BllController.Usuario.ActivarUsuario(usuarioID, updatedBy)
 	if apiErr != nil {
@@ -676,7 +693,12 @@ func (a *Api) DesactivarUsuario(c echo.Context) error {
 		})
 	}
 
-	updatedBy := int64(1)
+	// PATCH /usuario/:id permite desactivar la propia cuenta; a otros solo un administrador
+	sesion := usuarioDesdeContexto(c)
+	if sesion.ID != usuarioID && !sesion.TieneAlgunRol(model.RolAdministrador) {
+		return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+	}
+	updatedBy := sesion.ID
 
 	apiErr := a.BllController.Usuario.DesactivarUsuario(usuarioID, updatedBy)
 	if apiErr != nil {
@@ -721,7 +743,7 @@ func (a *Api) CambiarEstadoUsuario(c echo.Context) error {
 		})
 	}
 
-	updatedBy := int64(1)
+	updatedBy := usuarioDesdeContexto(c).ID
 
 	// Llamar al controller directamente
 	var apiErr *errors.Error
@@ -780,8 +802,12 @@ func (a *Api) ActualizarContrasenha(c echo.Context) error {
 		})
 	}
 
-	// 3) updatedBy (luego lo sacas del token/JWT)
-	updatedBy := usuarioID
+	// 3) Solo el propio usuario o un administrador pueden cambiar la contraseña
+	sesion := usuarioDesdeContexto(c)
+	if sesion.ID != usuarioID && !sesion.TieneAlgunRol(model.RolAdministrador) {
+		return errors.HandleError(errors.ForbiddenError.InsufficientPermissions, c)
+	}
+	updatedBy := sesion.ID
 
 	// 4) Llamar a la capa de negocio
 	apiErr := a.BllController.Usuario.ActualizarContrasenha(usuarioID, req.NuevaContrasenha, updatedBy)
@@ -0,0 +1,197 @@
+package adapter
+
+import (
+	"slices"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/notificaciones"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+)
+
+const (
+	// LimiteBandejaPorDefecto es el tamaño de página de la bandeja si el cliente no lo indica.
+	LimiteBandejaPorDefecto = 20
+	// limiteBandejaMaximo acota el tamaño de página que puede pedir el cliente.
+	limiteBandejaMaximo = 100
+)
+
+func notificacionABandeja(n *model.Notificacion) schemas.NotificacionBandeja {
+	item := schemas.NotificacionBandeja{
+		IdNotificacion: n.ID,
+		Tipo:           n.Tipo,
+		Asunto:         n.Asunto,
+		Mensaje:        n.Mensaje,
+		Leida:          n.Leida,
+		FechaCreacion:  n.FechaCreacion.Format(time.RFC3339),
+	}
+	if n.FechaLectura != nil {
+		f := n.FechaLectura.Format(time.RFC3339)
+		item.FechaLectura = &f
+	}
+	return item
+}
+
+// MensajeABandeja convierte un aviso in-app en vivo en la misma forma que devuelve la bandeja.
+func MensajeABandeja(m *notificaciones.Mensaje) schemas.NotificacionBandeja {
+	return schemas.NotificacionBandeja{
+		IdNotificacion: m.ID,
+		Tipo:           m.Tipo,
+		Asunto:         m.Asunto,
+		Mensaje:        m.Texto,
+		FechaCreacion:  m.Fecha.Format(time.RFC3339),
+	}
+}
+
+// ListarBandeja devuelve una página de la bandeja in-app del usuario, de la más reciente a la
+// más antigua. cursor es el siguienteCursor de la página anterior (0 para la primera).
+func (a *Notificacion) ListarBandeja(
+	usuario *model.Usuario,
+	cursor int64,
+	soloNoLeidas bool,
+	limite int,
+) (*schemas.BandejaNotificacionesResponse, *errors.Error) {
+	if cursor < 0 || limite < 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	if limite == 0 {
+		limite = LimiteBandejaPorDefecto
+	}
+	if limite > limiteBandejaMaximo {
+		limite = limiteBandejaMaximo
+	}
+
+	// Se pide uno de más para saber si hay otra página sin contar toda la bandeja
+	lista, err := a.DaoPostgresql.Notificacion.ListarBandeja(usuario.ID, cursor, soloNoLeidas, limite+1)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	resp := &schemas.BandejaNotificacionesResponse{}
+	if len(lista) > limite {
+		lista = lista[:limite]
+		siguiente := lista[limite-1].ID
+		resp.SiguienteCursor = &siguiente
+	}
+	resp.Notificaciones = make([]schemas.NotificacionBandeja, 0, len(lista))
+	for i := range lista {
+		resp.Notificaciones = append(resp.Notificaciones, notificacionABandeja(&lista[i]))
+	}
+	return resp, nil
+}
+
+// NuevasEnBandeja devuelve, en orden, las notificaciones de la bandeja posteriores a despuesDe.
+func (a *Notificacion) NuevasEnBandeja(usuarioID, despuesDe int64) ([]schemas.NotificacionBandeja, *errors.Error) {
+	lista, err := a.DaoPostgresql.Notificacion.NuevasEnBandeja(usuarioID, despuesDe, limiteBandejaMaximo)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	items := make([]schemas.NotificacionBandeja, 0, len(lista))
+	for i := range lista {
+		items = append(items, notificacionABandeja(&lista[i]))
+	}
+	return items, nil
+}
+
+// UltimaDeBandeja devuelve el id de la notificación más reciente de la bandeja (0 si está vacía).
+func (a *Notificacion) UltimaDeBandeja(usuarioID int64) (int64, *errors.Error) {
+	id, err := a.DaoPostgresql.Notificacion.UltimaDeBandeja(usuarioID)
+	if err != nil {
+		return 0, &errors.InternalServerError.Default
+	}
+	return id, nil
+}
+
+// ContarNoLeidas cuenta las notificaciones de la bandeja que el usuario no leyó.
+func (a *Notificacion) ContarNoLeidas(usuario *model.Usuario) (*schemas.NotificacionesNoLeidasResponse, *errors.Error) {
+	total, err := a.DaoPostgresql.Notificacion.ContarNoLeidas(usuario.ID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	return &schemas.NotificacionesNoLeidasResponse{NoLeidas: total}, nil
+}
+
+// MarcarLeida marca como leída una notificación de la bandeja del usuario.
+func (a *Notificacion) MarcarLeida(id int64, usuario *model.Usuario, ahora time.Time) *errors.Error {
+	ok, err := a.DaoPostgresql.Notificacion.MarcarLeida(id, usuario.ID, ahora)
+	if err != nil {
+		return &errors.InternalServerError.Default
+	}
+	if !ok {
+		return &errors.ObjectNotFoundError.NotificacionNotFound
+	}
+	return nil
+}
+
+// MarcarTodasLeidas marca como leída toda la bandeja del usuario.
+func (a *Notificacion) MarcarTodasLeidas(usuario *model.Usuario, ahora time.Time) (*schemas.NotificacionesMarcadasResponse, *errors.Error) {
+	marcadas, err := a.DaoPostgresql.Notificacion.MarcarTodasLeidas(usuario.ID, ahora)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	return &schemas.NotificacionesMarcadasResponse{Marcadas: marcadas}, nil
+}
+
+// ListarPreferencias devuelve todas las combinaciones de tipo y canal configurables, con lo que
+// el usuario eligió o habilitadas si nunca las cambió.
+func (a *Notificacion) ListarPreferencias(usuario *model.Usuario) (*schemas.PreferenciasNotificacionResponse, *errors.Error) {
+	guardadas, err := a.DaoPostgresql.Preferencias.ListarPorUsuario(usuario.ID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	apagadas := map[string]bool{}
+	for _, p := range guardadas {
+		if !p.Habilitado {
+			apagadas[p.Tipo+"/"+p.Canal] = true
+		}
+	}
+
+	resp := &schemas.PreferenciasNotificacionResponse{
+		Preferencias: make([]schemas.PreferenciaNotificacion, 0, len(model.TiposNotificacion)*len(model.CanalesPreferencia)),
+	}
+	for _, tipo := range model.TiposNotificacion {
+		for _, canal := range model.CanalesPreferencia {
+			obligatoria := model.PreferenciaObligatoria(tipo, canal)
+			resp.Preferencias = append(resp.Preferencias, schemas.PreferenciaNotificacion{
+				Tipo:        tipo,
+				Canal:       canal,
+				Habilitado:  obligatoria || !apagadas[tipo+"/"+canal],
+				Obligatoria: obligatoria,
+			})
+		}
+	}
+	return resp, nil
+}
+
+// GuardarPreferencias cambia las combinaciones de tipo y canal indicadas; las demás quedan como
+// estaban. Devuelve todas las preferencias resultantes.
+func (a *Notificacion) GuardarPreferencias(
+	usuario *model.Usuario,
+	req schemas.PreferenciasNotificacionRequest,
+	ahora time.Time,
+) (*schemas.PreferenciasNotificacionResponse, *errors.Error) {
+	preferencias := make([]model.PreferenciaNotificacion, 0, len(req.Preferencias))
+	vistas := map[string]int{}
+	for _, p := range req.Preferencias {
+		if !slices.Contains(model.TiposNotificacion, p.Tipo) || !slices.Contains(model.CanalesPreferencia, p.Canal) ||
+			(!p.Habilitado && model.PreferenciaObligatoria(p.Tipo, p.Canal)) {
+			return nil, &errors.UnprocessableEntityError.InvalidNotificationPref
+		}
+		// Si una combinación viene repetida gana la última; el upsert no admite duplicados
+		clave := p.Tipo + "/" + p.Canal
+		if i, ok := vistas[clave]; ok {
+			preferencias[i].Habilitado = p.Habilitado
+			continue
+		}
+		vistas[clave] = len(preferencias)
+		preferencias = append(preferencias, model.PreferenciaNotificacion{
+			Tipo:       p.Tipo,
+			Canal:      p.Canal,
+			Habilitado: p.Habilitado,
+		})
+	}
+	if err := a.DaoPostgresql.Preferencias.Guardar(usuario.ID, preferencias, ahora); err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	return a.ListarPreferencias(usuario)
+}
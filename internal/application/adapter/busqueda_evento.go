@@ -0,0 +1,94 @@
+package adapter
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+)
+
+// largoMaximoConsulta limita el texto de una búsqueda
+const largoMaximoConsulta = 200
+
+// BuscarEventos busca eventos a la venta por texto (en español, sin importar tildes y tolerando
+// errores de tipeo en el título) y devuelve los resultados por relevancia, con el texto
+// resaltado y las facetas por categoría, fecha y precio.
+func (e *Evento) BuscarEventos(req *schemas.BusquedaEventosRequest, ahora time.Time) (*schemas.BusquedaEventosResponse, *errors.Error) {
+	consulta := strings.TrimSpace(req.Consulta)
+	if consulta == "" || utf8.RuneCountInString(consulta) > largoMaximoConsulta {
+		return nil, &errors.UnprocessableEntityError.InvalidSearchQuery
+	}
+	filtros := daoPostgresql.FiltrosBusquedaEventos{
+		CategoriaID: req.CategoriaID,
+		RangoFecha:  strings.ToUpper(strings.TrimSpace(req.Fecha)),
+		RangoPrecio: strings.ToUpper(strings.TrimSpace(req.Precio)),
+	}
+	if (filtros.RangoFecha != "" && !slices.Contains(daoPostgresql.RangosFechaBusqueda, filtros.RangoFecha)) ||
+		(filtros.RangoPrecio != "" && !slices.Contains(daoPostgresql.RangosPrecioBusqueda, filtros.RangoPrecio)) {
+		return nil, &errors.UnprocessableEntityError.InvalidSearchFilter
+	}
+	pagina, perr := paginaEventos(&req.Pagina, daoPostgresql.OrdenEventosRelevancia, nil)
+	if perr != nil {
+		return nil, perr
+	}
+
+	busqueda, err := e.DaoPostgresql.Evento.BuscarEventos(consulta, filtros, pagina, ahora.In(zonaHorariaEventos))
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+
+	listado := eventosPaginados(&busqueda.ListadoEventos, pagina)
+	resp := &schemas.BusquedaEventosResponse{
+		Resultados:      make([]schemas.ResultadoBusquedaEvento, 0, len(listado.Eventos)),
+		Total:           listado.Total,
+		TotalPaginas:    listado.TotalPaginas,
+		Limite:          listado.Limite,
+		Orden:           listado.Orden,
+		SiguienteCursor: listado.SiguienteCursor,
+		Facetas: schemas.FacetasBusquedaEventos{
+			Categorias: make([]schemas.FacetaCategoria, 0, len(busqueda.Categorias)),
+			Fechas:     facetaRangos(daoPostgresql.RangosFechaBusqueda, busqueda.RangosFecha),
+			Precios:    facetaRangos(daoPostgresql.RangosPrecioBusqueda, busqueda.RangosPrecio),
+		},
+	}
+	for _, ev := range listado.Eventos {
+		resaltado := busqueda.Resaltados[ev.ID]
+		resp.Resultados = append(resp.Resultados, schemas.ResultadoBusquedaEvento{
+			Evento:          ev,
+			Relevancia:      busqueda.Relevancias[ev.ID],
+			TituloResaltado: marcarResaltado(resaltado.Titulo),
+			Fragmento:       marcarResaltado(resaltado.Fragmento),
+		})
+	}
+	for _, c := range busqueda.Categorias {
+		resp.Facetas.Categorias = append(resp.Facetas.Categorias, schemas.FacetaCategoria{
+			IdCategoria: c.CategoriaID,
+			Nombre:      c.Nombre,
+			Cantidad:    c.Cantidad,
+		})
+	}
+	return resp, nil
+}
+
+// facetaRangos lista todos los rangos de una faceta en su orden, con cero los que no tienen
+// eventos.
+func facetaRangos(claves []string, cantidades map[string]int64) []schemas.FacetaRango {
+	rangos := make([]schemas.FacetaRango, 0, len(claves))
+	for _, clave := range claves {
+		rangos = append(rangos, schemas.FacetaRango{Clave: clave, Cantidad: cantidades[clave]})
+	}
+	return rangos
+}
+
+// marcarResaltado escapa el texto resaltado por Postgres y cambia sus marcas por <mark>.
+func marcarResaltado(texto string) string {
+	return strings.NewReplacer(
+		daoPostgresql.MarcaInicioResaltado, "<mark>",
+		daoPostgresql.MarcaFinResaltado, "</mark>",
+	).Replace(html.EscapeString(texto))
+}
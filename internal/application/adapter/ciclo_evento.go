@@ -0,0 +1,137 @@
+package adapter
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"gorm.io/gorm"
+)
+
+func estadoEventoASchema(ev *model.Evento) *schemas.EstadoEventoResponse {
+	resp := &schemas.EstadoEventoResponse{
+		IdEvento: ev.ID,
+		Estado:   util.EstadoEvento(ev.EventoEstado).String(),
+	}
+	if ev.PublicarEn != nil {
+		resp.PublicarEn = ev.PublicarEn.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// estadoEventoDesdeTexto interpreta el nombre de un estado del evento ("PUBLICADO", ...).
+func estadoEventoDesdeTexto(texto string) (util.EstadoEvento, bool) {
+	texto = strings.ToUpper(strings.TrimSpace(texto))
+	for e := util.EventoBorrador; e.IsValid(); e++ {
+		if e.String() == texto {
+			return e, true
+		}
+	}
+	return 0, false
+}
+
+// verificarOrganizadorDelEvento comprueba que el evento exista y que el usuario sea su
+// organizador o un administrador.
+func (e *Evento) verificarOrganizadorDelEvento(eventoID int64, usuario *model.Usuario) *errors.Error {
+	ev, err := e.DaoPostgresql.Evento.ObtenerEventoBasico(eventoID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &errors.ObjectNotFoundError.EventoNotFound
+		}
+		e.logger.Errorf("verificarOrganizadorDelEvento(%d): %v", eventoID, err)
+		return &errors.InternalServerError.Default
+	}
+	if ev.OrganizadorID != usuario.ID && !usuario.TieneAlgunRol(model.RolAdministrador) {
+		return &errors.ForbiddenError.InsufficientPermissions
+	}
+	return nil
+}
+
+// CambiarEstadoEvento mueve el evento por su ciclo de vida. Cancelarlo corta las ventas y las
+// reservas en curso en el acto; los reembolsos de lo vendido los hace el ciclo de vida de eventos.
+func (e *Evento) CambiarEstadoEvento(
+	eventoID int64,
+	req *schemas.CambiarEstadoEventoRequest,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.EstadoEventoResponse, *errors.Error) {
+	nuevo, ok := estadoEventoDesdeTexto(req.Estado)
+	if !ok {
+		return nil, &errors.UnprocessableEntityError.InvalidRequestBody
+	}
+	if ferr := e.verificarOrganizadorDelEvento(eventoID, usuario); ferr != nil {
+		return nil, ferr
+	}
+
+	ev, err := e.DaoPostgresql.Evento.ActualizarEstadoWorkflowEvento(eventoID, nuevo.Codigo(), &usuario.ID, &ahora)
+	if err != nil {
+		switch err {
+		case daoPostgresql.ErrTransicionEventoInvalida:
+			return nil, &errors.ConflictError.InvalidEventTransition
+		case gorm.ErrRecordNotFound:
+			return nil, &errors.ObjectNotFoundError.EventoNotFound
+		}
+		return nil, &errors.InternalServerError.Default
+	}
+	e.logger.Infof("Evento %d pasó a %s (usuario %d)", eventoID, nuevo, usuario.ID)
+	return estadoEventoASchema(ev), nil
+}
+
+// ProgramarPublicacion fija cuándo se publica solo un evento en BORRADOR, o quita la
+// programación si publicarEn es null. La fecha debe ser futura.
+func (e *Evento) ProgramarPublicacion(
+	eventoID int64,
+	req *schemas.ProgramarPublicacionRequest,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.EstadoEventoResponse, *errors.Error) {
+	var publicarEn *time.Time
+	if req.PublicarEn != nil {
+		t, err := time.Parse(time.RFC3339, *req.PublicarEn)
+		if err != nil {
+			return nil, &errors.UnprocessableEntityError.InvalidDateFormat
+		}
+		if !t.After(ahora) {
+			return nil, &errors.UnprocessableEntityError.InvalidRequestBody
+		}
+		publicarEn = &t
+	}
+	if ferr := e.verificarOrganizadorDelEvento(eventoID, usuario); ferr != nil {
+		return nil, ferr
+	}
+
+	ev, err := e.DaoPostgresql.Evento.ProgramarPublicacion(eventoID, publicarEn, usuario.ID, ahora)
+	if err != nil {
+		switch err {
+		case daoPostgresql.ErrTransicionEventoInvalida:
+			return nil, &errors.ConflictError.InvalidEventTransition
+		case gorm.ErrRecordNotFound:
+			return nil, &errors.ObjectNotFoundError.EventoNotFound
+		}
+		return nil, &errors.InternalServerError.Default
+	}
+	return estadoEventoASchema(ev), nil
+}
+
+// PublicarProgramados publica, en lotes de tamaño lote, los eventos en BORRADOR cuya hora de
+// publicación ya llegó. Devuelve cuántos se publicaron.
+func (e *Evento) PublicarProgramados(ahora time.Time, lote int) (int, *errors.Error) {
+	publicados := 0
+	for {
+		ids, err := e.DaoPostgresql.Evento.PublicarProgramados(ahora, lote)
+		if err != nil {
+			return publicados, &errors.InternalServerError.Default
+		}
+		for _, id := range ids {
+			e.logger.Infof("Evento %d publicado según lo programado", id)
+		}
+		publicados += len(ids)
+		if len(ids) < lote {
+			return publicados, nil
+		}
+	}
+}
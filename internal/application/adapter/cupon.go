@@ -11,6 +11,7 @@ import (
 	"github.com/Nexivent/nexivent-backend/internal/schemas"
 	"github.com/Nexivent/nexivent-backend/logging"
 	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
 )
 
 type Cupon struct {
@@ -28,11 +29,26 @@ func NewCuponAdapter(
 	}
 }
 
-func (c *Cupon) CreatePostgresqlCupon(cuponReq *schemas.CuponResquest, usuarioCreacion int64) (*schemas.CuponResponse, *errors.Error) {
-	usuario, error := c.DaoPostgresql.Usuario.ObtenerUsuarioBasicoPorID(usuarioCreacion)
+// verificarEventoDelCupon comprueba que el evento del cupón exista y que el usuario sea su
+// organizador o un administrador.
+func (c *Cupon) verificarEventoDelCupon(eventoID int64, usuario *model.Usuario) *errors.Error {
+	evento, err := c.DaoPostgresql.Evento.ObtenerEventoBasico(eventoID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &errors.UnprocessableEntityError.InvalidEventoId
+		}
+		c.logger.Errorf("verificarEventoDelCupon(%d): %v", eventoID, err)
+		return &errors.InternalServerError.Default
+	}
+	if evento.OrganizadorID != usuario.ID && !usuario.TieneAlgunRol(model.RolAdministrador) {
+		return &errors.ForbiddenError.InsufficientPermissions
+	}
+	return nil
+}
 
-	if error != nil {
-		return nil, &errors.BadRequestError.InvalidUpdatedByValue
+func (c *Cupon) CreatePostgresqlCupon(cuponReq *schemas.CuponResquest, usuario *model.Usuario) (*schemas.CuponResponse, *errors.Error) {
+	if ferr := c.verificarEventoDelCupon(cuponReq.EventoID, usuario); ferr != nil {
+		return nil, ferr
 	}
 
 	cuponModel := &model.Cupon{
@@ -88,11 +104,9 @@ func (c *Cupon) CreatePostgresqlCupon(cuponReq *schemas.CuponResquest, usuarioCr
 	return cuponRes, nil
 }
 
-func (c *Cupon) UpdatePostgresqlCupon(cuponReq *schemas.CuponResquest, usuarioModificacion int64) (*schemas.CuponResponse, *errors.Error) {
-	usuario, error := c.DaoPostgresql.Usuario.ObtenerUsuarioBasicoPorID(usuarioModificacion)
-
-	if error != nil {
-		return nil, &errors.BadRequestError.InvalidUpdatedByValue
+func (c *Cupon) UpdatePostgresqlCupon(cuponReq *schemas.CuponResquest, usuario *model.Usuario) (*schemas.CuponResponse, *errors.Error) {
+	if ferr := c.verificarEventoDelCupon(cuponReq.EventoID, usuario); ferr != nil {
+		return nil, ferr
 	}
 
 	_, errorCupon := c.DaoPostgresql.Cupon.ObtenerCuponPorIdYIdEvento(cuponReq.ID, cuponReq.EventoID)
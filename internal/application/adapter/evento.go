@@ -6,6 +6,7 @@ import (
 
 	"github.com/Nexivent/nexivent-backend/errors"
 	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
 	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
 	"github.com/Nexivent/nexivent-backend/internal/schemas"
 	"github.com/Nexivent/nexivent-backend/logging"
@@ -32,6 +33,12 @@ func NewEventoAdapter(
 
 // CreatePostgresqlEvento creates a new event with all related entities
 func (e *Evento) CreatePostgresqlEvento(eventoReq *schemas.EventoRequest, usuarioCreacion int64) (*schemas.EventoResponse, *errors.Error) {
+	// Un evento nace en BORRADOR o PUBLICADO; el resto del ciclo de vida va por sus transiciones
+	estadoInicial := util.EstadoEvento(convert.MapEstadoToInt16(eventoReq.Estado))
+	if estadoInicial != util.EventoBorrador && estadoInicial != util.EventoPublicado {
+		return nil, &errors.ConflictError.InvalidEventTransition
+	}
+
 	// Start a transaction
 	tx := e.DaoPostgresql.Evento.PostgresqlDB.Begin()
 	if tx.Error != nil {
@@ -67,6 +74,11 @@ func (e *Evento) CreatePostgresqlEvento(eventoReq *schemas.EventoRequest, usuari
 		FechaCreacion:     now,
 	}
 
+	if ferr := asignarRecinto(tx, eventoModel, eventoReq.IdRecinto); ferr != nil {
+		tx.Rollback()
+		return nil, ferr
+	}
+
 	// Create the event
 	if err := tx.Create(eventoModel).Error; err != nil {
 		tx.Rollback()
@@ -276,6 +288,7 @@ func (e *Evento) CreatePostgresqlEvento(eventoReq *schemas.EventoRequest, usuari
 		IdEvento:          eventoModel.ID,
 		IdOrganizador:     eventoModel.OrganizadorID,
 		IdCategoria:       eventoModel.CategoriaID,
+		IdRecinto:         eventoModel.RecintoID,
 		Titulo:            eventoModel.Titulo,
 		Descripcion:       eventoModel.Descripcion,
 		Lugar:             eventoModel.Lugar,
@@ -304,11 +317,16 @@ func (e *Evento) CreatePostgresqlEvento(eventoReq *schemas.EventoRequest, usuari
 }
 
 // EditarEventoFull reemplaza completamente un evento (solo BORRADOR y sin ventas).
-// Borra dependencias y las recrea con el mismo formato de creación.
-func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullRequest) (*schemas.EventoResponse, *errors.Error) {
+// Borra dependencias y las recrea con el mismo formato de creación. Solo puede hacerlo su
+// organizador o un administrador, y solo un administrador puede pasarlo a otro organizador.
+func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullRequest, usuario *model.Usuario) (*schemas.EventoResponse, *errors.Error) {
 	if eventoID <= 0 {
 		return nil, &errors.BadRequestError.InvalidIDParam
 	}
+	if ferr := e.verificarOrganizadorDelEvento(eventoID, usuario); ferr != nil {
+		return nil, ferr
+	}
+	req.UsuarioModificacion = usuario.ID
 
 	tx := e.DaoPostgresql.Evento.PostgresqlDB.Begin()
 	if tx.Error != nil {
@@ -357,13 +375,27 @@ func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullR
 		return nil, &errors.UnprocessableEntityError.InvalidRequestBody
 	}
 
+	if req.IdOrganizador != ev.OrganizadorID && !usuario.TieneAlgunRol(model.RolAdministrador) {
+		tx.Rollback()
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+
 	// Actualizar cabecera de evento
 	ev.OrganizadorID = req.IdOrganizador
 	ev.CategoriaID = req.IdCategoria
 	ev.Titulo = req.Titulo
 	ev.Descripcion = req.Descripcion
 	ev.Lugar = req.Lugar
-	ev.EventoEstado = convert.MapEstadoToInt16(req.Estado)
+	nuevoEstado := util.EstadoEvento(convert.MapEstadoToInt16(req.Estado))
+	if nuevoEstado != util.EventoBorrador && nuevoEstado != util.EventoPublicado {
+		// Cancelar va por ActualizarEstadoWorkflowEvento, que hace la cascada
+		tx.Rollback()
+		return nil, &errors.ConflictError.InvalidEventTransition
+	}
+	ev.EventoEstado = nuevoEstado.Codigo()
+	if nuevoEstado != util.EventoBorrador {
+		ev.PublicarEn = nil
+	}
 	ev.CantMeGusta = req.Likes
 	ev.CantNoInteresa = req.NoInteres
 	ev.CantVendidoTotal = req.CantVendidasTotal
@@ -373,6 +405,10 @@ func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullR
 	ev.VideoPresentacion = req.VideoUrl
 	ev.UsuarioModificacion = &req.UsuarioModificacion
 	ev.FechaModificacion = &now
+	if ferr := asignarRecinto(tx, &ev, req.IdRecinto); ferr != nil {
+		tx.Rollback()
+		return nil, ferr
+	}
 
 	if err := tx.Save(&ev).Error; err != nil {
 		tx.Rollback()
@@ -431,7 +467,7 @@ func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullR
 		return nil, &errors.InternalServerError.Default
 	}
 
-	usuario := req.UsuarioModificacion
+	usuarioID := req.UsuarioModificacion
 
 	// Perfiles
 	perfilesMap := make(map[string]int64)
@@ -440,7 +476,7 @@ func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullR
 			EventoID:        ev.ID,
 			Nombre:          perfil.Label,
 			Estado:          1,
-			UsuarioCreacion: &usuario,
+			UsuarioCreacion: &usuarioID,
 			FechaCreacion:   now,
 		}
 		if err := tx.Create(perfilModel).Error; err != nil {
@@ -460,7 +496,7 @@ func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullR
 			TotalEntradas:   sector.Capacidad,
 			CantVendidas:    0,
 			Estado:          1,
-			UsuarioCreacion: &usuario,
+			UsuarioCreacion: &usuarioID,
 			FechaCreacion:   now,
 		}
 		if err := tx.Create(sectorModel).Error; err != nil {
@@ -482,7 +518,7 @@ func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullR
 			FechaIni:        fechaIni,
 			FechaFin:        fechaFin,
 			Estado:          1,
-			UsuarioCreacion: &usuario,
+			UsuarioCreacion: &usuarioID,
 			FechaCreacion:   now,
 		}
 		if err := tx.Create(tipoTicketModel).Error; err != nil {
@@ -524,7 +560,7 @@ func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullR
 					PerfilDePersonaID: &perfilDBID,
 					Precio:            precio,
 					Estado:            1,
-					UsuarioCreacion:   &usuario,
+					UsuarioCreacion:   &usuarioID,
 					FechaCreacion:     now,
 				}
 				if err := tx.Create(tarifaModel).Error; err != nil {
@@ -575,7 +611,7 @@ func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullR
 			FechaID:         fechaModel.ID,
 			HoraInicio:      horaInicioFull,
 			Estado:          1,
-			UsuarioCreacion: &usuario,
+			UsuarioCreacion: &usuarioID,
 			FechaCreacion:   now,
 		}
 		if err := tx.Create(eventoFechaModel).Error; err != nil {
@@ -633,6 +669,7 @@ func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullR
 		IdEvento:          ev.ID,
 		IdOrganizador:     ev.OrganizadorID,
 		IdCategoria:       ev.CategoriaID,
+		IdRecinto:         ev.RecintoID,
 		Titulo:            ev.Titulo,
 		Descripcion:       ev.Descripcion,
 		Lugar:             ev.Lugar,
@@ -660,118 +697,95 @@ func (e *Evento) EditarEventoFull(eventoID int64, req *schemas.EditarEventoFullR
 	return response, nil
 }
 
-// FetchPostgresqlEventos retrieves the upcoming events without filters
-func (e *Evento) FetchPostgresqlEventos() (*schemas.EventosPaginados, *errors.Error) {
-	eventos, err := e.DaoPostgresql.Evento.ObtenerEventosDisponiblesSinFiltros()
+// FetchPostgresqlEventos retrieves a page of the events without filters
+func (e *Evento) FetchPostgresqlEventos(req *schemas.PaginaEventosRequest) (*schemas.EventosPaginados, *errors.Error) {
+	pagina, perr := paginaEventos(req, daoPostgresql.OrdenEventosFecha, nil)
+	if perr != nil {
+		return nil, perr
+	}
+	listado, err := e.DaoPostgresql.Evento.ObtenerEventosDisponiblesSinFiltros(pagina)
 	if err != nil {
 		e.logger.Errorf("Failed to fetch eventos: %v", err)
 		return nil, &errors.BadRequestError.EventoNotFound
 	}
 
-	mapEventDates(eventos)
-
-	total := int64(len(eventos))
-	totalPaginas := 0
-	if total > 0 {
-		totalPaginas = 1
-	}
-
-	return &schemas.EventosPaginados{
-		Eventos:      eventos,
-		Total:        total,
-		PaginaActual: 1,
-		TotalPaginas: totalPaginas,
-	}, nil
+	return eventosPaginados(listado, pagina), nil
 }
 
-// FetchPostgresqlEventosFeed obtiene los eventos para el feed de recomendaciones
-func (e *Evento) FetchPostgresqlEventosFeed(usuarioId *int64) (*schemas.EventosPaginados, *errors.Error) {
-	eventos, err := e.DaoPostgresql.Evento.ObtenerEventosParaElFeed(usuarioId)
+// FetchPostgresqlEventosFeed obtiene una página del feed de recomendaciones
+func (e *Evento) FetchPostgresqlEventosFeed(usuarioId *int64, req *schemas.PaginaEventosRequest) (*schemas.EventosPaginados, *errors.Error) {
+	pagina, perr := paginaEventos(req, daoPostgresql.OrdenEventosRelevancia, nil)
+	if perr != nil {
+		return nil, perr
+	}
+	listado, err := e.DaoPostgresql.Evento.ObtenerEventosParaElFeed(usuarioId, pagina)
 	if err != nil {
 		e.logger.Errorf("Failed to fetch eventos: %v", err)
 		return nil, &errors.BadRequestError.EventoNotFound
 	}
 
-	mapEventDates(eventos)
-
-	total := int64(len(eventos))
-	totalPaginas := 0
-	if total > 0 {
-		totalPaginas = 1
-	}
-
-	return &schemas.EventosPaginados{
-		Eventos:      eventos,
-		Total:        total,
-		PaginaActual: 1,
-		TotalPaginas: totalPaginas,
-	}, nil
+	return eventosPaginados(listado, pagina), nil
 }
-func (e *Evento) FetchPostgresqlEventosConInteraccionesFeed(usuarioId *int64) (*schemas.EventosPaginados, *errors.Error) {
-	eventos, err := e.DaoPostgresql.Evento.CargarEventosNuevamenteParaElFeed(usuarioId)
+func (e *Evento) FetchPostgresqlEventosConInteraccionesFeed(usuarioId *int64, req *schemas.PaginaEventosRequest) (*schemas.EventosPaginados, *errors.Error) {
+	pagina, perr := paginaEventos(req, daoPostgresql.OrdenEventosRelevancia, nil)
+	if perr != nil {
+		return nil, perr
+	}
+	listado, err := e.DaoPostgresql.Evento.CargarEventosNuevamenteParaElFeed(usuarioId, pagina)
 	if err != nil {
 		e.logger.Errorf("Failed to fetch eventos: %v", err)
 		return nil, &errors.BadRequestError.EventoNotFound
 	}
 
-	mapEventDates(eventos)
-
-	total := int64(len(eventos))
-	totalPaginas := 0
-	if total > 0 {
-		totalPaginas = 1
-	}
-
-	return &schemas.EventosPaginados{
-		Eventos:      eventos,
-		Total:        total,
-		PaginaActual: 1,
-		TotalPaginas: totalPaginas,
-	}, nil
+	return eventosPaginados(listado, pagina), nil
 }
 
-// FetchPostgresqlEventos retrieves the upcoming events with filters
+// FetchPostgresqlEventos retrieves a page of the events with filters
 func (e *Evento) FetchPostgresqlEventosWithFilters(
 	categoriaID *int64,
 	organizadorID *int64,
 	titulo *string,
 	descripcion *string,
 	lugar *string,
+	recintoID *int64,
+	cercaniaReq *schemas.CercaniaRequest,
 	fecha *time.Time,
 	horaInicio *time.Time,
 	estado *int16,
-	soloFuturos bool) (*schemas.EventosPaginados, *errors.Error) {
-	eventos, err := e.DaoPostgresql.Evento.ObtenerEventosDisponiblesConFiltros(
+	soloFuturos bool,
+	req *schemas.PaginaEventosRequest) (*schemas.EventosPaginados, *errors.Error) {
+	cercania, cerr := cercaniaDesdeRequest(cercaniaReq)
+	if cerr != nil {
+		return nil, cerr
+	}
+	var origen *daoPostgresql.PuntoGeografico
+	if cercania != nil {
+		origen = &cercania.Punto
+	}
+	pagina, perr := paginaEventos(req, daoPostgresql.OrdenEventosFecha, origen)
+	if perr != nil {
+		return nil, perr
+	}
+	listado, err := e.DaoPostgresql.Evento.ObtenerEventosDisponiblesConFiltros(
 		categoriaID,
 		organizadorID,
 		titulo,
 		descripcion,
 		lugar,
+		recintoID,
+		cercania,
 		fecha,
 		horaInicio,
 		estado,
-		soloFuturos)
+		soloFuturos,
+		pagina)
 
 	if err != nil {
 		e.logger.Errorf("Failed to fetch eventos: %v", err)
 		return nil, &errors.BadRequestError.EventoNotFound
 	}
 
-	mapEventDates(eventos)
-
-	//Revisar esta lógica
-	total := int64(len(eventos))
-	totalPaginas := 0
-	if total > 0 {
-		totalPaginas = 1
-	}
-
-	return &schemas.EventosPaginados{
-		Eventos:      eventos,
-		Total:        total,
-		PaginaActual: 1,
-		TotalPaginas: totalPaginas,
-	}, nil
+	return eventosPaginados(listado, pagina), nil
 }
 
 // mapEventDates rellena el slice EventDates con fechas formateadas para la respuesta JSON.
@@ -808,6 +822,7 @@ func (e *Evento) GetPostgresqlEventoById(eventoID int64) (*schemas.EventoRespons
 		Preload("Perfiles").
 		Preload("Sectores").
 		Preload("TiposTicket").
+		Preload("Fechas", "estado = ?", util.Activo.Codigo()).
 		Preload("Fechas.Fecha").
 		First(&eventoModel, eventoID)
 
@@ -898,6 +913,7 @@ func (e *Evento) GetPostgresqlEventoById(eventoID int64) (*schemas.EventoRespons
 		IdEvento:          eventoModel.ID,
 		IdOrganizador:     eventoModel.OrganizadorID,
 		IdCategoria:       eventoModel.CategoriaID,
+		IdRecinto:         eventoModel.RecintoID,
 		Titulo:            eventoModel.Titulo,
 		Descripcion:       eventoModel.Descripcion,
 		Lugar:             eventoModel.Lugar,
@@ -982,6 +998,20 @@ func (e *Evento) GetPostgresqlReporteEventosOrganizador(
 		return nil, &errors.BadRequestError.EventoNotFound
 	}
 
+	// Reprogramaciones de todas las fechas de los eventos, por fecha original
+	eventoIDs := make([]int64, 0, len(eventos))
+	for _, ev := range eventos {
+		eventoIDs = append(eventoIDs, ev.ID)
+	}
+	resumenes, err := e.DaoPostgresql.Reprogramacion.ResumenDeEventos(eventoIDs)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	reprogramaciones := make(map[int64]daoPostgresql.ResumenReprogramacion, len(resumenes))
+	for _, r := range resumenes {
+		reprogramaciones[r.EventoFechaOrigenID] = r
+	}
+
 	reportes := make([]schemas.EventoOrganizadorReporte, 0, len(eventos))
 
 	for _, ev := range eventos {
@@ -1019,12 +1049,31 @@ func (e *Evento) GetPostgresqlReporteEventosOrganizador(
 				horaInicio = f.HoraInicio.Format("15:04")
 			}
 
-			fechas = append(fechas, schemas.EventoFechaOrganizadorReporte{
+			fecha := schemas.EventoFechaOrganizadorReporte{
 				IdFechaEvento: f.ID,
 				Fecha:         fechaStr,
 				HoraInicio:    horaInicio,
 				HoraFin:       "",
-			})
+				Estado:        "ACTIVA",
+			}
+			if f.Estado != util.Activo.Codigo() {
+				fecha.Estado = "INACTIVA"
+			}
+			if r, ok := reprogramaciones[f.ID]; ok {
+				fecha.Estado = "REPROGRAMADA"
+				fecha.Reprogramacion = &schemas.ReprogramacionFechaReporte{
+					IdReprogramacion:   r.ReprogramacionID,
+					IdFechaEventoNueva: r.EventoFechaDestinoID,
+					FechaLimite:        r.FechaLimite.Format(time.RFC3339),
+					Abierta:            r.FechaCierre == nil,
+					TicketsMigrados:    r.TicketsMigrados,
+					Pendientes:         r.Pendientes,
+					Aceptadas:          r.Aceptadas,
+					Reembolsadas:       r.Reembolsadas,
+					Vencidas:           r.Vencidas,
+				}
+			}
+			fechas = append(fechas, fecha)
 		}
 
 		estado := deriveEstadoEventoOrganizador(ev.EventoEstado, capacidadEvento, ticketsVendidos)
@@ -1053,8 +1102,9 @@ func (e *Evento) GetPostgresqlReporteEventosOrganizador(
 }
 
 func deriveEstadoEventoOrganizador(eventoEstado int16, capacidad int64, ticketsVendidos int64) string {
-	if eventoEstado == convert.MapEstadoToInt16("CANCELADO") {
-		return "CANCELADO"
+	switch eventoEstado {
+	case convert.MapEstadoToInt16("CANCELADO"), convert.MapEstadoToInt16("FINALIZADO"), convert.MapEstadoToInt16("POSTERGADO"):
+		return convert.MapEstadoToString(eventoEstado)
 	}
 
 	if capacidad > 0 && ticketsVendidos >= capacidad {
@@ -1144,6 +1194,14 @@ func (e *Evento) GetPostgresqlEventoDetalle(eventoId int64) (*schemas.EventoDeta
 		e.logger.Errorf("Failed to get evento detallado: %v", err)
 		return nil, &errors.BadRequestError.EventoNotFound
 	}
+	if eventoDetalle.IdRecinto != nil {
+		recinto, err := e.DaoPostgresql.Recinto.ObtenerRecintoPorId(*eventoDetalle.IdRecinto)
+		if err != nil {
+			e.logger.Errorf("GetPostgresqlEventoDetalle recinto=%d: %v", *eventoDetalle.IdRecinto, err)
+			return nil, &errors.InternalServerError.Default
+		}
+		eventoDetalle.Recinto = recintoResponse(recinto, nil)
+	}
 
 	return eventoDetalle, nil
 }
@@ -1154,14 +1212,20 @@ func (e *Evento) GetPostgresqlEventoDetalle(eventoId int64) (*schemas.EventoDeta
 // - Sectores
 // - Perfiles de persona
 // - Tipos de ticket
-// y devuelve el detalle actualizado del evento.
+// y devuelve el detalle actualizado del evento. Solo puede hacerlo su organizador o un
+// administrador, que queda como quien lo modificó.
 func (e *Evento) EditarEvento(
 	req *schemas.EditarEventoRequest,
+	usuario *model.Usuario,
 ) (*schemas.EventoDetalleDTO, *errors.Error) {
 
 	if req.IdEvento <= 0 {
 		return nil, &errors.BadRequestError.InvalidIDParam
 	}
+	if ferr := e.verificarOrganizadorDelEvento(req.IdEvento, usuario); ferr != nil {
+		return nil, ferr
+	}
+	req.UsuarioModificacion = usuario.ID
 
 	now := time.Now()
 	userID := req.UsuarioModificacion
@@ -1206,7 +1270,7 @@ func (e *Evento) EditarEvento(
 		}
 	}
 
-	// Estado workflow (borrador/publicado/finalizado)
+	// Estado workflow (solo transiciones permitidas; cancelar dispara la cascada)
 	if req.NuevoEstadoWorkflow != nil {
 		_, err := e.DaoPostgresql.Evento.ActualizarEstadoWorkflowEvento(
 			req.IdEvento,
@@ -1215,6 +1279,12 @@ func (e *Evento) EditarEvento(
 			&now,
 		)
 		if err != nil {
+			switch err {
+			case daoPostgresql.ErrTransicionEventoInvalida:
+				return nil, &errors.ConflictError.InvalidEventTransition
+			case gorm.ErrRecordNotFound:
+				return nil, &errors.ObjectNotFoundError.EventoNotFound
+			}
 			e.logger.Errorf("EditarEvento.ActualizarEstadoWorkflowEvento(%d): %v", req.IdEvento, err)
 			return nil, &errors.InternalServerError.Default
 		}
@@ -1248,6 +1318,9 @@ func (e *Evento) EditarEvento(
 				&userID,
 				&now,
 			); err != nil {
+				if err == daoPostgresql.ErrFechaConTicketsVendidos {
+					return nil, &errors.ConflictError.DateHasSoldTickets
+				}
 				e.logger.Errorf("EditarEvento.ActualizarFechaCalendario(fecha_id=%d): %v", *f.IdFecha, err)
 				return nil, &errors.InternalServerError.Default
 			}
@@ -1266,6 +1339,9 @@ func (e *Evento) EditarEvento(
 				&userID,
 				&now,
 			); err != nil {
+				if err == daoPostgresql.ErrFechaConTicketsVendidos {
+					return nil, &errors.ConflictError.DateHasSoldTickets
+				}
 				e.logger.Errorf("EditarEvento.ActualizarHoraInicioEventoFecha(evento_fecha_id=%d): %v", f.IdFechaEvento, err)
 				return nil, &errors.InternalServerError.Default
 			}
@@ -1279,6 +1355,9 @@ func (e *Evento) EditarEvento(
 				&userID,
 				&now,
 			); err != nil {
+				if err == daoPostgresql.ErrFechaConTicketsVendidos {
+					return nil, &errors.ConflictError.DateHasSoldTickets
+				}
 				e.logger.Errorf("EditarEvento.ReasignarFechaDeEventoFecha(evento_fecha_id=%d, nuevo_fecha_id=%d): %v",
 					f.IdFechaEvento, *f.NuevoFechaID, err)
 				return nil, &errors.InternalServerError.Default
@@ -0,0 +1,361 @@
+package adapter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/internal/schemas"
+	"gorm.io/gorm"
+)
+
+const largoMaximoPuerta = 60
+
+// Las fechas y horas de inicio de los eventos se guardan como hora local de Perú (UTC-5, sin
+// horario de verano).
+var zonaHorariaEventos = time.FixedZone("America/Lima", -5*60*60)
+
+// VentanaIngreso es cuánto antes y cuánto después de la hora de inicio de una fecha se deja
+// pasar gente por la puerta.
+type VentanaIngreso struct {
+	AntesDelInicio   time.Duration
+	DespuesDelInicio time.Duration
+}
+
+// inicioEventoFecha combina el día (fecha) y la hora de inicio (evento_fecha) de una función.
+func inicioEventoFecha(ef *model.EventoFecha) time.Time {
+	dia := ef.Fecha.FechaEvento
+	hora := ef.HoraInicio
+	return time.Date(dia.Year(), dia.Month(), dia.Day(), hora.Hour(), hora.Minute(), 0, 0, zonaHorariaEventos)
+}
+
+// limites devuelve cuándo abren y cierran las puertas para una fecha del evento.
+func (v VentanaIngreso) limites(ef *model.EventoFecha) (time.Time, time.Time) {
+	inicio := inicioEventoFecha(ef)
+	return inicio.Add(-v.AntesDelInicio), inicio.Add(v.DespuesDelInicio)
+}
+
+// motivoRechazoEstado traduce el estado de un ticket que no se puede admitir a su error.
+func motivoRechazoEstado(estado int16) *errors.Error {
+	switch util.EstadoDeTicket(estado) {
+	case util.TicketUsado:
+		return &errors.ConflictError.TicketAlreadyUsed
+	case util.TicketCancelado:
+		return &errors.ConflictError.TicketCancelled
+	default:
+		return &errors.ConflictError.TicketNotSold
+	}
+}
+
+// validarTicketEscaneado comprueba la firma del QR y que el ticket sea del evento (y de la fecha,
+// si eventoFechaID > 0) indicados. Devuelve el ticket con su fecha, evento y sector.
+func (t *Ticket) validarTicketEscaneado(codigoQR string, eventoID int64, eventoFechaID int64) (*model.Ticket, *errors.Error) {
+	// La firma se valida antes de tocar la BD: un QR falsificado no llega a consultar nada
+	contenido, err := t.verificadorQR.Verificar(codigoQR)
+	if err != nil {
+		return nil, &errors.UnprocessableEntityError.InvalidTicketQR
+	}
+
+	ticket, err := t.DaoPostgresql.Ticket.ObtenerParaIngreso(contenido.TicketID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.TicketNotFound
+		}
+		t.logger.Errorf("validarTicketEscaneado.ObtenerTicket(%d): %v", contenido.TicketID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	// El QR debe ser el vigente del ticket (no uno anterior a una reemisión)
+	if ticket.CodigoQR != codigoQR || ticket.EventoFechaID != contenido.EventoFechaID {
+		return nil, &errors.UnprocessableEntityError.InvalidTicketQR
+	}
+
+	eventoFecha := ticket.EventoFecha
+	if eventoFecha == nil || eventoFecha.Evento == nil || eventoFecha.Fecha == nil {
+		t.logger.Errorf("validarTicketEscaneado: ticket %d sin evento_fecha completo", ticket.ID)
+		return nil, &errors.InternalServerError.Default
+	}
+	if eventoFecha.Evento.ID != eventoID {
+		return nil, &errors.UnprocessableEntityError.TicketWrongEvent
+	}
+	if eventoFechaID > 0 && eventoFecha.ID != eventoFechaID {
+		return nil, &errors.UnprocessableEntityError.TicketWrongDate
+	}
+	return ticket, nil
+}
+
+// RegistrarIngreso valida un QR escaneado en la puerta y, si corresponde, marca el ticket como
+// USADO dejando registro de la puerta, el operador y la hora. Cada rechazo devuelve un error
+// con el motivo concreto (QR inválido, evento o fecha equivocados, puertas cerradas, ticket
+// ya usado o cancelado).
+func (t *Ticket) RegistrarIngreso(
+	req *schemas.CheckInRequest,
+	operador *model.Usuario,
+	ahora time.Time,
+) (*schemas.CheckInResponse, *errors.Error) {
+	puerta := strings.TrimSpace(req.Puerta)
+	if req.CodigoQR == "" || req.IdEvento <= 0 || puerta == "" || len(puerta) > largoMaximoPuerta {
+		return nil, &errors.UnprocessableEntityError.InvalidRequestBody
+	}
+
+	// 1) QR, evento y fecha
+	ticket, ferr := t.validarTicketEscaneado(req.CodigoQR, req.IdEvento, req.IdFechaEvento)
+	if ferr != nil {
+		t.logger.Warnf("RegistrarIngreso: QR rechazado en puerta %q: %s", puerta, ferr.Code)
+		return nil, ferr
+	}
+	eventoFecha := ticket.EventoFecha
+	evento := eventoFecha.Evento
+	if !operador.TieneAlgunRol(model.RolAdministrador) && evento.OrganizadorID != operador.ID {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+
+	// 2) Estado del ticket
+	if ticket.EstadoDeTicket != util.TicketVendido.Codigo() {
+		return nil, motivoRechazoEstado(ticket.EstadoDeTicket)
+	}
+
+	// 3) La fecha debe estar activa y con las puertas abiertas
+	if eventoFecha.Estado != util.Activo.Codigo() ||
+		evento.Estado != util.Activo.Codigo() ||
+		evento.EventoEstado != util.EventoPublicado.Codigo() {
+		return nil, &errors.ConflictError.DoorsClosed
+	}
+	abren, cierran := t.ventanaIngreso.limites(eventoFecha)
+	if ahora.Before(abren) || ahora.After(cierran) {
+		// Si el ticket ni siquiera es de hoy, es una fecha equivocada y no un tema de horario
+		if inicioEventoFecha(eventoFecha).Format(time.DateOnly) != ahora.In(zonaHorariaEventos).Format(time.DateOnly) {
+			return nil, &errors.UnprocessableEntityError.TicketWrongDate
+		}
+		return nil, &errors.ConflictError.DoorsClosed
+	}
+
+	// 4) VENDIDO -> USADO de forma atómica junto con el registro de ingreso
+	ingreso := &model.RegistroIngreso{
+		TicketID:      ticket.ID,
+		EventoFechaID: eventoFecha.ID,
+		Puerta:        puerta,
+		OperadorID:    operador.ID,
+		FechaIngreso:  ahora,
+	}
+	registrado, err := t.DaoPostgresql.RegistroIngreso.Registrar(ingreso)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	if !registrado {
+		// Otra puerta ganó la carrera (o el ticket se canceló entre la lectura y el UPDATE)
+		actual, err := t.DaoPostgresql.Ticket.ObtenerParaIngreso(ticket.ID)
+		if err != nil {
+			t.logger.Errorf("RegistrarIngreso.RecargarTicket(%d): %v", ticket.ID, err)
+			return nil, &errors.InternalServerError.Default
+		}
+		return nil, motivoRechazoEstado(actual.EstadoDeTicket)
+	}
+
+	sector := ""
+	if ticket.Tarifa != nil && ticket.Tarifa.Sector != nil {
+		sector = ticket.Tarifa.Sector.SectorTipo
+	}
+
+	t.logger.Infof("Ingreso registrado: ticket %d por puerta %q (operador %d)", ticket.ID, puerta, operador.ID)
+
+	return &schemas.CheckInResponse{
+		IdTicket:      ticket.ID,
+		Estado:        util.TicketUsado.String(),
+		IdEvento:      evento.ID,
+		IdFechaEvento: eventoFecha.ID,
+		Sector:        sector,
+		Puerta:        puerta,
+		IdOperador:    operador.ID,
+		FechaIngreso:  ahora.Format(time.RFC3339),
+	}, nil
+}
+
+const (
+	maxEscaneosPorLote     = 1000
+	largoMaximoDispositivo = 100
+	toleranciaRelojOffline = 5 * time.Minute // adelanto máximo aceptado en el reloj del escáner
+	veredictoAdmitido      = "ADMITIDO"
+	veredictoDuplicado     = "DUPLICADO"
+	veredictoRechazado     = "RECHAZADO"
+)
+
+// hashCodigoQR es la clave con la que el escáner busca un QR en el manifiesto.
+func hashCodigoQR(codigoQR string) string {
+	suma := sha256.Sum256([]byte(codigoQR))
+	return hex.EncodeToString(suma[:])
+}
+
+// obtenerFechaParaOperador carga la fecha del evento y verifica que el operador pueda operar
+// sus puertas (organizador dueño del evento o administrador).
+func (t *Ticket) obtenerFechaParaOperador(eventoFechaID int64, operador *model.Usuario) (*model.EventoFecha, *errors.Error) {
+	eventoFecha, err := t.DaoPostgresql.EventoFecha.ObtenerConEventoYFecha(eventoFechaID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.EventoNotFound
+		}
+		t.logger.Errorf("obtenerFechaParaOperador(%d): %v", eventoFechaID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if eventoFecha.Evento == nil || eventoFecha.Fecha == nil {
+		t.logger.Errorf("obtenerFechaParaOperador: evento_fecha %d sin evento o fecha", eventoFechaID)
+		return nil, &errors.InternalServerError.Default
+	}
+	if !operador.TieneAlgunRol(model.RolAdministrador) && eventoFecha.Evento.OrganizadorID != operador.ID {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+	return eventoFecha, nil
+}
+
+// ManifiestoIngreso arma lo que un escáner descarga antes de quedarse sin conexión: el hash y
+// el estado de cada ticket emitido para la fecha, la ventana de ingreso y las claves para
+// validar la firma de los QR.
+func (t *Ticket) ManifiestoIngreso(
+	eventoFechaID int64,
+	operador *model.Usuario,
+	ahora time.Time,
+) (*schemas.ManifiestoIngresoResponse, *errors.Error) {
+	if eventoFechaID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	eventoFecha, ferr := t.obtenerFechaParaOperador(eventoFechaID, operador)
+	if ferr != nil {
+		return nil, ferr
+	}
+
+	rows, err := t.DaoPostgresql.Ticket.ListarManifiesto(eventoFechaID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+
+	tickets := make([]schemas.TicketManifiesto, 0, len(rows))
+	for _, row := range rows {
+		tickets = append(tickets, schemas.TicketManifiesto{
+			HashQR:   hashCodigoQR(row.CodigoQR),
+			IdTicket: row.ID,
+			Estado:   util.EstadoDeTicket(row.Estado).String(),
+			Sector:   row.SectorTipo,
+		})
+	}
+
+	abren, cierran := t.ventanaIngreso.limites(eventoFecha)
+	return &schemas.ManifiestoIngresoResponse{
+		IdEvento:       eventoFecha.EventoID,
+		IdFechaEvento:  eventoFecha.ID,
+		GeneradoEn:     ahora.Format(time.RFC3339),
+		PuertasAbren:   abren.Format(time.RFC3339),
+		PuertasCierran: cierran.Format(time.RFC3339),
+		Claves:         *t.ClavesQR(),
+		Tickets:        tickets,
+	}, nil
+}
+
+// SincronizarIngresos concilia un lote de escaneos hechos sin conexión. Los escaneos se
+// procesan del más antiguo al más reciente según la hora del dispositivo; si un ticket se
+// escaneó en varios dispositivos gana el escaneo más temprano, aunque llegue en un lote
+// posterior. Devuelve un veredicto por escaneo en el orden del request.
+func (t *Ticket) SincronizarIngresos(
+	req *schemas.SincronizarIngresosRequest,
+	operador *model.Usuario,
+	ahora time.Time,
+) (*schemas.SincronizarIngresosResponse, *errors.Error) {
+	dispositivo := strings.TrimSpace(req.Dispositivo)
+	if req.IdFechaEvento <= 0 || dispositivo == "" || len(dispositivo) > largoMaximoDispositivo ||
+		len(req.Escaneos) == 0 || len(req.Escaneos) > maxEscaneosPorLote {
+		return nil, &errors.UnprocessableEntityError.InvalidRequestBody
+	}
+	eventoFecha, ferr := t.obtenerFechaParaOperador(req.IdFechaEvento, operador)
+	if ferr != nil {
+		return nil, ferr
+	}
+
+	veredictos := make([]schemas.VeredictoEscaneo, len(req.Escaneos))
+	horas := make([]time.Time, len(req.Escaneos))
+	var orden []int
+	for i, escaneo := range req.Escaneos {
+		veredictos[i].Indice = i
+		hora, err := time.Parse(time.RFC3339Nano, escaneo.EscaneadoEn)
+		if err != nil || hora.After(ahora.Add(toleranciaRelojOffline)) {
+			rechazarEscaneo(&veredictos[i], &errors.UnprocessableEntityError.InvalidScanTime)
+			continue
+		}
+		// Postgres guarda microsegundos: se trunca para que un reenvío compare igual
+		horas[i] = hora.Truncate(time.Microsecond)
+		orden = append(orden, i)
+	}
+	sort.SliceStable(orden, func(a, b int) bool { return horas[orden[a]].Before(horas[orden[b]]) })
+
+	for _, i := range orden {
+		escaneo := req.Escaneos[i]
+		ticket, ferr := t.validarTicketEscaneado(escaneo.CodigoQR, eventoFecha.EventoID, eventoFecha.ID)
+		if ferr != nil {
+			rechazarEscaneo(&veredictos[i], ferr)
+			continue
+		}
+		veredictos[i].IdTicket = ticket.ID
+
+		puerta := strings.TrimSpace(escaneo.Puerta)
+		if puerta == "" || len(puerta) > largoMaximoPuerta {
+			puerta = dispositivo
+		}
+		resultado, err := t.DaoPostgresql.RegistroIngreso.RegistrarOffline(&model.RegistroIngreso{
+			TicketID:      ticket.ID,
+			EventoFechaID: eventoFecha.ID,
+			Puerta:        puerta,
+			OperadorID:    operador.ID,
+			Dispositivo:   &dispositivo,
+			FechaIngreso:  horas[i],
+		})
+		if err != nil {
+			rechazarEscaneo(&veredictos[i], &errors.InternalServerError.Default)
+			continue
+		}
+
+		if resultado.Vigente != nil {
+			veredictos[i].IngresoVigente = &schemas.IngresoVigente{
+				Puerta:       resultado.Vigente.Puerta,
+				Dispositivo:  resultado.Vigente.Dispositivo,
+				FechaIngreso: resultado.Vigente.FechaIngreso.Format(time.RFC3339Nano),
+			}
+		}
+		switch {
+		case resultado.Admitido:
+			veredictos[i].Veredicto = veredictoAdmitido
+		case resultado.EstadoTicket == util.TicketUsado.Codigo():
+			veredictos[i].Veredicto = veredictoDuplicado
+			veredictos[i].Motivo = errors.ConflictError.TicketAlreadyUsed.Code
+		default:
+			rechazarEscaneo(&veredictos[i], motivoRechazoEstado(resultado.EstadoTicket))
+		}
+	}
+
+	resp := &schemas.SincronizarIngresosResponse{
+		IdFechaEvento: eventoFecha.ID,
+		Dispositivo:   dispositivo,
+		Veredictos:    veredictos,
+	}
+	for _, v := range veredictos {
+		switch v.Veredicto {
+		case veredictoAdmitido:
+			resp.Admitidos++
+		case veredictoDuplicado:
+			resp.Duplicados++
+		default:
+			resp.Rechazados++
+		}
+	}
+
+	t.logger.Infof("Sincronización de ingresos: fecha %d, dispositivo %q, %d admitidos, %d duplicados, %d rechazados",
+		eventoFecha.ID, dispositivo, resp.Admitidos, resp.Duplicados, resp.Rechazados)
+
+	return resp, nil
+}
+
+func rechazarEscaneo(v *schemas.VeredictoEscaneo, motivo *errors.Error) {
+	v.Veredicto = veredictoRechazado
+	v.Motivo = motivo.Code
+}
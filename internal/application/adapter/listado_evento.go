@@ -0,0 +1,124 @@
+package adapter
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+)
+
+// Tamaño de página de los listados de eventos
+const (
+	limiteEventosPorDefecto = 20
+	limiteEventosMaximo     = 100
+)
+
+// codificarCursorEventos vuelve opaca la posición de un evento en un listado. El cursor lleva el
+// orden con el que se emitió, para rechazarlo si se usa con otro.
+func codificarCursorEventos(orden string, cursor daoPostgresql.CursorEventos) string {
+	crudo := orden + "|" + strconv.FormatFloat(cursor.Valor, 'g', -1, 64) + "|" + strconv.FormatInt(cursor.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(crudo))
+}
+
+// decodificarCursorEventos es la inversa de codificarCursorEventos.
+func decodificarCursorEventos(orden string, cursor string) (*daoPostgresql.CursorEventos, bool) {
+	crudo, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, false
+	}
+	partes := strings.Split(string(crudo), "|")
+	if len(partes) != 3 || partes[0] != orden {
+		return nil, false
+	}
+	valor, errValor := strconv.ParseFloat(partes[1], 64)
+	id, errID := strconv.ParseInt(partes[2], 10, 64)
+	if errValor != nil || errID != nil {
+		return nil, false
+	}
+	return &daoPostgresql.CursorEventos{Valor: valor, ID: id}, true
+}
+
+// paginaEventos valida la paginación pedida y completa el tamaño y el orden por defecto. El orden
+// por distancia solo vale en los listados con origen (búsqueda por cercanía).
+func paginaEventos(req *schemas.PaginaEventosRequest, ordenPorDefecto string, origen *daoPostgresql.PuntoGeografico) (daoPostgresql.PaginaEventos, *errors.Error) {
+	pagina := daoPostgresql.PaginaEventos{
+		Orden:  strings.ToLower(strings.TrimSpace(req.Orden)),
+		Limite: req.Limite,
+		Origen: origen,
+	}
+	if pagina.Orden == "" {
+		pagina.Orden = ordenPorDefecto
+	}
+	switch pagina.Orden {
+	case daoPostgresql.OrdenEventosFecha, daoPostgresql.OrdenEventosPopularidad,
+		daoPostgresql.OrdenEventosPrecio, daoPostgresql.OrdenEventosRelevancia:
+	case daoPostgresql.OrdenEventosDistancia:
+		if origen == nil {
+			return pagina, &errors.UnprocessableEntityError.InvalidPagination
+		}
+	default:
+		return pagina, &errors.UnprocessableEntityError.InvalidPagination
+	}
+	if pagina.Limite == 0 {
+		pagina.Limite = limiteEventosPorDefecto
+	}
+	if pagina.Limite < 1 || pagina.Limite > limiteEventosMaximo {
+		return pagina, &errors.UnprocessableEntityError.InvalidPagination
+	}
+	if req.Cursor != "" {
+		cursor, ok := decodificarCursorEventos(pagina.Orden, req.Cursor)
+		if !ok {
+			return pagina, &errors.UnprocessableEntityError.InvalidPaginationCursor
+		}
+		pagina.Despues = cursor
+	}
+	return pagina, nil
+}
+
+// eventosPaginados arma la respuesta de una página de un listado de eventos.
+func eventosPaginados(listado *daoPostgresql.ListadoEventos, pagina daoPostgresql.PaginaEventos) *schemas.EventosPaginados {
+	mapEventDates(listado.Eventos)
+
+	resp := &schemas.EventosPaginados{
+		Eventos:      make([]schemas.EventoResumen, 0, len(listado.Eventos)),
+		Total:        listado.Total,
+		TotalPaginas: int((listado.Total + int64(pagina.Limite) - 1) / int64(pagina.Limite)),
+		Limite:       pagina.Limite,
+		Orden:        pagina.Orden,
+	}
+	for _, ev := range listado.Eventos {
+		resumen := schemas.EventoResumen{
+			ID:             ev.ID,
+			OrganizadorID:  ev.OrganizadorID,
+			CategoriaID:    ev.CategoriaID,
+			RecintoID:      ev.RecintoID,
+			Titulo:         ev.Titulo,
+			Descripcion:    ev.Descripcion,
+			Lugar:          ev.Lugar,
+			EventoEstado:   ev.EventoEstado,
+			CantMeGusta:    ev.CantMeGusta,
+			CantNoInteresa: ev.CantNoInteresa,
+			ImagenPortada:  ev.ImagenPortada,
+			EventDates:     ev.EventDates,
+			Interaccion:    ev.Interaccion,
+		}
+		if precio, ok := listado.PreciosDesde[ev.ID]; ok {
+			resumen.PrecioDesde = &precio
+		}
+		if ev.Recinto != nil {
+			resumen.Recinto = recintoResponse(ev.Recinto, nil)
+		}
+		if distancia, ok := listado.Distancias[ev.ID]; ok {
+			resumen.DistanciaKm = &distancia
+		}
+		resp.Eventos = append(resp.Eventos, resumen)
+	}
+	if listado.Siguiente != nil {
+		cursor := codificarCursorEventos(pagina.Orden, *listado.Siguiente)
+		resp.SiguienteCursor = &cursor
+	}
+	return resp
+}
@@ -0,0 +1,541 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/notificaciones"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/pases"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	"github.com/Nexivent/nexivent-backend/internal/mailer"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+const (
+	// maxIntentosNotificacion es cuántas veces se intenta un envío (o la expansión de un evento)
+	// antes de pasarlo a dead letter.
+	maxIntentosNotificacion = 8
+	// plazoEnvioNotificacion es cuánto tiempo se reserva una fila mientras se procesa.
+	plazoEnvioNotificacion = 5 * time.Minute
+	// esperaMaximaNotificacion acota la espera entre reintentos.
+	esperaMaximaNotificacion = 6 * time.Hour
+	// limiteFallidas es cuántos dead letters de cada tipo muestra el panel de administración.
+	limiteFallidas = 100
+)
+
+// Notificacion despacha el outbox: convierte cada evento de dominio en notificaciones, una por
+// destinatario y canal, y las entrega por el Canal correspondiente.
+type Notificacion struct {
+	logger        logging.Logger
+	DaoPostgresql *daoPostgresql.NexiventPsqlEntidades
+	canales       map[string]notificaciones.Canal
+	webhookURL    string // vacío: no se publican webhooks
+}
+
+func NewNotificacionAdapter(
+	logger logging.Logger,
+	daoPostgresql *daoPostgresql.NexiventPsqlEntidades,
+	webhookURL string,
+	canales ...notificaciones.Canal,
+) *Notificacion {
+	porNombre := make(map[string]notificaciones.Canal, len(canales))
+	for _, c := range canales {
+		porNombre[c.Nombre()] = c
+	}
+	return &Notificacion{
+		logger:        logger,
+		DaoPostgresql: daoPostgresql,
+		canales:       porNombre,
+		webhookURL:    webhookURL,
+	}
+}
+
+// esperaReintento duplica la espera en cada intento fallido: 1, 2, 4, 8... minutos, hasta
+// esperaMaximaNotificacion.
+func esperaReintento(intentos int16) time.Duration {
+	espera := time.Minute
+	for i := int16(1); i < intentos && espera < esperaMaximaNotificacion; i++ {
+		espera *= 2
+	}
+	if espera > esperaMaximaNotificacion {
+		espera = esperaMaximaNotificacion
+	}
+	return espera
+}
+
+// proximoIntento devuelve cuándo reintentar tras el intento número intentos, o nil si ya se
+// agotaron y la fila pasa a dead letter.
+func proximoIntento(intentos int16, ahora time.Time) *time.Time {
+	if intentos >= maxIntentosNotificacion {
+		return nil
+	}
+	p := ahora.Add(esperaReintento(intentos))
+	return &p
+}
+
+// Despachar hace una pasada completa por el outbox: primero expande los eventos pendientes y
+// luego envía las notificaciones pendientes. Varios workers pueden llamarla a la vez; cada fila
+// la toma uno solo.
+func (a *Notificacion) Despachar(ctx context.Context, lote int) (int, *errors.Error) {
+	if _, err := a.ProcesarEventos(ctx, lote); err != nil {
+		return 0, err
+	}
+	return a.EnviarPendientes(ctx, lote)
+}
+
+// ProcesarEventos expande, en lotes de tamaño lote, los eventos de dominio PENDIENTES. Los que
+// fallan se reintentan con espera creciente y, agotados los intentos, quedan FALLIDOS.
+// Devuelve cuántos se procesaron.
+func (a *Notificacion) ProcesarEventos(ctx context.Context, lote int) (int, *errors.Error) {
+	procesados := 0
+	for ctx.Err() == nil {
+		eventos, err := a.DaoPostgresql.EventoDominio.ReclamarPendientes(time.Now(), plazoEnvioNotificacion, lote)
+		if err != nil {
+			return procesados, &errors.InternalServerError.Default
+		}
+
+		for i := range eventos {
+			ev := &eventos[i]
+			ahora := time.Now()
+			lista, errExp := a.notificacionesDe(ev, ahora)
+			if errExp == nil {
+				lista, errExp = a.aplicarPreferencias(ev.Tipo, lista)
+			}
+			if errExp == nil {
+				errExp = a.DaoPostgresql.EventoDominio.Expandir(ev.ID, lista, ahora)
+			}
+			if errExp != nil {
+				a.logger.Warnf("Evento de dominio %d (%s, intento %d) no procesado: %v", ev.ID, ev.Tipo, ev.Intentos+1, errExp)
+				_ = a.DaoPostgresql.EventoDominio.RegistrarFallo(ev.ID, errExp.Error(), proximoIntento(ev.Intentos+1, ahora))
+				continue
+			}
+			procesados++
+		}
+
+		if len(eventos) < lote {
+			break
+		}
+	}
+	return procesados, nil
+}
+
+// EnviarPendientes envía, en lotes de tamaño lote, las notificaciones PENDIENTES cuyo próximo
+// intento ya venció. Las que fallan se reprograman con espera creciente y, agotados los
+// intentos, quedan NO_ENVIADO. Devuelve cuántas se enviaron.
+func (a *Notificacion) EnviarPendientes(ctx context.Context, lote int) (int, *errors.Error) {
+	enviadas := 0
+	for ctx.Err() == nil {
+		pendientes, err := a.DaoPostgresql.Notificacion.ReclamarPendientes(time.Now(), plazoEnvioNotificacion, lote)
+		if err != nil {
+			return enviadas, &errors.InternalServerError.Default
+		}
+
+		for i := range pendientes {
+			n := &pendientes[i]
+			if errEnvio := a.enviar(ctx, n); errEnvio != nil {
+				intentos := n.Intentos + 1
+				a.logger.Warnf("Notificación %d (%s, intento %d) no enviada: %v", n.ID, n.Canal, intentos, errEnvio)
+				_ = a.DaoPostgresql.Notificacion.RegistrarFallo(n.ID, errEnvio.Error(), proximoIntento(intentos, time.Now()), time.Now())
+				continue
+			}
+			if err := a.DaoPostgresql.Notificacion.MarcarEnviada(n.ID, time.Now()); err == nil {
+				enviadas++
+			}
+		}
+
+		if len(pendientes) < lote {
+			break
+		}
+	}
+	return enviadas, nil
+}
+
+func (a *Notificacion) enviar(ctx context.Context, n *model.Notificacion) error {
+	canal, ok := a.canales[n.Canal]
+	if !ok {
+		return fmt.Errorf("canal %q no configurado", n.Canal)
+	}
+	m := &notificaciones.Mensaje{
+		ID:        n.ID,
+		Tipo:      n.Tipo,
+		UsuarioID: n.UsuarioID,
+		Destino:   n.Destino,
+		Asunto:    n.Asunto,
+		Texto:     n.Mensaje,
+		Plantilla: n.Plantilla,
+		Fecha:     n.FechaCreacion,
+	}
+	if n.EventoDominioID != nil {
+		ev, err := a.DaoPostgresql.EventoDominio.ObtenerPorID(*n.EventoDominioID)
+		if err != nil {
+			return fmt.Errorf("obtener evento de dominio: %w", err)
+		}
+		m.Tipo = ev.Tipo
+		m.Payload = json.RawMessage(ev.Payload)
+	}
+	if n.Canal == model.CanalCorreo && n.Plantilla == model.PlantillaOrdenConfirmada {
+		if n.OrdenDeCompraID == nil {
+			return fmt.Errorf("notificación sin orden")
+		}
+		datos, adjuntos, err := a.correoOrden(*n.OrdenDeCompraID)
+		if err != nil {
+			return err
+		}
+		m.Datos, m.Adjuntos = datos, adjuntos
+	}
+	return canal.Enviar(ctx, m)
+}
+
+// nuevaNotificacion arma una notificación PENDIENTE lista para enviarse ya.
+func nuevaNotificacion(canal string, usuarioID *int64, destino, asunto, texto string, ahora time.Time) model.Notificacion {
+	return model.Notificacion{
+		Mensaje:            texto,
+		Asunto:             asunto,
+		Canal:              canal,
+		Destino:            destino,
+		FechaEnvio:         ahora,
+		EstadoNotificacion: util.NotificacionPendiente.Codigo(),
+		UsuarioID:          usuarioID,
+		ProximoIntento:     &ahora,
+		FechaCreacion:      ahora,
+	}
+}
+
+// avisos arma, para cada destinatario, el correo y la notificación in-app, más un único webhook
+// por evento si hay URL configurada.
+func (a *Notificacion) avisos(destinatarios []daoPostgresql.TitularTicket, asunto, texto string, ahora time.Time) []model.Notificacion {
+	var lista []model.Notificacion
+	for _, d := range destinatarios {
+		id := d.UsuarioID
+		if d.Correo != "" {
+			lista = append(lista, nuevaNotificacion(model.CanalCorreo, &id, d.Correo, asunto, texto, ahora))
+		}
+		lista = append(lista, nuevaNotificacion(model.CanalInApp, &id, "", asunto, texto, ahora))
+	}
+	if a.webhookURL != "" {
+		lista = append(lista, nuevaNotificacion(model.CanalWebhook, nil, a.webhookURL, asunto, texto, ahora))
+	}
+	return lista
+}
+
+// aplicarPreferencias marca las notificaciones con el tipo del evento y descarta las de los
+// canales que cada destinatario apagó para ese tipo, salvo las obligatorias.
+func (a *Notificacion) aplicarPreferencias(tipo string, lista []model.Notificacion) ([]model.Notificacion, error) {
+	var usuarios []int64
+	for _, n := range lista {
+		if n.UsuarioID != nil {
+			usuarios = append(usuarios, *n.UsuarioID)
+		}
+	}
+	apagadas, err := a.DaoPostgresql.Preferencias.Deshabilitadas(tipo, usuarios)
+	if err != nil {
+		return nil, fmt.Errorf("obtener preferencias: %w", err)
+	}
+	filtrada := lista[:0]
+	for _, n := range lista {
+		if n.UsuarioID != nil && apagadas[*n.UsuarioID][n.Canal] && !model.PreferenciaObligatoria(tipo, n.Canal) {
+			continue
+		}
+		n.Tipo = tipo
+		filtrada = append(filtrada, n)
+	}
+	return filtrada, nil
+}
+
+// notificacionesDe decide quién se entera de un evento de dominio y por qué canales.
+func (a *Notificacion) notificacionesDe(ev *model.EventoDominio, ahora time.Time) ([]model.Notificacion, error) {
+	switch ev.Tipo {
+	case model.EventoOrdenConfirmada:
+		var p model.PayloadOrdenConfirmada
+		if err := json.Unmarshal([]byte(ev.Payload), &p); err != nil {
+			return nil, err
+		}
+		comprador, err := a.DaoPostgresql.Usuario.ObtenerUsuarioBasicoPorID(p.UsuarioID)
+		if err != nil {
+			return nil, fmt.Errorf("obtener comprador: %w", err)
+		}
+		lista := a.avisos([]daoPostgresql.TitularTicket{{UsuarioID: comprador.ID, Nombre: comprador.Nombre, Correo: comprador.Correo}},
+			fmt.Sprintf("Tu compra en Nexivent: orden #%d", p.OrdenDeCompraID),
+			fmt.Sprintf("Tu pago fue confirmado. Los tickets de la orden #%d ya están en tu cuenta.", p.OrdenDeCompraID),
+			ahora)
+		for i := range lista {
+			lista[i].OrdenDeCompraID = &p.OrdenDeCompraID
+			if lista[i].Canal == model.CanalCorreo {
+				lista[i].Plantilla = model.PlantillaOrdenConfirmada
+			}
+		}
+		return lista, nil
+
+	case model.EventoEventoCancelado:
+		var p model.PayloadEventoCancelado
+		if err := json.Unmarshal([]byte(ev.Payload), &p); err != nil {
+			return nil, err
+		}
+		// ObtenerEventoPorId solo encuentra eventos publicados
+		evento, err := a.DaoPostgresql.Evento.ObtenerEventoBasico(p.EventoID)
+		if err != nil {
+			return nil, fmt.Errorf("obtener evento: %w", err)
+		}
+		// Los asistentes se anotan al cancelar: para cuando se despacha el aviso los reembolsos
+		// ya pueden haber cancelado sus tickets
+		var destinatarios []daoPostgresql.TitularTicket
+		if len(p.Asistentes) > 0 {
+			destinatarios, err = a.DaoPostgresql.Usuario.ObtenerDestinatarios(p.Asistentes)
+		} else {
+			destinatarios, err = a.DaoPostgresql.Ticket.TitularesDeEvento(p.EventoID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return a.avisos(destinatarios,
+			fmt.Sprintf("Se canceló %s", evento.Titulo),
+			fmt.Sprintf("Lamentamos informarte que %s fue cancelado. Tus entradas ya no son válidas para el ingreso. Lo pagado por ellas, incluido el cargo por servicio, se devuelve automáticamente al medio de pago con que se compraron.", evento.Titulo),
+			ahora), nil
+
+	case model.EventoEventoReprogramado:
+		var p model.PayloadEventoReprogramado
+		if err := json.Unmarshal([]byte(ev.Payload), &p); err != nil {
+			return nil, err
+		}
+		ef, err := a.DaoPostgresql.EventoFecha.ObtenerConEventoYFecha(p.EventoFechaID)
+		if err != nil {
+			return nil, fmt.Errorf("obtener fecha del evento: %w", err)
+		}
+		titulares, err := a.DaoPostgresql.Ticket.TitularesDeEventoFecha(p.EventoFechaID)
+		if err != nil {
+			return nil, err
+		}
+		titulo := ""
+		if ef.Evento != nil {
+			titulo = ef.Evento.Titulo
+		}
+		return a.avisos(titulares,
+			fmt.Sprintf("Cambio de fecha: %s", titulo),
+			fmt.Sprintf("%s cambió de horario: ahora es el %s. Tus entradas siguen siendo válidas.",
+				titulo, inicioEventoFecha(ef).Format("02/01/2006 a las 15:04")),
+			ahora), nil
+
+	case model.EventoFechaReprogramada:
+		var p model.PayloadFechaReprogramada
+		if err := json.Unmarshal([]byte(ev.Payload), &p); err != nil {
+			return nil, err
+		}
+		reprogramacion, err := a.DaoPostgresql.Reprogramacion.ObtenerConFechas(p.ReprogramacionID)
+		if err != nil {
+			return nil, fmt.Errorf("obtener reprogramación: %w", err)
+		}
+		titulares, err := a.DaoPostgresql.Reprogramacion.TitularesPendientes(p.ReprogramacionID)
+		if err != nil {
+			return nil, err
+		}
+		titulo := ""
+		if reprogramacion.Destino.Evento != nil {
+			titulo = reprogramacion.Destino.Evento.Titulo
+		}
+		return a.avisos(titulares,
+			fmt.Sprintf("%s cambia de fecha: confirma tu asistencia", titulo),
+			fmt.Sprintf("%s, previsto para el %s, se reprogramó al %s y tus entradas ya tienen un código QR nuevo para esa fecha. "+
+				"Si no puedes asistir, pide el reembolso de lo pagado, incluido el cargo por servicio, hasta el %s desde Mis tickets. "+
+				"Si no respondes, tus entradas quedan confirmadas para la nueva fecha.",
+				titulo,
+				inicioEventoFecha(reprogramacion.Origen).Format("02/01/2006 a las 15:04"),
+				inicioEventoFecha(reprogramacion.Destino).Format("02/01/2006 a las 15:04"),
+				reprogramacion.FechaLimite.In(zonaHorariaEventos).Format("02/01/2006 a las 15:04")),
+			ahora), nil
+
+	case model.EventoTicketTransferido:
+		var p model.PayloadTicketTransferido
+		if err := json.Unmarshal([]byte(ev.Payload), &p); err != nil {
+			return nil, err
+		}
+		ticket, err := a.DaoPostgresql.Ticket.ObtenerParaPase(p.TicketID)
+		if err != nil {
+			return nil, fmt.Errorf("obtener ticket: %w", err)
+		}
+		para, err := a.DaoPostgresql.Usuario.ObtenerUsuarioBasicoPorID(p.ParaUsuarioID)
+		if err != nil {
+			return nil, fmt.Errorf("obtener destinatario: %w", err)
+		}
+		titulo := datosDePase(ticket).Evento
+		lista := a.avisos([]daoPostgresql.TitularTicket{{UsuarioID: para.ID, Nombre: para.Nombre, Correo: para.Correo}},
+			fmt.Sprintf("Recibiste un ticket para %s", titulo),
+			fmt.Sprintf("Ya tienes en tu cuenta el ticket #%d para %s, con un código QR nuevo.", p.TicketID, titulo),
+			ahora)
+		de := p.DeUsuarioID
+		lista = append(lista, nuevaNotificacion(model.CanalInApp, &de, "",
+			fmt.Sprintf("Transferiste tu ticket para %s", titulo),
+			fmt.Sprintf("%s aceptó tu ticket #%d para %s. El código QR anterior ya no es válido.", para.Nombre, p.TicketID, titulo),
+			ahora))
+		return lista, nil
+
+	default:
+		return nil, fmt.Errorf("tipo de evento %q no soportado", ev.Tipo)
+	}
+}
+
+// lineaCorreoOrden es una fila del resumen de la orden en el correo.
+type lineaCorreoOrden struct {
+	Evento   string
+	Fecha    string
+	Lugar    string
+	Sector   string
+	Perfil   string
+	Cantidad int
+}
+
+// correoOrden arma los datos del correo de confirmación: el resumen de la orden, los datos del
+// comprobante y, adjunto, el PDF de los tickets que el comprador todavía conserva.
+func (a *Notificacion) correoOrden(orderID int64) (map[string]any, []mailer.Adjunto, error) {
+	orden, err := a.DaoPostgresql.OrdenDeCompra.ObtenerOrdenBasica(orderID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("obtener orden: %w", err)
+	}
+	comprador, err := a.DaoPostgresql.Usuario.ObtenerUsuarioBasicoPorID(orden.UsuarioID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("obtener comprador: %w", err)
+	}
+	comprobante, err := a.DaoPostgresql.Comprobante.ObtenerPorOrden(orderID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("obtener comprobante: %w", err)
+	}
+	tickets, err := a.DaoPostgresql.Ticket.ObtenerParaPasesDeCompra(orderID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("obtener tickets: %w", err)
+	}
+
+	var lineas []lineaCorreoOrden
+	indice := map[string]int{}
+	var datos []pases.Datos
+	for i := range tickets {
+		d := datosDePase(&tickets[i])
+		clave := fmt.Sprintf("%d-%d", tickets[i].EventoFechaID, tickets[i].TarifaID)
+		if j, ok := indice[clave]; ok {
+			lineas[j].Cantidad++
+		} else {
+			indice[clave] = len(lineas)
+			lineas = append(lineas, lineaCorreoOrden{
+				Evento:   d.Evento,
+				Fecha:    d.Inicio.Format("02/01/2006 15:04"),
+				Lugar:    d.Lugar,
+				Sector:   d.Sector,
+				Perfil:   d.Perfil,
+				Cantidad: 1,
+			})
+		}
+		if titularID, _ := titularActual(&tickets[i]); titularID == orden.UsuarioID &&
+			tickets[i].EstadoDeTicket == util.TicketVendido.Codigo() {
+			datos = append(datos, d)
+		}
+	}
+
+	var adjuntos []mailer.Adjunto
+	if len(datos) > 0 {
+		pdf, err := pases.GenerarPDF(datos)
+		if err != nil {
+			return nil, nil, fmt.Errorf("generar PDF: %w", err)
+		}
+		adjuntos = append(adjuntos, mailer.Adjunto{
+			Nombre: fmt.Sprintf("tickets-orden-%d.pdf", orderID),
+			Tipo:   "application/pdf",
+			Datos:  pdf,
+		})
+	}
+
+	ruc := ""
+	if comprobante.RUC != nil {
+		ruc = *comprobante.RUC
+	}
+	return map[string]any{
+		"Nombre":            comprador.Nombre,
+		"OrdenID":           orden.ID,
+		"Fecha":             orden.Fecha.In(zonaHorariaEventos).Format("02/01/2006 15:04"),
+		"Lineas":            lineas,
+		"Subtotal":          fmt.Sprintf("%.2f", redondearCentimos(orden.Total-orden.MontoFeeServicio)),
+		"FeeServicio":       fmt.Sprintf("%.2f", orden.MontoFeeServicio),
+		"Total":             fmt.Sprintf("%.2f", orden.Total),
+		"TipoComprobante":   util.TipoComprobante(comprobante.TipoDeComprobante).String(),
+		"NumeroComprobante": comprobante.Numero,
+		"FechaEmision":      comprobante.FechaEmision.In(zonaHorariaEventos).Format("02/01/2006"),
+		"RUC":               ruc,
+		"CantidadTickets":   len(datos),
+	}, adjuntos, nil
+}
+
+// ListarFallidas devuelve los dead letters: notificaciones NO_ENVIADO y eventos FALLIDOS.
+func (a *Notificacion) ListarFallidas() (*schemas.NotificacionesFallidasResponse, *errors.Error) {
+	notifs, err := a.DaoPostgresql.Notificacion.ListarFallidas(limiteFallidas)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	eventos, err := a.DaoPostgresql.EventoDominio.ListarFallidos(limiteFallidas)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+
+	resp := &schemas.NotificacionesFallidasResponse{
+		Notificaciones: make([]schemas.NotificacionFallida, 0, len(notifs)),
+		Eventos:        make([]schemas.EventoDominioFallido, 0, len(eventos)),
+	}
+	for _, n := range notifs {
+		item := schemas.NotificacionFallida{
+			IdNotificacion:   n.ID,
+			IdEventoDominio:  n.EventoDominioID,
+			IdUsuario:        n.UsuarioID,
+			Canal:            n.Canal,
+			Destino:          n.Destino,
+			Asunto:           n.Asunto,
+			Intentos:         n.Intentos,
+			FechaCreacion:    n.FechaCreacion.Format(time.RFC3339),
+			FechaUltimoEnvio: n.FechaEnvio.Format(time.RFC3339),
+		}
+		if n.UltimoError != nil {
+			item.UltimoError = *n.UltimoError
+		}
+		resp.Notificaciones = append(resp.Notificaciones, item)
+	}
+	for _, ev := range eventos {
+		item := schemas.EventoDominioFallido{
+			IdEventoDominio: ev.ID,
+			Tipo:            ev.Tipo,
+			IdAgregado:      ev.AgregadoID,
+			Payload:         ev.Payload,
+			Intentos:        ev.Intentos,
+			FechaCreacion:   ev.FechaCreacion.Format(time.RFC3339),
+		}
+		if ev.UltimoError != nil {
+			item.UltimoError = *ev.UltimoError
+		}
+		resp.Eventos = append(resp.Eventos, item)
+	}
+	return resp, nil
+}
+
+// ReintentarNotificacion devuelve una notificación NO_ENVIADO a la cola.
+func (a *Notificacion) ReintentarNotificacion(id int64, ahora time.Time) *errors.Error {
+	ok, err := a.DaoPostgresql.Notificacion.Reintentar(id, ahora)
+	if err != nil {
+		return &errors.InternalServerError.Default
+	}
+	if !ok {
+		return &errors.ObjectNotFoundError.NotificacionFallidaNotFound
+	}
+	return nil
+}
+
+// ReintentarEventoDominio devuelve un evento FALLIDO a la cola.
+func (a *Notificacion) ReintentarEventoDominio(id int64, ahora time.Time) *errors.Error {
+	ok, err := a.DaoPostgresql.EventoDominio.Reintentar(id, ahora)
+	if err != nil {
+		return &errors.InternalServerError.Default
+	}
+	if !ok {
+		return &errors.ObjectNotFoundError.EventoDominioFallidoNotFound
+	}
+	return nil
+}
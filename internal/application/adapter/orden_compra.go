@@ -1,10 +1,13 @@
 package adapter
 
 import (
-	"fmt"
+	"context"
+	goerrors "errors"
 	"time"
 
 	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/pagos"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/qr"
 	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
 
@@ -17,23 +20,33 @@ import (
 
 const ttlReservaSegundos int64 = 600 // 10 minutos de hold
 
-type StockReservado struct {
-	SectorID int64
-	Cantidad int64
-}
+// porcentajeFeeServicio es la comisión de la plataforma; va incluida en el total de la orden
+// y se descuenta de la ganancia del organizador al confirmar.
+const porcentajeFeeServicio = 0.025
 
 type OrdenDeCompra struct {
 	logger        logging.Logger
 	DaoPostgresql *daoPostgresql.NexiventPsqlEntidades
+	pagos         pagos.Proveedores
+	firmanteQR    *qr.Firmante // re-firma los tickets comprados en reventa
+	// corteCancelacion es cuánto antes del inicio de una fecha deja el comprador de poder
+	// cancelar sus tickets
+	corteCancelacion time.Duration
 }
 
 func NewOrdenDeCompraAdapter(
 	logger logging.Logger,
 	daoPostgresql *daoPostgresql.NexiventPsqlEntidades,
+	proveedoresPago pagos.Proveedores,
+	firmanteQR *qr.Firmante,
+	corteCancelacion time.Duration,
 ) *OrdenDeCompra {
 	return &OrdenDeCompra{
-		logger:        logger,
-		DaoPostgresql: daoPostgresql,
+		logger:           logger,
+		DaoPostgresql:    daoPostgresql,
+		pagos:            proveedoresPago,
+		firmanteQR:       firmanteQR,
+		corteCancelacion: corteCancelacion,
 	}
 }
 
@@ -45,128 +58,83 @@ func (a *OrdenDeCompra) CrearSesionOrdenTemporal(
 		return nil, &errors.UnprocessableEntityError.InvalidRequestBody
 	}
 
-	// Validar que el Total sea mayor que 0
-	if req.Total <= 0 {
-		a.logger.Errorf("Total inválido: %.2f", req.Total)
-		return nil, &errors.UnprocessableEntityError.InvalidRequestBody
+	now := time.Now()
+	cot, errCot := a.cotizarOrden(req, now)
+	if errCot != nil {
+		return nil, errCot
 	}
 
-	// ============================================================================
-	// Verificar y reservar stock ANTES de crear la orden
-	// ============================================================================
-
-	stocksReservados := []StockReservado{}
-
-	// 1. Validar stock disponible para cada entrada
-	for _, entrada := range req.Entradas {
-		sectorID := entrada.IdSector
-
-		a.logger.Infof("Validando stock: Sector %d, Cantidad solicitada %d", sectorID, entrada.Cantidad)
-
-		// Verificar que haya stock disponible
-		var totalEntradas, cantVendidas int64
-		row := a.DaoPostgresql.OrdenDeCompra.PostgresqlDB.
-			Table("sector").
-			Select("total_Entradas, cant_vendidas").
-			Where("sector_id = ?", sectorID).
-			Row()
-
-		if err := row.Scan(&totalEntradas, &cantVendidas); err != nil {
-			a.logger.Errorf("Hold.ObtenerStockSector(sector=%d): %v", sectorID, err)
-			a.rollbackStockReservado(stocksReservados)
-			return nil, &errors.InternalServerError.Default
-		}
-
-		disponible := (cantVendidas + entrada.Cantidad) <= totalEntradas
-
-		if !disponible {
-			a.logger.Warnf("Stock insuficiente para sector %d (solicitado: %d, disponible: %d)",
-				sectorID, entrada.Cantidad, totalEntradas-cantVendidas)
-			a.rollbackStockReservado(stocksReservados)
-			return nil, &errors.BadRequestError.EventoNotFound
-		}
-
-		// Reservar stock (incrementar cant_vendidas)
-		res := a.DaoPostgresql.OrdenDeCompra.PostgresqlDB.
-			Table("sector").
-			Where("sector_id = ?", sectorID).
-			UpdateColumn("cant_vendidas", gorm.Expr("cant_vendidas + ?", entrada.Cantidad))
-
-		if res.Error != nil {
-			a.logger.Errorf("Hold.IncrementarVendidasPorSector(sector=%d): %v", sectorID, res.Error)
-			a.rollbackStockReservado(stocksReservados)
-			return nil, &errors.InternalServerError.Default
-		}
-
-		// Guardar para posible rollback
-		stocksReservados = append(stocksReservados, StockReservado{
-			SectorID: sectorID,
-			Cantidad: entrada.Cantidad,
-		})
-
-		a.logger.Infof("Stock reservado: Sector %d, Cantidad %d", sectorID, entrada.Cantidad)
+	// El precio lo define el backend; el total del cliente solo se usa para detectar desfases
+	if redondearCentimos(req.Total) != cot.Total {
+		a.logger.Warnf("CrearSesionOrdenTemporal: total del cliente %.2f no coincide con el calculado %.2f", req.Total, cot.Total)
+		return nil, &errors.UnprocessableEntityError.TotalMismatch
 	}
 
-	// ========================
-	// Crear la orden temporal
-	// ========================
-
-	now := time.Now()
 	expiresAt := now.Add(time.Duration(ttlReservaSegundos) * time.Second)
 
-	// Calcular fee de servicio: 2.5% del total
-	feeServicio := req.Total * 0.025
-
 	orden := &model.OrdenDeCompra{
 		UsuarioID:        req.IdUsuario,
 		Fecha:            now,
 		FechaHoraIni:     now,
 		FechaHoraFin:     &expiresAt,
-		Total:            req.Total,
-		MontoFeeServicio: feeServicio,
+		Total:            cot.Total,
+		MontoFeeServicio: cot.MontoFeeServicio,
 		EstadoDeOrden:    util.OrdenTemporal.Codigo(),
+		CuponID:          cot.CuponID,
+		Detalles:         cot.Detalles,
 	}
 
-	if err := a.DaoPostgresql.OrdenDeCompra.CrearOrdenTemporal(orden); err != nil {
+	// Reserva de stock, uso del cupón y creación de la orden y sus detalles en una sola transacción
+	if err := a.DaoPostgresql.OrdenDeCompra.CrearOrdenTemporalConReserva(orden); err != nil {
+		if goerrors.Is(err, daoPostgresql.ErrStockInsuficiente) {
+			a.logger.Warnf("CrearSesionOrdenTemporal: %v", err)
+			return nil, &errors.ConflictError.InsufficientStock
+		}
+		if goerrors.Is(err, daoPostgresql.ErrEventoNoALaVenta) {
+			a.logger.Warnf("CrearSesionOrdenTemporal: %v", err)
+			return nil, &errors.ConflictError.EventNotOnSale
+		}
+		if goerrors.Is(err, daoPostgresql.ErrCuponAgotado) {
+			return nil, &errors.BadRequestError.CantLimitUseCupon
+		}
 		a.logger.Errorf("CrearSesionOrdenTemporal: %v", err)
-		a.rollbackStockReservado(stocksReservados)
-		return nil, &errors.BadRequestError.EventoNotCreated
+		return nil, &errors.BadRequestError.OrdenNotCreated
 	}
 
 	a.logger.Infof("Orden temporal %d creada con stock reservado (Total: %.2f, Fee Servicio: %.2f)", orden.ID, orden.Total, orden.MontoFeeServicio)
 
 	resp := &schemas.CrearOrdenTemporalResponse{
-		OrderID:    orden.ID,
-		Estado:     "TEMPORAL",
-		Total:      orden.Total,
-		StartedAt:  orden.FechaHoraIni.Format(time.RFC3339),
-		ExpiresAt:  expiresAt.Format(time.RFC3339),
-		TTLSeconds: ttlReservaSegundos,
+		OrderID:          orden.ID,
+		Estado:           "TEMPORAL",
+		Items:            cot.Items,
+		Subtotal:         cot.Subtotal,
+		Descuento:        cot.Descuento,
+		Total:            orden.Total,
+		MontoFeeServicio: orden.MontoFeeServicio,
+		StartedAt:        orden.FechaHoraIni.Format(time.RFC3339),
+		ExpiresAt:        expiresAt.Format(time.RFC3339),
+		TTLSeconds:       ttlReservaSegundos,
 	}
 	return resp, nil
 }
 
-func (a *OrdenDeCompra) rollbackStockReservado(stocks []StockReservado) {
-	for _, stock := range stocks {
-		res := a.DaoPostgresql.OrdenDeCompra.PostgresqlDB.
-			Table("sector").
-			Where("id = ?", stock.SectorID).
-			UpdateColumn("cant_vendidas", gorm.Expr("cant_vendidas - ?", stock.Cantidad))
-
-		if res.Error != nil {
-			a.logger.Errorf("Error al hacer rollback de stock: Sector %d, Cantidad %d: %v",
-				stock.SectorID, stock.Cantidad, res.Error)
-		} else {
-			a.logger.Infof("Rollback stock: Sector %d, Cantidad %d",
-				stock.SectorID, stock.Cantidad)
-		}
-	}
-}
-
 func (a *OrdenDeCompra) ObtenerEstadoHold(
 	orderID int64,
+	usuarioID int64,
 ) (*schemas.ObtenerHoldResponse, *errors.Error) {
 
+	orden, err := a.DaoPostgresql.OrdenDeCompra.ObtenerOrdenBasica(orderID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.OrdenNotFound
+		}
+		a.logger.Errorf("ObtenerEstadoHold.ObtenerOrden(%d): %v", orderID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if orden.UsuarioID != usuarioID {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+
 	estadoEnum, ini, fin, total, err := a.DaoPostgresql.OrdenDeCompra.ObtenerMetaTemporal(orderID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -202,200 +170,125 @@ func (a *OrdenDeCompra) ObtenerEstadoHold(
 
 func (a *OrdenDeCompra) ConfirmarOrden(
 	orderID int64,
+	usuarioID int64,
 	req *schemas.ConfirmarOrdenRequest,
 ) (*schemas.ConfirmarOrdenResponse, *errors.Error) {
 
-	ok, err := a.DaoPostgresql.OrdenDeCompra.VerificarOrdenExisteYEstado(orderID, util.OrdenTemporal)
+	if req.PaymentID == "" {
+		return nil, &errors.UnprocessableEntityError.InvalidRequestBody
+	}
+
+	orden, err := a.DaoPostgresql.OrdenDeCompra.ObtenerOrdenBasica(orderID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, &errors.ObjectNotFoundError.OrdenNotFound
 		}
-		a.logger.Errorf("ConfirmarOrden.Verificar(%d): %v", orderID, err)
-		return nil, &errors.BadRequestError.EventoNotCreated
+		a.logger.Errorf("ConfirmarOrden.ObtenerOrden(%d): %v", orderID, err)
+		return nil, &errors.InternalServerError.Default
 	}
-	if !ok {
-		a.logger.Errorf("Estado")
-		return nil, &errors.BadRequestError.OrdenNotCreated
+	if orden.UsuarioID != usuarioID {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
 	}
 
-	orden, err := a.DaoPostgresql.OrdenDeCompra.CerrarOrdenTemporal(orderID)
+	pago, err := a.DaoPostgresql.Pago.ObtenerPorOrdenYReferencia(orderID, req.PaymentID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, &errors.ObjectNotFoundError.OrdenNotFound
+			return nil, &errors.ObjectNotFoundError.PagoNotFound
 		}
-		a.logger.Errorf("ConfirmarOrden.CerrarTemporal(%d): %v", orderID, err)
-		return nil, &errors.ObjectNotFoundError.OrdenNotFound
+		a.logger.Errorf("ConfirmarOrden.ObtenerPago(%d): %v", orderID, err)
+		return nil, &errors.InternalServerError.Default
 	}
 
-	now := time.Now()
-	if orden.FechaHoraFin != nil && now.After(*orden.FechaHoraFin) {
-		_ = a.CancelarOrdenYLiberarStock(orderID)
-		return nil, &errors.ObjectNotFoundError.OrdenNotFound
-	}
-
-	if req.PaymentID == "" {
-		return nil, &errors.BadRequestError.EventoNotFound
+	// Orden ya confirmada (p.ej. llegó antes el webhook): la confirmación es idempotente
+	if orden.EstadoDeOrden == util.OrdenConfirmada.Codigo() && pago.EstadoDePago == util.PagoCapturado.Codigo() {
+		return confirmacionResponse(orderID), nil
 	}
 
-	metodoPagoID := int64(1)
-
-	if len(req.PaymentID) > 0 {
-		var tmpID int64
-		if _, scanErr := fmt.Sscanf(req.PaymentID, "%d", &tmpID); scanErr == nil && tmpID > 0 {
-			metodoPagoID = tmpID
-		}
-	}
-
-	a.logger.Infof("ConfirmarOrden: orderID=%d, paymentId=%s, metodoPagoID=%d",
-		orderID, req.PaymentID, metodoPagoID)
-
-	if errUpd := a.DaoPostgresql.OrdenDeCompra.ConfirmarOrdenConPago(
-		orderID,
-		metodoPagoID,
-		req.PaymentID,
-	); errUpd != nil {
-		if errUpd == gorm.ErrRecordNotFound {
-			return nil, &errors.ObjectNotFoundError.EventoNotFound
-		}
-		a.logger.Errorf("ConfirmarOrden.ConfirmarConPago(%d): %v", orderID, errUpd)
-		return nil, &errors.BadRequestError.EventoNotFound
+	proveedor, ok := a.pagos.Obtener(util.TipoMetodoPago(pago.MetodoPago))
+	if !ok {
+		return nil, &errors.BadRequestError.PaymentMethodNotSupported
 	}
 
-	a.logger.Infof("Orden %d confirmada exitosamente con método de pago %d",
-		orderID, metodoPagoID)
-
-	// var EventoFecha = &repository.EventoFecha{}
-	// fecha, err := time.Parse("2006-01-02", req.FechaEvento)
-	// EventoFecha.SumarGananciaNetaPorEventoYFecha(req.IdEvento,fecha,)
-	montoBruto := orden.Total
-	montoFee := orden.MontoFeeServicio
-	gananciaNeta := montoBruto - montoFee
-	if gananciaNeta < 0 {
-		gananciaNeta = 0
+	// Captura del lado del servidor: el paymentId del cliente no prueba nada por sí solo
+	intento, err := proveedor.Capturar(context.Background(), pago.Referencia)
+	if err != nil {
+		a.logger.Errorf("ConfirmarOrden.Capturar(order=%d, ref=%s): %v", orderID, pago.Referencia, err)
+		return nil, &errors.BadRequestError.PaymentNotCaptured
 	}
-	a.logger.Infof(
-		"Orden %d: Total=%.2f, FeeServicio=%.2f, GananciaNetaOrganizador=%.2f",
-		orderID, montoBruto, montoFee, gananciaNeta,
-	)
-	//Evento ganancia actualizar
-	// cantidadVendida: la cantidad de entradas / items de ESTA orden
-	cantidadVendida := req.CantidadVendida // ej: req.CantidadEntradas
-
-	ahora := time.Now()
-	usuarioMod := int64(1) // o el ID real del usuario que modifica
-
-	updates := map[string]any{
-		// SUMAR la ganancia de esta orden al total acumulado
-		"total_recaudado": gorm.Expr("total_recaudado + ?", gananciaNeta),
-
-		// SUMAR la cantidad vendida de esta orden al total acumulado
-		"cant_vendido_total": gorm.Expr("cant_vendido_total + ?", cantidadVendida),
+	if intento.Estado != pagos.IntentoCapturado {
+		a.logger.Warnf("ConfirmarOrden: pago %s de la orden %d en estado %s", pago.Referencia, orderID, intento.Estado)
+		return nil, &errors.BadRequestError.PaymentNotCaptured
 	}
 
-	_, err_2 := a.DaoPostgresql.Evento.ActualizarCamposEvento(
-		req.IdEvento, // eventoID
-		updates,
-		&usuarioMod,
-		&ahora,
-	)
-	if err_2 != nil {
-		a.logger.Errorf("Error actualizando totales del evento %d: %v", req.IdEvento, err)
-	}
-	// usar evento + fecha del request
-	if req.IdEvento > 0 && req.FechaEvento != "" {
-
-		// Parsear fecha que manda el front (YYYY-MM-DD)
-		fechaParsed, parseErr := time.Parse("2006-01-02", req.FechaEvento)
-		if parseErr != nil {
-			a.logger.Errorf("ConfirmarOrden: fechaEvento inválida '%s': %v", req.FechaEvento, parseErr)
-			// aquí tú decides si devuelves error o solo logueas
-		} else {
-			// Llamar al DAO que hace:
-			// 1) buscar fecha_evento en TABLA fecha
-			// 2) usar (evento_id, fecha_id) en TABLA evento_fecha
-			if errSum := a.DaoPostgresql.EventoFecha.SumarGananciaNetaPorEventoYFecha(
-				req.IdEvento,
-				fechaParsed,
-				gananciaNeta,
-			); errSum != nil {
-				a.logger.Errorf(
-					"ConfirmarOrden.SumarGananciaNetaPorEventoYFecha(order=%d, evento=%d, fecha=%s, monto=%.2f): %v",
-					orderID, req.IdEvento, fechaParsed.Format("2006-01-02"), gananciaNeta, errSum,
-				)
-				// igual, aquí decides si solo log o error
-			} else {
-				a.logger.Infof(
-					"Ganancia acumulada para evento=%d, fecha=%s: +%.2f (orden=%d)",
-					req.IdEvento, fechaParsed.Format("2006-01-02"), gananciaNeta, orderID,
-				)
-			}
-		}
-	} else {
-		a.logger.Warnf(
-			"ConfirmarOrden: IdEvento o FechaEvento no enviados (evento=%d, fecha='%s'), no se actualiza ganancia.",
-			req.IdEvento, req.FechaEvento,
-		)
+	if errConf := a.confirmarPagoCapturado(pago, intento.Monto); errConf != nil {
+		return nil, errConf
 	}
+	return confirmacionResponse(orderID), nil
+}
 
-	resp := &schemas.ConfirmarOrdenResponse{
+func confirmacionResponse(orderID int64) *schemas.ConfirmarOrdenResponse {
+	return &schemas.ConfirmarOrdenResponse{
 		OrderID: orderID,
 		Estado:  "CONFIRMADA",
 		Mensaje: "Compra confirmada",
 	}
-
-	return resp, nil
 }
 
 func (a *OrdenDeCompra) CancelarOrdenYLiberarStock(orderID int64) *errors.Error {
-	ok, err := a.DaoPostgresql.OrdenDeCompra.VerificarOrdenExisteYEstado(orderID, util.OrdenTemporal)
-	if err != nil {
+	// Cambio de estado y devolución de stock (según los detalles) en una sola transacción
+	if err := a.DaoPostgresql.OrdenDeCompra.CancelarOrdenTemporal(orderID); err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return &errors.ObjectNotFoundError.EventoNotFound
+			return &errors.ObjectNotFoundError.OrdenNotFound
+		}
+		if goerrors.Is(err, daoPostgresql.ErrOrdenNoTemporal) {
+			a.logger.Warnf("Orden %d no está en estado TEMPORAL", orderID)
+			return &errors.BadRequestError.EventoNotFound
 		}
-		a.logger.Errorf("CancelarOrden.Verificar(%d): %v", orderID, err)
+		a.logger.Errorf("CancelarOrden(%d): %v", orderID, err)
 		return &errors.InternalServerError.Default
 	}
-	if !ok {
-		a.logger.Warnf("Orden %d no está en estado TEMPORAL", orderID)
-		return &errors.BadRequestError.EventoNotFound
-	}
 
-	// Obtener detalles de la orden para liberar stock
-	type DetalleConSector struct {
-		SectorID int64
-		Cantidad int64
-	}
-	var detalles []DetalleConSector
+	a.logger.Infof("Orden %d cancelada y stock liberado", orderID)
+	return nil
+}
+
+// LiberarHoldsVencidos cancela, en lotes de tamaño lote, las órdenes TEMPORAL cuyo hold ya venció y
+// devuelve su stock. Solo una réplica a la vez hace el barrido (advisory lock); en las demás no hace nada.
+// Devuelve cuántas órdenes se liberaron.
+func (a *OrdenDeCompra) LiberarHoldsVencidos(ctx context.Context, lote int) (int, *errors.Error) {
+	liberadas := 0
+	_, err := a.DaoPostgresql.OrdenDeCompra.ConLockLiberadorHolds(ctx, func() error {
+		for ctx.Err() == nil {
+			ids, err := a.DaoPostgresql.OrdenDeCompra.ListarOrdenesTemporalesVencidas(time.Now(), lote)
+			if err != nil {
+				return err
+			}
 
-	err = a.DaoPostgresql.OrdenDeCompra.PostgresqlDB.
-		Table("orden_de_compra_detalle").
-		Select("id_sector as sector_id, cantidad").
-		Where("orden_de_compra_id = ?", orderID).
-		Find(&detalles).Error
+			liberadasLote := 0
+			for _, id := range ids {
+				if ctx.Err() != nil {
+					break
+				}
+				// CancelarOrdenYLiberarStock bloquea la orden y revisa que siga TEMPORAL,
+				// así una confirmación en paralelo no termina con el stock devuelto.
+				if errCancel := a.CancelarOrdenYLiberarStock(id); errCancel != nil {
+					a.logger.Warnf("LiberarHoldsVencidos: orden %d no liberada: %s", id, errCancel.Message)
+					continue
+				}
+				liberadasLote++
+			}
+			liberadas += liberadasLote
 
-	if err != nil {
-		a.logger.Errorf("CancelarOrden.ObtenerDetalles(%d): %v", orderID, err)
-	} else {
-		for _, d := range detalles {
-			res := a.DaoPostgresql.OrdenDeCompra.PostgresqlDB.
-				Table("sector").
-				Where("id = ?", d.SectorID).
-				UpdateColumn("cant_vendidas", gorm.Expr("cant_vendidas - ?", d.Cantidad))
-
-			if res.Error != nil {
-				a.logger.Errorf("CancelarOrden.DecrementarStock(sector=%d): %v", d.SectorID, res.Error)
-			} else {
-				a.logger.Infof("Stock liberado: Sector %d, Cantidad %d (Orden %d cancelada)",
-					d.SectorID, d.Cantidad, orderID)
+			// Lote incompleto: no quedan más vencidas. Lote sin avances: no reintentar en bucle.
+			if len(ids) < lote || liberadasLote == 0 {
+				return nil
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		a.logger.Errorf("LiberarHoldsVencidos: %v", err)
+		return liberadas, &errors.InternalServerError.Default
 	}
-
-	if err := a.DaoPostgresql.OrdenDeCompra.ActualizarEstadoOrden(orderID, util.OrdenCancelada); err != nil {
-		a.logger.Errorf("CancelarOrden.ActualizarEstado(%d): %v", orderID, err)
-		return &errors.InternalServerError.Default
-	}
-
-	a.logger.Infof("Orden %d cancelada y stock liberado", orderID)
-	return nil
+	return liberadas, nil
 }
@@ -0,0 +1,191 @@
+package adapter
+
+import (
+	"context"
+	goerrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/pagos"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"gorm.io/gorm"
+)
+
+const monedaPagos = "PEN"
+
+// CrearIntentoPago abre el cobro de una orden TEMPORAL vigente en la pasarela del método elegido.
+// El monto es siempre el total guardado en la orden.
+func (a *OrdenDeCompra) CrearIntentoPago(
+	orderID int64,
+	usuarioID int64,
+	req *schemas.CrearIntentoPagoRequest,
+) (*schemas.CrearIntentoPagoResponse, *errors.Error) {
+
+	metodo, ok := pagos.MetodoDesdeTexto(req.MetodoPago)
+	if !ok {
+		return nil, &errors.BadRequestError.PaymentMethodNotSupported
+	}
+	proveedor, ok := a.pagos.Obtener(metodo)
+	if !ok {
+		return nil, &errors.BadRequestError.PaymentMethodNotSupported
+	}
+
+	orden, err := a.DaoPostgresql.OrdenDeCompra.ObtenerOrdenBasica(orderID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.OrdenNotFound
+		}
+		a.logger.Errorf("CrearIntentoPago.ObtenerOrden(%d): %v", orderID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if orden.UsuarioID != usuarioID {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+	if orden.EstadoDeOrden != util.OrdenTemporal.Codigo() ||
+		(orden.FechaHoraFin != nil && time.Now().After(*orden.FechaHoraFin)) {
+		return nil, &errors.ConflictError.OrderNotPayable
+	}
+
+	intento, err := proveedor.CrearIntento(context.Background(), pagos.IntentoRequest{
+		OrdenID:     orden.ID,
+		Monto:       orden.Total,
+		Moneda:      monedaPagos,
+		Descripcion: "Orden Nexivent",
+	})
+	if err != nil {
+		a.logger.Errorf("CrearIntentoPago.Pasarela(order=%d, metodo=%s): %v", orderID, metodo, err)
+		return nil, &errors.InternalServerError.Default
+	}
+
+	pago := &model.Pago{
+		OrdenDeCompraID: orden.ID,
+		MetodoPago:      string(metodo),
+		Referencia:      intento.Referencia,
+		Monto:           orden.Total,
+		Moneda:          monedaPagos,
+		EstadoDePago:    util.PagoPendiente.Codigo(),
+	}
+	if err := a.DaoPostgresql.Pago.CrearPago(pago); err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+
+	return &schemas.CrearIntentoPagoResponse{
+		OrderID:      orden.ID,
+		PaymentID:    pago.Referencia,
+		MetodoPago:   pago.MetodoPago,
+		Monto:        pago.Monto,
+		Moneda:       pago.Moneda,
+		Estado:       util.PagoPendiente.String(),
+		ClientSecret: intento.ClientSecret,
+	}, nil
+}
+
+// ProcesarWebhookPago valida la firma de la notificación de la pasarela y confirma la orden
+// si el cobro quedó capturado. Reenvíos del mismo webhook no tienen efecto.
+func (a *OrdenDeCompra) ProcesarWebhookPago(metodoTexto string, payload []byte, headers http.Header) *errors.Error {
+	metodo, ok := pagos.MetodoDesdeTexto(metodoTexto)
+	if !ok {
+		return &errors.BadRequestError.PaymentMethodNotSupported
+	}
+	proveedor, ok := a.pagos.Obtener(metodo)
+	if !ok {
+		return &errors.BadRequestError.PaymentMethodNotSupported
+	}
+
+	evento, err := proveedor.VerificarWebhook(payload, headers)
+	if err != nil {
+		if goerrors.Is(err, pagos.ErrFirmaInvalida) {
+			a.logger.Warnf("ProcesarWebhookPago(%s): firma inválida", metodo)
+			return &errors.AuthenticationError.InvalidWebhookSignature
+		}
+		a.logger.Warnf("ProcesarWebhookPago(%s): %v", metodo, err)
+		return &errors.UnprocessableEntityError.InvalidRequestBody
+	}
+
+	pago, err := a.DaoPostgresql.Pago.ObtenerPorReferencia(metodo, evento.Referencia)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &errors.ObjectNotFoundError.PagoNotFound
+		}
+		a.logger.Errorf("ProcesarWebhookPago.ObtenerPago(%s): %v", evento.Referencia, err)
+		return &errors.InternalServerError.Default
+	}
+
+	switch evento.Estado {
+	case pagos.IntentoCapturado:
+		return a.confirmarPagoCapturado(pago, evento.Monto)
+	case pagos.IntentoFallido:
+		if pago.EstadoDePago == util.PagoPendiente.Codigo() {
+			if err := a.DaoPostgresql.Pago.ActualizarEstado(pago.ID, util.PagoFallido); err != nil {
+				return &errors.InternalServerError.Default
+			}
+		}
+		return nil
+	default:
+		// Otros estados (pendiente, reembolsado) no cambian la orden
+		return nil
+	}
+}
+
+// confirmarPagoCapturado confirma la orden de un pago ya capturado en la pasarela. Si la orden ya
+// no se puede confirmar (hold vencido o cancelado), si ya la pagó otro intento o si se cobró un
+// monto distinto al de la orden, devuelve el dinero al comprador.
+func (a *OrdenDeCompra) confirmarPagoCapturado(pago *model.Pago, montoCapturado float64) *errors.Error {
+	if redondearCentimos(montoCapturado) != redondearCentimos(pago.Monto) {
+		a.logger.Errorf("Pago %s: capturado %.2f, esperado %.2f", pago.Referencia, montoCapturado, pago.Monto)
+		a.reembolsarPagoHuerfano(pago, montoCapturado, "el monto cobrado no coincide con el de la orden")
+		return &errors.BadRequestError.PaymentAmountMismatch
+	}
+
+	metodo, err := a.DaoPostgresql.MetodoDePago.ObtenerOCrearPorTipo(util.TipoMetodoPago(pago.MetodoPago))
+	if err != nil {
+		return &errors.InternalServerError.Default
+	}
+
+	orden, err := a.DaoPostgresql.OrdenDeCompra.ConfirmarOrdenPagada(pago.OrdenDeCompraID, pago.ID, metodo.ID, firmadorQR(a.firmanteQR, time.Now()))
+	switch {
+	case err == nil:
+		a.logger.Infof("Orden %d confirmada con pago %s (%s, Total=%.2f, FeeServicio=%.2f)",
+			orden.ID, pago.Referencia, pago.MetodoPago, orden.Total, orden.MontoFeeServicio)
+		return nil
+	case goerrors.Is(err, daoPostgresql.ErrOrdenYaConfirmada):
+		return nil
+	case goerrors.Is(err, daoPostgresql.ErrOrdenPagadaConOtroPago):
+		a.reembolsarPagoHuerfano(pago, pago.Monto, "la orden ya se pagó con otro intento")
+		return &errors.ConflictError.OrderNotPayable
+	case goerrors.Is(err, daoPostgresql.ErrOrdenNoTemporal):
+		a.reembolsarPagoHuerfano(pago, pago.Monto, "la orden ya no estaba pendiente de pago")
+		return &errors.ConflictError.OrderNotPayable
+	case err == gorm.ErrRecordNotFound:
+		return &errors.ObjectNotFoundError.OrdenNotFound
+	default:
+		return &errors.InternalServerError.Default
+	}
+}
+
+// reembolsarPagoHuerfano devuelve el monto cobrado en un pago que no confirma su orden: la orden ya
+// se canceló (p.ej. el hold venció mientras el comprador pagaba), ya la pagó otro intento o se
+// cobró un monto distinto. motivo queda en el log.
+func (a *OrdenDeCompra) reembolsarPagoHuerfano(pago *model.Pago, monto float64, motivo string) {
+	if pago.EstadoDePago == util.PagoReembolsado.Codigo() {
+		return
+	}
+	proveedor, ok := a.pagos.Obtener(util.TipoMetodoPago(pago.MetodoPago))
+	if !ok {
+		a.logger.Errorf("Pago %s capturado para orden %d no confirmable y sin pasarela para reembolsar", pago.Referencia, pago.OrdenDeCompraID)
+		return
+	}
+	if _, err := proveedor.Reembolsar(context.Background(), pago.Referencia, monto); err != nil {
+		a.logger.Errorf("Reembolso del pago %s (orden %d): %v", pago.Referencia, pago.OrdenDeCompraID, err)
+		return
+	}
+	if err := a.DaoPostgresql.Pago.ActualizarEstado(pago.ID, util.PagoReembolsado); err != nil {
+		a.logger.Errorf("Pago %s reembolsado pero no se pudo actualizar su estado: %v", pago.Referencia, err)
+		return
+	}
+	a.logger.Warnf("Pago %s de la orden %d reembolsado (%.2f): %s", pago.Referencia, pago.OrdenDeCompraID, monto, motivo)
+}
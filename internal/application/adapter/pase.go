@@ -0,0 +1,179 @@
+package adapter
+
+import (
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/pases"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"gorm.io/gorm"
+)
+
+// titularActual es quien puede usar el ticket: el titular registrado o, en tickets anteriores a
+// las transferencias, el comprador de la orden.
+func titularActual(ticket *model.Ticket) (int64, string) {
+	if ticket.TitularID != nil {
+		nombre := ""
+		if ticket.Titular != nil {
+			nombre = ticket.Titular.Nombre
+		}
+		return *ticket.TitularID, nombre
+	}
+	if ticket.OrdenDeCompra != nil {
+		nombre := ""
+		if ticket.OrdenDeCompra.Usuario != nil {
+			nombre = ticket.OrdenDeCompra.Usuario.Nombre
+		}
+		return ticket.OrdenDeCompra.UsuarioID, nombre
+	}
+	return 0, ""
+}
+
+// datosDePase reúne lo que se imprime de un ticket cargado con ObtenerParaPase.
+func datosDePase(ticket *model.Ticket) pases.Datos {
+	_, titular := titularActual(ticket)
+	d := pases.Datos{
+		TicketID: ticket.ID,
+		Titular:  titular,
+		CodigoQR: ticket.CodigoQR,
+	}
+	if ef := ticket.EventoFecha; ef != nil {
+		if ef.Evento != nil {
+			d.Evento = ef.Evento.Titulo
+			d.Lugar = ef.Evento.Lugar
+		}
+		if ef.Fecha != nil {
+			d.Inicio = inicioEventoFecha(ef)
+		}
+	}
+	if tarifa := ticket.Tarifa; tarifa != nil {
+		if tarifa.Sector != nil {
+			d.Sector = tarifa.Sector.SectorTipo
+		}
+		if tarifa.PerfilPersona != nil {
+			d.Perfil = tarifa.PerfilPersona.Nombre
+		}
+		if tarifa.TipoDeTicket != nil {
+			d.TipoTicket = tarifa.TipoDeTicket.Nombre
+		}
+	}
+	return d
+}
+
+// ticketParaPase carga el ticket si el usuario es su titular y está VENDIDO: los usados o
+// cancelados ya no sirven para entrar y no se vuelven a entregar.
+func (t *Ticket) ticketParaPase(ticketID int64, usuario *model.Usuario) (*pases.Datos, *errors.Error) {
+	if ticketID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	ticket, err := t.DaoPostgresql.Ticket.ObtenerParaPase(ticketID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.TicketNotFound
+		}
+		t.logger.Errorf("ticketParaPase(%d): %v", ticketID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if titularID, _ := titularActual(ticket); titularID != usuario.ID {
+		return nil, &errors.ForbiddenError.NotTicketHolder
+	}
+	if ticket.EstadoDeTicket != util.TicketVendido.Codigo() {
+		return nil, &errors.ConflictError.TicketNotDownloadable
+	}
+	d := datosDePase(ticket)
+	return &d, nil
+}
+
+// ticketsDeOrdenParaPase devuelve los tickets VENDIDOS de la orden que siguen siendo del usuario.
+func (t *Ticket) ticketsDeOrdenParaPase(orderID int64, usuario *model.Usuario) ([]pases.Datos, *errors.Error) {
+	if orderID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	orden, err := t.DaoPostgresql.OrdenDeCompra.ObtenerOrdenBasica(orderID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.OrdenNotFound
+		}
+		t.logger.Errorf("ticketsDeOrdenParaPase.ObtenerOrden(%d): %v", orderID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+
+	tickets, err := t.DaoPostgresql.Ticket.ObtenerParaPasesDeOrden(orderID, usuario.ID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	datos := make([]pases.Datos, 0, len(tickets))
+	for i := range tickets {
+		if tickets[i].EstadoDeTicket == util.TicketVendido.Codigo() {
+			datos = append(datos, datosDePase(&tickets[i]))
+		}
+	}
+	if len(datos) == 0 {
+		if len(tickets) == 0 && orden.UsuarioID != usuario.ID {
+			return nil, &errors.ForbiddenError.InsufficientPermissions
+		}
+		return nil, &errors.ConflictError.TicketNotDownloadable
+	}
+	return datos, nil
+}
+
+// PDFTicket genera el PDF imprimible de un ticket del usuario.
+func (t *Ticket) PDFTicket(ticketID int64, usuario *model.Usuario) ([]byte, *errors.Error) {
+	d, ferr := t.ticketParaPase(ticketID, usuario)
+	if ferr != nil {
+		return nil, ferr
+	}
+	pdf, err := pases.GenerarPDF([]pases.Datos{*d})
+	if err != nil {
+		t.logger.Errorf("PDFTicket(%d): %v", ticketID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	return pdf, nil
+}
+
+// PDFOrden genera un PDF con una página por cada ticket de la orden que conserva el usuario.
+func (t *Ticket) PDFOrden(orderID int64, usuario *model.Usuario) ([]byte, *errors.Error) {
+	datos, ferr := t.ticketsDeOrdenParaPase(orderID, usuario)
+	if ferr != nil {
+		return nil, ferr
+	}
+	pdf, err := pases.GenerarPDF(datos)
+	if err != nil {
+		t.logger.Errorf("PDFOrden(%d): %v", orderID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	return pdf, nil
+}
+
+// PkpassTicket genera el pase de billetera de un ticket del usuario.
+func (t *Ticket) PkpassTicket(ticketID int64, usuario *model.Usuario) ([]byte, *errors.Error) {
+	if t.firmanteWallet == nil {
+		return nil, &errors.ConflictError.WalletPassesDisabled
+	}
+	d, ferr := t.ticketParaPase(ticketID, usuario)
+	if ferr != nil {
+		return nil, ferr
+	}
+	pkpass, err := t.firmanteWallet.GenerarPkpass(*d)
+	if err != nil {
+		t.logger.Errorf("PkpassTicket(%d): %v", ticketID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	return pkpass, nil
+}
+
+// PkpassesOrden agrupa en un .pkpasses los pases de los tickets de la orden que conserva el usuario.
+func (t *Ticket) PkpassesOrden(orderID int64, usuario *model.Usuario) ([]byte, *errors.Error) {
+	if t.firmanteWallet == nil {
+		return nil, &errors.ConflictError.WalletPassesDisabled
+	}
+	datos, ferr := t.ticketsDeOrdenParaPase(orderID, usuario)
+	if ferr != nil {
+		return nil, ferr
+	}
+	paquete, err := t.firmanteWallet.GenerarPkpasses(datos)
+	if err != nil {
+		t.logger.Errorf("PkpassesOrden(%d): %v", orderID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	return paquete, nil
+}
@@ -0,0 +1,161 @@
+package adapter
+
+import (
+	"math"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+)
+
+// cotizacionOrden es el precio de una orden calculado en el backend.
+type cotizacionOrden struct {
+	Detalles         []model.OrdenDeCompraDetalle
+	Items            []schemas.ItemOrdenResponse
+	Subtotal         float64
+	Descuento        float64
+	CuponID          *int64
+	Total            float64
+	MontoFeeServicio float64
+}
+
+func redondearCentimos(monto float64) float64 {
+	return math.Round(monto*100) / 100
+}
+
+// cotizarOrden arma los detalles de la orden con el precio de cada tarifa. Solo acepta tarifas
+// del evento cuyo tipo de ticket esté en venta en ahora, y que coincidan con el sector y el
+// perfil pedidos. Si viene un cupón, lo valida para el usuario y reparte el descuento entre
+// las líneas en proporción a su subtotal.
+func (a *OrdenDeCompra) cotizarOrden(
+	req *schemas.CrearOrdenTemporalRequest,
+	ahora time.Time,
+) (*cotizacionOrden, *errors.Error) {
+
+	pertenece, err := a.DaoPostgresql.EventoFecha.VerificarEventoFechaPerteneceAEvento(req.IdFechaEvento, req.IdEvento)
+	if err != nil {
+		a.logger.Errorf("cotizarOrden.VerificarEventoFecha(%d): %v", req.IdFechaEvento, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if !pertenece {
+		return nil, &errors.UnprocessableEntityError.InvalidEventoId
+	}
+
+	ids := make([]int64, 0, len(req.Entradas))
+	for _, entrada := range req.Entradas {
+		if entrada.IdTarifa <= 0 || entrada.Cantidad <= 0 {
+			return nil, &errors.UnprocessableEntityError.InvalidRequestBody
+		}
+		ids = append(ids, entrada.IdTarifa)
+	}
+
+	// Ventana de venta: tdt.fecha_ini <= hoy <= tdt.fecha_fin
+	tarifas, err := a.DaoPostgresql.Tarifa.ObtenerTarifasValidasParaFechaEvento(ids, req.IdEvento, ahora)
+	if err != nil {
+		a.logger.Errorf("cotizarOrden.ObtenerTarifas: %v", err)
+		return nil, &errors.InternalServerError.Default
+	}
+	porID := make(map[int64]*model.Tarifa, len(tarifas))
+	for _, t := range tarifas {
+		porID[t.ID] = t
+	}
+
+	cot := &cotizacionOrden{}
+	for _, entrada := range req.Entradas {
+		tarifa, ok := porID[entrada.IdTarifa]
+		if !ok {
+			a.logger.Warnf("cotizarOrden: tarifa %d no está en venta para el evento %d", entrada.IdTarifa, req.IdEvento)
+			return nil, &errors.UnprocessableEntityError.TarifaNotAvailable
+		}
+		if entrada.IdSector != 0 && entrada.IdSector != tarifa.SectorID {
+			a.logger.Warnf("cotizarOrden: tarifa %d no pertenece al sector %d", tarifa.ID, entrada.IdSector)
+			return nil, &errors.UnprocessableEntityError.InvalidRequestBody
+		}
+		if entrada.IdPerfil != 0 && (tarifa.PerfilDePersonaID == nil || *tarifa.PerfilDePersonaID != entrada.IdPerfil) {
+			a.logger.Warnf("cotizarOrden: tarifa %d no corresponde al perfil %d", tarifa.ID, entrada.IdPerfil)
+			return nil, &errors.UnprocessableEntityError.InvalidRequestBody
+		}
+
+		cot.Detalles = append(cot.Detalles, model.OrdenDeCompraDetalle{
+			TarifaID:          tarifa.ID,
+			SectorID:          tarifa.SectorID,
+			EventoFechaID:     req.IdFechaEvento,
+			PerfilDePersonaID: tarifa.PerfilDePersonaID,
+			Cantidad:          entrada.Cantidad,
+			PrecioUnitario:    tarifa.Precio,
+		})
+		cot.Items = append(cot.Items, schemas.ItemOrdenResponse{
+			IdTarifa:       tarifa.ID,
+			IdSector:       tarifa.SectorID,
+			IdTipoTicket:   tarifa.TipoDeTicketID,
+			IdPerfil:       tarifa.PerfilDePersonaID,
+			Cantidad:       entrada.Cantidad,
+			PrecioUnitario: tarifa.Precio,
+		})
+		cot.Subtotal += float64(entrada.Cantidad) * tarifa.Precio
+	}
+	cot.Subtotal = redondearCentimos(cot.Subtotal)
+
+	if req.CodigoCupon != "" {
+		cuponID, descuento, errCupon := a.calcularDescuentoCupon(req, cot.Subtotal, ahora)
+		if errCupon != nil {
+			return nil, errCupon
+		}
+		cot.CuponID = &cuponID
+		cot.Descuento = descuento
+		cot.repartirDescuento()
+	}
+
+	for i := range cot.Items {
+		cot.Items[i].Descuento = cot.Detalles[i].Descuento
+		cot.Items[i].Subtotal = redondearCentimos(cot.Detalles[i].Subtotal())
+	}
+
+	cot.Total = redondearCentimos(cot.Subtotal - cot.Descuento)
+	cot.MontoFeeServicio = redondearCentimos(cot.Total * porcentajeFeeServicio)
+	return cot, nil
+}
+
+// calcularDescuentoCupon valida el cupón para el usuario y el evento y devuelve su ID y el monto a
+// descontar del subtotal (nunca mayor que el subtotal). El uso se cuenta recién al crear el hold.
+func (a *OrdenDeCompra) calcularDescuentoCupon(
+	req *schemas.CrearOrdenTemporalRequest,
+	subtotal float64,
+	ahora time.Time,
+) (int64, float64, *errors.Error) {
+	cuponAdapter := NewCuponAdapter(a.logger, a.DaoPostgresql)
+	cupon, errCupon := cuponAdapter.FetchPostresqlValidarCuponParaOrdenDeCompra(req.IdUsuario, ahora, req.IdEvento, req.CodigoCupon)
+	if errCupon != nil {
+		return 0, 0, errCupon
+	}
+
+	var descuento float64
+	switch cupon.Tipo {
+	case util.TipoPorcentaje:
+		descuento = subtotal * cupon.Valor / 100
+	case util.TipoMonto:
+		descuento = cupon.Valor
+	}
+	return cupon.ID, redondearCentimos(math.Max(0, math.Min(descuento, subtotal))), nil
+}
+
+// repartirDescuento distribuye el descuento entre los detalles en proporción a su subtotal.
+// La última línea absorbe el redondeo para que la suma cuadre al céntimo.
+func (c *cotizacionOrden) repartirDescuento() {
+	if c.Subtotal <= 0 || c.Descuento <= 0 {
+		return
+	}
+	restante := c.Descuento
+	for i := range c.Detalles {
+		d := &c.Detalles[i]
+		if i == len(c.Detalles)-1 {
+			d.Descuento = redondearCentimos(restante)
+			break
+		}
+		bruto := float64(d.Cantidad) * d.PrecioUnitario
+		d.Descuento = redondearCentimos(c.Descuento * bruto / c.Subtotal)
+		restante -= d.Descuento
+	}
+}
@@ -0,0 +1,226 @@
+package adapter
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	model "github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"github.com/Nexivent/nexivent-backend/logging"
+	"gorm.io/gorm"
+)
+
+// Radio de las búsquedas por cercanía, en km
+const (
+	radioCercaniaPorDefectoKm = 10
+	radioCercaniaMaximoKm     = 500
+)
+
+type RecintoAdapter struct {
+	logger        logging.Logger
+	DaoPostgresql *daoPostgresql.NexiventPsqlEntidades
+}
+
+func NewRecintoAdapter(
+	logger logging.Logger,
+	daoPostgresql *daoPostgresql.NexiventPsqlEntidades,
+) *RecintoAdapter {
+	return &RecintoAdapter{
+		logger:        logger,
+		DaoPostgresql: daoPostgresql,
+	}
+}
+
+func (a *RecintoAdapter) CrearRecinto(req *schemas.RecintoRequest, usuario *model.Usuario, ahora time.Time) (*schemas.RecintoResponse, *errors.Error) {
+	if ferr := validarRecinto(req); ferr != nil {
+		return nil, ferr
+	}
+	recinto := &model.Recinto{
+		UsuarioCreacion: &usuario.ID,
+		FechaCreacion:   ahora,
+	}
+	copiarRecinto(recinto, req)
+
+	if err := a.DaoPostgresql.Recinto.CrearRecinto(recinto); err != nil {
+		a.logger.Errorf("CrearRecinto: %v", err)
+		return nil, &errors.InternalServerError.Default
+	}
+	return recintoResponse(recinto, nil), nil
+}
+
+// ActualizarRecinto reemplaza los datos del recinto. Los recintos los comparten todos los
+// organizadores, así que solo lo edita quien lo creó o un administrador.
+func (a *RecintoAdapter) ActualizarRecinto(id int64, req *schemas.RecintoRequest, usuario *model.Usuario, ahora time.Time) (*schemas.RecintoResponse, *errors.Error) {
+	if ferr := validarRecinto(req); ferr != nil {
+		return nil, ferr
+	}
+	recinto, err := a.DaoPostgresql.Recinto.ObtenerRecintoPorId(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.RecintoNotFound
+		}
+		a.logger.Errorf("ActualizarRecinto(%d): %v", id, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	creador := recinto.UsuarioCreacion != nil && *recinto.UsuarioCreacion == usuario.ID
+	if !creador && !usuario.TieneAlgunRol(model.RolAdministrador) {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+	copiarRecinto(recinto, req)
+	recinto.UsuarioModificacion = &usuario.ID
+	recinto.FechaModificacion = &ahora
+
+	if err := a.DaoPostgresql.Recinto.ActualizarRecinto(recinto); err != nil {
+		a.logger.Errorf("ActualizarRecinto(%d): %v", id, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	return recintoResponse(recinto, nil), nil
+}
+
+func (a *RecintoAdapter) ObtenerRecinto(id int64) (*schemas.RecintoResponse, *errors.Error) {
+	recinto, err := a.DaoPostgresql.Recinto.ObtenerRecintoPorId(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.RecintoNotFound
+		}
+		a.logger.Errorf("ObtenerRecinto(%d): %v", id, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	return recintoResponse(recinto, nil), nil
+}
+
+// ListarRecintos lista los recintos activos de un departamento, provincia o distrito (prefijo de
+// 2, 4 o 6 dígitos del ubigeo) y, si se pide, los que están cerca de un punto.
+func (a *RecintoAdapter) ListarRecintos(ubigeo string, cercaniaReq *schemas.CercaniaRequest) ([]schemas.RecintoResponse, *errors.Error) {
+	ubigeo = strings.TrimSpace(ubigeo)
+	if ubigeo != "" && (len(ubigeo)%2 != 0 || len(ubigeo) > 6 || !soloDigitos(ubigeo)) {
+		return nil, &errors.UnprocessableEntityError.InvalidVenue
+	}
+	cercania, ferr := cercaniaDesdeRequest(cercaniaReq)
+	if ferr != nil {
+		return nil, ferr
+	}
+
+	recintos, err := a.DaoPostgresql.Recinto.ListarRecintos(ubigeo, cercania)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	resp := make([]schemas.RecintoResponse, 0, len(recintos))
+	for i := range recintos {
+		resp = append(resp, *recintoResponse(&recintos[i].Recinto, recintos[i].DistanciaKm))
+	}
+	return resp, nil
+}
+
+// validarRecinto exige los datos de ubicación completos y coordenadas dentro de rango.
+func validarRecinto(req *schemas.RecintoRequest) *errors.Error {
+	for _, campo := range []*string{&req.Nombre, &req.Direccion, &req.Departamento, &req.Provincia, &req.Distrito, &req.Ubigeo} {
+		*campo = strings.TrimSpace(*campo)
+		if *campo == "" {
+			return &errors.UnprocessableEntityError.InvalidVenue
+		}
+	}
+	if len(req.Ubigeo) != 6 || !soloDigitos(req.Ubigeo) || !coordenadasValidas(req.Latitud, req.Longitud) || req.Capacidad < 0 {
+		return &errors.UnprocessableEntityError.InvalidVenue
+	}
+	if req.Estado != nil && *req.Estado != util.Activo.Codigo() && *req.Estado != util.Inactivo.Codigo() {
+		return &errors.UnprocessableEntityError.InvalidVenue
+	}
+	return nil
+}
+
+func copiarRecinto(recinto *model.Recinto, req *schemas.RecintoRequest) {
+	recinto.Nombre = req.Nombre
+	recinto.Direccion = req.Direccion
+	recinto.Departamento = req.Departamento
+	recinto.Provincia = req.Provincia
+	recinto.Distrito = req.Distrito
+	recinto.Ubigeo = req.Ubigeo
+	recinto.Latitud = req.Latitud
+	recinto.Longitud = req.Longitud
+	recinto.Capacidad = req.Capacidad
+	recinto.ImagenMapaAsientos = req.ImagenMapaAsientos
+	recinto.Estado = util.Activo.Codigo()
+	if req.Estado != nil {
+		recinto.Estado = *req.Estado
+	}
+}
+
+func recintoResponse(recinto *model.Recinto, distanciaKm *float64) *schemas.RecintoResponse {
+	return &schemas.RecintoResponse{
+		IdRecinto:          recinto.ID,
+		Nombre:             recinto.Nombre,
+		Direccion:          recinto.Direccion,
+		Departamento:       recinto.Departamento,
+		Provincia:          recinto.Provincia,
+		Distrito:           recinto.Distrito,
+		Ubigeo:             recinto.Ubigeo,
+		Latitud:            recinto.Latitud,
+		Longitud:           recinto.Longitud,
+		Capacidad:          recinto.Capacidad,
+		ImagenMapaAsientos: recinto.ImagenMapaAsientos,
+		Estado:             recinto.Estado,
+		DistanciaKm:        distanciaKm,
+	}
+}
+
+// cercaniaDesdeRequest valida una búsqueda por cercanía; sin lat ni lng no hay filtro (nil).
+func cercaniaDesdeRequest(req *schemas.CercaniaRequest) (*daoPostgresql.Cercania, *errors.Error) {
+	if req == nil || (req.Latitud == nil && req.Longitud == nil) {
+		if req != nil && req.RadioKm != nil {
+			return nil, &errors.UnprocessableEntityError.InvalidGeoQuery
+		}
+		return nil, nil
+	}
+	if req.Latitud == nil || req.Longitud == nil || !coordenadasValidas(*req.Latitud, *req.Longitud) {
+		return nil, &errors.UnprocessableEntityError.InvalidGeoQuery
+	}
+	cercania := &daoPostgresql.Cercania{
+		Punto:   daoPostgresql.PuntoGeografico{Latitud: *req.Latitud, Longitud: *req.Longitud},
+		RadioKm: radioCercaniaPorDefectoKm,
+	}
+	if req.RadioKm != nil {
+		if math.IsNaN(*req.RadioKm) || *req.RadioKm <= 0 || *req.RadioKm > radioCercaniaMaximoKm {
+			return nil, &errors.UnprocessableEntityError.InvalidGeoQuery
+		}
+		cercania.RadioKm = *req.RadioKm
+	}
+	return cercania, nil
+}
+
+func coordenadasValidas(latitud, longitud float64) bool {
+	return latitud >= -90 && latitud <= 90 && longitud >= -180 && longitud <= 180
+}
+
+func soloDigitos(texto string) bool {
+	return strings.Trim(texto, "0123456789") == ""
+}
+
+// asignarRecinto enlaza el evento al recinto activo idRecinto (nil: el lugar queda solo como
+// texto). Si el evento no trae lugar ni imagen del escenario, se toman el nombre y el mapa de
+// asientos del recinto.
+func asignarRecinto(tx *gorm.DB, ev *model.Evento, idRecinto *int64) *errors.Error {
+	ev.RecintoID = nil
+	if idRecinto == nil {
+		return nil
+	}
+	var recinto model.Recinto
+	if err := tx.Where("recinto_id = ? AND estado = ?", *idRecinto, util.Activo.Codigo()).First(&recinto).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &errors.ObjectNotFoundError.RecintoNotFound
+		}
+		return &errors.InternalServerError.Default
+	}
+	ev.RecintoID = &recinto.ID
+	if strings.TrimSpace(ev.Lugar) == "" {
+		ev.Lugar = recinto.Nombre
+	}
+	if ev.ImagenEscenario == "" {
+		ev.ImagenEscenario = recinto.ImagenMapaAsientos
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package adapter
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// limiteReembolsosFallidos acota cuántos reembolsos fallidos se listan en el panel.
+const limiteReembolsosFallidos = 100
+
+func reembolsoASchema(r *model.Reembolso) schemas.Reembolso {
+	item := schemas.Reembolso{
+		IdReembolso:      r.ID,
+		IdOrden:          r.OrdenDeCompraID,
+		Motivo:           r.Motivo,
+		Estado:           util.EstadoReembolso(r.Estado).String(),
+		Monto:            r.Monto,
+		MontoFeeServicio: r.MontoFeeServicio,
+		Tickets:          make([]schemas.TicketReembolsado, 0, len(r.Tickets)),
+		FechaCreacion:    r.FechaCreacion.Format(time.RFC3339),
+	}
+	for _, t := range r.Tickets {
+		item.Tickets = append(item.Tickets, schemas.TicketReembolsado{
+			IdTicket:         t.TicketID,
+			Monto:            t.Monto,
+			MontoFeeServicio: t.MontoFeeServicio,
+		})
+	}
+	if r.NotaCredito != nil {
+		item.NotaCredito = r.NotaCredito.Numero
+	}
+	if r.UltimoError != nil {
+		item.UltimoError = *r.UltimoError
+	}
+	if r.FechaCompletado != nil {
+		item.FechaCompletado = r.FechaCompletado.Format(time.RFC3339)
+	}
+	return item
+}
+
+// reembolsar cancela los tickets de la orden y devuelve lo que corresponde por la pasarela del
+// pago. Si la pasarela falla el reembolso queda FALLIDO (no es un error para quien lo pidió: los
+// tickets ya están cancelados y el dinero se le debe).
+func (a *OrdenDeCompra) reembolsar(
+	orderID int64,
+	ticketIDs []int64,
+	opciones daoPostgresql.OpcionesReembolso,
+	ahora time.Time,
+) (*schemas.Reembolso, *errors.Error) {
+	reembolso, err := a.DaoPostgresql.Reembolso.Crear(orderID, ticketIDs, opciones, ahora)
+	switch {
+	case err == nil:
+	case goerrors.Is(err, daoPostgresql.ErrOrdenNoReembolsable):
+		return nil, &errors.ConflictError.OrderNotRefundable
+	case goerrors.Is(err, daoPostgresql.ErrTicketNoReembolsable):
+		return nil, &errors.ConflictError.TicketNotRefundable
+	case goerrors.Is(err, daoPostgresql.ErrCancelacionCerrada):
+		return nil, &errors.ConflictError.CancellationClosed
+	case err == gorm.ErrRecordNotFound:
+		return nil, &errors.ObjectNotFoundError.OrdenNotFound
+	default:
+		return nil, &errors.InternalServerError.Default
+	}
+
+	resp := a.devolverPorPasarela(reembolso, ahora)
+	return &resp, nil
+}
+
+// devolverPorPasarela pide la devolución de un reembolso PENDIENTE y registra el resultado.
+func (a *OrdenDeCompra) devolverPorPasarela(reembolso *model.Reembolso, ahora time.Time) schemas.Reembolso {
+	fallar := func(causa string) schemas.Reembolso {
+		a.logger.Errorf("Reembolso %d (orden %d): %s", reembolso.ID, reembolso.OrdenDeCompraID, causa)
+		if err := a.DaoPostgresql.Reembolso.RegistrarFallo(reembolso.ID, causa); err == nil {
+			reembolso.Estado = util.ReembolsoFallido.Codigo()
+			reembolso.UltimoError = &causa
+		}
+		return reembolsoASchema(reembolso)
+	}
+
+	pago := reembolso.Pago
+	proveedor, ok := a.pagos.Obtener(util.TipoMetodoPago(pago.MetodoPago))
+	if !ok {
+		return fallar(fmt.Sprintf("sin pasarela para el método %s", pago.MetodoPago))
+	}
+	devuelto, err := proveedor.Reembolsar(context.Background(), pago.Referencia, reembolso.Monto)
+	if err != nil {
+		return fallar(err.Error())
+	}
+
+	completado, err := a.DaoPostgresql.Reembolso.Completar(reembolso.ID, devuelto.Referencia, ahora)
+	if err != nil {
+		// La pasarela ya devolvió el dinero: queda PENDIENTE y no se reintenta por la pasarela
+		a.logger.Errorf("Reembolso %d devuelto (%s) pero no se pudo completar: %v", reembolso.ID, devuelto.Referencia, err)
+		return reembolsoASchema(reembolso)
+	}
+	completado.Tickets = reembolso.Tickets
+	a.logger.Infof("Reembolso %d de la orden %d completado: %.2f", reembolso.ID, reembolso.OrdenDeCompraID, reembolso.Monto)
+	return reembolsoASchema(completado)
+}
+
+// CancelarTickets cancela y reembolsa tickets del usuario: debe ser quien los compró y seguir
+// siendo su titular (los administradores pueden cancelar cualquiera). Salvo para los
+// administradores, la cancelación se cierra corteCancelacion antes del inicio de la fecha del
+// ticket. Se genera un reembolso por orden, sin la parte del fee de servicio.
+func (a *OrdenDeCompra) CancelarTickets(
+	req *schemas.TicketCancelRequest,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.TicketCancelResponse, *errors.Error) {
+	if len(req.IdTickets) == 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidReservationId
+	}
+
+	filas, err := a.DaoPostgresql.Reembolso.TicketsConOrden(req.IdTickets)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	encontrados := make(map[int64]daoPostgresql.TicketDeOrden, len(filas))
+	for _, f := range filas {
+		encontrados[f.TicketID] = f
+	}
+
+	resp := &schemas.TicketCancelResponse{
+		Cancelados: []schemas.TicketCancelado{},
+		Reembolsos: []schemas.Reembolso{},
+	}
+	esAdmin := usuario.TieneAlgunRol(model.RolAdministrador)
+	var ordenes []int64
+	porOrden := map[int64][]int64{}
+	vistos := map[int64]bool{}
+	for _, id := range req.IdTickets {
+		if vistos[id] {
+			continue
+		}
+		vistos[id] = true
+		f, ok := encontrados[id]
+		if !ok {
+			resp.NoEncontrados = append(resp.NoEncontrados, id)
+			continue
+		}
+		if !esAdmin && (f.CompradorID != usuario.ID || f.TitularID != usuario.ID) {
+			resp.NoCancelables = append(resp.NoCancelables, id)
+			continue
+		}
+		if _, ok := porOrden[f.OrdenDeCompraID]; !ok {
+			ordenes = append(ordenes, f.OrdenDeCompraID)
+		}
+		porOrden[f.OrdenDeCompraID] = append(porOrden[f.OrdenDeCompraID], id)
+	}
+
+	opciones := daoPostgresql.OpcionesReembolso{
+		Motivo:    model.MotivoReembolsoCancelacionUsuario,
+		UsuarioID: &usuario.ID,
+	}
+	if !esAdmin {
+		opciones.FechaCerrada = func(ef *model.EventoFecha) bool {
+			return ef.Fecha == nil || !ahora.Before(inicioEventoFecha(ef).Add(-a.corteCancelacion))
+		}
+	}
+	fechaCerrada := false
+	for _, orderID := range ordenes {
+		ids := porOrden[orderID]
+		reembolso, errR := a.reembolsar(orderID, ids, opciones, ahora)
+		if errR != nil {
+			fechaCerrada = fechaCerrada || errR.Code == errors.ConflictError.CancellationClosed.Code
+			resp.NoCancelables = append(resp.NoCancelables, ids...)
+			continue
+		}
+		resp.Reembolsos = append(resp.Reembolsos, *reembolso)
+		for _, id := range ids {
+			resp.Cancelados = append(resp.Cancelados, schemas.TicketCancelado{
+				IdTicket: id,
+				Estado:   util.TicketCancelado.String(),
+			})
+		}
+	}
+
+	if len(resp.Cancelados) == 0 {
+		if fechaCerrada {
+			return nil, &errors.ConflictError.CancellationClosed
+		}
+		// “Error al cancelar” según contrato
+		return nil, &errors.ObjectNotFoundError.EventoNotFound
+	}
+	resp.Mensaje = "Tickets cancelados correctamente."
+	return resp, nil
+}
+
+// ReembolsarOrden reembolsa, a pedido de un administrador, los tickets indicados de la orden o,
+// si no se indica ninguno, todos los que aún se pueden reembolsar.
+func (a *OrdenDeCompra) ReembolsarOrden(
+	orderID int64,
+	req *schemas.ReembolsarOrdenRequest,
+	admin *model.Usuario,
+	ahora time.Time,
+) (*schemas.Reembolso, *errors.Error) {
+	ticketIDs := req.IdTickets
+	if len(ticketIDs) == 0 {
+		ids, err := a.DaoPostgresql.Reembolso.TicketsReembolsables(orderID)
+		if err != nil {
+			return nil, &errors.InternalServerError.Default
+		}
+		if len(ids) == 0 {
+			return nil, &errors.ConflictError.OrderNotRefundable
+		}
+		ticketIDs = ids
+	}
+	return a.reembolsar(orderID, ticketIDs, daoPostgresql.OpcionesReembolso{
+		Motivo:        model.MotivoReembolsoAdministrador,
+		ReembolsarFee: req.ReembolsarFee,
+		UsuarioID:     &admin.ID,
+	}, ahora)
+}
+
+// ListarReembolsosDeOrden devuelve los reembolsos de la orden. Los ve el comprador y los
+// administradores.
+func (a *OrdenDeCompra) ListarReembolsosDeOrden(orderID int64, usuario *model.Usuario) (*schemas.ReembolsosResponse, *errors.Error) {
+	orden, err := a.DaoPostgresql.OrdenDeCompra.ObtenerOrdenBasica(orderID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.OrdenNotFound
+		}
+		return nil, &errors.InternalServerError.Default
+	}
+	if orden.UsuarioID != usuario.ID && !usuario.TieneAlgunRol(model.RolAdministrador) {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+
+	reembolsos, err := a.DaoPostgresql.Reembolso.ListarPorOrden(orderID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	resp := &schemas.ReembolsosResponse{Reembolsos: make([]schemas.Reembolso, 0, len(reembolsos))}
+	for i := range reembolsos {
+		resp.Reembolsos = append(resp.Reembolsos, reembolsoASchema(&reembolsos[i]))
+	}
+	return resp, nil
+}
+
+// ListarReembolsosFallidos devuelve los reembolsos que la pasarela rechazó.
+func (a *OrdenDeCompra) ListarReembolsosFallidos() (*schemas.ReembolsosResponse, *errors.Error) {
+	reembolsos, err := a.DaoPostgresql.Reembolso.ListarFallidos(limiteReembolsosFallidos)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	resp := &schemas.ReembolsosResponse{Reembolsos: make([]schemas.Reembolso, 0, len(reembolsos))}
+	for i := range reembolsos {
+		resp.Reembolsos = append(resp.Reembolsos, reembolsoASchema(&reembolsos[i]))
+	}
+	return resp, nil
+}
+
+// ReintentarReembolso vuelve a pedir a la pasarela la devolución de un reembolso FALLIDO.
+func (a *OrdenDeCompra) ReintentarReembolso(id int64, ahora time.Time) (*schemas.Reembolso, *errors.Error) {
+	ok, err := a.DaoPostgresql.Reembolso.TomarParaReintento(id)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	if !ok {
+		return nil, &errors.ObjectNotFoundError.ReembolsoFallidoNotFound
+	}
+	reembolso, err := a.DaoPostgresql.Reembolso.ObtenerPorID(id)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	resp := a.devolverPorPasarela(reembolso, ahora)
+	return &resp, nil
+}
+
+// ReembolsarEventoCancelado reembolsa, con el fee de servicio incluido, los tickets vendidos que
+// aún quedan de un evento CANCELADO: un reembolso por orden y uno por cada ticket comprado en la
+// reventa (a ese comprador). Las órdenes que se pagaron sin llegar a tener tickets se reembolsan
+// enteras. Si un reembolso no se puede crear se registra y sus tickets siguen pendientes para la
+// siguiente pasada; si lo rechaza la pasarela queda FALLIDO con sus tickets ya cancelados y solo
+// lo reintenta un administrador.
+// Devuelve cuántos reembolsos se crearon.
+func (a *OrdenDeCompra) ReembolsarEventoCancelado(eventoID int64, ahora time.Time) (int, *errors.Error) {
+	pendientes, err := a.DaoPostgresql.Reembolso.PendientesDeEventoCancelado(eventoID)
+	if err != nil {
+		return 0, &errors.InternalServerError.Default
+	}
+
+	opciones := daoPostgresql.OpcionesReembolso{
+		Motivo:        model.MotivoReembolsoCancelacionEvento,
+		ReembolsarFee: true,
+	}
+	creados := 0
+	var ordenes []int64
+	porOrden := map[int64][]int64{}
+	for _, p := range pendientes {
+		if p.PublicacionReventaID == nil {
+			if _, ok := porOrden[p.OrdenDeCompraID]; !ok {
+				ordenes = append(ordenes, p.OrdenDeCompraID)
+			}
+			porOrden[p.OrdenDeCompraID] = append(porOrden[p.OrdenDeCompraID], p.TicketID)
+			continue
+		}
+		reembolso, err := a.DaoPostgresql.Reembolso.CrearPorReventa(*p.PublicacionReventaID, opciones, ahora)
+		if err != nil {
+			a.logger.Warnf("ReembolsarEventoCancelado(%d): reventa %d no reembolsada: %v", eventoID, *p.PublicacionReventaID, err)
+			continue
+		}
+		a.devolverPorPasarela(reembolso, ahora)
+		creados++
+	}
+	for _, orderID := range ordenes {
+		if _, ferr := a.reembolsar(orderID, porOrden[orderID], opciones, ahora); ferr != nil {
+			a.logger.Warnf("ReembolsarEventoCancelado(%d): orden %d no reembolsada: %s", eventoID, orderID, ferr.Message)
+			continue
+		}
+		creados++
+	}
+
+	sinTickets, err := a.DaoPostgresql.Reembolso.OrdenesSinTicketsDeEventoCancelado(eventoID)
+	if err != nil {
+		return creados, &errors.InternalServerError.Default
+	}
+	for _, orderID := range sinTickets {
+		reembolso, err := a.DaoPostgresql.Reembolso.CrearPorOrden(orderID, opciones, ahora)
+		if err != nil {
+			a.logger.Warnf("ReembolsarEventoCancelado(%d): orden %d sin tickets no reembolsada: %v", eventoID, orderID, err)
+			continue
+		}
+		a.devolverPorPasarela(reembolso, ahora)
+		creados++
+	}
+	return creados, nil
+}
+
+// ReembolsarEventosCancelados recorre, en lotes de tamaño lote, todos los eventos CANCELADOS que
+// aún tienen algo por reembolsar y los reembolsa. Avanza por id, así los eventos cuyos pendientes
+// no se pueden reembolsar no tapan a los que siguen. Devuelve cuántos reembolsos se crearon.
+func (a *OrdenDeCompra) ReembolsarEventosCancelados(ctx context.Context, lote int) (int, *errors.Error) {
+	creados := 0
+	var despuesDe int64
+	for ctx.Err() == nil {
+		eventos, err := a.DaoPostgresql.Reembolso.EventosCanceladosConPendientes(despuesDe, lote)
+		if err != nil {
+			return creados, &errors.InternalServerError.Default
+		}
+		for _, eventoID := range eventos {
+			if ctx.Err() != nil {
+				break
+			}
+			n, ferr := a.ReembolsarEventoCancelado(eventoID, time.Now())
+			if ferr != nil {
+				return creados, ferr
+			}
+			creados += n
+			despuesDe = eventoID
+		}
+		if len(eventos) == 0 || len(eventos) < lote {
+			break
+		}
+	}
+	return creados, nil
+}
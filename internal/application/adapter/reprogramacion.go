@@ -0,0 +1,188 @@
+package adapter
+
+import (
+	goerrors "errors"
+	"strings"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// Respuestas del titular a una reprogramación
+const (
+	respuestaReprogramacionAceptar    = "ACEPTAR"
+	respuestaReprogramacionReembolsar = "REEMBOLSAR"
+)
+
+// ReprogramarFecha mueve una fecha del evento con entradas vendidas a otro día u hora. La fecha
+// original queda inactiva, sus tickets pasan a la nueva con un QR nuevo y sus titulares reciben un
+// correo con el plazo para aceptar el cambio o pedir el reembolso. La hace el organizador del
+// evento o un administrador.
+func (a *OrdenDeCompra) ReprogramarFecha(
+	eventoID int64,
+	eventoFechaID int64,
+	req *schemas.ReprogramarFechaRequest,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.ReprogramacionResponse, *errors.Error) {
+	dia, errDia := time.Parse("2006-01-02", req.NuevaFecha)
+	hora, errHora := time.Parse("15:04", req.NuevaHoraInicio)
+	limite, errLimite := time.Parse(time.RFC3339, req.FechaLimite)
+	if errDia != nil || errHora != nil || errLimite != nil {
+		return nil, &errors.UnprocessableEntityError.InvalidDateFormat
+	}
+	inicio := time.Date(dia.Year(), dia.Month(), dia.Day(), hora.Hour(), hora.Minute(), 0, 0, zonaHorariaEventos)
+	if !inicio.After(ahora) || !limite.After(ahora) || !limite.Before(inicio) {
+		return nil, &errors.UnprocessableEntityError.InvalidReschedule
+	}
+
+	ef, err := a.DaoPostgresql.EventoFecha.ObtenerConEventoYFecha(eventoFechaID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.EventoNotFound
+		}
+		a.logger.Errorf("ReprogramarFecha.ObtenerConEventoYFecha(%d): %v", eventoFechaID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if ef.EventoID != eventoID || ef.Evento == nil {
+		return nil, &errors.ObjectNotFoundError.EventoNotFound
+	}
+	if ef.Evento.OrganizadorID != usuario.ID && !usuario.TieneAlgunRol(model.RolAdministrador) {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+
+	reprogramacion, err := a.DaoPostgresql.Reprogramacion.Reprogramar(eventoFechaID, daoPostgresql.DatosReprogramacion{
+		Dia:         dia,
+		HoraInicio:  hora,
+		FechaLimite: limite,
+		UsuarioID:   usuario.ID,
+	}, firmadorQR(a.firmanteQR, ahora), ahora)
+	switch err {
+	case nil:
+	case daoPostgresql.ErrFechaNoReprogramable:
+		return nil, &errors.ConflictError.DateNotReschedulable
+	case daoPostgresql.ErrFechaDestinoOcupada:
+		return nil, &errors.ConflictError.RescheduleDateTaken
+	case gorm.ErrRecordNotFound:
+		return nil, &errors.ObjectNotFoundError.EventoNotFound
+	default:
+		return nil, &errors.InternalServerError.Default
+	}
+	a.logger.Infof("Fecha %d del evento %d reprogramada a la %d (%d tickets, plazo %s)",
+		eventoFechaID, eventoID, reprogramacion.EventoFechaDestinoID, reprogramacion.TicketsMigrados,
+		limite.Format(time.RFC3339))
+
+	return &schemas.ReprogramacionResponse{
+		IdReprogramacion:      reprogramacion.ID,
+		IdEvento:              reprogramacion.EventoID,
+		IdFechaEventoOriginal: reprogramacion.EventoFechaOrigenID,
+		IdFechaEventoNueva:    reprogramacion.EventoFechaDestinoID,
+		Fecha:                 dia.Format("2006-01-02"),
+		HoraInicio:            hora.Format("15:04"),
+		FechaLimite:           reprogramacion.FechaLimite.Format(time.RFC3339),
+		TicketsMigrados:       reprogramacion.TicketsMigrados,
+	}, nil
+}
+
+// ResponderReprogramacion registra la respuesta del titular de un ticket reprogramado dentro del
+// plazo: lo acepta en la fecha nueva o pide el reembolso, que cancela el ticket y devuelve lo
+// pagado con el fee de servicio incluido a quien lo compró (en la reventa, al último comprador).
+func (a *OrdenDeCompra) ResponderReprogramacion(
+	ticketID int64,
+	req *schemas.ResponderReprogramacionRequest,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.RespuestaReprogramacionResponse, *errors.Error) {
+	respuesta := strings.ToUpper(strings.TrimSpace(req.Respuesta))
+	if respuesta != respuestaReprogramacionAceptar && respuesta != respuestaReprogramacionReembolsar {
+		return nil, &errors.UnprocessableEntityError.InvalidRequestBody
+	}
+
+	pendiente, err := a.DaoPostgresql.Reprogramacion.PendienteDeTicket(ticketID)
+	if err == daoPostgresql.ErrReprogramacionNoPendiente {
+		return nil, &errors.ConflictError.RescheduleNotPending
+	}
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	filas, err := a.DaoPostgresql.Reembolso.TicketsConOrden([]int64{ticketID})
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	if len(filas) == 0 || filas[0].TitularID != usuario.ID {
+		return nil, &errors.ForbiddenError.NotRescheduleHolder
+	}
+	if pendiente.ReprogramacionFecha == nil || !ahora.Before(pendiente.ReprogramacionFecha.FechaLimite) {
+		return nil, &errors.ConflictError.RescheduleExpired
+	}
+
+	if respuesta == respuestaReprogramacionAceptar {
+		if _, err := a.DaoPostgresql.Reprogramacion.Aceptar(ticketID, ahora); err != nil {
+			switch err {
+			case daoPostgresql.ErrReprogramacionNoPendiente:
+				return nil, &errors.ConflictError.RescheduleNotPending
+			case daoPostgresql.ErrReprogramacionVencida:
+				return nil, &errors.ConflictError.RescheduleExpired
+			}
+			return nil, &errors.InternalServerError.Default
+		}
+		return &schemas.RespuestaReprogramacionResponse{
+			IdTicket: ticketID,
+			Estado:   util.ReprogramacionAceptada.String(),
+		}, nil
+	}
+
+	origen, err := a.DaoPostgresql.Reembolso.OrigenDeTicket(ticketID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ConflictError.TicketNotRefundable
+		}
+		return nil, &errors.InternalServerError.Default
+	}
+	opciones := daoPostgresql.OpcionesReembolso{
+		Motivo:        model.MotivoReembolsoReprogramacion,
+		ReembolsarFee: true,
+		UsuarioID:     &usuario.ID,
+	}
+	var reembolso *schemas.Reembolso
+	if origen.PublicacionReventaID != nil {
+		creado, err := a.DaoPostgresql.Reembolso.CrearPorReventa(*origen.PublicacionReventaID, opciones, ahora)
+		switch {
+		case err == nil:
+		case goerrors.Is(err, daoPostgresql.ErrOrdenNoReembolsable):
+			return nil, &errors.ConflictError.OrderNotRefundable
+		case goerrors.Is(err, daoPostgresql.ErrTicketNoReembolsable):
+			return nil, &errors.ConflictError.TicketNotRefundable
+		default:
+			return nil, &errors.InternalServerError.Default
+		}
+		resp := a.devolverPorPasarela(creado, ahora)
+		reembolso = &resp
+	} else {
+		var ferr *errors.Error
+		reembolso, ferr = a.reembolsar(origen.OrdenDeCompraID, []int64{ticketID}, opciones, ahora)
+		if ferr != nil {
+			return nil, ferr
+		}
+	}
+	return &schemas.RespuestaReprogramacionResponse{
+		IdTicket:  ticketID,
+		Estado:    util.ReprogramacionReembolsada.String(),
+		Reembolso: reembolso,
+	}, nil
+}
+
+// CerrarReprogramacionesVencidas cierra las reprogramaciones cuyo plazo ya pasó; los tickets sin
+// respuesta se quedan en la fecha nueva. Devuelve cuántas cerró.
+func (a *OrdenDeCompra) CerrarReprogramacionesVencidas(ahora time.Time) (int, *errors.Error) {
+	cerradas, err := a.DaoPostgresql.Reprogramacion.CerrarVencidas(ahora)
+	if err != nil {
+		return 0, &errors.InternalServerError.Default
+	}
+	return cerradas, nil
+}
@@ -0,0 +1,387 @@
+package adapter
+
+import (
+	goerrors "errors"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// topeMaximoReventa limita lo que un organizador puede fijar como precio máximo (en % del original).
+const topeMaximoReventa = 1000.0
+
+// repartoReventa calcula, para un precio de reventa, el fee de la plataforma, la regalía del
+// organizador y lo que recibe el vendedor. El vendedor absorbe el redondeo.
+func repartoReventa(precio float64, regaliaPorcentaje float64) (fee, regalia, vendedor float64) {
+	fee = redondearCentimos(precio * porcentajeFeeServicio)
+	regalia = redondearCentimos(precio * regaliaPorcentaje / 100)
+	vendedor = redondearCentimos(precio - fee - regalia)
+	return fee, regalia, vendedor
+}
+
+func publicacionASchema(p *model.PublicacionReventa, conReparto bool) schemas.PublicacionReventa {
+	resp := schemas.PublicacionReventa{
+		IdPublicacion:  p.ID,
+		IdTicket:       p.TicketID,
+		IdEvento:       p.EventoID,
+		Precio:         p.Precio,
+		PrecioOriginal: p.PrecioOriginal,
+		Estado:         util.EstadoReventa(p.Estado).String(),
+		FechaCreacion:  p.FechaCreacion.Format(time.RFC3339),
+	}
+	if p.FechaVenta != nil {
+		resp.FechaVenta = p.FechaVenta.Format(time.RFC3339)
+	}
+	if conReparto {
+		resp.MontoFeePlataforma = &p.MontoFeePlataforma
+		resp.MontoRegalia = &p.MontoRegalia
+		resp.MontoVendedor = &p.MontoVendedor
+	}
+	if t := p.Ticket; t != nil {
+		resp.IdFechaEvento = t.EventoFechaID
+		if t.EventoFecha != nil && t.EventoFecha.Fecha != nil {
+			resp.FechaEvento = t.EventoFecha.Fecha.FechaEvento.Format(time.DateOnly)
+		}
+		if t.Tarifa != nil {
+			resp.IdSector = t.Tarifa.SectorID
+			if t.Tarifa.Sector != nil {
+				resp.Sector = t.Tarifa.Sector.SectorTipo
+			}
+		}
+	}
+	return resp
+}
+
+func politicaASchema(p *model.PoliticaReventa) *schemas.PoliticaReventaResponse {
+	return &schemas.PoliticaReventaResponse{
+		IdEvento:                p.EventoID,
+		Habilitada:              p.Habilitada,
+		PrecioMaximoPorcentaje:  p.PrecioMaximoPorcentaje,
+		RegaliaPorcentaje:       p.RegaliaPorcentaje,
+		FeePlataformaPorcentaje: porcentajeFeeServicio * 100,
+	}
+}
+
+// verificarOrganizadorDeEvento comprueba que el usuario sea el organizador del evento o un administrador.
+func (a *OrdenDeCompra) verificarOrganizadorDeEvento(eventoID int64, usuario *model.Usuario) *errors.Error {
+	organizadorID, err := a.DaoPostgresql.Reventa.OrganizadorDeEvento(eventoID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &errors.ObjectNotFoundError.EventoNotFound
+		}
+		a.logger.Errorf("verificarOrganizadorDeEvento(%d): %v", eventoID, err)
+		return &errors.InternalServerError.Default
+	}
+	if organizadorID != usuario.ID && !usuario.TieneAlgunRol(model.RolAdministrador) {
+		return &errors.ForbiddenError.InsufficientPermissions
+	}
+	return nil
+}
+
+// obtenerPoliticaHabilitada devuelve la política del evento si la reventa está habilitada.
+func (a *OrdenDeCompra) obtenerPoliticaHabilitada(eventoID int64) (*model.PoliticaReventa, *errors.Error) {
+	politica, err := a.DaoPostgresql.Reventa.ObtenerPolitica(eventoID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ConflictError.ResaleNotEnabled
+		}
+		a.logger.Errorf("obtenerPoliticaHabilitada(%d): %v", eventoID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if !politica.Habilitada {
+		return nil, &errors.ConflictError.ResaleNotEnabled
+	}
+	return politica, nil
+}
+
+// GuardarPoliticaReventa fija si el evento admite reventa, el tope de precio y la regalía del
+// organizador. La regalía más el fee de la plataforma no pueden llevarse todo el precio.
+func (a *OrdenDeCompra) GuardarPoliticaReventa(
+	eventoID int64,
+	req *schemas.PoliticaReventaRequest,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.PoliticaReventaResponse, *errors.Error) {
+	if eventoID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	if req.PrecioMaximoPorcentaje <= 0 || req.PrecioMaximoPorcentaje > topeMaximoReventa ||
+		req.RegaliaPorcentaje < 0 || req.RegaliaPorcentaje+porcentajeFeeServicio*100 >= 100 {
+		return nil, &errors.UnprocessableEntityError.InvalidResalePolicy
+	}
+	if ferr := a.verificarOrganizadorDeEvento(eventoID, usuario); ferr != nil {
+		return nil, ferr
+	}
+
+	politica := &model.PoliticaReventa{
+		EventoID:               eventoID,
+		Habilitada:             req.Habilitada,
+		PrecioMaximoPorcentaje: req.PrecioMaximoPorcentaje,
+		RegaliaPorcentaje:      req.RegaliaPorcentaje,
+		UsuarioModificacion:    &usuario.ID,
+		FechaModificacion:      ahora,
+	}
+	if err := a.DaoPostgresql.Reventa.GuardarPolitica(politica); err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	return politicaASchema(politica), nil
+}
+
+// ObtenerPoliticaReventa devuelve la política de reventa del evento; si el organizador no la
+// configuró, la reventa está deshabilitada.
+func (a *OrdenDeCompra) ObtenerPoliticaReventa(eventoID int64) (*schemas.PoliticaReventaResponse, *errors.Error) {
+	if eventoID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	politica, err := a.DaoPostgresql.Reventa.ObtenerPolitica(eventoID)
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			a.logger.Errorf("ObtenerPoliticaReventa(%d): %v", eventoID, err)
+			return nil, &errors.InternalServerError.Default
+		}
+		politica = &model.PoliticaReventa{EventoID: eventoID, PrecioMaximoPorcentaje: 100}
+	}
+	return politicaASchema(politica), nil
+}
+
+// PublicarReventa pone a la venta un ticket VENDIDO de su titular. El precio no puede superar
+// el tope del evento sobre el precio original de la tarifa; el reparto queda fijado al publicar.
+func (a *OrdenDeCompra) PublicarReventa(
+	ticketID int64,
+	req *schemas.PublicarReventaRequest,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.PublicacionReventa, *errors.Error) {
+	if ticketID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+
+	ticket, err := a.DaoPostgresql.Ticket.ObtenerParaIngreso(ticketID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.TicketNotFound
+		}
+		a.logger.Errorf("PublicarReventa.ObtenerTicket(%d): %v", ticketID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	eventoFecha := ticket.EventoFecha
+	if eventoFecha == nil || eventoFecha.Evento == nil || ticket.Tarifa == nil {
+		a.logger.Errorf("PublicarReventa: ticket %d sin evento o tarifa", ticketID)
+		return nil, &errors.InternalServerError.Default
+	}
+	evento := eventoFecha.Evento
+	if evento.Estado != util.Activo.Codigo() || evento.EventoEstado != util.EventoPublicado.Codigo() ||
+		eventoFecha.Estado != util.Activo.Codigo() {
+		return nil, &errors.ConflictError.ResaleNotEnabled
+	}
+
+	politica, ferr := a.obtenerPoliticaHabilitada(evento.ID)
+	if ferr != nil {
+		return nil, ferr
+	}
+	precio := redondearCentimos(req.Precio)
+	tope := redondearCentimos(ticket.Tarifa.Precio * politica.PrecioMaximoPorcentaje / 100)
+	if precio <= 0 || precio > tope {
+		return nil, &errors.UnprocessableEntityError.InvalidResalePrice
+	}
+
+	fee, regalia, vendedor := repartoReventa(precio, politica.RegaliaPorcentaje)
+	publicacion := &model.PublicacionReventa{
+		TicketID:           ticket.ID,
+		EventoID:           evento.ID,
+		VendedorID:         usuario.ID,
+		Precio:             precio,
+		PrecioOriginal:     ticket.Tarifa.Precio,
+		MontoFeePlataforma: fee,
+		MontoRegalia:       regalia,
+		MontoVendedor:      vendedor,
+		Estado:             util.ReventaPublicada.Codigo(),
+		FechaCreacion:      ahora,
+	}
+	if err := a.DaoPostgresql.Reventa.Publicar(publicacion); err != nil {
+		switch err {
+		case daoPostgresql.ErrNoEsTitular:
+			return nil, &errors.ForbiddenError.NotTicketHolder
+		case daoPostgresql.ErrTicketNoTransferible:
+			return nil, &errors.ConflictError.TicketNotTransferable
+		case daoPostgresql.ErrReventaActiva:
+			return nil, &errors.ConflictError.TicketAlreadyListed
+		case gorm.ErrRecordNotFound:
+			return nil, &errors.ObjectNotFoundError.TicketNotFound
+		}
+		return nil, &errors.InternalServerError.Default
+	}
+
+	a.logger.Infof("Reventa %d: ticket %d publicado por usuario %d a %.2f", publicacion.ID, ticket.ID, usuario.ID, precio)
+	publicacion.Ticket = ticket
+	resp := publicacionASchema(publicacion, true)
+	return &resp, nil
+}
+
+// CancelarReventa retira una publicación del vendedor mientras nadie la haya reservado.
+func (a *OrdenDeCompra) CancelarReventa(publicacionID int64, usuario *model.Usuario) (*schemas.PublicacionReventa, *errors.Error) {
+	if publicacionID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	publicacion, err := a.DaoPostgresql.Reventa.ObtenerPorID(publicacionID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.PublicacionReventaNotFound
+		}
+		a.logger.Errorf("CancelarReventa.Obtener(%d): %v", publicacionID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if publicacion.VendedorID != usuario.ID {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+
+	cancelada, err := a.DaoPostgresql.Reventa.Cancelar(publicacionID, usuario.ID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	if !cancelada {
+		return nil, &errors.ConflictError.ResaleNotAvailable
+	}
+	publicacion.Estado = util.ReventaCancelada.Codigo()
+	resp := publicacionASchema(publicacion, true)
+	return &resp, nil
+}
+
+// ListarReventasDeEvento devuelve las publicaciones disponibles del evento.
+func (a *OrdenDeCompra) ListarReventasDeEvento(eventoID int64) (*schemas.PublicacionesReventaResponse, *errors.Error) {
+	if eventoID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	publicaciones, err := a.DaoPostgresql.Reventa.ListarPublicadasPorEvento(eventoID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	resp := &schemas.PublicacionesReventaResponse{
+		Publicaciones: make([]schemas.PublicacionReventa, 0, len(publicaciones)),
+	}
+	for i := range publicaciones {
+		resp.Publicaciones = append(resp.Publicaciones, publicacionASchema(&publicaciones[i], false))
+	}
+	return resp, nil
+}
+
+// ListarMisReventas devuelve las publicaciones del usuario con su reparto.
+func (a *OrdenDeCompra) ListarMisReventas(usuario *model.Usuario) (*schemas.PublicacionesReventaResponse, *errors.Error) {
+	publicaciones, err := a.DaoPostgresql.Reventa.ListarPorVendedor(usuario.ID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	resp := &schemas.PublicacionesReventaResponse{
+		Publicaciones: make([]schemas.PublicacionReventa, 0, len(publicaciones)),
+	}
+	for i := range publicaciones {
+		resp.Publicaciones = append(resp.Publicaciones, publicacionASchema(&publicaciones[i], true))
+	}
+	return resp, nil
+}
+
+// ReservarReventa abre la compra de una publicación: crea una orden TEMPORAL por el precio de
+// reventa que sigue el flujo normal de pago y confirmación. Al confirmarse, el ticket pasa al
+// comprador con un QR nuevo; si el hold vence, la publicación vuelve a estar disponible.
+func (a *OrdenDeCompra) ReservarReventa(
+	publicacionID int64,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.CrearOrdenTemporalResponse, *errors.Error) {
+	if publicacionID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	publicacion, err := a.DaoPostgresql.Reventa.ObtenerPorID(publicacionID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.PublicacionReventaNotFound
+		}
+		a.logger.Errorf("ReservarReventa.Obtener(%d): %v", publicacionID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if publicacion.Estado != util.ReventaPublicada.Codigo() {
+		return nil, &errors.ConflictError.ResaleNotAvailable
+	}
+	if publicacion.VendedorID == usuario.ID {
+		return nil, &errors.UnprocessableEntityError.CannotBuyOwnResale
+	}
+	if _, ferr := a.obtenerPoliticaHabilitada(publicacion.EventoID); ferr != nil {
+		return nil, ferr
+	}
+
+	expiresAt := ahora.Add(time.Duration(ttlReservaSegundos) * time.Second)
+	orden := &model.OrdenDeCompra{
+		UsuarioID:        usuario.ID,
+		Fecha:            ahora,
+		FechaHoraIni:     ahora,
+		FechaHoraFin:     &expiresAt,
+		Total:            publicacion.Precio,
+		MontoFeeServicio: publicacion.MontoFeePlataforma,
+	}
+	if err := a.DaoPostgresql.Reventa.Reservar(publicacionID, orden); err != nil {
+		switch err {
+		case daoPostgresql.ErrReventaNoDisponible:
+			return nil, &errors.ConflictError.ResaleNotAvailable
+		case gorm.ErrRecordNotFound:
+			return nil, &errors.ObjectNotFoundError.PublicacionReventaNotFound
+		}
+		if goerrors.Is(err, daoPostgresql.ErrEventoNoALaVenta) {
+			return nil, &errors.ConflictError.EventNotOnSale
+		}
+		return nil, &errors.BadRequestError.OrdenNotCreated
+	}
+
+	a.logger.Infof("Orden temporal %d creada para la reventa %d (Total: %.2f)", orden.ID, publicacionID, orden.Total)
+
+	item := schemas.ItemOrdenResponse{
+		Cantidad:       1,
+		PrecioUnitario: publicacion.Precio,
+		Subtotal:       publicacion.Precio,
+	}
+	if t := publicacion.Ticket; t != nil && t.Tarifa != nil {
+		item.IdTarifa = t.TarifaID
+		item.IdSector = t.Tarifa.SectorID
+		item.IdTipoTicket = t.Tarifa.TipoDeTicketID
+		item.IdPerfil = t.Tarifa.PerfilDePersonaID
+	}
+	return &schemas.CrearOrdenTemporalResponse{
+		OrderID:          orden.ID,
+		Estado:           "TEMPORAL",
+		Items:            []schemas.ItemOrdenResponse{item},
+		Subtotal:         orden.Total,
+		Total:            orden.Total,
+		MontoFeeServicio: orden.MontoFeeServicio,
+		StartedAt:        orden.FechaHoraIni.Format(time.RFC3339),
+		ExpiresAt:        expiresAt.Format(time.RFC3339),
+		TTLSeconds:       ttlReservaSegundos,
+	}, nil
+}
+
+// ResumenReventa suma las reventas concretadas del evento: total vendido, fee de la plataforma,
+// regalías del organizador y lo pagado a los vendedores.
+func (a *OrdenDeCompra) ResumenReventa(eventoID int64, usuario *model.Usuario) (*schemas.ResumenReventaResponse, *errors.Error) {
+	if eventoID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	if ferr := a.verificarOrganizadorDeEvento(eventoID, usuario); ferr != nil {
+		return nil, ferr
+	}
+	resumen, err := a.DaoPostgresql.Reventa.ResumenPorEvento(eventoID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	return &schemas.ResumenReventaResponse{
+		IdEvento:           eventoID,
+		Publicadas:         resumen.Publicadas,
+		Vendidas:           resumen.Vendidas,
+		MontoTotal:         redondearCentimos(resumen.MontoTotal),
+		MontoFeePlataforma: redondearCentimos(resumen.MontoFeePlataforma),
+		MontoRegalia:       redondearCentimos(resumen.MontoRegalia),
+		MontoVendedores:    redondearCentimos(resumen.MontoVendedores),
+	}, nil
+}
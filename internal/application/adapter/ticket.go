@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/pases"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/qr"
 	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
 	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
@@ -14,23 +16,64 @@ import (
 )
 
 type Ticket struct {
-	logger        logging.Logger
-	DaoPostgresql *daoPostgresql.NexiventPsqlEntidades
+	logger         logging.Logger
+	DaoPostgresql  *daoPostgresql.NexiventPsqlEntidades
+	firmanteQR     *qr.Firmante
+	verificadorQR  *qr.Verificador
+	firmanteWallet *pases.FirmanteWallet // nil si no hay certificado de pases configurado
+	ventanaIngreso VentanaIngreso
 }
 
 func NewTicketAdapter(
 	logger logging.Logger,
 	daoPostgresql *daoPostgresql.NexiventPsqlEntidades,
+	firmanteQR *qr.Firmante,
+	verificadorQR *qr.Verificador,
+	firmanteWallet *pases.FirmanteWallet,
+	ventanaIngreso VentanaIngreso,
 ) *Ticket {
 	return &Ticket{
-		logger:        logger,
-		DaoPostgresql: daoPostgresql,
+		logger:         logger,
+		DaoPostgresql:  daoPostgresql,
+		firmanteQR:     firmanteQR,
+		verificadorQR:  verificadorQR,
+		firmanteWallet: firmanteWallet,
+		ventanaIngreso: ventanaIngreso,
 	}
 }
 
-// Genera un código QR pseudo-único (puedes ajustar el formato)
-func generarCodigoQR(orderID int64, tarifaID int64, corr int64) string {
-	return fmt.Sprintf("TCK-%d-%d-%d", orderID, tarifaID, corr)
+// firmadorQR devuelve la función con la que el DAO firma el QR de cada ticket una vez
+// conocido su ID.
+func firmadorQR(firmante *qr.Firmante, emitidoEn time.Time) func(*model.Ticket) (string, error) {
+	return func(ticket *model.Ticket) (string, error) {
+		return firmante.Firmar(qr.Contenido{
+			TicketID:      ticket.ID,
+			EventoFechaID: ticket.EventoFechaID,
+			TarifaID:      ticket.TarifaID,
+			EmitidoEn:     emitidoEn,
+		})
+	}
+}
+
+func (t *Ticket) firmarQR(emitidoEn time.Time) func(*model.Ticket) (string, error) {
+	return firmadorQR(t.firmanteQR, emitidoEn)
+}
+
+// ClavesQR publica las claves con las que los escáneres validan los QR sin conexión.
+func (t *Ticket) ClavesQR() *schemas.ClavesQRResponse {
+	claves := t.verificadorQR.Claves()
+	resp := &schemas.ClavesQRResponse{
+		Algoritmo: "Ed25519",
+		KIDActual: t.firmanteQR.KID(),
+		Claves:    make([]schemas.ClaveQR, 0, len(claves)),
+	}
+	for _, c := range claves {
+		resp.Claves = append(resp.Claves, schemas.ClaveQR{
+			KID:          c.KID,
+			ClavePublica: qr.CodificarClave(c.Clave),
+		})
+	}
+	return resp
 }
 
 func (t *Ticket) EmitirTickets(orderID int64) (*schemas.TicketIssueResponse, *errors.Error) {
@@ -61,75 +104,61 @@ func (t *Ticket) EmitirTickets(orderID int64) (*schemas.TicketIssueResponse, *er
 		return nil, &errors.InternalServerError.Default
 	}
 	if yaTiene {
-		// Orden ya procesada
-		return nil, &errors.ObjectNotFoundError.EventoNotFound
+		return nil, &errors.ConflictError.TicketsAlreadyIssued
 	}
 
-	// 3) Obtener detalles de la orden
-	detalles, err := daoTicket.ObtenerDetallesOrden(orderID)
+	// 3) Obtener detalles de la orden (el stock ya se reservó al crear el hold)
+	detalles, err := t.DaoPostgresql.OrdenDetalle.ListarPorOrden(orderID)
 	if err != nil {
-		t.logger.Errorf("EmitirTickets.ObtenerDetallesOrden(%d): %v", orderID, err)
+		t.logger.Errorf("EmitirTickets.ListarDetalles(%d): %v", orderID, err)
 		return nil, &errors.InternalServerError.Default
 	}
 	if len(detalles) == 0 {
 		return nil, &errors.ObjectNotFoundError.EventoNotFound
 	}
 
-	// 4) Verificar stock por cada detalle
-	for _, d := range detalles {
-		ok, err := daoTicket.VerificarStockDisponible(d.TarifaID, d.Cantidad)
-		if err != nil {
-			t.logger.Errorf("EmitirTickets.VerificarStockDisponible(order=%d, tarifa=%d): %v", orderID, d.TarifaID, err)
-			return nil, &errors.InternalServerError.Default
-		}
-		if !ok {
-			return nil, &errors.ObjectNotFoundError.EventoNotFound
-		}
+	// El comprador es el primer titular de los tickets
+	orden, err := t.DaoPostgresql.OrdenDeCompra.ObtenerOrdenBasica(orderID)
+	if err != nil {
+		t.logger.Errorf("EmitirTickets.ObtenerOrdenBasica(%d): %v", orderID, err)
+		return nil, &errors.InternalServerError.Default
 	}
 
-	// 5) Crear modelos de tickets (solo lógica de negocio, sin BD)
+	// 4) Crear modelos de tickets (solo lógica de negocio, sin BD)
 	var ticketsAInsertar []model.Ticket
 	for _, d := range detalles {
 		for i := int64(0); i < d.Cantidad; i++ {
-			qr := generarCodigoQR(orderID, d.TarifaID, i)
 			ticketModel := model.Ticket{
 				OrdenDeCompraID: &orderID,
+				TitularID:       &orden.UsuarioID,
 				EventoFechaID:   d.EventoFechaID,
 				TarifaID:        d.TarifaID,
-				CodigoQR:        qr,
 				EstadoDeTicket:  util.EstadoDeTicket(0).Codigo(), // DISPONIBLE
 			}
 			ticketsAInsertar = append(ticketsAInsertar, ticketModel)
 		}
 	}
 
-	// 6) Insertar tickets en BD (DAO)
-	if err := daoTicket.CrearTicketsBatch(ticketsAInsertar); err != nil {
-		t.logger.Errorf("EmitirTickets.CrearTicketsBatch(order=%d): %v", orderID, err)
-		return nil, &errors.InternalServerError.Default
-	}
-
-	// 7) Actualizar cant_vendidas por sector
-	for _, d := range detalles {
-		sectorID, err := daoTicket.ObtenerSectorPorTarifa(d.TarifaID)
-		if err != nil {
-			t.logger.Errorf("EmitirTickets.ObtenerSectorPorTarifa(tarifa=%d): %v", d.TarifaID, err)
-			return nil, &errors.InternalServerError.Default
+	// 5) Insertar tickets en BD (DAO), con el QR firmado; el correo al comprador queda encolado
+	if err := daoTicket.EmitirTicketsDeOrden(orderID, ticketsAInsertar, t.firmarQR(time.Now())); err != nil {
+		if err == daoPostgresql.ErrTicketsYaEmitidos {
+			return nil, &errors.ConflictError.TicketsAlreadyIssued
 		}
-		if err := daoTicket.IncrementarVendidasPorSector(sectorID, d.Cantidad); err != nil {
-			t.logger.Errorf("EmitirTickets.IncrementarVendidasPorSector(sector=%d): %v", sectorID, err)
-			return nil, &errors.InternalServerError.Default
+		if err == daoPostgresql.ErrEventoNoALaVenta {
+			return nil, &errors.ConflictError.EventNotOnSale
 		}
+		t.logger.Errorf("EmitirTickets.CrearTicketsBatch(order=%d): %v", orderID, err)
+		return nil, &errors.InternalServerError.Default
 	}
 
-	// 8) Traer info para respuesta (JOINs complejos ya encapsulados en DAO)
+	// 6) Traer info para respuesta (JOINs complejos ya encapsulados en DAO)
 	infoRows, err := daoTicket.ObtenerTicketsInfoPorOrden(orderID)
 	if err != nil {
 		t.logger.Errorf("EmitirTickets.ObtenerTicketsInfoPorOrden(order=%d): %v", orderID, err)
 		return nil, &errors.InternalServerError.Default
 	}
 
-	// 9) Mapear a schemas
+	// 7) Mapear a schemas
 	ticketsResp := make([]schemas.TicketEmitido, 0, len(infoRows))
 	for _, row := range infoRows {
 		ticketsResp = append(ticketsResp, schemas.TicketEmitido{
@@ -148,89 +177,11 @@ func (t *Ticket) EmitirTickets(orderID int64) (*schemas.TicketIssueResponse, *er
 	}, nil
 }
 
-func (t *Ticket) CancelarTickets(req *schemas.TicketCancelRequest) (*schemas.TicketCancelResponse, *errors.Error) {
-	if len(req.IdTickets) == 0 {
-		return nil, &errors.UnprocessableEntityError.InvalidReservationId
-	}
-
-	daoTicket := t.DaoPostgresql.Ticket
-
-	// 1) Obtener estado y tarifa de los tickets
-	rows, err := daoTicket.ObtenerTicketsEstadoTarifaPorIDs(req.IdTickets)
-	if err != nil {
-		t.logger.Errorf("CancelarTickets.ObtenerTicketsEstadoTarifaPorIDs: %v", err)
-		return nil, &errors.InternalServerError.Default
-	}
-
-	found := make(map[int64]daoPostgresql.TicketEstadoTarifa, len(rows))
-	for _, r := range rows {
-		found[r.ID] = r
-	}
-
-	cancelados := []schemas.TicketCancelado{}
-	noEncontrados := []int64{}
-	noCancelables := []int64{}
-
-	for _, id := range req.IdTickets {
-		row, ok := found[id]
-		if !ok {
-			noEncontrados = append(noEncontrados, id)
-			continue
-		}
-
-		// No se pueden cancelar USADO(1) ni CANCELADO(2)
-		if row.EstadoDeTicket == 2 || row.EstadoDeTicket == 3 {
-			noCancelables = append(noCancelables, id)
-			continue
-		}
-
-		// Cambiar a CANCELADO
-		if err := daoTicket.CambiarEstadoTicket(id, util.EstadoDeTicket(2)); err != nil {
-			t.logger.Errorf("CancelarTickets.CambiarEstadoTicket(%d): %v", id, err)
-			noCancelables = append(noCancelables, id)
-			continue
-		}
-
-		// Sector del ticket (vía tarifa)
-		sectorID, err := daoTicket.ObtenerSectorPorTarifa(row.TarifaID)
-		if err != nil {
-			t.logger.Errorf("CancelarTickets.ObtenerSectorPorTarifa(tarifa=%d): %v", row.TarifaID, err)
-			noCancelables = append(noCancelables, id)
-			continue
-		}
-
-		// Decrementar vendidas (sube disponibilidad)
-		if err := daoTicket.DecrementarVendidasPorSector(sectorID, 1); err != nil {
-			t.logger.Errorf("CancelarTickets.DecrementarVendidasPorSector(sector=%d): %v", sectorID, err)
-			noCancelables = append(noCancelables, id)
-			continue
-		}
-
-		cancelados = append(cancelados, schemas.TicketCancelado{
-			IdTicket: id,
-			Estado:   util.EstadoDeTicket(3).String(),
-		})
-	}
-
-	if len(cancelados) == 0 {
-		// “Error al cancelar” según contrato
-		return nil, &errors.ObjectNotFoundError.EventoNotFound
-	}
-
-	resp := &schemas.TicketCancelResponse{
-		Cancelados:    cancelados,
-		NoEncontrados: noEncontrados,
-		NoCancelables: noCancelables,
-		Mensaje:       "Tickets cancelados correctamente.",
-	}
-	return resp, nil
-}
-
 func (t *Ticket) EmitirTicketsConInfo(
 	req *schemas.EmitirTicketsRequest,
 ) (*schemas.EmitirTicketsResponse, *errors.Error) {
 
-	if req.OrderID == 0 || req.UserID == 0 || len(req.Tickets) == 0 {
+	if req.OrderID == 0 || req.UserID == 0 {
 		return nil, &errors.UnprocessableEntityError.InvalidRequestBody
 	}
 
@@ -254,34 +205,69 @@ func (t *Ticket) EmitirTicketsConInfo(
 		return nil, &errors.BadRequestError.EventoNotFound
 	}
 
-	var ticketsGenerados []schemas.TicketGenerado
+	// Las órdenes normales reciben sus tickets al confirmarse el pago; aquí solo se devuelven.
+	// La emisión de abajo queda para órdenes confirmadas antes de que fuera así.
+	yaTiene, err := t.DaoPostgresql.Ticket.VerificarTicketsExistentes(req.OrderID)
+	if err != nil {
+		t.logger.Errorf("EmitirTicketsConInfo.VerificarTicketsExistentes(%d): %v", req.OrderID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if yaTiene {
+		return t.ticketsEmitidos(req.OrderID)
+	}
 
-	for _, ticketInfo := range req.Tickets {
-		for i := 0; i < ticketInfo.Cantidad; i++ {
-			timestamp := time.Now().UnixNano()
-			codigoQR := fmt.Sprintf("QR-%d-%d-%d-%d", timestamp, req.OrderID, ticketInfo.IdTarifa, i)
+	// Los tickets salen de los detalles guardados en el hold, no de lo que manda el cliente
+	detalles, err := t.DaoPostgresql.OrdenDetalle.ListarPorOrden(req.OrderID)
+	if err != nil {
+		t.logger.Errorf("EmitirTicketsConInfo.ListarDetalles(%d): %v", req.OrderID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	if len(detalles) == 0 {
+		t.logger.Warnf("Orden %d no tiene detalles", req.OrderID)
+		return nil, &errors.ObjectNotFoundError.OrdenNotFound
+	}
 
+	var tickets []model.Ticket
+	var zonas []string
+	for _, d := range detalles {
+		zona := ""
+		if d.Sector != nil {
+			zona = d.Sector.SectorTipo
+		}
+		for i := int64(0); i < d.Cantidad; i++ {
 			ordenID := req.OrderID
-			ticket := &model.Ticket{
+			tickets = append(tickets, model.Ticket{
 				OrdenDeCompraID: &ordenID,
-				EventoFechaID:   req.IdFechaEvento,
-				TarifaID:        ticketInfo.IdTarifa,
-				CodigoQR:        codigoQR,
+				TitularID:       &orden.UsuarioID,
+				EventoFechaID:   d.EventoFechaID,
+				TarifaID:        d.TarifaID,
 				EstadoDeTicket:  util.TicketVendido.Codigo(), // ESTADO 1
-			}
-
-			if err := t.DaoPostgresql.Ticket.Crear(ticket); err != nil {
-				t.logger.Errorf("EmitirTicketsConInfo.CrearTicket: %v", err)
-				return nil, &errors.BadRequestError.EventoNotCreated
-			}
-
-			ticketsGenerados = append(ticketsGenerados, schemas.TicketGenerado{
-				IdTicket: fmt.Sprintf("%d", ticket.ID),
-				CodigoQR: ticket.CodigoQR,
-				Estado:   "VENDIDO",
-				Zona:     ticketInfo.NombreZona,
 			})
+			zonas = append(zonas, zona)
+		}
+	}
+
+	// El correo de confirmación con los tickets se encola en la misma transacción. La revisión de
+	// arriba es solo para responder rápido: la que cuenta se repite con la orden bloqueada.
+	if err := t.DaoPostgresql.Ticket.EmitirTicketsDeOrden(req.OrderID, tickets, t.firmarQR(time.Now())); err != nil {
+		if err == daoPostgresql.ErrTicketsYaEmitidos {
+			return t.ticketsEmitidos(req.OrderID)
+		}
+		if err == daoPostgresql.ErrEventoNoALaVenta {
+			return nil, &errors.ConflictError.EventNotOnSale
 		}
+		t.logger.Errorf("EmitirTicketsConInfo.CrearTickets: %v", err)
+		return nil, &errors.BadRequestError.EventoNotCreated
+	}
+
+	ticketsGenerados := make([]schemas.TicketGenerado, 0, len(tickets))
+	for i, ticket := range tickets {
+		ticketsGenerados = append(ticketsGenerados, schemas.TicketGenerado{
+			IdTicket: fmt.Sprintf("%d", ticket.ID),
+			CodigoQR: ticket.CodigoQR,
+			Estado:   "VENDIDO",
+			Zona:     zonas[i],
+		})
 	}
 
 	t.logger.Infof("✅ Tickets generados para orden %d: %d tickets", req.OrderID, len(ticketsGenerados))
@@ -293,6 +279,27 @@ func (t *Ticket) EmitirTicketsConInfo(
 	return resp, nil
 }
 
+// ticketsEmitidos arma la respuesta de EmitirTicketsConInfo con los tickets que la orden ya tiene.
+func (t *Ticket) ticketsEmitidos(orderID int64) (*schemas.EmitirTicketsResponse, *errors.Error) {
+	infoRows, err := t.DaoPostgresql.Ticket.ObtenerTicketsInfoPorOrden(orderID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	resp := &schemas.EmitirTicketsResponse{
+		Tickets: make([]schemas.TicketGenerado, 0, len(infoRows)),
+		OrderID: orderID,
+	}
+	for _, row := range infoRows {
+		resp.Tickets = append(resp.Tickets, schemas.TicketGenerado{
+			IdTicket: fmt.Sprintf("%d", row.ID),
+			CodigoQR: row.CodigoQR,
+			Estado:   util.EstadoDeTicket(row.Estado).String(),
+			Zona:     row.SectorTipo,
+		})
+	}
+	return resp, nil
+}
+
 func (t *Ticket) ObtenerTicketsPostesqlPorUsuario(idUser int64) ([]schemas.TicketDetalle, *errors.Error) {
 	tickets, err := t.DaoPostgresql.Ticket.ObternerTicketsPorUsuario(idUser)
 	if err != nil {
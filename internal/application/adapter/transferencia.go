@@ -0,0 +1,238 @@
+package adapter
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	"github.com/Nexivent/nexivent-backend/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// vigenciaTransferencia es cuánto tiempo puede aceptarse una oferta de transferencia.
+const vigenciaTransferencia = 7 * 24 * time.Hour
+
+// errorTransferencia traduce los rechazos del DAO de transferencias a errores de la API.
+func (t *Ticket) errorTransferencia(err error, operacion string) *errors.Error {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		return &errors.ObjectNotFoundError.TransferenciaNotFound
+	case daoPostgresql.ErrNoEsTitular:
+		return &errors.ForbiddenError.NotTicketHolder
+	case daoPostgresql.ErrTicketNoTransferible:
+		return &errors.ConflictError.TicketNotTransferable
+	case daoPostgresql.ErrTransferenciaPendiente:
+		return &errors.ConflictError.TransferAlreadyPending
+	case daoPostgresql.ErrTransferenciaNoPendiente:
+		return &errors.ConflictError.TransferNotPending
+	case daoPostgresql.ErrTransferenciaExpirada:
+		return &errors.ConflictError.TransferExpired
+	}
+	t.logger.Errorf("%s: %v", operacion, err)
+	return &errors.InternalServerError.Default
+}
+
+// estadoTransferencia muestra como EXPIRADA una oferta PENDIENTE vencida aunque todavía no se
+// haya marcado en la BD.
+func estadoTransferencia(tr *model.TransferenciaTicket, ahora time.Time) util.EstadoTransferencia {
+	estado := util.EstadoTransferencia(tr.Estado)
+	if estado == util.TransferenciaPendiente && !ahora.Before(tr.FechaExpiracion) {
+		return util.TransferenciaExpirada
+	}
+	return estado
+}
+
+func transferenciaASchema(tr *model.TransferenciaTicket, usuarioID int64, ahora time.Time) schemas.TransferenciaTicket {
+	resp := schemas.TransferenciaTicket{
+		IdTransferencia: tr.ID,
+		IdTicket:        tr.TicketID,
+		IdDeUsuario:     tr.DeUsuarioID,
+		ParaCorreo:      tr.ParaCorreo,
+		Estado:          estadoTransferencia(tr, ahora).String(),
+		Enviada:         tr.DeUsuarioID == usuarioID,
+		FechaCreacion:   tr.FechaCreacion.Format(time.RFC3339),
+		FechaExpiracion: tr.FechaExpiracion.Format(time.RFC3339),
+	}
+	if tr.FechaResolucion != nil {
+		resp.FechaResolucion = tr.FechaResolucion.Format(time.RFC3339)
+	}
+	if tr.DeUsuario != nil {
+		resp.DeNombre = tr.DeUsuario.Nombre
+	}
+	if tr.Ticket != nil && tr.Ticket.EventoFecha != nil {
+		if ev := tr.Ticket.EventoFecha.Evento; ev != nil {
+			resp.IdEvento = ev.ID
+			resp.Evento = ev.Titulo
+		}
+		if f := tr.Ticket.EventoFecha.Fecha; f != nil {
+			resp.FechaEvento = f.FechaEvento.Format(time.DateOnly)
+		}
+	}
+	return resp
+}
+
+// OfrecerTransferencia registra una oferta para pasar el ticket a quien tenga el correo
+// indicado. Solo el titular actual puede ofrecerlo y solo mientras el ticket esté VENDIDO.
+func (t *Ticket) OfrecerTransferencia(
+	ticketID int64,
+	req *schemas.OfrecerTransferenciaRequest,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.TransferenciaTicket, *errors.Error) {
+	if ticketID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	direccion, err := mail.ParseAddress(strings.TrimSpace(req.ParaCorreo))
+	if err != nil || direccion.Name != "" || strings.EqualFold(direccion.Address, usuario.Correo) {
+		return nil, &errors.UnprocessableEntityError.InvalidTransferRecipient
+	}
+
+	transferencia := &model.TransferenciaTicket{
+		TicketID:        ticketID,
+		DeUsuarioID:     usuario.ID,
+		ParaCorreo:      direccion.Address,
+		Estado:          util.TransferenciaPendiente.Codigo(),
+		FechaCreacion:   ahora,
+		FechaExpiracion: ahora.Add(vigenciaTransferencia),
+	}
+	if err := t.DaoPostgresql.Transferencia.CrearOferta(transferencia); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.TicketNotFound
+		}
+		return nil, t.errorTransferencia(err, "OfrecerTransferencia")
+	}
+
+	t.logger.Infof("Transferencia %d: ticket %d ofrecido por usuario %d", transferencia.ID, ticketID, usuario.ID)
+	resp := transferenciaASchema(transferencia, usuario.ID, ahora)
+	return &resp, nil
+}
+
+// ListarTransferencias devuelve las ofertas enviadas por el usuario y las recibidas en su correo.
+func (t *Ticket) ListarTransferencias(usuario *model.Usuario, ahora time.Time) (*schemas.TransferenciasResponse, *errors.Error) {
+	transferencias, err := t.DaoPostgresql.Transferencia.ListarPorUsuario(usuario.ID, usuario.Correo)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	resp := &schemas.TransferenciasResponse{
+		Transferencias: make([]schemas.TransferenciaTicket, 0, len(transferencias)),
+	}
+	for i := range transferencias {
+		resp.Transferencias = append(resp.Transferencias, transferenciaASchema(&transferencias[i], usuario.ID, ahora))
+	}
+	return resp, nil
+}
+
+// AceptarTransferencia pasa el ticket al usuario, que debe ser el destinatario de la oferta y
+// tener la cuenta verificada: la oferta se hace por correo y solo la verificación prueba que el
+// correo es suyo. El ticket recibe un QR nuevo firmado en este momento; el QR anterior deja de
+// servir en puerta.
+func (t *Ticket) AceptarTransferencia(
+	transferenciaID int64,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.TransferenciaTicket, *errors.Error) {
+	if transferenciaID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	oferta, err := t.DaoPostgresql.Transferencia.ObtenerPorID(transferenciaID)
+	if err != nil {
+		return nil, t.errorTransferencia(err, "AceptarTransferencia.ObtenerPorID")
+	}
+	if !strings.EqualFold(oferta.ParaCorreo, usuario.Correo) {
+		return nil, &errors.ForbiddenError.TransferNotForUser
+	}
+	if usuario.EstadoDeCuenta != 1 {
+		return nil, &errors.ForbiddenError.AccountNotVerified
+	}
+
+	aceptada, err := t.DaoPostgresql.Transferencia.Aceptar(transferenciaID, usuario.ID, t.firmarQR(ahora), ahora)
+	if err != nil {
+		return nil, t.errorTransferencia(err, "AceptarTransferencia")
+	}
+
+	t.logger.Infof("Transferencia %d aceptada: ticket %d pasa del usuario %d al %d",
+		aceptada.ID, aceptada.TicketID, aceptada.DeUsuarioID, usuario.ID)
+	resp := transferenciaASchema(aceptada, usuario.ID, ahora)
+	return &resp, nil
+}
+
+// CancelarTransferencia retira una oferta PENDIENTE. Puede hacerlo quien la ofreció o el
+// destinatario (para rechazarla).
+func (t *Ticket) CancelarTransferencia(
+	transferenciaID int64,
+	usuario *model.Usuario,
+	ahora time.Time,
+) (*schemas.TransferenciaTicket, *errors.Error) {
+	if transferenciaID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	oferta, err := t.DaoPostgresql.Transferencia.ObtenerPorID(transferenciaID)
+	if err != nil {
+		return nil, t.errorTransferencia(err, "CancelarTransferencia.ObtenerPorID")
+	}
+	if oferta.DeUsuarioID != usuario.ID && !strings.EqualFold(oferta.ParaCorreo, usuario.Correo) {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+
+	cancelada, err := t.DaoPostgresql.Transferencia.Cancelar(transferenciaID, usuario.ID, ahora)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	if !cancelada {
+		return nil, &errors.ConflictError.TransferNotPending
+	}
+
+	oferta.Estado = util.TransferenciaCancelada.Codigo()
+	oferta.FechaResolucion = &ahora
+	oferta.ResueltaPorID = &usuario.ID
+	resp := transferenciaASchema(oferta, usuario.ID, ahora)
+	return &resp, nil
+}
+
+// HistorialTitulares devuelve quién tuvo el ticket y desde cuándo. Lo pueden ver el titular
+// actual, el comprador original y los administradores.
+func (t *Ticket) HistorialTitulares(ticketID int64, usuario *model.Usuario) (*schemas.HistorialTitularesResponse, *errors.Error) {
+	if ticketID <= 0 {
+		return nil, &errors.UnprocessableEntityError.InvalidParsingInteger
+	}
+	titularID, compradorID, err := t.DaoPostgresql.Transferencia.TitularDeTicket(ticketID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.TicketNotFound
+		}
+		return nil, &errors.InternalServerError.Default
+	}
+	if usuario.ID != titularID && usuario.ID != compradorID && !usuario.TieneAlgunRol(model.RolAdministrador) {
+		return nil, &errors.ForbiddenError.InsufficientPermissions
+	}
+
+	historial, err := t.DaoPostgresql.Transferencia.ListarHistorial(ticketID)
+	if err != nil {
+		return nil, &errors.InternalServerError.Default
+	}
+	resp := &schemas.HistorialTitularesResponse{
+		IdTicket:  ticketID,
+		IdTitular: titularID,
+		Titulares: make([]schemas.TitularTicket, 0, len(historial)),
+	}
+	for _, h := range historial {
+		titular := schemas.TitularTicket{
+			IdUsuario:       h.UsuarioID,
+			Motivo:          h.Motivo,
+			IdTransferencia: h.TransferenciaID,
+			Desde:           h.Desde.Format(time.RFC3339),
+		}
+		if h.Usuario != nil {
+			titular.Nombre = h.Usuario.Nombre
+		}
+		if h.Hasta != nil {
+			titular.Hasta = h.Hasta.Format(time.RFC3339)
+		}
+		resp.Titulares = append(resp.Titulares, titular)
+	}
+	return resp, nil
+}
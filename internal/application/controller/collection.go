@@ -1,12 +1,19 @@
 package controller
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 
 	"github.com/Nexivent/nexivent-backend/internal/application/adapter"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/notificaciones"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/pagos"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/pases"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/qr"
 	"github.com/Nexivent/nexivent-backend/internal/application/service/storage"
 	config "github.com/Nexivent/nexivent-backend/internal/config"
 	"github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	"github.com/Nexivent/nexivent-backend/internal/mailer"
 	"github.com/Nexivent/nexivent-backend/logging"
 )
 
@@ -25,9 +32,12 @@ type ControllerCollection struct {
 	Tarifa        *TarifaController
 	Ticket        *TicketController
 	Token         *TokenController
+	Idempotencia  *IdempotenciaController
 	Rol           *RolController
 	ValidacionDocumento *ValidacionDocumentoController
 	RolUsuario    *RolUsuarioController
+	Notificacion  *NotificacionController
+	Recinto       *RecintoController
 }
 
 // Creates BLL controller collection
@@ -45,24 +55,78 @@ func NewControllerCollection(
 	eventoAdapter := adapter.NewEventoAdapter(logger, daoPostgresql)
 	categoriaAdapter := adapter.NewCategoriaAdapter(logger, daoPostgresql)
 	cuponAdapter := adapter.NewCuponAdapter(logger, daoPostgresql)
-	ordenAdapter := adapter.NewOrdenDeCompraAdapter(logger, daoPostgresql)
+	firmanteQR, verificadorQR, qrErr := qr.CargarClaves(configEnv.QRSigningKeyID, configEnv.QRSigningKey, configEnv.QRVerificationKeys)
+	if qrErr != nil {
+		if qrErr != qr.ErrSinClaveFirma {
+			logger.Panicln("Invalid QR signing keys:", qrErr)
+		}
+		if !configEnv.EsDesarrollo() {
+			logger.Panicln("QR_SIGNING_KEY not set: it is required outside APP_ENV=local or development")
+		}
+		logger.Warnln("QR_SIGNING_KEY not set, using an ephemeral key: ticket QR codes will not verify after a restart")
+		firmanteQR, qrErr = qr.GenerarFirmante(configEnv.QRSigningKeyID)
+		if qrErr != nil {
+			logger.Panicln("Failed to generate QR signing key:", qrErr)
+		}
+		publicas, pubErr := qr.ParsearClavesPublicas(configEnv.QRVerificationKeys)
+		if pubErr != nil {
+			logger.Panicln("Invalid QR verification keys:", pubErr)
+		}
+		verificadorQR = qr.NuevoVerificador(append(publicas, firmanteQR.ClavePublica())...)
+	}
+	firmanteWallet, walletErr := pases.NuevoFirmanteWallet(pases.ConfigWallet{
+		PassTypeIdentifier: configEnv.WalletPassTypeID,
+		TeamIdentifier:     configEnv.WalletTeamID,
+		Organizacion:       configEnv.WalletOrganization,
+		Certificado:        configEnv.WalletCertificate,
+		ClavePrivada:       configEnv.WalletPrivateKey,
+		CertificadoWWDR:    configEnv.WalletWWDRCert,
+	})
+	if walletErr != nil {
+		if walletErr != pases.ErrWalletNoConfigurado {
+			logger.Panicln("Invalid wallet pass certificate:", walletErr)
+		}
+		logger.Warnln("WALLET_CERTIFICATE not set, .pkpass downloads are disabled")
+	}
+	proveedoresPago, pagosErr := pagos.NuevosProveedores(configEnv.PaymentProvider, configEnv.PaymentWebhookSecret, configEnv.EsDesarrollo())
+	if pagosErr != nil {
+		logger.Panicln("Payment providers not initialized:", pagosErr)
+	}
+	if configEnv.EsDesarrollo() && configEnv.PaymentProvider == "fake" {
+		logger.Warnln("PAYMENT_PROVIDER=fake: every payment intent is captured without charging")
+	}
+	if configEnv.PaymentWebhookSecret == "" {
+		logger.Warnln("PAYMENT_WEBHOOK_SECRET not set, payment webhooks will be rejected")
+	}
+	ordenAdapter := adapter.NewOrdenDeCompraAdapter(
+		logger, daoPostgresql, proveedoresPago, firmanteQR,
+		time.Duration(configEnv.TicketCancelCutoff)*time.Minute,
+	)
 	perfilAdapter := adapter.NewPerfilPersonaAdapter(logger, daoPostgresql)
 	sectorAdapter := adapter.NewSectorAdapter(logger, daoPostgresql)
 	tipoTicketAdapter := adapter.NewTipoTicketAdapter(logger, daoPostgresql)
 	tarifaAdapter := adapter.NewTarifaAdapter(logger, daoPostgresql)
-	ticketAdapter := adapter.NewTicketAdapter(logger, daoPostgresql)
+	ticketAdapter := adapter.NewTicketAdapter(logger, daoPostgresql, firmanteQR, verificadorQR, firmanteWallet, adapter.VentanaIngreso{
+		AntesDelInicio:   time.Duration(configEnv.CheckInOpensBefore) * time.Minute,
+		DespuesDelInicio: time.Duration(configEnv.CheckInClosesAfter) * time.Minute,
+	})
 	rolAdapter := adapter.NewRolAdapter(logger, daoPostgresql)
 	validacionDocumentoAdapter := adapter.NewValidacionDocumentoAdapter(logger, configEnv.FactilizaToken)
 	rolUsuarioAdapter := adapter.NewRolUsuarioAdapter(logger, daoPostgresql)
+	recintoAdapter := adapter.NewRecintoAdapter(logger, daoPostgresql)
 
 	// Services
 	s3Storage, storageErr := storage.NewS3Storage(logger, configEnv)
 	if storageErr != nil {
 		logger.Warnln("S3 storage not initialized:", storageErr)
 	}
+	if configEnv.Host == "" {
+		logger.Warnln("MAIL_HOST not set, emails will fail to send")
+	}
+	mailSender := mailer.New(configEnv.Host, configEnv.Port, configEnv.Username, configEnv.Password, configEnv.Sender)
 
 	// Create controllers
-	eventoController := NewEventoController(logger, eventoAdapter)
+	eventoController := NewEventoController(logger, eventoAdapter, ordenAdapter)
 	categoriaController := NewCategoriaController(logger, categoriaAdapter)
 	cuponController := NewCuponController(logger, cuponAdapter)
 	ordenController := NewOrdenDeCompraController(logger, ordenAdapter)
@@ -74,6 +138,17 @@ func NewControllerCollection(
 	rolController := NewRolController(logger, rolAdapter)
 	validacionDocumentoController := NewValidacionDocumentoController(validacionDocumentoAdapter, logger)
 	rolUsuarioController := NewRolUsuarioController(logger, rolUsuarioAdapter)
+	recintoController := NewRecintoController(logger, recintoAdapter)
+	if configEnv.NotificationsWebhookURL != "" && configEnv.NotificationsWebhookSecret == "" {
+		logger.Warnln("NOTIFICATIONS_WEBHOOK_SECRET not set, outgoing webhooks will not be signed")
+	}
+	canalInApp := notificaciones.NuevoCanalInApp()
+	notificacionAdapter := adapter.NewNotificacionAdapter(logger, daoPostgresql, configEnv.NotificationsWebhookURL,
+		notificaciones.NuevoCanalCorreo(mailSender),
+		notificaciones.NuevoCanalWebhook(configEnv.NotificationsWebhookSecret, 10*time.Second),
+		canalInApp,
+	)
+	notificacionController := NewNotificacionController(logger, notificacionAdapter, canalInApp)
 
 	var mediaController *MediaController
 	if s3Storage != nil {
@@ -89,6 +164,8 @@ func NewControllerCollection(
 		Usuario: &UsuarioController{
 			Logger: logger,
 			DB:     daoPostgresql,
+			Mailer:      mailSender,
+			FrontendURL: configEnv.FrontendURL,
 		},
 		Comentario: &ComentarioController{
 			Logger: logger,
@@ -104,8 +181,14 @@ func NewControllerCollection(
 			Logger: logger,
 			DB:     daoPostgresql,
 		},
+		Idempotencia: &IdempotenciaController{
+			Logger: logger,
+			DB:     daoPostgresql,
+		},
 		Rol: rolController,
 		ValidacionDocumento: validacionDocumentoController,
 		RolUsuario: rolUsuarioController,
+		Notificacion: notificacionController,
+		Recinto:      recintoController,
 	}, nexiventPsqlDB
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Nexivent/nexivent-backend/errors"
 	"github.com/Nexivent/nexivent-backend/internal/application/adapter"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 	"github.com/Nexivent/nexivent-backend/internal/schemas"
 	"github.com/Nexivent/nexivent-backend/logging"
 )
@@ -26,16 +27,16 @@ func NewCuponController(
 
 func (cc *CuponController) CreateCupon(
 	cuponReq schemas.CuponResquest,
-	usuarioCreacion int64,
+	usuario *model.Usuario,
 ) (*schemas.CuponResponse, *errors.Error) {
-	return cc.CuponAdapter.CreatePostgresqlCupon(&cuponReq, usuarioCreacion)
+	return cc.CuponAdapter.CreatePostgresqlCupon(&cuponReq, usuario)
 }
 
 func (cc *CuponController) UpdateCupon(
 	cuponReq schemas.CuponResquest,
-	usuarioModificacion int64,
+	usuario *model.Usuario,
 ) (*schemas.CuponResponse, *errors.Error) {
-	return cc.CuponAdapter.UpdatePostgresqlCupon(&cuponReq, usuarioModificacion)
+	return cc.CuponAdapter.UpdatePostgresqlCupon(&cuponReq, usuario)
 }
 
 func (cc *CuponController) FetchCuponPorOrganizador(organizadorId int64) (*schemas.CuponesOrganizator, *errors.Error) {
@@ -1,10 +1,13 @@
 package controller
 
 import (
+	"context"
 	"time"
 
 	"github.com/Nexivent/nexivent-backend/errors"
 	"github.com/Nexivent/nexivent-backend/internal/application/adapter"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
 	daoPostgresql "github.com/Nexivent/nexivent-backend/internal/dao/repository"
 	"github.com/Nexivent/nexivent-backend/internal/schemas"
 	"github.com/Nexivent/nexivent-backend/logging"
@@ -13,16 +16,23 @@ import (
 type EventoController struct {
 	Logger        logging.Logger
 	EventoAdapter *adapter.Evento
+	OrdenAdapter  *adapter.OrdenDeCompra
+
+	// despertarCicloDeVida adelanta la siguiente pasada del ciclo de vida (p.ej. tras una cancelación)
+	despertarCicloDeVida chan struct{}
 }
 
 // NewEventoController creates a new controller for event operations
 func NewEventoController(
 	logger logging.Logger,
 	eventoAdapter *adapter.Evento,
+	ordenAdapter *adapter.OrdenDeCompra,
 ) *EventoController {
 	return &EventoController{
-		Logger:        logger,
-		EventoAdapter: eventoAdapter,
+		Logger:               logger,
+		EventoAdapter:        eventoAdapter,
+		OrdenAdapter:         ordenAdapter,
+		despertarCicloDeVida: make(chan struct{}, 1),
 	}
 }
 
@@ -34,16 +44,16 @@ func (ec *EventoController) CreateEvento(
 	return ec.EventoAdapter.CreatePostgresqlEvento(&eventoReq, usuarioCreacion)
 }
 
-// FetchEventos retrieves the list of available events
-func (ec *EventoController) FetchEventos() (*schemas.EventosPaginados, *errors.Error) {
-	return ec.EventoAdapter.FetchPostgresqlEventos()
+// FetchEventos retrieves a page of the available events
+func (ec *EventoController) FetchEventos(pagina schemas.PaginaEventosRequest) (*schemas.EventosPaginados, *errors.Error) {
+	return ec.EventoAdapter.FetchPostgresqlEventos(&pagina)
 }
 
-func (ec *EventoController) FetchEventosFeed(usuarioId *int64) (*schemas.EventosPaginados, *errors.Error) {
-	return ec.EventoAdapter.FetchPostgresqlEventosFeed(usuarioId)
+func (ec *EventoController) FetchEventosFeed(usuarioId *int64, pagina schemas.PaginaEventosRequest) (*schemas.EventosPaginados, *errors.Error) {
+	return ec.EventoAdapter.FetchPostgresqlEventosFeed(usuarioId, &pagina)
 }
-func (ec *EventoController) FetchEventosConInteraccionesFeed(usuarioId *int64) (*schemas.EventosPaginados, *errors.Error) {
-	return ec.EventoAdapter.FetchPostgresqlEventosConInteraccionesFeed(usuarioId)
+func (ec *EventoController) FetchEventosConInteraccionesFeed(usuarioId *int64, pagina schemas.PaginaEventosRequest) (*schemas.EventosPaginados, *errors.Error) {
+	return ec.EventoAdapter.FetchPostgresqlEventosConInteraccionesFeed(usuarioId, &pagina)
 }
 
 func (ec *EventoController) FetchEventosWithFilters(
@@ -52,20 +62,31 @@ func (ec *EventoController) FetchEventosWithFilters(
 	titulo *string,
 	descripcion *string,
 	lugar *string,
+	recintoID *int64,
+	cercania schemas.CercaniaRequest,
 	fecha *time.Time,
 	horaInicio *time.Time,
 	estado *int16,
-	soloFuturos bool) (*schemas.EventosPaginados, *errors.Error) {
+	soloFuturos bool,
+	pagina schemas.PaginaEventosRequest) (*schemas.EventosPaginados, *errors.Error) {
 	return ec.EventoAdapter.FetchPostgresqlEventosWithFilters(
 		categoriaID,
 		organizadorID,
 		titulo,
 		descripcion,
 		lugar,
+		recintoID,
+		&cercania,
 		fecha,
 		horaInicio,
 		estado,
-		soloFuturos)
+		soloFuturos,
+		&pagina)
+}
+
+// GET /evento/buscar
+func (ec *EventoController) BuscarEventos(req schemas.BusquedaEventosRequest) (*schemas.BusquedaEventosResponse, *errors.Error) {
+	return ec.EventoAdapter.BuscarEventos(&req, time.Now())
 }
 
 // GetEventoById retrieves an event by its ID with all related entities
@@ -100,12 +121,12 @@ func (ec *EventoController) GetEventoDetalle(eventoId int64) (*schemas.EventoDet
 	return ec.EventoAdapter.GetPostgresqlEventoDetalle(eventoId)
 }
 
-func (ec *EventoController) EditarEventoFull(eventoID int64, req schemas.EditarEventoFullRequest) (*schemas.EventoResponse, *errors.Error) {
-	return ec.EventoAdapter.EditarEventoFull(eventoID, &req)
+func (ec *EventoController) EditarEventoFull(eventoID int64, req schemas.EditarEventoFullRequest, usuario *model.Usuario) (*schemas.EventoResponse, *errors.Error) {
+	return ec.EventoAdapter.EditarEventoFull(eventoID, &req, usuario)
 }
 
-func (c *EventoController) EditarEvento(req *schemas.EditarEventoRequest) (*schemas.EventoDetalleDTO, *errors.Error) {
-	return c.EventoAdapter.EditarEvento(req)
+func (c *EventoController) EditarEvento(req *schemas.EditarEventoRequest, usuario *model.Usuario) (*schemas.EventoDetalleDTO, *errors.Error) {
+	return c.EventoAdapter.EditarEvento(req, usuario)
 }
 
 func (ec *EventoController) ObtenerTransaccionesPorEvento(eventoId int64) ([]daoPostgresql.Transaccion, *errors.Error) {
@@ -135,3 +156,69 @@ func (ec *EventoController) GetAsistentesPorEvento(eventoID int64) ([]map[string
 
 	return asistentes, nil
 }
+
+// POST /api/eventos/{id}/estado
+func (ec *EventoController) CambiarEstadoEvento(
+	eventoID int64,
+	req schemas.CambiarEstadoEventoRequest,
+	usuario *model.Usuario,
+) (*schemas.EstadoEventoResponse, *errors.Error) {
+	resp, err := ec.EventoAdapter.CambiarEstadoEvento(eventoID, &req, usuario, time.Now())
+	if err == nil && resp.Estado == util.EventoCancelado.String() {
+		// Los reembolsos no esperan al siguiente barrido
+		select {
+		case ec.despertarCicloDeVida <- struct{}{}:
+		default:
+		}
+	}
+	return resp, err
+}
+
+// PUT /api/eventos/{id}/publicacion
+func (ec *EventoController) ProgramarPublicacion(
+	eventoID int64,
+	req schemas.ProgramarPublicacionRequest,
+	usuario *model.Usuario,
+) (*schemas.EstadoEventoResponse, *errors.Error) {
+	return ec.EventoAdapter.ProgramarPublicacion(eventoID, &req, usuario, time.Now())
+}
+
+// POST /api/eventos/{id}/fechas/{idFechaEvento}/reprogramacion
+func (ec *EventoController) ReprogramarFecha(
+	eventoID int64,
+	eventoFechaID int64,
+	req schemas.ReprogramarFechaRequest,
+	usuario *model.Usuario,
+) (*schemas.ReprogramacionResponse, *errors.Error) {
+	return ec.OrdenAdapter.ReprogramarFecha(eventoID, eventoFechaID, &req, usuario, time.Now())
+}
+
+// IniciarCicloDeVidaEventos publica los eventos programados, reembolsa lo vendido de los
+// eventos cancelados y cierra las reprogramaciones con el plazo vencido, cada intervalo o en
+// cuanto se cancela un evento, hasta que ctx se cancele. Se ejecuta en segundo plano desde
+// api.RunService.
+func (ec *EventoController) IniciarCicloDeVidaEventos(ctx context.Context, intervalo time.Duration, lote int) {
+	ec.Logger.Infof("Ciclo de vida de eventos iniciado (intervalo: %s, lote: %d)", intervalo, lote)
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+
+	for {
+		if publicados, err := ec.EventoAdapter.PublicarProgramados(time.Now(), lote); err == nil && publicados > 0 {
+			ec.Logger.Infof("Ciclo de vida de eventos: %d eventos publicados", publicados)
+		}
+		if reembolsos, err := ec.OrdenAdapter.ReembolsarEventosCancelados(ctx, lote); err == nil && reembolsos > 0 {
+			ec.Logger.Infof("Ciclo de vida de eventos: %d reembolsos por cancelación", reembolsos)
+		}
+		if cerradas, err := ec.OrdenAdapter.CerrarReprogramacionesVencidas(time.Now()); err == nil && cerradas > 0 {
+			ec.Logger.Infof("Ciclo de vida de eventos: %d reprogramaciones cerradas", cerradas)
+		}
+
+		select {
+		case <-ctx.Done():
+			ec.Logger.Infoln("Ciclo de vida de eventos detenido")
+			return
+		case <-ticker.C:
+		case <-ec.despertarCicloDeVida:
+		}
+	}
+}
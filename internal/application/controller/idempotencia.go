@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+// Tiempo durante el cual un reintento con la misma Idempotency-Key repite la respuesta original
+const vigenciaClaveIdempotencia = 24 * time.Hour
+
+// Tiempo tras el cual una petición original que no respondió se da por abandonada y un reintento
+// con la misma clave la vuelve a ejecutar
+const bloqueoClaveIdempotencia = 5 * time.Minute
+
+type IdempotenciaController struct {
+	Logger logging.Logger
+	DB     *repository.NexiventPsqlEntidades
+}
+
+// Reservar registra la clave del usuario para la petición con el hash indicado.
+// Devuelve creada = true si la petición es nueva y debe ejecutarse; si la clave ya se usó
+// con la misma petición devuelve la respuesta guardada. Una clave reutilizada con otra
+// petición es un 422 y una clave cuya petición original sigue en curso es un 409, salvo que
+// lleve más de bloqueoClaveIdempotencia sin responder: entonces el reintento la toma.
+func (ic *IdempotenciaController) Reservar(
+	usuarioID int64,
+	clave string,
+	ruta string,
+	hashPeticion []byte,
+) (*model.ClaveIdempotencia, bool, *errors.Error) {
+	ahora := time.Now()
+	registro, creada, err := ic.DB.Idempotencia.Reservar(&model.ClaveIdempotencia{
+		UsuarioID:       usuarioID,
+		Clave:           clave,
+		Ruta:            ruta,
+		HashPeticion:    hashPeticion,
+		FechaCreacion:   ahora,
+		FechaBloqueo:    ahora,
+		FechaExpiracion: ahora.Add(vigenciaClaveIdempotencia),
+	}, ahora, bloqueoClaveIdempotencia)
+	if err != nil {
+		return nil, false, &errors.InternalServerError.Default
+	}
+	if creada {
+		return registro, true, nil
+	}
+
+	if !bytes.Equal(registro.HashPeticion, hashPeticion) {
+		return nil, false, &errors.UnprocessableEntityError.IdempotencyKeyReused
+	}
+	if !registro.Completada() {
+		return nil, false, &errors.ConflictError.IdempotencyKeyInUse
+	}
+	return registro, false, nil
+}
+
+// Completar guarda la respuesta enviada al cliente para la clave reservada.
+func (ic *IdempotenciaController) Completar(usuarioID int64, clave string, codigo int, tipoContenido string, cuerpo []byte) *errors.Error {
+	if err := ic.DB.Idempotencia.Completar(usuarioID, clave, codigo, tipoContenido, cuerpo); err != nil {
+		return &errors.InternalServerError.Default
+	}
+	return nil
+}
+
+// Liberar descarta la reserva de una petición que falló sin respuesta reutilizable.
+func (ic *IdempotenciaController) Liberar(usuarioID int64, clave string) *errors.Error {
+	if err := ic.DB.Idempotencia.Liberar(usuarioID, clave); err != nil {
+		return &errors.InternalServerError.Default
+	}
+	return nil
+}
+
+// IniciarLimpieza borra periódicamente las claves vencidas hasta que ctx se cancele.
+// Se ejecuta en segundo plano desde api.RunService.
+func (ic *IdempotenciaController) IniciarLimpieza(ctx context.Context, intervalo time.Duration) {
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+
+	for {
+		if borradas, err := ic.DB.Idempotencia.BorrarVencidas(time.Now()); err == nil && borradas > 0 {
+			ic.Logger.Infof("Limpieza de claves de idempotencia: %d claves vencidas borradas", borradas)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
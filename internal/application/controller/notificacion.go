@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	adapter "github.com/Nexivent/nexivent-backend/internal/application/adapter"
+	"github.com/Nexivent/nexivent-backend/internal/application/service/notificaciones"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+type NotificacionController struct {
+	Logger              logging.Logger
+	NotificacionAdapter *adapter.Notificacion
+	InApp               *notificaciones.InApp
+}
+
+func NewNotificacionController(
+	logger logging.Logger,
+	notificacionAdapter *adapter.Notificacion,
+	inApp *notificaciones.InApp,
+) *NotificacionController {
+	return &NotificacionController{
+		Logger:              logger,
+		NotificacionAdapter: notificacionAdapter,
+		InApp:               inApp,
+	}
+}
+
+// GET /api/admin/notificaciones/fallidas
+func (nc *NotificacionController) ListarFallidas() (*schemas.NotificacionesFallidasResponse, *errors.Error) {
+	return nc.NotificacionAdapter.ListarFallidas()
+}
+
+// POST /api/admin/notificaciones/{id}/reintentar
+func (nc *NotificacionController) ReintentarNotificacion(id int64) *errors.Error {
+	return nc.NotificacionAdapter.ReintentarNotificacion(id, time.Now())
+}
+
+// POST /api/admin/outbox/{id}/reintentar
+func (nc *NotificacionController) ReintentarEventoDominio(id int64) *errors.Error {
+	return nc.NotificacionAdapter.ReintentarEventoDominio(id, time.Now())
+}
+
+// GET /member/notificaciones
+func (nc *NotificacionController) ListarBandeja(
+	usuario *model.Usuario,
+	cursor int64,
+	soloNoLeidas bool,
+	limite int,
+) (*schemas.BandejaNotificacionesResponse, *errors.Error) {
+	return nc.NotificacionAdapter.ListarBandeja(usuario, cursor, soloNoLeidas, limite)
+}
+
+// GET /member/notificaciones/no-leidas
+func (nc *NotificacionController) ContarNoLeidas(usuario *model.Usuario) (*schemas.NotificacionesNoLeidasResponse, *errors.Error) {
+	return nc.NotificacionAdapter.ContarNoLeidas(usuario)
+}
+
+// POST /member/notificaciones/{id}/leer
+func (nc *NotificacionController) MarcarLeida(id int64, usuario *model.Usuario) *errors.Error {
+	return nc.NotificacionAdapter.MarcarLeida(id, usuario, time.Now())
+}
+
+// POST /member/notificaciones/leer-todas
+func (nc *NotificacionController) MarcarTodasLeidas(usuario *model.Usuario) (*schemas.NotificacionesMarcadasResponse, *errors.Error) {
+	return nc.NotificacionAdapter.MarcarTodasLeidas(usuario, time.Now())
+}
+
+// GET /member/notificaciones/preferencias
+func (nc *NotificacionController) ListarPreferencias(usuario *model.Usuario) (*schemas.PreferenciasNotificacionResponse, *errors.Error) {
+	return nc.NotificacionAdapter.ListarPreferencias(usuario)
+}
+
+// PUT /member/notificaciones/preferencias
+func (nc *NotificacionController) GuardarPreferencias(
+	usuario *model.Usuario,
+	req schemas.PreferenciasNotificacionRequest,
+) (*schemas.PreferenciasNotificacionResponse, *errors.Error) {
+	return nc.NotificacionAdapter.GuardarPreferencias(usuario, req, time.Now())
+}
+
+// GET /member/notificaciones/stream
+//
+// TransmitirBandeja llama a enviar con cada notificación nueva de la bandeja del usuario
+// posterior a ultimoID (nil: solo las que lleguen desde ahora) hasta que ctx se cancele o
+// enviar falle. Los avisos en vivo llegan por el canal in-app de este proceso; además, cada
+// intervalo se consulta la base, lo que cubre lo enviado por otras réplicas, y se llama a
+// latido para mantener viva la conexión. Solo devuelve error si falla antes del primer latido,
+// cuando todavía no se respondió nada al cliente.
+func (nc *NotificacionController) TransmitirBandeja(
+	ctx context.Context,
+	usuario *model.Usuario,
+	ultimoID *int64,
+	intervalo time.Duration,
+	enviar func(schemas.NotificacionBandeja) error,
+	latido func() error,
+) *errors.Error {
+	// Suscribirse antes de leer la base para no perder lo que llegue entre medio
+	avisos, cancelar := nc.InApp.Suscribir(usuario.ID)
+	defer cancelar()
+
+	var ultimo int64
+	if ultimoID != nil {
+		ultimo = *ultimoID
+	} else {
+		id, ferr := nc.NotificacionAdapter.UltimaDeBandeja(usuario.ID)
+		if ferr != nil {
+			return ferr
+		}
+		ultimo = id
+	}
+
+	// ponerseAlDia envía lo que haya en la base después de ultimo
+	ponerseAlDia := func() (*errors.Error, error) {
+		nuevas, ferr := nc.NotificacionAdapter.NuevasEnBandeja(usuario.ID, ultimo)
+		if ferr != nil {
+			return ferr, nil
+		}
+		for _, n := range nuevas {
+			if err := enviar(n); err != nil {
+				return nil, err
+			}
+			ultimo = n.IdNotificacion
+		}
+		return nil, nil
+	}
+	if ferr, _ := ponerseAlDia(); ferr != nil {
+		return ferr
+	}
+	if err := latido(); err != nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m := <-avisos:
+			if m.ID <= ultimo {
+				continue
+			}
+			if err := enviar(adapter.MensajeABandeja(m)); err != nil {
+				return nil
+			}
+			ultimo = m.ID
+		case <-ticker.C:
+			// Si la base falla se corta el stream; el cliente se reconecta con Last-Event-ID
+			if ferr, err := ponerseAlDia(); ferr != nil || err != nil {
+				return nil
+			}
+			if err := latido(); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// IniciarEnvioNotificaciones arranca trabajadores workers que despachan periódicamente el
+// outbox hasta que ctx se cancele, y espera a que terminen. Se ejecuta en segundo plano desde
+// api.RunService.
+func (nc *NotificacionController) IniciarEnvioNotificaciones(ctx context.Context, intervalo time.Duration, lote, trabajadores int) {
+	nc.Logger.Infof("Envío de notificaciones iniciado (intervalo: %s, lote: %d, workers: %d)", intervalo, lote, trabajadores)
+	var wg sync.WaitGroup
+	for i := 0; i < trabajadores; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nc.despachar(ctx, intervalo, lote)
+		}()
+	}
+	wg.Wait()
+	nc.Logger.Infoln("Envío de notificaciones detenido")
+}
+
+func (nc *NotificacionController) despachar(ctx context.Context, intervalo time.Duration, lote int) {
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+
+	for {
+		if enviadas, err := nc.NotificacionAdapter.Despachar(ctx, lote); err == nil && enviadas > 0 {
+			nc.Logger.Infof("Envío de notificaciones: %d enviadas", enviadas)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package controller
 
 import (
+	"context"
+	"net/http"
+	"time"
+
 	"github.com/Nexivent/nexivent-backend/errors"
 	adapter "github.com/Nexivent/nexivent-backend/internal/application/adapter"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 	schemas "github.com/Nexivent/nexivent-backend/internal/schemas"
 	"github.com/Nexivent/nexivent-backend/logging"
 )
@@ -32,14 +37,144 @@ func (oc *OrdenDeCompraController) CrearSesionOrdenTemporal(
 // GET /api/orders/{orderId}/hold
 func (oc *OrdenDeCompraController) ObtenerEstadoHold(
 	orderID int64,
+	usuarioID int64,
 ) (*schemas.ObtenerHoldResponse, *errors.Error) {
-	return oc.OrdenAdapter.ObtenerEstadoHold(orderID)
+	return oc.OrdenAdapter.ObtenerEstadoHold(orderID, usuarioID)
+}
+
+// POST /api/orders/{orderId}/pago
+func (oc *OrdenDeCompraController) CrearIntentoPago(
+	orderID int64,
+	usuarioID int64,
+	req schemas.CrearIntentoPagoRequest,
+) (*schemas.CrearIntentoPagoResponse, *errors.Error) {
+	return oc.OrdenAdapter.CrearIntentoPago(orderID, usuarioID, &req)
 }
 
 // POST /api/orders/{orderId}/confirm
 func (oc *OrdenDeCompraController) ConfirmarOrden(
 	orderID int64,
+	usuarioID int64,
 	req schemas.ConfirmarOrdenRequest,
 ) (*schemas.ConfirmarOrdenResponse, *errors.Error) {
-	return oc.OrdenAdapter.ConfirmarOrden(orderID, &req)
+	return oc.OrdenAdapter.ConfirmarOrden(orderID, usuarioID, &req)
+}
+
+// POST /pagos/webhook/{metodo}
+func (oc *OrdenDeCompraController) ProcesarWebhookPago(
+	metodo string,
+	payload []byte,
+	headers http.Header,
+) *errors.Error {
+	return oc.OrdenAdapter.ProcesarWebhookPago(metodo, payload, headers)
+}
+
+// PUT /api/eventos/{id}/reventa/politica
+func (oc *OrdenDeCompraController) GuardarPoliticaReventa(
+	eventoID int64,
+	req schemas.PoliticaReventaRequest,
+	usuario *model.Usuario,
+) (*schemas.PoliticaReventaResponse, *errors.Error) {
+	return oc.OrdenAdapter.GuardarPoliticaReventa(eventoID, &req, usuario, time.Now())
+}
+
+// GET /api/eventos/{id}/reventa/politica
+func (oc *OrdenDeCompraController) ObtenerPoliticaReventa(eventoID int64) (*schemas.PoliticaReventaResponse, *errors.Error) {
+	return oc.OrdenAdapter.ObtenerPoliticaReventa(eventoID)
+}
+
+// POST /member/tickets/{id}/reventa
+func (oc *OrdenDeCompraController) PublicarReventa(
+	ticketID int64,
+	req schemas.PublicarReventaRequest,
+	usuario *model.Usuario,
+) (*schemas.PublicacionReventa, *errors.Error) {
+	return oc.OrdenAdapter.PublicarReventa(ticketID, &req, usuario, time.Now())
+}
+
+// POST /reventa/{id}/cancelar
+func (oc *OrdenDeCompraController) CancelarReventa(publicacionID int64, usuario *model.Usuario) (*schemas.PublicacionReventa, *errors.Error) {
+	return oc.OrdenAdapter.CancelarReventa(publicacionID, usuario)
+}
+
+// GET /evento/{eventoId}/reventa
+func (oc *OrdenDeCompraController) ListarReventasDeEvento(eventoID int64) (*schemas.PublicacionesReventaResponse, *errors.Error) {
+	return oc.OrdenAdapter.ListarReventasDeEvento(eventoID)
+}
+
+// GET /member/reventa
+func (oc *OrdenDeCompraController) ListarMisReventas(usuario *model.Usuario) (*schemas.PublicacionesReventaResponse, *errors.Error) {
+	return oc.OrdenAdapter.ListarMisReventas(usuario)
+}
+
+// POST /reventa/{id}/hold
+func (oc *OrdenDeCompraController) ReservarReventa(publicacionID int64, usuario *model.Usuario) (*schemas.CrearOrdenTemporalResponse, *errors.Error) {
+	return oc.OrdenAdapter.ReservarReventa(publicacionID, usuario, time.Now())
+}
+
+// GET /api/eventos/{id}/reventa/resumen
+func (oc *OrdenDeCompraController) ResumenReventa(eventoID int64, usuario *model.Usuario) (*schemas.ResumenReventaResponse, *errors.Error) {
+	return oc.OrdenAdapter.ResumenReventa(eventoID, usuario)
+}
+
+// POST /api/tickets/cancel
+func (oc *OrdenDeCompraController) CancelarTickets(
+	req schemas.TicketCancelRequest,
+	usuario *model.Usuario,
+) (*schemas.TicketCancelResponse, *errors.Error) {
+	return oc.OrdenAdapter.CancelarTickets(&req, usuario, time.Now())
+}
+
+// POST /api/admin/ordenes/{orderId}/reembolsos
+func (oc *OrdenDeCompraController) ReembolsarOrden(
+	orderID int64,
+	req schemas.ReembolsarOrdenRequest,
+	admin *model.Usuario,
+) (*schemas.Reembolso, *errors.Error) {
+	return oc.OrdenAdapter.ReembolsarOrden(orderID, &req, admin, time.Now())
+}
+
+// POST /api/tickets/{ticketId}/reprogramacion
+func (oc *OrdenDeCompraController) ResponderReprogramacion(
+	ticketID int64,
+	req schemas.ResponderReprogramacionRequest,
+	usuario *model.Usuario,
+) (*schemas.RespuestaReprogramacionResponse, *errors.Error) {
+	return oc.OrdenAdapter.ResponderReprogramacion(ticketID, &req, usuario, time.Now())
+}
+
+// GET /orden_de_compra/{orderId}/reembolsos
+func (oc *OrdenDeCompraController) ListarReembolsosDeOrden(orderID int64, usuario *model.Usuario) (*schemas.ReembolsosResponse, *errors.Error) {
+	return oc.OrdenAdapter.ListarReembolsosDeOrden(orderID, usuario)
+}
+
+// GET /api/admin/reembolsos/fallidos
+func (oc *OrdenDeCompraController) ListarReembolsosFallidos() (*schemas.ReembolsosResponse, *errors.Error) {
+	return oc.OrdenAdapter.ListarReembolsosFallidos()
+}
+
+// POST /api/admin/reembolsos/{id}/reintentar
+func (oc *OrdenDeCompraController) ReintentarReembolso(id int64) (*schemas.Reembolso, *errors.Error) {
+	return oc.OrdenAdapter.ReintentarReembolso(id, time.Now())
+}
+
+// IniciarLiberadorDeHolds libera periódicamente los holds vencidos hasta que ctx se cancele.
+// Se ejecuta en segundo plano desde api.RunService.
+func (oc *OrdenDeCompraController) IniciarLiberadorDeHolds(ctx context.Context, intervalo time.Duration, lote int) {
+	oc.Logger.Infof("Liberador de holds iniciado (intervalo: %s, lote: %d)", intervalo, lote)
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+
+	for {
+		if liberadas, err := oc.OrdenAdapter.LiberarHoldsVencidos(ctx, lote); err == nil && liberadas > 0 {
+			oc.Logger.Infof("Liberador de holds: %d órdenes vencidas liberadas", liberadas)
+		}
+
+		select {
+		case <-ctx.Done():
+			oc.Logger.Infoln("Liberador de holds detenido")
+			return
+		case <-ticker.C:
+		}
+	}
 }
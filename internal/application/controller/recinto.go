@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/application/adapter"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/Nexivent/nexivent-backend/internal/schemas"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+type RecintoController struct {
+	Logger  logging.Logger
+	Adapter *adapter.RecintoAdapter
+}
+
+func NewRecintoController(
+	logger logging.Logger,
+	a *adapter.RecintoAdapter,
+) *RecintoController {
+	return &RecintoController{
+		Logger:  logger,
+		Adapter: a,
+	}
+}
+
+// POST /api/recintos
+func (c *RecintoController) CrearRecinto(req schemas.RecintoRequest, usuario *model.Usuario, ahora time.Time) (*schemas.RecintoResponse, *errors.Error) {
+	return c.Adapter.CrearRecinto(&req, usuario, ahora)
+}
+
+// PUT /api/recintos/{id}
+func (c *RecintoController) ActualizarRecinto(id int64, req schemas.RecintoRequest, usuario *model.Usuario, ahora time.Time) (*schemas.RecintoResponse, *errors.Error) {
+	return c.Adapter.ActualizarRecinto(id, &req, usuario, ahora)
+}
+
+// GET /recintos/{id}
+func (c *RecintoController) ObtenerRecinto(id int64) (*schemas.RecintoResponse, *errors.Error) {
+	return c.Adapter.ObtenerRecinto(id)
+}
+
+// GET /recintos
+func (c *RecintoController) ListarRecintos(ubigeo string, cercania schemas.CercaniaRequest) ([]schemas.RecintoResponse, *errors.Error) {
+	return c.Adapter.ListarRecintos(ubigeo, &cercania)
+}
@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"time"
+
 	"github.com/Nexivent/nexivent-backend/errors"
 	"github.com/Nexivent/nexivent-backend/internal/application/adapter"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 	"github.com/Nexivent/nexivent-backend/internal/schemas"
 	"github.com/Nexivent/nexivent-backend/logging"
 )
@@ -26,8 +29,20 @@ func (tc *TicketController) EmitirTickets(orderID int64) (*schemas.TicketIssueRe
 	return tc.TicketAdapter.EmitirTickets(orderID)
 }
 
-func (tc *TicketController) CancelarTickets(req schemas.TicketCancelRequest) (*schemas.TicketCancelResponse, *errors.Error) {
-	return tc.TicketAdapter.CancelarTickets(&req)
+func (tc *TicketController) ClavesQR() *schemas.ClavesQRResponse {
+	return tc.TicketAdapter.ClavesQR()
+}
+
+func (tc *TicketController) RegistrarIngreso(req schemas.CheckInRequest, operador *model.Usuario) (*schemas.CheckInResponse, *errors.Error) {
+	return tc.TicketAdapter.RegistrarIngreso(&req, operador, time.Now())
+}
+
+func (tc *TicketController) ManifiestoIngreso(eventoFechaID int64, operador *model.Usuario) (*schemas.ManifiestoIngresoResponse, *errors.Error) {
+	return tc.TicketAdapter.ManifiestoIngreso(eventoFechaID, operador, time.Now())
+}
+
+func (tc *TicketController) SincronizarIngresos(req schemas.SincronizarIngresosRequest, operador *model.Usuario) (*schemas.SincronizarIngresosResponse, *errors.Error) {
+	return tc.TicketAdapter.SincronizarIngresos(&req, operador, time.Now())
 }
 
 func (tc *TicketController) EmitirTicketsConInfo(
@@ -40,3 +55,39 @@ func (tc *TicketController) EmitirTicketsConInfo(
 func (tc *TicketController) ObtenerTicketsPorUsuario(idUser int64) ([]schemas.TicketDetalle, *errors.Error) {
 	return tc.TicketAdapter.ObtenerTicketsPostesqlPorUsuario(idUser)
 }
+
+func (tc *TicketController) OfrecerTransferencia(ticketID int64, req schemas.OfrecerTransferenciaRequest, usuario *model.Usuario) (*schemas.TransferenciaTicket, *errors.Error) {
+	return tc.TicketAdapter.OfrecerTransferencia(ticketID, &req, usuario, time.Now())
+}
+
+func (tc *TicketController) ListarTransferencias(usuario *model.Usuario) (*schemas.TransferenciasResponse, *errors.Error) {
+	return tc.TicketAdapter.ListarTransferencias(usuario, time.Now())
+}
+
+func (tc *TicketController) AceptarTransferencia(transferenciaID int64, usuario *model.Usuario) (*schemas.TransferenciaTicket, *errors.Error) {
+	return tc.TicketAdapter.AceptarTransferencia(transferenciaID, usuario, time.Now())
+}
+
+func (tc *TicketController) CancelarTransferencia(transferenciaID int64, usuario *model.Usuario) (*schemas.TransferenciaTicket, *errors.Error) {
+	return tc.TicketAdapter.CancelarTransferencia(transferenciaID, usuario, time.Now())
+}
+
+func (tc *TicketController) HistorialTitulares(ticketID int64, usuario *model.Usuario) (*schemas.HistorialTitularesResponse, *errors.Error) {
+	return tc.TicketAdapter.HistorialTitulares(ticketID, usuario)
+}
+
+func (tc *TicketController) PDFTicket(ticketID int64, usuario *model.Usuario) ([]byte, *errors.Error) {
+	return tc.TicketAdapter.PDFTicket(ticketID, usuario)
+}
+
+func (tc *TicketController) PDFOrden(orderID int64, usuario *model.Usuario) ([]byte, *errors.Error) {
+	return tc.TicketAdapter.PDFOrden(orderID, usuario)
+}
+
+func (tc *TicketController) PkpassTicket(ticketID int64, usuario *model.Usuario) ([]byte, *errors.Error) {
+	return tc.TicketAdapter.PkpassTicket(ticketID, usuario)
+}
+
+func (tc *TicketController) PkpassesOrden(orderID int64, usuario *model.Usuario) ([]byte, *errors.Error) {
+	return tc.TicketAdapter.PkpassesOrden(orderID, usuario)
+}
@@ -3,10 +3,12 @@ package controller
 import (
 	"time"
 
+	"gorm.io/gorm"
+
+	"github.com/Nexivent/nexivent-backend/errors"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 	"github.com/Nexivent/nexivent-backend/internal/dao/repository"
 	"github.com/Nexivent/nexivent-backend/logging"
-	"github.com/Nexivent/nexivent-backend/internal/dao/model"
-	"github.com/Nexivent/nexivent-backend/errors"
 )
 
 type TokenController struct {
@@ -17,34 +19,52 @@ type TokenController struct {
 func (tc *TokenController) CreateToken(userID int64, ttl time.Duration, scope string) (*model.Token, *errors.Error) {
 	token, err := tc.DB.Token.New(userID, ttl, scope)
 	if err != nil {
-		return nil, &errors.InternalServerError.Default
+		tc.Logger.Errorf("TokenController.CreateToken: %v", err)
+		return nil, &errors.InternalServerError.TokenCreationFailed
 	}
 	return token, nil
 }
 
-func (tc *TokenController) ValidateToken(tokenValue string) (*model.Token, *errors.Error) {
-	var token model.Token
-	result := tc.DB.Token.DB.Where("value = ?", tokenValue).First(&token)
-	if result.Error != nil {
-		if result.Error.Error() == "record not found" {
+// ValidateToken busca el token por su hash dentro del scope indicado y verifica que no haya expirado.
+func (tc *TokenController) ValidateToken(scope string, tokenValue string) (*model.Token, *errors.Error) {
+	if tokenValue == "" {
+		return nil, &errors.AuthenticationError.InvalidAccessToken
+	}
+
+	token, err := tc.DB.Token.ObtenerPorTextoPlano(scope, tokenValue)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
 			return nil, &errors.AuthenticationError.InvalidAccessToken
 		}
-		tc.Logger.Errorf("TokenController.ValidateToken: %v", result.Error)
+		tc.Logger.Errorf("TokenController.ValidateToken: %v", err)
 		return nil, &errors.InternalServerError.Default
 	}
 
 	if time.Now().After(token.Expiry) {
+		// Un token vencido ya no sirve para nada, se limpia de una vez
+		if delErr := tc.DB.Token.DeleteByPlaintext(scope, tokenValue); delErr != nil {
+			tc.Logger.Errorf("TokenController.ValidateToken.DeleteExpired: %v", delErr)
+		}
 		return nil, &errors.AuthenticationError.ExpiredToken
 	}
 
-	return &token, nil
+	return token, nil
+}
+
+// RevokeToken elimina un único token (logout del dispositivo actual).
+func (tc *TokenController) RevokeToken(scope string, tokenValue string) *errors.Error {
+	if err := tc.DB.Token.DeleteByPlaintext(scope, tokenValue); err != nil {
+		tc.Logger.Errorf("TokenController.RevokeToken: %v", err)
+		return &errors.InternalServerError.Default
+	}
+	return nil
 }
 
-// func (tc *TokenController) DeleteTokensForUser(scope string, userID int64) *logging.Error {
-// 	err := tc.DB.Token.DeleteAllForUser(scope, userID)
-// 	if err != nil {
-// 		tc.Logger.Errorf("TokenController.DeleteTokensForUser: %v", err)
-// 		return logging.NewErrorFromError(err, "TOKEN_ERROR_002", "Failed to delete tokens for user")
-// 	}
-// 	return nil
-// }
\ No newline at end of file
+// DeleteTokensForUser elimina todos los tokens del usuario para un scope (logout de todos los dispositivos).
+func (tc *TokenController) DeleteTokensForUser(scope string, userID int64) *errors.Error {
+	if err := tc.DB.Token.DeleteAllForUser(scope, userID); err != nil {
+		tc.Logger.Errorf("TokenController.DeleteTokensForUser: %v", err)
+		return &errors.InternalServerError.Default
+	}
+	return nil
+}
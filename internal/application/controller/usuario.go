@@ -3,20 +3,35 @@ package controller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"google.golang.org/api/idtoken"
 
 	"github.com/Nexivent/nexivent-backend/errors"
 	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 	"github.com/Nexivent/nexivent-backend/internal/dao/repository"
+	"github.com/Nexivent/nexivent-backend/internal/mailer"
 	"github.com/Nexivent/nexivent-backend/logging"
 	"gorm.io/gorm"
 )
 
+const (
+	// Vigencia del código de verificación de correo
+	duracionCodigoVerificacion = 15 * time.Minute
+	// Tiempo mínimo entre dos envíos de código al mismo usuario
+	intervaloReenvioCodigo = time.Minute
+	// Vigencia del link de restablecimiento de contraseña
+	duracionTokenRecuperacion = 30 * time.Minute
+	// Tiempo mínimo entre dos links de recuperación al mismo usuario
+	intervaloReenvioRecuperacion = 2 * time.Minute
+)
+
 type UsuarioController struct {
 	Logger         logging.Logger
 	DB             *repository.NexiventPsqlEntidades
 	GoogleClientID string
+	Mailer         mailer.Mailer
+	FrontendURL    string
 }
 
 type GoogleUser struct {
@@ -179,6 +194,32 @@ func (uc *UsuarioController) GetUsuario(id int64) (*model.Usuario, *errors.Error
 	return usuario, nil
 }
 
+// GetUsuarioAutenticado obtiene el usuario dueño de un token junto con sus roles activos.
+// Se usa en el middleware de autenticación, por eso no carga interacciones ni órdenes.
+func (uc *UsuarioController) GetUsuarioAutenticado(id int64) (*model.Usuario, *errors.Error) {
+	usuario, err := uc.DB.Usuario.ObtenerUsuarioBasicoPorID(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.AuthenticationError.InvalidAccessToken
+		}
+		uc.Logger.Errorf("GetUsuarioAutenticado: error obteniendo usuario %d: %v", id, err)
+		return nil, &errors.InternalServerError.Default
+	}
+
+	if usuario.Estado != 1 {
+		return nil, &errors.AuthenticationError.UnauthorizedUser
+	}
+
+	roles, err := uc.DB.RolesUsuario.ListarRolesDeUsuario(usuario.ID)
+	if err != nil {
+		uc.Logger.Errorf("GetUsuarioAutenticado: error listando roles de usuario %d: %v", id, err)
+		return nil, &errors.InternalServerError.Default
+	}
+	usuario.RolesAsignados = roles
+
+	return usuario, nil
+}
+
 func (uc *UsuarioController) GetUsuarioConRoles(id int64) ([]*model.Usuario, *errors.Error) {
 
 	usuarios, err := uc.DB.Usuario.ObtenerUsuariosPorRolID(id)
@@ -301,6 +342,156 @@ func (u *UsuarioController) GetOrCreateGoogleUser(googleUser *GoogleUser) (*mode
 	return &usuarioCreado, nil
 }
 
+// EnviarCodigoVerificacion genera un código nuevo, lo guarda y lo envía al correo del usuario.
+// El código nunca se devuelve al cliente. Los reenvíos están limitados a uno por minuto y a
+// repository.MaxEnviosCodigoPorHora por hora; el límite lo aplica el repositorio al guardar el
+// código, y solo se envía el correo si se guardó.
+func (uc *UsuarioController) EnviarCodigoVerificacion(usuario *model.Usuario) *errors.Error {
+	codigo, err := repository.GenerarCodigoVerificacion()
+	if err != nil {
+		uc.Logger.Errorf("EnviarCodigoVerificacion: error generando código para usuario %d: %v", usuario.ID, err)
+		return &errors.InternalServerError.Default
+	}
+
+	expira := time.Now().Add(duracionCodigoVerificacion)
+	if err := uc.DB.Usuario.ActualizarCodigoVerificacion(usuario.ID, codigo, expira, intervaloReenvioCodigo); err != nil {
+		if err == repository.ErrReenvioLimitado {
+			return &errors.TooManyRequestsError.VerificationCodeResendLimit
+		}
+		uc.Logger.Errorf("EnviarCodigoVerificacion: error guardando código para usuario %d: %v", usuario.ID, err)
+		return &errors.InternalServerError.Default
+	}
+
+	data := map[string]any{
+		"Nombre":             usuario.Nombre,
+		"CodigoVerificacion": codigo,
+		"MinutosExpiracion":  int(duracionCodigoVerificacion.Minutes()),
+	}
+	uc.enviarCorreo(usuario.ID, usuario.Correo, "verificacion_email.tmpl", data)
+
+	return nil
+}
+
+// enviarCorreo envía el correo en segundo plano: el SMTP puede tardar varios segundos y
+// no queremos bloquear la respuesta. Los errores solo se registran en el log.
+func (uc *UsuarioController) enviarCorreo(usuarioID int64, destinatario, plantilla string, data any) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				uc.Logger.Errorf("enviarCorreo: panic enviando %s a usuario %d: %v", plantilla, usuarioID, r)
+			}
+		}()
+		if err := uc.Mailer.Send(destinatario, plantilla, data); err != nil {
+			uc.Logger.Errorf("enviarCorreo: error enviando %s a usuario %d: %v", plantilla, usuarioID, err)
+			return
+		}
+		uc.Logger.Infof("Correo %s enviado a usuario %d", plantilla, usuarioID)
+	}()
+}
+
+// VerificarCorreo valida el código de verificación del usuario y marca su correo como verificado.
+// Los organizadores (RUC) quedan pendientes de aprobación (estado_de_cuenta = 0).
+func (uc *UsuarioController) VerificarCorreo(usuarioID int64, codigo string) (*model.Usuario, *errors.Error) {
+	usuario, err := uc.DB.Usuario.ObtenerUsuarioBasicoPorID(usuarioID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, &errors.ObjectNotFoundError.UserNotFound
+		}
+		uc.Logger.Errorf("VerificarCorreo: error obteniendo usuario %d: %v", usuarioID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+
+	estadoDeCuenta := int16(1)
+	if usuario.TipoDocumento == "RUC_PERSONA" || usuario.TipoDocumento == "RUC_EMPRESA" {
+		estadoDeCuenta = 0
+	}
+
+	err = uc.DB.Usuario.VerificarCodigo(usuarioID, codigo, estadoDeCuenta)
+	switch err {
+	case nil:
+	case repository.ErrCodigoInvalido:
+		return nil, &errors.BadRequestError.InvalidVerificationCode
+	case repository.ErrCodigoExpirado:
+		return nil, &errors.BadRequestError.ExpiredVerificationCode
+	case repository.ErrCodigoSinIntentos:
+		return nil, &errors.TooManyRequestsError.VerificationAttemptsExceeded
+	default:
+		uc.Logger.Errorf("VerificarCorreo: error verificando código de usuario %d: %v", usuarioID, err)
+		return nil, &errors.InternalServerError.Default
+	}
+
+	return uc.GetUsuario(usuarioID)
+}
+
+// SolicitarRecuperacionContrasenha envía al correo indicado un link de un solo uso para
+// restablecer la contraseña. Si el correo no existe no se informa al cliente, para no
+// revelar qué correos están registrados; por lo mismo, los pedidos que llegan antes de
+// intervaloReenvioRecuperacion se descartan sin error.
+func (uc *UsuarioController) SolicitarRecuperacionContrasenha(correo string) *errors.Error {
+	usuario, err := uc.DB.Usuario.ObtenerUsuarioPorCorreo(correo)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			uc.Logger.Infof("SolicitarRecuperacionContrasenha: correo no registrado")
+			return nil
+		}
+		uc.Logger.Errorf("SolicitarRecuperacionContrasenha: error buscando usuario: %v", err)
+		return &errors.InternalServerError.Default
+	}
+
+	if usuario.Estado != 1 {
+		return nil
+	}
+
+	if err := uc.DB.Usuario.RegistrarSolicitudRecuperacion(usuario.ID, intervaloReenvioRecuperacion); err != nil {
+		if err == repository.ErrRecuperacionLimitada {
+			uc.Logger.Infof("SolicitarRecuperacionContrasenha: pedido repetido para usuario %d, se descarta", usuario.ID)
+			return nil
+		}
+		uc.Logger.Errorf("SolicitarRecuperacionContrasenha: error registrando el pedido de usuario %d: %v", usuario.ID, err)
+		return &errors.InternalServerError.Default
+	}
+
+	// Solo el último link enviado es válido
+	if err := uc.DB.Token.DeleteAllForUser(model.ScopePasswordReset, usuario.ID); err != nil {
+		uc.Logger.Errorf("SolicitarRecuperacionContrasenha: error limpiando tokens de usuario %d: %v", usuario.ID, err)
+		return &errors.InternalServerError.Default
+	}
+
+	token, err := uc.DB.Token.New(usuario.ID, duracionTokenRecuperacion, model.ScopePasswordReset)
+	if err != nil {
+		uc.Logger.Errorf("SolicitarRecuperacionContrasenha: error creando token para usuario %d: %v", usuario.ID, err)
+		return &errors.InternalServerError.TokenCreationFailed
+	}
+
+	data := map[string]any{
+		"Nombre":            usuario.Nombre,
+		"ResetURL":          fmt.Sprintf("%s/reset-password?token=%s", uc.FrontendURL, token.Plaintext),
+		"MinutosExpiracion": int(duracionTokenRecuperacion.Minutes()),
+	}
+	uc.enviarCorreo(usuario.ID, usuario.Correo, "password_reset.tmpl", data)
+
+	return nil
+}
+
+// RestablecerContrasenha consume el token de recuperación y cambia la contraseña. Al cambiarla
+// se revocan todos los tokens de autenticación del usuario (ver ActualizarContrasenha).
+func (uc *UsuarioController) RestablecerContrasenha(tokenValue string, nuevaContrasenha string) *errors.Error {
+	token, err := uc.DB.Token.Consumir(model.ScopePasswordReset, tokenValue)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &errors.AuthenticationError.InvalidAccessToken
+		}
+		uc.Logger.Errorf("RestablecerContrasenha: error consumiendo token: %v", err)
+		return &errors.InternalServerError.Default
+	}
+
+	if time.Now().After(token.Expiry) {
+		return &errors.AuthenticationError.ExpiredToken
+	}
+
+	return uc.ActualizarContrasenha(token.UsuarioID, nuevaContrasenha, token.UsuarioID)
+}
+
 // ActivarUsuario activa un usuario (estado = 1)
 func (uc *UsuarioController) ActivarUsuario(usuarioID int64, updatedBy int64) *errors.Error {
 	// Verificar que el usuario que realiza la modificación existe
@@ -420,6 +611,12 @@ func (uc *UsuarioController) ActualizarContrasenha(
 		return &errors.InternalServerError.Default
 	}
 
+	// 5) Invalidar las sesiones abiertas con la contraseña anterior
+	if err := uc.DB.Token.DeleteAllForUser(model.ScopeAuthentication, usuarioID); err != nil {
+		uc.Logger.Errorf("Error revocando tokens de usuario %d: %v", usuarioID, err)
+		return &errors.InternalServerError.Default
+	}
+
 	uc.Logger.Infof("Contraseña de usuario %d actualizada exitosamente por usuario %d", usuarioID, updatedBy)
 	return nil
 }
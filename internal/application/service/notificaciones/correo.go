@@ -0,0 +1,38 @@
+package notificaciones
+
+import (
+	"context"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/Nexivent/nexivent-backend/internal/mailer"
+)
+
+// EnviadorCorreo es lo que el canal de correo necesita de mailer.Mailer.
+type EnviadorCorreo interface {
+	SendConAdjuntos(recipient, templateFile string, data any, adjuntos ...mailer.Adjunto) error
+}
+
+// Correo envía el mensaje por SMTP con las plantillas de internal/mailer/templates.
+type Correo struct {
+	enviador EnviadorCorreo
+}
+
+func NuevoCanalCorreo(enviador EnviadorCorreo) *Correo {
+	return &Correo{enviador: enviador}
+}
+
+func (c *Correo) Nombre() string { return model.CanalCorreo }
+
+func (c *Correo) Enviar(_ context.Context, m *Mensaje) error {
+	if m.Destino == "" {
+		return ErrSinDestino
+	}
+	plantilla, datos := m.Plantilla, m.Datos
+	if plantilla == "" {
+		plantilla = model.PlantillaAviso
+	}
+	if datos == nil {
+		datos = map[string]any{"Asunto": m.Asunto, "Texto": m.Texto}
+	}
+	return c.enviador.SendConAdjuntos(m.Destino, plantilla, datos, m.Adjuntos...)
+}
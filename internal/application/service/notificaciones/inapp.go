@@ -0,0 +1,62 @@
+package notificaciones
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+)
+
+// InApp entrega las notificaciones dentro de la aplicación. La fila de notificacion ya es la
+// entrada de la bandeja del usuario, así que enviar solo avisa a quienes estén suscritos en
+// este proceso; una réplica no ve las suscripciones de otra.
+type InApp struct {
+	mu           sync.Mutex
+	suscriptores map[int64]map[chan *Mensaje]struct{}
+}
+
+func NuevoCanalInApp() *InApp {
+	return &InApp{suscriptores: map[int64]map[chan *Mensaje]struct{}{}}
+}
+
+func (c *InApp) Nombre() string { return model.CanalInApp }
+
+func (c *InApp) Enviar(_ context.Context, m *Mensaje) error {
+	if m.UsuarioID == nil {
+		return ErrSinDestino
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ch := range c.suscriptores[*m.UsuarioID] {
+		// Un suscriptor lento pierde el aviso en vivo, pero el mensaje sigue en su bandeja
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+	return nil
+}
+
+// Suscribir devuelve un canal con los mensajes in-app que se envíen al usuario desde ahora y la
+// función que cancela la suscripción.
+func (c *InApp) Suscribir(usuarioID int64) (<-chan *Mensaje, func()) {
+	ch := make(chan *Mensaje, 16)
+	c.mu.Lock()
+	if c.suscriptores[usuarioID] == nil {
+		c.suscriptores[usuarioID] = map[chan *Mensaje]struct{}{}
+	}
+	c.suscriptores[usuarioID][ch] = struct{}{}
+	c.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.mu.Lock()
+			delete(c.suscriptores[usuarioID], ch)
+			if len(c.suscriptores[usuarioID]) == 0 {
+				delete(c.suscriptores, usuarioID)
+			}
+			c.mu.Unlock()
+		})
+	}
+}
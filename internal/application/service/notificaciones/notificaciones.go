@@ -0,0 +1,39 @@
+// Package notificaciones entrega las notificaciones del outbox por distintos canales: correo,
+// webhook saliente e in-app. Cada canal implementa Canal; quién recibe qué y cuándo se
+// reintenta lo decide el despachador (adapter.Notificacion).
+package notificaciones
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/mailer"
+)
+
+// ErrSinDestino indica que la notificación no tiene a quién enviarse por ese canal.
+var ErrSinDestino = errors.New("notificación sin destino para el canal")
+
+// Mensaje es una notificación lista para enviar.
+type Mensaje struct {
+	ID        int64  // id de la notificación; el receptor puede usarlo para descartar repetidos
+	Tipo      string // tipo del evento de dominio que la originó
+	UsuarioID *int64
+	Destino   string // correo o URL según el canal
+	Asunto    string
+	Texto     string
+	Plantilla string // plantilla de correo; vacía para usar la de aviso genérico
+	Datos     any    // datos de la plantilla; si es nil se usan Asunto y Texto
+	Adjuntos  []mailer.Adjunto
+	Payload   json.RawMessage // payload del evento de dominio
+	Fecha     time.Time
+}
+
+// Canal entrega mensajes por un medio. Enviar debe devolver error si el mensaje no llegó, para
+// que el despachador lo reintente.
+type Canal interface {
+	// Nombre es el valor de notificacion.canal que atiende (model.CanalCorreo, ...).
+	Nombre() string
+	Enviar(ctx context.Context, m *Mensaje) error
+}
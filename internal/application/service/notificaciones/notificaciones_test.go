@@ -0,0 +1,122 @@
+package notificaciones
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/application/service/pagos"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/Nexivent/nexivent-backend/internal/mailer"
+)
+
+func TestWebhookFirmaElCuerpo(t *testing.T) {
+	var recibido cuerpoWebhook
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cuerpo, _ := io.ReadAll(r.Body)
+		if !pagos.VerificarFirmaHMAC([]byte("secreto"), cuerpo, r.Header.Get(pagos.HeaderFirma)) {
+			t.Errorf("firma inválida: %q", r.Header.Get(pagos.HeaderFirma))
+		}
+		if r.Header.Get(HeaderEvento) != model.EventoOrdenConfirmada || r.Header.Get(HeaderNotificacion) != "9" {
+			t.Errorf("cabeceras inesperadas: %v", r.Header)
+		}
+		if err := json.Unmarshal(cuerpo, &recibido); err != nil {
+			t.Errorf("cuerpo no es JSON: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	canal := NuevoCanalWebhook("secreto", time.Second)
+	err := canal.Enviar(context.Background(), &Mensaje{
+		ID:      9,
+		Tipo:    model.EventoOrdenConfirmada,
+		Destino: srv.URL,
+		Asunto:  "Orden confirmada",
+		Payload: json.RawMessage(`{"orden_de_compra_id":3}`),
+	})
+	if err != nil {
+		t.Fatalf("Enviar: %v", err)
+	}
+	if recibido.ID != 9 || string(recibido.Datos) != `{"orden_de_compra_id":3}` {
+		t.Fatalf("cuerpo recibido inesperado: %+v", recibido)
+	}
+}
+
+func TestWebhookFallaSiNoResponde2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	if err := NuevoCanalWebhook("", time.Second).Enviar(context.Background(), &Mensaje{Destino: srv.URL}); err == nil {
+		t.Fatal("un 503 debió contar como fallo")
+	}
+	if err := NuevoCanalWebhook("", time.Second).Enviar(context.Background(), &Mensaje{}); err != ErrSinDestino {
+		t.Fatalf("sin URL se esperaba ErrSinDestino, se obtuvo %v", err)
+	}
+}
+
+type enviadorPrueba struct {
+	destino, plantilla string
+	datos              any
+}
+
+func (e *enviadorPrueba) SendConAdjuntos(recipient, templateFile string, data any, _ ...mailer.Adjunto) error {
+	e.destino, e.plantilla, e.datos = recipient, templateFile, data
+	return nil
+}
+
+func TestCorreoUsaLaPlantillaDeAvisoPorDefecto(t *testing.T) {
+	enviador := &enviadorPrueba{}
+	err := NuevoCanalCorreo(enviador).Enviar(context.Background(), &Mensaje{
+		Destino: "ana@example.com",
+		Asunto:  "Evento cancelado",
+		Texto:   "El evento fue cancelado.",
+	})
+	if err != nil {
+		t.Fatalf("Enviar: %v", err)
+	}
+	datos, _ := enviador.datos.(map[string]any)
+	if enviador.destino != "ana@example.com" || enviador.plantilla != model.PlantillaAviso || datos["Asunto"] != "Evento cancelado" {
+		t.Fatalf("envío inesperado: %+v", enviador)
+	}
+}
+
+func TestInAppAvisaSoloAlUsuarioSuscrito(t *testing.T) {
+	canal := NuevoCanalInApp()
+	recibidos, cancelar := canal.Suscribir(1)
+	otros, cancelarOtros := canal.Suscribir(2)
+	defer cancelarOtros()
+
+	usuario := int64(1)
+	if err := canal.Enviar(context.Background(), &Mensaje{ID: 5, UsuarioID: &usuario}); err != nil {
+		t.Fatalf("Enviar: %v", err)
+	}
+	select {
+	case m := <-recibidos:
+		if m.ID != 5 {
+			t.Fatalf("mensaje inesperado: %+v", m)
+		}
+	default:
+		t.Fatal("el suscriptor del usuario 1 no recibió el mensaje")
+	}
+	select {
+	case m := <-otros:
+		t.Fatalf("el usuario 2 no debió recibir %+v", m)
+	default:
+	}
+
+	cancelar()
+	cancelar()
+	if err := canal.Enviar(context.Background(), &Mensaje{UsuarioID: &usuario}); err != nil {
+		t.Fatalf("Enviar sin suscriptores: %v", err)
+	}
+	if err := canal.Enviar(context.Background(), &Mensaje{}); err != ErrSinDestino {
+		t.Fatalf("sin usuario se esperaba ErrSinDestino, se obtuvo %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package notificaciones
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/application/service/pagos"
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+)
+
+// Cabeceras que acompañan al webhook saliente, además de pagos.HeaderFirma con el HMAC-SHA256
+// del cuerpo.
+const (
+	HeaderEvento       = "X-Nexivent-Event"
+	HeaderNotificacion = "X-Nexivent-Delivery"
+)
+
+// Webhook publica el mensaje como JSON en la URL de destino. Cualquier respuesta que no sea
+// 2xx cuenta como fallo y se reintenta.
+type Webhook struct {
+	cliente *http.Client
+	secreto []byte
+}
+
+func NuevoCanalWebhook(secreto string, timeout time.Duration) *Webhook {
+	return &Webhook{
+		cliente: &http.Client{Timeout: timeout},
+		secreto: []byte(secreto),
+	}
+}
+
+func (w *Webhook) Nombre() string { return model.CanalWebhook }
+
+// cuerpoWebhook es lo que recibe el sistema externo.
+type cuerpoWebhook struct {
+	ID        int64           `json:"id"`
+	Tipo      string          `json:"tipo"`
+	UsuarioID *int64          `json:"usuario_id,omitempty"`
+	Asunto    string          `json:"asunto"`
+	Texto     string          `json:"texto"`
+	Datos     json.RawMessage `json:"datos,omitempty"`
+	Fecha     time.Time       `json:"fecha"`
+}
+
+func (w *Webhook) Enviar(ctx context.Context, m *Mensaje) error {
+	if m.Destino == "" {
+		return ErrSinDestino
+	}
+	cuerpo, err := json.Marshal(cuerpoWebhook{
+		ID:        m.ID,
+		Tipo:      m.Tipo,
+		UsuarioID: m.UsuarioID,
+		Asunto:    m.Asunto,
+		Texto:     m.Texto,
+		Datos:     m.Payload,
+		Fecha:     m.Fecha,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.Destino, bytes.NewReader(cuerpo))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvento, m.Tipo)
+	req.Header.Set(HeaderNotificacion, strconv.FormatInt(m.ID, 10))
+	if len(w.secreto) > 0 {
+		req.Header.Set(pagos.HeaderFirma, pagos.FirmarHMAC(w.secreto, cuerpo))
+	}
+
+	resp, err := w.cliente.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook respondió %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package pagos
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// HeaderFirma lleva el HMAC-SHA256 (hex) del cuerpo del webhook.
+const HeaderFirma = "X-Nexivent-Signature"
+
+// FakeProvider es una pasarela en memoria para desarrollo local: todo intento se puede capturar
+// y los webhooks se firman con HMAC-SHA256 usando PAYMENT_WEBHOOK_SECRET. Los intentos no
+// sobreviven a un reinicio ni se comparten entre réplicas.
+type FakeProvider struct {
+	secreto []byte
+
+	mu          sync.Mutex
+	intentos    map[string]*Intento
+	reembolsado map[string]float64
+}
+
+func NewFakeProvider(secretoWebhook string) *FakeProvider {
+	return &FakeProvider{
+		secreto:     []byte(secretoWebhook),
+		intentos:    map[string]*Intento{},
+		reembolsado: map[string]float64{},
+	}
+}
+
+func (f *FakeProvider) CrearIntento(_ context.Context, req IntentoRequest) (*Intento, error) {
+	ref, err := referenciaAleatoria("fake_")
+	if err != nil {
+		return nil, err
+	}
+
+	intento := &Intento{
+		Referencia:   ref,
+		Estado:       IntentoPendiente,
+		Monto:        req.Monto,
+		Moneda:       req.Moneda,
+		ClientSecret: ref + "_secret",
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.intentos[ref] = intento
+	copia := *intento
+	return &copia, nil
+}
+
+func (f *FakeProvider) Capturar(_ context.Context, referencia string) (*Intento, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intento, ok := f.intentos[referencia]
+	if !ok {
+		return nil, ErrIntentoNoEncontrado
+	}
+	if intento.Estado == IntentoPendiente {
+		intento.Estado = IntentoCapturado
+	}
+	copia := *intento
+	return &copia, nil
+}
+
+func (f *FakeProvider) Reembolsar(_ context.Context, referencia string, monto float64) (*Reembolso, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intento, ok := f.intentos[referencia]
+	if !ok {
+		return nil, ErrIntentoNoEncontrado
+	}
+	if intento.Estado != IntentoCapturado || monto <= 0 || f.reembolsado[referencia]+monto > intento.Monto+0.005 {
+		return nil, ErrMontoReembolso
+	}
+	refReembolso, err := referenciaAleatoria("fake_rf_")
+	if err != nil {
+		return nil, err
+	}
+	f.reembolsado[referencia] += monto
+	if f.reembolsado[referencia] >= intento.Monto-0.005 {
+		intento.Estado = IntentoReembolsado
+	}
+	return &Reembolso{Referencia: refReembolso, Monto: monto}, nil
+}
+
+func referenciaAleatoria(prefijo string) (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return prefijo + hex.EncodeToString(buf), nil
+}
+
+// VerificarWebhook espera un JSON {"referencia", "estado", "monto"} firmado en HeaderFirma.
+func (f *FakeProvider) VerificarWebhook(payload []byte, headers http.Header) (*EventoWebhook, error) {
+	if !VerificarFirmaHMAC(f.secreto, payload, headers.Get(HeaderFirma)) {
+		return nil, ErrFirmaInvalida
+	}
+	var evento EventoWebhook
+	if err := json.Unmarshal(payload, &evento); err != nil {
+		return nil, err
+	}
+
+	// Mantener el estado en memoria alineado con lo que notifica el webhook
+	f.mu.Lock()
+	if intento, ok := f.intentos[evento.Referencia]; ok && evento.Estado != "" {
+		intento.Estado = evento.Estado
+	}
+	f.mu.Unlock()
+
+	return &evento, nil
+}
@@ -0,0 +1,129 @@
+package pagos
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+)
+
+// EstadoIntento es el estado de un cobro tal como lo reporta la pasarela.
+type EstadoIntento string
+
+const (
+	IntentoPendiente   EstadoIntento = "PENDIENTE"
+	IntentoCapturado   EstadoIntento = "CAPTURADO"
+	IntentoFallido     EstadoIntento = "FALLIDO"
+	IntentoReembolsado EstadoIntento = "REEMBOLSADO"
+)
+
+var (
+	ErrFirmaInvalida       = errors.New("firma de webhook inválida")
+	ErrIntentoNoEncontrado = errors.New("intento de pago no encontrado en la pasarela")
+	ErrMontoReembolso      = errors.New("monto de reembolso inválido")
+	ErrSinPasarela         = errors.New("PAYMENT_PROVIDER no configurado")
+)
+
+type IntentoRequest struct {
+	OrdenID     int64
+	Monto       float64
+	Moneda      string
+	Descripcion string
+}
+
+// Intento es un cobro creado en la pasarela. Referencia es su id en la pasarela; ClientSecret
+// es lo que el frontend necesita para que el comprador autorice el pago (checkout, QR de Yape...).
+type Intento struct {
+	Referencia   string
+	Estado       EstadoIntento
+	Monto        float64
+	Moneda       string
+	ClientSecret string
+}
+
+type Reembolso struct {
+	Referencia string
+	Monto      float64
+}
+
+// EventoWebhook es una notificación de la pasarela ya verificada.
+type EventoWebhook struct {
+	Referencia string        `json:"referencia"`
+	Estado     EstadoIntento `json:"estado"`
+	Monto      float64       `json:"monto"`
+}
+
+// PaymentProvider es la pasarela detrás de un método de pago. Cada pasarela real (tarjeta, Yape)
+// implementa esta interfaz; FakeProvider la implementa en memoria para desarrollo local.
+type PaymentProvider interface {
+	// CrearIntento registra el cobro en la pasarela; todavía no mueve dinero.
+	CrearIntento(ctx context.Context, req IntentoRequest) (*Intento, error)
+	// Capturar cobra un intento ya autorizado por el comprador. Debe ser idempotente.
+	Capturar(ctx context.Context, referencia string) (*Intento, error)
+	// Reembolsar devuelve todo o parte de un intento capturado.
+	Reembolsar(ctx context.Context, referencia string, monto float64) (*Reembolso, error)
+	// VerificarWebhook valida la firma de una notificación y la interpreta.
+	// Devuelve ErrFirmaInvalida si la firma no corresponde.
+	VerificarWebhook(payload []byte, headers http.Header) (*EventoWebhook, error)
+}
+
+// Proveedores asocia cada método de pago a su pasarela.
+type Proveedores map[util.TipoMetodoPago]PaymentProvider
+
+func (p Proveedores) Obtener(metodo util.TipoMetodoPago) (PaymentProvider, bool) {
+	proveedor, ok := p[metodo]
+	return proveedor, ok
+}
+
+// NuevosProveedores arma las pasarelas según PAYMENT_PROVIDER. Por ahora solo existe "fake",
+// que atiende tarjeta y Yape en memoria y captura cualquier intento, así que solo se acepta con
+// permitirFake (desarrollo local); las pasarelas reales se agregan aquí.
+func NuevosProveedores(nombre string, secretoWebhook string, permitirFake bool) (Proveedores, error) {
+	switch strings.ToLower(nombre) {
+	case "":
+		return Proveedores{}, ErrSinPasarela
+	case "fake":
+		if !permitirFake {
+			return Proveedores{}, fmt.Errorf("la pasarela fake solo se permite con APP_ENV=local o development")
+		}
+		fake := NewFakeProvider(secretoWebhook)
+		return Proveedores{
+			util.MetodoTarjeta: fake,
+			util.MetodoYape:    fake,
+		}, nil
+	default:
+		return Proveedores{}, fmt.Errorf("pasarela de pago desconocida: %s", nombre)
+	}
+}
+
+// MetodoDesdeTexto normaliza "tarjeta", "YAPE", etc. al tipo de método de pago.
+func MetodoDesdeTexto(texto string) (util.TipoMetodoPago, bool) {
+	for _, m := range []util.TipoMetodoPago{util.MetodoTarjeta, util.MetodoYape} {
+		if strings.EqualFold(texto, string(m)) {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+// FirmarHMAC devuelve el HMAC-SHA256 del payload en hexadecimal.
+func FirmarHMAC(secreto []byte, payload []byte) string {
+	mac := hmac.New(sha256.New, secreto)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerificarFirmaHMAC compara en tiempo constante la firma recibida con la esperada.
+func VerificarFirmaHMAC(secreto []byte, payload []byte, firma string) bool {
+	if len(secreto) == 0 || firma == "" {
+		return false
+	}
+	esperada := FirmarHMAC(secreto, payload)
+	return hmac.Equal([]byte(esperada), []byte(strings.ToLower(firma)))
+}
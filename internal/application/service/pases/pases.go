@@ -0,0 +1,47 @@
+// Package pases genera los archivos descargables de los tickets: un PDF para imprimir (una
+// página por ticket) y pases de billetera en formato .pkpass firmados con el certificado de la
+// plataforma. Todo es Go puro, sin binarios externos, para que corra en el contenedor.
+package pases
+
+import (
+	"fmt"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Datos es lo que se imprime en el PDF y en el pase de un ticket.
+type Datos struct {
+	TicketID   int64
+	Evento     string
+	Lugar      string
+	Inicio     time.Time // fecha y hora de inicio de la función, en la zona horaria del evento
+	Sector     string
+	Perfil     string // vacío si la tarifa no distingue perfil
+	TipoTicket string
+	Titular    string
+	CodigoQR   string // token firmado que valida el escáner de puerta
+}
+
+// ladoQR es el tamaño en píxeles de la imagen PNG del QR.
+const ladoQR = 512
+
+// ImagenQR dibuja el código QR del ticket como PNG.
+func ImagenQR(contenido string) ([]byte, error) {
+	if contenido == "" {
+		return nil, fmt.Errorf("ticket sin código QR")
+	}
+	return qrcode.Encode(contenido, qrcode.Medium, ladoQR)
+}
+
+var (
+	diasSemana = [...]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"}
+	meses      = [...]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio",
+		"agosto", "septiembre", "octubre", "noviembre", "diciembre"}
+)
+
+// fechaLarga formatea el inicio como "sábado 14 de marzo de 2026, 20:00".
+func fechaLarga(t time.Time) string {
+	return fmt.Sprintf("%s %d de %s de %d, %s",
+		diasSemana[t.Weekday()], t.Day(), meses[t.Month()-1], t.Year(), t.Format("15:04"))
+}
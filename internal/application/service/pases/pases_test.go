@@ -0,0 +1,192 @@
+package pases
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+var datosPrueba = Datos{
+	TicketID:   42,
+	Evento:     "Concierto de Año Nuevo",
+	Lugar:      "Estadio Nacional, Lima",
+	Inicio:     time.Date(2026, time.March, 14, 20, 0, 0, 0, time.FixedZone("America/Lima", -5*60*60)),
+	Sector:     "Campo",
+	Perfil:     "Adulto",
+	TipoTicket: "General",
+	Titular:    "Ana Pérez",
+	CodigoQR:   "NXT1.k1.contenido.firma",
+}
+
+func TestGenerarPDFUnaPaginaPorTicket(t *testing.T) {
+	segundo := datosPrueba
+	segundo.TicketID = 43
+	pdf, err := GenerarPDF([]Datos{datosPrueba, segundo})
+	if err != nil {
+		t.Fatalf("GenerarPDF: %v", err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+		t.Fatalf("el resultado no es un PDF")
+	}
+	if n := bytes.Count(pdf, []byte("/Type /Page\n")); n != 2 {
+		t.Fatalf("se esperaban 2 páginas, se obtuvieron %d", n)
+	}
+
+	if _, err := GenerarPDF(nil); err == nil {
+		t.Fatal("un PDF sin tickets debió fallar")
+	}
+}
+
+func TestFechaLarga(t *testing.T) {
+	if got := fechaLarga(datosPrueba.Inicio); got != "sábado 14 de marzo de 2026, 20:00" {
+		t.Fatalf("fechaLarga = %q", got)
+	}
+}
+
+// certificadosPrueba emite un intermedio autofirmado y un certificado de pases firmado por él.
+func certificadosPrueba(t *testing.T) (certPEM, clavePEM, intermedioPEM string, intermedio *x509.Certificate) {
+	t.Helper()
+	claveCA, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	plantillaCA := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "WWDR de prueba"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	derCA, err := x509.CreateCertificate(rand.Reader, plantillaCA, plantillaCA, &claveCA.PublicKey, claveCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermedio, _ = x509.ParseCertificate(derCA)
+
+	clave, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	plantilla := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.pe.nexivent.ticket"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, plantilla, intermedio, &clave.PublicKey, claveCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	derClave, _ := x509.MarshalPKCS8PrivateKey(clave)
+
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	clavePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: derClave}))
+	intermedioPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derCA}))
+	return certPEM, clavePEM, intermedioPEM, intermedio
+}
+
+func leerZip(t *testing.T, datos []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(datos), int64(len(datos)))
+	if err != nil {
+		t.Fatalf("zip inválido: %v", err)
+	}
+	archivos := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		archivos[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	return archivos
+}
+
+func TestGenerarPkpassFirmaElManifest(t *testing.T) {
+	certPEM, clavePEM, intermedioPEM, intermedio := certificadosPrueba(t)
+	firmante, err := NuevoFirmanteWallet(ConfigWallet{
+		PassTypeIdentifier: "pass.pe.nexivent.ticket",
+		TeamIdentifier:     "ABCDE12345",
+		Certificado:        certPEM,
+		ClavePrivada:       clavePEM,
+		CertificadoWWDR:    intermedioPEM,
+	})
+	if err != nil {
+		t.Fatalf("NuevoFirmanteWallet: %v", err)
+	}
+
+	pkpass, err := firmante.GenerarPkpass(datosPrueba)
+	if err != nil {
+		t.Fatalf("GenerarPkpass: %v", err)
+	}
+	archivos := leerZip(t, pkpass)
+
+	var manifest map[string]string
+	if err := json.Unmarshal(archivos["manifest.json"], &manifest); err != nil {
+		t.Fatalf("manifest.json inválido: %v", err)
+	}
+	for _, nombre := range []string{"pass.json", "icon.png", "icon@2x.png"} {
+		suma := sha1.Sum(archivos[nombre])
+		if manifest[nombre] != hex.EncodeToString(suma[:]) {
+			t.Fatalf("el manifest no coincide con %s", nombre)
+		}
+	}
+
+	var p pase
+	if err := json.Unmarshal(archivos["pass.json"], &p); err != nil {
+		t.Fatalf("pass.json inválido: %v", err)
+	}
+	if p.SerialNumber != "nexivent-ticket-42" || len(p.Barcodes) != 1 || p.Barcodes[0].Message != datosPrueba.CodigoQR {
+		t.Fatalf("pass.json inesperado: %+v", p)
+	}
+
+	firma, err := pkcs7.Parse(archivos["signature"])
+	if err != nil {
+		t.Fatalf("signature inválida: %v", err)
+	}
+	firma.Content = archivos["manifest.json"]
+	pool := x509.NewCertPool()
+	pool.AddCert(intermedio)
+	if err := firma.VerifyWithChain(pool); err != nil {
+		t.Fatalf("la firma del manifest no verifica: %v", err)
+	}
+
+	lote, err := firmante.GenerarPkpasses([]Datos{datosPrueba})
+	if err != nil {
+		t.Fatalf("GenerarPkpasses: %v", err)
+	}
+	if _, ok := leerZip(t, lote)["ticket-42.pkpass"]; !ok {
+		t.Fatal("el paquete .pkpasses debió incluir ticket-42.pkpass")
+	}
+}
+
+func TestNuevoFirmanteWalletSinConfigurar(t *testing.T) {
+	if _, err := NuevoFirmanteWallet(ConfigWallet{}); err != ErrWalletNoConfigurado {
+		t.Fatalf("se esperaba ErrWalletNoConfigurado, se obtuvo %v", err)
+	}
+
+	certPEM, _, _, _ := certificadosPrueba(t)
+	_, otraClave, _, _ := certificadosPrueba(t)
+	_, err := NuevoFirmanteWallet(ConfigWallet{
+		PassTypeIdentifier: "pass.pe.nexivent.ticket",
+		TeamIdentifier:     "ABCDE12345",
+		Certificado:        certPEM,
+		ClavePrivada:       otraClave,
+	})
+	if err == nil || !strings.Contains(err.Error(), "no corresponde") {
+		t.Fatalf("una clave de otro certificado debió rechazarse, se obtuvo %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package pases
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// GenerarPDF arma un documento A4 con una página por ticket: título del evento, lugar, fecha y
+// hora, sector, perfil y el QR que se escanea en la puerta.
+func GenerarPDF(tickets []Datos) ([]byte, error) {
+	if len(tickets) == 0 {
+		return nil, fmt.Errorf("no hay tickets para generar el PDF")
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Tickets Nexivent", true)
+	pdf.SetCreator("Nexivent", true)
+	pdf.SetAutoPageBreak(false, 0)
+	// Las fuentes estándar del PDF usan cp1252: se traducen tildes y eñes desde UTF-8
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for _, d := range tickets {
+		png, err := ImagenQR(d.CodigoQR)
+		if err != nil {
+			return nil, fmt.Errorf("ticket %d: %w", d.TicketID, err)
+		}
+
+		pdf.AddPage()
+		pdf.SetFillColor(33, 37, 41)
+		pdf.Rect(0, 0, 210, 28, "F")
+		pdf.SetTextColor(255, 255, 255)
+		pdf.SetFont("Helvetica", "B", 16)
+		pdf.SetXY(15, 9)
+		pdf.CellFormat(180, 10, "NEXIVENT", "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.SetXY(15, 9)
+		pdf.CellFormat(180, 10, tr(fmt.Sprintf("Ticket N.º %d", d.TicketID)), "", 0, "R", false, 0, "")
+
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetXY(15, 40)
+		pdf.SetFont("Helvetica", "B", 20)
+		pdf.MultiCell(180, 9, tr(d.Evento), "", "L", false)
+
+		pdf.Ln(4)
+		fila := func(etiqueta, valor string) {
+			if valor == "" {
+				return
+			}
+			pdf.SetX(15)
+			pdf.SetFont("Helvetica", "B", 11)
+			pdf.CellFormat(35, 8, tr(etiqueta), "", 0, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "", 11)
+			pdf.MultiCell(145, 8, tr(valor), "", "L", false)
+		}
+		fila("Lugar", d.Lugar)
+		fila("Fecha", fechaLarga(d.Inicio))
+		fila("Sector", d.Sector)
+		fila("Perfil", d.Perfil)
+		fila("Entrada", d.TipoTicket)
+		fila("Titular", d.Titular)
+
+		nombre := fmt.Sprintf("qr-%d", d.TicketID)
+		opciones := fpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(nombre, opciones, bytes.NewReader(png))
+		pdf.ImageOptions(nombre, 55, 125, 100, 100, false, opciones, 0, "")
+
+		pdf.SetXY(15, 235)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.MultiCell(180, 5, tr("Presenta este código en la puerta. El QR es personal: "+
+			"si el ticket se transfiere o revende se emite uno nuevo y este deja de ser válido."), "", "C", false)
+	}
+
+	if err := pdf.Error(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,372 @@
+package pases
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// ErrWalletNoConfigurado indica que no hay certificado para firmar pases de billetera.
+var ErrWalletNoConfigurado = errors.New("certificado de pases de billetera no configurado")
+
+// ConfigWallet son los datos del certificado de pases (Pass Type ID) de la plataforma. Los
+// certificados y la clave van en PEM, o en base64 del PEM para poder pasarlos por variables
+// de entorno de una sola línea.
+type ConfigWallet struct {
+	PassTypeIdentifier string // p. ej. "pass.pe.nexivent.ticket"
+	TeamIdentifier     string
+	Organizacion       string
+	Certificado        string
+	ClavePrivada       string
+	CertificadoWWDR    string // intermedio de Apple que emitió el certificado
+}
+
+// FirmanteWallet arma y firma pases .pkpass.
+type FirmanteWallet struct {
+	passTypeID   string
+	teamID       string
+	organizacion string
+	certificado  *x509.Certificate
+	clave        crypto.PrivateKey
+	intermedios  []*x509.Certificate
+	icono        []byte
+	icono2x      []byte
+}
+
+// NuevoFirmanteWallet valida la configuración y carga el certificado. Devuelve
+// ErrWalletNoConfigurado si no hay certificado ni clave.
+func NuevoFirmanteWallet(cfg ConfigWallet) (*FirmanteWallet, error) {
+	if strings.TrimSpace(cfg.Certificado) == "" && strings.TrimSpace(cfg.ClavePrivada) == "" {
+		return nil, ErrWalletNoConfigurado
+	}
+	if cfg.PassTypeIdentifier == "" || cfg.TeamIdentifier == "" {
+		return nil, fmt.Errorf("faltan el pass type identifier o el team identifier del pase")
+	}
+
+	certificados, err := leerCertificados(cfg.Certificado)
+	if err != nil || len(certificados) == 0 {
+		return nil, fmt.Errorf("certificado de pases inválido: %v", err)
+	}
+	clave, err := leerClavePrivada(cfg.ClavePrivada)
+	if err != nil {
+		return nil, fmt.Errorf("clave privada de pases inválida: %w", err)
+	}
+	publica, ok := clave.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return nil, fmt.Errorf("tipo de clave privada no soportado")
+	}
+	if igual, ok := publica.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !igual.Equal(certificados[0].PublicKey) {
+		return nil, fmt.Errorf("la clave privada no corresponde al certificado de pases")
+	}
+
+	intermedios := certificados[1:]
+	if strings.TrimSpace(cfg.CertificadoWWDR) != "" {
+		wwdr, err := leerCertificados(cfg.CertificadoWWDR)
+		if err != nil {
+			return nil, fmt.Errorf("certificado WWDR inválido: %w", err)
+		}
+		intermedios = append(intermedios, wwdr...)
+	}
+	if len(intermedios) > 0 {
+		if err := certificados[0].CheckSignatureFrom(intermedios[0]); err != nil {
+			return nil, fmt.Errorf("el certificado de pases no fue emitido por el intermedio configurado: %w", err)
+		}
+	}
+
+	organizacion := cfg.Organizacion
+	if organizacion == "" {
+		organizacion = "Nexivent"
+	}
+	icono, err := generarIcono(29)
+	if err != nil {
+		return nil, err
+	}
+	icono2x, err := generarIcono(58)
+	if err != nil {
+		return nil, err
+	}
+	return &FirmanteWallet{
+		passTypeID:   cfg.PassTypeIdentifier,
+		teamID:       cfg.TeamIdentifier,
+		organizacion: organizacion,
+		certificado:  certificados[0],
+		clave:        clave,
+		intermedios:  intermedios,
+		icono:        icono,
+		icono2x:      icono2x,
+	}, nil
+}
+
+// decodificarPEM acepta el PEM tal cual o codificado en base64.
+func decodificarPEM(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-----BEGIN") {
+		return []byte(s), nil
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+func leerCertificados(s string) ([]*x509.Certificate, error) {
+	datos, err := decodificarPEM(s)
+	if err != nil {
+		return nil, err
+	}
+	var certificados []*x509.Certificate
+	for {
+		var bloque *pem.Block
+		bloque, datos = pem.Decode(datos)
+		if bloque == nil {
+			break
+		}
+		if bloque.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(bloque.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificados = append(certificados, cert)
+	}
+	if len(certificados) == 0 {
+		return nil, fmt.Errorf("no se encontró ningún certificado PEM")
+	}
+	return certificados, nil
+}
+
+func leerClavePrivada(s string) (crypto.PrivateKey, error) {
+	datos, err := decodificarPEM(s)
+	if err != nil {
+		return nil, err
+	}
+	bloque, _ := pem.Decode(datos)
+	if bloque == nil {
+		return nil, fmt.Errorf("no se encontró ninguna clave PEM")
+	}
+	switch bloque.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(bloque.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(bloque.Bytes)
+	default:
+		return x509.ParsePKCS8PrivateKey(bloque.Bytes)
+	}
+}
+
+// generarIcono dibuja el ícono cuadrado que exige el formato (Wallet lo muestra en las
+// notificaciones del pase).
+func generarIcono(lado int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, lado, lado))
+	fondo := color.RGBA{R: 33, G: 37, B: 41, A: 255}
+	for y := 0; y < lado; y++ {
+		for x := 0; x < lado; x++ {
+			img.Set(x, y, fondo)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type campoPase struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+type codigoPase struct {
+	Format          string `json:"format"`
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+	AltText         string `json:"altText,omitempty"`
+}
+
+type estructuraPase struct {
+	HeaderFields    []campoPase `json:"headerFields,omitempty"`
+	PrimaryFields   []campoPase `json:"primaryFields"`
+	SecondaryFields []campoPase `json:"secondaryFields,omitempty"`
+	AuxiliaryFields []campoPase `json:"auxiliaryFields,omitempty"`
+	BackFields      []campoPase `json:"backFields,omitempty"`
+}
+
+// pase es el pass.json de un ticket (tipo eventTicket).
+type pase struct {
+	FormatVersion      int            `json:"formatVersion"`
+	PassTypeIdentifier string         `json:"passTypeIdentifier"`
+	SerialNumber       string         `json:"serialNumber"`
+	TeamIdentifier     string         `json:"teamIdentifier"`
+	OrganizationName   string         `json:"organizationName"`
+	Description        string         `json:"description"`
+	LogoText           string         `json:"logoText"`
+	RelevantDate       string         `json:"relevantDate,omitempty"`
+	ForegroundColor    string         `json:"foregroundColor"`
+	BackgroundColor    string         `json:"backgroundColor"`
+	LabelColor         string         `json:"labelColor"`
+	Barcode            codigoPase     `json:"barcode"` // iOS < 9
+	Barcodes           []codigoPase   `json:"barcodes"`
+	EventTicket        estructuraPase `json:"eventTicket"`
+}
+
+func (f *FirmanteWallet) paseDe(d Datos) pase {
+	codigo := codigoPase{
+		Format:          "PKBarcodeFormatQR",
+		Message:         d.CodigoQR,
+		MessageEncoding: "iso-8859-1",
+		AltText:         fmt.Sprintf("Ticket %d", d.TicketID),
+	}
+	estructura := estructuraPase{
+		HeaderFields:    []campoPase{{Key: "sector", Label: "SECTOR", Value: d.Sector}},
+		PrimaryFields:   []campoPase{{Key: "evento", Label: "EVENTO", Value: d.Evento}},
+		SecondaryFields: []campoPase{{Key: "fecha", Label: "FECHA", Value: fechaLarga(d.Inicio)}},
+		BackFields: []campoPase{
+			{Key: "lugar", Label: "Lugar", Value: d.Lugar},
+			{Key: "ticket", Label: "Ticket", Value: fmt.Sprintf("%d", d.TicketID)},
+			{Key: "aviso", Label: "Importante", Value: "El QR es personal: si el ticket se transfiere o revende se emite uno nuevo y este deja de ser válido."},
+		},
+	}
+	if d.Lugar != "" {
+		estructura.AuxiliaryFields = append(estructura.AuxiliaryFields, campoPase{Key: "lugarCorto", Label: "LUGAR", Value: d.Lugar})
+	}
+	if d.Perfil != "" {
+		estructura.AuxiliaryFields = append(estructura.AuxiliaryFields, campoPase{Key: "perfil", Label: "PERFIL", Value: d.Perfil})
+	}
+	if d.TipoTicket != "" {
+		estructura.AuxiliaryFields = append(estructura.AuxiliaryFields, campoPase{Key: "tipo", Label: "ENTRADA", Value: d.TipoTicket})
+	}
+	if d.Titular != "" {
+		estructura.BackFields = append(estructura.BackFields, campoPase{Key: "titular", Label: "Titular", Value: d.Titular})
+	}
+
+	p := pase{
+		FormatVersion:      1,
+		PassTypeIdentifier: f.passTypeID,
+		SerialNumber:       fmt.Sprintf("nexivent-ticket-%d", d.TicketID),
+		TeamIdentifier:     f.teamID,
+		OrganizationName:   f.organizacion,
+		Description:        "Entrada para " + d.Evento,
+		LogoText:           f.organizacion,
+		ForegroundColor:    "rgb(255, 255, 255)",
+		BackgroundColor:    "rgb(33, 37, 41)",
+		LabelColor:         "rgb(200, 200, 200)",
+		Barcode:            codigo,
+		Barcodes:           []codigoPase{codigo},
+		EventTicket:        estructura,
+	}
+	if !d.Inicio.IsZero() {
+		p.RelevantDate = d.Inicio.Format(time.RFC3339)
+	}
+	return p
+}
+
+// GenerarPkpass arma el pase de un ticket: pass.json, íconos, manifest.json con el SHA-1 de
+// cada archivo y signature, la firma PKCS#7 separada del manifest.
+func (f *FirmanteWallet) GenerarPkpass(d Datos) ([]byte, error) {
+	if d.CodigoQR == "" {
+		return nil, fmt.Errorf("ticket %d sin código QR", d.TicketID)
+	}
+	passJSON, err := json.Marshal(f.paseDe(d))
+	if err != nil {
+		return nil, err
+	}
+	archivos := []struct {
+		nombre string
+		datos  []byte
+	}{
+		{"pass.json", passJSON},
+		{"icon.png", f.icono},
+		{"icon@2x.png", f.icono2x},
+	}
+
+	manifest := make(map[string]string, len(archivos))
+	for _, a := range archivos {
+		suma := sha1.Sum(a.datos)
+		manifest[a.nombre] = hex.EncodeToString(suma[:])
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	firma, err := f.firmar(manifestJSON)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo firmar el pase del ticket %d: %w", d.TicketID, err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, a := range archivos {
+		if err := escribirEnZip(zw, a.nombre, a.datos); err != nil {
+			return nil, err
+		}
+	}
+	if err := escribirEnZip(zw, "manifest.json", manifestJSON); err != nil {
+		return nil, err
+	}
+	if err := escribirEnZip(zw, "signature", firma); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerarPkpasses agrupa los pases de varios tickets en un paquete .pkpasses, que Wallet
+// importa de una sola vez.
+func (f *FirmanteWallet) GenerarPkpasses(tickets []Datos) ([]byte, error) {
+	if len(tickets) == 0 {
+		return nil, fmt.Errorf("no hay tickets para generar los pases")
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, d := range tickets {
+		pkpass, err := f.GenerarPkpass(d)
+		if err != nil {
+			return nil, err
+		}
+		if err := escribirEnZip(zw, fmt.Sprintf("ticket-%d.pkpass", d.TicketID), pkpass); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (f *FirmanteWallet) firmar(manifest []byte) ([]byte, error) {
+	sd, err := pkcs7.NewSignedData(manifest)
+	if err != nil {
+		return nil, err
+	}
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := sd.AddSignerChain(f.certificado, f.clave, f.intermedios, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, err
+	}
+	sd.Detach()
+	return sd.Finish()
+}
+
+func escribirEnZip(zw *zip.Writer, nombre string, datos []byte) error {
+	w, err := zw.Create(nombre)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(datos)
+	return err
+}
@@ -0,0 +1,227 @@
+// Package qr firma y verifica el contenido de los códigos QR de los tickets.
+//
+// El QR lleva un token compacto firmado con Ed25519:
+//
+//	NXT1.<kid>.<contenido>.<firma>
+//
+// donde <contenido> son el id del ticket, la fecha del evento, la tarifa y la hora de emisión
+// codificados como varints, y <firma> cubre todo lo anterior (incluido el kid). Ambos van en
+// base64url sin padding. El escáner de puerta solo necesita las claves públicas para validar
+// un QR sin conexión; el kid permite rotar la clave de firma sin invalidar tickets ya emitidos.
+package qr
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const prefijoToken = "NXT1"
+
+var (
+	ErrTokenMalformado  = errors.New("código QR con formato inválido")
+	ErrClaveDesconocida = errors.New("código QR firmado con una clave desconocida")
+	ErrFirmaInvalida    = errors.New("firma del código QR inválida")
+	ErrSinClaveFirma    = errors.New("clave de firma de QR no configurada")
+)
+
+var kidValido = regexp.MustCompile(`^[A-Za-z0-9_-]{1,16}$`)
+
+var codificacion = base64.RawURLEncoding
+
+// Contenido es lo que certifica un QR.
+type Contenido struct {
+	TicketID      int64
+	EventoFechaID int64
+	TarifaID      int64
+	EmitidoEn     time.Time
+}
+
+// ClavePublica es una clave de verificación publicada para los escáneres.
+type ClavePublica struct {
+	KID   string
+	Clave ed25519.PublicKey
+}
+
+// Firmante emite tokens con la clave privada vigente.
+type Firmante struct {
+	kid     string
+	privada ed25519.PrivateKey
+}
+
+// NuevoFirmante crea un firmante a partir de la semilla Ed25519 de 32 bytes.
+func NuevoFirmante(kid string, semilla []byte) (*Firmante, error) {
+	if !kidValido.MatchString(kid) {
+		return nil, fmt.Errorf("kid de QR inválido: %q", kid)
+	}
+	if len(semilla) != ed25519.SeedSize {
+		return nil, fmt.Errorf("la semilla Ed25519 debe tener %d bytes", ed25519.SeedSize)
+	}
+	return &Firmante{kid: kid, privada: ed25519.NewKeyFromSeed(semilla)}, nil
+}
+
+// GenerarFirmante crea un firmante con una clave aleatoria (solo para desarrollo: los QR
+// emitidos dejan de verificarse cuando el proceso se reinicia).
+func GenerarFirmante(kid string) (*Firmante, error) {
+	semilla := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(semilla); err != nil {
+		return nil, err
+	}
+	return NuevoFirmante(kid, semilla)
+}
+
+func (f *Firmante) KID() string { return f.kid }
+
+func (f *Firmante) ClavePublica() ClavePublica {
+	return ClavePublica{KID: f.kid, Clave: f.privada.Public().(ed25519.PublicKey)}
+}
+
+// Firmar arma el token que se guarda en ticket.codigo_qr.
+func (f *Firmante) Firmar(c Contenido) (string, error) {
+	if c.TicketID <= 0 || c.EventoFechaID <= 0 || c.TarifaID <= 0 {
+		return "", fmt.Errorf("contenido de QR incompleto: %+v", c)
+	}
+
+	buf := make([]byte, 0, 4*binary.MaxVarintLen64)
+	buf = binary.AppendUvarint(buf, uint64(c.TicketID))
+	buf = binary.AppendUvarint(buf, uint64(c.EventoFechaID))
+	buf = binary.AppendUvarint(buf, uint64(c.TarifaID))
+	buf = binary.AppendUvarint(buf, uint64(c.EmitidoEn.Unix()))
+
+	firmado := prefijoToken + "." + f.kid + "." + codificacion.EncodeToString(buf)
+	firma := ed25519.Sign(f.privada, []byte(firmado))
+	return firmado + "." + codificacion.EncodeToString(firma), nil
+}
+
+// Verificador valida tokens contra un conjunto de claves públicas indexado por kid.
+type Verificador struct {
+	claves map[string]ed25519.PublicKey
+}
+
+func NuevoVerificador(claves ...ClavePublica) *Verificador {
+	v := &Verificador{claves: make(map[string]ed25519.PublicKey, len(claves))}
+	for _, c := range claves {
+		v.claves[c.KID] = c.Clave
+	}
+	return v
+}
+
+// Claves devuelve las claves públicas aceptadas, para publicarlas a los escáneres.
+func (v *Verificador) Claves() []ClavePublica {
+	claves := make([]ClavePublica, 0, len(v.claves))
+	for kid, clave := range v.claves {
+		claves = append(claves, ClavePublica{KID: kid, Clave: clave})
+	}
+	return claves
+}
+
+// Verificar comprueba la firma del token y devuelve su contenido. No consulta la base de datos:
+// que el ticket siga vigente (no usado, no cancelado) lo decide quien llama.
+func (v *Verificador) Verificar(token string) (*Contenido, error) {
+	partes := strings.Split(token, ".")
+	if len(partes) != 4 || partes[0] != prefijoToken {
+		return nil, ErrTokenMalformado
+	}
+
+	clave, ok := v.claves[partes[1]]
+	if !ok {
+		return nil, ErrClaveDesconocida
+	}
+
+	firma, err := codificacion.DecodeString(partes[3])
+	if err != nil || len(firma) != ed25519.SignatureSize {
+		return nil, ErrTokenMalformado
+	}
+	firmado := token[:len(token)-len(partes[3])-1]
+	if !ed25519.Verify(clave, []byte(firmado), firma) {
+		return nil, ErrFirmaInvalida
+	}
+
+	buf, err := codificacion.DecodeString(partes[2])
+	if err != nil {
+		return nil, ErrTokenMalformado
+	}
+	var campos [4]uint64
+	for i := range campos {
+		valor, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, ErrTokenMalformado
+		}
+		campos[i] = valor
+		buf = buf[n:]
+	}
+	if len(buf) != 0 {
+		return nil, ErrTokenMalformado
+	}
+
+	return &Contenido{
+		TicketID:      int64(campos[0]),
+		EventoFechaID: int64(campos[1]),
+		TarifaID:      int64(campos[2]),
+		EmitidoEn:     time.Unix(int64(campos[3]), 0),
+	}, nil
+}
+
+// CargarClaves arma el firmante y el verificador desde la configuración:
+//   - semillaBase64: semilla Ed25519 de 32 bytes de la clave vigente.
+//   - anteriores: claves públicas retiradas que aún deben aceptarse, "kid:base64,kid2:base64".
+//
+// Devuelve ErrSinClaveFirma si no hay semilla configurada.
+func CargarClaves(kid string, semillaBase64 string, anteriores string) (*Firmante, *Verificador, error) {
+	if strings.TrimSpace(semillaBase64) == "" {
+		return nil, nil, ErrSinClaveFirma
+	}
+	semilla, err := decodificarBase64(semillaBase64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("semilla de QR inválida: %w", err)
+	}
+	firmante, err := NuevoFirmante(kid, semilla)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicas, err := ParsearClavesPublicas(anteriores)
+	if err != nil {
+		return nil, nil, err
+	}
+	return firmante, NuevoVerificador(append(publicas, firmante.ClavePublica())...), nil
+}
+
+// ParsearClavesPublicas lee una lista "kid:base64,kid2:base64" de claves públicas Ed25519.
+func ParsearClavesPublicas(s string) ([]ClavePublica, error) {
+	var claves []ClavePublica
+	for _, entrada := range strings.Split(s, ",") {
+		entrada = strings.TrimSpace(entrada)
+		if entrada == "" {
+			continue
+		}
+		kid, valor, ok := strings.Cut(entrada, ":")
+		if !ok || !kidValido.MatchString(kid) {
+			return nil, fmt.Errorf("clave pública de QR inválida: %q", entrada)
+		}
+		clave, err := decodificarBase64(valor)
+		if err != nil || len(clave) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("clave pública de QR inválida para kid %q", kid)
+		}
+		claves = append(claves, ClavePublica{KID: kid, Clave: ed25519.PublicKey(clave)})
+	}
+	return claves, nil
+}
+
+// CodificarClave codifica una clave pública en base64url, el formato que se publica a los escáneres.
+func CodificarClave(clave ed25519.PublicKey) string {
+	return codificacion.EncodeToString(clave)
+}
+
+// decodificarBase64 acepta base64 estándar o url, con o sin padding.
+func decodificarBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	return codificacion.DecodeString(s)
+}
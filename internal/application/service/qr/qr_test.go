@@ -0,0 +1,69 @@
+package qr
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFirmarYVerificar(t *testing.T) {
+	firmante, err := GenerarFirmante("k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	emitido := time.Unix(1767225600, 0)
+	token, err := firmante.Firmar(Contenido{TicketID: 42, EventoFechaID: 7, TarifaID: 3, EmitidoEn: emitido})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contenido, err := NuevoVerificador(firmante.ClavePublica()).Verificar(token)
+	if err != nil {
+		t.Fatalf("Verificar: %v", err)
+	}
+	if contenido.TicketID != 42 || contenido.EventoFechaID != 7 || contenido.TarifaID != 3 || !contenido.EmitidoEn.Equal(emitido) {
+		t.Fatalf("contenido inesperado: %+v", contenido)
+	}
+}
+
+func TestVerificarRechazaTokensAlterados(t *testing.T) {
+	firmante, _ := GenerarFirmante("k1")
+	otro, _ := GenerarFirmante("k1")
+	token, _ := firmante.Firmar(Contenido{TicketID: 42, EventoFechaID: 7, TarifaID: 3, EmitidoEn: time.Now()})
+	verificador := NuevoVerificador(firmante.ClavePublica())
+
+	falsificado, _ := otro.Firmar(Contenido{TicketID: 43, EventoFechaID: 7, TarifaID: 3, EmitidoEn: time.Now()})
+	partes := strings.Split(token, ".")
+	partesFalsas := strings.Split(falsificado, ".")
+	contenidoCambiado := strings.Join([]string{partes[0], partes[1], partesFalsas[2], partes[3]}, ".")
+
+	casos := map[string]struct {
+		token string
+		err   error
+	}{
+		"otra clave":         {falsificado, ErrFirmaInvalida},
+		"contenido cambiado": {contenidoCambiado, ErrFirmaInvalida},
+		"kid desconocido":    {strings.Replace(token, ".k1.", ".k2.", 1), ErrClaveDesconocida},
+		"formato antiguo":    {"TCK-1-2-0", ErrTokenMalformado},
+	}
+	for nombre, caso := range casos {
+		if _, err := verificador.Verificar(caso.token); err != caso.err {
+			t.Errorf("%s: se esperaba %v, se obtuvo %v", nombre, caso.err, err)
+		}
+	}
+}
+
+func TestRotacionAceptaClavesAnteriores(t *testing.T) {
+	anterior, _ := GenerarFirmante("k1")
+	token, _ := anterior.Firmar(Contenido{TicketID: 1, EventoFechaID: 1, TarifaID: 1, EmitidoEn: time.Now()})
+
+	publica := anterior.ClavePublica()
+	semilla := strings.Repeat("A", 43) // 32 bytes en base64url
+	_, verificador, err := CargarClaves("k2", semilla, "k1:"+CodificarClave(publica.Clave))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verificador.Verificar(token); err != nil {
+		t.Fatalf("un QR firmado con la clave anterior debe seguir siendo válido: %v", err)
+	}
+}
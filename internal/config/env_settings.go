@@ -12,6 +12,9 @@ import (
 )
 
 type ConfigEnv struct {
+	// Ambiente de ejecución (APP_ENV): "local", "development" o vacío (producción)
+	AppEnv string
+
 	// LOGS
 	EnableSqlLogs bool
 
@@ -44,6 +47,50 @@ type ConfigEnv struct {
 	FactilizaToken string `env:"FACTILIZA_TOKEN"`
 
 	GoogleClientID string
+
+	// URL pública del frontend (para armar links en correos)
+	FrontendURL string
+
+	// Liberador de holds vencidos
+	HoldReaperInterval  int64 // segundos entre barridos
+	HoldReaperBatchSize int
+
+	// Ciclo de vida de eventos (publicación programada y reembolsos por cancelación)
+	EventLifecycleInterval  int64 // segundos entre barridos
+	EventLifecycleBatchSize int
+
+	// Envío de notificaciones pendientes (outbox)
+	OutboxInterval  int64 // segundos entre barridos
+	OutboxBatchSize int
+	OutboxWorkers   int
+
+	// Webhook saliente con los eventos de dominio (vacío: no se publica)
+	NotificationsWebhookURL    string
+	NotificationsWebhookSecret string
+
+	// Pasarela de pagos
+	PaymentProvider      string // "fake" solo en desarrollo local (APP_ENV)
+	PaymentWebhookSecret string
+
+	// Firma de los QR de tickets (Ed25519)
+	QRSigningKeyID     string
+	QRSigningKey       string // semilla de 32 bytes en base64
+	QRVerificationKeys string // claves públicas anteriores, "kid:base64,..."
+
+	// Ventana de ingreso en puerta, relativa a la hora de inicio de cada fecha
+	CheckInOpensBefore int64 // minutos antes del inicio
+	CheckInClosesAfter int64 // minutos después del inicio
+
+	// Cancelación de tickets por el comprador; se cierra esta cantidad de minutos antes del inicio
+	TicketCancelCutoff int64
+
+	// Pases de billetera (.pkpass); certificados y clave en PEM o base64 del PEM
+	WalletPassTypeID   string
+	WalletTeamID       string
+	WalletOrganization string
+	WalletCertificate  string
+	WalletPrivateKey   string
+	WalletWWDRCert     string
 }
 
 func NuevoConfigEnv(logger logging.Logger) *ConfigEnv {
@@ -161,26 +208,121 @@ func NuevoConfigEnv(logger logging.Logger) *ConfigEnv {
 	// Factiliza API token
 	factilizaToken := os.Getenv("FACTILIZA_TOKEN")
 
+	frontendURL := strings.TrimRight(os.Getenv("FRONTEND_URL"), "/")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:3000"
+	}
+
+	// Liberador de holds vencidos
+	var holdReaperInterval int64 = 60 // default 1 minuto
+	if v, err := strconv.ParseInt(os.Getenv("HOLD_REAPER_INTERVAL_SECONDS"), 10, 64); err == nil && v > 0 {
+		holdReaperInterval = v
+	}
+	holdReaperBatchSize := 100
+	if v, err := strconv.Atoi(os.Getenv("HOLD_REAPER_BATCH_SIZE")); err == nil && v > 0 {
+		holdReaperBatchSize = v
+	}
+
+	// Ciclo de vida de eventos
+	var eventLifecycleInterval int64 = 60
+	if v, err := strconv.ParseInt(os.Getenv("EVENT_LIFECYCLE_INTERVAL_SECONDS"), 10, 64); err == nil && v > 0 {
+		eventLifecycleInterval = v
+	}
+	eventLifecycleBatchSize := 50
+	if v, err := strconv.Atoi(os.Getenv("EVENT_LIFECYCLE_BATCH_SIZE")); err == nil && v > 0 {
+		eventLifecycleBatchSize = v
+	}
+
+	// Envío de notificaciones pendientes
+	var outboxInterval int64 = 15
+	if v, err := strconv.ParseInt(os.Getenv("OUTBOX_INTERVAL_SECONDS"), 10, 64); err == nil && v > 0 {
+		outboxInterval = v
+	}
+	outboxBatchSize := 50
+	if v, err := strconv.Atoi(os.Getenv("OUTBOX_BATCH_SIZE")); err == nil && v > 0 {
+		outboxBatchSize = v
+	}
+	outboxWorkers := 4
+	if v, err := strconv.Atoi(os.Getenv("OUTBOX_WORKERS")); err == nil && v > 0 {
+		outboxWorkers = v
+	}
+
+	// Pasarela de pagos; sin valor por defecto: el arranque falla si falta
+	paymentProvider := strings.TrimSpace(os.Getenv("PAYMENT_PROVIDER"))
+
+	// Firma de los QR de tickets
+	qrSigningKeyID := os.Getenv("QR_SIGNING_KEY_ID")
+	if qrSigningKeyID == "" {
+		qrSigningKeyID = "k1"
+	}
+
+	// Ventana de ingreso en puerta
+	var checkInOpensBefore int64 = 180 // default 3 horas antes
+	if v, err := strconv.ParseInt(os.Getenv("CHECKIN_OPENS_BEFORE_MINUTES"), 10, 64); err == nil && v >= 0 {
+		checkInOpensBefore = v
+	}
+	var checkInClosesAfter int64 = 360 // default 6 horas después
+	if v, err := strconv.ParseInt(os.Getenv("CHECKIN_CLOSES_AFTER_MINUTES"), 10, 64); err == nil && v >= 0 {
+		checkInClosesAfter = v
+	}
+
+	// Cancelación de tickets por el comprador
+	var ticketCancelCutoff int64 // default 0: hasta el inicio de la fecha
+	if v, err := strconv.ParseInt(os.Getenv("TICKET_CANCEL_CUTOFF_MINUTES"), 10, 64); err == nil && v >= 0 {
+		ticketCancelCutoff = v
+	}
+
 	return &ConfigEnv{
-		EnableSqlLogs:       enableSqlLogs,
-		MainPort:            mainPort,
-		EnableSwagger:       enableSwagger,
-		PostgresHost:        PostgresHost,
-		PostgresPort:        PostgresPort,
-		PostgresUser:        PostgresUser,
-		PostgresPassword:    PostgresPassword,
-		PostgresDBName:      PostgresDBName,
-		PostgresPsqlMode:    PostgresPsqlMode,
-		AwsRegion:           awsRegion,
-		AwsS3Bucket:         awsBucket,
-		AwsS3Prefix:         awsPrefix,
-		AwsS3UploadDuration: awsDuration,
-		Host:                host,
-		Port:                port,
-		Username:            username,
-		Password:            password,
-		Sender:              sender,
-		FactilizaToken:      factilizaToken,
-		GoogleClientID:      os.Getenv("GOOGLE_CLIENT_ID"),
+		AppEnv:                     strings.ToLower(strings.TrimSpace(os.Getenv("APP_ENV"))),
+		EnableSqlLogs:              enableSqlLogs,
+		MainPort:                   mainPort,
+		EnableSwagger:              enableSwagger,
+		PostgresHost:               PostgresHost,
+		PostgresPort:               PostgresPort,
+		PostgresUser:               PostgresUser,
+		PostgresPassword:           PostgresPassword,
+		PostgresDBName:             PostgresDBName,
+		PostgresPsqlMode:           PostgresPsqlMode,
+		AwsRegion:                  awsRegion,
+		AwsS3Bucket:                awsBucket,
+		AwsS3Prefix:                awsPrefix,
+		AwsS3UploadDuration:        awsDuration,
+		Host:                       host,
+		Port:                       port,
+		Username:                   username,
+		Password:                   password,
+		Sender:                     sender,
+		FactilizaToken:             factilizaToken,
+		GoogleClientID:             os.Getenv("GOOGLE_CLIENT_ID"),
+		FrontendURL:                frontendURL,
+		HoldReaperInterval:         holdReaperInterval,
+		HoldReaperBatchSize:        holdReaperBatchSize,
+		EventLifecycleInterval:     eventLifecycleInterval,
+		EventLifecycleBatchSize:    eventLifecycleBatchSize,
+		OutboxInterval:             outboxInterval,
+		OutboxBatchSize:            outboxBatchSize,
+		OutboxWorkers:              outboxWorkers,
+		NotificationsWebhookURL:    os.Getenv("NOTIFICATIONS_WEBHOOK_URL"),
+		NotificationsWebhookSecret: os.Getenv("NOTIFICATIONS_WEBHOOK_SECRET"),
+		PaymentProvider:            paymentProvider,
+		PaymentWebhookSecret:       os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+		QRSigningKeyID:             qrSigningKeyID,
+		QRSigningKey:               os.Getenv("QR_SIGNING_KEY"),
+		QRVerificationKeys:         os.Getenv("QR_VERIFICATION_KEYS"),
+		CheckInOpensBefore:         checkInOpensBefore,
+		CheckInClosesAfter:         checkInClosesAfter,
+		TicketCancelCutoff:         ticketCancelCutoff,
+		WalletPassTypeID:           os.Getenv("WALLET_PASS_TYPE_ID"),
+		WalletTeamID:               os.Getenv("WALLET_TEAM_ID"),
+		WalletOrganization:         os.Getenv("WALLET_ORGANIZATION"),
+		WalletCertificate:          os.Getenv("WALLET_CERTIFICATE"),
+		WalletPrivateKey:           os.Getenv("WALLET_PRIVATE_KEY"),
+		WalletWWDRCert:             os.Getenv("WALLET_WWDR_CERTIFICATE"),
 	}
 }
+
+// EsDesarrollo indica si el servidor corre en un ambiente local o de desarrollo, donde se
+// permiten la pasarela fake y una clave temporal para los QR. Sin APP_ENV se asume producción.
+func (c *ConfigEnv) EsDesarrollo() bool {
+	return c.AppEnv == "local" || c.AppEnv == "development"
+}
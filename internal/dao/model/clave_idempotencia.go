@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ClaveIdempotencia guarda la primera respuesta de una petición enviada con el header
+// Idempotency-Key, para repetirla tal cual si el cliente reintenta con la misma clave.
+// Mientras CodigoRespuesta sea nil la petición original sigue en curso; FechaBloqueo es cuándo la
+// tomó el proceso que la ejecuta.
+type ClaveIdempotencia struct {
+	UsuarioID       int64  `gorm:"primaryKey"`
+	Clave           string `gorm:"primaryKey;size:255"`
+	Ruta            string `gorm:"not null"`
+	HashPeticion    []byte `gorm:"not null"` // sha256 de método, ruta y cuerpo
+	CodigoRespuesta *int
+	TipoContenido   string
+	CuerpoRespuesta []byte
+	FechaCreacion   time.Time `gorm:"default:now()"`
+	FechaBloqueo    time.Time `gorm:"not null;default:now()"`
+	FechaExpiracion time.Time `gorm:"not null;index"`
+
+	Usuario *Usuario `gorm:"foreignKey:UsuarioID;references:usuario_id;constraint:OnDelete:CASCADE"`
+}
+
+func (ClaveIdempotencia) TableName() string { return "clave_idempotencia" }
+
+// Completada indica si ya se guardó la respuesta de la petición original.
+func (k ClaveIdempotencia) Completada() bool {
+	return k.CodigoRespuesta != nil
+}
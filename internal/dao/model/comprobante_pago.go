@@ -12,8 +12,13 @@ type ComprobanteDePago struct {
 	FechaEmision      time.Time
 	RUC               *string
 	DireccionFiscal   *string
+	// Solo en notas de crédito: el comprobante que modifican y el monto devuelto. En boletas y
+	// facturas el monto es el total de la orden.
+	ComprobanteReferenciaID *int64
+	Monto                   *float64
 
-	OrdenDeCompra *OrdenDeCompra `gorm:"foreignKey:OrdenDeCompraID;references:orden_de_compra_id"`
+	OrdenDeCompra         *OrdenDeCompra     `gorm:"foreignKey:OrdenDeCompraID;references:orden_de_compra_id"`
+	ComprobanteReferencia *ComprobanteDePago `gorm:"foreignKey:ComprobanteReferenciaID;references:comprobante_de_pago_id"`
 }
 
 func (ComprobanteDePago) TableName() string { return "comprobante_de_pago" }
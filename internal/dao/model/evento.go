@@ -16,13 +16,15 @@ type Evento struct {
 	ID                  int64 `gorm:"column:evento_id;primaryKey;autoIncrement"`
 	OrganizadorID       int64
 	CategoriaID         int64
+	RecintoID           *int64 `gorm:"index"` // nil en eventos con el lugar solo como texto
 	Titulo              string
 	Descripcion         string
 	Lugar               string
-	EventoEstado        int16 `gorm:"default:0"`
-	CantMeGusta         int64 `gorm:"default:0"`
-	CantNoInteresa      int64 `gorm:"default:0"`
-	CantVendidoTotal    int64 `gorm:"default:0"`
+	EventoEstado        int16      `gorm:"default:0"`
+	PublicarEn          *time.Time `gorm:"column:publish_at;index"` // publicación programada de un BORRADOR
+	CantMeGusta         int64      `gorm:"default:0"`
+	CantNoInteresa      int64      `gorm:"default:0"`
+	CantVendidoTotal    int64      `gorm:"default:0"`
 	ImagenDescripcion   string
 	ImagenPortada       string
 	VideoPresentacion   string
@@ -36,6 +38,7 @@ type Evento struct {
 
 	Organizador *Usuario   `gorm:"foreignKey:OrganizadorID;references:ID"`
 	Categoria   *Categoria `gorm:"foreignKey:CategoriaID;references:ID"`
+	Recinto     *Recinto   `gorm:"foreignKey:RecintoID;references:ID"`
 
 	Interaccion []Interaccion
 	Sectores    []Sector          `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// Tipos de evento de dominio
+const (
+	EventoOrdenConfirmada    = "ORDEN_CONFIRMADA"
+	EventoEventoCancelado    = "EVENTO_CANCELADO"
+	EventoEventoReprogramado = "EVENTO_REPROGRAMADO"
+	EventoFechaReprogramada  = "FECHA_REPROGRAMADA"
+	EventoTicketTransferido  = "TICKET_TRANSFERIDO"
+)
+
+// EventoDominio es una fila del outbox transaccional: algo que pasó en el dominio (una orden
+// confirmada, un evento cancelado...) y que se inserta en la misma transacción que el cambio.
+// El despachador lo expande después en notificaciones, una por destinatario y canal, así el
+// envío nunca hace fallar ni deshace el cambio que lo originó.
+type EventoDominio struct {
+	ID             int64      `gorm:"column:evento_dominio_id;primaryKey;autoIncrement"`
+	Tipo           string     `gorm:"size:40;not null"`
+	AgregadoID     int64      `gorm:"not null"` // orden, evento, evento_fecha o ticket según Tipo
+	Payload        string     `gorm:"type:jsonb;not null"`
+	Estado         int16      `gorm:"not null;default:0"`
+	Intentos       int16      `gorm:"not null;default:0"`
+	ProximoIntento *time.Time `gorm:"index"` // nil cuando ya se procesó o quedó FALLIDO
+	UltimoError    *string
+	FechaCreacion  time.Time `gorm:"default:now()"`
+	FechaProcesado *time.Time
+}
+
+func (EventoDominio) TableName() string { return "evento_dominio" }
+
+// PayloadOrdenConfirmada acompaña a EventoOrdenConfirmada.
+type PayloadOrdenConfirmada struct {
+	OrdenDeCompraID int64 `json:"orden_de_compra_id"`
+	UsuarioID       int64 `json:"usuario_id"`
+}
+
+// PayloadEventoCancelado acompaña a EventoEventoCancelado. Asistentes se toma al cancelar,
+// antes de que los reembolsos cancelen los tickets; sin él se avisa a los titulares vigentes.
+type PayloadEventoCancelado struct {
+	EventoID   int64   `json:"evento_id"`
+	Asistentes []int64 `json:"asistentes,omitempty"`
+}
+
+// PayloadEventoReprogramado acompaña a EventoEventoReprogramado: la fecha del evento que cambió
+// de día u hora.
+type PayloadEventoReprogramado struct {
+	EventoID      int64 `json:"evento_id"`
+	EventoFechaID int64 `json:"evento_fecha_id"`
+}
+
+// PayloadFechaReprogramada acompaña a EventoFechaReprogramada: una fecha con entradas vendidas
+// que se movió y cuyos titulares tienen que aceptar el cambio o pedir el reembolso.
+type PayloadFechaReprogramada struct {
+	ReprogramacionID int64 `json:"reprogramacion_id"`
+	EventoID         int64 `json:"evento_id"`
+}
+
+// PayloadTicketTransferido acompaña a EventoTicketTransferido.
+type PayloadTicketTransferido struct {
+	TicketID        int64 `json:"ticket_id"`
+	TransferenciaID int64 `json:"transferencia_id"`
+	DeUsuarioID     int64 `json:"de_usuario_id"`
+	ParaUsuarioID   int64 `json:"para_usuario_id"`
+}
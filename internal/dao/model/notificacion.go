@@ -2,12 +2,47 @@ package model
 
 import "time"
 
+// Canales de notificación
+const (
+	CanalCorreo  = "EMAIL"
+	CanalWebhook = "WEBHOOK"
+	CanalInApp   = "IN_APP"
+)
+
+// Plantillas de correo que se envían desde el outbox
+const (
+	PlantillaOrdenConfirmada = "orden_confirmada.tmpl"
+	PlantillaAviso           = "aviso.tmpl"
+)
+
+// Notificacion es un envío por un canal. El despachador las crea al procesar un EventoDominio,
+// una por destinatario y canal, y un worker las envía después, reintentando con espera
+// creciente hasta quedar ENVIADO o, agotados los intentos, NO_ENVIADO (dead letter, que un
+// administrador puede reintentar). Así una falla del SMTP nunca deshace una compra pagada.
+// Las IN_APP ya enviadas forman la bandeja del usuario; Leida solo tiene sentido en ellas.
 type Notificacion struct {
 	ID                 int64 `gorm:"column:notificacion_id;primaryKey;autoIncrement"`
 	Mensaje            string
+	Asunto             string
 	Canal              string
-	FechaEnvio         time.Time
+	Destino            string    // correo o URL según el canal; vacío para IN_APP
+	FechaEnvio         time.Time // último intento; mientras no se intenta, la fecha de creación
 	EstadoNotificacion int16
+	Plantilla          string
+	Tipo               string `gorm:"size:40"` // tipo del evento de dominio que la originó
+	Leida              bool   `gorm:"not null;default:false"`
+	FechaLectura       *time.Time
+	EventoDominioID    *int64     `gorm:"index"`
+	UsuarioID          *int64     `gorm:"index"`
+	OrdenDeCompraID    *int64     `gorm:"index"`
+	Intentos           int16      `gorm:"not null;default:0"`
+	ProximoIntento     *time.Time `gorm:"index"` // nil cuando ya no hay nada que enviar
+	UltimoError        *string
+	FechaCreacion      time.Time `gorm:"default:now()"`
+
+	EventoDominio *EventoDominio `gorm:"foreignKey:EventoDominioID;references:evento_dominio_id"`
+	Usuario       *Usuario       `gorm:"foreignKey:UsuarioID;references:usuario_id"`
+	OrdenDeCompra *OrdenDeCompra `gorm:"foreignKey:OrdenDeCompraID;references:orden_de_compra_id"`
 }
 
 func (Notificacion) TableName() string { return "notificacion" }
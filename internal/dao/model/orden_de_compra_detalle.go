@@ -0,0 +1,29 @@
+package model
+
+// OrdenDeCompraDetalle es una línea de la orden: cuántas entradas de una tarifa se compraron
+// para una fecha del evento y a qué precio. Se escribe al crear el hold y de ella se leen
+// la emisión de tickets, la liberación de stock y los reportes.
+type OrdenDeCompraDetalle struct {
+	ID                int64 `gorm:"column:orden_de_compra_detalle_id;primaryKey;autoIncrement"`
+	OrdenDeCompraID   int64 `gorm:"not null;index"`
+	TarifaID          int64 `gorm:"not null"`
+	SectorID          int64 `gorm:"not null;index"`
+	EventoFechaID     int64 `gorm:"not null;index"`
+	PerfilDePersonaID *int64
+	Cantidad          int64   `gorm:"not null"`
+	PrecioUnitario    float64 `gorm:"not null"`
+	Descuento         float64 `gorm:"default:0"` // descuento total de la línea (no por entrada)
+
+	OrdenDeCompra   *OrdenDeCompra   `gorm:"foreignKey:OrdenDeCompraID;references:orden_de_compra_id"`
+	Tarifa          *Tarifa          `gorm:"foreignKey:TarifaID;references:tarifa_id"`
+	Sector          *Sector          `gorm:"foreignKey:SectorID;references:sector_id"`
+	EventoFecha     *EventoFecha     `gorm:"foreignKey:EventoFechaID;references:evento_fecha_id"`
+	PerfilDePersona *PerfilDePersona `gorm:"foreignKey:PerfilDePersonaID;references:perfil_de_persona_id"`
+}
+
+func (OrdenDeCompraDetalle) TableName() string { return "orden_de_compra_detalle" }
+
+// Subtotal es el monto de la línea ya descontado.
+func (d OrdenDeCompraDetalle) Subtotal() float64 {
+	return float64(d.Cantidad)*d.PrecioUnitario - d.Descuento
+}
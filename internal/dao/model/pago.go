@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// Pago es el cobro de una orden en una pasarela. Referencia es el id del intento en la pasarela
+// y es lo que llega en la confirmación y en los webhooks.
+type Pago struct {
+	ID                int64  `gorm:"column:pago_id;primaryKey;autoIncrement"`
+	OrdenDeCompraID   int64  `gorm:"not null;index"`
+	MetodoPago        string `gorm:"not null"` // util.TipoMetodoPago
+	Referencia        string `gorm:"not null;uniqueIndex"`
+	Monto             float64
+	Moneda            string    `gorm:"default:PEN"`
+	EstadoDePago      int16     `gorm:"default:0"`
+	FechaCreacion     time.Time `gorm:"default:now()"`
+	FechaModificacion *time.Time
+
+	OrdenDeCompra *OrdenDeCompra `gorm:"foreignKey:OrdenDeCompraID;references:orden_de_compra_id"`
+}
+
+func (Pago) TableName() string { return "pago" }
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// TiposNotificacion son los tipos de notificación que un usuario puede configurar, uno por tipo
+// de evento de dominio.
+var TiposNotificacion = []string{
+	EventoOrdenConfirmada,
+	EventoEventoCancelado,
+	EventoEventoReprogramado,
+	EventoFechaReprogramada,
+	EventoTicketTransferido,
+}
+
+// CanalesPreferencia son los canales que un usuario puede apagar; el webhook es de la
+// plataforma, no del usuario.
+var CanalesPreferencia = []string{CanalCorreo, CanalInApp}
+
+// PreferenciaObligatoria indica si el canal no se puede apagar para ese tipo: el correo de la
+// orden confirmada lleva el comprobante y los tickets, y el de una fecha reprogramada el plazo
+// para pedir el reembolso.
+func PreferenciaObligatoria(tipo, canal string) bool {
+	return (tipo == EventoOrdenConfirmada || tipo == EventoFechaReprogramada) && canal == CanalCorreo
+}
+
+// PreferenciaNotificacion indica si un usuario quiere recibir un tipo de notificación por un
+// canal. Sin fila, el canal está habilitado.
+type PreferenciaNotificacion struct {
+	UsuarioID         int64     `gorm:"column:usuario_id;primaryKey"`
+	Tipo              string    `gorm:"size:40;primaryKey"`
+	Canal             string    `gorm:"size:40;primaryKey"`
+	Habilitado        bool      `gorm:"not null;default:true"`
+	FechaModificacion time.Time `gorm:"default:now()"`
+
+	Usuario *Usuario `gorm:"foreignKey:UsuarioID;references:usuario_id"`
+}
+
+func (PreferenciaNotificacion) TableName() string { return "preferencia_notificacion" }
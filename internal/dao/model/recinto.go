@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Recinto es el local donde se hacen los eventos (estadio, teatro, centro de convenciones). La
+// ubicación administrativa sigue a ValidacionDocumento (departamento, provincia, distrito y el
+// ubigeo de 6 dígitos del INEI) y las coordenadas permiten ubicarlo en un mapa y buscar eventos
+// por cercanía.
+type Recinto struct {
+	ID                  int64   `gorm:"column:recinto_id;primaryKey;autoIncrement"`
+	Nombre              string  `gorm:"size:80;not null"`
+	Direccion           string  `gorm:"size:200;not null"`
+	Departamento        string  `gorm:"size:60;not null"`
+	Provincia           string  `gorm:"size:60;not null"`
+	Distrito            string  `gorm:"size:60;not null"`
+	Ubigeo              string  `gorm:"size:6;not null;index"`
+	Latitud             float64 `gorm:"not null;index:idx_recinto_coordenadas,priority:1"`
+	Longitud            float64 `gorm:"not null;index:idx_recinto_coordenadas,priority:2"`
+	Capacidad           int64   `gorm:"not null;default:0"`
+	ImagenMapaAsientos  string
+	Estado              int16 `gorm:"default:1"`
+	UsuarioCreacion     *int64
+	FechaCreacion       time.Time `gorm:"default:now()"`
+	UsuarioModificacion *int64
+	FechaModificacion   *time.Time
+}
+
+func (Recinto) TableName() string { return "recinto" }
@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// Motivos de reembolso
+const (
+	MotivoReembolsoCancelacionUsuario = "CANCELACION_USUARIO" // el comprador canceló sus tickets
+	MotivoReembolsoCancelacionEvento  = "CANCELACION_EVENTO"  // el organizador canceló el evento
+	MotivoReembolsoReprogramacion     = "REPROGRAMACION"      // el titular no aceptó la nueva fecha
+	MotivoReembolsoAdministrador      = "ADMINISTRADOR"       // reembolso manual desde el panel
+)
+
+// Reembolso es la devolución de parte o todo lo pagado en una orden, por los tickets de
+// ReembolsoTicket. Al crearse los tickets quedan CANCELADOS y se descuentan de los acumulados
+// del evento; el dinero se devuelve después por la pasarela y, si esta falla, el reembolso queda
+// FALLIDO hasta que un administrador lo reintente. Al completarse se emite una nota de crédito
+// contra el comprobante de la orden.
+type Reembolso struct {
+	ID               int64   `gorm:"column:reembolso_id;primaryKey;autoIncrement"`
+	OrdenDeCompraID  int64   `gorm:"not null;index"`
+	PagoID           int64   `gorm:"not null;index"`
+	Motivo           string  `gorm:"size:40;not null"`
+	Monto            float64 `gorm:"not null"` // lo que se devuelve al comprador
+	MontoFeeServicio float64 `gorm:"not null"` // parte del fee de servicio incluida en Monto
+	MontoOrganizador float64 `gorm:"not null"` // lo que se descuenta de la ganancia del organizador
+	Estado           int16   `gorm:"not null;default:0"`
+	Referencia       *string // id del reembolso en la pasarela
+	UltimoError      *string
+	NotaCreditoID    *int64
+	UsuarioCreacion  *int64
+	FechaCreacion    time.Time `gorm:"default:now()"`
+	FechaCompletado  *time.Time
+
+	OrdenDeCompra *OrdenDeCompra     `gorm:"foreignKey:OrdenDeCompraID;references:orden_de_compra_id"`
+	Pago          *Pago              `gorm:"foreignKey:PagoID;references:pago_id"`
+	NotaCredito   *ComprobanteDePago `gorm:"foreignKey:NotaCreditoID;references:comprobante_de_pago_id"`
+	Tickets       []ReembolsoTicket
+}
+
+func (Reembolso) TableName() string { return "reembolso" }
+
+// ReembolsoTicket es un ticket devuelto en un reembolso. Un ticket se reembolsa una sola vez.
+type ReembolsoTicket struct {
+	ReembolsoID      int64   `gorm:"primaryKey"`
+	TicketID         int64   `gorm:"primaryKey;uniqueIndex"`
+	Monto            float64 `gorm:"not null"` // lo pagado por el ticket que se devuelve
+	MontoFeeServicio float64 `gorm:"not null"`
+
+	Reembolso *Reembolso `gorm:"foreignKey:ReembolsoID;references:reembolso_id"`
+	Ticket    *Ticket    `gorm:"foreignKey:TicketID;references:ticket_id"`
+}
+
+func (ReembolsoTicket) TableName() string { return "reembolso_ticket" }
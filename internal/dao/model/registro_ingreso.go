@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// RegistroIngreso es el check-in de un ticket en la puerta: quién lo escaneó, en qué puerta
+// y cuándo. Se crea en la misma transacción que pasa el ticket de VENDIDO a USADO, así que
+// hay a lo sumo uno por ticket. Los escaneos sincronizados desde un escáner offline guardan
+// el dispositivo y la hora del dispositivo; si el mismo ticket se escaneó en dos dispositivos
+// queda el escaneo más temprano.
+type RegistroIngreso struct {
+	ID            int64     `gorm:"column:registro_ingreso_id;primaryKey;autoIncrement"`
+	TicketID      int64     `gorm:"not null;uniqueIndex"`
+	EventoFechaID int64     `gorm:"not null;index"`
+	Puerta        string    `gorm:"size:60;not null"`
+	OperadorID    int64     `gorm:"not null"`
+	Dispositivo   *string   `gorm:"size:100"` // nil para check-in en línea
+	FechaIngreso  time.Time `gorm:"not null"`
+
+	Ticket      *Ticket      `gorm:"foreignKey:TicketID;references:ticket_id"`
+	EventoFecha *EventoFecha `gorm:"foreignKey:EventoFechaID;references:evento_fecha_id"`
+	Operador    *Usuario     `gorm:"foreignKey:OperadorID;references:usuario_id"`
+}
+
+func (RegistroIngreso) TableName() string { return "registro_ingreso" }
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// ReprogramacionFecha es el cambio de una fecha del evento que ya tenía entradas vendidas. La
+// fecha original se conserva inactiva para el historial y sus tickets VENDIDOS pasan a la nueva,
+// con un QR nuevo; cada titular tiene hasta FechaLimite para aceptar el cambio o pedir el
+// reembolso (ReprogramacionTicket). Al vencer el plazo se cierra y lo que no se respondió cuenta
+// como aceptado.
+type ReprogramacionFecha struct {
+	ID                   int64     `gorm:"column:reprogramacion_fecha_id;primaryKey;autoIncrement"`
+	EventoID             int64     `gorm:"not null;index"`
+	EventoFechaOrigenID  int64     `gorm:"not null;index"`
+	EventoFechaDestinoID int64     `gorm:"not null;index"`
+	FechaLimite          time.Time `gorm:"not null"`
+	TicketsMigrados      int64     `gorm:"not null;default:0"`
+	UsuarioCreacion      *int64
+	FechaCreacion        time.Time  `gorm:"default:now()"`
+	FechaCierre          *time.Time // nil mientras los titulares pueden responder
+
+	Origen  *EventoFecha `gorm:"foreignKey:EventoFechaOrigenID;references:evento_fecha_id"`
+	Destino *EventoFecha `gorm:"foreignKey:EventoFechaDestinoID;references:evento_fecha_id"`
+	Tickets []ReprogramacionTicket
+}
+
+func (ReprogramacionFecha) TableName() string { return "reprogramacion_fecha" }
+
+// ReprogramacionTicket es la respuesta del titular de un ticket migrado. Un reembolso del ticket,
+// pedido por él o hecho por un administrador, la deja REEMBOLSADA.
+type ReprogramacionTicket struct {
+	ReprogramacionFechaID int64 `gorm:"primaryKey"`
+	TicketID              int64 `gorm:"primaryKey;index"`
+	Estado                int16 `gorm:"not null;default:0"`
+	ReembolsoID           *int64
+	FechaRespuesta        *time.Time
+
+	ReprogramacionFecha *ReprogramacionFecha `gorm:"foreignKey:ReprogramacionFechaID;references:reprogramacion_fecha_id"`
+	Ticket              *Ticket              `gorm:"foreignKey:TicketID;references:ticket_id"`
+}
+
+func (ReprogramacionTicket) TableName() string { return "reprogramacion_ticket" }
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// PoliticaReventa es lo que el organizador permite en la reventa de los tickets de su evento:
+// si está habilitada, el precio máximo (en % del precio original de la tarifa) y la regalía que
+// se queda el organizador en cada venta (en % del precio de reventa).
+type PoliticaReventa struct {
+	EventoID               int64   `gorm:"column:evento_id;primaryKey"`
+	Habilitada             bool    `gorm:"not null;default:false"`
+	PrecioMaximoPorcentaje float64 `gorm:"not null;default:100"` // 100 = precio original
+	RegaliaPorcentaje      float64 `gorm:"not null;default:0"`
+	UsuarioModificacion    *int64
+	FechaModificacion      time.Time `gorm:"default:now()"`
+
+	Evento *Evento `gorm:"foreignKey:EventoID;references:evento_id"`
+}
+
+func (PoliticaReventa) TableName() string { return "politica_reventa" }
+
+// PublicacionReventa es un ticket VENDIDO que su titular puso a la venta. Los montos del reparto
+// (fee de la plataforma, regalía del organizador y lo que recibe el vendedor) se fijan al
+// publicar. Un comprador la reserva con una orden TEMPORAL y al confirmarse el pago el ticket
+// pasa a su nombre con un QR nuevo. Solo puede haber una publicación activa por ticket.
+type PublicacionReventa struct {
+	ID                 int64   `gorm:"column:publicacion_reventa_id;primaryKey;autoIncrement"`
+	TicketID           int64   `gorm:"not null;index;uniqueIndex:idx_reventa_activa,where:estado IN (0,1)"`
+	EventoID           int64   `gorm:"not null;index"`
+	VendedorID         int64   `gorm:"not null;index"`
+	Precio             float64 `gorm:"not null"`
+	PrecioOriginal     float64 `gorm:"not null"`
+	MontoFeePlataforma float64 `gorm:"not null"`
+	MontoRegalia       float64 `gorm:"not null"`
+	MontoVendedor      float64 `gorm:"not null"`
+	Estado             int16   `gorm:"not null;default:0"`
+	OrdenDeCompraID    *int64  `gorm:"index"` // orden del comprador que la reservó o la compró
+	CompradorID        *int64
+	FechaCreacion      time.Time `gorm:"default:now()"`
+	FechaVenta         *time.Time
+
+	Ticket        *Ticket        `gorm:"foreignKey:TicketID;references:ticket_id"`
+	Evento        *Evento        `gorm:"foreignKey:EventoID;references:evento_id"`
+	Vendedor      *Usuario       `gorm:"foreignKey:VendedorID;references:usuario_id"`
+	Comprador     *Usuario       `gorm:"foreignKey:CompradorID;references:usuario_id"`
+	OrdenDeCompra *OrdenDeCompra `gorm:"foreignKey:OrdenDeCompraID;references:orden_de_compra_id"`
+}
+
+func (PublicacionReventa) TableName() string { return "publicacion_reventa" }
@@ -4,6 +4,13 @@ import (
 	"time"
 )
 
+// Nombres de rol sembrados en la tabla rol
+const (
+	RolAdministrador = "ADMINISTRADOR"
+	RolOrganizador   = "ORGANIZADOR"
+	RolAsistente     = "ASISTENTE"
+)
+
 type Rol struct {
 	ID                  int64  `gorm:"column:rol_id;primaryKey;autoIncrement"`
 	Nombre              string `gorm:"uniqueIndex"`
@@ -3,12 +3,14 @@ package model
 type Ticket struct {
 	ID              int64 `gorm:"column:ticket_id;primaryKey;autoIncrement"`
 	OrdenDeCompraID *int64
+	TitularID       *int64 // quien puede usar el ticket; nil en tickets anteriores a las transferencias (titular = comprador)
 	EventoFechaID   int64
 	TarifaID        int64
 	CodigoQR        string `gorm:"uniqueIndex"`
 	EstadoDeTicket  int16  `gorm:"default:0"`
 
 	OrdenDeCompra *OrdenDeCompra `gorm:"foreignKey:OrdenDeCompraID;references:orden_de_compra_id"`
+	Titular       *Usuario       `gorm:"foreignKey:TitularID;references:usuario_id"`
 	EventoFecha   *EventoFecha   `gorm:"foreignKey:EventoFechaID;references:evento_fecha_id"`
 	Tarifa        *Tarifa        `gorm:"foreignKey:TarifaID;references:tarifa_id"`
 }
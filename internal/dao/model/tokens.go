@@ -7,15 +7,23 @@ import (
 	"time"
 )
 
+// Scopes de token soportados
+const (
+	ScopeAuthentication = "authentication"
+	ScopePasswordReset  = "password-reset"
+)
+
 type Token struct {
-    Plaintext string    `json:"token"` // El token que se envía al cliente
-    Hash      []byte    `json:"-" gorm:"-"` // No serializar ni guardar en BD
-    UsuarioID int64     `json:"user_id" gorm:"-"`
-    Expiry    time.Time `json:"expiry" gorm:"-"`
-    Scope     string    `json:"scope" gorm:"-"`
+	Plaintext string    `json:"token" gorm:"-"` // El token que se envía al cliente
+	Hash      []byte    `json:"-" gorm:"column:hash;primaryKey"`
+	UsuarioID int64     `json:"user_id" gorm:"not null;index:idx_token_usuario_scope"`
+	Expiry    time.Time `json:"expiry" gorm:"not null"`
+	Scope     string    `json:"scope" gorm:"not null;index:idx_token_usuario_scope"`
+
+	Usuario *Usuario `json:"-" gorm:"foreignKey:UsuarioID;references:usuario_id;constraint:OnDelete:CASCADE"`
 }
 
-//func (Token) TableName() string { return "token" }
+func (Token) TableName() string { return "token" }
 
 func GenerateToken(usuarioID int64, ttl time.Duration, scope string) (*Token, error) {
 	// Create a Token instance containing the user ID, expiry, and scope information.
@@ -23,8 +31,8 @@ func GenerateToken(usuarioID int64, ttl time.Duration, scope string) (*Token, er
 	// current time to get the expiry time?
 	token := &Token{
 		UsuarioID: usuarioID,
-		Expiry: time.Now().Add(ttl),
-		Scope:  scope,
+		Expiry:    time.Now().Add(ttl),
+		Scope:     scope,
 	}
 	// Initialize a zero-valued byte slice with a length of 16 bytes.
 	randomBytes := make([]byte, 16)
@@ -49,7 +57,12 @@ func GenerateToken(usuarioID int64, ttl time.Duration, scope string) (*Token, er
 	// that we store in the `hash` field of our database table. Note that the
 	// sha256.Sum256() function returns an *array* of length 32, so to make it easier to
 	// work with we convert it to a slice using the [:] operator before storing it.
-	hash := sha256.Sum256([]byte(token.Plaintext))
-	token.Hash = hash[:]
+	token.Hash = HashToken(token.Plaintext)
 	return token, nil
 }
+
+// HashToken calcula el SHA-256 del token en texto plano, que es lo único que se guarda en BD.
+func HashToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// TransferenciaTicket es la oferta de un titular para pasarle su ticket a otra persona,
+// identificada por correo. Al aceptarse el ticket cambia de titular y se le firma un QR nuevo.
+// Solo puede haber una oferta PENDIENTE por ticket.
+type TransferenciaTicket struct {
+	ID              int64     `gorm:"column:transferencia_ticket_id;primaryKey;autoIncrement"`
+	TicketID        int64     `gorm:"not null;index;uniqueIndex:idx_transferencia_pendiente,where:estado = 0"`
+	DeUsuarioID     int64     `gorm:"not null;index"`
+	ParaCorreo      string    `gorm:"not null;index"`
+	ParaUsuarioID   *int64    // se completa al aceptar
+	Estado          int16     `gorm:"not null;default:0"`
+	FechaCreacion   time.Time `gorm:"default:now()"`
+	FechaExpiracion time.Time `gorm:"not null"`
+	FechaResolucion *time.Time
+	ResueltaPorID   *int64 // quién aceptó o canceló
+
+	Ticket      *Ticket  `gorm:"foreignKey:TicketID;references:ticket_id"`
+	DeUsuario   *Usuario `gorm:"foreignKey:DeUsuarioID;references:usuario_id"`
+	ParaUsuario *Usuario `gorm:"foreignKey:ParaUsuarioID;references:usuario_id"`
+}
+
+func (TransferenciaTicket) TableName() string { return "transferencia_ticket" }
+
+// HistorialTitular registra cada titular que tuvo un ticket y desde cuándo. La fila vigente
+// es la que tiene Hasta en nil.
+type HistorialTitular struct {
+	ID              int64  `gorm:"column:historial_titular_id;primaryKey;autoIncrement"`
+	TicketID        int64  `gorm:"not null;index"`
+	UsuarioID       int64  `gorm:"not null;index"`
+	Motivo          string `gorm:"size:20;not null"` // "EMISION" | "TRANSFERENCIA" | "REVENTA"
+	TransferenciaID *int64
+	Desde           time.Time `gorm:"not null"`
+	Hasta           *time.Time
+
+	Ticket        *Ticket              `gorm:"foreignKey:TicketID;references:ticket_id"`
+	Usuario       *Usuario             `gorm:"foreignKey:UsuarioID;references:usuario_id"`
+	Transferencia *TransferenciaTicket `gorm:"foreignKey:TransferenciaID;references:transferencia_ticket_id"`
+}
+
+func (HistorialTitular) TableName() string { return "historial_titular" }
+
+const (
+	MotivoTitularEmision       = "EMISION"
+	MotivoTitularTransferencia = "TRANSFERENCIA"
+	MotivoTitularReventa       = "REVENTA"
+)
@@ -22,6 +22,10 @@ type Usuario struct {
 	EstadoDeCuenta        int16 `gorm:"default:0"`
 	CodigoVerificacion    *string
 	FechaExpiracionCodigo *time.Time
+	IntentosCodigo        int16 `gorm:"default:0"`
+	EnviosCodigo          int16 `gorm:"default:0"`
+	FechaUltimoEnvio      *time.Time
+	FechaRecuperacion     *time.Time
 	CuentaDeBanco         *string
 	UsuarioCreacion       *int64
 	FechaCreacion         time.Time `gorm:"default:now()"`
@@ -120,3 +124,19 @@ func VerifyPassword(password, encodedHash string) (bool, error) {
 func (u *Usuario) IsAnonymous() bool {
 	return u == AnonymousUser
 }
+
+// TieneAlgunRol indica si alguna de las asignaciones activas cargadas en RolesAsignados
+// corresponde a uno de los roles indicados.
+func (u *Usuario) TieneAlgunRol(roles ...string) bool {
+	for _, asignacion := range u.RolesAsignados {
+		if asignacion.Estado != 1 || asignacion.Rol == nil {
+			continue
+		}
+		for _, rol := range roles {
+			if asignacion.Rol.Nombre == rol {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -8,11 +8,35 @@ import (
 type EstadoEvento int16
 
 const (
-	EventoBorrador  EstadoEvento = iota // 0
-	EventoPublicado                     // 1
-	EventoCancelado                     // 2
+	EventoBorrador   EstadoEvento = iota // 0
+	EventoPublicado                      // 1
+	EventoCancelado                      // 2
+	EventoFinalizado                     // 3
+	EventoPostergado                     // 4
 )
 
+// transicionesEvento son los cambios de estado permitidos. CANCELADO y FINALIZADO son finales;
+// un evento POSTERGADO vuelve a PUBLICADO cuando se confirma la nueva fecha.
+var transicionesEvento = map[EstadoEvento][]EstadoEvento{
+	EventoBorrador:   {EventoPublicado, EventoCancelado},
+	EventoPublicado:  {EventoPostergado, EventoFinalizado, EventoCancelado},
+	EventoPostergado: {EventoPublicado, EventoCancelado},
+}
+
+// PuedePasarA indica si el evento puede cambiar de e a nuevo. Quedarse en el mismo estado
+// siempre está permitido.
+func (e EstadoEvento) PuedePasarA(nuevo EstadoEvento) bool {
+	if e == nuevo {
+		return true
+	}
+	for _, destino := range transicionesEvento[e] {
+		if destino == nuevo {
+			return true
+		}
+	}
+	return false
+}
+
 // == equivalente a getCodigo() ==
 func (e EstadoEvento) Codigo() int16 { return int16(e) }
 
@@ -25,6 +49,10 @@ func ValueOfEstadoEventoCodigo(c int16) (EstadoEvento, error) {
 		return EventoPublicado, nil
 	case 2:
 		return EventoCancelado, nil
+	case 3:
+		return EventoFinalizado, nil
+	case 4:
+		return EventoPostergado, nil
 	default:
 		return 0, fmt.Errorf("código de estado inválido: %d", c)
 	}
@@ -38,12 +66,16 @@ func (e EstadoEvento) String() string {
 		return "PUBLICADO"
 	case EventoCancelado:
 		return "CANCELADO"
+	case EventoFinalizado:
+		return "FINALIZADO"
+	case EventoPostergado:
+		return "POSTERGADO"
 	default:
 		return "DESCONOCIDO"
 	}
 }
 
-func (e EstadoEvento) IsValid() bool { return e >= EventoBorrador && e <= EventoCancelado }
+func (e EstadoEvento) IsValid() bool { return e >= EventoBorrador && e <= EventoPostergado }
 
 /* ---- Integración con database/sql (columna SMALLINT) ---- */
 
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+type EstadoEventoDominio int16
+
+const (
+	EventoDominioPendiente EstadoEventoDominio = iota // 0
+	EventoDominioProcesado                            // 1
+	EventoDominioFallido                              // 2
+)
+
+func (e EstadoEventoDominio) Codigo() int16 { return int16(e) }
+
+func ValueOfEstadoEventoDominioCodigo(c int16) (EstadoEventoDominio, error) {
+	switch c {
+	case 0:
+		return EventoDominioPendiente, nil
+	case 1:
+		return EventoDominioProcesado, nil
+	case 2:
+		return EventoDominioFallido, nil
+	default:
+		return 0, fmt.Errorf("código de estado de evento de dominio inválido: %d", c)
+	}
+}
+
+func (e EstadoEventoDominio) String() string {
+	switch e {
+	case EventoDominioPendiente:
+		return "PENDIENTE"
+	case EventoDominioProcesado:
+		return "PROCESADO"
+	case EventoDominioFallido:
+		return "FALLIDO"
+	default:
+		return "DESCONOCIDO"
+	}
+}
+
+func (e EstadoEventoDominio) IsValid() bool {
+	return e >= EventoDominioPendiente && e <= EventoDominioFallido
+}
+
+/* ---- Integración con database/sql (columna SMALLINT) ---- */
+
+func (e EstadoEventoDominio) Value() (driver.Value, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("estado de evento de dominio inválido: %d", e)
+	}
+	return int64(e), nil
+}
+
+func (e *EstadoEventoDominio) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*e = EstadoEventoDominio(v)
+	case int32:
+		*e = EstadoEventoDominio(v)
+	case int16:
+		*e = EstadoEventoDominio(v)
+	case []byte:
+		var n int16
+		if _, err := fmt.Sscanf(string(v), "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoEventoDominio: %w", err)
+		}
+		*e = EstadoEventoDominio(n)
+	case string:
+		var n int16
+		if _, err := fmt.Sscanf(v, "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoEventoDominio: %w", err)
+		}
+		*e = EstadoEventoDominio(n)
+	default:
+		return fmt.Errorf("tipo no soportado para EstadoEventoDominio: %T", src)
+	}
+	if !e.IsValid() {
+		return fmt.Errorf("estado de evento de dominio inválido: %d", *e)
+	}
+	return nil
+}
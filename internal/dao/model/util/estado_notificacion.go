@@ -10,6 +10,7 @@ type EstadoNotificacion int16
 const (
 	NotificacionEnviada   EstadoNotificacion = iota // 0
 	NotificacionNoEnviada                           // 1
+	NotificacionPendiente                           // 2
 )
 
 func (e EstadoNotificacion) Codigo() int16 { return int16(e) }
@@ -20,6 +21,8 @@ func ValueOfEstadoNotificacionCodigo(c int16) (EstadoNotificacion, error) {
 		return NotificacionEnviada, nil
 	case 1:
 		return NotificacionNoEnviada, nil
+	case 2:
+		return NotificacionPendiente, nil
 	default:
 		return 0, fmt.Errorf("código de estado de notificación inválido: %d", c)
 	}
@@ -31,13 +34,15 @@ func (e EstadoNotificacion) String() string {
 		return "ENVIADO"
 	case NotificacionNoEnviada:
 		return "NO_ENVIADO"
+	case NotificacionPendiente:
+		return "PENDIENTE"
 	default:
 		return "DESCONOCIDO"
 	}
 }
 
 func (e EstadoNotificacion) IsValid() bool {
-	return e == NotificacionEnviada || e == NotificacionNoEnviada
+	return e == NotificacionEnviada || e == NotificacionNoEnviada || e == NotificacionPendiente
 }
 
 /* ---- Integración con database/sql (columna SMALLINT) ---- */
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// EstadoPago modela el cobro de una orden en la pasarela (columna: estado_de_pago)
+// 0=PENDIENTE, 1=CAPTURADO, 2=FALLIDO, 3=REEMBOLSADO
+type EstadoPago int16
+
+const (
+	PagoPendiente   EstadoPago = iota // 0
+	PagoCapturado                     // 1
+	PagoFallido                       // 2
+	PagoReembolsado                   // 3
+)
+
+func (e EstadoPago) Codigo() int16 { return int16(e) }
+
+func (e EstadoPago) String() string {
+	switch e {
+	case PagoPendiente:
+		return "PENDIENTE"
+	case PagoCapturado:
+		return "CAPTURADO"
+	case PagoFallido:
+		return "FALLIDO"
+	case PagoReembolsado:
+		return "REEMBOLSADO"
+	default:
+		return "DESCONOCIDO"
+	}
+}
+
+func (e EstadoPago) IsValid() bool {
+	return e >= PagoPendiente && e <= PagoReembolsado
+}
+
+/* ---- Integración con database/sql (columna SMALLINT) ---- */
+
+func (e EstadoPago) Value() (driver.Value, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("estado_de_pago inválido: %d", e)
+	}
+	return int64(e), nil
+}
+
+func (e *EstadoPago) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*e = EstadoPago(v)
+	case int32:
+		*e = EstadoPago(v)
+	case int16:
+		*e = EstadoPago(v)
+	case []byte:
+		var n int16
+		if _, err := fmt.Sscanf(string(v), "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoPago: %w", err)
+		}
+		*e = EstadoPago(n)
+	case string:
+		var n int16
+		if _, err := fmt.Sscanf(v, "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoPago: %w", err)
+		}
+		*e = EstadoPago(n)
+	default:
+		return fmt.Errorf("tipo no soportado para EstadoPago: %T", src)
+	}
+	if !e.IsValid() {
+		return fmt.Errorf("estado_de_pago inválido: %d", *e)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// EstadoReembolso modela la devolución de dinero de una orden (columna: estado)
+// 0=PENDIENTE, 1=COMPLETADO, 2=FALLIDO
+type EstadoReembolso int16
+
+const (
+	ReembolsoPendiente  EstadoReembolso = iota // 0
+	ReembolsoCompletado                        // 1
+	ReembolsoFallido                           // 2
+)
+
+func (e EstadoReembolso) Codigo() int16 { return int16(e) }
+
+func (e EstadoReembolso) String() string {
+	switch e {
+	case ReembolsoPendiente:
+		return "PENDIENTE"
+	case ReembolsoCompletado:
+		return "COMPLETADO"
+	case ReembolsoFallido:
+		return "FALLIDO"
+	default:
+		return "DESCONOCIDO"
+	}
+}
+
+func (e EstadoReembolso) IsValid() bool {
+	return e >= ReembolsoPendiente && e <= ReembolsoFallido
+}
+
+/* ---- Integración con database/sql (columna SMALLINT) ---- */
+
+func (e EstadoReembolso) Value() (driver.Value, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("estado de reembolso inválido: %d", e)
+	}
+	return int64(e), nil
+}
+
+func (e *EstadoReembolso) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*e = EstadoReembolso(v)
+	case int32:
+		*e = EstadoReembolso(v)
+	case int16:
+		*e = EstadoReembolso(v)
+	case []byte:
+		var n int16
+		if _, err := fmt.Sscanf(string(v), "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoReembolso: %w", err)
+		}
+		*e = EstadoReembolso(n)
+	case string:
+		var n int16
+		if _, err := fmt.Sscanf(v, "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoReembolso: %w", err)
+		}
+		*e = EstadoReembolso(n)
+	default:
+		return fmt.Errorf("tipo no soportado para EstadoReembolso: %T", src)
+	}
+	if !e.IsValid() {
+		return fmt.Errorf("estado de reembolso inválido: %d", *e)
+	}
+	return nil
+}
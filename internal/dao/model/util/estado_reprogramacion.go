@@ -0,0 +1,77 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// EstadoReprogramacion modela la respuesta del titular de un ticket a la reprogramación de su
+// fecha (columna: estado). VENCIDA es la que no se respondió a tiempo y cuenta como aceptada.
+// 0=PENDIENTE, 1=ACEPTADA, 2=REEMBOLSADA, 3=VENCIDA
+type EstadoReprogramacion int16
+
+const (
+	ReprogramacionPendiente   EstadoReprogramacion = iota // 0
+	ReprogramacionAceptada                                // 1
+	ReprogramacionReembolsada                             // 2
+	ReprogramacionVencida                                 // 3
+)
+
+func (e EstadoReprogramacion) Codigo() int16 { return int16(e) }
+
+func (e EstadoReprogramacion) String() string {
+	switch e {
+	case ReprogramacionPendiente:
+		return "PENDIENTE"
+	case ReprogramacionAceptada:
+		return "ACEPTADA"
+	case ReprogramacionReembolsada:
+		return "REEMBOLSADA"
+	case ReprogramacionVencida:
+		return "VENCIDA"
+	default:
+		return "DESCONOCIDO"
+	}
+}
+
+func (e EstadoReprogramacion) IsValid() bool {
+	return e >= ReprogramacionPendiente && e <= ReprogramacionVencida
+}
+
+/* ---- Integración con database/sql (columna SMALLINT) ---- */
+
+func (e EstadoReprogramacion) Value() (driver.Value, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("estado de reprogramación inválido: %d", e)
+	}
+	return int64(e), nil
+}
+
+func (e *EstadoReprogramacion) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*e = EstadoReprogramacion(v)
+	case int32:
+		*e = EstadoReprogramacion(v)
+	case int16:
+		*e = EstadoReprogramacion(v)
+	case []byte:
+		var n int16
+		if _, err := fmt.Sscanf(string(v), "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoReprogramacion: %w", err)
+		}
+		*e = EstadoReprogramacion(n)
+	case string:
+		var n int16
+		if _, err := fmt.Sscanf(v, "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoReprogramacion: %w", err)
+		}
+		*e = EstadoReprogramacion(n)
+	default:
+		return fmt.Errorf("tipo no soportado para EstadoReprogramacion: %T", src)
+	}
+	if !e.IsValid() {
+		return fmt.Errorf("estado de reprogramación inválido: %d", *e)
+	}
+	return nil
+}
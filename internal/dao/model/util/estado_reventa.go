@@ -0,0 +1,76 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// EstadoReventa modela una publicación de reventa de ticket (columna: estado)
+// 0=PUBLICADA, 1=RESERVADA, 2=VENDIDA, 3=CANCELADA
+type EstadoReventa int16
+
+const (
+	ReventaPublicada EstadoReventa = iota // 0
+	ReventaReservada                      // 1
+	ReventaVendida                        // 2
+	ReventaCancelada                      // 3
+)
+
+func (e EstadoReventa) Codigo() int16 { return int16(e) }
+
+func (e EstadoReventa) String() string {
+	switch e {
+	case ReventaPublicada:
+		return "PUBLICADA"
+	case ReventaReservada:
+		return "RESERVADA"
+	case ReventaVendida:
+		return "VENDIDA"
+	case ReventaCancelada:
+		return "CANCELADA"
+	default:
+		return "DESCONOCIDO"
+	}
+}
+
+func (e EstadoReventa) IsValid() bool {
+	return e >= ReventaPublicada && e <= ReventaCancelada
+}
+
+/* ---- Integración con database/sql (columna SMALLINT) ---- */
+
+func (e EstadoReventa) Value() (driver.Value, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("estado de reventa inválido: %d", e)
+	}
+	return int64(e), nil
+}
+
+func (e *EstadoReventa) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*e = EstadoReventa(v)
+	case int32:
+		*e = EstadoReventa(v)
+	case int16:
+		*e = EstadoReventa(v)
+	case []byte:
+		var n int16
+		if _, err := fmt.Sscanf(string(v), "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoReventa: %w", err)
+		}
+		*e = EstadoReventa(n)
+	case string:
+		var n int16
+		if _, err := fmt.Sscanf(v, "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoReventa: %w", err)
+		}
+		*e = EstadoReventa(n)
+	default:
+		return fmt.Errorf("tipo no soportado para EstadoReventa: %T", src)
+	}
+	if !e.IsValid() {
+		return fmt.Errorf("estado de reventa inválido: %d", *e)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// EstadoTransferencia modela una oferta de transferencia de ticket (columna: estado)
+// 0=PENDIENTE, 1=ACEPTADA, 2=CANCELADA, 3=EXPIRADA
+type EstadoTransferencia int16
+
+const (
+	TransferenciaPendiente EstadoTransferencia = iota // 0
+	TransferenciaAceptada                             // 1
+	TransferenciaCancelada                            // 2
+	TransferenciaExpirada                             // 3
+)
+
+func (e EstadoTransferencia) Codigo() int16 { return int16(e) }
+
+func (e EstadoTransferencia) String() string {
+	switch e {
+	case TransferenciaPendiente:
+		return "PENDIENTE"
+	case TransferenciaAceptada:
+		return "ACEPTADA"
+	case TransferenciaCancelada:
+		return "CANCELADA"
+	case TransferenciaExpirada:
+		return "EXPIRADA"
+	default:
+		return "DESCONOCIDO"
+	}
+}
+
+func (e EstadoTransferencia) IsValid() bool {
+	return e >= TransferenciaPendiente && e <= TransferenciaExpirada
+}
+
+/* ---- Integración con database/sql (columna SMALLINT) ---- */
+
+func (e EstadoTransferencia) Value() (driver.Value, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("estado de transferencia inválido: %d", e)
+	}
+	return int64(e), nil
+}
+
+func (e *EstadoTransferencia) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*e = EstadoTransferencia(v)
+	case int32:
+		*e = EstadoTransferencia(v)
+	case int16:
+		*e = EstadoTransferencia(v)
+	case []byte:
+		var n int16
+		if _, err := fmt.Sscanf(string(v), "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoTransferencia: %w", err)
+		}
+		*e = EstadoTransferencia(n)
+	case string:
+		var n int16
+		if _, err := fmt.Sscanf(v, "%d", &n); err != nil {
+			return fmt.Errorf("scan EstadoTransferencia: %w", err)
+		}
+		*e = EstadoTransferencia(n)
+	default:
+		return fmt.Errorf("tipo no soportado para EstadoTransferencia: %T", src)
+	}
+	if !e.IsValid() {
+		return fmt.Errorf("estado de transferencia inválido: %d", *e)
+	}
+	return nil
+}
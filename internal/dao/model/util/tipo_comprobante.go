@@ -8,8 +8,9 @@ import (
 type TipoComprobante int16
 
 const (
-	ComprobanteBoleta  TipoComprobante = iota // 0
-	ComprobanteFactura                        // 1
+	ComprobanteBoleta      TipoComprobante = iota // 0
+	ComprobanteFactura                            // 1
+	ComprobanteNotaCredito                        // 2: anula total o parcialmente otro comprobante
 )
 
 func (t TipoComprobante) Codigo() int16 { return int16(t) }
@@ -20,6 +21,8 @@ func ValueOfTipoComprobanteCodigo(c int16) (TipoComprobante, error) {
 		return ComprobanteBoleta, nil
 	case 1:
 		return ComprobanteFactura, nil
+	case 2:
+		return ComprobanteNotaCredito, nil
 	default:
 		return 0, fmt.Errorf("código de tipo de comprobante inválido: %d", c)
 	}
@@ -31,13 +34,15 @@ func (t TipoComprobante) String() string {
 		return "BOLETA"
 	case ComprobanteFactura:
 		return "FACTURA"
+	case ComprobanteNotaCredito:
+		return "NOTA_CREDITO"
 	default:
 		return "DESCONOCIDO"
 	}
 }
 
 func (t TipoComprobante) IsValid() bool {
-	return t == ComprobanteBoleta || t == ComprobanteFactura
+	return t >= ComprobanteBoleta && t <= ComprobanteNotaCredito
 }
 
 // ---- Integración con database/sql ----
@@ -0,0 +1,333 @@
+package repository
+
+import (
+	"time"
+
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"gorm.io/gorm"
+)
+
+// Claves de las facetas de fecha (por la próxima fecha del evento) y de precio (por su precio
+// más bajo a la venta)
+const (
+	RangoFechaHoy      = "HOY"
+	RangoFechaSemana   = "PROXIMOS_7_DIAS"
+	RangoFechaMes      = "PROXIMOS_30_DIAS"
+	RangoFechaDespues  = "MAS_ADELANTE"
+	RangoPrecioHasta50 = "HASTA_50"
+	RangoPrecio50a100  = "DE_50_A_100"
+	RangoPrecio100a200 = "DE_100_A_200"
+	RangoPrecioMas200  = "MAS_DE_200"
+)
+
+// RangosFechaBusqueda y RangosPrecioBusqueda son las claves de cada faceta, en el orden en que
+// se muestran.
+var (
+	RangosFechaBusqueda  = []string{RangoFechaHoy, RangoFechaSemana, RangoFechaMes, RangoFechaDespues}
+	RangosPrecioBusqueda = []string{RangoPrecioHasta50, RangoPrecio50a100, RangoPrecio100a200, RangoPrecioMas200}
+)
+
+// Marcas que rodean las coincidencias en los textos resaltados; no aparecen en textos normales,
+// así que se pueden cambiar por etiquetas después de escapar el texto.
+const (
+	MarcaInicioResaltado = "⟦"
+	MarcaFinResaltado    = "⟧"
+)
+
+// sentenciasBusquedaEventos preparan la búsqueda de texto de los eventos, igual que
+// migrations/001_create_tables.sql. Se pueden ejecutar más de una vez.
+var sentenciasBusquedaEventos = []string{
+	`CREATE EXTENSION IF NOT EXISTS unaccent SCHEMA public`,
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public`,
+	`CREATE OR REPLACE FUNCTION f_unaccent(text) RETURNS text
+		LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+		AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, $1) $$`,
+	`DO $$ BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM pg_ts_config
+			WHERE cfgname = 'es_unaccent'
+			AND cfgnamespace = (SELECT oid FROM pg_namespace WHERE nspname = current_schema())
+		) THEN
+			CREATE TEXT SEARCH CONFIGURATION es_unaccent (COPY = pg_catalog.spanish);
+			ALTER TEXT SEARCH CONFIGURATION es_unaccent
+				ALTER MAPPING FOR hword, hword_part, word WITH public.unaccent, spanish_stem;
+		END IF;
+	END $$`,
+	`ALTER TABLE evento ADD COLUMN IF NOT EXISTS busqueda tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('es_unaccent', coalesce(titulo, '')), 'A')
+		|| setweight(to_tsvector('es_unaccent', coalesce(lugar, '')), 'B')
+		|| setweight(to_tsvector('es_unaccent', coalesce(descripcion, '')), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_evento_busqueda ON evento USING GIN (busqueda)`,
+	`CREATE INDEX IF NOT EXISTS idx_evento_titulo_trgm ON evento USING GIN (f_unaccent(lower(titulo)) gin_trgm_ops)`,
+}
+
+// PrepararBusquedaEventos crea la columna busqueda de evento, que Postgres recalcula al crear o
+// editar cada evento, y sus índices. La usa crearTablas después de migrar evento.
+func PrepararBusquedaEventos(db *gorm.DB) error {
+	for _, sentencia := range sentenciasBusquedaEventos {
+		if err := db.Exec(sentencia).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Expresiones de la búsqueda; cada ? es el texto buscado. Un evento coincide por sus palabras
+// (con raíces en español y sin tildes) o, para tolerar errores de tipeo, por trigramas del título.
+const (
+	coincideBusqueda = `(evento.busqueda @@ websearch_to_tsquery('es_unaccent', ?)
+		OR f_unaccent(lower(?)) <% f_unaccent(lower(evento.titulo)))`
+	relevanciaBusqueda = `(ts_rank_cd(evento.busqueda, websearch_to_tsquery('es_unaccent', ?))
+		+ word_similarity(f_unaccent(lower(?)), f_unaccent(lower(evento.titulo))))::float8`
+)
+
+// rangoFechaBusqueda y rangoPrecioBusqueda dan la clave de faceta de cada evento buscado (b).
+// Los tres ? de rangoFechaBusqueda son el día de hoy.
+const (
+	rangoFechaBusqueda = `(CASE WHEN b.proxima_fecha <= ?::date THEN 'HOY'
+		WHEN b.proxima_fecha <= ?::date + 7 THEN 'PROXIMOS_7_DIAS'
+		WHEN b.proxima_fecha <= ?::date + 30 THEN 'PROXIMOS_30_DIAS'
+		ELSE 'MAS_ADELANTE' END)`
+	rangoPrecioBusqueda = `(CASE WHEN b.precio_desde IS NULL THEN NULL
+		WHEN b.precio_desde <= 50 THEN 'HASTA_50'
+		WHEN b.precio_desde <= 100 THEN 'DE_50_A_100'
+		WHEN b.precio_desde <= 200 THEN 'DE_100_A_200'
+		ELSE 'MAS_DE_200' END)`
+)
+
+// ordenesBusqueda da, por criterio, la columna de los eventos buscados por la que se ordena. En
+// la búsqueda, relevancia es la del texto.
+var ordenesBusqueda = map[string]struct {
+	columna     string
+	descendente bool
+}{
+	OrdenEventosRelevancia:  {columna: "b.relevancia", descendente: true},
+	OrdenEventosFecha:       {columna: "b.inicio"},
+	OrdenEventosPopularidad: {columna: "b.popularidad", descendente: true},
+	OrdenEventosPrecio:      {columna: "COALESCE(b.precio_desde, 'Infinity')"},
+}
+
+// FiltrosBusquedaEventos acota una búsqueda a una categoría y a un rango de cada faceta; los
+// vacíos no filtran.
+type FiltrosBusquedaEventos struct {
+	CategoriaID *int64
+	RangoFecha  string // uno de RangosFechaBusqueda
+	RangoPrecio string // uno de RangosPrecioBusqueda
+}
+
+// ResaltadoEvento es el título y un fragmento de la descripción de un evento con las
+// coincidencias entre MarcaInicioResaltado y MarcaFinResaltado.
+type ResaltadoEvento struct {
+	Titulo    string
+	Fragmento string
+}
+
+// FacetaCategoria cuenta los eventos encontrados de una categoría.
+type FacetaCategoria struct {
+	CategoriaID int64
+	Nombre      string
+	Cantidad    int64
+}
+
+// BusquedaEventos es una página de resultados de una búsqueda, con la relevancia y el texto
+// resaltado de cada evento y las facetas. Cada faceta cuenta con los demás filtros aplicados,
+// pero no el suyo, para poder cambiar de opción.
+type BusquedaEventos struct {
+	ListadoEventos
+	Relevancias  map[int64]float64
+	Resaltados   map[int64]ResaltadoEvento
+	Categorias   []FacetaCategoria
+	RangosFecha  map[string]int64
+	RangosPrecio map[string]int64
+}
+
+// eventosBuscados devuelve, por cada evento publicado con alguna fecha activa desde hoy que
+// coincide con consulta, su categoría, relevancia, próxima fecha, popularidad y precio desde.
+func (e *Evento) eventosBuscados(consulta string, hoy string) *gorm.DB {
+	return e.PostgresqlDB.
+		Table("evento").
+		Select(`evento.evento_id, evento.categoria_id,
+			`+relevanciaBusqueda+` AS relevancia,
+			MIN(f.fecha_evento) AS proxima_fecha,
+			EXTRACT(EPOCH FROM MIN(f.fecha_evento + ef.hora_inicio))::float8 AS inicio,
+			(evento.cant_vendido_total + evento.cant_me_gusta)::float8 AS popularidad,
+			`+precioDesdeEvento+`::float8 AS precio_desde`,
+			consulta, consulta).
+		Joins("JOIN evento_fecha ef ON ef.evento_id = evento.evento_id AND ef.estado = ?", util.Activo.Codigo()).
+		Joins("JOIN fecha f ON f.fecha_id = ef.fecha_id").
+		Where("evento.evento_estado = ? AND evento.estado = ?", util.EventoPublicado.Codigo(), util.Activo.Codigo()).
+		Where("f.fecha_evento >= ?::date", hoy).
+		Where(coincideBusqueda, consulta, consulta).
+		Group("evento.evento_id")
+}
+
+// filtrarBusqueda aplica los filtros sobre los eventos buscados (b), menos el de la faceta omitir
+// ("categoria", "fecha" o "precio").
+func filtrarBusqueda(query *gorm.DB, filtros FiltrosBusquedaEventos, hoy string, omitir string) *gorm.DB {
+	if filtros.CategoriaID != nil && omitir != "categoria" {
+		query = query.Where("b.categoria_id = ?", *filtros.CategoriaID)
+	}
+	if filtros.RangoFecha != "" && omitir != "fecha" {
+		query = query.Where(rangoFechaBusqueda+" = ?", hoy, hoy, hoy, filtros.RangoFecha)
+	}
+	if filtros.RangoPrecio != "" && omitir != "precio" {
+		query = query.Where(rangoPrecioBusqueda+" = ?", filtros.RangoPrecio)
+	}
+	return query
+}
+
+// BuscarEventos busca consulta en el título, el lugar y la descripción de los eventos a la venta
+// (hoy es el día de hoy en la zona de los eventos) y devuelve una página de resultados en el
+// orden pedido, por keyset como los listados.
+func (e *Evento) BuscarEventos(
+	consulta string,
+	filtros FiltrosBusquedaEventos,
+	pagina PaginaEventos,
+	hoy time.Time,
+) (*BusquedaEventos, error) {
+	orden, ok := ordenesBusqueda[pagina.Orden]
+	if !ok {
+		return nil, ErrOrdenEventosInvalido
+	}
+	dia := hoy.Format("2006-01-02")
+	buscados := func(omitir string) *gorm.DB {
+		return filtrarBusqueda(e.PostgresqlDB.Table("(?) AS b", e.eventosBuscados(consulta, dia)), filtros, dia, omitir)
+	}
+
+	busqueda := &BusquedaEventos{
+		ListadoEventos: ListadoEventos{PreciosDesde: map[int64]float64{}},
+		Relevancias:    map[int64]float64{},
+		Resaltados:     map[int64]ResaltadoEvento{},
+		RangosFecha:    map[string]int64{},
+		RangosPrecio:   map[string]int64{},
+	}
+	if err := e.buscarPagina(busqueda, buscados, orden.columna, orden.descendente, pagina); err != nil {
+		e.logger.Errorf("Evento.BuscarEventos(%q): %v", consulta, err)
+		return nil, err
+	}
+	if err := e.contarFacetas(busqueda, buscados, dia); err != nil {
+		e.logger.Errorf("Evento.BuscarEventos(%q, facetas): %v", consulta, err)
+		return nil, err
+	}
+	if len(busqueda.Eventos) == 0 {
+		return busqueda, nil
+	}
+
+	ids := make([]int64, len(busqueda.Eventos))
+	for i, ev := range busqueda.Eventos {
+		ids[i] = ev.ID
+	}
+	var resaltados []struct {
+		EventoID  int64
+		Titulo    string
+		Fragmento string
+	}
+	if err := e.PostgresqlDB.
+		Table("evento").
+		Select(`evento_id,
+			ts_headline('es_unaccent', titulo, websearch_to_tsquery('es_unaccent', ?), ?) AS titulo,
+			ts_headline('es_unaccent', descripcion, websearch_to_tsquery('es_unaccent', ?), ?) AS fragmento`,
+			consulta, `StartSel="`+MarcaInicioResaltado+`", StopSel="`+MarcaFinResaltado+`", HighlightAll=true`,
+			consulta, `StartSel="`+MarcaInicioResaltado+`", StopSel="`+MarcaFinResaltado+`", MaxFragments=2, MinWords=12, MaxWords=30, FragmentDelimiter=" … "`).
+		Where("evento_id IN ?", ids).
+		Scan(&resaltados).Error; err != nil {
+		e.logger.Errorf("Evento.BuscarEventos(%q, resaltado): %v", consulta, err)
+		return nil, err
+	}
+	for _, r := range resaltados {
+		busqueda.Resaltados[r.EventoID] = ResaltadoEvento{Titulo: r.Titulo, Fragmento: r.Fragmento}
+	}
+	return busqueda, nil
+}
+
+// buscarPagina completa busqueda con el total y la página pedida de los eventos buscados, en el
+// orden de columna (el evento_id desempata).
+func (e *Evento) buscarPagina(
+	busqueda *BusquedaEventos,
+	buscados func(omitir string) *gorm.DB,
+	columna string,
+	descendente bool,
+	pagina PaginaEventos,
+) error {
+	if err := buscados("").Count(&busqueda.Total).Error; err != nil {
+		return err
+	}
+
+	comparacion, sentido := ">", "ASC"
+	if descendente {
+		comparacion, sentido = "<", "DESC"
+	}
+	query := buscados("").Select("b.evento_id, b.relevancia, " + columna + " AS valor_orden")
+	if pagina.Despues != nil {
+		query = query.Where("("+columna+", b.evento_id) "+comparacion+" (?, ?)", pagina.Despues.Valor, pagina.Despues.ID)
+	}
+	var filas []struct {
+		EventoID   int64
+		Relevancia float64
+		ValorOrden float64
+	}
+	if err := query.
+		Order(columna + " " + sentido + ", b.evento_id " + sentido).
+		Limit(pagina.Limite + 1).
+		Scan(&filas).Error; err != nil {
+		return err
+	}
+	if len(filas) > pagina.Limite {
+		filas = filas[:pagina.Limite]
+		ultima := filas[len(filas)-1]
+		busqueda.Siguiente = &CursorEventos{Valor: ultima.ValorOrden, ID: ultima.EventoID}
+	}
+	if len(filas) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(filas))
+	for i, fila := range filas {
+		ids[i] = fila.EventoID
+		busqueda.Relevancias[fila.EventoID] = fila.Relevancia
+	}
+	return e.cargarEventosListado(&busqueda.ListadoEventos, ids)
+}
+
+// contarFacetas cuenta los eventos buscados por categoría, rango de fecha y rango de precio.
+func (e *Evento) contarFacetas(busqueda *BusquedaEventos, buscados func(omitir string) *gorm.DB, hoy string) error {
+	if err := buscados("categoria").
+		Select("b.categoria_id, c.nombre, COUNT(*) AS cantidad").
+		Joins("JOIN categoria c ON c.id_categoria = b.categoria_id").
+		Group("b.categoria_id, c.nombre").
+		Order("cantidad DESC, c.nombre").
+		Scan(&busqueda.Categorias).Error; err != nil {
+		return err
+	}
+
+	var rangos []struct {
+		Clave    *string
+		Cantidad int64
+	}
+	if err := buscados("fecha").
+		Select(rangoFechaBusqueda+" AS clave, COUNT(*) AS cantidad", hoy, hoy, hoy).
+		Group("clave").
+		Scan(&rangos).Error; err != nil {
+		return err
+	}
+	for _, r := range rangos {
+		if r.Clave != nil {
+			busqueda.RangosFecha[*r.Clave] = r.Cantidad
+		}
+	}
+
+	rangos = nil
+	if err := buscados("precio").
+		Select(rangoPrecioBusqueda + " AS clave, COUNT(*) AS cantidad").
+		Group("clave").
+		Scan(&rangos).Error; err != nil {
+		return err
+	}
+	for _, r := range rangos {
+		if r.Clave != nil {
+			busqueda.RangosPrecio[*r.Clave] = r.Cantidad
+		}
+	}
+	return nil
+}
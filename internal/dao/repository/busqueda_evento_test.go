@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestBuscarEventosSinTildesConErroresYFacetas(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Categoria{}, &model.Fecha{}, &model.Tarifa{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	if err := PrepararBusquedaEventos(db); err != nil {
+		t.Fatalf("PrepararBusquedaEventos: %v", err)
+	}
+	eventos := NewEventoController(logging.NewLoggerMock(), db)
+	hoy := time.Now().UTC().Truncate(24 * time.Hour)
+
+	conciertos := &model.Categoria{Nombre: "Conciertos"}
+	teatro := &model.Categoria{Nombre: "Teatro"}
+	db.Create(conciertos)
+	db.Create(teatro)
+	crear := func(titulo, descripcion string, categoria *model.Categoria, dias int, precio float64) int64 {
+		t.Helper()
+		ev := &model.Evento{
+			OrganizadorID: 1, CategoriaID: categoria.ID, Titulo: titulo, Descripcion: descripcion, Lugar: "Lima",
+			EventoEstado: util.EventoPublicado.Codigo(),
+		}
+		if err := db.Create(ev).Error; err != nil {
+			t.Fatalf("crear evento: %v", err)
+		}
+		fecha := &model.Fecha{FechaEvento: hoy.AddDate(0, 0, dias)}
+		db.Create(fecha)
+		db.Create(&model.EventoFecha{EventoID: ev.ID, FechaID: fecha.ID, HoraInicio: hoy.Add(20 * time.Hour)})
+		sector := &model.Sector{EventoID: ev.ID, SectorTipo: "GENERAL", TotalEntradas: 10}
+		db.Create(sector)
+		db.Create(&model.Tarifa{SectorID: sector.ID, TipoDeTicketID: 1, Precio: precio})
+		return ev.ID
+	}
+	metallica := crear("Metallica en Lima", "Gira mundial de la banda de metal", conciertos, 3, 250)
+	musica := crear("Noche de música criolla", "Valses y marineras con músicos invitados", conciertos, 20, 40)
+	obra := crear("La obra de la música", "Teatro musical para toda la familia", teatro, 60, 80)
+	crear("Feria del libro", "Presentaciones y firmas", teatro, 5, 10)
+
+	pagina := PaginaEventos{Orden: OrdenEventosRelevancia, Limite: 10}
+
+	// Sin tilde encuentra la palabra con tilde y el título pesa más que la descripción
+	busqueda, err := eventos.BuscarEventos("musica", FiltrosBusquedaEventos{}, pagina, hoy)
+	if err != nil {
+		t.Fatalf("BuscarEventos: %v", err)
+	}
+	if busqueda.Total != 2 || len(busqueda.Eventos) != 2 {
+		t.Fatalf("se esperaban los dos eventos de música, se obtuvo total=%d", busqueda.Total)
+	}
+	if busqueda.Eventos[0].ID != musica && busqueda.Eventos[0].ID != obra {
+		t.Fatalf("resultado inesperado: %d", busqueda.Eventos[0].ID)
+	}
+	if r := busqueda.Resaltados[musica]; !strings.Contains(r.Titulo, MarcaInicioResaltado+"música"+MarcaFinResaltado) {
+		t.Fatalf("el título debió resaltar la coincidencia: %q", r.Titulo)
+	}
+	if busqueda.RangosFecha[RangoFechaMes] != 1 || busqueda.RangosFecha[RangoFechaDespues] != 1 ||
+		busqueda.RangosPrecio[RangoPrecioHasta50] != 1 || busqueda.RangosPrecio[RangoPrecio50a100] != 1 {
+		t.Fatalf("facetas de fecha y precio: %v %v", busqueda.RangosFecha, busqueda.RangosPrecio)
+	}
+
+	// Una faceta elegida filtra los resultados, pero no se cuenta a sí misma
+	busqueda, err = eventos.BuscarEventos("musica", FiltrosBusquedaEventos{CategoriaID: &teatro.ID}, pagina, hoy)
+	if err != nil {
+		t.Fatalf("BuscarEventos con categoría: %v", err)
+	}
+	if busqueda.Total != 1 || busqueda.Eventos[0].ID != obra || len(busqueda.Categorias) != 2 {
+		t.Fatalf("filtro por categoría: total=%d categorías=%+v", busqueda.Total, busqueda.Categorias)
+	}
+
+	// Un error de tipeo en el título también encuentra el evento
+	busqueda, err = eventos.BuscarEventos("metalica", FiltrosBusquedaEventos{}, pagina, hoy)
+	if err != nil {
+		t.Fatalf("BuscarEventos con error de tipeo: %v", err)
+	}
+	if busqueda.Total < 1 || busqueda.Eventos[0].ID != metallica {
+		t.Fatalf("se esperaba encontrar Metallica, se obtuvo total=%d", busqueda.Total)
+	}
+}
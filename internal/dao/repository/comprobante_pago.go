@@ -1,12 +1,52 @@
 package repository
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
 	"github.com/Nexivent/nexivent-backend/logging"
 	"gorm.io/gorm"
-	// "github.com/Loui27/nexivent-backend/internal/dao/model"
 )
 
 type ComprobanteDePago struct {
 	logger       logging.Logger
 	PostgresqlDB *gorm.DB
 }
+
+func NewComprobanteDePagoController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *ComprobanteDePago {
+	return &ComprobanteDePago{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+// crearComprobanteDeOrden emite, dentro de tx, la boleta de una orden recién pagada. El número
+// de serie B001 sigue el id de la orden, así cada orden tiene a lo sumo un comprobante.
+func crearComprobanteDeOrden(tx *gorm.DB, orderID int64, ahora time.Time) error {
+	return tx.Create(&model.ComprobanteDePago{
+		OrdenDeCompraID:   orderID,
+		TipoDeComprobante: util.ComprobanteBoleta.Codigo(),
+		Numero:            fmt.Sprintf("B001-%08d", orderID),
+		FechaEmision:      ahora,
+	}).Error
+}
+
+// ObtenerPorOrden devuelve el comprobante emitido para la orden.
+func (r *ComprobanteDePago) ObtenerPorOrden(orderID int64) (*model.ComprobanteDePago, error) {
+	var comprobante model.ComprobanteDePago
+	if err := r.PostgresqlDB.
+		Where("orden_de_compra_id = ? AND tipo_de_comprobante <> ?", orderID, util.ComprobanteNotaCredito.Codigo()).
+		Order("comprobante_de_pago_id").
+		First(&comprobante).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			r.logger.Errorf("ComprobanteDePago.ObtenerPorOrden(%d): %v", orderID, err)
+		}
+		return nil, err
+	}
+	return &comprobante, nil
+}
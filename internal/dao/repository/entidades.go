@@ -21,14 +21,28 @@ type NexiventPsqlEntidades struct {
 	RolesUsuario    *RolUsuarioRepo
 	Interaccion     *Interaccion
 	OrdenDeCompra   *OrdenDeCompra
+	OrdenDetalle    *OrdenDeCompraDetalle
+	MetodoDePago    *MetodoDePago
+	Pago            *Pago
+	Comprobante     *ComprobanteDePago
+	Reembolso       *Reembolso
+	Reprogramacion  *Reprogramacion
+	Notificacion    *Notificacion
+	EventoDominio   *EventoDominio
+	Preferencias    *PreferenciaNotificacion
 	PerfilDePersona *PerfilDePersona
 	Sector          *Sector
 	TipoDeTicket    *TipoDeTicket
 	Tarifa          *Tarifa
 	Ticket          *Ticket
+	RegistroIngreso *RegistroIngreso
+	Transferencia   *TransferenciaTicket
+	Reventa         *Reventa
 	Token           *Token
+	Idempotencia    *Idempotencia
 	UsuarioCupon    *UsuarioCupon
 	EventoFecha     *EventoFecha
+	Recinto         *Recinto
 }
 
 // Clase que crea colección de entidades para Nexivent Postgresql
@@ -64,7 +78,11 @@ func NewNexiventPsqlEntidades(
 		TipoDeTicket:    NewTipoDeTicketController(logger, postgresqlDB),
 		Tarifa:          NewTarifaController(logger, postgresqlDB),
 		Ticket:          NewTicketController(logger, postgresqlDB),
+		RegistroIngreso: NewRegistroIngresoController(logger, postgresqlDB),
+		Transferencia:   NewTransferenciaTicketController(logger, postgresqlDB),
+		Reventa:         NewReventaController(logger, postgresqlDB),
 		EventoFecha:     NewEventoFechaController(logger, postgresqlDB),
+		Recinto:         NewRecintoController(logger, postgresqlDB),
 		Usuario: &Usuario{
 			logger:       logger,
 			PostgresqlDB: postgresqlDB,
@@ -83,10 +101,20 @@ func NewNexiventPsqlEntidades(
 			logger:       logger,
 			PostgresqlDB: postgresqlDB,
 		},
+		OrdenDetalle:   NewOrdenDeCompraDetalleController(logger, postgresqlDB),
+		MetodoDePago:   NewMetodoDePagoController(logger, postgresqlDB),
+		Pago:           NewPagoController(logger, postgresqlDB),
+		Comprobante:    NewComprobanteDePagoController(logger, postgresqlDB),
+		Reembolso:      NewReembolsoController(logger, postgresqlDB),
+		Reprogramacion: NewReprogramacionController(logger, postgresqlDB),
+		Notificacion:   NewNotificacionController(logger, postgresqlDB),
+		EventoDominio:  NewEventoDominioController(logger, postgresqlDB),
+		Preferencias:   NewPreferenciaNotificacionController(logger, postgresqlDB),
 		Token: &Token{
 			logger: logger,
 			DB:     postgresqlDB,
 		},
+		Idempotencia: NewIdempotenciaController(logger, postgresqlDB),
 		UsuarioCupon: NewUsuarioCuponController(logger, postgresqlDB),
 	}, postgresqlDB
 }
@@ -119,6 +147,14 @@ func crearTablas(astroCatPsqlDB *gorm.DB) {
 	}
 	fmt.Println("Tabla Categoria creada exitosamente.")
 
+	// Crear tabla Recinto
+	fmt.Println("Creando tabla Recinto...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.Recinto{}); err != nil {
+		fmt.Printf("Error creando tabla Recinto: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla Recinto creada exitosamente.")
+
 	// Crear tabla Evento
 	fmt.Println("Creando tabla Evento...")
 	if err := astroCatPsqlDB.AutoMigrate(&model.Evento{}); err != nil {
@@ -126,6 +162,10 @@ func crearTablas(astroCatPsqlDB *gorm.DB) {
 		panic(err)
 	}
 	fmt.Println("Tabla Evento creada exitosamente.")
+	if err := PrepararBusquedaEventos(astroCatPsqlDB); err != nil {
+		fmt.Printf("Error preparando la búsqueda de eventos: %v\n", err)
+		panic(err)
+	}
 
 	// Crear tabla Interaccion
 	fmt.Println("Creando tabla Interaccion...")
@@ -207,6 +247,62 @@ func crearTablas(astroCatPsqlDB *gorm.DB) {
 	}
 	fmt.Println("Tabla Ticket creada exitosamente.")
 
+	// Crear tabla RegistroIngreso
+	fmt.Println("Creando tabla RegistroIngreso...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.RegistroIngreso{}); err != nil {
+		fmt.Printf("Error creando tabla RegistroIngreso: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla RegistroIngreso creada exitosamente.")
+
+	// Crear tabla TransferenciaTicket
+	fmt.Println("Creando tabla TransferenciaTicket...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.TransferenciaTicket{}); err != nil {
+		fmt.Printf("Error creando tabla TransferenciaTicket: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla TransferenciaTicket creada exitosamente.")
+
+	// Crear tabla HistorialTitular
+	fmt.Println("Creando tabla HistorialTitular...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.HistorialTitular{}); err != nil {
+		fmt.Printf("Error creando tabla HistorialTitular: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla HistorialTitular creada exitosamente.")
+
+	// Crear tabla PoliticaReventa
+	fmt.Println("Creando tabla PoliticaReventa...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.PoliticaReventa{}); err != nil {
+		fmt.Printf("Error creando tabla PoliticaReventa: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla PoliticaReventa creada exitosamente.")
+
+	// Crear tabla PublicacionReventa
+	fmt.Println("Creando tabla PublicacionReventa...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.PublicacionReventa{}); err != nil {
+		fmt.Printf("Error creando tabla PublicacionReventa: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla PublicacionReventa creada exitosamente.")
+
+	// Crear tabla OrdenDeCompraDetalle
+	fmt.Println("Creando tabla OrdenDeCompraDetalle...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.OrdenDeCompraDetalle{}); err != nil {
+		fmt.Printf("Error creando tabla OrdenDeCompraDetalle: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla OrdenDeCompraDetalle creada exitosamente.")
+
+	// Crear tabla Pago
+	fmt.Println("Creando tabla Pago...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.Pago{}); err != nil {
+		fmt.Printf("Error creando tabla Pago: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla Pago creada exitosamente.")
+
 	// Crear tabla Cupon (otra vez por si la necesitas en otro contexto)
 	fmt.Println("Creando tabla Cupon...")
 	if err := astroCatPsqlDB.AutoMigrate(&model.Cupon{}); err != nil {
@@ -233,6 +329,22 @@ func crearTablas(astroCatPsqlDB *gorm.DB) {
 	}
 	fmt.Println("Tabla ComprobanteDePago creada exitosamente.")
 
+	// Crear tablas Reembolso y ReembolsoTicket
+	fmt.Println("Creando tablas Reembolso y ReembolsoTicket...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.Reembolso{}, &model.ReembolsoTicket{}); err != nil {
+		fmt.Printf("Error creando tablas Reembolso y ReembolsoTicket: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tablas Reembolso y ReembolsoTicket creadas exitosamente.")
+
+	// Crear tablas ReprogramacionFecha y ReprogramacionTicket
+	fmt.Println("Creando tablas ReprogramacionFecha y ReprogramacionTicket...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.ReprogramacionFecha{}, &model.ReprogramacionTicket{}); err != nil {
+		fmt.Printf("Error creando tablas ReprogramacionFecha y ReprogramacionTicket: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tablas ReprogramacionFecha y ReprogramacionTicket creadas exitosamente.")
+
 	// Crear tabla Rol
 	fmt.Println("Creando tabla Rol...")
 	if err := astroCatPsqlDB.AutoMigrate(&model.Rol{}); err != nil {
@@ -249,6 +361,14 @@ func crearTablas(astroCatPsqlDB *gorm.DB) {
 	}
 	fmt.Println("Tabla RolUsuario creada exitosamente.")
 
+	// Crear tabla EventoDominio
+	fmt.Println("Creando tabla EventoDominio...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.EventoDominio{}); err != nil {
+		fmt.Printf("Error creando tabla EventoDominio: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla EventoDominio creada exitosamente.")
+
 	// Crear tabla Notificacion
 	fmt.Println("Creando tabla Notificacion...")
 	if err := astroCatPsqlDB.AutoMigrate(&model.Notificacion{}); err != nil {
@@ -257,6 +377,30 @@ func crearTablas(astroCatPsqlDB *gorm.DB) {
 	}
 	fmt.Println("Tabla Notificacion creada exitosamente.")
 
+	// Crear tabla PreferenciaNotificacion
+	fmt.Println("Creando tabla PreferenciaNotificacion...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.PreferenciaNotificacion{}); err != nil {
+		fmt.Printf("Error creando tabla PreferenciaNotificacion: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla PreferenciaNotificacion creada exitosamente.")
+
+	// Crear tabla Token
+	fmt.Println("Creando tabla Token...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.Token{}); err != nil {
+		fmt.Printf("Error creando tabla Token: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla Token creada exitosamente.")
+
+	// Crear tabla ClaveIdempotencia
+	fmt.Println("Creando tabla ClaveIdempotencia...")
+	if err := astroCatPsqlDB.AutoMigrate(&model.ClaveIdempotencia{}); err != nil {
+		fmt.Printf("Error creando tabla ClaveIdempotencia: %v\n", err)
+		panic(err)
+	}
+	fmt.Println("Tabla ClaveIdempotencia creada exitosamente.")
+
 	fmt.Println("Todas las tablas fiueron creadas exitosamente.")
 }
 
@@ -267,11 +411,27 @@ func BorrarTodasLasTablas(nexiventPsqlDB *gorm.DB) {
 
 	// Drop all tables in reverse order of dependencies
 	tablesToDrop := []string{
+		"clave_idempotencia",
+		"token",
 		"rol_usuario",
 		"usuario_cupon",
 		"evento_cupon",
+		"publicacion_reventa",
+		"politica_reventa",
+		"historial_titular",
+		"transferencia_ticket",
+		"registro_ingreso",
+		"reprogramacion_ticket",
+		"reprogramacion_fecha",
+		"reembolso_ticket",
+		"reembolso",
 		"ticket",
+		"orden_de_compra_detalle",
+		"pago",
 		"comprobante_de_pago",
+		"preferencia_notificacion",
+		"notificacion",
+		"evento_dominio",
 		"evento_fecha",
 		"fecha",
 		"tarifa",
@@ -282,9 +442,9 @@ func BorrarTodasLasTablas(nexiventPsqlDB *gorm.DB) {
 		"orden_de_compra",
 		"metodo_de_pago",
 		"evento",
+		"recinto",
 		"cupon",
 		"rol",
-		"notificacion",
 		"categoria",
 		"usuario",
 	}
@@ -1,10 +1,12 @@
 package repository
 
 import (
+	"errors"
 	"strings"
 	"time"
 
 	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
 	"github.com/Nexivent/nexivent-backend/internal/schemas"
 	"github.com/Nexivent/nexivent-backend/logging"
 	"gorm.io/gorm"
@@ -16,6 +18,15 @@ type Evento struct {
 	PostgresqlDB *gorm.DB
 }
 
+// ErrTransicionEventoInvalida se devuelve al pedir un cambio de estado que el ciclo de vida del
+// evento no permite (ver util.EstadoEvento.PuedePasarA).
+var ErrTransicionEventoInvalida = errors.New("el evento no puede pasar a ese estado")
+
+// ErrFechaConTicketsVendidos se devuelve al mover en silencio una fecha que ya tiene entradas
+// vendidas: esa fecha se cambia con Reprogramacion.Reprogramar, que pide conformidad a los
+// titulares.
+var ErrFechaConTicketsVendidos = errors.New("la fecha tiene entradas vendidas")
+
 func NewEventoController(
 	logger logging.Logger,
 	postgresqlDB *gorm.DB,
@@ -34,7 +45,197 @@ func (e *Evento) CrearEvento(Evento *model.Evento) error {
 	return nil
 }
 
-func (e *Evento) ObtenerEventosDisponiblesSinFiltros() ([]*model.Evento, error) {
+// Criterios de orden de los listados de eventos
+const (
+	OrdenEventosFecha       = "fecha"       // la fecha más próxima primero
+	OrdenEventosPopularidad = "popularidad" // más entradas vendidas y me gusta primero
+	OrdenEventosPrecio      = "precio"      // el precio más bajo primero; sin tarifas al final
+	OrdenEventosRelevancia  = "relevancia"  // puntaje del feed: opiniones sobre días que faltan
+	OrdenEventosDistancia   = "distancia"   // el recinto más cercano al origen primero; sin recinto al final
+)
+
+// ErrOrdenEventosInvalido se devuelve al pedir un listado con un criterio de orden desconocido.
+var ErrOrdenEventosInvalido = errors.New("criterio de orden de eventos desconocido")
+
+// precioDesdeEvento es el precio más bajo a la venta del evento, NULL si no tiene tarifas.
+const precioDesdeEvento = `(SELECT MIN(t.precio) FROM tarifa t
+	JOIN sector s ON s.sector_id = t.sector_id
+	WHERE s.evento_id = evento.evento_id AND s.estado = 1 AND t.estado = 1)`
+
+// ordenesEventos da, por criterio, el valor por el que se ordena cada evento (sobre las filas del
+// evento con sus fechas, agrupadas por evento) y si va de mayor a menor. El evento_id desempata.
+var ordenesEventos = map[string]struct {
+	valor       string
+	descendente bool
+}{
+	OrdenEventosFecha: {
+		valor: "EXTRACT(EPOCH FROM MIN(f.fecha_evento + ef.hora_inicio))::float8",
+	},
+	OrdenEventosPopularidad: {
+		valor:       "(evento.cant_vendido_total + evento.cant_me_gusta)::float8",
+		descendente: true,
+	},
+	OrdenEventosPrecio: {
+		valor: "COALESCE(" + precioDesdeEvento + ", 'Infinity')::float8",
+	},
+	OrdenEventosRelevancia: {
+		valor:       "((2*evento.cant_me_gusta - evento.cant_no_interesa) / GREATEST(1, (MIN(f.fecha_evento)::date - CURRENT_DATE)))::float8",
+		descendente: true,
+	},
+	OrdenEventosDistancia: {}, // el valor depende de PaginaEventos.Origen (ver paginarEventos)
+}
+
+// CursorEventos es la posición de un evento en un listado: el valor por el que se ordenó y su ID.
+type CursorEventos struct {
+	Valor float64
+	ID    int64
+}
+
+// PaginaEventos pide una página de un listado: Limite eventos en el Orden indicado, a partir del
+// evento que sigue a Despues (nil para la primera). Con Origen, cada evento con recinto trae su
+// distancia a ese punto; el orden por distancia lo exige.
+type PaginaEventos struct {
+	Orden   string
+	Limite  int
+	Despues *CursorEventos
+	Origen  *PuntoGeografico
+}
+
+// ListadoEventos es una página de un listado de eventos, con solo las fechas activas de cada uno
+// (sin sectores ni tarifas).
+type ListadoEventos struct {
+	Eventos      []*model.Evento   // en el orden del listado
+	PreciosDesde map[int64]float64 // precio más bajo a la venta de cada evento que tiene tarifas
+	Distancias   map[int64]float64 // km del origen al recinto de cada evento con recinto
+	Total        int64             // eventos del listado completo
+	Siguiente    *CursorEventos    // nil en la última página
+}
+
+// paginarEventos arma una página del listado de los eventos que deja pasar filtrar, que recibe
+// "evento" unido a sus fechas activas (ef), a su día (f) y a su recinto (r, si tiene). Pagina por
+// keyset sobre (valor de orden, evento_id), así que una página no depende de cuántas se leyeron
+// antes.
+func (e *Evento) paginarEventos(filtrar func(*gorm.DB) *gorm.DB, pagina PaginaEventos) (*ListadoEventos, error) {
+	orden, ok := ordenesEventos[pagina.Orden]
+	if !ok || (pagina.Orden == OrdenEventosDistancia && pagina.Origen == nil) {
+		return nil, ErrOrdenEventosInvalido
+	}
+	seleccion, args := "evento.evento_id, "+orden.valor+" AS valor_orden", []any(nil)
+	if pagina.Origen != nil {
+		distancia, argsDistancia := distanciaKm("r", *pagina.Origen)
+		if pagina.Orden == OrdenEventosDistancia {
+			seleccion = "evento.evento_id, COALESCE(MIN(" + distancia + "), 'Infinity')::float8 AS valor_orden"
+			args = append(args, argsDistancia...)
+		}
+		seleccion += ", MIN(" + distancia + ") AS distancia_km"
+		args = append(args, argsDistancia...)
+	}
+	claves := func() *gorm.DB {
+		return filtrar(e.PostgresqlDB.
+			Table("evento").
+			Joins("JOIN evento_fecha ef ON ef.evento_id = evento.evento_id AND ef.estado = ?", util.Activo.Codigo()).
+			Joins("JOIN fecha f ON f.fecha_id = ef.fecha_id").
+			Joins("LEFT JOIN recinto r ON r.recinto_id = evento.recinto_id")).
+			Select(seleccion, args...).
+			Group("evento.evento_id")
+	}
+
+	listado := &ListadoEventos{PreciosDesde: map[int64]float64{}, Distancias: map[int64]float64{}}
+	if err := e.PostgresqlDB.Table("(?) AS l", claves()).Count(&listado.Total).Error; err != nil {
+		return nil, err
+	}
+
+	columnas := "l.evento_id, l.valor_orden"
+	if pagina.Origen != nil {
+		columnas += ", l.distancia_km"
+	}
+	query := e.PostgresqlDB.Table("(?) AS l", claves()).Select(columnas)
+	comparacion, sentido := ">", "ASC"
+	if orden.descendente {
+		comparacion, sentido = "<", "DESC"
+	}
+	if pagina.Despues != nil {
+		query = query.Where("(l.valor_orden, l.evento_id) "+comparacion+" (?, ?)", pagina.Despues.Valor, pagina.Despues.ID)
+	}
+	var filas []struct {
+		EventoID    int64
+		ValorOrden  float64
+		DistanciaKm *float64
+	}
+	if err := query.
+		Order("l.valor_orden " + sentido + ", l.evento_id " + sentido).
+		Limit(pagina.Limite + 1).
+		Scan(&filas).Error; err != nil {
+		return nil, err
+	}
+	if len(filas) > pagina.Limite {
+		filas = filas[:pagina.Limite]
+		ultima := filas[len(filas)-1]
+		listado.Siguiente = &CursorEventos{Valor: ultima.ValorOrden, ID: ultima.EventoID}
+	}
+	if len(filas) == 0 {
+		return listado, nil
+	}
+
+	ids := make([]int64, len(filas))
+	for i, fila := range filas {
+		ids[i] = fila.EventoID
+		if fila.DistanciaKm != nil {
+			listado.Distancias[fila.EventoID] = *fila.DistanciaKm
+		}
+	}
+	if err := e.cargarEventosListado(listado, ids); err != nil {
+		return nil, err
+	}
+	return listado, nil
+}
+
+// cargarEventosListado completa listado con los eventos ids, en ese orden, con sus fechas
+// activas, su recinto y el precio más bajo a la venta de cada uno.
+func (e *Evento) cargarEventosListado(listado *ListadoEventos, ids []int64) error {
+	var eventos []*model.Evento
+	if err := e.PostgresqlDB.
+		Select("evento_id", "organizador_id", "categoria_id", "recinto_id", "titulo", "descripcion", "lugar",
+			"evento_estado", "cant_me_gusta", "cant_no_interesa", "imagen_portada").
+		Preload("Fechas", func(db *gorm.DB) *gorm.DB {
+			return db.Where("estado = ?", util.Activo.Codigo()).Order("evento_fecha_id")
+		}).
+		Preload("Fechas.Fecha").
+		Preload("Recinto").
+		Where("evento_id IN ?", ids).
+		Find(&eventos).Error; err != nil {
+		return err
+	}
+	porID := make(map[int64]*model.Evento, len(eventos))
+	for _, ev := range eventos {
+		porID[ev.ID] = ev
+	}
+	for _, id := range ids {
+		if ev, ok := porID[id]; ok {
+			listado.Eventos = append(listado.Eventos, ev)
+		}
+	}
+
+	var precios []struct {
+		EventoID int64
+		Precio   float64
+	}
+	if err := e.PostgresqlDB.
+		Table("tarifa t").
+		Select("s.evento_id, MIN(t.precio) AS precio").
+		Joins("JOIN sector s ON s.sector_id = t.sector_id").
+		Where("s.evento_id IN ? AND s.estado = ? AND t.estado = ?", ids, util.Activo.Codigo(), util.Activo.Codigo()).
+		Group("s.evento_id").
+		Scan(&precios).Error; err != nil {
+		return err
+	}
+	for _, p := range precios {
+		listado.PreciosDesde[p.EventoID] = p.Precio
+	}
+	return nil
+}
+
+func (e *Evento) ObtenerEventosDisponiblesSinFiltros(pagina PaginaEventos) (*ListadoEventos, error) {
 	var categoriaID *int64
 	var titulo *string
 	var descripcion *string
@@ -44,14 +245,14 @@ func (e *Evento) ObtenerEventosDisponiblesSinFiltros() ([]*model.Evento, error)
 	var estado *int16
 	soloFuturos := false
 
-	eventos, respuesta := e.ObtenerEventosDisponiblesConFiltros(
-		categoriaID, nil, titulo, descripcion, lugar, fecha, horaInicio, estado, soloFuturos,
+	listado, respuesta := e.ObtenerEventosDisponiblesConFiltros(
+		categoriaID, nil, titulo, descripcion, lugar, nil, nil, fecha, horaInicio, estado, soloFuturos, pagina,
 	)
 	if respuesta != nil {
 		return nil, respuesta
 	}
 
-	return eventos, nil
+	return listado, nil
 }
 
 func (e *Evento) ObtenerEventosDisponiblesConFiltros(
@@ -60,159 +261,140 @@ func (e *Evento) ObtenerEventosDisponiblesConFiltros(
 	titulo *string,
 	descripcion *string,
 	lugar *string,
+	recintoID *int64,
+	cercania *Cercania,
 	fecha *time.Time,
 	horaInicio *time.Time,
 	estado *int16,
 	soloFuturos bool,
-) ([]*model.Evento, error) {
-	var eventos []*model.Evento
+	pagina PaginaEventos,
+) (*ListadoEventos, error) {
+	filtrar := func(query *gorm.DB) *gorm.DB {
+		// Aplicar filtros dinámicamente
+		if categoriaID != nil {
+			query = query.Where("evento.categoria_id = ?", *categoriaID)
+		}
+		if recintoID != nil {
+			query = query.Where("evento.recinto_id = ?", *recintoID)
+		}
+		if cercania != nil {
+			query = filtrarCercania(query, "r", *cercania)
+		}
+		if fecha != nil {
+			query = query.Where("f.fecha_evento = ?", *fecha)
+		}
+		if horaInicio != nil {
+			query = query.Where("ef.hora_inicio = ?", *horaInicio)
+		}
+		if soloFuturos {
+			hoy := time.Now().Truncate(24 * time.Hour)
+			query = query.Where("f.fecha_evento >= ?", hoy)
+		}
+		if organizadorID != nil {
+			query = query.Where("evento.organizador_id = ?", *organizadorID)
+		}
+		if estado != nil {
+			query = query.Where("evento.evento_estado = ?", *estado)
+		}
 
-	// Construcción base del query
-	query := e.PostgresqlDB.
-		Model(&model.Evento{}).
-		Select("DISTINCT ON (evento.evento_id) evento.*").
-		Preload("Fechas.Fecha").
-		Preload("Sectores").
-		Preload("Sectores.Tarifa").
-		Preload("Sectores.Tarifa.TipoDeTicket").
-		Preload("Sectores.Tarifa.PerfilPersona").
-		Preload("TiposTicket").
-		Joins("JOIN evento_fecha ef ON ef.evento_id = evento.evento_id").
-		Joins("JOIN fecha f ON f.fecha_id = ef.fecha_id")
+		// Filtro OR agrupado para búsqueda textual
+		var condiciones []string
+		var valores []interface{}
 
-	// Aplicar filtros dinámicamente
-	if categoriaID != nil {
-		query = query.Where("evento.categoria_id = ?", *categoriaID)
-	}
-	if fecha != nil {
-		query = query.Where("f.fecha_evento = ?", *fecha)
-	}
-	if horaInicio != nil {
-		query = query.Where("ef.hora_inicio = ?", *horaInicio)
-	}
-	if soloFuturos {
-		hoy := time.Now().Truncate(24 * time.Hour)
-		query = query.Where("f.fecha_evento >= ?", hoy)
-	}
-	if organizadorID != nil {
-		query = query.Where("evento.organizador_id = ?", *organizadorID)
-	}
-	if estado != nil {
-		query = query.Where("evento.evento_estado = ?", *estado)
-	}
+		if titulo != nil && *titulo != "" {
+			condiciones = append(condiciones, "evento.titulo ILIKE ?")
+			valores = append(valores, "%"+*titulo+"%")
+		}
 
-	// Filtro OR agrupado para búsqueda textual
-	var condiciones []string
-	var valores []interface{}
+		if descripcion != nil && *descripcion != "" {
+			condiciones = append(condiciones, "evento.descripcion ILIKE ?")
+			valores = append(valores, "%"+*descripcion+"%")
+		}
 
-	if titulo != nil && *titulo != "" {
-		condiciones = append(condiciones, "evento.titulo ILIKE ?")
-		valores = append(valores, "%"+*titulo+"%")
-	}
+		if lugar != nil && *lugar != "" {
+			condiciones = append(condiciones, "evento.lugar ILIKE ? OR r.nombre ILIKE ? OR r.distrito ILIKE ?")
+			valores = append(valores, "%"+*lugar+"%", "%"+*lugar+"%", "%"+*lugar+"%")
+		}
 
-	if descripcion != nil && *descripcion != "" {
-		condiciones = append(condiciones, "evento.descripcion ILIKE ?")
-		valores = append(valores, "%"+*descripcion+"%")
+		// Solo agregar el OR si al menos un campo se envió
+		if len(condiciones) > 0 {
+			orGroup := "(" + strings.Join(condiciones, " OR ") + ")"
+			query = query.Where(orGroup, valores...)
+		}
+		return query
 	}
 
-	if lugar != nil && *lugar != "" {
-		condiciones = append(condiciones, "evento.lugar ILIKE ?")
-		valores = append(valores, "%"+*lugar+"%")
+	listado, err := e.paginarEventos(filtrar, pagina)
+	if err != nil {
+		if err != ErrOrdenEventosInvalido {
+			e.logger.Errorf("Evento.ObtenerEventosDisponiblesConFiltros: %v", err)
+		}
+		return nil, err
 	}
+	return listado, nil
+}
 
-	// Solo agregar el OR si al menos un campo se envió
-	if len(condiciones) > 0 {
-		orGroup := "(" + strings.Join(condiciones, " OR ") + ")"
-		query = query.Where(orGroup, valores...)
+// filtrarFeed deja los eventos publicados con alguna fecha activa desde hoy; con usuarioId,
+// además, los que ese usuario todavía no calificó.
+func filtrarFeed(usuarioId *int64, excluirCalificados bool) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		query = query.
+			Where("f.fecha_evento >= CURRENT_DATE").
+			Where("evento.evento_estado = ?", util.EventoPublicado.Codigo()).
+			Where("evento.estado = ?", util.Activo.Codigo())
+		if usuarioId != nil && excluirCalificados {
+			query = query.Where(`NOT EXISTS (SELECT 1 FROM interaccion i WHERE i.usuario_id = ? AND i.evento_id = evento.evento_id)`, *usuarioId)
+		}
+		return query
 	}
+}
 
-	// Aplicar paginación
-	respuesta := query.
-		Order("evento.evento_id, f.fecha_evento ASC").
-		//Limit(limit).
-		//Offset(offset).
-		Find(&eventos)
-
-	if respuesta.Error != nil {
-		return nil, respuesta.Error
+// ObtenerEventosParaElFeed pagina el feed de recomendaciones, sin los eventos que el usuario ya
+// calificó.
+func (e *Evento) ObtenerEventosParaElFeed(usuarioId *int64, pagina PaginaEventos) (*ListadoEventos, error) {
+	listado, err := e.paginarEventos(filtrarFeed(usuarioId, true), pagina)
+	if err != nil {
+		if err != ErrOrdenEventosInvalido {
+			e.logger.Errorf("Evento.ObtenerEventosParaElFeed: %v", err)
+		}
+		return nil, err
 	}
-
-	return eventos, nil
+	return listado, nil
 }
 
-func (e *Evento) ObtenerEventosParaElFeed(usuarioId *int64) ([]*model.Evento, error) {
-	// Construcción base del query
-	var eventos []*model.Evento
-	query := e.PostgresqlDB.
-		Preload("Fechas").
-		Preload("Fechas.Fecha").
-		Preload("Sectores").
-		Preload("Sectores.Tarifa").
-		Preload("Sectores.Tarifa.TipoDeTicket").
-		Preload("Sectores.Tarifa.PerfilPersona").
-		Preload("TiposTicket").
-		Joins("JOIN evento_fecha ef ON ef.evento_id = evento.evento_id").
-		Joins("JOIN fecha f ON f.fecha_id = ef.fecha_id").
-		Where("f.fecha_evento >= CURRENT_DATE").
-		Where("evento.evento_estado = 1").
-		Where("evento.estado = 1").
-		Where(`f.fecha_evento = ( SELECT MIN(f2.fecha_evento)
-        	FROM fecha f2
-        	JOIN evento_fecha ef2 ON ef2.fecha_id = f2.fecha_id
-        	WHERE ef2.evento_id = evento.evento_id)`)
-
-	if usuarioId != nil {
-		query = query.Where(`NOT EXISTS (SELECT 1 FROM interaccion i WHERE i.usuario_id = ? AND i.evento_id = evento.evento_id)`, usuarioId)
+// CargarEventosNuevamenteParaElFeed pagina el feed completo; con usuarioId, cada evento trae la
+// calificación de ese usuario, si la hay.
+func (e *Evento) CargarEventosNuevamenteParaElFeed(usuarioId *int64, pagina PaginaEventos) (*ListadoEventos, error) {
+	listado, err := e.paginarEventos(filtrarFeed(usuarioId, false), pagina)
+	if err != nil {
+		if err != ErrOrdenEventosInvalido {
+			e.logger.Errorf("Evento.CargarEventosNuevamenteParaElFeed: %v", err)
+		}
+		return nil, err
 	}
-
-	respuesta := query.
-		Order("((2*evento.cant_me_gusta - evento.cant_no_interesa) / GREATEST(1, (f.fecha_evento::date - CURRENT_DATE))) DESC").
-		Find(&eventos)
-
-	if respuesta.Error != nil {
-		return nil, respuesta.Error
+	if usuarioId == nil || len(listado.Eventos) == 0 {
+		return listado, nil
 	}
 
-	return eventos, nil
-}
-
-func (e *Evento) CargarEventosNuevamenteParaElFeed(usuarioId *int64) ([]*model.Evento, error) {
-	var eventos []*model.Evento
-
-	// Query base
-	query := e.PostgresqlDB.
-		Preload("Fechas").
-		Preload("Fechas.Fecha").
-		Preload("Sectores").
-		Preload("Sectores.Tarifa").
-		Preload("Sectores.Tarifa.TipoDeTicket").
-		Preload("Sectores.Tarifa.PerfilPersona").
-		Preload("TiposTicket").
-		Joins("JOIN evento_fecha ef ON ef.evento_id = evento.evento_id").
-		Joins("JOIN fecha f ON f.fecha_id = ef.fecha_id").
-		Where("f.fecha_evento >= CURRENT_DATE").
-		Where("evento.evento_estado = 1").
-		Where("evento.estado = 1").
-		Where(`f.fecha_evento = (
-            SELECT MIN(f2.fecha_evento)
-            FROM fecha f2
-            JOIN evento_fecha ef2 ON ef2.fecha_id = f2.fecha_id
-            WHERE ef2.evento_id = evento.evento_id
-        )`)
-	// solo pre-cargamos la interacción del usuario (si existe)
-	if usuarioId != nil {
-		query = query.Preload("Interacciones", "usuario_id = ?", *usuarioId)
-	}
-
-	respuesta := query.
-		Order("((2*evento.cant_me_gusta - evento.cant_no_interesa) / GREATEST(1, (f.fecha_evento::date - CURRENT_DATE))) DESC").
-		Find(&eventos)
-
-	if respuesta.Error != nil {
-		return nil, respuesta.Error
+	ids := make([]int64, len(listado.Eventos))
+	for i, ev := range listado.Eventos {
+		ids[i] = ev.ID
 	}
-
-	return eventos, nil
+	var interacciones []model.Interaccion
+	if err := e.PostgresqlDB.
+		Where("usuario_id = ? AND evento_id IN ?", *usuarioId, ids).
+		Find(&interacciones).Error; err != nil {
+		e.logger.Errorf("Evento.CargarEventosNuevamenteParaElFeed(interacciones): %v", err)
+		return nil, err
+	}
+	for _, ev := range listado.Eventos {
+		for _, i := range interacciones {
+			if i.EventoID == ev.ID {
+				ev.Interaccion = append(ev.Interaccion, i)
+			}
+		}
+	}
+	return listado, nil
 }
 
 // ===============================
@@ -261,9 +443,14 @@ func (e *Evento) ActualizarUbicacionEvento(
 // =======================================
 //
 //	Actualización: ESTADO WORKFLOW (evento_estado)
-//	(p.ej., 0=Borrador, 1=Publicado, 2=Finalizado)
+//	(0=Borrador, 1=Publicado, 2=Cancelado, 3=Finalizado, 4=Postergado)
 //
 // =======================================
+
+// ActualizarEstadoWorkflowEvento cambia el estado del evento si la transición está permitida
+// (si no, ErrTransicionEventoInvalida). Al salir de BORRADOR se descarta la publicación
+// programada; al pasar a CANCELADO se cancela en cascada (ver cancelarEventoEnCascada) en la
+// misma transacción.
 func (e *Evento) ActualizarEstadoWorkflowEvento(
 	eventoID int64,
 	nuevoEstado int16,
@@ -278,6 +465,9 @@ func (e *Evento) ActualizarEstadoWorkflowEvento(
 	updates := map[string]any{
 		"evento_estado": nuevoEstado,
 	}
+	if nuevoEstado != util.EventoBorrador.Codigo() {
+		updates["publish_at"] = nil
+	}
 	if usuarioModificacion != nil {
 		updates["usuario_modificacion"] = *usuarioModificacion
 	}
@@ -286,22 +476,189 @@ func (e *Evento) ActualizarEstadoWorkflowEvento(
 	}
 
 	var ev model.Evento
-	res := e.PostgresqlDB.
-		Model(&ev).
-		Clauses(clause.Returning{}).
-		Where("evento_id = ?", eventoID).
-		Updates(updates)
+	err := e.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		var anterior int16
+		res := tx.
+			Model(&model.Evento{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("evento_estado").
+			Where("evento_id = ?", eventoID).
+			Scan(&anterior)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		nuevo := util.EstadoEvento(nuevoEstado)
+		if !nuevo.IsValid() || !util.EstadoEvento(anterior).PuedePasarA(nuevo) {
+			return ErrTransicionEventoInvalida
+		}
 
-	if res.Error != nil {
-		e.logger.Errorf("ActualizarEstadoWorkflowEvento id=%d: %v", eventoID, res.Error)
-		return nil, res.Error
+		if err := tx.
+			Model(&ev).
+			Clauses(clause.Returning{}).
+			Where("evento_id = ?", eventoID).
+			Updates(updates).Error; err != nil {
+			return err
+		}
+
+		cancelado := util.EventoCancelado.Codigo()
+		if nuevoEstado != cancelado || anterior == cancelado {
+			return nil
+		}
+		return cancelarEventoEnCascada(tx, eventoID, time.Now())
+	})
+	if err != nil {
+		if err != gorm.ErrRecordNotFound && err != ErrTransicionEventoInvalida {
+			e.logger.Errorf("ActualizarEstadoWorkflowEvento id=%d: %v", eventoID, err)
+		}
+		return nil, err
 	}
-	if res.RowsAffected == 0 {
-		return nil, gorm.ErrRecordNotFound
+	return &ev, nil
+}
+
+// cancelarEventoEnCascada hace, dentro de tx y con el evento ya CANCELADO y bloqueado, lo que
+// sigue a una cancelación: anota a quiénes avisar antes de que los reembolsos cancelen sus
+// tickets, cancela las órdenes TEMPORAL abiertas (devolviendo su stock y sus reservas de
+// reventa) y retira las publicaciones de reventa. Los tickets vendidos los reembolsa después el
+// ciclo de vida de eventos. Como CrearOrdenTemporalConReserva lee el evento FOR SHARE, ningún
+// hold nuevo se cuela mientras tanto.
+func cancelarEventoEnCascada(tx *gorm.DB, eventoID int64, ahora time.Time) error {
+	var asistentes []int64
+	if err := tx.Raw(`
+		SELECT DISTINCT u.usuario_id
+		FROM ticket t
+		INNER JOIN evento_fecha ef ON t.evento_fecha_id = ef.evento_fecha_id
+		INNER JOIN evento ev ON ef.evento_id = ev.evento_id
+		INNER JOIN orden_de_compra odc ON t.orden_de_compra_id = odc.orden_de_compra_id
+		CROSS JOIN LATERAL (VALUES (odc.usuario_id), (COALESCE(t.titular_id, odc.usuario_id))) AS u(usuario_id)
+		WHERE ev.evento_id = ?
+		  AND odc.estado_de_orden = ?
+		  AND t.estado_de_ticket = ?
+		  AND u.usuario_id != ev.organizador_id
+		ORDER BY u.usuario_id`,
+		eventoID, util.OrdenConfirmada.Codigo(), util.TicketVendido.Codigo(),
+	).Scan(&asistentes).Error; err != nil {
+		return err
+	}
+
+	// SKIP LOCKED: una orden bloqueada se está confirmando o liberando. Si se confirma, ve el
+	// evento CANCELADO, no emite tickets y la orden se reembolsa entera con el resto; esperarla
+	// podría trabar ambas transacciones, porque la confirmación bloquea la fila del evento que
+	// esta ya tiene bloqueada.
+	var temporales []int64
+	if err := tx.
+		Model(&model.OrdenDeCompra{}).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("estado_de_orden = ?", util.OrdenTemporal.Codigo()).
+		Where(`(orden_de_compra_id IN (
+			SELECT d.orden_de_compra_id FROM orden_de_compra_detalle d
+			INNER JOIN evento_fecha ef ON ef.evento_fecha_id = d.evento_fecha_id
+			WHERE ef.evento_id = ?
+		) OR orden_de_compra_id IN (
+			SELECT pr.orden_de_compra_id FROM publicacion_reventa pr
+			WHERE pr.evento_id = ? AND pr.estado = ?
+		))`, eventoID, eventoID, util.ReventaReservada.Codigo()).
+		Order("orden_de_compra_id").
+		Pluck("orden_de_compra_id", &temporales).Error; err != nil {
+		return err
+	}
+	for _, orderID := range temporales {
+		// Una orden que se confirmó o venció en paralelo ya no es TEMPORAL: se deja como está
+		if err := cancelarOrdenTemporalTx(tx, orderID); err != nil && err != ErrOrdenNoTemporal {
+			return err
+		}
+	}
+
+	if err := tx.
+		Model(&model.PublicacionReventa{}).
+		Where("evento_id = ? AND estado = ?", eventoID, util.ReventaPublicada.Codigo()).
+		Update("estado", util.ReventaCancelada.Codigo()).Error; err != nil {
+		return err
+	}
+
+	return registrarEventoDominio(tx, model.EventoEventoCancelado, eventoID,
+		model.PayloadEventoCancelado{EventoID: eventoID, Asistentes: asistentes}, ahora)
+}
+
+// ProgramarPublicacion fija cuándo se publica solo un evento en BORRADOR; con publicarEn nil
+// quita la programación. Si el evento ya salió de BORRADOR devuelve ErrTransicionEventoInvalida.
+func (e *Evento) ProgramarPublicacion(
+	eventoID int64,
+	publicarEn *time.Time,
+	usuarioModificacion int64,
+	fechaModificacion time.Time,
+) (*model.Evento, error) {
+	var ev model.Evento
+	err := e.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		var estado int16
+		res := tx.
+			Model(&model.Evento{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("evento_estado").
+			Where("evento_id = ?", eventoID).
+			Scan(&estado)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if estado != util.EventoBorrador.Codigo() {
+			return ErrTransicionEventoInvalida
+		}
+		return tx.
+			Model(&ev).
+			Clauses(clause.Returning{}).
+			Where("evento_id = ?", eventoID).
+			Updates(map[string]any{
+				"publish_at":           publicarEn,
+				"usuario_modificacion": usuarioModificacion,
+				"fecha_modificacion":   fechaModificacion,
+			}).Error
+	})
+	if err != nil {
+		if err != gorm.ErrRecordNotFound && err != ErrTransicionEventoInvalida {
+			e.logger.Errorf("ProgramarPublicacion id=%d: %v", eventoID, err)
+		}
+		return nil, err
 	}
 	return &ev, nil
 }
 
+// PublicarProgramados publica hasta limite eventos en BORRADOR cuya publish_at ya llegó y
+// devuelve sus ids. Los eventos se toman con SKIP LOCKED: si otra réplica o un cambio manual
+// los tiene bloqueados, quedan para la siguiente pasada.
+func (e *Evento) PublicarProgramados(ahora time.Time, limite int) ([]int64, error) {
+	vencidos := e.PostgresqlDB.
+		Model(&model.Evento{}).
+		Select("evento_id").
+		Where("evento_estado = ? AND publish_at <= ?", util.EventoBorrador.Codigo(), ahora).
+		Order("publish_at").
+		Limit(limite).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var publicados []model.Evento
+	if err := e.PostgresqlDB.
+		Model(&publicados).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "evento_id"}}}).
+		Where("evento_id IN (?)", vencidos).
+		Updates(map[string]any{
+			"evento_estado":      util.EventoPublicado.Codigo(),
+			"publish_at":         nil,
+			"fecha_modificacion": ahora,
+		}).Error; err != nil {
+		e.logger.Errorf("PublicarProgramados: %v", err)
+		return nil, err
+	}
+	ids := make([]int64, 0, len(publicados))
+	for _, ev := range publicados {
+		ids = append(ids, ev.ID)
+	}
+	return ids, nil
+}
+
 // =======================================
 //
 //	Actualización: ESTADO FLAG (estado)
@@ -388,8 +745,49 @@ func (e *Evento) ActualizarCamposEvento(
 //  - Reasignar fecha en un evento_fecha (cambiar fecha_id)
 // =====================================================
 
+// exigirFechasSinVentas devuelve ErrFechaConTicketsVendidos si algún evento_fecha (ef) que cumpla
+// el filtro tiene tickets vendidos o usados.
+func exigirFechasSinVentas(tx *gorm.DB, filtro string, arg int64) error {
+	var vendidos int64
+	if err := tx.
+		Table("ticket t").
+		Joins("JOIN evento_fecha ef ON ef.evento_fecha_id = t.evento_fecha_id").
+		Where(filtro, arg).
+		Where("t.estado_de_ticket IN ?", []int16{util.TicketVendido.Codigo(), util.TicketUsado.Codigo()}).
+		Count(&vendidos).Error; err != nil {
+		return err
+	}
+	if vendidos > 0 {
+		return ErrFechaConTicketsVendidos
+	}
+	return nil
+}
+
+// registrarReprogramaciones registra, dentro de tx, un EVENTO_REPROGRAMADO por cada
+// evento_fecha que cumpla el filtro.
+func registrarReprogramaciones(tx *gorm.DB, filtro string, arg int64) error {
+	var afectadas []model.EventoFecha
+	if err := tx.
+		Select("evento_fecha_id", "evento_id").
+		Where(filtro, arg).
+		Find(&afectadas).Error; err != nil {
+		return err
+	}
+	ahora := time.Now()
+	for _, ef := range afectadas {
+		if err := registrarEventoDominio(tx, model.EventoEventoReprogramado, ef.ID, model.PayloadEventoReprogramado{
+			EventoID:      ef.EventoID,
+			EventoFechaID: ef.ID,
+		}, ahora); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Cambia el valor de fecha_evento (tabla FECHA) para un fecha_id dado.
-// Ojo: este cambio afecta a todos los evento_fecha que referencien ese fecha_id.
+// Ojo: este cambio afecta a todos los evento_fecha que referencien ese fecha_id, así que si
+// alguno tiene entradas vendidas devuelve ErrFechaConTicketsVendidos.
 func (e *Evento) ActualizarFechaCalendario(
 	fechaID int64,
 	nuevaFecha time.Time, // usar solo la parte de día acorde a tu diseño
@@ -412,22 +810,33 @@ func (e *Evento) ActualizarFechaCalendario(
 		updates["fecha_modificacion"] = *fechaModificacion
 	}
 
-	res := e.PostgresqlDB.
-		Table("fecha").
-		Where("fecha_id = ?", fechaID).
-		Updates(updates)
-
-	if res.Error != nil {
-		e.logger.Errorf("ActualizarFechaCalendario fecha_id=%d: %v", fechaID, res.Error)
-		return res.Error
-	}
-	if res.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+	err := e.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := exigirFechasSinVentas(tx, "ef.fecha_id = ?", fechaID); err != nil {
+			return err
+		}
+		res := tx.
+			Table("fecha").
+			Where("fecha_id = ?", fechaID).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return registrarReprogramaciones(tx, "fecha_id = ?", fechaID)
+	})
+	if err != nil {
+		if err != gorm.ErrRecordNotFound && err != ErrFechaConTicketsVendidos {
+			e.logger.Errorf("ActualizarFechaCalendario fecha_id=%d: %v", fechaID, err)
+		}
+		return err
 	}
 	return nil
 }
 
-// Cambia la HORA de inicio de un registro evento_fecha (no la fecha).
+// Cambia la HORA de inicio de un registro evento_fecha (no la fecha). Con entradas vendidas
+// devuelve ErrFechaConTicketsVendidos.
 func (e *Evento) ActualizarHoraInicioEventoFecha(
 	eventoFechaID int64,
 	nuevaHora time.Time, // usa time con la hora deseada (Postgres TIMESTAMPTZ)
@@ -449,23 +858,34 @@ func (e *Evento) ActualizarHoraInicioEventoFecha(
 		updates["fecha_modificacion"] = *fechaModificacion
 	}
 
-	res := e.PostgresqlDB.
-		Table("evento_fecha").
-		Where("evento_fecha_id = ?", eventoFechaID).
-		Updates(updates)
-
-	if res.Error != nil {
-		e.logger.Errorf("ActualizarHoraInicioEventoFecha evento_fecha_id=%d: %v", eventoFechaID, res.Error)
-		return res.Error
-	}
-	if res.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+	err := e.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := exigirFechasSinVentas(tx, "ef.evento_fecha_id = ?", eventoFechaID); err != nil {
+			return err
+		}
+		res := tx.
+			Table("evento_fecha").
+			Where("evento_fecha_id = ?", eventoFechaID).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return registrarReprogramaciones(tx, "evento_fecha_id = ?", eventoFechaID)
+	})
+	if err != nil {
+		if err != gorm.ErrRecordNotFound && err != ErrFechaConTicketsVendidos {
+			e.logger.Errorf("ActualizarHoraInicioEventoFecha evento_fecha_id=%d: %v", eventoFechaID, err)
+		}
+		return err
 	}
 	return nil
 }
 
 // Reasigna la fecha (fecha_id) de un evento_fecha específico.
 // Útil si creas una nueva fecha en 'fecha' y quieres apuntar el evento_fecha a esa nueva fecha.
+// Con entradas vendidas devuelve ErrFechaConTicketsVendidos.
 func (e *Evento) ReasignarFechaDeEventoFecha(
 	eventoFechaID int64,
 	nuevoFechaID int64,
@@ -487,17 +907,27 @@ func (e *Evento) ReasignarFechaDeEventoFecha(
 		updates["fecha_modificacion"] = *fechaModificacion
 	}
 
-	res := e.PostgresqlDB.
-		Table("evento_fecha").
-		Where("evento_fecha_id = ?", eventoFechaID).
-		Updates(updates)
-
-	if res.Error != nil {
-		e.logger.Errorf("ReasignarFechaDeEventoFecha evento_fecha_id=%d: %v", eventoFechaID, res.Error)
-		return res.Error
-	}
-	if res.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+	err := e.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := exigirFechasSinVentas(tx, "ef.evento_fecha_id = ?", eventoFechaID); err != nil {
+			return err
+		}
+		res := tx.
+			Table("evento_fecha").
+			Where("evento_fecha_id = ?", eventoFechaID).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return registrarReprogramaciones(tx, "evento_fecha_id = ?", eventoFechaID)
+	})
+	if err != nil {
+		if err != gorm.ErrRecordNotFound && err != ErrFechaConTicketsVendidos {
+			e.logger.Errorf("ReasignarFechaDeEventoFecha evento_fecha_id=%d: %v", eventoFechaID, err)
+		}
+		return err
 	}
 	return nil
 }
@@ -552,11 +982,20 @@ func (e *Evento) ObtenerEventosDelOrganizador(idOrganizador int64) ([]*model.Eve
 	return eventos, nil
 }
 
+// ObtenerEventoBasico devuelve el evento sin sus relaciones, en cualquier estado.
+func (e *Evento) ObtenerEventoBasico(id int64) (*model.Evento, error) {
+	var evento model.Evento
+	if err := e.PostgresqlDB.First(&evento, "evento_id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &evento, nil
+}
+
 func (e *Evento) ObtenerEventoPorId(id int64) (*model.Evento, error) {
 	var evento *model.Evento
 
 	res := e.PostgresqlDB.Table("evento").
-		Preload("Fechas").
+		Preload("Fechas", "estado = ?", util.Activo.Codigo()).
 		Preload("Fechas.Fecha").
 		Where("evento_id = ? AND evento_estado = 1", id).
 		Find(&evento)
@@ -574,7 +1013,7 @@ func (e *Evento) ObtenerEventoDetalle(eventoId int64) (*schemas.EventoDetalleDTO
 
 	respuesta := e.PostgresqlDB.
 		Table("evento").
-		Select("evento_id , titulo, descripcion, imagen_portada, lugar").
+		Select("evento_id , titulo, descripcion, imagen_portada, lugar, recinto_id").
 		Where("evento_id = ?", eventoId).
 		First(&eventoBase)
 
@@ -637,6 +1076,7 @@ func (e *Evento) ObtenerEventoDetalle(eventoId int64) (*schemas.EventoDetalleDTO
 		Titulo:        eventoBase.Titulo,
 		Descripcion:   eventoBase.Descripcion,
 		Lugar:         eventoBase.Lugar,
+		IdRecinto:     eventoBase.RecintoID,
 		ImagenPortada: eventoBase.ImagenPortada,
 		Fechas:        fechas,
 		Tarifas:       tarifas,
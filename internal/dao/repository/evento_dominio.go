@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+	"gorm.io/gorm"
+)
+
+type EventoDominio struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewEventoDominioController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *EventoDominio {
+	return &EventoDominio{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+// registrarEventoDominio deja PENDIENTE en el outbox, dentro de tx, un evento de dominio con su
+// payload. Si la transacción se deshace, el evento tampoco existe.
+func registrarEventoDominio(tx *gorm.DB, tipo string, agregadoID int64, payload any, ahora time.Time) error {
+	datos, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&model.EventoDominio{
+		Tipo:           tipo,
+		AgregadoID:     agregadoID,
+		Payload:        string(datos),
+		Estado:         util.EventoDominioPendiente.Codigo(),
+		ProximoIntento: &ahora,
+		FechaCreacion:  ahora,
+	}).Error
+}
+
+// registrarOrdenConfirmada registra, dentro de tx, el evento ORDEN_CONFIRMADA de la orden.
+func registrarOrdenConfirmada(tx *gorm.DB, orderID int64, ahora time.Time) error {
+	var usuarioID int64
+	if err := tx.
+		Model(&model.OrdenDeCompra{}).
+		Select("usuario_id").
+		Where("orden_de_compra_id = ?", orderID).
+		Scan(&usuarioID).Error; err != nil {
+		return err
+	}
+	return registrarEventoDominio(tx, model.EventoOrdenConfirmada, orderID, model.PayloadOrdenConfirmada{
+		OrdenDeCompraID: orderID,
+		UsuarioID:       usuarioID,
+	}, ahora)
+}
+
+// reclamarVencidos toma hasta lote filas de tabla en estadoPendiente cuyo proximo_intento ya
+// venció y lo corre a ahora+plazo, para que otro worker no las tome mientras este las procesa.
+// Si el proceso muere a mitad de camino, vuelven a estar disponibles cuando vence el plazo.
+func reclamarVencidos(
+	db *gorm.DB,
+	tabla, columnaID, columnaEstado string,
+	estadoPendiente int16,
+	ahora time.Time,
+	plazo time.Duration,
+	lote int,
+	destino any,
+) error {
+	return db.Raw(fmt.Sprintf(`
+		UPDATE %[1]s SET proximo_intento = ?
+		WHERE %[2]s IN (
+			SELECT %[2]s FROM %[1]s
+			WHERE %[3]s = ? AND proximo_intento <= ?
+			ORDER BY proximo_intento
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, tabla, columnaID, columnaEstado),
+		ahora.Add(plazo), estadoPendiente, ahora, lote,
+	).Scan(destino).Error
+}
+
+// ReclamarPendientes toma hasta lote eventos PENDIENTES cuyo próximo intento ya venció (ver
+// reclamarVencidos).
+func (r *EventoDominio) ReclamarPendientes(ahora time.Time, plazo time.Duration, lote int) ([]model.EventoDominio, error) {
+	var eventos []model.EventoDominio
+	err := reclamarVencidos(r.PostgresqlDB, "evento_dominio", "evento_dominio_id", "estado",
+		util.EventoDominioPendiente.Codigo(), ahora, plazo, lote, &eventos)
+	if err != nil {
+		r.logger.Errorf("EventoDominio.ReclamarPendientes: %v", err)
+		return nil, err
+	}
+	return eventos, nil
+}
+
+// Expandir crea, en una sola transacción, las notificaciones de un evento y lo marca
+// PROCESADO. Si otro worker ya lo procesó no hace nada, así un evento nunca se notifica dos
+// veces.
+func (r *EventoDominio) Expandir(id int64, notificaciones []model.Notificacion, ahora time.Time) error {
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Model(&model.EventoDominio{}).
+			Where("evento_dominio_id = ? AND estado = ?", id, util.EventoDominioPendiente.Codigo()).
+			Updates(map[string]any{
+				"estado":          util.EventoDominioProcesado.Codigo(),
+				"intentos":        gorm.Expr("intentos + 1"),
+				"proximo_intento": nil,
+				"ultimo_error":    nil,
+				"fecha_procesado": ahora,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		if len(notificaciones) == 0 {
+			return nil
+		}
+		for i := range notificaciones {
+			notificaciones[i].EventoDominioID = &id
+		}
+		return tx.Create(&notificaciones).Error
+	})
+	if err != nil {
+		r.logger.Errorf("EventoDominio.Expandir(%d): %v", id, err)
+	}
+	return err
+}
+
+// RegistrarFallo anota un intento fallido de expandir el evento. Con proximo se reintenta en esa
+// fecha; sin él el evento queda FALLIDO.
+func (r *EventoDominio) RegistrarFallo(id int64, causa string, proximo *time.Time) error {
+	estado := util.EventoDominioPendiente
+	if proximo == nil {
+		estado = util.EventoDominioFallido
+	}
+	err := r.PostgresqlDB.
+		Model(&model.EventoDominio{}).
+		Where("evento_dominio_id = ?", id).
+		Updates(map[string]any{
+			"estado":          estado.Codigo(),
+			"intentos":        gorm.Expr("intentos + 1"),
+			"proximo_intento": proximo,
+			"ultimo_error":    causa,
+		}).Error
+	if err != nil {
+		r.logger.Errorf("EventoDominio.RegistrarFallo(%d): %v", id, err)
+	}
+	return err
+}
+
+// ListarFallidos devuelve los eventos que agotaron sus intentos, del más reciente al más antiguo.
+func (r *EventoDominio) ListarFallidos(limite int) ([]model.EventoDominio, error) {
+	var eventos []model.EventoDominio
+	if err := r.PostgresqlDB.
+		Where("estado = ?", util.EventoDominioFallido.Codigo()).
+		Order("evento_dominio_id DESC").
+		Limit(limite).
+		Find(&eventos).Error; err != nil {
+		r.logger.Errorf("EventoDominio.ListarFallidos: %v", err)
+		return nil, err
+	}
+	return eventos, nil
+}
+
+// Reintentar devuelve un evento FALLIDO a PENDIENTE con los intentos en cero. Devuelve false si
+// el evento no existe o no estaba FALLIDO.
+func (r *EventoDominio) Reintentar(id int64, ahora time.Time) (bool, error) {
+	res := r.PostgresqlDB.
+		Model(&model.EventoDominio{}).
+		Where("evento_dominio_id = ? AND estado = ?", id, util.EventoDominioFallido.Codigo()).
+		Updates(map[string]any{
+			"estado":          util.EventoDominioPendiente.Codigo(),
+			"intentos":        0,
+			"proximo_intento": ahora,
+		})
+	if res.Error != nil {
+		r.logger.Errorf("EventoDominio.Reintentar(%d): %v", id, res.Error)
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// ObtenerPorID devuelve un evento del outbox.
+func (r *EventoDominio) ObtenerPorID(id int64) (*model.EventoDominio, error) {
+	var evento model.EventoDominio
+	if err := r.PostgresqlDB.First(&evento, "evento_dominio_id = ?", id).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			r.logger.Errorf("EventoDominio.ObtenerPorID(%d): %v", id, err)
+		}
+		return nil, err
+	}
+	return &evento, nil
+}
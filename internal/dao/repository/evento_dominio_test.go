@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestEventoDominioSeExpandeUnaSolaVez(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.EventoDominio{}, &model.Notificacion{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewEventoDominioController(logging.NewLoggerMock(), db)
+
+	ahora := time.Now().Truncate(time.Microsecond)
+	if err := registrarEventoDominio(db, model.EventoEventoCancelado, 3, model.PayloadEventoCancelado{EventoID: 3}, ahora); err != nil {
+		t.Fatalf("registrarEventoDominio: %v", err)
+	}
+
+	eventos, err := repo.ReclamarPendientes(ahora, time.Minute, 10)
+	if err != nil || len(eventos) != 1 {
+		t.Fatalf("se esperaba reclamar 1 evento, se obtuvo %d (%v)", len(eventos), err)
+	}
+	var usuario int64 = 5
+	notificacion := func() []model.Notificacion {
+		return []model.Notificacion{{
+			Mensaje:            "El evento fue cancelado",
+			Canal:              model.CanalInApp,
+			UsuarioID:          &usuario,
+			FechaEnvio:         ahora,
+			EstadoNotificacion: util.NotificacionPendiente.Codigo(),
+			ProximoIntento:     &ahora,
+		}}
+	}
+	if err := repo.Expandir(eventos[0].ID, notificacion(), ahora); err != nil {
+		t.Fatalf("Expandir: %v", err)
+	}
+	// Un worker que reclamó el mismo evento tras vencer el plazo no debe duplicar notificaciones
+	if err := repo.Expandir(eventos[0].ID, notificacion(), ahora); err != nil {
+		t.Fatalf("Expandir repetido: %v", err)
+	}
+
+	var total int64
+	db.Model(&model.Notificacion{}).Where("evento_dominio_id = ?", eventos[0].ID).Count(&total)
+	if total != 1 {
+		t.Fatalf("se esperaba 1 notificación del evento, hay %d", total)
+	}
+	var procesado model.EventoDominio
+	db.First(&procesado, "evento_dominio_id = ?", eventos[0].ID)
+	if procesado.Estado != util.EventoDominioProcesado.Codigo() || procesado.ProximoIntento != nil {
+		t.Fatalf("el evento debió quedar PROCESADO, se obtuvo %+v", procesado)
+	}
+}
@@ -36,6 +36,19 @@ func (e *EventoFecha) CrearEventoFecha(EventoFecha *model.EventoFecha) error {
 	return nil
 }
 
+// ObtenerConEventoYFecha: devuelve la fecha del evento con su evento y su día.
+func (r *EventoFecha) ObtenerConEventoYFecha(eventoFechaID int64) (*model.EventoFecha, error) {
+	var ef model.EventoFecha
+	if err := r.PostgresqlDB.
+		Preload("Evento").
+		Preload("Fecha").
+		Where("evento_fecha_id = ?", eventoFechaID).
+		First(&ef).Error; err != nil {
+		return nil, err
+	}
+	return &ef, nil
+}
+
 // ListarEventoFechasPorEventoID: devuelve TODAS las filas por evento_id (sin filtrar estado)
 func (r *EventoFecha) ListarEventoFechasPorEventoID(eventoID int64) ([]model.EventoFecha, error) {
 	var list []model.EventoFecha
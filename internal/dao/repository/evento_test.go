@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestCancelarEventoCortaVentasYCancelaHolds(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(
+		&model.EventoFecha{}, &model.Ticket{}, &model.PublicacionReventa{}, &model.EventoDominio{},
+	); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	eventos := NewEventoController(logging.NewLoggerMock(), db)
+	ordenes := NewOrdenDeCompraController(logging.NewLoggerMock(), db)
+	ahora := time.Now().Truncate(time.Microsecond)
+
+	sector := crearSectorPrueba(t, db, "GENERAL", 10)
+	fecha := &model.EventoFecha{EventoID: sector.EventoID, FechaID: 1, HoraInicio: ahora}
+	if err := db.Create(fecha).Error; err != nil {
+		t.Fatalf("crear fecha: %v", err)
+	}
+	detalle := func(cantidad int64) model.OrdenDeCompraDetalle {
+		return model.OrdenDeCompraDetalle{TarifaID: 1, SectorID: sector.ID, EventoFechaID: fecha.ID, Cantidad: cantidad, PrecioUnitario: 5}
+	}
+
+	// Un asistente que ya compró y le pasó su entrada a otro
+	var comprador, titular int64 = 7, 8
+	vendida := &model.OrdenDeCompra{UsuarioID: comprador, Fecha: ahora, FechaHoraIni: ahora, Total: 5, EstadoDeOrden: util.OrdenConfirmada.Codigo()}
+	if err := db.Create(vendida).Error; err != nil {
+		t.Fatalf("crear orden confirmada: %v", err)
+	}
+	ticket := &model.Ticket{
+		OrdenDeCompraID: &vendida.ID, EventoFechaID: fecha.ID, TarifaID: 1, TitularID: &titular,
+		CodigoQR: "qr-vendido", EstadoDeTicket: util.TicketVendido.Codigo(),
+	}
+	if err := db.Create(ticket).Error; err != nil {
+		t.Fatalf("crear ticket: %v", err)
+	}
+
+	hold := nuevaOrdenPrueba(9, detalle(3))
+	if err := ordenes.CrearOrdenTemporalConReserva(hold); err != nil {
+		t.Fatalf("reservar con el evento publicado: %v", err)
+	}
+
+	if _, err := eventos.ActualizarEstadoWorkflowEvento(sector.EventoID, util.EventoBorrador.Codigo(), nil, &ahora); err != ErrTransicionEventoInvalida {
+		t.Fatalf("un evento publicado no vuelve a BORRADOR, se obtuvo %v", err)
+	}
+	if _, err := eventos.ActualizarEstadoWorkflowEvento(sector.EventoID, util.EventoCancelado.Codigo(), nil, &ahora); err != nil {
+		t.Fatalf("cancelar: %v", err)
+	}
+
+	var orden model.OrdenDeCompra
+	db.First(&orden, "orden_de_compra_id = ?", hold.ID)
+	var sec model.Sector
+	db.First(&sec, "sector_id = ?", sector.ID)
+	if orden.EstadoDeOrden != util.OrdenCancelada.Codigo() || sec.CantVendidas != 0 {
+		t.Fatalf("el hold debió cancelarse y devolver el stock: orden=%d vendidas=%d", orden.EstadoDeOrden, sec.CantVendidas)
+	}
+
+	var aviso model.EventoDominio
+	if err := db.First(&aviso, "tipo = ? AND agregado_id = ?", model.EventoEventoCancelado, sector.EventoID).Error; err != nil {
+		t.Fatalf("debió registrarse EVENTO_CANCELADO: %v", err)
+	}
+	var payload model.PayloadEventoCancelado
+	if err := json.Unmarshal([]byte(aviso.Payload), &payload); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if len(payload.Asistentes) != 2 || payload.Asistentes[0] != comprador || payload.Asistentes[1] != titular {
+		t.Fatalf("se debió avisar al comprador y al titular, se anotó %v", payload.Asistentes)
+	}
+
+	if err := ordenes.CrearOrdenTemporalConReserva(nuevaOrdenPrueba(9, detalle(1))); !errors.Is(err, ErrEventoNoALaVenta) {
+		t.Fatalf("un evento cancelado no acepta reservas, se obtuvo %v", err)
+	}
+	if _, err := eventos.ActualizarEstadoWorkflowEvento(sector.EventoID, util.EventoPublicado.Codigo(), nil, &ahora); err != ErrTransicionEventoInvalida {
+		t.Fatalf("CANCELADO es final, se obtuvo %v", err)
+	}
+}
+
+func TestPublicarProgramados(t *testing.T) {
+	db := abrirBDPrueba(t)
+	repo := NewEventoController(logging.NewLoggerMock(), db)
+	ahora := time.Now().Truncate(time.Microsecond)
+
+	vencido := crearEventoPrueba(t, db, util.EventoBorrador)
+	futuro := crearEventoPrueba(t, db, util.EventoBorrador)
+	antes, despues := ahora.Add(-time.Minute), ahora.Add(time.Hour)
+	if _, err := repo.ProgramarPublicacion(vencido.ID, &antes, 1, ahora); err != nil {
+		t.Fatalf("ProgramarPublicacion: %v", err)
+	}
+	if _, err := repo.ProgramarPublicacion(futuro.ID, &despues, 1, ahora); err != nil {
+		t.Fatalf("ProgramarPublicacion: %v", err)
+	}
+	publicado := crearEventoPrueba(t, db, util.EventoPublicado)
+	if _, err := repo.ProgramarPublicacion(publicado.ID, &antes, 1, ahora); err != ErrTransicionEventoInvalida {
+		t.Fatalf("solo se programa un BORRADOR, se obtuvo %v", err)
+	}
+
+	ids, err := repo.PublicarProgramados(ahora, 10)
+	if err != nil {
+		t.Fatalf("PublicarProgramados: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != vencido.ID {
+		t.Fatalf("solo debió publicarse el evento vencido, se publicaron %v", ids)
+	}
+	var ev model.Evento
+	db.First(&ev, "evento_id = ?", vencido.ID)
+	if ev.EventoEstado != util.EventoPublicado.Codigo() || ev.PublicarEn != nil {
+		t.Fatalf("el evento debió quedar PUBLICADO y sin programación: %+v", ev)
+	}
+	if ids, _ := repo.PublicarProgramados(ahora, 10); len(ids) != 0 {
+		t.Fatalf("una segunda pasada no debe publicar nada, publicó %v", ids)
+	}
+}
+
+func TestListadoDeEventosPaginaPorCursor(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Fecha{}, &model.Tarifa{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	eventos := NewEventoController(logging.NewLoggerMock(), db)
+	hoy := time.Now().UTC().Truncate(24 * time.Hour)
+
+	// Cinco eventos, cada uno un día más tarde y más barato que el anterior
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		sector := crearSectorPrueba(t, db, "GENERAL", 10)
+		fecha := &model.Fecha{FechaEvento: hoy.AddDate(0, 0, i+1)}
+		if err := db.Create(fecha).Error; err != nil {
+			t.Fatalf("crear fecha: %v", err)
+		}
+		if err := db.Create(&model.EventoFecha{EventoID: sector.EventoID, FechaID: fecha.ID, HoraInicio: hoy.Add(20 * time.Hour)}).Error; err != nil {
+			t.Fatalf("crear evento_fecha: %v", err)
+		}
+		if err := db.Create(&model.Tarifa{SectorID: sector.ID, TipoDeTicketID: 1, Precio: float64(100 - 10*i)}).Error; err != nil {
+			t.Fatalf("crear tarifa: %v", err)
+		}
+		ids = append(ids, sector.EventoID)
+	}
+
+	leer := func(orden string) []int64 {
+		var vistos []int64
+		pagina := PaginaEventos{Orden: orden, Limite: 2}
+		for {
+			listado, err := eventos.ObtenerEventosDisponiblesSinFiltros(pagina)
+			if err != nil {
+				t.Fatalf("listar por %s: %v", orden, err)
+			}
+			if listado.Total != 5 || len(listado.Eventos) > 2 {
+				t.Fatalf("página por %s: total=%d eventos=%d", orden, listado.Total, len(listado.Eventos))
+			}
+			for _, ev := range listado.Eventos {
+				if len(ev.Fechas) != 1 || ev.Sectores != nil {
+					t.Fatalf("el listado trae solo las fechas del evento: %+v", ev)
+				}
+				vistos = append(vistos, ev.ID)
+			}
+			if listado.Siguiente == nil {
+				return vistos
+			}
+			pagina.Despues = listado.Siguiente
+		}
+	}
+
+	porFecha := leer(OrdenEventosFecha)
+	porPrecio := leer(OrdenEventosPrecio)
+	if len(porFecha) != 5 || len(porPrecio) != 5 {
+		t.Fatalf("se debieron recorrer los cinco eventos: fecha=%v precio=%v", porFecha, porPrecio)
+	}
+	for i := range ids {
+		if porFecha[i] != ids[i] || porPrecio[i] != ids[len(ids)-1-i] {
+			t.Fatalf("orden inesperado: fecha=%v precio=%v, creados=%v", porFecha, porPrecio, ids)
+		}
+	}
+
+	if _, err := eventos.ObtenerEventosDisponiblesSinFiltros(PaginaEventos{Orden: "nombre", Limite: 2}); err != ErrOrdenEventosInvalido {
+		t.Fatalf("se esperaba ErrOrdenEventosInvalido, se obtuvo %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+type Idempotencia struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewIdempotenciaController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *Idempotencia {
+	return &Idempotencia{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+// Reservar registra la clave como "en curso". Si el usuario ya usó la clave (y no ha vencido)
+// no inserta nada y devuelve la fila existente con creada = false. La PK (usuario_id, clave)
+// garantiza que entre dos peticiones simultáneas solo una gane la reserva. Una reserva en curso
+// de la misma petición tomada hace más de bloqueo se da por abandonada (el proceso que la tenía
+// murió sin liberarla) y pasa a esta petición con creada = true.
+func (r *Idempotencia) Reservar(clave *model.ClaveIdempotencia, ahora time.Time, bloqueo time.Duration) (*model.ClaveIdempotencia, bool, error) {
+	var existente model.ClaveIdempotencia
+	creada := false
+
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		// Una clave vencida se puede reutilizar
+		if err := tx.
+			Where("usuario_id = ? AND clave = ? AND fecha_expiracion <= ?", clave.UsuarioID, clave.Clave, ahora).
+			Delete(&model.ClaveIdempotencia{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(clave)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 1 {
+			creada = true
+			return nil
+		}
+
+		// El UPDATE condicional hace que entre dos reintentos solo uno tome la reserva abandonada
+		res = tx.Model(&model.ClaveIdempotencia{}).
+			Where("usuario_id = ? AND clave = ? AND hash_peticion = ?", clave.UsuarioID, clave.Clave, clave.HashPeticion).
+			Where("codigo_respuesta IS NULL AND fecha_bloqueo <= ?", ahora.Add(-bloqueo)).
+			Update("fecha_bloqueo", ahora)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 1 {
+			creada = true
+			return nil
+		}
+
+		return tx.
+			Where("usuario_id = ? AND clave = ?", clave.UsuarioID, clave.Clave).
+			First(&existente).Error
+	})
+	if err != nil {
+		r.logger.Errorf("Idempotencia.Reservar: %v", err)
+		return nil, false, err
+	}
+	if creada {
+		return clave, true, nil
+	}
+	return &existente, false, nil
+}
+
+// Completar guarda la respuesta de la petición original para repetirla en los reintentos.
+func (r *Idempotencia) Completar(usuarioID int64, clave string, codigo int, tipoContenido string, cuerpo []byte) error {
+	if err := r.PostgresqlDB.
+		Model(&model.ClaveIdempotencia{}).
+		Where("usuario_id = ? AND clave = ?", usuarioID, clave).
+		Updates(map[string]any{
+			"codigo_respuesta": codigo,
+			"tipo_contenido":   tipoContenido,
+			"cuerpo_respuesta": cuerpo,
+		}).Error; err != nil {
+		r.logger.Errorf("Idempotencia.Completar: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Liberar borra una reserva que no llegó a completarse, para que el cliente pueda reintentar.
+func (r *Idempotencia) Liberar(usuarioID int64, clave string) error {
+	if err := r.PostgresqlDB.
+		Where("usuario_id = ? AND clave = ? AND codigo_respuesta IS NULL", usuarioID, clave).
+		Delete(&model.ClaveIdempotencia{}).Error; err != nil {
+		r.logger.Errorf("Idempotencia.Liberar: %v", err)
+		return err
+	}
+	return nil
+}
+
+// BorrarVencidas elimina las claves cuya ventana de reintento ya terminó.
+func (r *Idempotencia) BorrarVencidas(ahora time.Time) (int64, error) {
+	res := r.PostgresqlDB.
+		Where("fecha_expiracion <= ?", ahora).
+		Delete(&model.ClaveIdempotencia{})
+	if res.Error != nil {
+		r.logger.Errorf("Idempotencia.BorrarVencidas: %v", res.Error)
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestReservaAbandonadaLaTomaUnReintento(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Usuario{}, &model.ClaveIdempotencia{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewIdempotenciaController(logging.NewLoggerMock(), db)
+
+	usuario := &model.Usuario{Nombre: "Eva", TipoDocumento: "DNI", NumDocumento: "11223344", Correo: "eva@example.com"}
+	if err := db.Create(usuario).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+	ahora := time.Now()
+	nueva := func(hash string) *model.ClaveIdempotencia {
+		return &model.ClaveIdempotencia{
+			UsuarioID: usuario.ID, Clave: "k-1", Ruta: "POST /api/orden/hold", HashPeticion: []byte(hash),
+			FechaCreacion: ahora, FechaBloqueo: ahora, FechaExpiracion: ahora.Add(24 * time.Hour),
+		}
+	}
+
+	if _, creada, err := repo.Reservar(nueva("a"), ahora, time.Minute); err != nil || !creada {
+		t.Fatalf("la primera reserva debió crearse: %v, %v", creada, err)
+	}
+	if _, creada, err := repo.Reservar(nueva("a"), ahora.Add(30*time.Second), time.Minute); err != nil || creada {
+		t.Fatalf("con la original en curso el reintento no debió tomarla: %v, %v", creada, err)
+	}
+	// Pasado el bloqueo la original se da por abandonada, pero solo para la misma petición
+	if _, creada, err := repo.Reservar(nueva("b"), ahora.Add(2*time.Minute), time.Minute); err != nil || creada {
+		t.Fatalf("otra petición con la misma clave no debió tomarla: %v, %v", creada, err)
+	}
+	if _, creada, err := repo.Reservar(nueva("a"), ahora.Add(2*time.Minute), time.Minute); err != nil || !creada {
+		t.Fatalf("el reintento debió tomar la reserva abandonada: %v, %v", creada, err)
+	}
+	if _, creada, err := repo.Reservar(nueva("a"), ahora.Add(2*time.Minute), time.Minute); err != nil || creada {
+		t.Fatalf("la reserva recién tomada no debió pasar a otro reintento: %v, %v", creada, err)
+	}
+}
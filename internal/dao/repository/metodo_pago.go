@@ -127,6 +127,19 @@ func (r *MetodoDePago) VerificarMetodoDePagoActivo(id int64) (bool, error) {
 	return count == 1, nil
 }
 
+// ObtenerOCrearPorTipo devuelve el método de pago activo de un tipo (Tarjeta, Yape), creándolo si aún no existe.
+func (r *MetodoDePago) ObtenerOCrearPorTipo(tipo util.TipoMetodoPago) (*model.MetodoDePago, error) {
+	var m model.MetodoDePago
+	err := r.PostgresqlDB.
+		Where(model.MetodoDePago{Tipo: string(tipo), Estado: 1}).
+		FirstOrCreate(&m).Error
+	if err != nil {
+		r.logger.Errorf("ObtenerOCrearPorTipo(%s): %v", tipo, err)
+		return nil, err
+	}
+	return &m, nil
+}
+
 // Obtiene 'tipo' (p.ej., 'Tarjeta' | 'Yape') para responder el campo "metodoPago"
 func (r *MetodoDePago) ObtenerTipoDeMetodoPago(id int64) (string, error) {
 	var tipo string
@@ -1,12 +1,225 @@
 package repository
 
 import (
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
 	"github.com/Nexivent/nexivent-backend/logging"
 	"gorm.io/gorm"
-	// "github.com/Loui27/nexivent-backend/internal/dao/model"
 )
 
 type Notificacion struct {
 	logger       logging.Logger
 	PostgresqlDB *gorm.DB
 }
+
+func NewNotificacionController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *Notificacion {
+	return &Notificacion{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+// ReclamarPendientes toma hasta lote notificaciones PENDIENTES cuyo próximo intento ya venció
+// (ver reclamarVencidos).
+func (r *Notificacion) ReclamarPendientes(ahora time.Time, plazo time.Duration, lote int) ([]model.Notificacion, error) {
+	var notificaciones []model.Notificacion
+	err := reclamarVencidos(r.PostgresqlDB, "notificacion", "notificacion_id", "estado_notificacion",
+		util.NotificacionPendiente.Codigo(), ahora, plazo, lote, &notificaciones)
+	if err != nil {
+		r.logger.Errorf("Notificacion.ReclamarPendientes: %v", err)
+		return nil, err
+	}
+	return notificaciones, nil
+}
+
+// MarcarEnviada registra el envío exitoso.
+func (r *Notificacion) MarcarEnviada(id int64, ahora time.Time) error {
+	err := r.PostgresqlDB.
+		Model(&model.Notificacion{}).
+		Where("notificacion_id = ?", id).
+		Updates(map[string]any{
+			"estado_notificacion": util.NotificacionEnviada.Codigo(),
+			"fecha_envio":         ahora,
+			"intentos":            gorm.Expr("intentos + 1"),
+			"proximo_intento":     nil,
+			"ultimo_error":        nil,
+		}).Error
+	if err != nil {
+		r.logger.Errorf("Notificacion.MarcarEnviada(%d): %v", id, err)
+	}
+	return err
+}
+
+// RegistrarFallo anota un intento fallido. Con proximo se reintenta en esa fecha; sin él la
+// notificación queda NO_ENVIADO.
+func (r *Notificacion) RegistrarFallo(id int64, causa string, proximo *time.Time, ahora time.Time) error {
+	estado := util.NotificacionPendiente
+	if proximo == nil {
+		estado = util.NotificacionNoEnviada
+	}
+	err := r.PostgresqlDB.
+		Model(&model.Notificacion{}).
+		Where("notificacion_id = ?", id).
+		Updates(map[string]any{
+			"estado_notificacion": estado.Codigo(),
+			"fecha_envio":         ahora,
+			"intentos":            gorm.Expr("intentos + 1"),
+			"proximo_intento":     proximo,
+			"ultimo_error":        causa,
+		}).Error
+	if err != nil {
+		r.logger.Errorf("Notificacion.RegistrarFallo(%d): %v", id, err)
+	}
+	return err
+}
+
+// ListarPorOrden devuelve las notificaciones de una orden, de la más reciente a la más antigua.
+func (r *Notificacion) ListarPorOrden(orderID int64) ([]model.Notificacion, error) {
+	var notificaciones []model.Notificacion
+	if err := r.PostgresqlDB.
+		Where("orden_de_compra_id = ?", orderID).
+		Order("notificacion_id DESC").
+		Find(&notificaciones).Error; err != nil {
+		r.logger.Errorf("Notificacion.ListarPorOrden(%d): %v", orderID, err)
+		return nil, err
+	}
+	return notificaciones, nil
+}
+
+// ListarFallidas devuelve las notificaciones que agotaron sus intentos (NO_ENVIADO), de la más
+// reciente a la más antigua.
+func (r *Notificacion) ListarFallidas(limite int) ([]model.Notificacion, error) {
+	var notificaciones []model.Notificacion
+	if err := r.PostgresqlDB.
+		Where("estado_notificacion = ?", util.NotificacionNoEnviada.Codigo()).
+		Order("notificacion_id DESC").
+		Limit(limite).
+		Find(&notificaciones).Error; err != nil {
+		r.logger.Errorf("Notificacion.ListarFallidas: %v", err)
+		return nil, err
+	}
+	return notificaciones, nil
+}
+
+// Reintentar devuelve una notificación NO_ENVIADO a PENDIENTE con los intentos en cero.
+// Devuelve false si no existe o no estaba NO_ENVIADO.
+func (r *Notificacion) Reintentar(id int64, ahora time.Time) (bool, error) {
+	res := r.PostgresqlDB.
+		Model(&model.Notificacion{}).
+		Where("notificacion_id = ? AND estado_notificacion = ?", id, util.NotificacionNoEnviada.Codigo()).
+		Updates(map[string]any{
+			"estado_notificacion": util.NotificacionPendiente.Codigo(),
+			"intentos":            0,
+			"proximo_intento":     ahora,
+		})
+	if res.Error != nil {
+		r.logger.Errorf("Notificacion.Reintentar(%d): %v", id, res.Error)
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// bandeja filtra las notificaciones in-app ya entregadas al usuario.
+func bandeja(db *gorm.DB, usuarioID int64) *gorm.DB {
+	return db.
+		Model(&model.Notificacion{}).
+		Where("usuario_id = ? AND canal = ? AND estado_notificacion = ?",
+			usuarioID, model.CanalInApp, util.NotificacionEnviada.Codigo())
+}
+
+// ListarBandeja devuelve hasta limite notificaciones de la bandeja del usuario, de la más
+// reciente a la más antigua, con id menor que antesDe (0 para empezar por la última).
+func (r *Notificacion) ListarBandeja(usuarioID, antesDe int64, soloNoLeidas bool, limite int) ([]model.Notificacion, error) {
+	q := bandeja(r.PostgresqlDB, usuarioID)
+	if antesDe > 0 {
+		q = q.Where("notificacion_id < ?", antesDe)
+	}
+	if soloNoLeidas {
+		q = q.Where("NOT leida")
+	}
+	var notificaciones []model.Notificacion
+	if err := q.
+		Order("notificacion_id DESC").
+		Limit(limite).
+		Find(&notificaciones).Error; err != nil {
+		r.logger.Errorf("Notificacion.ListarBandeja(%d): %v", usuarioID, err)
+		return nil, err
+	}
+	return notificaciones, nil
+}
+
+// NuevasEnBandeja devuelve, de la más antigua a la más reciente, las notificaciones de la
+// bandeja con id mayor que despuesDe.
+func (r *Notificacion) NuevasEnBandeja(usuarioID, despuesDe int64, limite int) ([]model.Notificacion, error) {
+	var notificaciones []model.Notificacion
+	if err := bandeja(r.PostgresqlDB, usuarioID).
+		Where("notificacion_id > ?", despuesDe).
+		Order("notificacion_id").
+		Limit(limite).
+		Find(&notificaciones).Error; err != nil {
+		r.logger.Errorf("Notificacion.NuevasEnBandeja(%d): %v", usuarioID, err)
+		return nil, err
+	}
+	return notificaciones, nil
+}
+
+// UltimaDeBandeja devuelve el id de la notificación más reciente de la bandeja, o 0 si está
+// vacía.
+func (r *Notificacion) UltimaDeBandeja(usuarioID int64) (int64, error) {
+	var id int64
+	if err := bandeja(r.PostgresqlDB, usuarioID).
+		Select("COALESCE(MAX(notificacion_id), 0)").
+		Scan(&id).Error; err != nil {
+		r.logger.Errorf("Notificacion.UltimaDeBandeja(%d): %v", usuarioID, err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// ContarNoLeidas cuenta las notificaciones de la bandeja que el usuario no leyó.
+func (r *Notificacion) ContarNoLeidas(usuarioID int64) (int64, error) {
+	var total int64
+	if err := bandeja(r.PostgresqlDB, usuarioID).
+		Where("NOT leida").
+		Count(&total).Error; err != nil {
+		r.logger.Errorf("Notificacion.ContarNoLeidas(%d): %v", usuarioID, err)
+		return 0, err
+	}
+	return total, nil
+}
+
+// MarcarLeida marca como leída una notificación de la bandeja del usuario. Devuelve false si
+// no existe o no es suya; marcarla otra vez no cambia la fecha de lectura.
+func (r *Notificacion) MarcarLeida(id, usuarioID int64, ahora time.Time) (bool, error) {
+	res := bandeja(r.PostgresqlDB, usuarioID).
+		Where("notificacion_id = ?", id).
+		Updates(map[string]any{
+			"leida":         true,
+			"fecha_lectura": gorm.Expr("COALESCE(fecha_lectura, ?)", ahora),
+		})
+	if res.Error != nil {
+		r.logger.Errorf("Notificacion.MarcarLeida(%d): %v", id, res.Error)
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// MarcarTodasLeidas marca como leída toda la bandeja del usuario y devuelve cuántas cambiaron.
+func (r *Notificacion) MarcarTodasLeidas(usuarioID int64, ahora time.Time) (int64, error) {
+	res := bandeja(r.PostgresqlDB, usuarioID).
+		Where("NOT leida").
+		Updates(map[string]any{
+			"leida":         true,
+			"fecha_lectura": ahora,
+		})
+	if res.Error != nil {
+		r.logger.Errorf("Notificacion.MarcarTodasLeidas(%d): %v", usuarioID, res.Error)
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
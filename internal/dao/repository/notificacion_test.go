@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestNotificacionReclamarReintentarYEnviar(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Notificacion{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewNotificacionController(logging.NewLoggerMock(), db)
+
+	ahora := time.Now().Truncate(time.Microsecond)
+	var orden int64 = 7
+	if err := db.Create(&model.Notificacion{
+		Mensaje:            "Confirmación de la orden 7",
+		Canal:              model.CanalCorreo,
+		Destino:            "comprador@example.com",
+		FechaEnvio:         ahora,
+		EstadoNotificacion: util.NotificacionPendiente.Codigo(),
+		Plantilla:          model.PlantillaOrdenConfirmada,
+		OrdenDeCompraID:    &orden,
+		ProximoIntento:     &ahora,
+		FechaCreacion:      ahora,
+	}).Error; err != nil {
+		t.Fatalf("crear notificación: %v", err)
+	}
+
+	reclamadas, err := repo.ReclamarPendientes(ahora, 5*time.Minute, 10)
+	if err != nil || len(reclamadas) != 1 {
+		t.Fatalf("se esperaba reclamar 1 notificación, se obtuvo %d (%v)", len(reclamadas), err)
+	}
+	n := reclamadas[0]
+	if otra, _ := repo.ReclamarPendientes(ahora, 5*time.Minute, 10); len(otra) != 0 {
+		t.Fatalf("una notificación reclamada no debe volver a tomarse dentro del plazo")
+	}
+
+	proximo := ahora.Add(time.Minute)
+	if err := repo.RegistrarFallo(n.ID, "smtp caído", &proximo, ahora); err != nil {
+		t.Fatalf("RegistrarFallo: %v", err)
+	}
+	if antes, _ := repo.ReclamarPendientes(ahora, 5*time.Minute, 10); len(antes) != 0 {
+		t.Fatalf("no debe reintentarse antes del próximo intento")
+	}
+	reintento, err := repo.ReclamarPendientes(proximo, 5*time.Minute, 10)
+	if err != nil || len(reintento) != 1 || reintento[0].Intentos != 1 {
+		t.Fatalf("se esperaba reintentar la notificación con 1 intento, se obtuvo %+v (%v)", reintento, err)
+	}
+
+	if err := repo.MarcarEnviada(n.ID, proximo); err != nil {
+		t.Fatalf("MarcarEnviada: %v", err)
+	}
+	notificaciones, err := repo.ListarPorOrden(7)
+	if err != nil || len(notificaciones) != 1 {
+		t.Fatalf("ListarPorOrden: %d (%v)", len(notificaciones), err)
+	}
+	if got := notificaciones[0]; got.EstadoNotificacion != util.NotificacionEnviada.Codigo() || got.Intentos != 2 || got.ProximoIntento != nil {
+		t.Fatalf("estado final inesperado: %+v", got)
+	}
+}
+
+func TestNotificacionBandejaPaginaYMarcaLeidas(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Notificacion{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewNotificacionController(logging.NewLoggerMock(), db)
+
+	ahora := time.Now().Truncate(time.Microsecond)
+	var usuario, otro int64 = 4, 9
+	crear := func(usuarioID *int64, canal string, estado int16) int64 {
+		n := model.Notificacion{
+			Mensaje:            "aviso",
+			Canal:              canal,
+			FechaEnvio:         ahora,
+			EstadoNotificacion: estado,
+			Tipo:               model.EventoEventoCancelado,
+			UsuarioID:          usuarioID,
+			FechaCreacion:      ahora,
+		}
+		if err := db.Create(&n).Error; err != nil {
+			t.Fatalf("crear notificación: %v", err)
+		}
+		return n.ID
+	}
+	enviada := util.NotificacionEnviada.Codigo()
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		ids = append(ids, crear(&usuario, model.CanalInApp, enviada))
+	}
+	// Nada de esto es parte de la bandeja del usuario
+	crear(&usuario, model.CanalCorreo, enviada)
+	crear(&usuario, model.CanalInApp, util.NotificacionPendiente.Codigo())
+	ajena := crear(&otro, model.CanalInApp, enviada)
+
+	pagina, err := repo.ListarBandeja(usuario, 0, false, 2)
+	if err != nil || len(pagina) != 2 || pagina[0].ID != ids[2] || pagina[1].ID != ids[1] {
+		t.Fatalf("primera página inesperada: %+v (%v)", pagina, err)
+	}
+	pagina, err = repo.ListarBandeja(usuario, pagina[1].ID, false, 2)
+	if err != nil || len(pagina) != 1 || pagina[0].ID != ids[0] {
+		t.Fatalf("segunda página inesperada: %+v (%v)", pagina, err)
+	}
+
+	if ok, err := repo.MarcarLeida(ajena, usuario, ahora); err != nil || ok {
+		t.Fatalf("no se debe poder marcar una notificación ajena (ok=%v, %v)", ok, err)
+	}
+	if ok, err := repo.MarcarLeida(ids[1], usuario, ahora); err != nil || !ok {
+		t.Fatalf("MarcarLeida: ok=%v, %v", ok, err)
+	}
+	if total, _ := repo.ContarNoLeidas(usuario); total != 2 {
+		t.Fatalf("se esperaban 2 no leídas, hay %d", total)
+	}
+	if noLeidas, _ := repo.ListarBandeja(usuario, 0, true, 10); len(noLeidas) != 2 {
+		t.Fatalf("se esperaban 2 no leídas en la bandeja, hay %d", len(noLeidas))
+	}
+	if marcadas, err := repo.MarcarTodasLeidas(usuario, ahora); err != nil || marcadas != 2 {
+		t.Fatalf("MarcarTodasLeidas: %d (%v)", marcadas, err)
+	}
+	if total, _ := repo.ContarNoLeidas(usuario); total != 0 {
+		t.Fatalf("no deberían quedar no leídas, hay %d", total)
+	}
+	if total, _ := repo.ContarNoLeidas(otro); total != 1 {
+		t.Fatalf("la bandeja de otro usuario no debe cambiar, tiene %d no leídas", total)
+	}
+
+	if ultima, _ := repo.UltimaDeBandeja(usuario); ultima != ids[2] {
+		t.Fatalf("UltimaDeBandeja = %d, se esperaba %d", ultima, ids[2])
+	}
+	if nuevas, _ := repo.NuevasEnBandeja(usuario, ids[0], 10); len(nuevas) != 2 || nuevas[0].ID != ids[1] {
+		t.Fatalf("NuevasEnBandeja inesperado: %+v", nuevas)
+	}
+}
@@ -1,7 +1,11 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/Nexivent/nexivent-backend/internal/dao/model"
@@ -54,6 +58,277 @@ func (c *OrdenDeCompra) CrearOrdenTemporal(orden *model.OrdenDeCompra) error {
 	return nil
 }
 
+var (
+	// ErrStockInsuficiente se devuelve cuando algún sector no tiene entradas suficientes para el hold.
+	ErrStockInsuficiente = errors.New("stock insuficiente")
+	// ErrOrdenNoTemporal se devuelve al intentar liberar o confirmar una orden que ya no está en TEMPORAL.
+	ErrOrdenNoTemporal = errors.New("la orden no está en estado TEMPORAL")
+	// ErrOrdenYaConfirmada se devuelve al confirmar otra vez una orden con el pago que la confirmó.
+	ErrOrdenYaConfirmada = errors.New("la orden ya está confirmada")
+	// ErrOrdenPagadaConOtroPago se devuelve al confirmar con un pago una orden que ya confirmó otro:
+	// el comprador pagó dos veces y este cobro hay que devolverlo.
+	ErrOrdenPagadaConOtroPago = errors.New("la orden ya se confirmó con otro pago")
+	// ErrEventoNoALaVenta se devuelve al reservar entradas de un evento que no está PUBLICADO y activo.
+	ErrEventoNoALaVenta = errors.New("el evento no está a la venta")
+	// ErrCuponAgotado se devuelve cuando el usuario ya usó el cupón todas las veces permitidas.
+	ErrCuponAgotado = errors.New("el usuario agotó los usos del cupón")
+)
+
+// consumirCupon suma un uso del cupón de la orden al usuario. El límite uso_por_usuario va en la
+// condición del UPDATE, así dos holds simultáneos con el mismo cupón no pueden pasar ambos del
+// límite: si no se actualizó la fila devuelve ErrCuponAgotado.
+func consumirCupon(tx *gorm.DB, orden *model.OrdenDeCompra) error {
+	if orden.CuponID == nil {
+		return nil
+	}
+	uso := model.UsuarioCupon{CuponID: *orden.CuponID, UsuarioID: orden.UsuarioID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&uso).Error; err != nil {
+		return err
+	}
+	res := tx.Model(&model.UsuarioCupon{}).
+		Where("cupon_id = ? AND usuario_id = ?", *orden.CuponID, orden.UsuarioID).
+		Where("cant_usada < (SELECT uso_por_usuario FROM cupon WHERE cupon_id = ?)", *orden.CuponID).
+		UpdateColumn("cant_usada", gorm.Expr("cant_usada + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrCuponAgotado
+	}
+	return tx.Model(&model.Cupon{}).
+		Where("cupon_id = ?", *orden.CuponID).
+		UpdateColumn("uso_realizados", gorm.Expr("uso_realizados + 1")).Error
+}
+
+// devolverCupon deshace consumirCupon cuando la orden se cancela o su hold vence.
+func devolverCupon(tx *gorm.DB, orden *model.OrdenDeCompra) error {
+	if orden.CuponID == nil {
+		return nil
+	}
+	if err := tx.Model(&model.UsuarioCupon{}).
+		Where("cupon_id = ? AND usuario_id = ?", *orden.CuponID, orden.UsuarioID).
+		UpdateColumn("cant_usada", gorm.Expr("GREATEST(cant_usada - 1, 0)")).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.Cupon{}).
+		Where("cupon_id = ?", *orden.CuponID).
+		UpdateColumn("uso_realizados", gorm.Expr("GREATEST(uso_realizados - 1, 0)")).Error
+}
+
+// exigirEventosALaVenta bloquea FOR SHARE los eventos que cumplan el filtro y devuelve
+// ErrEventoNoALaVenta si alguno no está PUBLICADO y activo (o si no hay ninguno). El lock
+// compartido hace que una cancelación en curso espere a que termine el hold (y lo cancele
+// después) o que el hold vea el evento ya cancelado.
+func exigirEventosALaVenta(tx *gorm.DB, filtro string, args ...any) error {
+	var eventos []model.Evento
+	if err := tx.
+		Clauses(clause.Locking{Strength: "SHARE"}).
+		Select("evento_id", "evento_estado", "estado").
+		Where(filtro, args...).
+		Order("evento_id").
+		Find(&eventos).Error; err != nil {
+		return err
+	}
+	if len(eventos) == 0 {
+		return ErrEventoNoALaVenta
+	}
+	for _, ev := range eventos {
+		if ev.EventoEstado != util.EventoPublicado.Codigo() || ev.Estado != util.Activo.Codigo() {
+			return fmt.Errorf("evento %d: %w", ev.ID, ErrEventoNoALaVenta)
+		}
+	}
+	return nil
+}
+
+// exigirFechasALaVenta bloquea FOR SHARE las fechas de los detalles y devuelve
+// ErrEventoNoALaVenta si alguna ya no está activa, por ejemplo porque se reprogramó.
+func exigirFechasALaVenta(tx *gorm.DB, detalles []model.OrdenDeCompraDetalle) error {
+	ids := make([]int64, 0, len(detalles))
+	for _, d := range detalles {
+		ids = append(ids, d.EventoFechaID)
+	}
+	var fechas []model.EventoFecha
+	if err := tx.
+		Clauses(clause.Locking{Strength: "SHARE"}).
+		Select("evento_fecha_id", "estado").
+		Where("evento_fecha_id IN ?", ids).
+		Order("evento_fecha_id").
+		Find(&fechas).Error; err != nil {
+		return err
+	}
+	for _, f := range fechas {
+		if f.Estado != util.Activo.Codigo() {
+			return fmt.Errorf("fecha %d: %w", f.ID, ErrEventoNoALaVenta)
+		}
+	}
+	return nil
+}
+
+// cantidadesPorSector agrupa las cantidades de los detalles por sector y devuelve los sectores
+// ordenados por sector_id: bloquear siempre en el mismo orden evita deadlocks entre transacciones.
+func cantidadesPorSector(detalles []model.OrdenDeCompraDetalle) ([]int64, map[int64]int64) {
+	porSector := map[int64]int64{}
+	for _, d := range detalles {
+		porSector[d.SectorID] += d.Cantidad
+	}
+	sectorIDs := make([]int64, 0, len(porSector))
+	for id := range porSector {
+		sectorIDs = append(sectorIDs, id)
+	}
+	sort.Slice(sectorIDs, func(i, j int) bool { return sectorIDs[i] < sectorIDs[j] })
+	return sectorIDs, porSector
+}
+
+// CrearOrdenTemporalConReserva reserva el stock de los sectores de orden.Detalles y crea la orden
+// temporal con sus detalles en una sola transacción: o se reserva todo, o no se reserva nada.
+//
+// Cada sector se actualiza con un UPDATE condicional (cant_vendidas + n <= total_entradas).
+// Postgres toma el lock de la fila y vuelve a evaluar la condición al obtenerlo, así dos
+// compradores concurrentes no pueden pasar ambos la validación y sobrevender el sector. Con el
+// uso del cupón de la orden pasa lo mismo (ver consumirCupon).
+func (c *OrdenDeCompra) CrearOrdenTemporalConReserva(orden *model.OrdenDeCompra) error {
+	if orden == nil || len(orden.Detalles) == 0 {
+		return gorm.ErrInvalidData
+	}
+	for _, d := range orden.Detalles {
+		if d.SectorID <= 0 || d.Cantidad <= 0 {
+			return gorm.ErrInvalidData
+		}
+	}
+	sectorIDs, porSector := cantidadesPorSector(orden.Detalles)
+
+	return c.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := exigirEventosALaVenta(tx,
+			"evento_id IN (SELECT evento_id FROM sector WHERE sector_id IN ?)", sectorIDs); err != nil {
+			return err
+		}
+		if err := exigirFechasALaVenta(tx, orden.Detalles); err != nil {
+			return err
+		}
+
+		for _, sectorID := range sectorIDs {
+			cantidad := porSector[sectorID]
+			res := tx.Model(&model.Sector{}).
+				Where("sector_id = ? AND cant_vendidas + ? <= total_entradas", sectorID, cantidad).
+				UpdateColumn("cant_vendidas", gorm.Expr("cant_vendidas + ?", cantidad))
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return fmt.Errorf("sector %d: %w", sectorID, ErrStockInsuficiente)
+			}
+		}
+
+		if err := consumirCupon(tx, orden); err != nil {
+			return err
+		}
+
+		// Create inserta también orden.Detalles
+		orden.EstadoDeOrden = util.OrdenTemporal.Codigo()
+		return tx.Create(orden).Error
+	})
+}
+
+// CancelarOrdenTemporal pasa una orden TEMPORAL a CANCELADA y devuelve a cada sector las entradas
+// de sus detalles, en una sola transacción. La orden se bloquea con FOR UPDATE, así una orden que
+// se confirma o se libera en paralelo no devuelve stock dos veces (se obtiene ErrOrdenNoTemporal).
+func (c *OrdenDeCompra) CancelarOrdenTemporal(orderID int64) error {
+	return c.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		return cancelarOrdenTemporalTx(tx, orderID)
+	})
+}
+
+// cancelarOrdenTemporalTx es CancelarOrdenTemporal dentro de una transacción ya abierta.
+func cancelarOrdenTemporalTx(tx *gorm.DB, orderID int64) error {
+	var orden model.OrdenDeCompra
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&orden, "orden_de_compra_id = ?", orderID).Error; err != nil {
+		return err
+	}
+	if orden.EstadoDeOrden != util.OrdenTemporal.Codigo() {
+		return ErrOrdenNoTemporal
+	}
+
+	// Si era la compra de una reventa, la publicación vuelve a estar disponible
+	if err := liberarReventaDeOrden(tx, orderID); err != nil {
+		return err
+	}
+	if err := devolverCupon(tx, &orden); err != nil {
+		return err
+	}
+
+	var detalles []model.OrdenDeCompraDetalle
+	if err := tx.Where("orden_de_compra_id = ?", orderID).Find(&detalles).Error; err != nil {
+		return err
+	}
+
+	sectorIDs, porSector := cantidadesPorSector(detalles)
+	for _, sectorID := range sectorIDs {
+		res := tx.Model(&model.Sector{}).
+			Where("sector_id = ?", sectorID).
+			UpdateColumn("cant_vendidas", gorm.Expr("GREATEST(cant_vendidas - ?, 0)", porSector[sectorID]))
+		if res.Error != nil {
+			return res.Error
+		}
+	}
+
+	return tx.Model(&model.OrdenDeCompra{}).
+		Where("orden_de_compra_id = ?", orderID).
+		Update("estado_de_orden", util.OrdenCancelada.Codigo()).Error
+}
+
+// lockLiberadorHolds es la clave del advisory lock que deja un solo liberador de holds activo entre réplicas.
+const lockLiberadorHolds int64 = 7_270_001
+
+// ListarOrdenesTemporalesVencidas devuelve hasta limite órdenes TEMPORAL cuyo fecha_hora_fin ya pasó,
+// las más antiguas primero.
+func (c *OrdenDeCompra) ListarOrdenesTemporalesVencidas(ahora time.Time, limite int) ([]int64, error) {
+	var ids []int64
+	res := c.PostgresqlDB.
+		Model(&model.OrdenDeCompra{}).
+		Where("estado_de_orden = ?", util.OrdenTemporal.Codigo()).
+		Where("fecha_hora_fin < ?", ahora).
+		Order("fecha_hora_fin").
+		Limit(limite).
+		Pluck("orden_de_compra_id", &ids)
+	if res.Error != nil {
+		c.logger.Errorf("ListarOrdenesTemporalesVencidas: %v", res.Error)
+		return nil, res.Error
+	}
+	return ids, nil
+}
+
+// ConLockLiberadorHolds ejecuta fn solo si esta réplica obtiene el advisory lock del liberador de holds;
+// si otra réplica lo tiene, no hace nada y devuelve false. El lock es de sesión, así que se toma y se
+// suelta sobre una misma conexión del pool (si la conexión se cae, Postgres lo libera solo).
+func (c *OrdenDeCompra) ConLockLiberadorHolds(ctx context.Context, fn func() error) (bool, error) {
+	sqlDB, err := c.PostgresqlDB.DB()
+	if err != nil {
+		return false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var obtenido bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockLiberadorHolds).Scan(&obtenido); err != nil {
+		return false, err
+	}
+	if !obtenido {
+		return false, nil
+	}
+	defer func() {
+		// Sin ctx: el lock se suelta aunque el servicio se esté apagando
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockLiberadorHolds); err != nil {
+			c.logger.Errorf("ConLockLiberadorHolds.Unlock: %v", err)
+		}
+	}()
+
+	return true, fn()
+}
+
 // ObtenerOrdenBasica trae una orden completa por ID.
 func (c *OrdenDeCompra) ObtenerOrdenBasica(orderID int64) (*model.OrdenDeCompra, error) {
 	var o model.OrdenDeCompra
@@ -146,17 +421,21 @@ func (o *OrdenDeCompra) ObtenerIngresoCargoPorFecha(eventoID int64, fechaDesde *
 	}
 	var data IngresoCargoDTO
 
+	// Entradas por orden para este evento (desde el detalle, una fila por orden)
+	detallesEvento := o.PostgresqlDB.Table("orden_de_compra_detalle d").
+		Select("d.orden_de_compra_id, SUM(d.cantidad) AS cantidad").
+		Joins("JOIN evento_fecha ef ON ef.evento_fecha_id = d.evento_fecha_id").
+		Where("ef.evento_id = ?", eventoID).
+		Group("d.orden_de_compra_id")
+
 	query := o.PostgresqlDB.Table("orden_de_compra oc").
 		Select(`
             COALESCE(SUM(oc.total), 0) AS ingreso_total,
             COALESCE(SUM(oc.monto_fee_servicio), 0) AS cargo_serv,
-            COUNT(t.ticket_id) AS tickets_vendidos
+            COALESCE(SUM(d.cantidad), 0) AS tickets_vendidos
         `).
-		Joins("JOIN ticket t ON t.orden_de_compra_id = oc.orden_de_compra_id").
-		Joins("JOIN evento_fecha ef ON ef.evento_fecha_id = t.evento_fecha_id").
-		Where("ef.evento_id = ?", eventoID).
-		Where("oc.estado_de_orden = ?", util.OrdenConfirmada.Codigo()).
-		Where("t.estado_de_ticket = ?", util.TicketVendido.Codigo())
+		Joins("JOIN (?) d ON d.orden_de_compra_id = oc.orden_de_compra_id", detallesEvento).
+		Where("oc.estado_de_orden = ?", util.OrdenConfirmada.Codigo())
 
 	if fechaDesde != nil {
 		query = query.Where("oc.fecha BETWEEN ? AND ?", fechaDesde, fechaHasta)
@@ -192,15 +471,13 @@ func (o *OrdenDeCompra) ObtenerVentasPorSector(eventoID int64, fechaDesde *time.
 		Select(`
 			s.sector_tipo AS tipo_sector,
 			s.total_entradas as capacidad,
-			COUNT(t.ticket_id) AS tickets_vendidos,
-			COALESCE(SUM(tf.precio), 0) AS ingresos
+			COALESCE(SUM(d.cantidad), 0) AS tickets_vendidos,
+			COALESCE(SUM(d.cantidad * d.precio_unitario - d.descuento), 0) AS ingresos
 		`).
-		Joins("JOIN tarifa tf ON tf.sector_id = s.sector_id").
-		Joins("JOIN ticket t ON t.tarifa_id = tf.tarifa_id").
-		Joins("JOIN orden_de_compra oc ON oc.orden_de_compra_id = t.orden_de_compra_id").
+		Joins("JOIN orden_de_compra_detalle d ON d.sector_id = s.sector_id").
+		Joins("JOIN orden_de_compra oc ON oc.orden_de_compra_id = d.orden_de_compra_id").
 		Where("s.evento_id = ?", eventoID).
 		Where("oc.estado_de_orden = ?", util.OrdenConfirmada.Codigo()).
-		Where("t.estado_de_ticket = ?", util.TicketVendido.Codigo()).
 		Group("s.sector_tipo, s.total_entradas")
 
 	if fechaDesde != nil {
@@ -217,23 +494,184 @@ func (o *OrdenDeCompra) ObtenerVentasPorSector(eventoID int64, fechaDesde *time.
 	return data, nil
 }
 
-func (c *OrdenDeCompra) ConfirmarOrdenConPago(orderID int64, metodoPagoID int64, paymentReference string) error {
-	updates := map[string]interface{}{
-		"estado_de_orden":   util.OrdenConfirmada.Codigo(),
-		"metodo_de_pago_id": metodoPagoID,
+// ConfirmarOrdenPagada pasa la orden de TEMPORAL a CONFIRMADA por un pago capturado, suma la venta
+// a los acumulados del evento y de la fecha y emite los tickets de sus detalles con QR firmados con
+// firmar, todo en una sola transacción: la confirme el comprador o el webhook, la orden nunca queda
+// pagada sin tickets. Si la orden es la compra de una reventa, en lugar de sumar la venta y emitir
+// le entrega el ticket revendido al comprador. Si el evento se canceló mientras se pagaba, la orden
+// se confirma sin tickets y el ciclo de vida de eventos le reembolsa todo lo cobrado.
+// Devuelve ErrOrdenYaConfirmada si la orden ya estaba confirmada con este pago (webhook repetido,
+// confirmación doble), ErrOrdenPagadaConOtroPago si la confirmó otro pago (el comprador abrió dos
+// intentos y pagó ambos; este pago queda PENDIENTE para que se devuelva)
+// y ErrOrdenNoTemporal si la orden se canceló o venció antes del pago, o si el vendedor de la
+// reventa ya no puede entregar el ticket (en ese caso la orden queda CANCELADA).
+func (c *OrdenDeCompra) ConfirmarOrdenPagada(
+	orderID int64,
+	pagoID int64,
+	metodoPagoID int64,
+	firmar func(*model.Ticket) (string, error),
+) (*model.OrdenDeCompra, error) {
+	var orden model.OrdenDeCompra
+	var rechazo error
+	err := c.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&orden, "orden_de_compra_id = ?", orderID).Error; err != nil {
+			return err
+		}
+		switch orden.EstadoDeOrden {
+		case util.OrdenConfirmada.Codigo():
+			// Al confirmar, el pago queda CAPTURADO en esta misma transacción
+			var estadoPago int16
+			if err := tx.Model(&model.Pago{}).
+				Select("estado_de_pago").
+				Where("pago_id = ?", pagoID).
+				Scan(&estadoPago).Error; err != nil {
+				return err
+			}
+			if estadoPago == util.PagoCapturado.Codigo() || estadoPago == util.PagoReembolsado.Codigo() {
+				return ErrOrdenYaConfirmada
+			}
+			return ErrOrdenPagadaConOtroPago
+		case util.OrdenTemporal.Codigo():
+		default:
+			return ErrOrdenNoTemporal
+		}
+
+		esReventa, vendida, err := completarReventaDeOrden(tx, &orden, firmar, time.Now())
+		if err != nil {
+			return err
+		}
+		if esReventa && !vendida {
+			rechazo = ErrOrdenNoTemporal
+			return tx.Model(&model.OrdenDeCompra{}).
+				Where("orden_de_compra_id = ?", orderID).
+				Update("estado_de_orden", util.OrdenCancelada.Codigo()).Error
+		}
+
+		if err := tx.Model(&model.OrdenDeCompra{}).
+			Where("orden_de_compra_id = ?", orderID).
+			Updates(map[string]any{
+				"estado_de_orden":   util.OrdenConfirmada.Codigo(),
+				"metodo_de_pago_id": metodoPagoID,
+			}).Error; err != nil {
+			return err
+		}
+		orden.EstadoDeOrden = util.OrdenConfirmada.Codigo()
+		orden.MetodoDePagoID = &metodoPagoID
+
+		if err := tx.Model(&model.Pago{}).
+			Where("pago_id = ?", pagoID).
+			Updates(map[string]any{
+				"estado_de_pago":     util.PagoCapturado.Codigo(),
+				"fecha_modificacion": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		if err := crearComprobanteDeOrden(tx, orden.ID, time.Now()); err != nil {
+			return err
+		}
+
+		if esReventa {
+			// El ticket revendido ya es del comprador: solo falta notificar la orden
+			return registrarOrdenConfirmada(tx, orden.ID, time.Now())
+		}
+		cancelado, err := eventoCanceladoDeOrden(tx, orden.ID)
+		if err != nil {
+			return err
+		}
+		if err := sumarVentaAcumulados(tx, &orden, 1); err != nil {
+			return err
+		}
+		if cancelado {
+			// El reembolso de la orden revierte esta venta
+			return nil
+		}
+		tickets, err := ticketsDeOrden(tx, &orden)
+		if err != nil {
+			return err
+		}
+		if len(tickets) == 0 {
+			return registrarOrdenConfirmada(tx, orden.ID, time.Now())
+		}
+		// Registra también ORDEN_CONFIRMADA, del que sale el correo con los tickets
+		return emitirTicketsDeOrdenTx(tx, &orden, tickets, firmar, time.Now())
+	})
+	if err != nil {
+		if !errors.Is(err, ErrOrdenYaConfirmada) && !errors.Is(err, ErrOrdenPagadaConOtroPago) && !errors.Is(err, ErrOrdenNoTemporal) {
+			c.logger.Errorf("ConfirmarOrdenPagada(%d): %v", orderID, err)
+		}
+		return &orden, err
+	}
+	if rechazo != nil {
+		return &orden, rechazo
+	}
+	return &orden, nil
+}
+
+// sumarVentaAcumulados suma (signo=1) o resta (signo=-1) la venta de la orden en evento.total_recaudado,
+// evento.cant_vendido_total y evento_fecha.ganancia_neta_organizador. La ganancia neta es el total
+// menos el fee de servicio, repartida entre las fechas según el subtotal de cada detalle.
+func sumarVentaAcumulados(tx *gorm.DB, orden *model.OrdenDeCompra, signo float64) error {
+	var detalles []model.OrdenDeCompraDetalle
+	if err := tx.Preload("EventoFecha").
+		Where("orden_de_compra_id = ?", orden.ID).
+		Find(&detalles).Error; err != nil {
+		return err
 	}
 
-	res := c.PostgresqlDB.
-		Table("orden_de_compra").
-		Where("orden_de_compra_id = ?", orderID).
-		Updates(updates)
+	var bruto float64
+	for _, d := range detalles {
+		bruto += d.Subtotal()
+	}
+	neta := orden.Total - orden.MontoFeeServicio
+	if neta < 0 {
+		neta = 0
+	}
 
-	if res.Error != nil {
-		c.logger.Errorf("ConfirmarOrdenConPago(%d): %v", orderID, res.Error)
-		return res.Error
+	netaPorFecha := map[int64]float64{}
+	netaPorEvento := map[int64]float64{}
+	cantidadPorEvento := map[int64]int64{}
+	for _, d := range detalles {
+		if d.EventoFecha == nil {
+			continue
+		}
+		parte := 0.0
+		if bruto > 0 {
+			parte = neta * d.Subtotal() / bruto
+		}
+		netaPorFecha[d.EventoFechaID] += parte
+		netaPorEvento[d.EventoFecha.EventoID] += parte
+		cantidadPorEvento[d.EventoFecha.EventoID] += d.Cantidad
 	}
-	if res.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+
+	return actualizarAcumulados(tx, netaPorEvento, cantidadPorEvento, netaPorFecha, signo)
+}
+
+// actualizarAcumulados suma (signo=1) o resta (signo=-1) los montos netos y las cantidades en
+// evento.total_recaudado, evento.cant_vendido_total y evento_fecha.ganancia_neta_organizador.
+func actualizarAcumulados(
+	tx *gorm.DB,
+	netaPorEvento map[int64]float64,
+	cantidadPorEvento map[int64]int64,
+	netaPorFecha map[int64]float64,
+	signo float64,
+) error {
+	for eventoID, monto := range netaPorEvento {
+		if err := tx.Model(&model.Evento{}).
+			Where("evento_id = ?", eventoID).
+			UpdateColumns(map[string]any{
+				"total_recaudado":    gorm.Expr("total_recaudado + ?", signo*monto),
+				"cant_vendido_total": gorm.Expr("cant_vendido_total + ?", int64(signo)*cantidadPorEvento[eventoID]),
+			}).Error; err != nil {
+			return err
+		}
+	}
+	for eventoFechaID, monto := range netaPorFecha {
+		if err := tx.Model(&model.EventoFecha{}).
+			Where("evento_fecha_id = ?", eventoFechaID).
+			UpdateColumn("ganancia_neta_organizador", gorm.Expr("ganancia_neta_organizador + ?", signo*monto)).Error; err != nil {
+			return err
+		}
 	}
 	return nil
 }
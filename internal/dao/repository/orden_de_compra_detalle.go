@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/Nexivent/nexivent-backend/logging"
+	"gorm.io/gorm"
+)
+
+type OrdenDeCompraDetalle struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewOrdenDeCompraDetalleController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *OrdenDeCompraDetalle {
+	return &OrdenDeCompraDetalle{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+// ListarPorOrden devuelve las líneas de una orden con su sector (para nombre de zona y stock).
+func (c *OrdenDeCompraDetalle) ListarPorOrden(orderID int64) ([]model.OrdenDeCompraDetalle, error) {
+	var detalles []model.OrdenDeCompraDetalle
+	res := c.PostgresqlDB.
+		Preload("Sector").
+		Where("orden_de_compra_id = ?", orderID).
+		Order("orden_de_compra_detalle_id").
+		Find(&detalles)
+	if res.Error != nil {
+		c.logger.Errorf("OrdenDeCompraDetalle.ListarPorOrden(%d): %v", orderID, res.Error)
+		return nil, res.Error
+	}
+	return detalles, nil
+}
@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+// Estas pruebas necesitan un Postgres real. Se activan con, por ejemplo:
+//
+//	NEXIVENT_TEST_POSTGRES_DSN="host=localhost user=postgres password=postgres dbname=nexivent_test port=5432 sslmode=disable" go test ./internal/dao/repository/
+//
+// El DSN debe estar en formato clave=valor. Cada prueba trabaja en un schema temporal que se borra al terminar.
+func abrirBDPrueba(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("NEXIVENT_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("NEXIVENT_TEST_POSTGRES_DSN no configurado, se omite la prueba contra Postgres")
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("no se pudo conectar a Postgres: %v", err)
+	}
+
+	schema := fmt.Sprintf("test_hold_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("no se pudo crear el schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// search_path en el DSN para que aplique a todas las conexiones del pool; public al final para
+	// ver las extensiones de la búsqueda (unaccent, pg_trgm)
+	db, err := gorm.Open(postgres.Open(dsn+" search_path="+schema+",public"), &gorm.Config{
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("no se pudo conectar al schema %s: %v", schema, err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("no se pudo obtener el pool: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(20)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&model.Recinto{}, &model.Evento{}, &model.EventoFecha{}, &model.Sector{}, &model.OrdenDeCompra{}, &model.OrdenDeCompraDetalle{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	return db
+}
+
+func crearEventoPrueba(t *testing.T, db *gorm.DB, estado util.EstadoEvento) *model.Evento {
+	t.Helper()
+	evento := &model.Evento{OrganizadorID: 1, Titulo: "Evento de prueba", EventoEstado: estado.Codigo()}
+	if err := db.Create(evento).Error; err != nil {
+		t.Fatalf("crear evento: %v", err)
+	}
+	return evento
+}
+
+// crearSectorPrueba crea el sector en un evento PUBLICADO propio, para que se pueda reservar.
+func crearSectorPrueba(t *testing.T, db *gorm.DB, tipo string, total int) *model.Sector {
+	t.Helper()
+	evento := crearEventoPrueba(t, db, util.EventoPublicado)
+	sector := &model.Sector{EventoID: evento.ID, SectorTipo: tipo, TotalEntradas: total}
+	if err := db.Create(sector).Error; err != nil {
+		t.Fatalf("crear sector %s: %v", tipo, err)
+	}
+	return sector
+}
+
+func nuevaOrdenPrueba(usuarioID int64, detalles ...model.OrdenDeCompraDetalle) *model.OrdenDeCompra {
+	now := time.Now()
+	fin := now.Add(10 * time.Minute)
+	return &model.OrdenDeCompra{
+		UsuarioID:    usuarioID,
+		Fecha:        now,
+		FechaHoraIni: now,
+		FechaHoraFin: &fin,
+		Total:        10,
+		Detalles:     detalles,
+	}
+}
+
+func detallePrueba(sectorID, cantidad int64) model.OrdenDeCompraDetalle {
+	return model.OrdenDeCompraDetalle{TarifaID: 1, SectorID: sectorID, EventoFechaID: 1, Cantidad: cantidad, PrecioUnitario: 5}
+}
+
+func TestCrearOrdenTemporalConReservaNoSobrevende(t *testing.T) {
+	db := abrirBDPrueba(t)
+	repo := NewOrdenDeCompraController(logging.NewLoggerMock(), db)
+
+	const stock = 50
+	const compradores = 200
+	sector := crearSectorPrueba(t, db, "GENERAL", stock)
+
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		reservadas int64
+		exitosas   int
+	)
+	inicio := make(chan struct{})
+
+	for i := 0; i < compradores; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cantidad := int64(1 + i%3)
+			<-inicio
+
+			err := repo.CrearOrdenTemporalConReserva(nuevaOrdenPrueba(int64(i+1), detallePrueba(sector.ID, cantidad)))
+			if err != nil {
+				if !errors.Is(err, ErrStockInsuficiente) {
+					t.Errorf("hold %d: error inesperado: %v", i, err)
+				}
+				return
+			}
+			mu.Lock()
+			reservadas += cantidad
+			exitosas++
+			mu.Unlock()
+		}(i)
+	}
+	close(inicio)
+	wg.Wait()
+
+	var actualizado model.Sector
+	if err := db.First(&actualizado, "sector_id = ?", sector.ID).Error; err != nil {
+		t.Fatalf("leer sector: %v", err)
+	}
+	if actualizado.CantVendidas > stock {
+		t.Fatalf("sobreventa: cant_vendidas=%d, total_entradas=%d", actualizado.CantVendidas, stock)
+	}
+	if int64(actualizado.CantVendidas) != reservadas {
+		t.Fatalf("cant_vendidas=%d no coincide con lo reservado por los holds exitosos=%d", actualizado.CantVendidas, reservadas)
+	}
+
+	var ordenes int64
+	db.Model(&model.OrdenDeCompra{}).Where("estado_de_orden = ?", util.OrdenTemporal.Codigo()).Count(&ordenes)
+	if ordenes != int64(exitosas) {
+		t.Fatalf("se crearon %d órdenes temporales pero hubo %d holds exitosos", ordenes, exitosas)
+	}
+}
+
+func TestCrearOrdenTemporalConReservaTodoONada(t *testing.T) {
+	db := abrirBDPrueba(t)
+	repo := NewOrdenDeCompraController(logging.NewLoggerMock(), db)
+
+	conStock := crearSectorPrueba(t, db, "PLATEA", 10)
+	agotado := crearSectorPrueba(t, db, "VIP", 1)
+
+	err := repo.CrearOrdenTemporalConReserva(nuevaOrdenPrueba(1,
+		detallePrueba(conStock.ID, 2),
+		detallePrueba(agotado.ID, 2),
+	))
+	if !errors.Is(err, ErrStockInsuficiente) {
+		t.Fatalf("se esperaba ErrStockInsuficiente, se obtuvo %v", err)
+	}
+
+	var sector model.Sector
+	db.First(&sector, "sector_id = ?", conStock.ID)
+	if sector.CantVendidas != 0 {
+		t.Fatalf("el sector con stock quedó con cant_vendidas=%d tras un hold fallido", sector.CantVendidas)
+	}
+
+	var ordenes int64
+	db.Model(&model.OrdenDeCompra{}).Count(&ordenes)
+	if ordenes != 0 {
+		t.Fatalf("no debió crearse ninguna orden, hay %d", ordenes)
+	}
+}
+
+func TestCancelarOrdenTemporalLiberaStockUnaSolaVez(t *testing.T) {
+	db := abrirBDPrueba(t)
+	repo := NewOrdenDeCompraController(logging.NewLoggerMock(), db)
+
+	sector := crearSectorPrueba(t, db, "GENERAL", 10)
+	orden := nuevaOrdenPrueba(1, detallePrueba(sector.ID, 3))
+	if err := repo.CrearOrdenTemporalConReserva(orden); err != nil {
+		t.Fatalf("crear hold: %v", err)
+	}
+
+	var detalles int64
+	db.Model(&model.OrdenDeCompraDetalle{}).Where("orden_de_compra_id = ?", orden.ID).Count(&detalles)
+	if detalles != 1 {
+		t.Fatalf("se esperaba 1 detalle para la orden, hay %d", detalles)
+	}
+
+	if err := repo.CancelarOrdenTemporal(orden.ID); err != nil {
+		t.Fatalf("cancelar: %v", err)
+	}
+	if err := repo.CancelarOrdenTemporal(orden.ID); !errors.Is(err, ErrOrdenNoTemporal) {
+		t.Fatalf("la segunda cancelación debió devolver ErrOrdenNoTemporal, se obtuvo %v", err)
+	}
+
+	var actualizado model.Sector
+	db.First(&actualizado, "sector_id = ?", sector.ID)
+	if actualizado.CantVendidas != 0 {
+		t.Fatalf("cant_vendidas=%d tras cancelar, se esperaba 0", actualizado.CantVendidas)
+	}
+}
+
+func TestCuponSeConsumeEnElHoldYSeDevuelveAlCancelar(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Cupon{}, &model.UsuarioCupon{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewOrdenDeCompraController(logging.NewLoggerMock(), db)
+
+	sector := crearSectorPrueba(t, db, "GENERAL", 10)
+	cupon := &model.Cupon{Codigo: "UNAVEZ", EventoID: sector.EventoID, UsoPorUsuario: 1, Valor: 5}
+	if err := db.Create(cupon).Error; err != nil {
+		t.Fatalf("crear cupón: %v", err)
+	}
+	conCupon := func() *model.OrdenDeCompra {
+		orden := nuevaOrdenPrueba(1, detallePrueba(sector.ID, 1))
+		orden.CuponID = &cupon.ID
+		return orden
+	}
+
+	primera := conCupon()
+	if err := repo.CrearOrdenTemporalConReserva(primera); err != nil {
+		t.Fatalf("primer hold: %v", err)
+	}
+	if err := repo.CrearOrdenTemporalConReserva(conCupon()); !errors.Is(err, ErrCuponAgotado) {
+		t.Fatalf("el segundo uso del cupón debió rechazarse, se obtuvo %v", err)
+	}
+	var actualizado model.Sector
+	db.First(&actualizado, "sector_id = ?", sector.ID)
+	if actualizado.CantVendidas != 1 {
+		t.Fatalf("el hold rechazado no debe reservar stock, cant_vendidas=%d", actualizado.CantVendidas)
+	}
+
+	if err := repo.CancelarOrdenTemporal(primera.ID); err != nil {
+		t.Fatalf("cancelar: %v", err)
+	}
+	var uso model.UsuarioCupon
+	db.First(&uso, "cupon_id = ? AND usuario_id = ?", cupon.ID, 1)
+	if uso.CantUsada != 0 {
+		t.Fatalf("cancelar el hold debe devolver el uso del cupón, cant_usada=%d", uso.CantUsada)
+	}
+	if err := repo.CrearOrdenTemporalConReserva(conCupon()); err != nil {
+		t.Fatalf("tras cancelar, el cupón debe poder usarse otra vez: %v", err)
+	}
+}
+
+func TestConfirmarOrdenPagadaEmiteLosTickets(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(
+		&model.Pago{}, &model.ComprobanteDePago{}, &model.PublicacionReventa{},
+		&model.Ticket{}, &model.HistorialTitular{}, &model.EventoDominio{},
+	); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewOrdenDeCompraController(logging.NewLoggerMock(), db)
+
+	sector := crearSectorPrueba(t, db, "GENERAL", 10)
+	orden := nuevaOrdenPrueba(4, detallePrueba(sector.ID, 3))
+	if err := repo.CrearOrdenTemporalConReserva(orden); err != nil {
+		t.Fatalf("crear hold: %v", err)
+	}
+	pago := &model.Pago{OrdenDeCompraID: orden.ID, MetodoPago: "TARJETA", Referencia: "ref-1", Monto: orden.Total}
+	if err := db.Create(pago).Error; err != nil {
+		t.Fatalf("crear pago: %v", err)
+	}
+	firmar := func(tk *model.Ticket) (string, error) { return fmt.Sprintf("qr-%d", tk.ID), nil }
+
+	// Como si llegara el webhook: nadie llama después a la emisión de tickets
+	if _, err := repo.ConfirmarOrdenPagada(orden.ID, pago.ID, 1, firmar); err != nil {
+		t.Fatalf("ConfirmarOrdenPagada: %v", err)
+	}
+	if _, err := repo.ConfirmarOrdenPagada(orden.ID, pago.ID, 1, firmar); !errors.Is(err, ErrOrdenYaConfirmada) {
+		t.Fatalf("la segunda confirmación debió devolver ErrOrdenYaConfirmada, se obtuvo %v", err)
+	}
+
+	var tickets, correos int64
+	db.Model(&model.Ticket{}).Where("orden_de_compra_id = ? AND titular_id = ?", orden.ID, 4).Count(&tickets)
+	db.Model(&model.EventoDominio{}).Where("tipo = ?", model.EventoOrdenConfirmada).Count(&correos)
+	if tickets != 3 || correos != 1 {
+		t.Fatalf("se esperaban 3 tickets del comprador y 1 evento de orden confirmada, hay %d y %d", tickets, correos)
+	}
+}
+
+func TestConfirmarOrdenPagadaConOtroPagoNoLoDaPorBueno(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(
+		&model.Pago{}, &model.ComprobanteDePago{}, &model.PublicacionReventa{},
+		&model.Ticket{}, &model.HistorialTitular{}, &model.EventoDominio{},
+	); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewOrdenDeCompraController(logging.NewLoggerMock(), db)
+
+	sector := crearSectorPrueba(t, db, "GENERAL", 10)
+	orden := nuevaOrdenPrueba(4, detallePrueba(sector.ID, 1))
+	if err := repo.CrearOrdenTemporalConReserva(orden); err != nil {
+		t.Fatalf("crear hold: %v", err)
+	}
+	// El comprador abrió dos intentos y pagó los dos
+	primero := &model.Pago{OrdenDeCompraID: orden.ID, MetodoPago: "TARJETA", Referencia: "ref-1", Monto: orden.Total}
+	segundo := &model.Pago{OrdenDeCompraID: orden.ID, MetodoPago: "TARJETA", Referencia: "ref-2", Monto: orden.Total}
+	if err := db.Create(primero).Error; err != nil {
+		t.Fatalf("crear pago: %v", err)
+	}
+	if err := db.Create(segundo).Error; err != nil {
+		t.Fatalf("crear pago: %v", err)
+	}
+	firmar := func(tk *model.Ticket) (string, error) { return fmt.Sprintf("qr-%d", tk.ID), nil }
+
+	if _, err := repo.ConfirmarOrdenPagada(orden.ID, primero.ID, 1, firmar); err != nil {
+		t.Fatalf("ConfirmarOrdenPagada: %v", err)
+	}
+	if _, err := repo.ConfirmarOrdenPagada(orden.ID, primero.ID, 1, firmar); !errors.Is(err, ErrOrdenYaConfirmada) {
+		t.Fatalf("repetir el mismo pago debió devolver ErrOrdenYaConfirmada, se obtuvo %v", err)
+	}
+	if _, err := repo.ConfirmarOrdenPagada(orden.ID, segundo.ID, 1, firmar); !errors.Is(err, ErrOrdenPagadaConOtroPago) {
+		t.Fatalf("el segundo pago debió devolver ErrOrdenPagadaConOtroPago, se obtuvo %v", err)
+	}
+	var estado int16
+	db.Model(&model.Pago{}).Select("estado_de_pago").Where("pago_id = ?", segundo.ID).Scan(&estado)
+	if estado != util.PagoPendiente.Codigo() {
+		t.Fatalf("el segundo pago no debió quedar capturado, quedó %d", estado)
+	}
+}
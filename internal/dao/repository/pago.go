@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+	"gorm.io/gorm"
+)
+
+type Pago struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewPagoController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *Pago {
+	return &Pago{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+func (p *Pago) CrearPago(pago *model.Pago) error {
+	if pago == nil {
+		return gorm.ErrInvalidData
+	}
+	if err := p.PostgresqlDB.Create(pago).Error; err != nil {
+		p.logger.Errorf("CrearPago: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ObtenerPorReferencia busca el pago por el id del intento en la pasarela.
+func (p *Pago) ObtenerPorReferencia(metodo util.TipoMetodoPago, referencia string) (*model.Pago, error) {
+	var pago model.Pago
+	if err := p.PostgresqlDB.
+		Where("metodo_pago = ? AND referencia = ?", string(metodo), referencia).
+		First(&pago).Error; err != nil {
+		return nil, err
+	}
+	return &pago, nil
+}
+
+// ObtenerPorOrdenYReferencia busca el pago de una orden por la referencia de la pasarela.
+func (p *Pago) ObtenerPorOrdenYReferencia(orderID int64, referencia string) (*model.Pago, error) {
+	var pago model.Pago
+	if err := p.PostgresqlDB.
+		Where("orden_de_compra_id = ? AND referencia = ?", orderID, referencia).
+		First(&pago).Error; err != nil {
+		return nil, err
+	}
+	return &pago, nil
+}
+
+func (p *Pago) ActualizarEstado(pagoID int64, estado util.EstadoPago) error {
+	res := p.PostgresqlDB.
+		Model(&model.Pago{}).
+		Where("pago_id = ?", pagoID).
+		Updates(map[string]any{
+			"estado_de_pago":     estado.Codigo(),
+			"fecha_modificacion": time.Now(),
+		})
+	if res.Error != nil {
+		p.logger.Errorf("Pago.ActualizarEstado(%d): %v", pagoID, res.Error)
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/Nexivent/nexivent-backend/logging"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PreferenciaNotificacion struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewPreferenciaNotificacionController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *PreferenciaNotificacion {
+	return &PreferenciaNotificacion{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+// ListarPorUsuario devuelve las preferencias que el usuario guardó alguna vez.
+func (r *PreferenciaNotificacion) ListarPorUsuario(usuarioID int64) ([]model.PreferenciaNotificacion, error) {
+	var preferencias []model.PreferenciaNotificacion
+	if err := r.PostgresqlDB.
+		Where("usuario_id = ?", usuarioID).
+		Find(&preferencias).Error; err != nil {
+		r.logger.Errorf("PreferenciaNotificacion.ListarPorUsuario(%d): %v", usuarioID, err)
+		return nil, err
+	}
+	return preferencias, nil
+}
+
+// Guardar crea o reemplaza, en una sola sentencia, las preferencias indicadas del usuario.
+func (r *PreferenciaNotificacion) Guardar(usuarioID int64, preferencias []model.PreferenciaNotificacion, ahora time.Time) error {
+	if len(preferencias) == 0 {
+		return nil
+	}
+	for i := range preferencias {
+		preferencias[i].UsuarioID = usuarioID
+		preferencias[i].FechaModificacion = ahora
+	}
+	if err := r.PostgresqlDB.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "usuario_id"}, {Name: "tipo"}, {Name: "canal"}},
+			DoUpdates: clause.AssignmentColumns([]string{"habilitado", "fecha_modificacion"}),
+		}).
+		Create(&preferencias).Error; err != nil {
+		r.logger.Errorf("PreferenciaNotificacion.Guardar(%d): %v", usuarioID, err)
+		return err
+	}
+	return nil
+}
+
+// Deshabilitadas devuelve, de entre usuarioIDs, qué canales apagó cada usuario para el tipo.
+func (r *PreferenciaNotificacion) Deshabilitadas(tipo string, usuarioIDs []int64) (map[int64]map[string]bool, error) {
+	apagadas := map[int64]map[string]bool{}
+	if len(usuarioIDs) == 0 {
+		return apagadas, nil
+	}
+	var preferencias []model.PreferenciaNotificacion
+	if err := r.PostgresqlDB.
+		Where("tipo = ? AND usuario_id IN ? AND NOT habilitado", tipo, usuarioIDs).
+		Find(&preferencias).Error; err != nil {
+		r.logger.Errorf("PreferenciaNotificacion.Deshabilitadas(%s): %v", tipo, err)
+		return nil, err
+	}
+	for _, p := range preferencias {
+		if apagadas[p.UsuarioID] == nil {
+			apagadas[p.UsuarioID] = map[string]bool{}
+		}
+		apagadas[p.UsuarioID][p.Canal] = true
+	}
+	return apagadas, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestPreferenciaNotificacionGuardarYDeshabilitadas(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.PreferenciaNotificacion{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewPreferenciaNotificacionController(logging.NewLoggerMock(), db)
+	ahora := time.Now()
+
+	if err := repo.Guardar(1, []model.PreferenciaNotificacion{
+		{Tipo: model.EventoEventoCancelado, Canal: model.CanalCorreo, Habilitado: false},
+		{Tipo: model.EventoEventoCancelado, Canal: model.CanalInApp, Habilitado: false},
+	}, ahora); err != nil {
+		t.Fatalf("Guardar: %v", err)
+	}
+	// Volver a guardar reemplaza la preferencia existente
+	if err := repo.Guardar(1, []model.PreferenciaNotificacion{
+		{Tipo: model.EventoEventoCancelado, Canal: model.CanalInApp, Habilitado: true},
+	}, ahora); err != nil {
+		t.Fatalf("Guardar de nuevo: %v", err)
+	}
+
+	apagadas, err := repo.Deshabilitadas(model.EventoEventoCancelado, []int64{1, 2})
+	if err != nil {
+		t.Fatalf("Deshabilitadas: %v", err)
+	}
+	if !apagadas[1][model.CanalCorreo] || apagadas[1][model.CanalInApp] || len(apagadas[2]) != 0 {
+		t.Fatalf("canales deshabilitados inesperados: %+v", apagadas)
+	}
+	if otras, _ := repo.Deshabilitadas(model.EventoTicketTransferido, []int64{1}); len(otras) != 0 {
+		t.Fatalf("las preferencias de un tipo no deben afectar a otro: %+v", otras)
+	}
+	if guardadas, _ := repo.ListarPorUsuario(1); len(guardadas) != 2 {
+		t.Fatalf("se esperaban 2 preferencias guardadas, hay %d", len(guardadas))
+	}
+}
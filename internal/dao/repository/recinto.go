@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+	"gorm.io/gorm"
+)
+
+type Recinto struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewRecintoController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *Recinto {
+	return &Recinto{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+// radioTierraKm es el radio medio de la Tierra con el que se calculan las distancias
+const radioTierraKm = 6371.0
+
+// PuntoGeografico es una posición en grados decimales (WGS 84)
+type PuntoGeografico struct {
+	Latitud  float64
+	Longitud float64
+}
+
+// Cercania deja solo lo que está a RadioKm o menos de Punto
+type Cercania struct {
+	Punto   PuntoGeografico
+	RadioKm float64
+}
+
+// distanciaKm es la distancia en km, por la fórmula de haversine, del punto a las coordenadas
+// del recinto con alias recinto. Devuelve la expresión SQL y sus argumentos.
+func distanciaKm(recinto string, punto PuntoGeografico) (string, []any) {
+	expr := fmt.Sprintf(`(2 * %[2]g * ASIN(SQRT(LEAST(1,
+		POWER(SIN(RADIANS(%[1]s.latitud - ?) / 2), 2)
+		+ COS(RADIANS(?)) * COS(RADIANS(%[1]s.latitud)) * POWER(SIN(RADIANS(%[1]s.longitud - ?) / 2), 2)))))`,
+		recinto, radioTierraKm)
+	return expr, []any{punto.Latitud, punto.Latitud, punto.Longitud}
+}
+
+// filtrarCercania deja las filas cuyo recinto (con alias recinto) está dentro del radio. Primero
+// acota con el recuadro de latitud y longitud que contiene al círculo, que usa el índice de
+// coordenadas, y recién ahí calcula la distancia exacta.
+func filtrarCercania(query *gorm.DB, recinto string, cercania Cercania) *gorm.DB {
+	gradosLatitud := cercania.RadioKm / (radioTierraKm * math.Pi / 180)
+	query = query.Where(recinto+".latitud BETWEEN ? AND ?",
+		cercania.Punto.Latitud-gradosLatitud, cercania.Punto.Latitud+gradosLatitud)
+
+	// Cerca de los polos o del antimeridiano el recuadro de longitud no sirve: basta la distancia
+	coseno := math.Cos(cercania.Punto.Latitud * math.Pi / 180)
+	if coseno > 0.01 {
+		gradosLongitud := gradosLatitud / coseno
+		desde, hasta := cercania.Punto.Longitud-gradosLongitud, cercania.Punto.Longitud+gradosLongitud
+		if desde >= -180 && hasta <= 180 {
+			query = query.Where(recinto+".longitud BETWEEN ? AND ?", desde, hasta)
+		}
+	}
+
+	expr, args := distanciaKm(recinto, cercania.Punto)
+	return query.Where(expr+" <= ?", append(args, cercania.RadioKm)...)
+}
+
+// RecintoListado es un recinto de un listado, con su distancia al punto de búsqueda si lo hubo
+type RecintoListado struct {
+	model.Recinto `gorm:"embedded"`
+	DistanciaKm   *float64
+}
+
+func (r *Recinto) CrearRecinto(recinto *model.Recinto) error {
+	return r.PostgresqlDB.Create(recinto).Error
+}
+
+func (r *Recinto) ActualizarRecinto(recinto *model.Recinto) error {
+	return r.PostgresqlDB.Save(recinto).Error
+}
+
+func (r *Recinto) ObtenerRecintoPorId(id int64) (*model.Recinto, error) {
+	var recinto model.Recinto
+	if err := r.PostgresqlDB.Where("recinto_id = ?", id).First(&recinto).Error; err != nil {
+		return nil, err
+	}
+	return &recinto, nil
+}
+
+// ListarRecintos devuelve los recintos activos cuyo ubigeo empieza con prefijoUbigeo (vacío: todos;
+// "15" es un departamento, "1501" una provincia y "150101" un distrito). Con cercania, solo los
+// que están dentro del radio y del más cercano al más lejano; si no, por nombre.
+func (r *Recinto) ListarRecintos(prefijoUbigeo string, cercania *Cercania) ([]RecintoListado, error) {
+	query := r.PostgresqlDB.
+		Table("recinto").
+		Where("recinto.estado = ?", util.Activo.Codigo())
+	if prefijoUbigeo != "" {
+		query = query.Where("recinto.ubigeo LIKE ?", prefijoUbigeo+"%")
+	}
+	if cercania != nil {
+		expr, args := distanciaKm("recinto", cercania.Punto)
+		query = filtrarCercania(query, "recinto", *cercania).
+			Select("recinto.*, "+expr+" AS distancia_km", args...).
+			Order("distancia_km, recinto.recinto_id")
+	} else {
+		query = query.Select("recinto.*").Order("recinto.nombre, recinto.recinto_id")
+	}
+
+	var recintos []RecintoListado
+	if err := query.Scan(&recintos).Error; err != nil {
+		r.logger.Errorf("Recinto.ListarRecintos: %v", err)
+		return nil, err
+	}
+	return recintos, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestEventosCercaDeUnPuntoPorDistancia(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Fecha{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	eventos := NewEventoController(logging.NewLoggerMock(), db)
+	recintos := NewRecintoController(logging.NewLoggerMock(), db)
+	hoy := time.Now().UTC().Truncate(24 * time.Hour)
+
+	crearRecinto := func(nombre, ubigeo string, latitud, longitud float64) *model.Recinto {
+		t.Helper()
+		recinto := &model.Recinto{
+			Nombre: nombre, Direccion: "-", Departamento: "-", Provincia: "-", Distrito: "-",
+			Ubigeo: ubigeo, Latitud: latitud, Longitud: longitud, Estado: util.Activo.Codigo(),
+		}
+		if err := recintos.CrearRecinto(recinto); err != nil {
+			t.Fatalf("crear recinto %s: %v", nombre, err)
+		}
+		return recinto
+	}
+	crearEvento := func(recinto *model.Recinto) int64 {
+		t.Helper()
+		ev := crearEventoPrueba(t, db, util.EventoPublicado)
+		if recinto != nil {
+			db.Model(ev).Update("recinto_id", recinto.ID)
+		}
+		fecha := &model.Fecha{FechaEvento: hoy.AddDate(0, 0, 7)}
+		db.Create(fecha)
+		db.Create(&model.EventoFecha{EventoID: ev.ID, FechaID: fecha.ID, HoraInicio: hoy.Add(20 * time.Hour)})
+		return ev.ID
+	}
+
+	estadio := crearRecinto("Estadio Nacional", "150101", -12.0670, -77.0337)
+	teatro := crearRecinto("Teatro Municipal", "150101", -12.0503, -77.0368)
+	arequipa := crearRecinto("Teatro Municipal de Arequipa", "040101", -16.3989, -71.5350)
+	enEstadio := crearEvento(estadio)
+	enTeatro := crearEvento(teatro)
+	crearEvento(arequipa)
+	crearEvento(nil)
+
+	// Plaza San Martín, Lima
+	cercania := Cercania{Punto: PuntoGeografico{Latitud: -12.0519, Longitud: -77.0345}, RadioKm: 5}
+
+	listado, err := eventos.ObtenerEventosDisponiblesConFiltros(nil, nil, nil, nil, nil, nil, &cercania, nil, nil, nil, false,
+		PaginaEventos{Orden: OrdenEventosDistancia, Limite: 10, Origen: &cercania.Punto})
+	if err != nil {
+		t.Fatalf("listar por distancia: %v", err)
+	}
+	if listado.Total != 2 || len(listado.Eventos) != 2 || listado.Eventos[0].ID != enTeatro || listado.Eventos[1].ID != enEstadio {
+		t.Fatalf("se esperaban el teatro y luego el estadio, se obtuvo total=%d %v", listado.Total, listado.Eventos)
+	}
+	if listado.Eventos[0].Recinto == nil || listado.Eventos[0].Recinto.ID != teatro.ID {
+		t.Fatalf("el listado debe traer el recinto del evento")
+	}
+	// Unos 1,7 km de la plaza al estadio
+	if d := listado.Distancias[enEstadio]; math.Abs(d-1.7) > 0.2 {
+		t.Fatalf("distancia al estadio: %.3f km", d)
+	}
+
+	if _, err := eventos.ObtenerEventosDisponiblesSinFiltros(PaginaEventos{Orden: OrdenEventosDistancia, Limite: 10}); err != ErrOrdenEventosInvalido {
+		t.Fatalf("ordenar por distancia sin origen debe fallar, se obtuvo %v", err)
+	}
+
+	// Los recintos del departamento de Lima, del más cercano al más lejano
+	cercanos, err := recintos.ListarRecintos("15", &Cercania{Punto: cercania.Punto, RadioKm: 500})
+	if err != nil {
+		t.Fatalf("ListarRecintos: %v", err)
+	}
+	if len(cercanos) != 2 || cercanos[0].ID != teatro.ID || cercanos[1].ID != estadio.ID || cercanos[0].DistanciaKm == nil {
+		t.Fatalf("recintos cercanos inesperados: %+v", cercanos)
+	}
+}
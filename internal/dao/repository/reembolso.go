@@ -0,0 +1,795 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Reembolso struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewReembolsoController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *Reembolso {
+	return &Reembolso{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+var (
+	// ErrOrdenNoReembolsable indica que la orden no está CONFIRMADA, no tiene un pago capturado
+	// o ya se devolvió todo lo cobrado.
+	ErrOrdenNoReembolsable = errors.New("la orden no tiene un pago que se pueda reembolsar")
+	// ErrTicketNoReembolsable indica que algún ticket no es de la orden, no está VENDIDO o pasó
+	// por la reventa (su dinero no lo pagó el comprador de la orden).
+	ErrTicketNoReembolsable = errors.New("el ticket no se puede reembolsar")
+	// ErrCancelacionCerrada indica que la fecha de algún ticket ya no admite cancelaciones.
+	ErrCancelacionCerrada = errors.New("la fecha del ticket ya no admite cancelaciones")
+)
+
+// OpcionesReembolso dice por qué se reembolsa y si se devuelve también el fee de servicio.
+// Con FechaCerrada, Crear rechaza los tickets cuya fecha (con su día cargado) ya no admite
+// cancelaciones.
+type OpcionesReembolso struct {
+	Motivo        string
+	ReembolsarFee bool
+	UsuarioID     *int64 // quién lo pidió
+	FechaCerrada  func(*model.EventoFecha) bool
+}
+
+func redondearCentimos(monto float64) float64 {
+	return math.Round(monto*100) / 100
+}
+
+// detalleDeTicket devuelve la línea de la orden con la que se vendió el ticket.
+func detalleDeTicket(detalles []model.OrdenDeCompraDetalle, ticket *model.Ticket) *model.OrdenDeCompraDetalle {
+	for i := range detalles {
+		if detalles[i].TarifaID == ticket.TarifaID && detalles[i].EventoFechaID == ticket.EventoFechaID {
+			return &detalles[i]
+		}
+	}
+	return nil
+}
+
+// pagoCapturadoDeOrden devuelve el último pago CAPTURADO de la orden o ErrOrdenNoReembolsable.
+func pagoCapturadoDeOrden(tx *gorm.DB, orderID int64) (model.Pago, error) {
+	var pago model.Pago
+	err := tx.
+		Where("orden_de_compra_id = ? AND estado_de_pago = ?", orderID, util.PagoCapturado.Codigo()).
+		Order("pago_id DESC").
+		First(&pago).Error
+	if err == gorm.ErrRecordNotFound {
+		return pago, ErrOrdenNoReembolsable
+	}
+	return pago, err
+}
+
+// pagadoPorTicket devuelve lo que se pagó por un ticket de la línea, con el descuento del cupón
+// ya repartido, y la parte del fee de servicio de la orden que le corresponde.
+func pagadoPorTicket(orden *model.OrdenDeCompra, d *model.OrdenDeCompraDetalle) (pagado float64, fee float64) {
+	pagado = redondearCentimos(d.Subtotal() / float64(d.Cantidad))
+	if orden.Total > 0 {
+		fee = redondearCentimos(orden.MontoFeeServicio * pagado / orden.Total)
+	}
+	return pagado, fee
+}
+
+// reversionVenta acumula lo que un reembolso le resta a cada sector, fecha y evento.
+type reversionVenta struct {
+	netaPorFecha      map[int64]float64
+	netaPorEvento     map[int64]float64
+	cantidadPorEvento map[int64]int64
+	cantidadPorSector map[int64]int64
+}
+
+func nuevaReversionVenta() *reversionVenta {
+	return &reversionVenta{
+		netaPorFecha:      map[int64]float64{},
+		netaPorEvento:     map[int64]float64{},
+		cantidadPorEvento: map[int64]int64{},
+		cantidadPorSector: map[int64]int64{},
+	}
+}
+
+// sumar resta un ticket de la línea d y neta de la ganancia del organizador.
+func (v *reversionVenta) sumar(d *model.OrdenDeCompraDetalle, neta float64) {
+	v.netaPorFecha[d.EventoFechaID] += neta
+	v.netaPorEvento[d.EventoFecha.EventoID] += neta
+	v.cantidadPorEvento[d.EventoFecha.EventoID]++
+	v.cantidadPorSector[d.SectorID]++
+}
+
+// registrarReembolso guarda el reembolso PENDIENTE armado con sus tickets, los cancela junto con
+// sus transferencias pendientes y revierte la venta en sectores, fechas y eventos. Nunca deja
+// devolver más de lo cobrado en el pago, contando los reembolsos anteriores aunque hayan fallado.
+func registrarReembolso(
+	tx *gorm.DB,
+	reembolso *model.Reembolso,
+	pago *model.Pago,
+	reversion *reversionVenta,
+	opciones OpcionesReembolso,
+	ahora time.Time,
+) error {
+	ticketIDs := make([]int64, 0, len(reembolso.Tickets))
+	for _, linea := range reembolso.Tickets {
+		ticketIDs = append(ticketIDs, linea.TicketID)
+		reembolso.Monto += linea.Monto
+		reembolso.MontoFeeServicio += linea.MontoFeeServicio
+	}
+	reembolso.Monto = redondearCentimos(reembolso.Monto)
+	reembolso.MontoFeeServicio = redondearCentimos(reembolso.MontoFeeServicio)
+	reembolso.MontoOrganizador = redondearCentimos(reembolso.MontoOrganizador)
+
+	var devuelto float64
+	if err := tx.Model(&model.Reembolso{}).
+		Select("COALESCE(SUM(monto), 0)").
+		Where("pago_id = ?", pago.ID).
+		Scan(&devuelto).Error; err != nil {
+		return err
+	}
+	if reembolso.Monto <= 0 || devuelto+reembolso.Monto > pago.Monto+0.005 {
+		return ErrOrdenNoReembolsable
+	}
+
+	if err := tx.Create(reembolso).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.Ticket{}).
+		Where("ticket_id IN ?", ticketIDs).
+		Update("estado_de_ticket", util.TicketCancelado.Codigo()).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.TransferenciaTicket{}).
+		Where("ticket_id IN ? AND estado = ?", ticketIDs, util.TransferenciaPendiente.Codigo()).
+		Updates(map[string]any{
+			"estado":           util.TransferenciaCancelada.Codigo(),
+			"fecha_resolucion": ahora,
+			"resuelta_por_id":  opciones.UsuarioID,
+		}).Error; err != nil {
+		return err
+	}
+	// Reembolsar un ticket responde la reprogramación que tuviera pendiente
+	if err := tx.Model(&model.ReprogramacionTicket{}).
+		Where("ticket_id IN ? AND estado = ?", ticketIDs, util.ReprogramacionPendiente.Codigo()).
+		Updates(map[string]any{
+			"estado":          util.ReprogramacionReembolsada.Codigo(),
+			"reembolso_id":    reembolso.ID,
+			"fecha_respuesta": ahora,
+		}).Error; err != nil {
+		return err
+	}
+	for sectorID, cantidad := range reversion.cantidadPorSector {
+		if err := tx.Model(&model.Sector{}).
+			Where("sector_id = ?", sectorID).
+			UpdateColumn("cant_vendidas", gorm.Expr("GREATEST(cant_vendidas - ?, 0)", cantidad)).Error; err != nil {
+			return err
+		}
+	}
+	return actualizarAcumulados(tx, reversion.netaPorEvento, reversion.cantidadPorEvento, reversion.netaPorFecha, -1)
+}
+
+// Crear registra, en una sola transacción, el reembolso PENDIENTE de los tickets de la orden:
+// los cancela (junto con sus transferencias pendientes), libera su lugar en el sector y resta
+// su venta de los acumulados del evento y de la fecha. Lo que se devuelve por ticket es lo que
+// se pagó por él, con el descuento del cupón ya repartido en la línea; la parte del fee de
+// servicio solo se devuelve con ReembolsarFee. Si algún ticket es de una fecha que FechaCerrada
+// da por cerrada devuelve ErrCancelacionCerrada. La devolución en la pasarela la hace el adapter
+// después, con el reembolso ya guardado.
+func (r *Reembolso) Crear(orderID int64, ticketIDs []int64, opciones OpcionesReembolso, ahora time.Time) (*model.Reembolso, error) {
+	var reembolso *model.Reembolso
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		var orden model.OrdenDeCompra
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&orden, "orden_de_compra_id = ?", orderID).Error; err != nil {
+			return err
+		}
+		if orden.EstadoDeOrden != util.OrdenConfirmada.Codigo() {
+			return ErrOrdenNoReembolsable
+		}
+		pago, err := pagoCapturadoDeOrden(tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		unicos := map[int64]bool{}
+		for _, id := range ticketIDs {
+			unicos[id] = true
+		}
+		var tickets []model.Ticket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("ticket_id IN ? AND orden_de_compra_id = ?", ticketIDs, orderID).
+			Order("ticket_id").
+			Find(&tickets).Error; err != nil {
+			return err
+		}
+		if len(tickets) == 0 || len(tickets) != len(unicos) {
+			return ErrTicketNoReembolsable
+		}
+		for _, t := range tickets {
+			if t.EstadoDeTicket != util.TicketVendido.Codigo() {
+				return ErrTicketNoReembolsable
+			}
+		}
+		var enReventa int64
+		if err := tx.Model(&model.PublicacionReventa{}).
+			Where("ticket_id IN ? AND estado IN ?", ticketIDs, []int16{
+				util.ReventaPublicada.Codigo(), util.ReventaReservada.Codigo(), util.ReventaVendida.Codigo(),
+			}).
+			Count(&enReventa).Error; err != nil {
+			return err
+		}
+		if enReventa > 0 {
+			return ErrTicketNoReembolsable
+		}
+
+		var detalles []model.OrdenDeCompraDetalle
+		if err := tx.Preload("EventoFecha.Fecha").
+			Where("orden_de_compra_id = ?", orderID).
+			Find(&detalles).Error; err != nil {
+			return err
+		}
+
+		reembolso = &model.Reembolso{
+			OrdenDeCompraID: orderID,
+			PagoID:          pago.ID,
+			Motivo:          opciones.Motivo,
+			Estado:          util.ReembolsoPendiente.Codigo(),
+			UsuarioCreacion: opciones.UsuarioID,
+			FechaCreacion:   ahora,
+		}
+		reversion := nuevaReversionVenta()
+		for i := range tickets {
+			d := detalleDeTicket(detalles, &tickets[i])
+			if d == nil || d.Cantidad <= 0 || d.EventoFecha == nil {
+				return ErrTicketNoReembolsable
+			}
+			if opciones.FechaCerrada != nil && opciones.FechaCerrada(d.EventoFecha) {
+				return ErrCancelacionCerrada
+			}
+			pagado, fee := pagadoPorTicket(&orden, d)
+			neta := pagado - fee
+
+			linea := model.ReembolsoTicket{TicketID: tickets[i].ID, Monto: neta}
+			if opciones.ReembolsarFee {
+				linea.Monto = pagado
+				linea.MontoFeeServicio = fee
+			}
+			reembolso.Tickets = append(reembolso.Tickets, linea)
+			reembolso.MontoOrganizador += neta
+			reversion.sumar(d, neta)
+		}
+		if err := registrarReembolso(tx, reembolso, &pago, reversion, opciones, ahora); err != nil {
+			return err
+		}
+		reembolso.Pago = &pago
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrOrdenNoReembolsable) && !errors.Is(err, ErrTicketNoReembolsable) &&
+			!errors.Is(err, ErrCancelacionCerrada) && err != gorm.ErrRecordNotFound {
+			r.logger.Errorf("Reembolso.Crear(orden=%d): %v", orderID, err)
+		}
+		return nil, err
+	}
+	return reembolso, nil
+}
+
+// CrearPorReventa registra el reembolso PENDIENTE de un ticket que se compró en la reventa: se le
+// devuelve al comprador de la publicación VENDIDA lo que pagó en esa orden (sin el fee de la
+// plataforma salvo con ReembolsarFee). Al organizador se le resta la venta original del ticket y
+// las regalías que cobró por sus reventas. El resto es igual que en Crear.
+func (r *Reembolso) CrearPorReventa(publicacionID int64, opciones OpcionesReembolso, ahora time.Time) (*model.Reembolso, error) {
+	var reembolso *model.Reembolso
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		var publicacion model.PublicacionReventa
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&publicacion, "publicacion_reventa_id = ?", publicacionID).Error; err != nil {
+			return err
+		}
+		if publicacion.Estado != util.ReventaVendida.Codigo() || publicacion.OrdenDeCompraID == nil {
+			return ErrTicketNoReembolsable
+		}
+		var ordenReventa model.OrdenDeCompra
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&ordenReventa, "orden_de_compra_id = ?", *publicacion.OrdenDeCompraID).Error; err != nil {
+			return err
+		}
+		if ordenReventa.EstadoDeOrden != util.OrdenConfirmada.Codigo() {
+			return ErrOrdenNoReembolsable
+		}
+		pago, err := pagoCapturadoDeOrden(tx, ordenReventa.ID)
+		if err != nil {
+			return err
+		}
+
+		var ticket model.Ticket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&ticket, "ticket_id = ?", publicacion.TicketID).Error; err != nil {
+			return err
+		}
+		if ticket.EstadoDeTicket != util.TicketVendido.Codigo() || ticket.OrdenDeCompraID == nil {
+			return ErrTicketNoReembolsable
+		}
+		// Solo la última reventa del ticket: las anteriores ya las pagó quien lo revendió después
+		var regalias float64
+		var ultima int64
+		if err := tx.Model(&model.PublicacionReventa{}).
+			Select("COALESCE(SUM(monto_regalia), 0), COALESCE(MAX(publicacion_reventa_id), 0)").
+			Where("ticket_id = ? AND estado = ?", ticket.ID, util.ReventaVendida.Codigo()).
+			Row().Scan(&regalias, &ultima); err != nil {
+			return err
+		}
+		if ultima != publicacion.ID {
+			return ErrTicketNoReembolsable
+		}
+
+		var original model.OrdenDeCompra
+		if err := tx.First(&original, "orden_de_compra_id = ?", *ticket.OrdenDeCompraID).Error; err != nil {
+			return err
+		}
+		var detalles []model.OrdenDeCompraDetalle
+		if err := tx.Preload("EventoFecha").
+			Where("orden_de_compra_id = ?", original.ID).
+			Find(&detalles).Error; err != nil {
+			return err
+		}
+		d := detalleDeTicket(detalles, &ticket)
+		if d == nil || d.Cantidad <= 0 || d.EventoFecha == nil {
+			return ErrTicketNoReembolsable
+		}
+		pagado, fee := pagadoPorTicket(&original, d)
+		neta := pagado - fee + regalias
+
+		linea := model.ReembolsoTicket{TicketID: ticket.ID, Monto: publicacion.Precio - publicacion.MontoFeePlataforma}
+		if opciones.ReembolsarFee {
+			linea.Monto = publicacion.Precio
+			linea.MontoFeeServicio = publicacion.MontoFeePlataforma
+		}
+		reembolso = &model.Reembolso{
+			OrdenDeCompraID:  ordenReventa.ID,
+			PagoID:           pago.ID,
+			Motivo:           opciones.Motivo,
+			Estado:           util.ReembolsoPendiente.Codigo(),
+			MontoOrganizador: neta,
+			UsuarioCreacion:  opciones.UsuarioID,
+			FechaCreacion:    ahora,
+			Tickets:          []model.ReembolsoTicket{linea},
+		}
+		reversion := nuevaReversionVenta()
+		reversion.sumar(d, neta)
+		if err := registrarReembolso(tx, reembolso, &pago, reversion, opciones, ahora); err != nil {
+			return err
+		}
+		reembolso.Pago = &pago
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrOrdenNoReembolsable) && !errors.Is(err, ErrTicketNoReembolsable) && err != gorm.ErrRecordNotFound {
+			r.logger.Errorf("Reembolso.CrearPorReventa(%d): %v", publicacionID, err)
+		}
+		return nil, err
+	}
+	return reembolso, nil
+}
+
+// CrearPorOrden registra el reembolso PENDIENTE de una orden CONFIRMADA a la que nunca se le
+// emitieron tickets (se pagó mientras su evento se cancelaba): se devuelve lo pagado por cada
+// entrada de sus líneas, con el fee solo si ReembolsarFee, y se revierte su venta como en Crear.
+func (r *Reembolso) CrearPorOrden(orderID int64, opciones OpcionesReembolso, ahora time.Time) (*model.Reembolso, error) {
+	var reembolso *model.Reembolso
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		var orden model.OrdenDeCompra
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&orden, "orden_de_compra_id = ?", orderID).Error; err != nil {
+			return err
+		}
+		if orden.EstadoDeOrden != util.OrdenConfirmada.Codigo() {
+			return ErrOrdenNoReembolsable
+		}
+		pago, err := pagoCapturadoDeOrden(tx, orderID)
+		if err != nil {
+			return err
+		}
+		// Con tickets se reembolsa con Crear; la compra de una reventa, con CrearPorReventa
+		var conTickets int64
+		if err := tx.Model(&model.Ticket{}).
+			Where("orden_de_compra_id = ?", orderID).
+			Count(&conTickets).Error; err != nil {
+			return err
+		}
+		var deReventa int64
+		if err := tx.Model(&model.PublicacionReventa{}).
+			Where("orden_de_compra_id = ?", orderID).
+			Count(&deReventa).Error; err != nil {
+			return err
+		}
+		if conTickets > 0 || deReventa > 0 {
+			return ErrOrdenNoReembolsable
+		}
+
+		var detalles []model.OrdenDeCompraDetalle
+		if err := tx.Preload("EventoFecha").
+			Where("orden_de_compra_id = ?", orderID).
+			Find(&detalles).Error; err != nil {
+			return err
+		}
+		reembolso = &model.Reembolso{
+			OrdenDeCompraID: orderID,
+			PagoID:          pago.ID,
+			Motivo:          opciones.Motivo,
+			Estado:          util.ReembolsoPendiente.Codigo(),
+			UsuarioCreacion: opciones.UsuarioID,
+			FechaCreacion:   ahora,
+		}
+		reversion := nuevaReversionVenta()
+		for i := range detalles {
+			d := &detalles[i]
+			if d.Cantidad <= 0 || d.EventoFecha == nil {
+				return ErrOrdenNoReembolsable
+			}
+			pagado, fee := pagadoPorTicket(&orden, d)
+			neta := pagado - fee
+			for n := int64(0); n < d.Cantidad; n++ {
+				reembolso.Monto += neta
+				if opciones.ReembolsarFee {
+					reembolso.Monto += fee
+					reembolso.MontoFeeServicio += fee
+				}
+				reembolso.MontoOrganizador += neta
+				reversion.sumar(d, neta)
+			}
+		}
+		if err := registrarReembolso(tx, reembolso, &pago, reversion, opciones, ahora); err != nil {
+			return err
+		}
+		reembolso.Pago = &pago
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrOrdenNoReembolsable) && err != gorm.ErrRecordNotFound {
+			r.logger.Errorf("Reembolso.CrearPorOrden(%d): %v", orderID, err)
+		}
+		return nil, err
+	}
+	return reembolso, nil
+}
+
+// Completar registra que la pasarela devolvió el dinero: emite la nota de crédito contra el
+// comprobante de la orden y, si ya se devolvió todo lo cobrado, deja el pago REEMBOLSADO. Si el
+// reembolso ya estaba completado no hace nada.
+func (r *Reembolso) Completar(id int64, referencia string, ahora time.Time) (*model.Reembolso, error) {
+	var reembolso model.Reembolso
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&reembolso, "reembolso_id = ?", id).Error; err != nil {
+			return err
+		}
+		if reembolso.Estado == util.ReembolsoCompletado.Codigo() {
+			return nil
+		}
+
+		nota := model.ComprobanteDePago{
+			OrdenDeCompraID:   reembolso.OrdenDeCompraID,
+			TipoDeComprobante: util.ComprobanteNotaCredito.Codigo(),
+			Numero:            fmt.Sprintf("BC01-%08d", reembolso.ID),
+			FechaEmision:      ahora,
+			Monto:             &reembolso.Monto,
+		}
+		var original model.ComprobanteDePago
+		err := tx.
+			Where("orden_de_compra_id = ? AND tipo_de_comprobante <> ?", reembolso.OrdenDeCompraID, util.ComprobanteNotaCredito.Codigo()).
+			Order("comprobante_de_pago_id").
+			First(&original).Error
+		switch {
+		case err == nil:
+			nota.ComprobanteReferenciaID = &original.ID
+			nota.RUC = original.RUC
+			nota.DireccionFiscal = original.DireccionFiscal
+		case err != gorm.ErrRecordNotFound:
+			return err
+		}
+		if err := tx.Create(&nota).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&reembolso).Updates(map[string]any{
+			"estado":           util.ReembolsoCompletado.Codigo(),
+			"referencia":       referencia,
+			"ultimo_error":     nil,
+			"nota_credito_id":  nota.ID,
+			"fecha_completado": ahora,
+		}).Error; err != nil {
+			return err
+		}
+		reembolso.Estado = util.ReembolsoCompletado.Codigo()
+		reembolso.Referencia = &referencia
+		reembolso.NotaCreditoID = &nota.ID
+		reembolso.FechaCompletado = &ahora
+		reembolso.NotaCredito = &nota
+
+		var pago model.Pago
+		if err := tx.First(&pago, "pago_id = ?", reembolso.PagoID).Error; err != nil {
+			return err
+		}
+		var devuelto float64
+		if err := tx.Model(&model.Reembolso{}).
+			Select("COALESCE(SUM(monto), 0)").
+			Where("pago_id = ? AND estado = ?", pago.ID, util.ReembolsoCompletado.Codigo()).
+			Scan(&devuelto).Error; err != nil {
+			return err
+		}
+		if devuelto >= pago.Monto-0.005 {
+			return tx.Model(&pago).Updates(map[string]any{
+				"estado_de_pago":     util.PagoReembolsado.Codigo(),
+				"fecha_modificacion": ahora,
+			}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		r.logger.Errorf("Reembolso.Completar(%d): %v", id, err)
+		return nil, err
+	}
+	return &reembolso, nil
+}
+
+// RegistrarFallo deja el reembolso FALLIDO con la causa. Los tickets siguen cancelados: el
+// dinero se le debe al comprador hasta que un administrador lo reintente.
+func (r *Reembolso) RegistrarFallo(id int64, causa string) error {
+	err := r.PostgresqlDB.
+		Model(&model.Reembolso{}).
+		Where("reembolso_id = ? AND estado = ?", id, util.ReembolsoPendiente.Codigo()).
+		Updates(map[string]any{
+			"estado":       util.ReembolsoFallido.Codigo(),
+			"ultimo_error": causa,
+		}).Error
+	if err != nil {
+		r.logger.Errorf("Reembolso.RegistrarFallo(%d): %v", id, err)
+	}
+	return err
+}
+
+// TomarParaReintento pasa un reembolso FALLIDO a PENDIENTE para volver a intentarlo en la
+// pasarela. Devuelve false si no existe o no estaba FALLIDO, así dos reintentos simultáneos no
+// devuelven el dinero dos veces.
+func (r *Reembolso) TomarParaReintento(id int64) (bool, error) {
+	res := r.PostgresqlDB.
+		Model(&model.Reembolso{}).
+		Where("reembolso_id = ? AND estado = ?", id, util.ReembolsoFallido.Codigo()).
+		Update("estado", util.ReembolsoPendiente.Codigo())
+	if res.Error != nil {
+		r.logger.Errorf("Reembolso.TomarParaReintento(%d): %v", id, res.Error)
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// ObtenerPorID devuelve el reembolso con sus tickets, su pago y su nota de crédito.
+func (r *Reembolso) ObtenerPorID(id int64) (*model.Reembolso, error) {
+	var reembolso model.Reembolso
+	if err := r.PostgresqlDB.
+		Preload("Tickets").
+		Preload("Pago").
+		Preload("NotaCredito").
+		First(&reembolso, "reembolso_id = ?", id).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			r.logger.Errorf("Reembolso.ObtenerPorID(%d): %v", id, err)
+		}
+		return nil, err
+	}
+	return &reembolso, nil
+}
+
+// ListarPorOrden devuelve los reembolsos de la orden, del más reciente al más antiguo.
+func (r *Reembolso) ListarPorOrden(orderID int64) ([]model.Reembolso, error) {
+	var reembolsos []model.Reembolso
+	if err := r.PostgresqlDB.
+		Preload("Tickets").
+		Preload("NotaCredito").
+		Where("orden_de_compra_id = ?", orderID).
+		Order("reembolso_id DESC").
+		Find(&reembolsos).Error; err != nil {
+		r.logger.Errorf("Reembolso.ListarPorOrden(%d): %v", orderID, err)
+		return nil, err
+	}
+	return reembolsos, nil
+}
+
+// ListarFallidos devuelve los reembolsos FALLIDOS, del más reciente al más antiguo.
+func (r *Reembolso) ListarFallidos(limite int) ([]model.Reembolso, error) {
+	var reembolsos []model.Reembolso
+	if err := r.PostgresqlDB.
+		Preload("Tickets").
+		Where("estado = ?", util.ReembolsoFallido.Codigo()).
+		Order("reembolso_id DESC").
+		Limit(limite).
+		Find(&reembolsos).Error; err != nil {
+		r.logger.Errorf("Reembolso.ListarFallidos: %v", err)
+		return nil, err
+	}
+	return reembolsos, nil
+}
+
+// TicketsReembolsables devuelve los tickets VENDIDOS de la orden que nunca pasaron por la
+// reventa.
+func (r *Reembolso) TicketsReembolsables(orderID int64) ([]int64, error) {
+	var ids []int64
+	if err := r.PostgresqlDB.
+		Model(&model.Ticket{}).
+		Where("orden_de_compra_id = ? AND estado_de_ticket = ?", orderID, util.TicketVendido.Codigo()).
+		Where("NOT EXISTS (SELECT 1 FROM publicacion_reventa pr WHERE pr.ticket_id = ticket.ticket_id AND pr.estado IN ?)",
+			[]int16{util.ReventaPublicada.Codigo(), util.ReventaReservada.Codigo(), util.ReventaVendida.Codigo()}).
+		Order("ticket_id").
+		Pluck("ticket_id", &ids).Error; err != nil {
+		r.logger.Errorf("Reembolso.TicketsReembolsables(%d): %v", orderID, err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// TicketDeOrden es un ticket con la orden que lo compró y quién puede usarlo.
+type TicketDeOrden struct {
+	TicketID        int64
+	OrdenDeCompraID int64
+	TitularID       int64
+	CompradorID     int64
+}
+
+// TicketsConOrden devuelve la orden, el titular y el comprador de cada ticket que exista y se
+// haya vendido en una orden.
+func (r *Reembolso) TicketsConOrden(ticketIDs []int64) ([]TicketDeOrden, error) {
+	var filas []TicketDeOrden
+	if err := r.PostgresqlDB.
+		Table("ticket t").
+		Select("t.ticket_id, t.orden_de_compra_id, COALESCE(t.titular_id, oc.usuario_id) AS titular_id, oc.usuario_id AS comprador_id").
+		Joins("INNER JOIN orden_de_compra oc ON t.orden_de_compra_id = oc.orden_de_compra_id").
+		Where("t.ticket_id IN ?", ticketIDs).
+		Order("t.ticket_id").
+		Scan(&filas).Error; err != nil {
+		r.logger.Errorf("Reembolso.TicketsConOrden: %v", err)
+		return nil, err
+	}
+	return filas, nil
+}
+
+// TicketPorReembolsar es un ticket VENDIDO por reembolsar y de dónde sale su reembolso: de su
+// orden o, si se compró en la reventa, de la publicación VENDIDA.
+type TicketPorReembolsar struct {
+	TicketID             int64
+	OrdenDeCompraID      int64
+	PublicacionReventaID *int64
+}
+
+// OrigenDeTicket devuelve de dónde sale el reembolso de un ticket vendido en una orden: su
+// última publicación VENDIDA si pasó por la reventa o, si no, su orden.
+func (r *Reembolso) OrigenDeTicket(ticketID int64) (*TicketPorReembolsar, error) {
+	var fila TicketPorReembolsar
+	res := r.PostgresqlDB.Raw(`
+		SELECT t.ticket_id, t.orden_de_compra_id, (
+			SELECT pr.publicacion_reventa_id FROM publicacion_reventa pr
+			WHERE pr.ticket_id = t.ticket_id AND pr.estado = ?
+			ORDER BY pr.publicacion_reventa_id DESC
+			LIMIT 1
+		) AS publicacion_reventa_id
+		FROM ticket t
+		WHERE t.ticket_id = ? AND t.orden_de_compra_id IS NOT NULL`,
+		util.ReventaVendida.Codigo(), ticketID,
+	).Scan(&fila)
+	if res.Error != nil {
+		r.logger.Errorf("Reembolso.OrigenDeTicket(%d): %v", ticketID, res.Error)
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &fila, nil
+}
+
+// ticketsPorReembolsarSQL son los tickets VENDIDOS cuyo último comprador tiene un pago capturado
+// (las entradas gratuitas no tienen nada que devolver).
+const ticketsPorReembolsarSQL = `
+	FROM ticket t
+	INNER JOIN evento_fecha ef ON ef.evento_fecha_id = t.evento_fecha_id
+	INNER JOIN evento ev ON ev.evento_id = ef.evento_id
+	LEFT JOIN LATERAL (
+		SELECT pr.publicacion_reventa_id, pr.orden_de_compra_id
+		FROM publicacion_reventa pr
+		WHERE pr.ticket_id = t.ticket_id AND pr.estado = ?
+		ORDER BY pr.publicacion_reventa_id DESC
+		LIMIT 1
+	) rv ON TRUE
+	WHERE ev.evento_estado = ?
+	  AND t.estado_de_ticket = ?
+	  AND t.orden_de_compra_id IS NOT NULL
+	  AND EXISTS (
+		SELECT 1 FROM pago p
+		WHERE p.orden_de_compra_id = COALESCE(rv.orden_de_compra_id, t.orden_de_compra_id)
+		  AND p.estado_de_pago = ?
+	  )`
+
+func argsTicketsPorReembolsar() []any {
+	return []any{
+		util.ReventaVendida.Codigo(), util.EventoCancelado.Codigo(),
+		util.TicketVendido.Codigo(), util.PagoCapturado.Codigo(),
+	}
+}
+
+// ordenesSinTicketsPorReembolsarSQL son las órdenes CONFIRMADAS con un pago capturado que nunca
+// tuvieron tickets ni un reembolso: se pagaron mientras su evento se cancelaba.
+const ordenesSinTicketsPorReembolsarSQL = `
+	FROM orden_de_compra oc
+	INNER JOIN orden_de_compra_detalle d ON d.orden_de_compra_id = oc.orden_de_compra_id
+	INNER JOIN evento_fecha ef ON ef.evento_fecha_id = d.evento_fecha_id
+	INNER JOIN evento ev ON ev.evento_id = ef.evento_id
+	WHERE ev.evento_estado = ?
+	  AND oc.estado_de_orden = ?
+	  AND NOT EXISTS (SELECT 1 FROM ticket t WHERE t.orden_de_compra_id = oc.orden_de_compra_id)
+	  AND NOT EXISTS (SELECT 1 FROM reembolso r WHERE r.orden_de_compra_id = oc.orden_de_compra_id)
+	  AND EXISTS (
+		SELECT 1 FROM pago p
+		WHERE p.orden_de_compra_id = oc.orden_de_compra_id
+		  AND p.estado_de_pago = ?
+	  )`
+
+func argsOrdenesSinTicketsPorReembolsar() []any {
+	return []any{util.EventoCancelado.Codigo(), util.OrdenConfirmada.Codigo(), util.PagoCapturado.Codigo()}
+}
+
+// EventosCanceladosConPendientes devuelve, en orden, hasta limite eventos CANCELADOS con id mayor
+// que despuesDe que aún tienen tickets vendidos u órdenes pagadas sin tickets por reembolsar.
+func (r *Reembolso) EventosCanceladosConPendientes(despuesDe int64, limite int) ([]int64, error) {
+	args := append(argsTicketsPorReembolsar(), argsOrdenesSinTicketsPorReembolsar()...)
+	var ids []int64
+	if err := r.PostgresqlDB.
+		Raw("SELECT evento_id FROM (SELECT ev.evento_id"+ticketsPorReembolsarSQL+
+			" UNION SELECT ev.evento_id"+ordenesSinTicketsPorReembolsarSQL+
+			") pendientes WHERE evento_id > ? ORDER BY evento_id LIMIT ?",
+			append(args, despuesDe, limite)...).
+		Scan(&ids).Error; err != nil {
+		r.logger.Errorf("Reembolso.EventosCanceladosConPendientes: %v", err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// PendientesDeEventoCancelado devuelve los tickets vendidos del evento CANCELADO que aún falta
+// reembolsar.
+func (r *Reembolso) PendientesDeEventoCancelado(eventoID int64) ([]TicketPorReembolsar, error) {
+	var filas []TicketPorReembolsar
+	if err := r.PostgresqlDB.
+		Raw("SELECT t.ticket_id, t.orden_de_compra_id, rv.publicacion_reventa_id"+ticketsPorReembolsarSQL+
+			" AND ev.evento_id = ? ORDER BY t.ticket_id",
+			append(argsTicketsPorReembolsar(), eventoID)...).
+		Scan(&filas).Error; err != nil {
+		r.logger.Errorf("Reembolso.PendientesDeEventoCancelado(%d): %v", eventoID, err)
+		return nil, err
+	}
+	return filas, nil
+}
+
+// OrdenesSinTicketsDeEventoCancelado devuelve las órdenes pagadas del evento CANCELADO que nunca
+// tuvieron tickets y aún falta reembolsar.
+func (r *Reembolso) OrdenesSinTicketsDeEventoCancelado(eventoID int64) ([]int64, error) {
+	var ids []int64
+	if err := r.PostgresqlDB.
+		Raw("SELECT DISTINCT oc.orden_de_compra_id"+ordenesSinTicketsPorReembolsarSQL+
+			" AND ev.evento_id = ? ORDER BY oc.orden_de_compra_id",
+			append(argsOrdenesSinTicketsPorReembolsar(), eventoID)...).
+		Scan(&ids).Error; err != nil {
+		r.logger.Errorf("Reembolso.OrdenesSinTicketsDeEventoCancelado(%d): %v", eventoID, err)
+		return nil, err
+	}
+	return ids, nil
+}
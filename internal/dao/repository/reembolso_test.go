@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestReembolsoParcialRevierteAcumuladosYEmiteNotaDeCredito(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(
+		&model.Evento{}, &model.EventoFecha{}, &model.Ticket{}, &model.Pago{}, &model.ComprobanteDePago{},
+		&model.TransferenciaTicket{}, &model.PublicacionReventa{}, &model.Reembolso{}, &model.ReembolsoTicket{},
+		&model.ReprogramacionFecha{}, &model.ReprogramacionTicket{},
+	); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewReembolsoController(logging.NewLoggerMock(), db)
+	ahora := time.Now().Truncate(time.Microsecond)
+
+	// Dos entradas de 100 con 20 de cupón: se pagan 90 cada una y el fee (4.5) sale del total
+	evento := &model.Evento{Titulo: "Concierto", TotalRecaudado: 175.5, CantVendidoTotal: 2}
+	if err := db.Create(evento).Error; err != nil {
+		t.Fatalf("crear evento: %v", err)
+	}
+	fecha := &model.EventoFecha{EventoID: evento.ID, FechaID: 1, HoraInicio: ahora, GananciaNetaOrganizador: 175.5}
+	if err := db.Create(fecha).Error; err != nil {
+		t.Fatalf("crear fecha: %v", err)
+	}
+	sector := crearSectorPrueba(t, db, "General", 10)
+	db.Model(sector).Update("cant_vendidas", 2)
+
+	orden := &model.OrdenDeCompra{
+		UsuarioID: 1, Fecha: ahora, FechaHoraIni: ahora, Total: 180, MontoFeeServicio: 4.5,
+		EstadoDeOrden: util.OrdenConfirmada.Codigo(),
+		Detalles: []model.OrdenDeCompraDetalle{{
+			TarifaID: 1, SectorID: sector.ID, EventoFechaID: fecha.ID, Cantidad: 2, PrecioUnitario: 100, Descuento: 20,
+		}},
+	}
+	if err := db.Create(orden).Error; err != nil {
+		t.Fatalf("crear orden: %v", err)
+	}
+	pago := &model.Pago{OrdenDeCompraID: orden.ID, MetodoPago: "Tarjeta", Referencia: "fake_1", Monto: 180, EstadoDePago: util.PagoCapturado.Codigo()}
+	if err := db.Create(pago).Error; err != nil {
+		t.Fatalf("crear pago: %v", err)
+	}
+	if err := crearComprobanteDeOrden(db, orden.ID, ahora); err != nil {
+		t.Fatalf("crear comprobante: %v", err)
+	}
+	var tickets [2]model.Ticket
+	for i := range tickets {
+		tickets[i] = model.Ticket{
+			OrdenDeCompraID: &orden.ID, EventoFechaID: fecha.ID, TarifaID: 1,
+			CodigoQR: "qr-" + string(rune('a'+i)), EstadoDeTicket: util.TicketVendido.Codigo(),
+		}
+		if err := db.Create(&tickets[i]).Error; err != nil {
+			t.Fatalf("crear ticket: %v", err)
+		}
+	}
+	cerca := func(a, b float64) bool { return math.Abs(a-b) < 0.001 }
+
+	// El comprador cancela un ticket: se le devuelve lo pagado sin la parte del fee
+	r1, err := repo.Crear(orden.ID, []int64{tickets[0].ID}, OpcionesReembolso{Motivo: model.MotivoReembolsoCancelacionUsuario}, ahora)
+	if err != nil {
+		t.Fatalf("Crear: %v", err)
+	}
+	if !cerca(r1.Monto, 87.75) || r1.MontoFeeServicio != 0 || !cerca(r1.MontoOrganizador, 87.75) {
+		t.Fatalf("montos del reembolso sin fee: %+v", r1)
+	}
+	if _, err := repo.Crear(orden.ID, []int64{tickets[0].ID}, OpcionesReembolso{Motivo: model.MotivoReembolsoAdministrador}, ahora); err != ErrTicketNoReembolsable {
+		t.Fatalf("un ticket ya reembolsado no puede reembolsarse otra vez, se obtuvo %v", err)
+	}
+
+	var ev model.Evento
+	db.First(&ev, "evento_id = ?", evento.ID)
+	var ef model.EventoFecha
+	db.First(&ef, "evento_fecha_id = ?", fecha.ID)
+	var sec model.Sector
+	db.First(&sec, "sector_id = ?", sector.ID)
+	if !cerca(ev.TotalRecaudado, 87.75) || ev.CantVendidoTotal != 1 || !cerca(ef.GananciaNetaOrganizador, 87.75) || sec.CantVendidas != 1 {
+		t.Fatalf("acumulados tras el reembolso: evento=%v/%d fecha=%v sector=%d", ev.TotalRecaudado, ev.CantVendidoTotal, ef.GananciaNetaOrganizador, sec.CantVendidas)
+	}
+	var cancelado model.Ticket
+	db.First(&cancelado, "ticket_id = ?", tickets[0].ID)
+	if cancelado.EstadoDeTicket != util.TicketCancelado.Codigo() {
+		t.Fatalf("el ticket debió quedar CANCELADO, quedó %d", cancelado.EstadoDeTicket)
+	}
+
+	completado, err := repo.Completar(r1.ID, "re_1", ahora)
+	if err != nil {
+		t.Fatalf("Completar: %v", err)
+	}
+	if completado.Estado != util.ReembolsoCompletado.Codigo() || completado.NotaCredito == nil ||
+		completado.NotaCredito.ComprobanteReferenciaID == nil || completado.NotaCredito.Monto == nil || !cerca(*completado.NotaCredito.Monto, 87.75) {
+		t.Fatalf("el reembolso debió completarse con nota de crédito contra la boleta: %+v", completado)
+	}
+	boleta, err := NewComprobanteDePagoController(logging.NewLoggerMock(), db).ObtenerPorOrden(orden.ID)
+	if err != nil || boleta.TipoDeComprobante != util.ComprobanteBoleta.Codigo() || *completado.NotaCredito.ComprobanteReferenciaID != boleta.ID {
+		t.Fatalf("el comprobante de la orden debe seguir siendo la boleta: %+v, %v", boleta, err)
+	}
+
+	// El administrador reembolsa el otro con fee: el fee del primero sigue cobrado
+	r2, err := repo.Crear(orden.ID, []int64{tickets[1].ID}, OpcionesReembolso{Motivo: model.MotivoReembolsoAdministrador, ReembolsarFee: true}, ahora)
+	if err != nil {
+		t.Fatalf("Crear con fee: %v", err)
+	}
+	if !cerca(r2.Monto, 90) || !cerca(r2.MontoFeeServicio, 2.25) || !cerca(r2.MontoOrganizador, 87.75) {
+		t.Fatalf("montos del reembolso con fee: %+v", r2)
+	}
+	if _, err := repo.Completar(r2.ID, "re_2", ahora); err != nil {
+		t.Fatalf("Completar: %v", err)
+	}
+	var final model.Pago
+	db.First(&final, "pago_id = ?", pago.ID)
+	if final.EstadoDePago != util.PagoCapturado.Codigo() {
+		t.Fatalf("con parte del fee cobrada el pago no está reembolsado del todo, quedó %d", final.EstadoDePago)
+	}
+	db.First(&ev, "evento_id = ?", evento.ID)
+	if !cerca(ev.TotalRecaudado, 0) || ev.CantVendidoTotal != 0 {
+		t.Fatalf("el evento debió quedar sin ventas: %v/%d", ev.TotalRecaudado, ev.CantVendidoTotal)
+	}
+	if _, err := repo.Crear(orden.ID, []int64{tickets[1].ID}, OpcionesReembolso{Motivo: model.MotivoReembolsoAdministrador}, ahora); err != ErrTicketNoReembolsable {
+		t.Fatalf("se obtuvo %v", err)
+	}
+}
+
+func TestOrdenPagadaConEventoCanceladoSeReembolsaSinTickets(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(
+		&model.Evento{}, &model.EventoFecha{}, &model.Ticket{}, &model.HistorialTitular{}, &model.EventoDominio{},
+		&model.Pago{}, &model.ComprobanteDePago{}, &model.TransferenciaTicket{}, &model.PublicacionReventa{},
+		&model.Reembolso{}, &model.ReembolsoTicket{}, &model.ReprogramacionFecha{}, &model.ReprogramacionTicket{},
+	); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewReembolsoController(logging.NewLoggerMock(), db)
+	ahora := time.Now().Truncate(time.Microsecond)
+
+	// El evento se cancela mientras el comprador paga su hold
+	evento := crearEventoPrueba(t, db, util.EventoCancelado)
+	fecha := &model.EventoFecha{EventoID: evento.ID, FechaID: 1, HoraInicio: ahora}
+	if err := db.Create(fecha).Error; err != nil {
+		t.Fatalf("crear fecha: %v", err)
+	}
+	sector := &model.Sector{EventoID: evento.ID, SectorTipo: "General", TotalEntradas: 10, CantVendidas: 2}
+	if err := db.Create(sector).Error; err != nil {
+		t.Fatalf("crear sector: %v", err)
+	}
+	orden := &model.OrdenDeCompra{
+		UsuarioID: 1, Fecha: ahora, FechaHoraIni: ahora, Total: 180, MontoFeeServicio: 4.5,
+		EstadoDeOrden: util.OrdenTemporal.Codigo(),
+		Detalles: []model.OrdenDeCompraDetalle{{
+			TarifaID: 1, SectorID: sector.ID, EventoFechaID: fecha.ID, Cantidad: 2, PrecioUnitario: 100, Descuento: 20,
+		}},
+	}
+	if err := db.Create(orden).Error; err != nil {
+		t.Fatalf("crear orden: %v", err)
+	}
+	pago := &model.Pago{OrdenDeCompraID: orden.ID, MetodoPago: "Tarjeta", Referencia: "fake_1", Monto: 180}
+	if err := db.Create(pago).Error; err != nil {
+		t.Fatalf("crear pago: %v", err)
+	}
+	firmar := func(tk *model.Ticket) (string, error) { return fmt.Sprintf("qr-%d", tk.ID), nil }
+	if _, err := NewOrdenDeCompraController(logging.NewLoggerMock(), db).ConfirmarOrdenPagada(orden.ID, pago.ID, 1, firmar); err != nil {
+		t.Fatalf("ConfirmarOrdenPagada: %v", err)
+	}
+	var emitidos int64
+	db.Model(&model.Ticket{}).Where("orden_de_compra_id = ?", orden.ID).Count(&emitidos)
+	if emitidos != 0 {
+		t.Fatalf("no se emiten tickets de un evento cancelado, se emitieron %d", emitidos)
+	}
+	if err := NewTicketController(logging.NewLoggerMock(), db).EmitirTicketsDeOrden(orden.ID, nil, firmar); err != ErrEventoNoALaVenta {
+		t.Fatalf("la emisión tardía debió rechazarse con ErrEventoNoALaVenta, se obtuvo %v", err)
+	}
+
+	eventos, err := repo.EventosCanceladosConPendientes(0, 10)
+	if err != nil || len(eventos) != 1 || eventos[0] != evento.ID {
+		t.Fatalf("el evento debió quedar pendiente de reembolso: %v, %v", eventos, err)
+	}
+	if siguientes, err := repo.EventosCanceladosConPendientes(evento.ID, 10); err != nil || len(siguientes) != 0 {
+		t.Fatalf("después del evento no hay otro pendiente: %v, %v", siguientes, err)
+	}
+	ordenes, err := repo.OrdenesSinTicketsDeEventoCancelado(evento.ID)
+	if err != nil || len(ordenes) != 1 || ordenes[0] != orden.ID {
+		t.Fatalf("la orden sin tickets debió quedar pendiente: %v, %v", ordenes, err)
+	}
+
+	r, err := repo.CrearPorOrden(orden.ID, OpcionesReembolso{Motivo: model.MotivoReembolsoCancelacionEvento, ReembolsarFee: true}, ahora)
+	if err != nil {
+		t.Fatalf("CrearPorOrden: %v", err)
+	}
+	cerca := func(a, b float64) bool { return math.Abs(a-b) < 0.001 }
+	if !cerca(r.Monto, 180) || !cerca(r.MontoFeeServicio, 4.5) || !cerca(r.MontoOrganizador, 175.5) {
+		t.Fatalf("se debió devolver todo lo cobrado: %+v", r)
+	}
+	var ev model.Evento
+	db.First(&ev, "evento_id = ?", evento.ID)
+	var sec model.Sector
+	db.First(&sec, "sector_id = ?", sector.ID)
+	if !cerca(ev.TotalRecaudado, 0) || ev.CantVendidoTotal != 0 || sec.CantVendidas != 0 {
+		t.Fatalf("la venta debió revertirse: evento=%v/%d sector=%d", ev.TotalRecaudado, ev.CantVendidoTotal, sec.CantVendidas)
+	}
+	if _, err := repo.CrearPorOrden(orden.ID, OpcionesReembolso{Motivo: model.MotivoReembolsoCancelacionEvento, ReembolsarFee: true}, ahora); err != ErrOrdenNoReembolsable {
+		t.Fatalf("la orden no puede reembolsarse dos veces, se obtuvo %v", err)
+	}
+	if eventos, err := repo.EventosCanceladosConPendientes(0, 10); err != nil || len(eventos) != 0 {
+		t.Fatalf("ya no debió quedar nada pendiente: %v, %v", eventos, err)
+	}
+}
+
+func TestReembolsoRechazaFechaCerradaParaCancelar(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(
+		&model.Evento{}, &model.Fecha{}, &model.EventoFecha{}, &model.Ticket{}, &model.Pago{},
+		&model.TransferenciaTicket{}, &model.PublicacionReventa{}, &model.Reembolso{}, &model.ReembolsoTicket{},
+	); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewReembolsoController(logging.NewLoggerMock(), db)
+	ahora := time.Now().Truncate(time.Microsecond)
+
+	// La función fue ayer: el comprador ya no puede cancelar, un administrador sí
+	dia := model.Fecha{FechaEvento: ahora.AddDate(0, 0, -1).Truncate(24 * time.Hour)}
+	if err := db.Where("fecha_evento = ?", dia.FechaEvento).FirstOrCreate(&dia).Error; err != nil {
+		t.Fatalf("crear día: %v", err)
+	}
+	evento := &model.Evento{Titulo: "Función pasada", TotalRecaudado: 100, CantVendidoTotal: 1}
+	if err := db.Create(evento).Error; err != nil {
+		t.Fatalf("crear evento: %v", err)
+	}
+	fecha := &model.EventoFecha{EventoID: evento.ID, FechaID: dia.ID, HoraInicio: ahora, GananciaNetaOrganizador: 100}
+	if err := db.Create(fecha).Error; err != nil {
+		t.Fatalf("crear fecha: %v", err)
+	}
+	sector := crearSectorPrueba(t, db, "General", 10)
+	db.Model(sector).Update("cant_vendidas", 1)
+	orden := &model.OrdenDeCompra{
+		UsuarioID: 1, Fecha: ahora, FechaHoraIni: ahora, Total: 100,
+		EstadoDeOrden: util.OrdenConfirmada.Codigo(),
+		Detalles: []model.OrdenDeCompraDetalle{{
+			TarifaID: 1, SectorID: sector.ID, EventoFechaID: fecha.ID, Cantidad: 1, PrecioUnitario: 100,
+		}},
+	}
+	if err := db.Create(orden).Error; err != nil {
+		t.Fatalf("crear orden: %v", err)
+	}
+	if err := db.Create(&model.Pago{OrdenDeCompraID: orden.ID, MetodoPago: "Tarjeta", Referencia: fmt.Sprintf("fake_cerrada_%d", orden.ID), Monto: 100, EstadoDePago: util.PagoCapturado.Codigo()}).Error; err != nil {
+		t.Fatalf("crear pago: %v", err)
+	}
+	ticket := &model.Ticket{
+		OrdenDeCompraID: &orden.ID, EventoFechaID: fecha.ID, TarifaID: 1,
+		CodigoQR: fmt.Sprintf("qr-cerrada-%d", orden.ID), EstadoDeTicket: util.TicketVendido.Codigo(),
+	}
+	if err := db.Create(ticket).Error; err != nil {
+		t.Fatalf("crear ticket: %v", err)
+	}
+
+	cerrada := func(ef *model.EventoFecha) bool {
+		return ef.Fecha == nil || !ahora.Before(ef.Fecha.FechaEvento.AddDate(0, 0, 1))
+	}
+	opciones := OpcionesReembolso{Motivo: model.MotivoReembolsoCancelacionUsuario, FechaCerrada: cerrada}
+	if _, err := repo.Crear(orden.ID, []int64{ticket.ID}, opciones, ahora); err != ErrCancelacionCerrada {
+		t.Fatalf("con la fecha ya pasada se esperaba ErrCancelacionCerrada, se obtuvo %v", err)
+	}
+	var sigue model.Ticket
+	db.First(&sigue, "ticket_id = ?", ticket.ID)
+	if sigue.EstadoDeTicket != util.TicketVendido.Codigo() {
+		t.Fatalf("el ticket debió seguir VENDIDO, quedó %d", sigue.EstadoDeTicket)
+	}
+
+	if _, err := repo.Crear(orden.ID, []int64{ticket.ID}, OpcionesReembolso{Motivo: model.MotivoReembolsoAdministrador}, ahora); err != nil {
+		t.Fatalf("sin corte el reembolso debió crearse: %v", err)
+	}
+}
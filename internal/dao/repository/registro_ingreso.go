@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+type RegistroIngreso struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewRegistroIngresoController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *RegistroIngreso {
+	return &RegistroIngreso{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+// Registrar pasa el ticket de VENDIDO a USADO y guarda el check-in en una sola transacción.
+// El UPDATE es condicional al estado VENDIDO: si dos puertas escanean el mismo ticket a la vez,
+// solo una lo actualiza y la otra recibe registrado = false.
+func (r *RegistroIngreso) Registrar(ingreso *model.RegistroIngreso) (bool, error) {
+	registrado := false
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Model(&model.Ticket{}).
+			Where("ticket_id = ? AND estado_de_ticket = ?", ingreso.TicketID, util.TicketVendido.Codigo()).
+			Update("estado_de_ticket", util.TicketUsado.Codigo())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Create(ingreso).Error; err != nil {
+			return err
+		}
+		registrado = true
+		return nil
+	})
+	if err != nil {
+		r.logger.Errorf("RegistroIngreso.Registrar(ticket=%d): %v", ingreso.TicketID, err)
+		return false, err
+	}
+	return registrado, nil
+}
+
+// ObtenerPorTicket devuelve el check-in de un ticket ya usado.
+func (r *RegistroIngreso) ObtenerPorTicket(ticketID int64) (*model.RegistroIngreso, error) {
+	var ingreso model.RegistroIngreso
+	if err := r.PostgresqlDB.
+		Where("ticket_id = ?", ticketID).
+		First(&ingreso).Error; err != nil {
+		return nil, err
+	}
+	return &ingreso, nil
+}
+
+// ResultadoIngresoOffline es cómo quedó un escaneo offline al conciliarlo con la BD.
+type ResultadoIngresoOffline struct {
+	Admitido     bool
+	EstadoTicket int16                  // estado del ticket al conciliar
+	Vigente      *model.RegistroIngreso // check-in que quedó registrado para el ticket (si hay)
+}
+
+// RegistrarOffline concilia un escaneo hecho sin conexión. Si el ticket sigue VENDIDO se admite
+// como en Registrar. Si ya está USADO gana el escaneo más temprano: cuando este escaneo es
+// anterior al registrado, lo reemplaza. Reenviar el mismo escaneo del mismo dispositivo
+// devuelve el mismo resultado. El ticket se bloquea con FOR UPDATE durante la conciliación.
+func (r *RegistroIngreso) RegistrarOffline(ingreso *model.RegistroIngreso) (*ResultadoIngresoOffline, error) {
+	var resultado ResultadoIngresoOffline
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		var ticket model.Ticket
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("ticket_id = ?", ingreso.TicketID).
+			First(&ticket).Error; err != nil {
+			return err
+		}
+		resultado.EstadoTicket = ticket.EstadoDeTicket
+
+		switch ticket.EstadoDeTicket {
+		case util.TicketVendido.Codigo():
+			if err := tx.
+				Model(&model.Ticket{}).
+				Where("ticket_id = ?", ticket.ID).
+				Update("estado_de_ticket", util.TicketUsado.Codigo()).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(ingreso).Error; err != nil {
+				return err
+			}
+			resultado.Admitido = true
+			resultado.EstadoTicket = util.TicketUsado.Codigo()
+			resultado.Vigente = ingreso
+			return nil
+
+		case util.TicketUsado.Codigo():
+			var vigente model.RegistroIngreso
+			err := tx.
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("ticket_id = ?", ticket.ID).
+				First(&vigente).Error
+			if err != nil {
+				if err == gorm.ErrRecordNotFound {
+					// Usado sin registro de ingreso (datos anteriores al check-in): no se toca
+					return nil
+				}
+				return err
+			}
+
+			mismoEscaneo := vigente.FechaIngreso.Equal(ingreso.FechaIngreso) &&
+				vigente.Dispositivo != nil && ingreso.Dispositivo != nil && *vigente.Dispositivo == *ingreso.Dispositivo
+			if mismoEscaneo {
+				resultado.Admitido = true
+				resultado.Vigente = &vigente
+				return nil
+			}
+
+			if ingreso.FechaIngreso.Before(vigente.FechaIngreso) {
+				if err := tx.
+					Model(&vigente).
+					Updates(map[string]any{
+						"puerta":        ingreso.Puerta,
+						"operador_id":   ingreso.OperadorID,
+						"dispositivo":   ingreso.Dispositivo,
+						"fecha_ingreso": ingreso.FechaIngreso,
+					}).Error; err != nil {
+					return err
+				}
+				ingreso.ID = vigente.ID
+				resultado.Admitido = true
+				resultado.Vigente = ingreso
+				return nil
+			}
+
+			resultado.Vigente = &vigente
+			return nil
+
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			r.logger.Errorf("RegistroIngreso.RegistrarOffline(ticket=%d): %v", ingreso.TicketID, err)
+		}
+		return nil, err
+	}
+	return &resultado, nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestRegistrarIngresoAdmiteUnaSolaVez(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Ticket{}, &model.RegistroIngreso{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewRegistroIngresoController(logging.NewLoggerMock(), db)
+
+	ticket := &model.Ticket{EventoFechaID: 1, TarifaID: 1, CodigoQR: "qr-prueba", EstadoDeTicket: util.TicketVendido.Codigo()}
+	if err := db.Create(ticket).Error; err != nil {
+		t.Fatalf("crear ticket: %v", err)
+	}
+
+	const puertas = 20
+	var (
+		wg        sync.WaitGroup
+		admitidos atomic.Int32
+	)
+	inicio := make(chan struct{})
+	for i := 0; i < puertas; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-inicio
+			ok, err := repo.Registrar(&model.RegistroIngreso{
+				TicketID:      ticket.ID,
+				EventoFechaID: 1,
+				Puerta:        "puerta",
+				OperadorID:    int64(i + 1),
+				FechaIngreso:  time.Now(),
+			})
+			if err != nil {
+				t.Errorf("puerta %d: %v", i, err)
+				return
+			}
+			if ok {
+				admitidos.Add(1)
+			}
+		}(i)
+	}
+	close(inicio)
+	wg.Wait()
+
+	if admitidos.Load() != 1 {
+		t.Fatalf("el ticket fue admitido %d veces", admitidos.Load())
+	}
+
+	var actualizado model.Ticket
+	db.First(&actualizado, "ticket_id = ?", ticket.ID)
+	if actualizado.EstadoDeTicket != util.TicketUsado.Codigo() {
+		t.Fatalf("el ticket quedó en estado %d, se esperaba USADO", actualizado.EstadoDeTicket)
+	}
+	var registros int64
+	db.Model(&model.RegistroIngreso{}).Where("ticket_id = ?", ticket.ID).Count(&registros)
+	if registros != 1 {
+		t.Fatalf("se esperaba 1 registro de ingreso, hay %d", registros)
+	}
+}
+
+func TestRegistrarOfflineGanaElEscaneoMasTemprano(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Ticket{}, &model.RegistroIngreso{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewRegistroIngresoController(logging.NewLoggerMock(), db)
+
+	ticket := &model.Ticket{EventoFechaID: 1, TarifaID: 1, CodigoQR: "qr-offline", EstadoDeTicket: util.TicketVendido.Codigo()}
+	if err := db.Create(ticket).Error; err != nil {
+		t.Fatalf("crear ticket: %v", err)
+	}
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+	escaneo := func(dispositivo string, hora time.Time) *ResultadoIngresoOffline {
+		t.Helper()
+		res, err := repo.RegistrarOffline(&model.RegistroIngreso{
+			TicketID:      ticket.ID,
+			EventoFechaID: 1,
+			Puerta:        dispositivo,
+			OperadorID:    1,
+			Dispositivo:   &dispositivo,
+			FechaIngreso:  hora,
+		})
+		if err != nil {
+			t.Fatalf("RegistrarOffline(%s): %v", dispositivo, err)
+		}
+		return res
+	}
+
+	if res := escaneo("B", base.Add(5*time.Minute)); !res.Admitido {
+		t.Fatal("el primer escaneo sincronizado debió admitirse")
+	}
+	if res := escaneo("A", base); !res.Admitido {
+		t.Fatal("un escaneo anterior de otro dispositivo debió reemplazar al registrado")
+	}
+	if res := escaneo("B", base.Add(5*time.Minute)); res.Admitido || *res.Vigente.Dispositivo != "A" {
+		t.Fatalf("el escaneo posterior debió quedar como duplicado del de A, se obtuvo %+v", res)
+	}
+	if res := escaneo("A", base); !res.Admitido {
+		t.Fatal("reenviar el mismo escaneo debió devolver el mismo resultado")
+	}
+
+	var registros []model.RegistroIngreso
+	db.Where("ticket_id = ?", ticket.ID).Find(&registros)
+	if len(registros) != 1 || *registros[0].Dispositivo != "A" || !registros[0].FechaIngreso.Equal(base) {
+		t.Fatalf("debió quedar un solo registro, el de A, se obtuvo %+v", registros)
+	}
+}
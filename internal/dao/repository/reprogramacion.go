@@ -0,0 +1,429 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Reprogramacion struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewReprogramacionController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *Reprogramacion {
+	return &Reprogramacion{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+var (
+	// ErrFechaNoReprogramable indica que la fecha ya no está activa, que el evento no está
+	// PUBLICADO ni POSTERGADO o que alguien ya entró con su ticket.
+	ErrFechaNoReprogramable = errors.New("la fecha no se puede reprogramar")
+	// ErrFechaDestinoOcupada indica que el evento ya tiene una fecha ese día a esa hora.
+	ErrFechaDestinoOcupada = errors.New("el evento ya tiene una fecha ese día a esa hora")
+	// ErrReprogramacionNoPendiente indica que el ticket no tiene una reprogramación por responder.
+	ErrReprogramacionNoPendiente = errors.New("el ticket no tiene una reprogramación pendiente")
+	// ErrReprogramacionVencida indica que ya pasó el plazo para responder.
+	ErrReprogramacionVencida = errors.New("venció el plazo para responder la reprogramación")
+)
+
+// DatosReprogramacion es a qué día y hora se mueve la fecha y hasta cuándo pueden responder los
+// titulares.
+type DatosReprogramacion struct {
+	Dia         time.Time // solo cuenta el día
+	HoraInicio  time.Time // solo cuenta la hora
+	FechaLimite time.Time
+	UsuarioID   int64
+}
+
+// Reprogramar mueve una fecha del evento con entradas vendidas, en una sola transacción: crea la
+// nueva fecha con la ganancia de la original, le pasa los tickets VENDIDOS con un QR nuevo
+// firmado con firmar (el QR lleva la fecha) y las líneas de las órdenes vigentes, cancela las
+// órdenes TEMPORAL y las publicaciones de reventa de la fecha original y la deja inactiva, como
+// historial. Cada ticket migrado queda PENDIENTE de respuesta hasta datos.FechaLimite y se
+// registra FECHA_REPROGRAMADA para avisar a sus titulares.
+func (r *Reprogramacion) Reprogramar(
+	eventoFechaID int64,
+	datos DatosReprogramacion,
+	firmar func(*model.Ticket) (string, error),
+	ahora time.Time,
+) (*model.ReprogramacionFecha, error) {
+	var reprogramacion *model.ReprogramacionFecha
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		var origen model.EventoFecha
+		if err := tx.First(&origen, "evento_fecha_id = ?", eventoFechaID).Error; err != nil {
+			return err
+		}
+
+		// Primero las órdenes TEMPORAL de la fecha, esperando a las que se están confirmando: la
+		// confirmación bloquea la orden antes que el evento, así que tomarlas después de
+		// bloquear el evento podría trabar ambas transacciones
+		ordenesDeLaFecha := `(orden_de_compra_id IN (
+			SELECT d.orden_de_compra_id FROM orden_de_compra_detalle d WHERE d.evento_fecha_id = ?
+		) OR orden_de_compra_id IN (
+			SELECT pr.orden_de_compra_id FROM publicacion_reventa pr
+			INNER JOIN ticket t ON t.ticket_id = pr.ticket_id
+			WHERE t.evento_fecha_id = ? AND pr.estado = ?
+		))`
+		argsOrdenes := []any{origen.ID, origen.ID, util.ReventaReservada.Codigo()}
+		var temporales []int64
+		if err := tx.
+			Model(&model.OrdenDeCompra{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("estado_de_orden = ?", util.OrdenTemporal.Codigo()).
+			Where(ordenesDeLaFecha, argsOrdenes...).
+			Order("orden_de_compra_id").
+			Pluck("orden_de_compra_id", &temporales).Error; err != nil {
+			return err
+		}
+
+		// El evento FOR UPDATE frena los holds nuevos, que lo leen FOR SHARE
+		var evento model.Evento
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("evento_id", "evento_estado", "estado").
+			First(&evento, "evento_id = ?", origen.EventoID).Error; err != nil {
+			return err
+		}
+		if evento.Estado != util.Activo.Codigo() ||
+			(evento.EventoEstado != util.EventoPublicado.Codigo() && evento.EventoEstado != util.EventoPostergado.Codigo()) {
+			return ErrFechaNoReprogramable
+		}
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&origen, "evento_fecha_id = ?", eventoFechaID).Error; err != nil {
+			return err
+		}
+		if origen.Estado != util.Activo.Codigo() {
+			return ErrFechaNoReprogramable
+		}
+		var usados int64
+		if err := tx.Model(&model.Ticket{}).
+			Where("evento_fecha_id = ? AND estado_de_ticket = ?", origen.ID, util.TicketUsado.Codigo()).
+			Count(&usados).Error; err != nil {
+			return err
+		}
+		if usados > 0 {
+			return ErrFechaNoReprogramable
+		}
+
+		dia := time.Date(datos.Dia.Year(), datos.Dia.Month(), datos.Dia.Day(), 0, 0, 0, 0, time.UTC)
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.Fecha{FechaEvento: dia}).Error; err != nil {
+			return err
+		}
+		var fecha model.Fecha
+		if err := tx.First(&fecha, "fecha_evento = ?", dia).Error; err != nil {
+			return err
+		}
+		var ocupadas int64
+		if err := tx.Model(&model.EventoFecha{}).
+			Where("evento_id = ? AND fecha_id = ? AND hora_inicio = ?::time",
+				origen.EventoID, fecha.ID, datos.HoraInicio.Format("15:04:05")).
+			Count(&ocupadas).Error; err != nil {
+			return err
+		}
+		if ocupadas > 0 {
+			return ErrFechaDestinoOcupada
+		}
+		destino := model.EventoFecha{
+			EventoID:                origen.EventoID,
+			FechaID:                 fecha.ID,
+			HoraInicio:              datos.HoraInicio,
+			GananciaNetaOrganizador: origen.GananciaNetaOrganizador,
+			Estado:                  util.Activo.Codigo(),
+			UsuarioCreacion:         &datos.UsuarioID,
+			FechaCreacion:           ahora,
+		}
+		if err := tx.Create(&destino).Error; err != nil {
+			return err
+		}
+
+		// Una orden creada después de la primera pasada y bloqueada ahora solo puede estar
+		// confirmándose: sus líneas se mueven igual más abajo y sus tickets se emiten en la
+		// fecha nueva
+		if err := tx.
+			Model(&model.OrdenDeCompra{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("estado_de_orden = ?", util.OrdenTemporal.Codigo()).
+			Where(ordenesDeLaFecha, argsOrdenes...).
+			Order("orden_de_compra_id").
+			Pluck("orden_de_compra_id", &temporales).Error; err != nil {
+			return err
+		}
+		for _, orderID := range temporales {
+			if err := cancelarOrdenTemporalTx(tx, orderID); err != nil && err != ErrOrdenNoTemporal {
+				return err
+			}
+		}
+		// Las publicaciones describen la fecha original
+		if err := tx.Model(&model.PublicacionReventa{}).
+			Where("estado = ? AND ticket_id IN (SELECT ticket_id FROM ticket WHERE evento_fecha_id = ?)",
+				util.ReventaPublicada.Codigo(), origen.ID).
+			Update("estado", util.ReventaCancelada.Codigo()).Error; err != nil {
+			return err
+		}
+
+		var tickets []model.Ticket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("evento_fecha_id = ? AND estado_de_ticket = ?", origen.ID, util.TicketVendido.Codigo()).
+			Order("ticket_id").
+			Find(&tickets).Error; err != nil {
+			return err
+		}
+		ticketIDs := make([]int64, 0, len(tickets))
+		for i := range tickets {
+			tickets[i].EventoFechaID = destino.ID
+			codigo, err := firmar(&tickets[i])
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Ticket{}).
+				Where("ticket_id = ?", tickets[i].ID).
+				Updates(map[string]any{
+					"evento_fecha_id": destino.ID,
+					"codigo_qr":       codigo,
+				}).Error; err != nil {
+				return err
+			}
+			ticketIDs = append(ticketIDs, tickets[i].ID)
+		}
+		// Los reembolsos buscan la línea del ticket por su fecha: las líneas se mueven con él
+		if err := tx.Model(&model.OrdenDeCompraDetalle{}).
+			Where("evento_fecha_id = ?", origen.ID).
+			Where("orden_de_compra_id IN (SELECT orden_de_compra_id FROM orden_de_compra WHERE estado_de_orden != ?)",
+				util.OrdenCancelada.Codigo()).
+			Update("evento_fecha_id", destino.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.EventoFecha{}).
+			Where("evento_fecha_id = ?", origen.ID).
+			Updates(map[string]any{
+				"estado":                    util.Inactivo.Codigo(),
+				"ganancia_neta_organizador": 0,
+				"usuario_modificacion":      datos.UsuarioID,
+				"fecha_modificacion":        ahora,
+			}).Error; err != nil {
+			return err
+		}
+
+		// Una reprogramación nueva reemplaza la respuesta pendiente de una anterior
+		if len(ticketIDs) > 0 {
+			if err := tx.Model(&model.ReprogramacionTicket{}).
+				Where("ticket_id IN ? AND estado = ?", ticketIDs, util.ReprogramacionPendiente.Codigo()).
+				Updates(map[string]any{
+					"estado":          util.ReprogramacionVencida.Codigo(),
+					"fecha_respuesta": ahora,
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		reprogramacion = &model.ReprogramacionFecha{
+			EventoID:             origen.EventoID,
+			EventoFechaOrigenID:  origen.ID,
+			EventoFechaDestinoID: destino.ID,
+			FechaLimite:          datos.FechaLimite,
+			TicketsMigrados:      int64(len(ticketIDs)),
+			UsuarioCreacion:      &datos.UsuarioID,
+			FechaCreacion:        ahora,
+		}
+		for _, id := range ticketIDs {
+			reprogramacion.Tickets = append(reprogramacion.Tickets, model.ReprogramacionTicket{
+				TicketID: id,
+				Estado:   util.ReprogramacionPendiente.Codigo(),
+			})
+		}
+		if err := tx.Create(reprogramacion).Error; err != nil {
+			return err
+		}
+		return registrarEventoDominio(tx, model.EventoFechaReprogramada, reprogramacion.ID,
+			model.PayloadFechaReprogramada{ReprogramacionID: reprogramacion.ID, EventoID: origen.EventoID}, ahora)
+	})
+	if err != nil {
+		if err != gorm.ErrRecordNotFound && err != ErrFechaNoReprogramable && err != ErrFechaDestinoOcupada {
+			r.logger.Errorf("Reprogramacion.Reprogramar(evento_fecha_id=%d): %v", eventoFechaID, err)
+		}
+		return nil, err
+	}
+	return reprogramacion, nil
+}
+
+// ObtenerConFechas devuelve la reprogramación con sus fechas de origen y destino y el día de
+// cada una.
+func (r *Reprogramacion) ObtenerConFechas(id int64) (*model.ReprogramacionFecha, error) {
+	var reprogramacion model.ReprogramacionFecha
+	if err := r.PostgresqlDB.
+		Preload("Origen.Fecha").
+		Preload("Destino.Fecha").
+		Preload("Destino.Evento").
+		First(&reprogramacion, "reprogramacion_fecha_id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &reprogramacion, nil
+}
+
+// PendienteDeTicket devuelve la respuesta PENDIENTE del ticket con su reprogramación o
+// ErrReprogramacionNoPendiente.
+func (r *Reprogramacion) PendienteDeTicket(ticketID int64) (*model.ReprogramacionTicket, error) {
+	var respuesta model.ReprogramacionTicket
+	err := r.PostgresqlDB.
+		Preload("ReprogramacionFecha").
+		Where("ticket_id = ? AND estado = ?", ticketID, util.ReprogramacionPendiente.Codigo()).
+		First(&respuesta).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrReprogramacionNoPendiente
+	}
+	if err != nil {
+		r.logger.Errorf("Reprogramacion.PendienteDeTicket(%d): %v", ticketID, err)
+		return nil, err
+	}
+	return &respuesta, nil
+}
+
+// Aceptar registra que el titular se queda con el ticket en la fecha nueva. Si ya respondió
+// devuelve ErrReprogramacionNoPendiente; si venció el plazo, ErrReprogramacionVencida.
+func (r *Reprogramacion) Aceptar(ticketID int64, ahora time.Time) (*model.ReprogramacionTicket, error) {
+	var respuesta model.ReprogramacionTicket
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("ticket_id = ? AND estado = ?", ticketID, util.ReprogramacionPendiente.Codigo()).
+			First(&respuesta).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrReprogramacionNoPendiente
+			}
+			return err
+		}
+		var reprogramacion model.ReprogramacionFecha
+		if err := tx.First(&reprogramacion, "reprogramacion_fecha_id = ?", respuesta.ReprogramacionFechaID).Error; err != nil {
+			return err
+		}
+		if !ahora.Before(reprogramacion.FechaLimite) {
+			return ErrReprogramacionVencida
+		}
+		respuesta.Estado = util.ReprogramacionAceptada.Codigo()
+		respuesta.FechaRespuesta = &ahora
+		respuesta.ReprogramacionFecha = &reprogramacion
+		return tx.Model(&model.ReprogramacionTicket{}).
+			Where("reprogramacion_fecha_id = ? AND ticket_id = ?", respuesta.ReprogramacionFechaID, ticketID).
+			Updates(map[string]any{
+				"estado":          respuesta.Estado,
+				"fecha_respuesta": ahora,
+			}).Error
+	})
+	if err != nil {
+		if err != ErrReprogramacionNoPendiente && err != ErrReprogramacionVencida {
+			r.logger.Errorf("Reprogramacion.Aceptar(ticket=%d): %v", ticketID, err)
+		}
+		return nil, err
+	}
+	return &respuesta, nil
+}
+
+// CerrarVencidas cierra las reprogramaciones cuyo plazo ya pasó: lo que nadie respondió queda
+// VENCIDO, que cuenta como aceptado. Devuelve cuántas cerró.
+func (r *Reprogramacion) CerrarVencidas(ahora time.Time) (int, error) {
+	var ids []int64
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Model(&model.ReprogramacionFecha{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("fecha_cierre IS NULL AND fecha_limite <= ?", ahora).
+			Order("reprogramacion_fecha_id").
+			Pluck("reprogramacion_fecha_id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Model(&model.ReprogramacionTicket{}).
+			Where("reprogramacion_fecha_id IN ? AND estado = ?", ids, util.ReprogramacionPendiente.Codigo()).
+			Updates(map[string]any{
+				"estado":          util.ReprogramacionVencida.Codigo(),
+				"fecha_respuesta": ahora,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.ReprogramacionFecha{}).
+			Where("reprogramacion_fecha_id IN ?", ids).
+			Update("fecha_cierre", ahora).Error
+	})
+	if err != nil {
+		r.logger.Errorf("Reprogramacion.CerrarVencidas: %v", err)
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// ResumenReprogramacion cuenta cómo respondieron los titulares a una reprogramación.
+type ResumenReprogramacion struct {
+	ReprogramacionID     int64      `gorm:"column:reprogramacion_fecha_id"`
+	EventoID             int64      `gorm:"column:evento_id"`
+	EventoFechaOrigenID  int64      `gorm:"column:evento_fecha_origen_id"`
+	EventoFechaDestinoID int64      `gorm:"column:evento_fecha_destino_id"`
+	FechaLimite          time.Time  `gorm:"column:fecha_limite"`
+	FechaCierre          *time.Time `gorm:"column:fecha_cierre"`
+	TicketsMigrados      int64      `gorm:"column:tickets_migrados"`
+	Pendientes           int64      `gorm:"column:pendientes"`
+	Aceptadas            int64      `gorm:"column:aceptadas"`
+	Reembolsadas         int64      `gorm:"column:reembolsadas"`
+	Vencidas             int64      `gorm:"column:vencidas"`
+}
+
+// ResumenDeEventos devuelve el resumen de cada reprogramación de los eventos indicados.
+func (r *Reprogramacion) ResumenDeEventos(eventoIDs []int64) ([]ResumenReprogramacion, error) {
+	var filas []ResumenReprogramacion
+	if len(eventoIDs) == 0 {
+		return filas, nil
+	}
+	if err := r.PostgresqlDB.
+		Table("reprogramacion_fecha rf").
+		Select(`rf.reprogramacion_fecha_id, rf.evento_id, rf.evento_fecha_origen_id, rf.evento_fecha_destino_id,
+			rf.fecha_limite, rf.fecha_cierre, rf.tickets_migrados,
+			COUNT(rt.ticket_id) FILTER (WHERE rt.estado = ?) AS pendientes,
+			COUNT(rt.ticket_id) FILTER (WHERE rt.estado = ?) AS aceptadas,
+			COUNT(rt.ticket_id) FILTER (WHERE rt.estado = ?) AS reembolsadas,
+			COUNT(rt.ticket_id) FILTER (WHERE rt.estado = ?) AS vencidas`,
+			util.ReprogramacionPendiente.Codigo(), util.ReprogramacionAceptada.Codigo(),
+			util.ReprogramacionReembolsada.Codigo(), util.ReprogramacionVencida.Codigo()).
+		Joins("LEFT JOIN reprogramacion_ticket rt ON rt.reprogramacion_fecha_id = rf.reprogramacion_fecha_id").
+		Where("rf.evento_id IN ?", eventoIDs).
+		Group("rf.reprogramacion_fecha_id").
+		Order("rf.reprogramacion_fecha_id").
+		Scan(&filas).Error; err != nil {
+		r.logger.Errorf("Reprogramacion.ResumenDeEventos: %v", err)
+		return nil, err
+	}
+	return filas, nil
+}
+
+// TitularesPendientes devuelve a quién avisar de una reprogramación: los titulares de los
+// tickets que todavía no respondieron.
+func (r *Reprogramacion) TitularesPendientes(reprogramacionID int64) ([]TitularTicket, error) {
+	var rows []TitularTicket
+	if err := r.PostgresqlDB.
+		Table("reprogramacion_ticket rt").
+		Select("DISTINCT u.usuario_id, u.nombre, u.correo").
+		Joins("JOIN ticket t ON t.ticket_id = rt.ticket_id").
+		Joins("LEFT JOIN orden_de_compra o ON o.orden_de_compra_id = t.orden_de_compra_id").
+		Joins("JOIN usuario u ON u.usuario_id = COALESCE(t.titular_id, o.usuario_id)").
+		Where("rt.reprogramacion_fecha_id = ? AND rt.estado = ?", reprogramacionID, util.ReprogramacionPendiente.Codigo()).
+		Where("t.estado_de_ticket = ?", util.TicketVendido.Codigo()).
+		Order("u.usuario_id").
+		Find(&rows).Error; err != nil {
+		r.logger.Errorf("Reprogramacion.TitularesPendientes(%d): %v", reprogramacionID, err)
+		return nil, err
+	}
+	return rows, nil
+}
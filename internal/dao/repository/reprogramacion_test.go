@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestReprogramarMueveTicketsYLineasALaNuevaFecha(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(
+		&model.Fecha{}, &model.Ticket{}, &model.PublicacionReventa{}, &model.EventoDominio{},
+		&model.ReprogramacionFecha{}, &model.ReprogramacionTicket{},
+	); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	eventos := NewEventoController(logging.NewLoggerMock(), db)
+	ordenes := NewOrdenDeCompraController(logging.NewLoggerMock(), db)
+	repo := NewReprogramacionController(logging.NewLoggerMock(), db)
+	ahora := time.Now().Truncate(time.Microsecond)
+
+	sector := crearSectorPrueba(t, db, "GENERAL", 10)
+	origen := &model.EventoFecha{EventoID: sector.EventoID, FechaID: 1, HoraInicio: ahora, GananciaNetaOrganizador: 50}
+	if err := db.Create(origen).Error; err != nil {
+		t.Fatalf("crear fecha: %v", err)
+	}
+	detalle := func(cantidad int64) model.OrdenDeCompraDetalle {
+		return model.OrdenDeCompraDetalle{TarifaID: 1, SectorID: sector.ID, EventoFechaID: origen.ID, Cantidad: cantidad, PrecioUnitario: 50}
+	}
+
+	vendida := &model.OrdenDeCompra{
+		UsuarioID: 7, Fecha: ahora, FechaHoraIni: ahora, Total: 50,
+		EstadoDeOrden: util.OrdenConfirmada.Codigo(), Detalles: []model.OrdenDeCompraDetalle{detalle(1)},
+	}
+	if err := db.Create(vendida).Error; err != nil {
+		t.Fatalf("crear orden confirmada: %v", err)
+	}
+	ticket := &model.Ticket{
+		OrdenDeCompraID: &vendida.ID, EventoFechaID: origen.ID, TarifaID: 1,
+		CodigoQR: "qr-original", EstadoDeTicket: util.TicketVendido.Codigo(),
+	}
+	if err := db.Create(ticket).Error; err != nil {
+		t.Fatalf("crear ticket: %v", err)
+	}
+	hold := nuevaOrdenPrueba(9, detalle(2))
+	if err := ordenes.CrearOrdenTemporalConReserva(hold); err != nil {
+		t.Fatalf("reservar: %v", err)
+	}
+
+	// Con entradas vendidas la hora no se edita: se reprograma
+	if err := eventos.ActualizarHoraInicioEventoFecha(origen.ID, ahora.Add(time.Hour), nil, nil); err != ErrFechaConTicketsVendidos {
+		t.Fatalf("se esperaba ErrFechaConTicketsVendidos, se obtuvo %v", err)
+	}
+
+	dia := ahora.AddDate(0, 1, 0)
+	hora := time.Date(0, 1, 1, 21, 0, 0, 0, time.UTC)
+	limite := ahora.Add(7 * 24 * time.Hour)
+	firmar := func(*model.Ticket) (string, error) { return "qr-nuevo", nil }
+	repro, err := repo.Reprogramar(origen.ID, DatosReprogramacion{Dia: dia, HoraInicio: hora, FechaLimite: limite, UsuarioID: 1}, firmar, ahora)
+	if err != nil {
+		t.Fatalf("Reprogramar: %v", err)
+	}
+	if repro.TicketsMigrados != 1 || repro.EventoFechaDestinoID == origen.ID {
+		t.Fatalf("reprogramación: %+v", repro)
+	}
+
+	var movido model.Ticket
+	db.First(&movido, "ticket_id = ?", ticket.ID)
+	if movido.EventoFechaID != repro.EventoFechaDestinoID || movido.CodigoQR != "qr-nuevo" {
+		t.Fatalf("el ticket debió pasar a la fecha nueva con otro QR: %+v", movido)
+	}
+	var linea model.OrdenDeCompraDetalle
+	db.First(&linea, "orden_de_compra_id = ?", vendida.ID)
+	if linea.EventoFechaID != repro.EventoFechaDestinoID {
+		t.Fatalf("la línea de la orden debió moverse con el ticket, quedó en %d", linea.EventoFechaID)
+	}
+	var orig, dest model.EventoFecha
+	db.First(&orig, "evento_fecha_id = ?", origen.ID)
+	db.First(&dest, "evento_fecha_id = ?", repro.EventoFechaDestinoID)
+	if orig.Estado != util.Inactivo.Codigo() || orig.GananciaNetaOrganizador != 0 || dest.GananciaNetaOrganizador != 50 {
+		t.Fatalf("la ganancia debió pasar a la fecha nueva: origen=%+v destino=%+v", orig, dest)
+	}
+	var cancelada model.OrdenDeCompra
+	db.First(&cancelada, "orden_de_compra_id = ?", hold.ID)
+	if cancelada.EstadoDeOrden != util.OrdenCancelada.Codigo() {
+		t.Fatalf("el hold de la fecha original debió cancelarse, quedó %d", cancelada.EstadoDeOrden)
+	}
+	var aviso model.EventoDominio
+	if err := db.First(&aviso, "tipo = ? AND agregado_id = ?", model.EventoFechaReprogramada, repro.ID).Error; err != nil {
+		t.Fatalf("debió registrarse FECHA_REPROGRAMADA: %v", err)
+	}
+
+	if _, err := repo.Reprogramar(origen.ID, DatosReprogramacion{Dia: dia, HoraInicio: hora, FechaLimite: limite, UsuarioID: 1}, firmar, ahora); err != ErrFechaNoReprogramable {
+		t.Fatalf("la fecha original ya no se puede reprogramar, se obtuvo %v", err)
+	}
+	if _, err := repo.Reprogramar(repro.EventoFechaDestinoID, DatosReprogramacion{Dia: dia, HoraInicio: hora, FechaLimite: limite, UsuarioID: 1}, firmar, ahora); err != ErrFechaDestinoOcupada {
+		t.Fatalf("no se puede reprogramar al mismo día y hora, se obtuvo %v", err)
+	}
+
+	if _, err := repo.Aceptar(ticket.ID, limite); err != ErrReprogramacionVencida {
+		t.Fatalf("fuera de plazo no se acepta, se obtuvo %v", err)
+	}
+	if _, err := repo.Aceptar(ticket.ID, ahora); err != nil {
+		t.Fatalf("Aceptar: %v", err)
+	}
+	if _, err := repo.Aceptar(ticket.ID, ahora); err != ErrReprogramacionNoPendiente {
+		t.Fatalf("una respuesta no se registra dos veces, se obtuvo %v", err)
+	}
+
+	cerradas, err := repo.CerrarVencidas(limite)
+	if err != nil || cerradas != 1 {
+		t.Fatalf("CerrarVencidas: %d, %v", cerradas, err)
+	}
+	resumen, err := repo.ResumenDeEventos([]int64{sector.EventoID})
+	if err != nil || len(resumen) != 1 || resumen[0].Aceptadas != 1 || resumen[0].Pendientes != 0 {
+		t.Fatalf("resumen tras el cierre: %+v, %v", resumen, err)
+	}
+}
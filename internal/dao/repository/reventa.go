@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+type Reventa struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewReventaController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *Reventa {
+	return &Reventa{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+var (
+	// ErrReventaActiva se devuelve al publicar un ticket que ya está publicado o reservado.
+	ErrReventaActiva = errors.New("el ticket ya está publicado en reventa")
+	// ErrReventaNoDisponible se devuelve al reservar o cancelar una publicación que ya no está PUBLICADA.
+	ErrReventaNoDisponible = errors.New("la publicación de reventa ya no está disponible")
+)
+
+// ObtenerPolitica devuelve la política de reventa del evento (gorm.ErrRecordNotFound si el
+// organizador nunca la configuró).
+func (r *Reventa) ObtenerPolitica(eventoID int64) (*model.PoliticaReventa, error) {
+	var politica model.PoliticaReventa
+	if err := r.PostgresqlDB.
+		Where("evento_id = ?", eventoID).
+		First(&politica).Error; err != nil {
+		return nil, err
+	}
+	return &politica, nil
+}
+
+// GuardarPolitica crea o reemplaza la política de reventa del evento.
+func (r *Reventa) GuardarPolitica(politica *model.PoliticaReventa) error {
+	if err := r.PostgresqlDB.
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "evento_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"habilitada", "precio_maximo_porcentaje", "regalia_porcentaje",
+				"usuario_modificacion", "fecha_modificacion",
+			}),
+		}).
+		Create(politica).Error; err != nil {
+		r.logger.Errorf("Reventa.GuardarPolitica(evento=%d): %v", politica.EventoID, err)
+		return err
+	}
+	return nil
+}
+
+// tieneReventaActiva indica si el ticket está PUBLICADO o RESERVADO en reventa.
+func tieneReventaActiva(tx *gorm.DB, ticketID int64) (bool, error) {
+	var n int64
+	if err := tx.
+		Model(&model.PublicacionReventa{}).
+		Where("ticket_id = ? AND estado IN ?", ticketID,
+			[]int16{util.ReventaPublicada.Codigo(), util.ReventaReservada.Codigo()}).
+		Count(&n).Error; err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Publicar pone el ticket a la venta. Bloquea el ticket para comprobar que el vendedor sigue
+// siendo su titular, que está VENDIDO y que no tiene una transferencia pendiente; el índice
+// único parcial sobre las publicaciones activas impide publicarlo dos veces.
+func (r *Reventa) Publicar(publicacion *model.PublicacionReventa) error {
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		ticket, titularID, err := bloquearTicketConTitular(tx, publicacion.TicketID)
+		if err != nil {
+			return err
+		}
+		if titularID != publicacion.VendedorID {
+			return ErrNoEsTitular
+		}
+		if ticket.EstadoDeTicket != util.TicketVendido.Codigo() {
+			return ErrTicketNoTransferible
+		}
+
+		var pendientes int64
+		if err := tx.
+			Model(&model.TransferenciaTicket{}).
+			Where("ticket_id = ? AND estado = ? AND fecha_expiracion > ?",
+				ticket.ID, util.TransferenciaPendiente.Codigo(), publicacion.FechaCreacion).
+			Count(&pendientes).Error; err != nil {
+			return err
+		}
+		if pendientes > 0 {
+			return ErrTicketNoTransferible
+		}
+
+		if err := tx.Create(publicacion).Error; err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return ErrReventaActiva
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil && err != gorm.ErrRecordNotFound && err != ErrNoEsTitular &&
+		err != ErrTicketNoTransferible && err != ErrReventaActiva {
+		r.logger.Errorf("Reventa.Publicar(ticket=%d): %v", publicacion.TicketID, err)
+	}
+	return err
+}
+
+// ObtenerPorID devuelve la publicación con su ticket, tarifa, sector y fecha.
+func (r *Reventa) ObtenerPorID(id int64) (*model.PublicacionReventa, error) {
+	var publicacion model.PublicacionReventa
+	if err := r.PostgresqlDB.
+		Preload("Ticket.Tarifa.Sector").
+		Preload("Ticket.EventoFecha.Fecha").
+		Preload("Evento").
+		Where("publicacion_reventa_id = ?", id).
+		First(&publicacion).Error; err != nil {
+		return nil, err
+	}
+	return &publicacion, nil
+}
+
+// ListarPublicadasPorEvento devuelve las publicaciones disponibles del evento, de la más barata
+// a la más cara.
+func (r *Reventa) ListarPublicadasPorEvento(eventoID int64) ([]model.PublicacionReventa, error) {
+	var publicaciones []model.PublicacionReventa
+	if err := r.PostgresqlDB.
+		Preload("Ticket.Tarifa.Sector").
+		Preload("Ticket.EventoFecha.Fecha").
+		Where("evento_id = ? AND estado = ?", eventoID, util.ReventaPublicada.Codigo()).
+		Order("precio ASC, publicacion_reventa_id ASC").
+		Find(&publicaciones).Error; err != nil {
+		r.logger.Errorf("Reventa.ListarPublicadasPorEvento(%d): %v", eventoID, err)
+		return nil, err
+	}
+	return publicaciones, nil
+}
+
+// ListarPorVendedor devuelve todas las publicaciones del usuario, de la más reciente a la más antigua.
+func (r *Reventa) ListarPorVendedor(vendedorID int64) ([]model.PublicacionReventa, error) {
+	var publicaciones []model.PublicacionReventa
+	if err := r.PostgresqlDB.
+		Preload("Ticket.Tarifa.Sector").
+		Preload("Ticket.EventoFecha.Fecha").
+		Where("vendedor_id = ?", vendedorID).
+		Order("fecha_creacion DESC, publicacion_reventa_id DESC").
+		Find(&publicaciones).Error; err != nil {
+		r.logger.Errorf("Reventa.ListarPorVendedor(%d): %v", vendedorID, err)
+		return nil, err
+	}
+	return publicaciones, nil
+}
+
+// Cancelar retira una publicación PUBLICADA del vendedor. Devuelve false si ya no estaba
+// PUBLICADA (reservada por un comprador, vendida o cancelada).
+func (r *Reventa) Cancelar(id int64, vendedorID int64) (bool, error) {
+	res := r.PostgresqlDB.
+		Model(&model.PublicacionReventa{}).
+		Where("publicacion_reventa_id = ? AND vendedor_id = ? AND estado = ?", id, vendedorID, util.ReventaPublicada.Codigo()).
+		Update("estado", util.ReventaCancelada.Codigo())
+	if res.Error != nil {
+		r.logger.Errorf("Reventa.Cancelar(%d): %v", id, res.Error)
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// Reservar crea la orden TEMPORAL del comprador y le reserva la publicación en una sola
+// transacción. La publicación se bloquea con FOR UPDATE: si dos compradores la piden a la vez,
+// el segundo recibe ErrReventaNoDisponible. La orden no lleva detalles, así que no mueve el
+// stock de los sectores ni cuenta como venta en los reportes de taquilla.
+func (r *Reventa) Reservar(id int64, orden *model.OrdenDeCompra) error {
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		var publicacion model.PublicacionReventa
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("publicacion_reventa_id = ?", id).
+			First(&publicacion).Error; err != nil {
+			return err
+		}
+		if publicacion.Estado != util.ReventaPublicada.Codigo() {
+			return ErrReventaNoDisponible
+		}
+		if err := exigirEventosALaVenta(tx, "evento_id = ?", publicacion.EventoID); err != nil {
+			return err
+		}
+
+		orden.EstadoDeOrden = util.OrdenTemporal.Codigo()
+		if err := tx.Create(orden).Error; err != nil {
+			return err
+		}
+		return tx.
+			Model(&model.PublicacionReventa{}).
+			Where("publicacion_reventa_id = ?", id).
+			Updates(map[string]any{
+				"estado":             util.ReventaReservada.Codigo(),
+				"orden_de_compra_id": orden.ID,
+			}).Error
+	})
+	if err != nil && err != gorm.ErrRecordNotFound && err != ErrReventaNoDisponible && !errors.Is(err, ErrEventoNoALaVenta) {
+		r.logger.Errorf("Reventa.Reservar(%d): %v", id, err)
+	}
+	return err
+}
+
+// liberarReventaDeOrden devuelve a PUBLICADA la publicación que reservaba una orden que se
+// cancela o vence.
+func liberarReventaDeOrden(tx *gorm.DB, orderID int64) error {
+	return tx.
+		Model(&model.PublicacionReventa{}).
+		Where("orden_de_compra_id = ? AND estado = ?", orderID, util.ReventaReservada.Codigo()).
+		Updates(map[string]any{
+			"estado":             util.ReventaPublicada.Codigo(),
+			"orden_de_compra_id": nil,
+		}).Error
+}
+
+// completarReventaDeOrden entrega al comprador el ticket de la publicación reservada por la
+// orden: le cambia el titular y el QR (ver cambiarTitular), marca la publicación VENDIDA y suma
+// la regalía a la ganancia del organizador. esReventa es false si la orden no es de reventa.
+// Si el vendedor ya no puede entregar el ticket (lo usó o dejó de ser su titular), la
+// publicación se cancela y vendida es false.
+func completarReventaDeOrden(
+	tx *gorm.DB,
+	orden *model.OrdenDeCompra,
+	firmar func(*model.Ticket) (string, error),
+	ahora time.Time,
+) (esReventa bool, vendida bool, err error) {
+	var publicacion model.PublicacionReventa
+	err = tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("orden_de_compra_id = ? AND estado = ?", orden.ID, util.ReventaReservada.Codigo()).
+		First(&publicacion).Error
+	if err == gorm.ErrRecordNotFound {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	ticket, titularID, err := bloquearTicketConTitular(tx, publicacion.TicketID)
+	if err != nil {
+		return true, false, err
+	}
+	if ticket.EstadoDeTicket != util.TicketVendido.Codigo() || titularID != publicacion.VendedorID {
+		err = tx.Model(&publicacion).Update("estado", util.ReventaCancelada.Codigo()).Error
+		return true, false, err
+	}
+
+	if err := cambiarTitular(tx, ticket, titularID, orden.UsuarioID, model.MotivoTitularReventa, nil, firmar, ahora); err != nil {
+		return true, false, err
+	}
+	if err := tx.Model(&publicacion).Updates(map[string]any{
+		"estado":       util.ReventaVendida.Codigo(),
+		"comprador_id": orden.UsuarioID,
+		"fecha_venta":  ahora,
+	}).Error; err != nil {
+		return true, false, err
+	}
+
+	if publicacion.MontoRegalia > 0 {
+		if err := tx.Model(&model.Evento{}).
+			Where("evento_id = ?", publicacion.EventoID).
+			UpdateColumn("total_recaudado", gorm.Expr("total_recaudado + ?", publicacion.MontoRegalia)).Error; err != nil {
+			return true, false, err
+		}
+		if err := tx.Model(&model.EventoFecha{}).
+			Where("evento_fecha_id = ?", ticket.EventoFechaID).
+			UpdateColumn("ganancia_neta_organizador", gorm.Expr("ganancia_neta_organizador + ?", publicacion.MontoRegalia)).Error; err != nil {
+			return true, false, err
+		}
+	}
+	return true, true, nil
+}
+
+// ResumenReventa son los totales de las reventas concretadas de un evento.
+type ResumenReventa struct {
+	Publicadas         int64
+	Vendidas           int64
+	MontoTotal         float64
+	MontoFeePlataforma float64
+	MontoRegalia       float64
+	MontoVendedores    float64
+}
+
+// ResumenPorEvento suma las reventas VENDIDAS del evento y cuenta las que siguen publicadas.
+func (r *Reventa) ResumenPorEvento(eventoID int64) (*ResumenReventa, error) {
+	var resumen ResumenReventa
+	if err := r.PostgresqlDB.
+		Model(&model.PublicacionReventa{}).
+		Select(`COUNT(*) FILTER (WHERE estado = ?) AS publicadas,
+			COUNT(*) FILTER (WHERE estado = ?) AS vendidas,
+			COALESCE(SUM(precio) FILTER (WHERE estado = ?), 0) AS monto_total,
+			COALESCE(SUM(monto_fee_plataforma) FILTER (WHERE estado = ?), 0) AS monto_fee_plataforma,
+			COALESCE(SUM(monto_regalia) FILTER (WHERE estado = ?), 0) AS monto_regalia,
+			COALESCE(SUM(monto_vendedor) FILTER (WHERE estado = ?), 0) AS monto_vendedores`,
+			util.ReventaPublicada.Codigo(), util.ReventaVendida.Codigo(), util.ReventaVendida.Codigo(),
+			util.ReventaVendida.Codigo(), util.ReventaVendida.Codigo(), util.ReventaVendida.Codigo()).
+		Where("evento_id = ?", eventoID).
+		Scan(&resumen).Error; err != nil {
+		r.logger.Errorf("Reventa.ResumenPorEvento(%d): %v", eventoID, err)
+		return nil, err
+	}
+	return &resumen, nil
+}
+
+// OrganizadorDeEvento devuelve el organizador del evento (gorm.ErrRecordNotFound si no existe).
+func (r *Reventa) OrganizadorDeEvento(eventoID int64) (int64, error) {
+	var evento model.Evento
+	if err := r.PostgresqlDB.
+		Select("evento_id", "organizador_id").
+		Where("evento_id = ?", eventoID).
+		First(&evento).Error; err != nil {
+		return 0, err
+	}
+	return evento.OrganizadorID, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+	"gorm.io/gorm"
+)
+
+func TestReventaPublicarReservarYCompletar(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(
+		&model.Ticket{}, &model.OrdenDeCompra{}, &model.TransferenciaTicket{},
+		&model.HistorialTitular{}, &model.PublicacionReventa{},
+	); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewReventaController(logging.NewLoggerMock(), db)
+
+	evento := crearEventoPrueba(t, db, util.EventoPublicado)
+	var vendedor, comprador int64 = 1, 2
+	ticket := &model.Ticket{EventoFechaID: 1, TarifaID: 1, TitularID: &vendedor, CodigoQR: "qr-original", EstadoDeTicket: util.TicketVendido.Codigo()}
+	if err := db.Create(ticket).Error; err != nil {
+		t.Fatalf("crear ticket: %v", err)
+	}
+
+	ahora := time.Now().Truncate(time.Microsecond)
+	nueva := func(vendedorID int64) *model.PublicacionReventa {
+		return &model.PublicacionReventa{
+			TicketID: ticket.ID, EventoID: evento.ID, VendedorID: vendedorID,
+			Precio: 100, PrecioOriginal: 100, MontoFeePlataforma: 2.5, MontoVendedor: 97.5,
+			FechaCreacion: ahora,
+		}
+	}
+	if err := repo.Publicar(nueva(comprador)); err != ErrNoEsTitular {
+		t.Fatalf("solo el titular puede publicar, se obtuvo %v", err)
+	}
+	publicacion := nueva(vendedor)
+	if err := repo.Publicar(publicacion); err != nil {
+		t.Fatalf("Publicar: %v", err)
+	}
+	if err := repo.Publicar(nueva(vendedor)); err != ErrReventaActiva {
+		t.Fatalf("una segunda publicación activa debió rechazarse, se obtuvo %v", err)
+	}
+
+	orden := &model.OrdenDeCompra{UsuarioID: comprador, Fecha: ahora, FechaHoraIni: ahora, Total: publicacion.Precio}
+	if err := repo.Reservar(publicacion.ID, orden); err != nil {
+		t.Fatalf("Reservar: %v", err)
+	}
+	otra := &model.OrdenDeCompra{UsuarioID: 3, Fecha: ahora, FechaHoraIni: ahora, Total: publicacion.Precio}
+	if err := repo.Reservar(publicacion.ID, otra); err != ErrReventaNoDisponible {
+		t.Fatalf("una publicación reservada no puede reservarse otra vez, se obtuvo %v", err)
+	}
+
+	firmar := func(tk *model.Ticket) (string, error) { return "qr-comprador", nil }
+	var esReventa, vendida bool
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		esReventa, vendida, err = completarReventaDeOrden(tx, orden, firmar, ahora)
+		return err
+	}); err != nil {
+		t.Fatalf("completarReventaDeOrden: %v", err)
+	}
+	if !esReventa || !vendida {
+		t.Fatalf("la orden debió completar la reventa (esReventa=%v, vendida=%v)", esReventa, vendida)
+	}
+
+	var actualizado model.Ticket
+	db.First(&actualizado, "ticket_id = ?", ticket.ID)
+	if actualizado.TitularID == nil || *actualizado.TitularID != comprador || actualizado.CodigoQR != "qr-comprador" {
+		t.Fatalf("el ticket debió pasar al comprador con QR nuevo, se obtuvo %+v", actualizado)
+	}
+	var final model.PublicacionReventa
+	db.First(&final, "publicacion_reventa_id = ?", publicacion.ID)
+	if final.Estado != util.ReventaVendida.Codigo() || final.CompradorID == nil || *final.CompradorID != comprador {
+		t.Fatalf("la publicación debió quedar VENDIDA al comprador, se obtuvo %+v", final)
+	}
+}
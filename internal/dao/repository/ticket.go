@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	//"github.com/Nexivent/nexivent-backend/internal/schemas"
@@ -58,28 +59,6 @@ func (c *Ticket) VerificarTicketsExistentes(orderID int64) (bool, error) {
 	return count > 0, nil
 }
 
-// VerificarStockDisponible: revisa capacidad del sector asociado a la tarifa.
-func (c *Ticket) VerificarStockDisponible(tarifaID int64, cantidad int64) (bool, error) {
-	if tarifaID <= 0 || cantidad <= 0 {
-		return false, gorm.ErrInvalidData
-	}
-
-	var total, vendidas int64
-	row := c.PostgresqlDB.
-		Table("sector s").
-		Select("s.total_entradas, s.cant_vendidas").
-		Joins("JOIN tarifa t ON t.sector_id = s.sector_id").
-		Where("t.tarifa_id = ?", tarifaID).
-		Row()
-
-	if err := row.Scan(&total, &vendidas); err != nil {
-		c.logger.Errorf("VerificarStockDisponible(tarifa=%d): %v", tarifaID, err)
-		return false, err
-	}
-
-	return vendidas+cantidad <= total, nil
-}
-
 // VerificarTicketPerteneceAOrden: true si ticket_id pertenece a la orden dada.
 func (c *Ticket) VerificarTicketPerteneceAOrden(ticketID, orderID int64) (bool, error) {
 	var count int64
@@ -118,6 +97,191 @@ func (c *Ticket) CrearTicketsBatch(tickets []model.Ticket) error {
 	return nil
 }
 
+// CrearTicketsFirmados inserta tickets cuyo código QR se firma con su propio ID. Los IDs se
+// piden antes a la secuencia de la tabla porque forman parte de la firma. Para los tickets con
+// titular se abre también su historial de titulares.
+func (c *Ticket) CrearTicketsFirmados(tickets []model.Ticket, firmar func(*model.Ticket) (string, error)) error {
+	if len(tickets) == 0 {
+		return nil
+	}
+
+	err := c.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		return crearTicketsFirmados(tx, tickets, firmar, time.Now())
+	})
+	if err != nil {
+		c.logger.Errorf("CrearTicketsFirmados: %v", err)
+		return err
+	}
+	return nil
+}
+
+var (
+	// ErrTicketsYaEmitidos se devuelve al emitir los tickets de una orden que ya los tiene.
+	ErrTicketsYaEmitidos = errors.New("la orden ya tiene sus tickets emitidos")
+	// ErrOrdenNoConfirmada se devuelve al emitir los tickets de una orden que no está CONFIRMADA.
+	ErrOrdenNoConfirmada = errors.New("la orden no está confirmada")
+)
+
+// EmitirTicketsDeOrden crea los tickets de una orden confirmada (ver CrearTicketsFirmados) y, en
+// la misma transacción, registra el evento ORDEN_CONFIRMADA del que sale el correo con los
+// tickets adjuntos. Devuelve ErrTicketsYaEmitidos si la orden ya tiene tickets.
+func (c *Ticket) EmitirTicketsDeOrden(orderID int64, tickets []model.Ticket, firmar func(*model.Ticket) (string, error)) error {
+	if len(tickets) == 0 {
+		return nil
+	}
+
+	err := c.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		var orden model.OrdenDeCompra
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&orden, "orden_de_compra_id = ?", orderID).Error; err != nil {
+			return err
+		}
+		if orden.EstadoDeOrden != util.OrdenConfirmada.Codigo() {
+			return ErrOrdenNoConfirmada
+		}
+		return emitirTicketsDeOrdenTx(tx, &orden, tickets, firmar, time.Now())
+	})
+	if err != nil {
+		if err != ErrTicketsYaEmitidos && err != ErrEventoNoALaVenta {
+			c.logger.Errorf("EmitirTicketsDeOrden(%d): %v", orderID, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// emitirTicketsDeOrdenTx es EmitirTicketsDeOrden dentro de una transacción que ya tiene la orden
+// bloqueada FOR UPDATE. Una emisión concurrente espera ese lock y luego ve los tickets ya creados;
+// además fecha_emision_tickets solo se marca si seguía vacía, así que ni un camino que no tome el
+// lock puede emitir dos veces. Si el evento ya está CANCELADO devuelve ErrEventoNoALaVenta: la
+// orden se reembolsa entera en el ciclo de vida de eventos.
+func emitirTicketsDeOrdenTx(tx *gorm.DB, orden *model.OrdenDeCompra, tickets []model.Ticket, firmar func(*model.Ticket) (string, error), ahora time.Time) error {
+	if orden.FechaEmisionTickets != nil {
+		return ErrTicketsYaEmitidos
+	}
+	cancelado, err := eventoCanceladoDeOrden(tx, orden.ID)
+	if err != nil {
+		return err
+	}
+	if cancelado {
+		return ErrEventoNoALaVenta
+	}
+	var existentes int64
+	if err := tx.Model(&model.Ticket{}).Where("orden_de_compra_id = ?", orden.ID).Count(&existentes).Error; err != nil {
+		return err
+	}
+	if existentes > 0 {
+		return ErrTicketsYaEmitidos
+	}
+
+	res := tx.Model(&model.OrdenDeCompra{}).
+		Where("orden_de_compra_id = ? AND fecha_emision_tickets IS NULL", orden.ID).
+		Update("fecha_emision_tickets", ahora)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTicketsYaEmitidos
+	}
+	orden.FechaEmisionTickets = &ahora
+
+	if err := crearTicketsFirmados(tx, tickets, firmar, ahora); err != nil {
+		return err
+	}
+	return registrarOrdenConfirmada(tx, orden.ID, ahora)
+}
+
+// eventoCanceladoDeOrden dice si el evento de alguna línea de la orden está CANCELADO. Bloquea
+// los eventos como lo hace la suma de la venta: si uno se está cancelando espera a que termine y
+// ve su estado final.
+func eventoCanceladoDeOrden(tx *gorm.DB, orderID int64) (bool, error) {
+	var estados []int16
+	if err := tx.Raw(`
+		SELECT ev.evento_estado FROM evento ev
+		WHERE ev.evento_id IN (
+			SELECT ef.evento_id FROM orden_de_compra_detalle d
+			INNER JOIN evento_fecha ef ON ef.evento_fecha_id = d.evento_fecha_id
+			WHERE d.orden_de_compra_id = ?
+		)
+		ORDER BY ev.evento_id
+		FOR NO KEY UPDATE`, orderID).
+		Scan(&estados).Error; err != nil {
+		return false, err
+	}
+	for _, estado := range estados {
+		if estado == util.EventoCancelado.Codigo() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ticketsDeOrden arma, sin guardarlos, un ticket VENDIDO por cada entrada de los detalles de la
+// orden, con el comprador como titular.
+func ticketsDeOrden(tx *gorm.DB, orden *model.OrdenDeCompra) ([]model.Ticket, error) {
+	var detalles []model.OrdenDeCompraDetalle
+	if err := tx.Where("orden_de_compra_id = ?", orden.ID).
+		Order("orden_de_compra_detalle_id").
+		Find(&detalles).Error; err != nil {
+		return nil, err
+	}
+	var tickets []model.Ticket
+	for _, d := range detalles {
+		for i := int64(0); i < d.Cantidad; i++ {
+			tickets = append(tickets, model.Ticket{
+				OrdenDeCompraID: &orden.ID,
+				TitularID:       &orden.UsuarioID,
+				EventoFechaID:   d.EventoFechaID,
+				TarifaID:        d.TarifaID,
+				EstadoDeTicket:  util.TicketVendido.Codigo(),
+			})
+		}
+	}
+	return tickets, nil
+}
+
+func crearTicketsFirmados(tx *gorm.DB, tickets []model.Ticket, firmar func(*model.Ticket) (string, error), ahora time.Time) error {
+	var ids []int64
+	if err := tx.
+		Raw("SELECT nextval(pg_get_serial_sequence('ticket', 'ticket_id')) FROM generate_series(1, ?)", len(tickets)).
+		Scan(&ids).Error; err != nil {
+		return err
+	}
+	if len(ids) != len(tickets) {
+		return fmt.Errorf("se reservaron %d ids para %d tickets", len(ids), len(tickets))
+	}
+
+	for i := range tickets {
+		tickets[i].ID = ids[i]
+		codigo, err := firmar(&tickets[i])
+		if err != nil {
+			return fmt.Errorf("firmar ticket %d: %w", ids[i], err)
+		}
+		tickets[i].CodigoQR = codigo
+	}
+
+	if err := tx.Create(&tickets).Error; err != nil {
+		return err
+	}
+
+	var historial []model.HistorialTitular
+	for _, t := range tickets {
+		if t.TitularID == nil {
+			continue
+		}
+		historial = append(historial, model.HistorialTitular{
+			TicketID:  t.ID,
+			UsuarioID: *t.TitularID,
+			Motivo:    model.MotivoTitularEmision,
+			Desde:     ahora,
+		})
+	}
+	if len(historial) > 0 {
+		return tx.Create(&historial).Error
+	}
+	return nil
+}
+
 // IncrementarVendidasPorSector: suma cantidad a cant_vendidas (sector).
 func (c *Ticket) IncrementarVendidasPorSector(sectorID int64, cantidad int64) error {
 	if sectorID <= 0 || cantidad <= 0 {
@@ -206,6 +370,85 @@ func (c *Ticket) CambiarEstadoTicketsDeOrden(orderID int64, nuevo util.EstadoDeT
 	return res.RowsAffected, nil
 }
 
+// ObtenerParaIngreso: devuelve el ticket con su fecha, evento y sector para validarlo en puerta.
+func (c *Ticket) ObtenerParaIngreso(ticketID int64) (*model.Ticket, error) {
+	var ticket model.Ticket
+	if err := c.PostgresqlDB.
+		Preload("EventoFecha.Evento").
+		Preload("EventoFecha.Fecha").
+		Preload("Tarifa.Sector").
+		Where("ticket_id = ?", ticketID).
+		First(&ticket).Error; err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// ObtenerParaPase: devuelve el ticket con lo que se imprime en su PDF o pase de billetera
+// (evento, fecha, sector, perfil, tipo de entrada y titular).
+func (c *Ticket) ObtenerParaPase(ticketID int64) (*model.Ticket, error) {
+	var ticket model.Ticket
+	if err := c.PostgresqlDB.
+		Preload("EventoFecha.Evento").
+		Preload("EventoFecha.Fecha").
+		Preload("Tarifa.Sector").
+		Preload("Tarifa.TipoDeTicket").
+		Preload("Tarifa.PerfilPersona").
+		Preload("Titular").
+		Preload("OrdenDeCompra.Usuario").
+		Where("ticket_id = ?", ticketID).
+		First(&ticket).Error; err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// ObtenerParaPasesDeOrden: tickets de la orden que siguen siendo del usuario (los transferidos
+// o revendidos ya no se incluyen), con los mismos datos que ObtenerParaPase.
+func (c *Ticket) ObtenerParaPasesDeOrden(orderID, usuarioID int64) ([]model.Ticket, error) {
+	var ts []model.Ticket
+	if err := c.PostgresqlDB.
+		Preload("EventoFecha.Evento").
+		Preload("EventoFecha.Fecha").
+		Preload("Tarifa.Sector").
+		Preload("Tarifa.TipoDeTicket").
+		Preload("Tarifa.PerfilPersona").
+		Preload("Titular").
+		Preload("OrdenDeCompra.Usuario").
+		Joins("JOIN orden_de_compra oc ON oc.orden_de_compra_id = ticket.orden_de_compra_id").
+		Where("ticket.orden_de_compra_id = ? AND COALESCE(ticket.titular_id, oc.usuario_id) = ?", orderID, usuarioID).
+		Order("ticket.ticket_id").
+		Find(&ts).Error; err != nil {
+		c.logger.Errorf("ObtenerParaPasesDeOrden(%d): %v", orderID, err)
+		return nil, err
+	}
+	return ts, nil
+}
+
+// ObtenerParaPasesDeCompra: tickets que el comprador recibió con la orden, sea porque se
+// emitieron para ella o porque los compró en reventa, con los mismos datos que ObtenerParaPase.
+func (c *Ticket) ObtenerParaPasesDeCompra(orderID int64) ([]model.Ticket, error) {
+	var ts []model.Ticket
+	if err := c.PostgresqlDB.
+		Preload("EventoFecha.Evento").
+		Preload("EventoFecha.Fecha").
+		Preload("Tarifa.Sector").
+		Preload("Tarifa.TipoDeTicket").
+		Preload("Tarifa.PerfilPersona").
+		Preload("Titular").
+		Preload("OrdenDeCompra.Usuario").
+		Where("orden_de_compra_id = ? OR ticket_id IN (?)", orderID,
+			c.PostgresqlDB.Model(&model.PublicacionReventa{}).
+				Select("ticket_id").
+				Where("orden_de_compra_id = ? AND estado = ?", orderID, util.ReventaVendida.Codigo())).
+		Order("ticket_id").
+		Find(&ts).Error; err != nil {
+		c.logger.Errorf("ObtenerParaPasesDeCompra(%d): %v", orderID, err)
+		return nil, err
+	}
+	return ts, nil
+}
+
 // ObtenerTicketsPorOrden: devuelve tickets (modelo puro) de una orden.
 func (c *Ticket) ObtenerTicketsPorOrden(orderID int64) ([]model.Ticket, error) {
 	var ts []model.Ticket
@@ -231,27 +474,71 @@ func (c *Ticket) LockTicketsDeOrden(orderID int64) ([]model.Ticket, error) {
 	return ts, nil
 }
 
-// Helpers extra para BO (solo structs internos, NO schemas
-// DetalleOrden representa una fila de la tabla orden_de_compra_detalle.
-type DetalleOrden struct {
-	TarifaID      int64 `gorm:"column:tarifa_id"`
-	Cantidad      int64 `gorm:"column:cantidad"`
-	EventoFechaID int64 `gorm:"column:evento_fecha_id"`
+// TicketManifiesto: lo mínimo que un escáner necesita de cada ticket para validar sin conexión.
+type TicketManifiesto struct {
+	ID         int64  `gorm:"column:ticket_id"`
+	CodigoQR   string `gorm:"column:codigo_qr"`
+	Estado     int16  `gorm:"column:estado_de_ticket"`
+	SectorTipo string `gorm:"column:sector_tipo"`
 }
 
-func (c *Ticket) ObtenerDetallesOrden(orderID int64) ([]DetalleOrden, error) {
-	var detalles []DetalleOrden
-	res := c.PostgresqlDB.
-		Table("orden_de_compra_detalle").
-		Select("tarifa_id, cantidad, evento_fecha_id").
-		Where("orden_de_compra_id = ?", orderID).
-		Find(&detalles)
+// ListarManifiesto: tickets emitidos (vendidos, usados o cancelados) de una fecha del evento.
+func (c *Ticket) ListarManifiesto(eventoFechaID int64) ([]TicketManifiesto, error) {
+	var rows []TicketManifiesto
+	if err := c.PostgresqlDB.
+		Table("ticket t").
+		Select("t.ticket_id, t.codigo_qr, t.estado_de_ticket, s.sector_tipo").
+		Joins("JOIN tarifa tf ON tf.tarifa_id = t.tarifa_id").
+		Joins("JOIN sector s ON s.sector_id = tf.sector_id").
+		Where("t.evento_fecha_id = ? AND t.estado_de_ticket <> ?", eventoFechaID, util.TicketDisponible.Codigo()).
+		Order("t.ticket_id").
+		Find(&rows).Error; err != nil {
+		c.logger.Errorf("ListarManifiesto(evento_fecha=%d): %v", eventoFechaID, err)
+		return nil, err
+	}
+	return rows, nil
+}
 
-	if res.Error != nil {
-		c.logger.Errorf("ObtenerDetallesOrden(orderID=%d): %v", orderID, res.Error)
-		return nil, res.Error
+// TitularTicket: quien tiene al menos un ticket vendido (no usado ni cancelado), para avisarle
+// de cambios en el evento.
+type TitularTicket struct {
+	UsuarioID int64  `gorm:"column:usuario_id"`
+	Nombre    string `gorm:"column:nombre"`
+	Correo    string `gorm:"column:correo"`
+}
+
+// TitularesDeEvento: titulares con tickets vigentes en cualquier fecha del evento.
+func (c *Ticket) TitularesDeEvento(eventoID int64) ([]TitularTicket, error) {
+	rows, err := c.titularesVigentes("ef.evento_id = ?", eventoID)
+	if err != nil {
+		c.logger.Errorf("TitularesDeEvento(%d): %v", eventoID, err)
+	}
+	return rows, err
+}
+
+// TitularesDeEventoFecha: titulares con tickets vigentes en una fecha del evento.
+func (c *Ticket) TitularesDeEventoFecha(eventoFechaID int64) ([]TitularTicket, error) {
+	rows, err := c.titularesVigentes("t.evento_fecha_id = ?", eventoFechaID)
+	if err != nil {
+		c.logger.Errorf("TitularesDeEventoFecha(%d): %v", eventoFechaID, err)
 	}
-	return detalles, nil
+	return rows, err
+}
+
+// titularesVigentes: el titular de un ticket es titular_id o, si nunca cambió, el comprador.
+func (c *Ticket) titularesVigentes(filtro string, arg int64) ([]TitularTicket, error) {
+	var rows []TitularTicket
+	err := c.PostgresqlDB.
+		Table("ticket t").
+		Select("DISTINCT u.usuario_id, u.nombre, u.correo").
+		Joins("JOIN evento_fecha ef ON ef.evento_fecha_id = t.evento_fecha_id").
+		Joins("LEFT JOIN orden_de_compra o ON o.orden_de_compra_id = t.orden_de_compra_id").
+		Joins("JOIN usuario u ON u.usuario_id = COALESCE(t.titular_id, o.usuario_id)").
+		Where(filtro, arg).
+		Where("t.estado_de_ticket = ?", util.TicketVendido.Codigo()).
+		Order("u.usuario_id").
+		Find(&rows).Error
+	return rows, err
 }
 
 // TicketInfo: datos enriquecidos para mostrar tickets (JOIN con evento, sector, fecha, etc.).
@@ -364,7 +651,7 @@ func (t *Ticket) ObternerTicketsPorUsuario(idUser int64) ([]*TicketRaro, error)
 		Joins("INNER JOIN evento e ON s.evento_id = e.evento_id").
 		Joins("INNER JOIN evento_fecha ef ON t.evento_fecha_id = ef.evento_fecha_id").
 		Joins("LEFT JOIN perfil_de_persona pp ON tar.perfil_de_persona_id = pp.perfil_de_persona_id").
-		Where("oc.usuario_id = ?", idUser).
+		Where("COALESCE(t.titular_id, oc.usuario_id) = ?", idUser).
 		Where("e.estado = 1").
 		Where("ef.estado = 1").
 		Where("t.estado_de_ticket = 1").
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestEmitirTicketsDeOrdenSimultaneosEmiteUnaSolaVez(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Ticket{}, &model.HistorialTitular{}, &model.EventoDominio{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewTicketController(logging.NewLoggerMock(), db)
+
+	ahora := time.Now()
+	orden := &model.OrdenDeCompra{UsuarioID: 7, Fecha: ahora, FechaHoraIni: ahora, Total: 20, EstadoDeOrden: util.OrdenConfirmada.Codigo()}
+	if err := db.Create(orden).Error; err != nil {
+		t.Fatalf("crear orden: %v", err)
+	}
+	tickets := func() []model.Ticket {
+		var lista []model.Ticket
+		for i := 0; i < 2; i++ {
+			lista = append(lista, model.Ticket{
+				OrdenDeCompraID: &orden.ID, TitularID: &orden.UsuarioID, EventoFechaID: 1, TarifaID: 1,
+				EstadoDeTicket: util.TicketVendido.Codigo(),
+			})
+		}
+		return lista
+	}
+	firmar := func(tk *model.Ticket) (string, error) { return fmt.Sprintf("qr-%d", tk.ID), nil }
+
+	const intentos = 6
+	var wg sync.WaitGroup
+	resultados := make(chan error, intentos)
+	for i := 0; i < intentos; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resultados <- repo.EmitirTicketsDeOrden(orden.ID, tickets(), firmar)
+		}()
+	}
+	wg.Wait()
+	close(resultados)
+
+	emisiones := 0
+	for err := range resultados {
+		switch err {
+		case nil:
+			emisiones++
+		case ErrTicketsYaEmitidos:
+		default:
+			t.Fatalf("EmitirTicketsDeOrden: %v", err)
+		}
+	}
+	if emisiones != 1 {
+		t.Fatalf("se esperaba una sola emisión, hubo %d", emisiones)
+	}
+
+	var creados, correos int64
+	db.Model(&model.Ticket{}).Where("orden_de_compra_id = ?", orden.ID).Count(&creados)
+	db.Model(&model.EventoDominio{}).Where("tipo = ?", model.EventoOrdenConfirmada).Count(&correos)
+	if creados != 2 || correos != 1 {
+		t.Fatalf("se esperaban 2 tickets y 1 evento de orden confirmada, hay %d y %d", creados, correos)
+	}
+}
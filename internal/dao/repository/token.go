@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 	"github.com/Nexivent/nexivent-backend/logging"
@@ -14,29 +15,74 @@ type Token struct {
 	DB     *gorm.DB
 }
 
+// New genera un token para el usuario y guarda su hash en la tabla token.
 func (m Token) New(userID int64, ttl time.Duration, scope string) (*model.Token, error) {
 	token, err := model.GenerateToken(userID, ttl, scope)
 	if err != nil {
 		return nil, err
 	}
-	//err = m.Insert(token)
-	return token, nil
+	err = m.Insert(token)
+	return token, err
 }
 
-//func (m Token) Insert(token *model.Token) error {
-//	result := m.DB.Create(token)
-//	if result.Error != nil {
-//		m.logger.Errorf("Token.Insert: %v", result.Error)
-//		return result.Error
-//	}
-//	return nil
-//}
-//
-//func (m Token) DeleteAllForUser(scope string, userID int64) error {
-//	result := m.DB.Where("usuario_id = ? AND scope = ?", userID, scope).Delete(&model.Token{})
-//	if result.Error != nil {
-//		m.logger.Errorf("Token.DeleteAllForUser: %v", result.Error)
-//		return result.Error
-//	}
-//	return nil
-//}
+func (m Token) Insert(token *model.Token) error {
+	result := m.DB.Create(token)
+	if result.Error != nil {
+		m.logger.Errorf("Token.Insert: %v", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// ObtenerPorTextoPlano busca un token vigente por el hash de su texto plano y scope.
+func (m Token) ObtenerPorTextoPlano(scope string, plaintext string) (*model.Token, error) {
+	var token model.Token
+	result := m.DB.
+		Where("hash = ? AND scope = ?", model.HashToken(plaintext), scope).
+		First(&token)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	token.Plaintext = plaintext
+	return &token, nil
+}
+
+// DeleteByPlaintext elimina un único token (p.ej. logout del dispositivo actual).
+func (m Token) DeleteByPlaintext(scope string, plaintext string) error {
+	result := m.DB.
+		Where("hash = ? AND scope = ?", model.HashToken(plaintext), scope).
+		Delete(&model.Token{})
+	if result.Error != nil {
+		m.logger.Errorf("Token.DeleteByPlaintext: %v", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// Consumir elimina el token y lo devuelve en la misma sentencia, así un token de un solo uso
+// no puede ser usado dos veces aunque lleguen dos peticiones a la vez.
+func (m Token) Consumir(scope string, plaintext string) (*model.Token, error) {
+	var tokens []model.Token
+	result := m.DB.
+		Clauses(clause.Returning{}).
+		Where("hash = ? AND scope = ?", model.HashToken(plaintext), scope).
+		Delete(&tokens)
+	if result.Error != nil {
+		m.logger.Errorf("Token.Consumir: %v", result.Error)
+		return nil, result.Error
+	}
+	if len(tokens) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	tokens[0].Plaintext = plaintext
+	return &tokens[0], nil
+}
+
+func (m Token) DeleteAllForUser(scope string, userID int64) error {
+	result := m.DB.Where("usuario_id = ? AND scope = ?", userID, scope).Delete(&model.Token{})
+	if result.Error != nil {
+		m.logger.Errorf("Token.DeleteAllForUser: %v", result.Error)
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+type TransferenciaTicket struct {
+	logger       logging.Logger
+	PostgresqlDB *gorm.DB
+}
+
+func NewTransferenciaTicketController(
+	logger logging.Logger,
+	postgresqlDB *gorm.DB,
+) *TransferenciaTicket {
+	return &TransferenciaTicket{
+		logger:       logger,
+		PostgresqlDB: postgresqlDB,
+	}
+}
+
+var (
+	// ErrNoEsTitular se devuelve cuando quien transfiere ya no es el titular del ticket.
+	ErrNoEsTitular = errors.New("el usuario no es el titular del ticket")
+	// ErrTicketNoTransferible se devuelve cuando el ticket no está VENDIDO (usado, cancelado...).
+	ErrTicketNoTransferible = errors.New("el ticket no se puede transferir")
+	// ErrTransferenciaPendiente se devuelve al ofrecer un ticket que ya tiene una oferta PENDIENTE.
+	ErrTransferenciaPendiente = errors.New("el ticket ya tiene una transferencia pendiente")
+	// ErrTransferenciaNoPendiente se devuelve al aceptar o cancelar una oferta ya resuelta.
+	ErrTransferenciaNoPendiente = errors.New("la transferencia ya no está pendiente")
+	// ErrTransferenciaExpirada se devuelve al aceptar una oferta vencida.
+	ErrTransferenciaExpirada = errors.New("la transferencia expiró")
+)
+
+// bloquearTicketConTitular bloquea el ticket con FOR UPDATE y devuelve también su titular. Los
+// tickets emitidos antes de las transferencias no tienen titular_id: su titular es el comprador.
+func bloquearTicketConTitular(tx *gorm.DB, ticketID int64) (*model.Ticket, int64, error) {
+	var ticket model.Ticket
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("ticket_id = ?", ticketID).
+		First(&ticket).Error; err != nil {
+		return nil, 0, err
+	}
+	if ticket.TitularID != nil {
+		return &ticket, *ticket.TitularID, nil
+	}
+	if ticket.OrdenDeCompraID == nil {
+		return &ticket, 0, nil
+	}
+	var compradorID int64
+	if err := tx.
+		Model(&model.OrdenDeCompra{}).
+		Select("usuario_id").
+		Where("orden_de_compra_id = ?", *ticket.OrdenDeCompraID).
+		Scan(&compradorID).Error; err != nil {
+		return nil, 0, err
+	}
+	return &ticket, compradorID, nil
+}
+
+// cambiarTitular pasa el ticket (ya bloqueado) de titularAnteriorID a nuevoID: le firma un QR
+// nuevo, con lo que el anterior deja de ser válido, cierra la fila vigente del historial y abre
+// la del nuevo titular con el motivo indicado.
+func cambiarTitular(
+	tx *gorm.DB,
+	ticket *model.Ticket,
+	titularAnteriorID int64,
+	nuevoID int64,
+	motivo string,
+	transferenciaID *int64,
+	firmar func(*model.Ticket) (string, error),
+	ahora time.Time,
+) error {
+	codigo, err := firmar(ticket)
+	if err != nil {
+		return err
+	}
+	if err := tx.
+		Model(&model.Ticket{}).
+		Where("ticket_id = ?", ticket.ID).
+		Updates(map[string]any{
+			"titular_id": nuevoID,
+			"codigo_qr":  codigo,
+		}).Error; err != nil {
+		return err
+	}
+
+	// Cierra la fila vigente del historial; los tickets anteriores a las transferencias no
+	// tienen ninguna, así que se reconstruye la del comprador desde la fecha de la orden
+	res := tx.
+		Model(&model.HistorialTitular{}).
+		Where("ticket_id = ? AND hasta IS NULL", ticket.ID).
+		Update("hasta", ahora)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		desde := ahora
+		if ticket.OrdenDeCompraID != nil {
+			var orden model.OrdenDeCompra
+			if err := tx.
+				Select("fecha").
+				Where("orden_de_compra_id = ?", *ticket.OrdenDeCompraID).
+				First(&orden).Error; err != nil {
+				return err
+			}
+			desde = orden.Fecha
+		}
+		if err := tx.Create(&model.HistorialTitular{
+			TicketID:  ticket.ID,
+			UsuarioID: titularAnteriorID,
+			Motivo:    model.MotivoTitularEmision,
+			Desde:     desde,
+			Hasta:     &ahora,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Create(&model.HistorialTitular{
+		TicketID:        ticket.ID,
+		UsuarioID:       nuevoID,
+		Motivo:          motivo,
+		TransferenciaID: transferenciaID,
+		Desde:           ahora,
+	}).Error
+}
+
+// CrearOferta registra una oferta PENDIENTE para el ticket. Bloquea el ticket para comprobar
+// que quien ofrece sigue siendo el titular y que el ticket está VENDIDO y no publicado en
+// reventa; las ofertas vencidas del ticket se marcan EXPIRADA antes de insertar. El índice
+// único parcial sobre las ofertas PENDIENTE impide que haya dos a la vez.
+func (r *TransferenciaTicket) CrearOferta(transferencia *model.TransferenciaTicket) error {
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		ticket, titularID, err := bloquearTicketConTitular(tx, transferencia.TicketID)
+		if err != nil {
+			return err
+		}
+		if titularID != transferencia.DeUsuarioID {
+			return ErrNoEsTitular
+		}
+		if ticket.EstadoDeTicket != util.TicketVendido.Codigo() {
+			return ErrTicketNoTransferible
+		}
+		// Un ticket publicado en reventa no se puede regalar a la vez
+		enReventa, err := tieneReventaActiva(tx, ticket.ID)
+		if err != nil {
+			return err
+		}
+		if enReventa {
+			return ErrTicketNoTransferible
+		}
+
+		if err := tx.
+			Model(&model.TransferenciaTicket{}).
+			Where("ticket_id = ? AND estado = ? AND fecha_expiracion <= ?",
+				transferencia.TicketID, util.TransferenciaPendiente.Codigo(), transferencia.FechaCreacion).
+			Updates(map[string]any{
+				"estado":           util.TransferenciaExpirada.Codigo(),
+				"fecha_resolucion": transferencia.FechaCreacion,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(transferencia).Error; err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return ErrTransferenciaPendiente
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil && !esRechazoTransferencia(err) && err != gorm.ErrRecordNotFound {
+		r.logger.Errorf("TransferenciaTicket.CrearOferta(ticket=%d): %v", transferencia.TicketID, err)
+	}
+	return err
+}
+
+// ObtenerPorID devuelve una transferencia sin relaciones.
+func (r *TransferenciaTicket) ObtenerPorID(id int64) (*model.TransferenciaTicket, error) {
+	var transferencia model.TransferenciaTicket
+	if err := r.PostgresqlDB.
+		Where("transferencia_ticket_id = ?", id).
+		First(&transferencia).Error; err != nil {
+		return nil, err
+	}
+	return &transferencia, nil
+}
+
+// ListarPorUsuario devuelve las transferencias que el usuario ofreció y las que le ofrecieron
+// a su correo, de la más reciente a la más antigua.
+func (r *TransferenciaTicket) ListarPorUsuario(usuarioID int64, correo string) ([]model.TransferenciaTicket, error) {
+	var transferencias []model.TransferenciaTicket
+	if err := r.PostgresqlDB.
+		Preload("Ticket.EventoFecha.Evento").
+		Preload("Ticket.EventoFecha.Fecha").
+		Preload("DeUsuario").
+		Where("de_usuario_id = ? OR lower(para_correo) = lower(?)", usuarioID, correo).
+		Order("fecha_creacion DESC, transferencia_ticket_id DESC").
+		Find(&transferencias).Error; err != nil {
+		r.logger.Errorf("TransferenciaTicket.ListarPorUsuario(%d): %v", usuarioID, err)
+		return nil, err
+	}
+	return transferencias, nil
+}
+
+// Cancelar pasa una oferta PENDIENTE a CANCELADA. Devuelve false si ya estaba resuelta.
+func (r *TransferenciaTicket) Cancelar(id int64, usuarioID int64, ahora time.Time) (bool, error) {
+	res := r.PostgresqlDB.
+		Model(&model.TransferenciaTicket{}).
+		Where("transferencia_ticket_id = ? AND estado = ?", id, util.TransferenciaPendiente.Codigo()).
+		Updates(map[string]any{
+			"estado":           util.TransferenciaCancelada.Codigo(),
+			"fecha_resolucion": ahora,
+			"resuelta_por_id":  usuarioID,
+		})
+	if res.Error != nil {
+		r.logger.Errorf("TransferenciaTicket.Cancelar(%d): %v", id, res.Error)
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// Aceptar entrega el ticket al destinatario en una sola transacción: bloquea la oferta y el
+// ticket, comprueba que la oferta siga PENDIENTE y vigente y que quien ofreció siga siendo el
+// titular, y le cambia el titular al ticket (ver cambiarTitular). Una oferta vencida queda
+// marcada EXPIRADA.
+func (r *TransferenciaTicket) Aceptar(
+	id int64,
+	paraUsuarioID int64,
+	firmar func(*model.Ticket) (string, error),
+	ahora time.Time,
+) (*model.TransferenciaTicket, error) {
+	var transferencia model.TransferenciaTicket
+	var rechazo error
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("transferencia_ticket_id = ?", id).
+			First(&transferencia).Error; err != nil {
+			return err
+		}
+		if transferencia.Estado != util.TransferenciaPendiente.Codigo() {
+			rechazo = ErrTransferenciaNoPendiente
+			return nil
+		}
+		if !ahora.Before(transferencia.FechaExpiracion) {
+			rechazo = ErrTransferenciaExpirada
+			return tx.Model(&transferencia).Updates(map[string]any{
+				"estado":           util.TransferenciaExpirada.Codigo(),
+				"fecha_resolucion": ahora,
+			}).Error
+		}
+
+		ticket, titularID, err := bloquearTicketConTitular(tx, transferencia.TicketID)
+		if err != nil {
+			return err
+		}
+		if titularID != transferencia.DeUsuarioID {
+			rechazo = ErrNoEsTitular
+			return nil
+		}
+		enReventa, err := tieneReventaActiva(tx, ticket.ID)
+		if err != nil {
+			return err
+		}
+		if ticket.EstadoDeTicket != util.TicketVendido.Codigo() || enReventa {
+			rechazo = ErrTicketNoTransferible
+			return nil
+		}
+
+		if err := cambiarTitular(tx, ticket, titularID, paraUsuarioID, model.MotivoTitularTransferencia, &transferencia.ID, firmar, ahora); err != nil {
+			return err
+		}
+
+		transferencia.Estado = util.TransferenciaAceptada.Codigo()
+		transferencia.ParaUsuarioID = &paraUsuarioID
+		transferencia.ResueltaPorID = &paraUsuarioID
+		transferencia.FechaResolucion = &ahora
+		if err := tx.Model(&transferencia).Updates(map[string]any{
+			"estado":           transferencia.Estado,
+			"para_usuario_id":  paraUsuarioID,
+			"resuelta_por_id":  paraUsuarioID,
+			"fecha_resolucion": ahora,
+		}).Error; err != nil {
+			return err
+		}
+		return registrarEventoDominio(tx, model.EventoTicketTransferido, ticket.ID, model.PayloadTicketTransferido{
+			TicketID:        ticket.ID,
+			TransferenciaID: transferencia.ID,
+			DeUsuarioID:     titularID,
+			ParaUsuarioID:   paraUsuarioID,
+		}, ahora)
+	})
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			r.logger.Errorf("TransferenciaTicket.Aceptar(%d): %v", id, err)
+		}
+		return nil, err
+	}
+	if rechazo != nil {
+		return nil, rechazo
+	}
+	return &transferencia, nil
+}
+
+// ListarHistorial devuelve los titulares que tuvo un ticket, del primero al vigente.
+func (r *TransferenciaTicket) ListarHistorial(ticketID int64) ([]model.HistorialTitular, error) {
+	var historial []model.HistorialTitular
+	if err := r.PostgresqlDB.
+		Preload("Usuario").
+		Where("ticket_id = ?", ticketID).
+		Order("desde ASC, historial_titular_id ASC").
+		Find(&historial).Error; err != nil {
+		r.logger.Errorf("TransferenciaTicket.ListarHistorial(%d): %v", ticketID, err)
+		return nil, err
+	}
+	return historial, nil
+}
+
+// TitularDeTicket devuelve el titular actual de un ticket y el comprador de su orden.
+func (r *TransferenciaTicket) TitularDeTicket(ticketID int64) (titularID int64, compradorID int64, err error) {
+	var fila struct {
+		TitularID   int64
+		CompradorID int64
+	}
+	res := r.PostgresqlDB.
+		Table("ticket t").
+		Select("COALESCE(t.titular_id, oc.usuario_id) AS titular_id, oc.usuario_id AS comprador_id").
+		Joins("INNER JOIN orden_de_compra oc ON t.orden_de_compra_id = oc.orden_de_compra_id").
+		Where("t.ticket_id = ?", ticketID).
+		Limit(1).
+		Scan(&fila)
+	if res.Error != nil {
+		r.logger.Errorf("TransferenciaTicket.TitularDeTicket(%d): %v", ticketID, res.Error)
+		return 0, 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, 0, gorm.ErrRecordNotFound
+	}
+	return fila.TitularID, fila.CompradorID, nil
+}
+
+func esRechazoTransferencia(err error) bool {
+	return err == ErrNoEsTitular || err == ErrTicketNoTransferible || err == ErrTransferenciaPendiente
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestAceptarTransferenciaCambiaTitularYQR(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Ticket{}, &model.TransferenciaTicket{}, &model.HistorialTitular{}, &model.EventoDominio{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewTransferenciaTicketController(logging.NewLoggerMock(), db)
+
+	var de, para int64 = 1, 2
+	ticket := &model.Ticket{EventoFechaID: 1, TarifaID: 1, TitularID: &de, CodigoQR: "qr-original", EstadoDeTicket: util.TicketVendido.Codigo()}
+	if err := db.Create(ticket).Error; err != nil {
+		t.Fatalf("crear ticket: %v", err)
+	}
+
+	ahora := time.Now().Truncate(time.Microsecond)
+	oferta := &model.TransferenciaTicket{
+		TicketID:        ticket.ID,
+		DeUsuarioID:     de,
+		ParaCorreo:      "destino@example.com",
+		FechaCreacion:   ahora,
+		FechaExpiracion: ahora.Add(time.Hour),
+	}
+	if err := repo.CrearOferta(oferta); err != nil {
+		t.Fatalf("CrearOferta: %v", err)
+	}
+	segunda := *oferta
+	segunda.ID = 0
+	if err := repo.CrearOferta(&segunda); err != ErrTransferenciaPendiente {
+		t.Fatalf("una segunda oferta pendiente debió rechazarse, se obtuvo %v", err)
+	}
+
+	firmar := func(tk *model.Ticket) (string, error) { return "qr-nuevo", nil }
+	if _, err := repo.Aceptar(oferta.ID, para, firmar, ahora); err != nil {
+		t.Fatalf("Aceptar: %v", err)
+	}
+	if _, err := repo.Aceptar(oferta.ID, para, firmar, ahora); err != ErrTransferenciaNoPendiente {
+		t.Fatalf("aceptar dos veces debió fallar con ErrTransferenciaNoPendiente, se obtuvo %v", err)
+	}
+
+	var actualizado model.Ticket
+	db.First(&actualizado, "ticket_id = ?", ticket.ID)
+	if actualizado.TitularID == nil || *actualizado.TitularID != para || actualizado.CodigoQR != "qr-nuevo" {
+		t.Fatalf("el ticket debió pasar al usuario %d con QR nuevo, se obtuvo %+v", para, actualizado)
+	}
+
+	historial, err := repo.ListarHistorial(ticket.ID)
+	if err != nil {
+		t.Fatalf("ListarHistorial: %v", err)
+	}
+	if len(historial) != 2 || historial[0].UsuarioID != de || historial[0].Hasta == nil ||
+		historial[1].UsuarioID != para || historial[1].Hasta != nil {
+		t.Fatalf("historial inesperado: %+v", historial)
+	}
+
+	var eventos []model.EventoDominio
+	db.Where("tipo = ?", model.EventoTicketTransferido).Find(&eventos)
+	if len(eventos) != 1 || eventos[0].AgregadoID != ticket.ID {
+		t.Fatalf("aceptar debió registrar un único TICKET_TRANSFERIDO, se obtuvo %+v", eventos)
+	}
+}
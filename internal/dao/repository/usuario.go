@@ -2,8 +2,8 @@ package repository
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"errors"
-	"fmt"
 	"math/big"
 	"time"
 
@@ -193,6 +193,18 @@ func (u *Usuario) ObtenerUsuarioBasicoPorID(id int64) (*model.Usuario, error) {
 	return &user, nil
 }
 
+// ObtenerDestinatarios devuelve nombre y correo de los usuarios indicados, para avisarles algo.
+func (u *Usuario) ObtenerDestinatarios(ids []int64) ([]TitularTicket, error) {
+	var rows []TitularTicket
+	err := u.PostgresqlDB.
+		Table("usuario").
+		Select("usuario_id, nombre, correo").
+		Where("usuario_id IN ?", ids).
+		Order("usuario_id").
+		Find(&rows).Error
+	return rows, err
+}
+
 //Funcion para obtener los usuarios que tienen un rol específico por id de rol
 
 func (c *Usuario) ObtenerUsuariosPorRolID(rolID int64) ([]*model.Usuario, error) {
@@ -238,46 +250,121 @@ func GenerarCodigoVerificacion() (string, error) {
     return string(code), nil
 }
 
-// ActualizarCodigoVerificacion actualiza el código de verificación del usuario
-func (r *Usuario) ActualizarCodigoVerificacion(usuarioID int64, codigo string, expira time.Time) error {
-    result := r.PostgresqlDB.Model(&model.Usuario{}).
-        Where("usuario_id = ?", usuarioID).
-        Updates(map[string]interface{}{
-            "codigo_verificacion":      codigo,
-            "fecha_expiracion_codigo":  expira,
-        })
-    
-    if result.Error != nil {
-        return result.Error
-    }
-    return nil
+const (
+	// MaxIntentosCodigo es la cantidad de códigos erróneos permitidos antes de invalidar el código
+	MaxIntentosCodigo int16 = 5
+	// MaxEnviosCodigoPorHora limita los reenvíos de código dentro de una ventana de una hora
+	MaxEnviosCodigoPorHora int16 = 5
+)
+
+var (
+	ErrCodigoInvalido       = errors.New("código inválido")
+	ErrCodigoExpirado       = errors.New("código expirado")
+	ErrCodigoSinIntentos    = errors.New("se agotaron los intentos del código")
+	ErrReenvioLimitado      = errors.New("se alcanzó el límite de reenvíos del código")
+	ErrRecuperacionLimitada = errors.New("ya se envió un link de recuperación hace poco")
+)
+
+// ActualizarCodigoVerificacion guarda un nuevo código para el usuario, reinicia los intentos fallidos
+// y lleva la cuenta de envíos dentro de la ventana de una hora. El límite de reenvíos (uno cada
+// intervaloReenvio y MaxEnviosCodigoPorHora por hora) se evalúa en el mismo UPDATE, así dos
+// pedidos simultáneos no pueden pasar ambos: si no se actualizó ninguna fila devuelve
+// ErrReenvioLimitado.
+func (r *Usuario) ActualizarCodigoVerificacion(usuarioID int64, codigo string, expira time.Time, intervaloReenvio time.Duration) error {
+	now := time.Now()
+	result := r.PostgresqlDB.Model(&model.Usuario{}).
+		Where("usuario_id = ?", usuarioID).
+		Where("fecha_ultimo_envio IS NULL OR (fecha_ultimo_envio < ? AND (fecha_ultimo_envio < ? OR envios_codigo < ?))",
+			now.Add(-intervaloReenvio), now.Add(-time.Hour), MaxEnviosCodigoPorHora).
+		Updates(map[string]interface{}{
+			"codigo_verificacion":     codigo,
+			"fecha_expiracion_codigo": expira,
+			"intentos_codigo":         0,
+			"envios_codigo": gorm.Expr(
+				"CASE WHEN fecha_ultimo_envio IS NULL OR fecha_ultimo_envio < ? THEN 1 ELSE envios_codigo + 1 END",
+				now.Add(-time.Hour),
+			),
+			"fecha_ultimo_envio": now,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReenvioLimitado
+	}
+	return nil
 }
 
-// VerificarCodigo verifica el código de verificación
-func (r *Usuario) VerificarCodigo(usuarioID int64, codigo string) error {
-    var usuario model.Usuario
-    err := r.PostgresqlDB.Where("usuario_id = ?", usuarioID).First(&usuario).Error
-    if err != nil {
-        return fmt.Errorf("usuario no encontrado")
-    }
+// RegistrarSolicitudRecuperacion marca el envío de un link de recuperación de contraseña si el
+// anterior fue hace más de intervalo. Como el reenvío del código de verificación, la condición va
+// en el mismo UPDATE; si no se actualizó ninguna fila devuelve ErrRecuperacionLimitada.
+func (r *Usuario) RegistrarSolicitudRecuperacion(usuarioID int64, intervalo time.Duration) error {
+	now := time.Now()
+	result := r.PostgresqlDB.Model(&model.Usuario{}).
+		Where("usuario_id = ?", usuarioID).
+		Where("fecha_recuperacion IS NULL OR fecha_recuperacion < ?", now.Add(-intervalo)).
+		Update("fecha_recuperacion", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecuperacionLimitada
+	}
+	return nil
+}
 
-    if usuario.CodigoVerificacion == nil || *usuario.CodigoVerificacion != codigo {
-        return fmt.Errorf("código inválido")
-    }
+// VerificarCodigo compara el código recibido con el guardado. Cada fallo suma un intento y, al llegar
+// a MaxIntentosCodigo, el código se invalida. Si es correcto, marca el correo como verificado
+// dejando la cuenta en estadoDeCuenta (0=pendiente, 1=verificado, 2=bloqueado).
+func (r *Usuario) VerificarCodigo(usuarioID int64, codigo string, estadoDeCuenta int16) error {
+	// El intento fallido se tiene que guardar aunque el resultado sea un error,
+	// por eso el error del código se devuelve fuera de la transacción.
+	var errCodigo error
+	err := r.PostgresqlDB.Transaction(func(tx *gorm.DB) error {
+		var usuario model.Usuario
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("usuario_id = ?", usuarioID).
+			First(&usuario).Error
+		if err != nil {
+			return err
+		}
 
-    if usuario.FechaExpiracionCodigo == nil || time.Now().After(*usuario.FechaExpiracionCodigo) {
-        return fmt.Errorf("código expirado")
-    }
+		if usuario.CodigoVerificacion == nil || usuario.IntentosCodigo >= MaxIntentosCodigo {
+			errCodigo = ErrCodigoSinIntentos
+			return nil
+		}
+
+		if usuario.FechaExpiracionCodigo == nil || time.Now().After(*usuario.FechaExpiracionCodigo) {
+			errCodigo = ErrCodigoExpirado
+			return nil
+		}
 
-    // Marcar como verificado (usando estado_de_cuenta)
-    // estado_de_cuenta: 0=pendiente verificación, 1=verificado, 2=bloqueado
-    result := r.PostgresqlDB.Model(&model.Usuario{}).
-        Where("usuario_id = ?", usuarioID).
-        Updates(map[string]interface{}{
-            "estado_de_cuenta":         1, // Verificado
-            "codigo_verificacion":      nil,
-            "fecha_expiracion_codigo":  nil,
-        })
-
-    return result.Error
-}
\ No newline at end of file
+		if subtle.ConstantTimeCompare([]byte(*usuario.CodigoVerificacion), []byte(codigo)) != 1 {
+			intentos := usuario.IntentosCodigo + 1
+			updates := map[string]interface{}{"intentos_codigo": intentos}
+			if intentos >= MaxIntentosCodigo {
+				updates["codigo_verificacion"] = nil
+				updates["fecha_expiracion_codigo"] = nil
+			}
+			if err := tx.Model(&model.Usuario{}).Where("usuario_id = ?", usuarioID).Updates(updates).Error; err != nil {
+				return err
+			}
+			errCodigo = ErrCodigoInvalido
+			return nil
+		}
+
+		return tx.Model(&model.Usuario{}).
+			Where("usuario_id = ?", usuarioID).
+			Updates(map[string]interface{}{
+				"estado_de_cuenta":        estadoDeCuenta,
+				"codigo_verificacion":     nil,
+				"fecha_expiracion_codigo": nil,
+				"intentos_codigo":         0,
+			}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return errCodigo
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nexivent/nexivent-backend/internal/dao/model"
+	"github.com/Nexivent/nexivent-backend/logging"
+)
+
+func TestReenviosDeCodigoSimultaneosSoloGuardanUno(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Usuario{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewUsuariosController(logging.NewLoggerMock(), db)
+
+	usuario := &model.Usuario{Nombre: "Ana", TipoDocumento: "DNI", NumDocumento: "12345678", Correo: "ana@example.com"}
+	if err := db.Create(usuario).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+
+	const pedidos = 8
+	var wg sync.WaitGroup
+	resultados := make(chan error, pedidos)
+	for i := 0; i < pedidos; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resultados <- repo.ActualizarCodigoVerificacion(usuario.ID, "123456", time.Now().Add(15*time.Minute), time.Minute)
+		}()
+	}
+	wg.Wait()
+	close(resultados)
+
+	guardados := 0
+	for err := range resultados {
+		switch err {
+		case nil:
+			guardados++
+		case ErrReenvioLimitado:
+		default:
+			t.Fatalf("ActualizarCodigoVerificacion: %v", err)
+		}
+	}
+	if guardados != 1 {
+		t.Fatalf("solo un reenvío por minuto debe pasar, pasaron %d", guardados)
+	}
+
+	// Pasado el intervalo se permite otro envío, hasta MaxEnviosCodigoPorHora en la hora
+	for i := int16(1); i < MaxEnviosCodigoPorHora; i++ {
+		db.Model(usuario).Update("fecha_ultimo_envio", time.Now().Add(-2*time.Minute))
+		if err := repo.ActualizarCodigoVerificacion(usuario.ID, "123456", time.Now().Add(15*time.Minute), time.Minute); err != nil {
+			t.Fatalf("reenvío %d: %v", i+1, err)
+		}
+	}
+	db.Model(usuario).Update("fecha_ultimo_envio", time.Now().Add(-2*time.Minute))
+	if err := repo.ActualizarCodigoVerificacion(usuario.ID, "123456", time.Now().Add(15*time.Minute), time.Minute); err != ErrReenvioLimitado {
+		t.Fatalf("se esperaba el límite por hora, se obtuvo %v", err)
+	}
+}
+
+func TestSolicitudesDeRecuperacionRespetanElIntervalo(t *testing.T) {
+	db := abrirBDPrueba(t)
+	if err := db.AutoMigrate(&model.Usuario{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	repo := NewUsuariosController(logging.NewLoggerMock(), db)
+
+	usuario := &model.Usuario{Nombre: "Luis", TipoDocumento: "DNI", NumDocumento: "87654321", Correo: "luis@example.com"}
+	if err := db.Create(usuario).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+
+	if err := repo.RegistrarSolicitudRecuperacion(usuario.ID, 2*time.Minute); err != nil {
+		t.Fatalf("primera solicitud: %v", err)
+	}
+	if err := repo.RegistrarSolicitudRecuperacion(usuario.ID, 2*time.Minute); err != ErrRecuperacionLimitada {
+		t.Fatalf("una segunda solicitud inmediata debió limitarse, se obtuvo %v", err)
+	}
+	db.Model(usuario).Update("fecha_recuperacion", time.Now().Add(-3*time.Minute))
+	if err := repo.RegistrarSolicitudRecuperacion(usuario.ID, 2*time.Minute); err != nil {
+		t.Fatalf("pasado el intervalo debió permitirse, se obtuvo %v", err)
+	}
+}
@@ -42,6 +42,18 @@ func New(host string, port int, username, password, sender string) Mailer {
 // as the first parameter, the name of the file containing the templates, and any
 // dynamic data for the templates as an any parameter.
 func (m Mailer) Send(recipient, templateFile string, data any) error {
+	return m.SendConAdjuntos(recipient, templateFile, data)
+}
+
+// Adjunto es un archivo que se envía junto al correo (p. ej. el PDF de los tickets).
+type Adjunto struct {
+	Nombre string
+	Tipo   string // MIME, p. ej. "application/pdf"
+	Datos  []byte
+}
+
+// SendConAdjuntos es Send con archivos adjuntos.
+func (m Mailer) SendConAdjuntos(recipient, templateFile string, data any, adjuntos ...Adjunto) error {
 	// Use the ParseFS() method to parse the required template file from the embedded
 	// file system.
 	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
@@ -79,6 +91,10 @@ func (m Mailer) Send(recipient, templateFile string, data any) error {
 	msg.SetHeader("Subject", subject.String())
 	msg.SetBody("text/plain", plainBody.String())
 	msg.AddAlternative("text/html", htmlBody.String())
+	for _, adjunto := range adjuntos {
+		msg.AttachReader(adjunto.Nombre, bytes.NewReader(adjunto.Datos),
+			mail.SetHeader(map[string][]string{"Content-Type": {adjunto.Tipo}}))
+	}
 	// Call the DialAndSend() method on the dialer, passing in the message to send. This
 	// opens a connection to the SMTP server, sends the message, then closes the
 	// connection. If there is a timeout, it will return a "dial tcp: i/o timeout"
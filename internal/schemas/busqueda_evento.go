@@ -0,0 +1,50 @@
+package schemas
+
+// Búsqueda de eventos: /evento/buscar?q=rock lima&categoriaId=2&fecha=PROXIMOS_7_DIAS&precio=HASTA_50
+// con la misma paginación que los listados (por defecto, por relevancia).
+type BusquedaEventosRequest struct {
+	Consulta    string
+	CategoriaID *int64
+	Fecha       string // "HOY" | "PROXIMOS_7_DIAS" | "PROXIMOS_30_DIAS" | "MAS_ADELANTE"
+	Precio      string // "HASTA_50" | "DE_50_A_100" | "DE_100_A_200" | "MAS_DE_200"
+	Pagina      PaginaEventosRequest
+}
+
+// Response 200 de la búsqueda: una página de resultados y las facetas para acotarla. Cada faceta
+// cuenta con los demás filtros aplicados, pero no el suyo.
+type BusquedaEventosResponse struct {
+	Resultados      []ResultadoBusquedaEvento `json:"resultados"`
+	Total           int64                     `json:"total"`
+	TotalPaginas    int                       `json:"total_paginas"`
+	Limite          int                       `json:"limite"`
+	Orden           string                    `json:"orden"`
+	SiguienteCursor *string                   `json:"siguiente_cursor"` // null en la última página
+	Facetas         FacetasBusquedaEventos    `json:"facetas"`
+}
+
+// Un evento encontrado. El título y el fragmento vienen con el HTML escapado y las coincidencias
+// entre <mark> y </mark>.
+type ResultadoBusquedaEvento struct {
+	Evento          EventoResumen `json:"evento"`
+	Relevancia      float64       `json:"relevancia"`
+	TituloResaltado string        `json:"tituloResaltado"`
+	Fragmento       string        `json:"fragmento"`
+}
+
+type FacetasBusquedaEventos struct {
+	Categorias []FacetaCategoria `json:"categorias"`
+	Fechas     []FacetaRango     `json:"fechas"`
+	Precios    []FacetaRango     `json:"precios"`
+}
+
+type FacetaCategoria struct {
+	IdCategoria int64  `json:"idCategoria"`
+	Nombre      string `json:"nombre"`
+	Cantidad    int64  `json:"cantidad"`
+}
+
+// Clave es el valor para el parámetro fecha o precio de la búsqueda
+type FacetaRango struct {
+	Clave    string `json:"clave"`
+	Cantidad int64  `json:"cantidad"`
+}
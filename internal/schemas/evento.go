@@ -4,11 +4,42 @@ import (
 	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 )
 
+// Paginación de los listados de eventos: ?limite=20&orden=fecha y, para las páginas siguientes,
+// ?cursor= con el siguiente_cursor de la respuesta anterior (con el mismo orden y filtros).
+type PaginaEventosRequest struct {
+	Cursor string
+	Limite int    // 0: 20 por página; hasta 100
+	Orden  string // "fecha" | "popularidad" | "precio" | "relevancia" | "distancia"; vacío: el del listado
+}
+
 type EventosPaginados struct {
-	Eventos      []*model.Evento `json:"eventos"`
-	Total        int64           `json:"total"`
-	PaginaActual int             `json:"pagina_actual"`
-	TotalPaginas int             `json:"total_paginas"`
+	Eventos         []EventoResumen `json:"eventos"`
+	Total           int64           `json:"total"`
+	TotalPaginas    int             `json:"total_paginas"`
+	Limite          int             `json:"limite"`
+	Orden           string          `json:"orden"`
+	SiguienteCursor *string         `json:"siguiente_cursor"` // null en la última página
+}
+
+// EventoResumen es un evento en los listados: sin sectores, tarifas ni tipos de ticket, que vienen
+// completos en /evento/{eventoId}/. Las claves son las de model.Evento.
+type EventoResumen struct {
+	ID             int64                 `json:"ID"`
+	OrganizadorID  int64                 `json:"OrganizadorID"`
+	CategoriaID    int64                 `json:"CategoriaID"`
+	RecintoID      *int64                `json:"RecintoID"`
+	Titulo         string                `json:"Titulo"`
+	Descripcion    string                `json:"Descripcion"`
+	Lugar          string                `json:"Lugar"`
+	EventoEstado   int16                 `json:"EventoEstado"`
+	CantMeGusta    int64                 `json:"CantMeGusta"`
+	CantNoInteresa int64                 `json:"CantNoInteresa"`
+	ImagenPortada  string                `json:"ImagenPortada"`
+	PrecioDesde    *float64              `json:"PrecioDesde"`           // null si no tiene tarifas a la venta
+	Recinto        *RecintoResponse      `json:"Recinto"`               // null si el lugar es solo texto
+	DistanciaKm    *float64              `json:"DistanciaKm,omitempty"` // con búsqueda por cercanía
+	EventDates     []model.EventDateView `json:"eventDates"`
+	Interaccion    []model.Interaccion   `json:"Interaccion,omitempty"` // feed con interacciones
 }
 
 // EventDateRequest represents the event date information in the request
@@ -96,6 +127,7 @@ type MetadataResponse struct {
 type EventoRequest struct {
 	IdOrganizador     int64               `json:"idOrganizador"`
 	IdCategoria       int64               `json:"idCategoria"`
+	IdRecinto         *int64              `json:"idRecinto,omitempty"` // sin lugar ni imagenLugar, se toman del recinto
 	Titulo            string              `json:"titulo"`
 	Descripcion       string              `json:"descripcion"`
 	Lugar             string              `json:"lugar"`
@@ -120,6 +152,7 @@ type EventoResponse struct {
 	IdEvento          int64                `json:"idEvento"`
 	IdOrganizador     int64                `json:"idOrganizador"`
 	IdCategoria       int64                `json:"idCategoria"`
+	IdRecinto         *int64               `json:"idRecinto,omitempty"`
 	Titulo            string               `json:"titulo"`
 	Descripcion       string               `json:"descripcion"`
 	Lugar             string               `json:"lugar"`
@@ -187,10 +220,12 @@ type VentaPorSectorOrganizador struct {
 }
 
 type EventoFechaOrganizadorReporte struct {
-	IdFechaEvento int64  `json:"idFechaEvento"`
-	Fecha         string `json:"fecha"`
-	HoraInicio    string `json:"horaInicio"`
-	HoraFin       string `json:"horaFin"`
+	IdFechaEvento  int64                       `json:"idFechaEvento"`
+	Fecha          string                      `json:"fecha"`
+	HoraInicio     string                      `json:"horaInicio"`
+	HoraFin        string                      `json:"horaFin"`
+	Estado         string                      `json:"estado"` // "ACTIVA" | "REPROGRAMADA" | "INACTIVA"
+	Reprogramacion *ReprogramacionFechaReporte `json:"reprogramacion,omitempty"`
 }
 
 type InteraccionConEventoRequest struct {
@@ -213,3 +248,27 @@ type InteraccionConEventoResponse struct {
 	Data    EventoInteraccionResponse `json:"data"`
 }
 
+
+// Request para cambiar el estado de un evento:
+// { "estado": "CANCELADO" }
+//
+// Transiciones permitidas: BORRADOR → PUBLICADO | CANCELADO; PUBLICADO → POSTERGADO |
+// FINALIZADO | CANCELADO; POSTERGADO → PUBLICADO | CANCELADO.
+type CambiarEstadoEventoRequest struct {
+	Estado string `json:"estado"`
+}
+
+// Request para programar la publicación de un evento en BORRADOR:
+// { "publicarEn": "2026-11-01T10:00:00-05:00" }
+//
+// Con publicarEn null se quita la programación.
+type ProgramarPublicacionRequest struct {
+	PublicarEn *string `json:"publicarEn"` // RFC3339
+}
+
+// Estado de un evento en su ciclo de vida
+type EstadoEventoResponse struct {
+	IdEvento   int64  `json:"idEvento"`
+	Estado     string `json:"estado"`               // "BORRADOR" | "PUBLICADO" | "CANCELADO" | "FINALIZADO" | "POSTERGADO"
+	PublicarEn string `json:"publicarEn,omitempty"` // RFC3339
+}
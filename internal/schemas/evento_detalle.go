@@ -29,6 +29,8 @@ type EventoDetalleDTO struct {
 	Descripcion   string           `json:"descripcion"`
 	ImagenPortada string           `json:"imagenPortada"`
 	Lugar         string           `json:"lugar"`
+	IdRecinto     *int64           `json:"idRecinto"`
+	Recinto       *RecintoResponse `json:"recinto"` // null si el lugar es solo texto
 	Fechas        []FechaEventoDTO `json:"fechas"`
 	Tarifas       []TarifaDTO      `json:"tarifas"`
 }
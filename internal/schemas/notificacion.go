@@ -0,0 +1,77 @@
+package schemas
+
+// Notificación que agotó sus intentos (dead letter), para el panel de administración
+type NotificacionFallida struct {
+	IdNotificacion   int64  `json:"idNotificacion"`
+	IdEventoDominio  *int64 `json:"idEventoDominio,omitempty"`
+	IdUsuario        *int64 `json:"idUsuario,omitempty"`
+	Canal            string `json:"canal"` // "EMAIL" | "WEBHOOK" | "IN_APP"
+	Destino          string `json:"destino,omitempty"`
+	Asunto           string `json:"asunto"`
+	Intentos         int16  `json:"intentos"`
+	UltimoError      string `json:"ultimoError,omitempty"`
+	FechaCreacion    string `json:"fechaCreacion"` // RFC3339
+	FechaUltimoEnvio string `json:"fechaUltimoEnvio"`
+}
+
+// Evento de dominio que no se pudo convertir en notificaciones
+type EventoDominioFallido struct {
+	IdEventoDominio int64  `json:"idEventoDominio"`
+	Tipo            string `json:"tipo"` // "ORDEN_CONFIRMADA" | "EVENTO_CANCELADO" | "EVENTO_REPROGRAMADO" | "TICKET_TRANSFERIDO"
+	IdAgregado      int64  `json:"idAgregado"`
+	Payload         string `json:"payload"`
+	Intentos        int16  `json:"intentos"`
+	UltimoError     string `json:"ultimoError,omitempty"`
+	FechaCreacion   string `json:"fechaCreacion"` // RFC3339
+}
+
+// Response 200 de GET /api/admin/notificaciones/fallidas
+type NotificacionesFallidasResponse struct {
+	Notificaciones []NotificacionFallida  `json:"notificaciones"`
+	Eventos        []EventoDominioFallido `json:"eventos"`
+}
+
+// Notificación de la bandeja in-app del usuario
+type NotificacionBandeja struct {
+	IdNotificacion int64   `json:"idNotificacion"`
+	Tipo           string  `json:"tipo"` // "ORDEN_CONFIRMADA" | "EVENTO_CANCELADO" | "EVENTO_REPROGRAMADO" | "TICKET_TRANSFERIDO"
+	Asunto         string  `json:"asunto"`
+	Mensaje        string  `json:"mensaje"`
+	Leida          bool    `json:"leida"`
+	FechaCreacion  string  `json:"fechaCreacion"` // RFC3339
+	FechaLectura   *string `json:"fechaLectura,omitempty"`
+}
+
+// Response 200 de GET /member/notificaciones
+type BandejaNotificacionesResponse struct {
+	Notificaciones  []NotificacionBandeja `json:"notificaciones"`
+	SiguienteCursor *int64                `json:"siguienteCursor"` // null cuando no hay más páginas
+}
+
+// Response 200 de GET /member/notificaciones/no-leidas
+type NotificacionesNoLeidasResponse struct {
+	NoLeidas int64 `json:"noLeidas"`
+}
+
+// Response 200 de POST /member/notificaciones/leer-todas
+type NotificacionesMarcadasResponse struct {
+	Marcadas int64 `json:"marcadas"`
+}
+
+// Preferencia de un usuario para un tipo de notificación y un canal
+type PreferenciaNotificacion struct {
+	Tipo        string `json:"tipo"`
+	Canal       string `json:"canal"` // "EMAIL" | "IN_APP"
+	Habilitado  bool   `json:"habilitado"`
+	Obligatoria bool   `json:"obligatoria,omitempty"` // no se puede deshabilitar
+}
+
+// Request de PUT /member/notificaciones/preferencias; las combinaciones que no se envían no cambian
+type PreferenciasNotificacionRequest struct {
+	Preferencias []PreferenciaNotificacion `json:"preferencias"`
+}
+
+// Response 200 de GET y PUT /member/notificaciones/preferencias, con todas las combinaciones
+type PreferenciasNotificacionResponse struct {
+	Preferencias []PreferenciaNotificacion `json:"preferencias"`
+}
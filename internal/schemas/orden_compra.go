@@ -15,18 +15,35 @@ type EntradaOrdenRequest struct {
 //   "idFechaEvento": "",
 //   "idUsuario": "",
 //   "total": "",
+//   "codigoCupon": "",
 //   "entradas": [
 //     { "idTarifa": "", "cantidad": "" }
 //   ]
 // }
+//
+// "total" es el monto que el cliente espera pagar: el backend calcula el precio
+// y rechaza la orden si no coincide.
 type CrearOrdenTemporalRequest struct {
 	IdEvento      int64                 `json:"idEvento"`
 	IdFechaEvento int64                 `json:"idFechaEvento"`
 	IdUsuario     int64                 `json:"idUsuario"`
 	Total         float64               `json:"total"`
+	CodigoCupon   string                `json:"codigoCupon,omitempty"`
 	Entradas      []EntradaOrdenRequest `json:"entradas"`
 }
 
+// Línea del desglose de precios de la orden (calculada en el backend)
+type ItemOrdenResponse struct {
+	IdTarifa       int64   `json:"idTarifa"`
+	IdSector       int64   `json:"idSector"`
+	IdTipoTicket   int64   `json:"idTipoTicket"`
+	IdPerfil       *int64  `json:"idPerfil,omitempty"`
+	Cantidad       int64   `json:"cantidad"`
+	PrecioUnitario float64 `json:"precioUnitario"`
+	Descuento      float64 `json:"descuento"`
+	Subtotal       float64 `json:"subtotal"`
+}
+
 // Response 201:
 // {
 //   "orderId": "",
@@ -37,12 +54,16 @@ type CrearOrdenTemporalRequest struct {
 //   "ttlSeconds": ""
 // }
 type CrearOrdenTemporalResponse struct {
-	OrderID    int64   `json:"orderId"`
-	Estado     string  `json:"estado"` // "TEMPORAL"
-	Total      float64 `json:"total"`
-	StartedAt  string  `json:"startedAt"`  // RFC3339
-	ExpiresAt  string  `json:"expiresAt"`  // RFC3339
-	TTLSeconds int64   `json:"ttlSeconds"` // segundos
+	OrderID          int64               `json:"orderId"`
+	Estado           string              `json:"estado"` // "TEMPORAL"
+	Items            []ItemOrdenResponse `json:"items"`
+	Subtotal         float64             `json:"subtotal"`  // antes de descuentos
+	Descuento        float64             `json:"descuento"` // descuento del cupón
+	Total            float64             `json:"total"`
+	MontoFeeServicio float64             `json:"montoFeeServicio"` // incluido en total
+	StartedAt        string              `json:"startedAt"`        // RFC3339
+	ExpiresAt        string              `json:"expiresAt"`        // RFC3339
+	TTLSeconds       int64               `json:"ttlSeconds"`       // segundos
 }
 
 // Response 200:
@@ -63,13 +84,31 @@ type ObtenerHoldResponse struct {
 	Total         float64 `json:"total"`
 }
 
+// Request:
+// { "metodoPago": "Tarjeta" }
+type CrearIntentoPagoRequest struct {
+	MetodoPago string `json:"metodoPago"` // "Tarjeta" | "Yape"
+}
+
+// Response 201:
+// { "orderId": "", "paymentId": "", "metodoPago": "", "monto": "", "moneda": "PEN", "estado": "PENDIENTE", "clientSecret": "" }
+type CrearIntentoPagoResponse struct {
+	OrderID      int64   `json:"orderId"`
+	PaymentID    string  `json:"paymentId"` // referencia del intento en la pasarela
+	MetodoPago   string  `json:"metodoPago"`
+	Monto        float64 `json:"monto"`
+	Moneda       string  `json:"moneda"`
+	Estado       string  `json:"estado"`
+	ClientSecret string  `json:"clientSecret"` // para que el frontend complete el pago en la pasarela
+}
+
 // Request:
 // { "paymentId": "" }
+//
+// paymentId es la referencia devuelta al crear el intento de pago; el backend captura
+// el cobro en la pasarela y solo confirma la orden si la captura se completa.
 type ConfirmarOrdenRequest struct {
-	PaymentID       string `json:"paymentId"`
-	IdEvento        int64  `json:"idEvento"`
-	FechaEvento     string `json:"fechaEvento"` // "YYYY-MM-DD"
-	CantidadVendida int64  `json:"cantidadVendida"`
+	PaymentID string `json:"paymentId"`
 }
 
 // Response 200:
@@ -0,0 +1,41 @@
+package schemas
+
+// RecintoRequest crea o reemplaza un recinto. El ubigeo es el código INEI de 6 dígitos del
+// distrito (el mismo que devuelve la validación de documentos).
+type RecintoRequest struct {
+	Nombre             string  `json:"nombre"`
+	Direccion          string  `json:"direccion"`
+	Departamento       string  `json:"departamento"`
+	Provincia          string  `json:"provincia"`
+	Distrito           string  `json:"distrito"`
+	Ubigeo             string  `json:"ubigeo"`
+	Latitud            float64 `json:"latitud"`
+	Longitud           float64 `json:"longitud"`
+	Capacidad          int64   `json:"capacidad"`
+	ImagenMapaAsientos string  `json:"imagenMapaAsientos"`
+	Estado             *int16  `json:"estado,omitempty"` // 1 por defecto; 0 lo oculta de los listados
+}
+
+type RecintoResponse struct {
+	IdRecinto          int64    `json:"idRecinto"`
+	Nombre             string   `json:"nombre"`
+	Direccion          string   `json:"direccion"`
+	Departamento       string   `json:"departamento"`
+	Provincia          string   `json:"provincia"`
+	Distrito           string   `json:"distrito"`
+	Ubigeo             string   `json:"ubigeo"`
+	Latitud            float64  `json:"latitud"`
+	Longitud           float64  `json:"longitud"`
+	Capacidad          int64    `json:"capacidad"`
+	ImagenMapaAsientos string   `json:"imagenMapaAsientos"`
+	Estado             int16    `json:"estado"`
+	DistanciaKm        *float64 `json:"distanciaKm,omitempty"` // solo al buscar por cercanía
+}
+
+// Búsqueda por cercanía: ?lat=-12.06&lng=-77.04&radioKm=10. Lat y lng van juntos; sin radioKm se
+// usan 10 km.
+type CercaniaRequest struct {
+	Latitud  *float64
+	Longitud *float64
+	RadioKm  *float64
+}
@@ -0,0 +1,39 @@
+package schemas
+
+// Request del administrador para reembolsar parte de una orden:
+// { "idTickets": [10, 11], "reembolsarFee": false }
+//
+// Sin idTickets se reembolsan todos los tickets reembolsables de la orden. reembolsarFee
+// devuelve también la parte proporcional del fee de servicio.
+type ReembolsarOrdenRequest struct {
+	IdTickets     []int64 `json:"idTickets"`
+	ReembolsarFee bool    `json:"reembolsarFee"`
+}
+
+// Ticket devuelto en un reembolso
+type TicketReembolsado struct {
+	IdTicket         int64   `json:"idTicket"`
+	Monto            float64 `json:"monto"`
+	MontoFeeServicio float64 `json:"montoFeeServicio"`
+}
+
+// Reembolso de una orden. Si la pasarela falló queda FALLIDO con el error y los tickets siguen
+// cancelados hasta que un administrador lo reintente.
+type Reembolso struct {
+	IdReembolso      int64               `json:"idReembolso"`
+	IdOrden          int64               `json:"idOrden"`
+	Motivo           string              `json:"motivo"` // "CANCELACION_USUARIO" | "CANCELACION_EVENTO" | "REPROGRAMACION" | "ADMINISTRADOR"
+	Estado           string              `json:"estado"` // "PENDIENTE" | "COMPLETADO" | "FALLIDO"
+	Monto            float64             `json:"monto"`
+	MontoFeeServicio float64             `json:"montoFeeServicio"`
+	Tickets          []TicketReembolsado `json:"tickets"`
+	NotaCredito      string              `json:"notaCredito,omitempty"` // número del comprobante
+	UltimoError      string              `json:"ultimoError,omitempty"`
+	FechaCreacion    string              `json:"fechaCreacion"` // RFC3339
+	FechaCompletado  string              `json:"fechaCompletado,omitempty"`
+}
+
+// Response 200 con un listado de reembolsos
+type ReembolsosResponse struct {
+	Reembolsos []Reembolso `json:"reembolsos"`
+}
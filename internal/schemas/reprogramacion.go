@@ -0,0 +1,52 @@
+package schemas
+
+// Request del organizador para reprogramar una fecha con entradas vendidas:
+// { "nuevaFecha": "2026-12-05", "nuevaHoraInicio": "20:30", "fechaLimite": "2026-11-20T23:59:00-05:00" }
+//
+// fechaLimite es hasta cuándo los titulares pueden pedir el reembolso; tiene que caer antes del
+// nuevo inicio.
+type ReprogramarFechaRequest struct {
+	NuevaFecha      string `json:"nuevaFecha"`      // YYYY-MM-DD
+	NuevaHoraInicio string `json:"nuevaHoraInicio"` // HH:MM
+	FechaLimite     string `json:"fechaLimite"`     // RFC3339
+}
+
+// Response 201 de una reprogramación: la fecha original queda inactiva y sus tickets vendidos
+// pasan a la nueva.
+type ReprogramacionResponse struct {
+	IdReprogramacion      int64  `json:"idReprogramacion"`
+	IdEvento              int64  `json:"idEvento"`
+	IdFechaEventoOriginal int64  `json:"idFechaEventoOriginal"`
+	IdFechaEventoNueva    int64  `json:"idFechaEventoNueva"`
+	Fecha                 string `json:"fecha"`
+	HoraInicio            string `json:"horaInicio"`
+	FechaLimite           string `json:"fechaLimite"` // RFC3339
+	TicketsMigrados       int64  `json:"ticketsMigrados"`
+}
+
+// Request del titular para responder a la reprogramación de su ticket:
+// { "respuesta": "ACEPTAR" } o { "respuesta": "REEMBOLSAR" }
+type ResponderReprogramacionRequest struct {
+	Respuesta string `json:"respuesta"`
+}
+
+// Response 200 con la respuesta registrada. Si pidió el reembolso, el ticket queda cancelado y se
+// devuelve lo pagado con el fee de servicio incluido.
+type RespuestaReprogramacionResponse struct {
+	IdTicket  int64      `json:"idTicket"`
+	Estado    string     `json:"estado"` // "ACEPTADA" | "REEMBOLSADA"
+	Reembolso *Reembolso `json:"reembolso,omitempty"`
+}
+
+// Estado de la reprogramación de una fecha en el reporte del organizador
+type ReprogramacionFechaReporte struct {
+	IdReprogramacion   int64  `json:"idReprogramacion"`
+	IdFechaEventoNueva int64  `json:"idFechaEventoNueva"`
+	FechaLimite        string `json:"fechaLimite"` // RFC3339
+	Abierta            bool   `json:"abierta"`     // los titulares aún pueden responder
+	TicketsMigrados    int64  `json:"ticketsMigrados"`
+	Pendientes         int64  `json:"pendientes"`
+	Aceptadas          int64  `json:"aceptadas"`
+	Reembolsadas       int64  `json:"reembolsadas"`
+	Vencidas           int64  `json:"vencidas"` // sin respuesta al cierre: cuentan como aceptadas
+}
@@ -0,0 +1,63 @@
+package schemas
+
+// Request del organizador:
+// { "habilitada": true, "precioMaximoPorcentaje": 120, "regaliaPorcentaje": 5 }
+//
+// precioMaximoPorcentaje es el tope del precio de reventa en % del precio original de la
+// tarifa (100 = no se puede revender por encima de lo que costó). regaliaPorcentaje es la
+// parte de cada reventa que recibe el organizador.
+type PoliticaReventaRequest struct {
+	Habilitada             bool    `json:"habilitada"`
+	PrecioMaximoPorcentaje float64 `json:"precioMaximoPorcentaje"`
+	RegaliaPorcentaje      float64 `json:"regaliaPorcentaje"`
+}
+
+// Response 200 con la política vigente del evento
+type PoliticaReventaResponse struct {
+	IdEvento                int64   `json:"idEvento"`
+	Habilitada              bool    `json:"habilitada"`
+	PrecioMaximoPorcentaje  float64 `json:"precioMaximoPorcentaje"`
+	RegaliaPorcentaje       float64 `json:"regaliaPorcentaje"`
+	FeePlataformaPorcentaje float64 `json:"feePlataformaPorcentaje"` // comisión de Nexivent, fija
+}
+
+// Request del titular para publicar su ticket:
+// { "precio": "" }
+type PublicarReventaRequest struct {
+	Precio float64 `json:"precio"`
+}
+
+// Publicación de reventa. El reparto (fee, regalía y monto del vendedor) solo se muestra al vendedor.
+type PublicacionReventa struct {
+	IdPublicacion      int64    `json:"idPublicacion"`
+	IdTicket           int64    `json:"idTicket"`
+	IdEvento           int64    `json:"idEvento"`
+	IdFechaEvento      int64    `json:"idFechaEvento"`
+	FechaEvento        string   `json:"fechaEvento,omitempty"` // YYYY-MM-DD
+	IdSector           int64    `json:"idSector"`
+	Sector             string   `json:"sector"`
+	Precio             float64  `json:"precio"`
+	PrecioOriginal     float64  `json:"precioOriginal"`
+	Estado             string   `json:"estado"` // "PUBLICADA" | "RESERVADA" | "VENDIDA" | "CANCELADA"
+	MontoFeePlataforma *float64 `json:"montoFeePlataforma,omitempty"`
+	MontoRegalia       *float64 `json:"montoRegalia,omitempty"`
+	MontoVendedor      *float64 `json:"montoVendedor,omitempty"`
+	FechaCreacion      string   `json:"fechaCreacion"` // RFC3339
+	FechaVenta         string   `json:"fechaVenta,omitempty"`
+}
+
+// Response 200 con un listado de publicaciones
+type PublicacionesReventaResponse struct {
+	Publicaciones []PublicacionReventa `json:"publicaciones"`
+}
+
+// Response 200 de GET /api/eventos/{id}/reventa/resumen
+type ResumenReventaResponse struct {
+	IdEvento           int64   `json:"idEvento"`
+	Publicadas         int64   `json:"publicadas"`
+	Vendidas           int64   `json:"vendidas"`
+	MontoTotal         float64 `json:"montoTotal"`
+	MontoFeePlataforma float64 `json:"montoFeePlataforma"`
+	MontoRegalia       float64 `json:"montoRegalia"`
+	MontoVendedores    float64 `json:"montoVendedores"`
+}
@@ -21,6 +21,104 @@ type TicketIssueResponse struct {
 	Tickets []TicketEmitido `json:"tickets"`
 }
 
+// Clave pública con la que se valida un QR, en base64url
+type ClaveQR struct {
+	KID          string `json:"kid"`
+	ClavePublica string `json:"clavePublica"`
+}
+
+// Response con las claves que el escáner necesita para validar QR sin conexión.
+// Incluye la clave vigente y las anteriores que todavía firman tickets válidos.
+type ClavesQRResponse struct {
+	Algoritmo string    `json:"algoritmo"` // "Ed25519"
+	KIDActual string    `json:"kidActual"`
+	Claves    []ClaveQR `json:"claves"`
+}
+
+// Request del escáner de puerta:
+// { "codigoQR": "", "idEvento": "", "idFechaEvento": "", "puerta": "" }
+//
+// idFechaEvento es opcional; si se envía, el ticket debe ser de esa fecha.
+type CheckInRequest struct {
+	CodigoQR      string `json:"codigoQR"`
+	IdEvento      int64  `json:"idEvento"`
+	IdFechaEvento int64  `json:"idFechaEvento,omitempty"`
+	Puerta        string `json:"puerta"`
+}
+
+// Response 200 cuando el ticket es admitido
+type CheckInResponse struct {
+	IdTicket      int64  `json:"idTicket"`
+	Estado        string `json:"estado"` // "USADO"
+	IdEvento      int64  `json:"idEvento"`
+	IdFechaEvento int64  `json:"idFechaEvento"`
+	Sector        string `json:"sector"`
+	Puerta        string `json:"puerta"`
+	IdOperador    int64  `json:"idOperador"`
+	FechaIngreso  string `json:"fechaIngreso"` // RFC3339
+}
+
+// Ticket dentro del manifiesto offline. El escáner calcula el SHA-256 (hex) del QR leído y lo
+// busca por hashQR; la firma del QR la valida con las claves del manifiesto.
+type TicketManifiesto struct {
+	HashQR   string `json:"hashQR"`
+	IdTicket int64  `json:"idTicket"`
+	Estado   string `json:"estado"` // "VENDIDO" | "USADO" | "CANCELADO"
+	Sector   string `json:"sector"`
+}
+
+// Response 200 de GET /api/tickets/checkin/manifiesto/{idFechaEvento}
+type ManifiestoIngresoResponse struct {
+	IdEvento       int64              `json:"idEvento"`
+	IdFechaEvento  int64              `json:"idFechaEvento"`
+	GeneradoEn     string             `json:"generadoEn"`     // RFC3339
+	PuertasAbren   string             `json:"puertasAbren"`   // RFC3339
+	PuertasCierran string             `json:"puertasCierran"` // RFC3339
+	Claves         ClavesQRResponse   `json:"claves"`
+	Tickets        []TicketManifiesto `json:"tickets"`
+}
+
+// Escaneo hecho sin conexión, con la hora del dispositivo
+type EscaneoOffline struct {
+	CodigoQR    string `json:"codigoQR"`
+	Puerta      string `json:"puerta"`
+	EscaneadoEn string `json:"escaneadoEn"` // RFC3339
+}
+
+// Request:
+// { "idFechaEvento": "", "dispositivo": "", "escaneos": [ { "codigoQR": "", "puerta": "", "escaneadoEn": "" } ] }
+type SincronizarIngresosRequest struct {
+	IdFechaEvento int64            `json:"idFechaEvento"`
+	Dispositivo   string           `json:"dispositivo"`
+	Escaneos      []EscaneoOffline `json:"escaneos"`
+}
+
+// Check-in que quedó vigente para un ticket
+type IngresoVigente struct {
+	Puerta       string  `json:"puerta"`
+	Dispositivo  *string `json:"dispositivo,omitempty"` // nil si fue en línea
+	FechaIngreso string  `json:"fechaIngreso"`          // RFC3339
+}
+
+// Veredicto de un escaneo. Indice es su posición en el request.
+type VeredictoEscaneo struct {
+	Indice         int             `json:"indice"`
+	IdTicket       int64           `json:"idTicket,omitempty"`
+	Veredicto      string          `json:"veredicto"`        // "ADMITIDO" | "DUPLICADO" | "RECHAZADO"
+	Motivo         string          `json:"motivo,omitempty"` // código de error cuando no es ADMITIDO
+	IngresoVigente *IngresoVigente `json:"ingresoVigente,omitempty"`
+}
+
+// Response 200 con un veredicto por escaneo, en el mismo orden del request
+type SincronizarIngresosResponse struct {
+	IdFechaEvento int64              `json:"idFechaEvento"`
+	Dispositivo   string             `json:"dispositivo"`
+	Admitidos     int                `json:"admitidos"`
+	Duplicados    int                `json:"duplicados"`
+	Rechazados    int                `json:"rechazados"`
+	Veredictos    []VeredictoEscaneo `json:"veredictos"`
+}
+
 // Request para cancelar tickets
 type TicketCancelRequest struct {
 	IdTickets []int64 `json:"idTickets"`
@@ -37,6 +135,7 @@ type TicketCancelResponse struct {
 	Cancelados    []TicketCancelado `json:"cancelados"`
 	NoEncontrados []int64           `json:"noEncontrados,omitempty"`
 	NoCancelables []int64           `json:"noCancelables,omitempty"`
+	Reembolsos    []Reembolso       `json:"reembolsos"` // uno por orden
 	Mensaje       string            `json:"mensaje"`
 }
 
@@ -84,4 +183,49 @@ type EventoMini struct {
 	Titulo        string `json:"titulo"`
 	Lugar         string `json:"lugar"`
 	ImagenPortada string `json:"imagenPortada"`
-}
\ No newline at end of file
+}
+
+// Request para ofrecer un ticket a otra persona:
+// { "paraCorreo": "" }
+type OfrecerTransferenciaRequest struct {
+	ParaCorreo string `json:"paraCorreo"`
+}
+
+// Oferta de transferencia de un ticket
+type TransferenciaTicket struct {
+	IdTransferencia int64  `json:"idTransferencia"`
+	IdTicket        int64  `json:"idTicket"`
+	IdEvento        int64  `json:"idEvento,omitempty"`
+	Evento          string `json:"evento,omitempty"`
+	FechaEvento     string `json:"fechaEvento,omitempty"` // YYYY-MM-DD
+	IdDeUsuario     int64  `json:"idDeUsuario"`
+	DeNombre        string `json:"deNombre,omitempty"`
+	ParaCorreo      string `json:"paraCorreo"`
+	Estado          string `json:"estado"`          // "PENDIENTE" | "ACEPTADA" | "CANCELADA" | "EXPIRADA"
+	Enviada         bool   `json:"enviada"`         // true si la ofreció el usuario que consulta
+	FechaCreacion   string `json:"fechaCreacion"`   // RFC3339
+	FechaExpiracion string `json:"fechaExpiracion"` // RFC3339
+	FechaResolucion string `json:"fechaResolucion,omitempty"`
+}
+
+// Response 200 de GET /member/transferencias
+type TransferenciasResponse struct {
+	Transferencias []TransferenciaTicket `json:"transferencias"`
+}
+
+// Titular que tuvo un ticket
+type TitularTicket struct {
+	IdUsuario       int64  `json:"idUsuario"`
+	Nombre          string `json:"nombre"`
+	Motivo          string `json:"motivo"` // "EMISION" | "TRANSFERENCIA"
+	IdTransferencia *int64 `json:"idTransferencia,omitempty"`
+	Desde           string `json:"desde"`           // RFC3339
+	Hasta           string `json:"hasta,omitempty"` // vacío para el titular vigente
+}
+
+// Response 200 de GET /member/tickets/{id}/historial
+type HistorialTitularesResponse struct {
+	IdTicket  int64           `json:"idTicket"`
+	IdTitular int64           `json:"idTitular"`
+	Titulares []TitularTicket `json:"titulares"`
+}
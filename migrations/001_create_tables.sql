@@ -1,11 +1,27 @@
 -- =========================================================
 -- RESET: DROP tables (hijas/asociativas primero) y tipos
 -- =========================================================
+DROP TABLE IF EXISTS clave_idempotencia;
+DROP TABLE IF EXISTS token;
 DROP TABLE IF EXISTS rol_usuario;
 DROP TABLE IF EXISTS usuario_cupon;
 DROP TABLE IF EXISTS evento_cupon;
+DROP TABLE IF EXISTS publicacion_reventa;
+DROP TABLE IF EXISTS politica_reventa;
+DROP TABLE IF EXISTS historial_titular;
+DROP TABLE IF EXISTS transferencia_ticket;
+DROP TABLE IF EXISTS registro_ingreso;
+DROP TABLE IF EXISTS reprogramacion_ticket;
+DROP TABLE IF EXISTS reprogramacion_fecha;
+DROP TABLE IF EXISTS reembolso_ticket;
+DROP TABLE IF EXISTS reembolso;
 DROP TABLE IF EXISTS ticket;
+DROP TABLE IF EXISTS orden_de_compra_detalle;
+DROP TABLE IF EXISTS pago;
 DROP TABLE IF EXISTS comprobante_de_pago;
+DROP TABLE IF EXISTS preferencia_notificacion;
+DROP TABLE IF EXISTS notificacion;
+DROP TABLE IF EXISTS evento_dominio;
 DROP TABLE IF EXISTS evento_fecha;
 DROP TABLE IF EXISTS fecha;
 DROP TABLE IF EXISTS tarifa;
@@ -16,19 +32,33 @@ DROP TABLE IF EXISTS comentario;
 DROP TABLE IF EXISTS orden_de_compra;
 DROP TABLE IF EXISTS metodo_de_pago;
 DROP TABLE IF EXISTS evento;
+DROP TABLE IF EXISTS recinto;
 DROP TABLE IF EXISTS cupon;
 DROP TABLE IF EXISTS rol;
-DROP TABLE IF EXISTS notificacion;
 DROP TABLE IF EXISTS categoria;
 DROP TABLE IF EXISTS usuario;
 DROP TYPE IF EXISTS tipo_metodo_pago_enum;
 DROP TYPE IF EXISTS tipo_documento_enum;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS es_unaccent;
+DROP FUNCTION IF EXISTS f_unaccent(text);
 -- =========================================================
 -- TIPOS
 -- =========================================================
 CREATE TYPE tipo_documento_enum AS ENUM ('DNI', 'CE', 'RUC');
 CREATE TYPE tipo_metodo_pago_enum AS ENUM ('Tarjeta', 'Yape');
 -- =========================================================
+-- BÚSQUEDA DE TEXTO (español, sin tildes, tolerante a errores de tipeo)
+-- =========================================================
+CREATE EXTENSION IF NOT EXISTS unaccent SCHEMA public;
+CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public;
+-- unaccent() no es IMMUTABLE y los índices por expresión lo exigen
+CREATE FUNCTION f_unaccent(text) RETURNS text
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+    AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, $1) $$;
+CREATE TEXT SEARCH CONFIGURATION es_unaccent (COPY = pg_catalog.spanish);
+ALTER TEXT SEARCH CONFIGURATION es_unaccent
+    ALTER MAPPING FOR hword, hword_part, word WITH public.unaccent, spanish_stem;
+-- =========================================================
 -- TABLAS BASE
 -- =========================================================
 CREATE TABLE usuario (
@@ -42,6 +72,10 @@ CREATE TABLE usuario (
     estado_de_cuenta SMALLINT NOT NULL DEFAULT 0,
     codigo_verificacion VARCHAR(10),
     fecha_expiracion_codigo TIMESTAMPTZ,
+    intentos_codigo SMALLINT NOT NULL DEFAULT 0,
+    envios_codigo SMALLINT NOT NULL DEFAULT 0,
+    fecha_ultimo_envio TIMESTAMPTZ,
+    fecha_recuperacion TIMESTAMPTZ,
     usuario_creacion BIGINT,
     fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     usuario_modificacion BIGINT,
@@ -60,14 +94,44 @@ CREATE TABLE categoria (
     estado SMALLINT NOT NULL DEFAULT 1,
     CONSTRAINT chk_categoria_estado CHECK (estado IN (0, 1))
 );
+CREATE TABLE recinto (
+    recinto_id BIGSERIAL PRIMARY KEY,
+    nombre VARCHAR(80) NOT NULL,
+    direccion VARCHAR(200) NOT NULL,
+    departamento VARCHAR(60) NOT NULL,
+    provincia VARCHAR(60) NOT NULL,
+    distrito VARCHAR(60) NOT NULL,
+    ubigeo CHAR(6) NOT NULL,
+    latitud DOUBLE PRECISION NOT NULL,
+    longitud DOUBLE PRECISION NOT NULL,
+    capacidad INT NOT NULL DEFAULT 0,
+    imagen_mapa_asientos TEXT,
+    estado SMALLINT NOT NULL DEFAULT 1,
+    usuario_creacion BIGINT,
+    fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    usuario_modificacion BIGINT,
+    fecha_modificacion TIMESTAMPTZ,
+    CONSTRAINT chk_recinto_ubigeo CHECK (ubigeo ~ '^[0-9]{6}$'),
+    CONSTRAINT chk_recinto_coordenadas CHECK (
+        latitud BETWEEN -90 AND 90
+        AND longitud BETWEEN -180 AND 180
+    ),
+    CONSTRAINT chk_recinto_capacidad CHECK (capacidad >= 0),
+    CONSTRAINT chk_recinto_estado CHECK (estado IN (0, 1))
+);
+CREATE INDEX idx_recinto_ubigeo ON recinto (ubigeo);
+-- la búsqueda por radio filtra primero por un recuadro de latitud y longitud
+CREATE INDEX idx_recinto_coordenadas ON recinto (latitud, longitud);
 CREATE TABLE evento (
     evento_id BIGSERIAL PRIMARY KEY,
     organizador_id BIGINT NOT NULL,
     categoria_id BIGINT NOT NULL,
+    recinto_id BIGINT,
     titulo VARCHAR(80) NOT NULL,
     descripcion TEXT NOT NULL,
     lugar VARCHAR(80) NOT NULL,
     evento_estado SMALLINT NOT NULL DEFAULT 0,
+    publish_at TIMESTAMPTZ,
     cant_me_gusta INT NOT NULL DEFAULT 0,
     cant_no_interesa INT NOT NULL DEFAULT 0,
     cant_vendido_total INT NOT NULL DEFAULT 0,
@@ -77,9 +141,16 @@ CREATE TABLE evento (
     fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     usuario_modificacion BIGINT,
     fecha_modificacion TIMESTAMPTZ,
+    -- se recalcula solo al crear o editar el evento
+    busqueda TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('es_unaccent', coalesce(titulo, '')), 'A')
+        || setweight(to_tsvector('es_unaccent', coalesce(lugar, '')), 'B')
+        || setweight(to_tsvector('es_unaccent', coalesce(descripcion, '')), 'C')
+    ) STORED,
     CONSTRAINT fk_evento_organizador FOREIGN KEY (organizador_id) REFERENCES usuario(usuario_id) ON DELETE RESTRICT,
     CONSTRAINT fk_evento_categoria FOREIGN KEY (categoria_id) REFERENCES categoria(id_categoria) ON DELETE RESTRICT,
-    CONSTRAINT chk_evento_estado CHECK (evento_estado IN (0, 1, 2)),
+    CONSTRAINT fk_evento_recinto FOREIGN KEY (recinto_id) REFERENCES recinto(recinto_id) ON DELETE RESTRICT,
+    CONSTRAINT chk_evento_estado CHECK (evento_estado IN (0, 1, 2, 3, 4)),
     CONSTRAINT chk_evento_estado_flag CHECK (estado IN (0, 1)),
     CONSTRAINT chk_evento_contadores_nn CHECK (
         cant_me_gusta >= 0
@@ -88,6 +159,10 @@ CREATE TABLE evento (
         AND total_recaudado >= 0
     )
 );
+CREATE INDEX idx_evento_publish_at ON evento (publish_at) WHERE evento_estado = 0;
+CREATE INDEX idx_evento_recinto ON evento (recinto_id);
+CREATE INDEX idx_evento_busqueda ON evento USING GIN (busqueda);
+CREATE INDEX idx_evento_titulo_trgm ON evento USING GIN (f_unaccent(lower(titulo)) gin_trgm_ops);
 CREATE TABLE comentario (
     comentario_id BIGSERIAL PRIMARY KEY,
     usuario_id BIGINT NOT NULL,
@@ -174,13 +249,15 @@ CREATE TABLE metodo_de_pago (
 CREATE TABLE orden_de_compra(
     orden_de_compra_id BIGSERIAL PRIMARY KEY,
     usuario_id BIGINT NOT NULL,
-    metodo_de_pago_id BIGINT NOT NULL,
+    metodo_de_pago_id BIGINT,
     fecha DATE NOT NULL DEFAULT CURRENT_DATE,
     fecha_hora_ini TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     fecha_hora_fin TIMESTAMPTZ,
     total NUMERIC(12, 2) NOT NULL,
     monto_fee_servicio NUMERIC(12, 2) NOT NULL,
     estado_de_orden SMALLINT NOT NULL DEFAULT 0,
+    cupon_id BIGINT,
+    fecha_emision_tickets TIMESTAMPTZ,
     CONSTRAINT fk_orden_de_compra_usuario FOREIGN KEY (usuario_id) REFERENCES usuario(usuario_id),
     CONSTRAINT fk_orden_de_compra_pago FOREIGN KEY (metodo_de_pago_id) REFERENCES metodo_de_pago(metodo_de_pago_id),
     CONSTRAINT chk_orden_de_compra_estado CHECK (estado_de_orden IN (0, 1, 2)),
@@ -215,16 +292,149 @@ CREATE TABLE evento_fecha (
 CREATE TABLE ticket (
     ticket_id BIGSERIAL PRIMARY KEY,
     orden_de_compra_id BIGINT,
+    titular_id BIGINT, -- NULL: el titular es el comprador de la orden
     evento_fecha_id BIGINT NOT NULL,
     tarifa_id BIGINT NOT NULL,
-    codigo_qr VARCHAR(50) NOT NULL,
+    codigo_qr VARCHAR(255) NOT NULL, -- token firmado (ver service/qr)
     estado_de_ticket SMALLINT NOT NULL DEFAULT 0,
     CONSTRAINT fk_ticket_orden FOREIGN KEY (orden_de_compra_id) REFERENCES orden_de_compra(orden_de_compra_id),
+    CONSTRAINT fk_ticket_titular FOREIGN KEY (titular_id) REFERENCES usuario(usuario_id),
     CONSTRAINT fk_ticket_fecha FOREIGN KEY (evento_fecha_id) REFERENCES evento_fecha(evento_fecha_id),
     CONSTRAINT fk_ticket_tarifa FOREIGN KEY (tarifa_id) REFERENCES tarifa(tarifa_id),
     CONSTRAINT chk_ticket_estado CHECK (estado_de_ticket IN (0, 1, 2, 3)),
     CONSTRAINT uq_ticket_qr UNIQUE (codigo_qr)
 );
+-- Check-in en puerta: a lo sumo uno por ticket (se escribe al pasar el ticket a USADO)
+CREATE TABLE registro_ingreso (
+    registro_ingreso_id BIGSERIAL PRIMARY KEY,
+    ticket_id BIGINT NOT NULL,
+    evento_fecha_id BIGINT NOT NULL,
+    puerta VARCHAR(60) NOT NULL,
+    operador_id BIGINT NOT NULL,
+    dispositivo VARCHAR(100),
+    fecha_ingreso TIMESTAMPTZ NOT NULL,
+    CONSTRAINT fk_registro_ingreso_ticket FOREIGN KEY (ticket_id) REFERENCES ticket(ticket_id),
+    CONSTRAINT fk_registro_ingreso_fecha FOREIGN KEY (evento_fecha_id) REFERENCES evento_fecha(evento_fecha_id),
+    CONSTRAINT fk_registro_ingreso_operador FOREIGN KEY (operador_id) REFERENCES usuario(usuario_id),
+    CONSTRAINT uq_registro_ingreso_ticket UNIQUE (ticket_id)
+);
+CREATE INDEX idx_registro_ingreso_fecha ON registro_ingreso (evento_fecha_id);
+CREATE INDEX idx_ticket_titular ON ticket (titular_id);
+-- Ofertas de transferencia de tickets entre usuarios (a lo sumo una PENDIENTE por ticket)
+CREATE TABLE transferencia_ticket (
+    transferencia_ticket_id BIGSERIAL PRIMARY KEY,
+    ticket_id BIGINT NOT NULL,
+    de_usuario_id BIGINT NOT NULL,
+    para_correo TEXT NOT NULL,
+    para_usuario_id BIGINT,
+    estado SMALLINT NOT NULL DEFAULT 0,
+    fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fecha_expiracion TIMESTAMPTZ NOT NULL,
+    fecha_resolucion TIMESTAMPTZ,
+    resuelta_por_id BIGINT,
+    CONSTRAINT fk_transferencia_ticket FOREIGN KEY (ticket_id) REFERENCES ticket(ticket_id),
+    CONSTRAINT fk_transferencia_de_usuario FOREIGN KEY (de_usuario_id) REFERENCES usuario(usuario_id),
+    CONSTRAINT fk_transferencia_para_usuario FOREIGN KEY (para_usuario_id) REFERENCES usuario(usuario_id),
+    CONSTRAINT chk_transferencia_estado CHECK (estado IN (0, 1, 2, 3))
+);
+CREATE INDEX idx_transferencia_ticket_ticket ON transferencia_ticket (ticket_id);
+CREATE INDEX idx_transferencia_ticket_de_usuario ON transferencia_ticket (de_usuario_id);
+CREATE INDEX idx_transferencia_ticket_para_correo ON transferencia_ticket (lower(para_correo));
+CREATE UNIQUE INDEX idx_transferencia_pendiente ON transferencia_ticket (ticket_id) WHERE estado = 0;
+-- Titulares de cada ticket a lo largo del tiempo (hasta NULL = titular actual)
+CREATE TABLE historial_titular (
+    historial_titular_id BIGSERIAL PRIMARY KEY,
+    ticket_id BIGINT NOT NULL,
+    usuario_id BIGINT NOT NULL,
+    motivo VARCHAR(20) NOT NULL,
+    transferencia_id BIGINT,
+    desde TIMESTAMPTZ NOT NULL,
+    hasta TIMESTAMPTZ,
+    CONSTRAINT fk_historial_titular_ticket FOREIGN KEY (ticket_id) REFERENCES ticket(ticket_id),
+    CONSTRAINT fk_historial_titular_usuario FOREIGN KEY (usuario_id) REFERENCES usuario(usuario_id),
+    CONSTRAINT fk_historial_titular_transferencia FOREIGN KEY (transferencia_id) REFERENCES transferencia_ticket(transferencia_ticket_id)
+);
+CREATE INDEX idx_historial_titular_ticket ON historial_titular (ticket_id);
+CREATE INDEX idx_historial_titular_usuario ON historial_titular (usuario_id);
+-- Reventa: lo que permite el organizador por evento y los tickets publicados por sus titulares
+CREATE TABLE politica_reventa (
+    evento_id BIGINT PRIMARY KEY,
+    habilitada BOOLEAN NOT NULL DEFAULT FALSE,
+    precio_maximo_porcentaje NUMERIC(6,2) NOT NULL DEFAULT 100,
+    regalia_porcentaje NUMERIC(5,2) NOT NULL DEFAULT 0,
+    usuario_modificacion BIGINT,
+    fecha_modificacion TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_politica_reventa_evento FOREIGN KEY (evento_id) REFERENCES evento(evento_id),
+    CONSTRAINT chk_politica_reventa_tope CHECK (precio_maximo_porcentaje > 0),
+    CONSTRAINT chk_politica_reventa_regalia CHECK (regalia_porcentaje >= 0 AND regalia_porcentaje < 100)
+);
+CREATE TABLE publicacion_reventa (
+    publicacion_reventa_id BIGSERIAL PRIMARY KEY,
+    ticket_id BIGINT NOT NULL,
+    evento_id BIGINT NOT NULL,
+    vendedor_id BIGINT NOT NULL,
+    precio NUMERIC(10,2) NOT NULL,
+    precio_original NUMERIC(10,2) NOT NULL,
+    monto_fee_plataforma NUMERIC(10,2) NOT NULL,
+    monto_regalia NUMERIC(10,2) NOT NULL,
+    monto_vendedor NUMERIC(10,2) NOT NULL,
+    estado SMALLINT NOT NULL DEFAULT 0,
+    orden_de_compra_id BIGINT,
+    comprador_id BIGINT,
+    fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fecha_venta TIMESTAMPTZ,
+    CONSTRAINT fk_publicacion_reventa_ticket FOREIGN KEY (ticket_id) REFERENCES ticket(ticket_id),
+    CONSTRAINT fk_publicacion_reventa_evento FOREIGN KEY (evento_id) REFERENCES evento(evento_id),
+    CONSTRAINT fk_publicacion_reventa_vendedor FOREIGN KEY (vendedor_id) REFERENCES usuario(usuario_id),
+    CONSTRAINT fk_publicacion_reventa_comprador FOREIGN KEY (comprador_id) REFERENCES usuario(usuario_id),
+    CONSTRAINT fk_publicacion_reventa_orden FOREIGN KEY (orden_de_compra_id) REFERENCES orden_de_compra(orden_de_compra_id),
+    CONSTRAINT chk_publicacion_reventa_estado CHECK (estado IN (0, 1, 2, 3)),
+    CONSTRAINT chk_publicacion_reventa_precio CHECK (precio > 0)
+);
+CREATE INDEX idx_publicacion_reventa_ticket ON publicacion_reventa (ticket_id);
+CREATE INDEX idx_publicacion_reventa_evento ON publicacion_reventa (evento_id);
+CREATE INDEX idx_publicacion_reventa_vendedor ON publicacion_reventa (vendedor_id);
+CREATE INDEX idx_publicacion_reventa_orden ON publicacion_reventa (orden_de_compra_id);
+CREATE UNIQUE INDEX idx_reventa_activa ON publicacion_reventa (ticket_id) WHERE estado IN (0, 1);
+-- Líneas de la orden: se escriben al crear el hold y de ellas salen los tickets y los reportes
+CREATE TABLE orden_de_compra_detalle (
+    orden_de_compra_detalle_id BIGSERIAL PRIMARY KEY,
+    orden_de_compra_id BIGINT NOT NULL,
+    tarifa_id BIGINT NOT NULL,
+    sector_id BIGINT NOT NULL,
+    evento_fecha_id BIGINT NOT NULL,
+    perfil_de_persona_id BIGINT,
+    cantidad BIGINT NOT NULL,
+    precio_unitario NUMERIC(10, 2) NOT NULL,
+    descuento NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    CONSTRAINT fk_orden_detalle_orden FOREIGN KEY (orden_de_compra_id) REFERENCES orden_de_compra(orden_de_compra_id) ON DELETE CASCADE,
+    CONSTRAINT fk_orden_detalle_tarifa FOREIGN KEY (tarifa_id) REFERENCES tarifa(tarifa_id),
+    CONSTRAINT fk_orden_detalle_sector FOREIGN KEY (sector_id) REFERENCES sector(sector_id),
+    CONSTRAINT fk_orden_detalle_fecha FOREIGN KEY (evento_fecha_id) REFERENCES evento_fecha(evento_fecha_id),
+    CONSTRAINT fk_orden_detalle_perfil FOREIGN KEY (perfil_de_persona_id) REFERENCES perfil_de_persona(perfil_de_persona_id),
+    CONSTRAINT chk_orden_detalle_cantidad CHECK (cantidad > 0),
+    CONSTRAINT chk_orden_detalle_montos CHECK (precio_unitario >= 0 AND descuento >= 0)
+);
+CREATE INDEX idx_orden_detalle_orden ON orden_de_compra_detalle (orden_de_compra_id);
+CREATE INDEX idx_orden_detalle_sector ON orden_de_compra_detalle (sector_id);
+CREATE INDEX idx_orden_detalle_fecha ON orden_de_compra_detalle (evento_fecha_id);
+-- Cobros en la pasarela: referencia es el id del intento que llega en la confirmación y en los webhooks
+CREATE TABLE pago (
+    pago_id BIGSERIAL PRIMARY KEY,
+    orden_de_compra_id BIGINT NOT NULL,
+    metodo_pago VARCHAR(20) NOT NULL,
+    referencia VARCHAR(120) NOT NULL,
+    monto NUMERIC(12, 2) NOT NULL,
+    moneda VARCHAR(3) NOT NULL DEFAULT 'PEN',
+    estado_de_pago SMALLINT NOT NULL DEFAULT 0,
+    fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fecha_modificacion TIMESTAMPTZ,
+    CONSTRAINT fk_pago_orden FOREIGN KEY (orden_de_compra_id) REFERENCES orden_de_compra(orden_de_compra_id),
+    CONSTRAINT uq_pago_referencia UNIQUE (referencia),
+    CONSTRAINT chk_pago_estado CHECK (estado_de_pago IN (0, 1, 2, 3)),
+    CONSTRAINT chk_pago_monto CHECK (monto >= 0)
+);
+CREATE INDEX idx_pago_orden ON pago (orden_de_compra_id);
 CREATE TABLE cupon (
     cupon_id BIGSERIAL PRIMARY KEY,
     descripcion TEXT NOT NULL,
@@ -246,6 +456,9 @@ CREATE TABLE cupon (
         AND uso_realizados >= 0
     )
 );
+-- cupon se crea después de orden_de_compra
+ALTER TABLE orden_de_compra
+ADD CONSTRAINT fk_orden_de_compra_cupon FOREIGN KEY (cupon_id) REFERENCES cupon(cupon_id);
 CREATE TABLE evento_cupon (
     evento_id BIGINT NOT NULL,
     cupon_id BIGINT NOT NULL,
@@ -275,14 +488,82 @@ CREATE TABLE comprobante_de_pago (
     comprobante_de_pago_id BIGSERIAL PRIMARY KEY,
     orden_de_compra_id BIGINT NOT NULL,
     tipo_de_comprobante SMALLINT NOT NULL DEFAULT 0,
-    -- 0=boleta,1=factura (ej.)
+    -- 0=boleta,1=factura,2=nota de crédito
     numero VARCHAR(20) NOT NULL,
     fecha_emision TIMESTAMPTZ NOT NULL,
     ruc VARCHAR(20),
     direccion_fiscal VARCHAR(80),
+    -- solo en notas de crédito
+    comprobante_referencia_id BIGINT,
+    monto NUMERIC(12, 2),
     CONSTRAINT fk_comprobante_de_pago_orden FOREIGN KEY (orden_de_compra_id) REFERENCES orden_de_compra(orden_de_compra_id) ON DELETE RESTRICT,
-    CONSTRAINT chk_comprobante_de_pago_tipo CHECK (tipo_de_comprobante IN (0, 1))
+    CONSTRAINT fk_comprobante_de_pago_referencia FOREIGN KEY (comprobante_referencia_id) REFERENCES comprobante_de_pago(comprobante_de_pago_id),
+    CONSTRAINT chk_comprobante_de_pago_tipo CHECK (tipo_de_comprobante IN (0, 1, 2))
+);
+CREATE TABLE reembolso (
+    reembolso_id BIGSERIAL PRIMARY KEY,
+    orden_de_compra_id BIGINT NOT NULL,
+    pago_id BIGINT NOT NULL,
+    motivo VARCHAR(40) NOT NULL,
+    monto NUMERIC(12, 2) NOT NULL,
+    monto_fee_servicio NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    monto_organizador NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    estado SMALLINT NOT NULL DEFAULT 0,
+    -- 0=PENDIENTE,1=COMPLETADO,2=FALLIDO
+    referencia VARCHAR(120),
+    ultimo_error TEXT,
+    nota_credito_id BIGINT,
+    usuario_creacion BIGINT,
+    fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fecha_completado TIMESTAMPTZ,
+    CONSTRAINT fk_reembolso_orden FOREIGN KEY (orden_de_compra_id) REFERENCES orden_de_compra(orden_de_compra_id),
+    CONSTRAINT fk_reembolso_pago FOREIGN KEY (pago_id) REFERENCES pago(pago_id),
+    CONSTRAINT fk_reembolso_nota_credito FOREIGN KEY (nota_credito_id) REFERENCES comprobante_de_pago(comprobante_de_pago_id),
+    CONSTRAINT chk_reembolso_estado CHECK (estado IN (0, 1, 2)),
+    CONSTRAINT chk_reembolso_monto CHECK (monto > 0)
+);
+CREATE INDEX idx_reembolso_orden ON reembolso (orden_de_compra_id);
+CREATE INDEX idx_reembolso_pago ON reembolso (pago_id);
+CREATE TABLE reembolso_ticket (
+    reembolso_id BIGINT NOT NULL,
+    ticket_id BIGINT NOT NULL,
+    monto NUMERIC(12, 2) NOT NULL,
+    monto_fee_servicio NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (reembolso_id, ticket_id),
+    CONSTRAINT fk_reembolso_ticket_reembolso FOREIGN KEY (reembolso_id) REFERENCES reembolso(reembolso_id) ON DELETE CASCADE,
+    CONSTRAINT fk_reembolso_ticket_ticket FOREIGN KEY (ticket_id) REFERENCES ticket(ticket_id),
+    CONSTRAINT uq_reembolso_ticket_ticket UNIQUE (ticket_id)
 );
+CREATE TABLE reprogramacion_fecha (
+    reprogramacion_fecha_id BIGSERIAL PRIMARY KEY,
+    evento_id BIGINT NOT NULL,
+    evento_fecha_origen_id BIGINT NOT NULL,
+    evento_fecha_destino_id BIGINT NOT NULL,
+    fecha_limite TIMESTAMPTZ NOT NULL,
+    tickets_migrados BIGINT NOT NULL DEFAULT 0,
+    usuario_creacion BIGINT,
+    fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fecha_cierre TIMESTAMPTZ,
+    CONSTRAINT fk_reprogramacion_fecha_evento FOREIGN KEY (evento_id) REFERENCES evento(evento_id),
+    CONSTRAINT fk_reprogramacion_fecha_origen FOREIGN KEY (evento_fecha_origen_id) REFERENCES evento_fecha(evento_fecha_id),
+    CONSTRAINT fk_reprogramacion_fecha_destino FOREIGN KEY (evento_fecha_destino_id) REFERENCES evento_fecha(evento_fecha_id)
+);
+CREATE INDEX idx_reprogramacion_fecha_evento ON reprogramacion_fecha (evento_id);
+CREATE INDEX idx_reprogramacion_fecha_abiertas ON reprogramacion_fecha (fecha_limite) WHERE fecha_cierre IS NULL;
+CREATE TABLE reprogramacion_ticket (
+    reprogramacion_fecha_id BIGINT NOT NULL,
+    ticket_id BIGINT NOT NULL,
+    estado SMALLINT NOT NULL DEFAULT 0,
+    -- 0=PENDIENTE,1=ACEPTADA,2=REEMBOLSADA,3=VENCIDA
+    reembolso_id BIGINT,
+    fecha_respuesta TIMESTAMPTZ,
+    PRIMARY KEY (reprogramacion_fecha_id, ticket_id),
+    CONSTRAINT fk_reprogramacion_ticket_reprogramacion FOREIGN KEY (reprogramacion_fecha_id) REFERENCES reprogramacion_fecha(reprogramacion_fecha_id) ON DELETE CASCADE,
+    CONSTRAINT fk_reprogramacion_ticket_ticket FOREIGN KEY (ticket_id) REFERENCES ticket(ticket_id),
+    CONSTRAINT fk_reprogramacion_ticket_reembolso FOREIGN KEY (reembolso_id) REFERENCES reembolso(reembolso_id),
+    CONSTRAINT chk_reprogramacion_ticket_estado CHECK (estado IN (0, 1, 2, 3))
+);
+CREATE UNIQUE INDEX uq_reprogramacion_ticket_pendiente ON reprogramacion_ticket (ticket_id) WHERE estado = 0;
 CREATE TABLE rol (
     rol_id BIGSERIAL PRIMARY KEY,
     nombre VARCHAR(20) NOT NULL,
@@ -307,11 +588,85 @@ CREATE TABLE rol_usuario (
     CONSTRAINT uq_rol_usuario UNIQUE (usuario_id, rol_id),
     CONSTRAINT chk_rol_usuario_estado CHECK (estado IN (0, 1))
 );
+-- Outbox transaccional: eventos de dominio registrados en la misma transacción que el cambio
+CREATE TABLE evento_dominio (
+    evento_dominio_id BIGSERIAL PRIMARY KEY,
+    tipo VARCHAR(40) NOT NULL,
+    agregado_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    estado SMALLINT NOT NULL DEFAULT 0,
+    intentos SMALLINT NOT NULL DEFAULT 0,
+    proximo_intento TIMESTAMPTZ,
+    ultimo_error TEXT,
+    fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT now(),
+    fecha_procesado TIMESTAMPTZ,
+    CONSTRAINT chk_evento_dominio_estado CHECK (estado IN (0, 1, 2))
+);
+CREATE INDEX idx_evento_dominio_pendiente ON evento_dominio(proximo_intento) WHERE estado = 0;
 CREATE TABLE notificacion (
     notificacion_id BIGSERIAL PRIMARY KEY,
     mensaje TEXT NOT NULL,
+    asunto TEXT NOT NULL DEFAULT '',
     canal VARCHAR(40) NOT NULL,
+    destino TEXT NOT NULL DEFAULT '',
     fecha_envio TIMESTAMPTZ NOT NULL,
     estado_notificacion SMALLINT NOT NULL,
+    plantilla VARCHAR(80),
+    tipo VARCHAR(40),
+    leida BOOLEAN NOT NULL DEFAULT FALSE,
+    fecha_lectura TIMESTAMPTZ,
+    evento_dominio_id BIGINT,
+    usuario_id BIGINT,
+    orden_de_compra_id BIGINT,
+    intentos SMALLINT NOT NULL DEFAULT 0,
+    proximo_intento TIMESTAMPTZ,
+    ultimo_error TEXT,
+    fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT fk_notificacion_evento_dominio FOREIGN KEY (evento_dominio_id) REFERENCES evento_dominio(evento_dominio_id),
+    CONSTRAINT fk_notificacion_usuario FOREIGN KEY (usuario_id) REFERENCES usuario(usuario_id),
+    CONSTRAINT fk_notificacion_orden FOREIGN KEY (orden_de_compra_id) REFERENCES orden_de_compra(orden_de_compra_id),
     CONSTRAINT chk_notificacion CHECK (estado_notificacion IN (0, 1, 2))
-);
\ No newline at end of file
+);
+-- Outbox: el worker busca las pendientes cuyo próximo intento ya venció
+CREATE INDEX idx_notificacion_pendiente ON notificacion(proximo_intento) WHERE estado_notificacion = 2;
+CREATE INDEX idx_notificacion_orden ON notificacion(orden_de_compra_id);
+CREATE INDEX idx_notificacion_evento_dominio ON notificacion(evento_dominio_id);
+CREATE INDEX idx_notificacion_usuario ON notificacion(usuario_id);
+-- Bandeja in-app: se pagina por notificacion_id descendente y se cuentan las no leídas
+CREATE INDEX idx_notificacion_bandeja ON notificacion(usuario_id, notificacion_id DESC)
+    WHERE canal = 'IN_APP' AND estado_notificacion = 0;
+-- Preferencias de notificación por tipo y canal; sin fila, el canal está habilitado
+CREATE TABLE preferencia_notificacion (
+    usuario_id BIGINT NOT NULL,
+    tipo VARCHAR(40) NOT NULL,
+    canal VARCHAR(40) NOT NULL,
+    habilitado BOOLEAN NOT NULL DEFAULT TRUE,
+    fecha_modificacion TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (usuario_id, tipo, canal),
+    CONSTRAINT fk_preferencia_notificacion_usuario FOREIGN KEY (usuario_id) REFERENCES usuario(usuario_id)
+);
+-- Tokens de sesión: solo se guarda el SHA-256 del token entregado al cliente
+CREATE TABLE token (
+    hash BYTEA PRIMARY KEY,
+    usuario_id BIGINT NOT NULL,
+    expiry TIMESTAMPTZ NOT NULL,
+    scope TEXT NOT NULL,
+    CONSTRAINT fk_token_usuario FOREIGN KEY (usuario_id) REFERENCES usuario(usuario_id) ON DELETE CASCADE
+);
+CREATE INDEX idx_token_usuario_scope ON token (usuario_id, scope);
+-- Idempotency-Key: primera respuesta por (usuario, clave) para repetirla en los reintentos
+CREATE TABLE clave_idempotencia (
+    usuario_id BIGINT NOT NULL,
+    clave VARCHAR(255) NOT NULL,
+    ruta TEXT NOT NULL,
+    hash_peticion BYTEA NOT NULL,
+    codigo_respuesta INTEGER,
+    tipo_contenido TEXT,
+    cuerpo_respuesta BYTEA,
+    fecha_creacion TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fecha_bloqueo TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fecha_expiracion TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (usuario_id, clave),
+    CONSTRAINT fk_clave_idempotencia_usuario FOREIGN KEY (usuario_id) REFERENCES usuario(usuario_id) ON DELETE CASCADE
+);
+CREATE INDEX idx_clave_idempotencia_expiracion ON clave_idempotencia (fecha_expiracion);
@@ -6,6 +6,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/Nexivent/nexivent-backend/internal/application/service/qr"
 	config "github.com/Nexivent/nexivent-backend/internal/config"
 	"github.com/Nexivent/nexivent-backend/internal/dao/model"
 	util "github.com/Nexivent/nexivent-backend/internal/dao/model/util"
@@ -35,7 +36,17 @@ func main() {
 	// 3. SIEMPRE ejecutar seeds independientemente de si es local o no
 	logger.Info("🌱 Iniciando proceso de seeds...")
 
-	if err := seedDatabase(logger, nexiventPsqlDB, entidad); err != nil {
+	// Los QR de los tickets semilla se firman con la misma clave que usa el backend
+	firmanteQR, _, err := qr.CargarClaves(envSettings.QRSigningKeyID, envSettings.QRSigningKey, envSettings.QRVerificationKeys)
+	if err == qr.ErrSinClaveFirma {
+		logger.Warn("QR_SIGNING_KEY no configurada: los QR semilla se firman con una clave temporal")
+		firmanteQR, err = qr.GenerarFirmante(envSettings.QRSigningKeyID)
+	}
+	if err != nil {
+		log.Fatalf("❌ Error cargando la clave de firma de QR: %v", err)
+	}
+
+	if err := seedDatabase(logger, nexiventPsqlDB, entidad, firmanteQR); err != nil {
 		log.Fatalf("❌ Error al ejecutar seeds: %v", err)
 	}
 
@@ -46,6 +57,7 @@ func seedDatabase(
 	logger logging.Logger,
 	db *gorm.DB,
 	entidad *repository.NexiventPsqlEntidades,
+	firmanteQR *qr.Firmante,
 ) error {
 	//var eventosExistentes int64
 	//if err := db.Model(&model.Evento{}).Count(&eventosExistentes).Error; err != nil {
@@ -78,7 +90,7 @@ func seedDatabase(
 	if err != nil {
 		return err
 	}
-	if err := seedTicketsComprados(logger, db, entidad, eventos, usuarios); err != nil {
+	if err := seedTicketsComprados(logger, db, entidad, eventos, usuarios, firmanteQR); err != nil {
 		return err
 	}
 
@@ -709,6 +721,7 @@ func seedTicketsComprados(
 	entidad *repository.NexiventPsqlEntidades,
 	eventos []model.Evento,
 	usuarios []model.Usuario,
+	firmanteQR *qr.Firmante,
 ) error {
 	if len(usuarios) == 0 {
 		return fmt.Errorf("no hay usuarios para asignar compras seed")
@@ -768,32 +781,53 @@ func seedTicketsComprados(
 			total += tf.Precio
 		}
 
+		// Una línea por tarifa comprada: los reportes y la emisión leen del detalle
+		var detalles []model.OrdenDeCompraDetalle
+		for _, tf := range seleccion {
+			detalles = append(detalles, model.OrdenDeCompraDetalle{
+				TarifaID:          tf.ID,
+				SectorID:          tf.SectorID,
+				EventoFechaID:     eventoFecha.ID,
+				PerfilDePersonaID: tf.PerfilDePersonaID,
+				Cantidad:          1,
+				PrecioUnitario:    tf.Precio,
+			})
+		}
+
 		orden := model.OrdenDeCompra{
 			UsuarioID:        comprador.ID,
-			MetodoDePagoID:   metodoPago.ID,
+			MetodoDePagoID:   &metodoPago.ID,
 			Fecha:            horaCompra,
 			FechaHoraIni:     horaCompra,
 			Total:            math.Round(total*100) / 100,
 			MontoFeeServicio: math.Round(total*0.05*100) / 100,
 			EstadoDeOrden:    util.OrdenConfirmada.Codigo(),
+			Detalles:         detalles,
 		}
 		if err := db.Create(&orden).Error; err != nil {
 			return fmt.Errorf("no se pudo crear orden seed para %s: %w", ev.Titulo, err)
 		}
 
 		var tickets []model.Ticket
-		for idxTf, tf := range seleccion {
-			qr := fmt.Sprintf("SEED-%d-%d-%d", ev.ID, orden.ID, idxTf+1)
+		for _, tf := range seleccion {
 			tickets = append(tickets, model.Ticket{
 				OrdenDeCompraID: &orden.ID,
+				TitularID:       &comprador.ID,
 				EventoFechaID:   eventoFecha.ID,
 				TarifaID:        tf.ID,
-				CodigoQR:        qr,
 				EstadoDeTicket:  util.TicketVendido.Codigo(),
 			})
 		}
 
-		if err := entidad.Ticket.CrearTicketsBatch(tickets); err != nil {
+		firmar := func(ticket *model.Ticket) (string, error) {
+			return firmanteQR.Firmar(qr.Contenido{
+				TicketID:      ticket.ID,
+				EventoFechaID: ticket.EventoFechaID,
+				TarifaID:      ticket.TarifaID,
+				EmitidoEn:     horaCompra,
+			})
+		}
+		if err := entidad.Ticket.CrearTicketsFirmados(tickets, firmar); err != nil {
 			return fmt.Errorf("no se pudieron crear tickets seed para %s: %w", ev.Titulo, err)
 		}
 
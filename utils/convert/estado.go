@@ -11,6 +11,10 @@ func MapEstadoToInt16(estado string) int16 {
 		return 1
 	case "CANCELADO":
 		return 2
+	case "FINALIZADO":
+		return 3
+	case "POSTERGADO":
+		return 4
 	default:
 		return 0
 	}
@@ -25,6 +29,10 @@ func MapEstadoToString(estado int16) string {
 		return "PUBLICADO"
 	case 2:
 		return "CANCELADO"
+	case 3:
+		return "FINALIZADO"
+	case 4:
+		return "POSTERGADO"
 	default:
 		return "BORRADOR"
 	}
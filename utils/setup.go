@@ -83,11 +83,27 @@ func ClearPostgresqlDatabaseTesting(
 			model any
 		}{
 			// First delete tables with foreign key dependencies
+			{"clave_idempotencia", &model.ClaveIdempotencia{}},
+			{"token", &model.Token{}},
 			{"rol_usuario", &model.RolUsuario{}},
 			{"usuario_cupon", &model.UsuarioCupon{}},
 			//{"evento_cupon", &model.EventoCupon{}},
+			{"publicacion_reventa", &model.PublicacionReventa{}},
+			{"politica_reventa", &model.PoliticaReventa{}},
+			{"historial_titular", &model.HistorialTitular{}},
+			{"transferencia_ticket", &model.TransferenciaTicket{}},
+			{"registro_ingreso", &model.RegistroIngreso{}},
+			{"reprogramacion_ticket", &model.ReprogramacionTicket{}},
+			{"reprogramacion_fecha", &model.ReprogramacionFecha{}},
+			{"reembolso_ticket", &model.ReembolsoTicket{}},
+			{"reembolso", &model.Reembolso{}},
 			{"ticket", &model.Ticket{}},
+			{"orden_de_compra_detalle", &model.OrdenDeCompraDetalle{}},
+			{"pago", &model.Pago{}},
 			{"comprobante_de_pago", &model.ComprobanteDePago{}},
+			{"preferencia_notificacion", &model.PreferenciaNotificacion{}},
+			{"notificacion", &model.Notificacion{}},
+			{"evento_dominio", &model.EventoDominio{}},
 			{"evento_fecha", &model.EventoFecha{}},
 			{"fecha", &model.Fecha{}},
 			{"tarifa", &model.Tarifa{}},
@@ -98,9 +114,9 @@ func ClearPostgresqlDatabaseTesting(
 			{"orden_de_compra", &model.OrdenDeCompra{}},
 			{"metodo_de_pago", &model.MetodoDePago{}},
 			{"evento", &model.Evento{}},
+			{"recinto", &model.Recinto{}},
 			{"cupon", &model.Cupon{}},
 			{"rol", &model.Rol{}},
-			{"notificacion", &model.Notificacion{}},
 			{"categoria", &model.Categoria{}},
 			{"usuario", &model.Usuario{}}, // Clear audit logs first to avoid FK constraints
 		}
@@ -173,11 +189,27 @@ func ClearPostgresqlDatabase(
 			model any
 		}{
 			// First delete tables with foreign key dependencies
+			{"clave_idempotencia", &model.ClaveIdempotencia{}},
+			{"token", &model.Token{}},
 			{"rol_usuario", &model.RolUsuario{}},
 			{"usuario_cupon", &model.UsuarioCupon{}},
 			//{"evento_cupon", &model.EventoCupon{}},
+			{"publicacion_reventa", &model.PublicacionReventa{}},
+			{"politica_reventa", &model.PoliticaReventa{}},
+			{"historial_titular", &model.HistorialTitular{}},
+			{"transferencia_ticket", &model.TransferenciaTicket{}},
+			{"registro_ingreso", &model.RegistroIngreso{}},
+			{"reprogramacion_ticket", &model.ReprogramacionTicket{}},
+			{"reprogramacion_fecha", &model.ReprogramacionFecha{}},
+			{"reembolso_ticket", &model.ReembolsoTicket{}},
+			{"reembolso", &model.Reembolso{}},
 			{"ticket", &model.Ticket{}},
+			{"orden_de_compra_detalle", &model.OrdenDeCompraDetalle{}},
+			{"pago", &model.Pago{}},
 			{"comprobante_de_pago", &model.ComprobanteDePago{}},
+			{"preferencia_notificacion", &model.PreferenciaNotificacion{}},
+			{"notificacion", &model.Notificacion{}},
+			{"evento_dominio", &model.EventoDominio{}},
 			{"evento_fecha", &model.EventoFecha{}},
 			{"fecha", &model.Fecha{}},
 			{"tarifa", &model.Tarifa{}},
@@ -188,9 +220,9 @@ func ClearPostgresqlDatabase(
 			{"orden_de_compra", &model.OrdenDeCompra{}},
 			{"metodo_de_pago", &model.MetodoDePago{}},
 			{"evento", &model.Evento{}},
+			{"recinto", &model.Recinto{}},
 			{"cupon", &model.Cupon{}},
 			{"rol", &model.Rol{}},
-			{"notificacion", &model.Notificacion{}},
 			{"categoria", &model.Categoria{}},
 			{"usuario", &model.Usuario{}},
 		}